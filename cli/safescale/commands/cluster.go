@@ -25,6 +25,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 
@@ -69,12 +70,16 @@ var ClusterCommand = &cli.Command{
 		clusterStopCommand,
 		clusterExpandCommand,
 		clusterShrinkCommand,
+		clusterAutoscaleCommand,
+		clusterUpgradeCommand,
 		clusterKubectlCommand,
 		clusterHelmCommand,
 		clusterListFeaturesCommand,
 		clusterCheckFeatureCommand,
 		clusterAddFeatureCommand,
 		clusterRemoveFeatureCommand,
+		clusterUpgradeFeatureCommand,
+		clusterFeatureHistoryCommand,
 		clusterFeatureCommands,
 	},
 }
@@ -251,6 +256,51 @@ func convertToMap(c *protocol.ClusterResponse) (map[string]interface{}, fail.Err
 	}
 	result["nodes"] = nodes
 
+	if len(c.NodePools) > 0 {
+		pools := make(map[string]interface{}, len(c.NodePools))
+		for _, v := range c.NodePools {
+			pools[v.Name] = map[string]interface{}{
+				"sizing": v.Sizing,
+				"image":  v.Image,
+				"count":  v.Count,
+				"labels": v.Labels,
+				"taints": v.Taints,
+				"nodes":  v.Nodes,
+			}
+		}
+		result["node_pools"] = pools
+	}
+
+	if c.Autoscaling != nil && c.Autoscaling.Policy != nil && (c.Autoscaling.Policy.Enabled || len(c.Autoscaling.Decisions) > 0) {
+		policy := c.Autoscaling.Policy
+		decisions := make([]map[string]interface{}, 0, len(c.Autoscaling.Decisions))
+		for _, v := range c.Autoscaling.Decisions {
+			decision := map[string]interface{}{
+				"action": v.Action,
+				"count":  v.Count,
+				"reason": v.Reason,
+			}
+			if date, err := ptypes.Timestamp(v.Date); err == nil {
+				decision["date"] = date.Local().Format(time.RFC3339)
+			}
+			if v.Error != "" {
+				decision["error"] = v.Error
+			}
+			decisions = append(decisions, decision)
+		}
+		result["autoscaling"] = map[string]interface{}{
+			"enabled":              policy.Enabled,
+			"pool":                 policy.Pool,
+			"min_nodes":            policy.MinNodes,
+			"max_nodes":            policy.MaxNodes,
+			"cooldown":             (time.Duration(policy.Cooldown) * time.Second).String(),
+			"metric":               policy.Metric,
+			"scale_up_threshold":   policy.ScaleUpThreshold,
+			"scale_down_threshold": policy.ScaleDownThreshold,
+			"decisions":            decisions,
+		}
+	}
+
 	if c.InstalledFeatures != nil {
 		result["installed_features"] = c.InstalledFeatures
 	}
@@ -380,6 +430,26 @@ var clusterCreateCommand = &cli.Command{
 	example:
 		--node-sizing "cpu~4, ram~15, count=8" will create 8 nodes`,
 		},
+		&cli.StringSliceFlag{
+			Name: "pool",
+			Usage: `Defines a named pool of nodes in format "<name>[:<sizing>]" (can be used several times to define several pools), where:
+	<name> contains only lowercase alphanumeric characters or '-'; pool "default" contains the nodes defined by --node-sizing
+	<sizing> is in the format of --node-sizing (cf. --sizing for details), including count
+	example:
+		--pool "gpu:gpu>=1,cpu>=8,count=2" --pool "highmem:ram>=64,count=1"`,
+		},
+		&cli.StringSliceFlag{
+			Name:  "pool-os",
+			Usage: `Defines the operating system of the nodes of a pool, in format "<name>:<os>" (default: value of --os)`,
+		},
+		&cli.StringSliceFlag{
+			Name:  "pool-label",
+			Usage: `Defines a label applied to the nodes of a pool (if the flavor supports it, like K8S), in format "<name>:<key>=<value>" (can be used several times)`,
+		},
+		&cli.StringSliceFlag{
+			Name:  "pool-taint",
+			Usage: `Defines a taint applied to the nodes of a pool (if the flavor supports it, like K8S), in format "<name>:<key>[=<value>]:<effect>" (can be used several times)`,
+		},
 	},
 
 	Action: func(c *cli.Context) (err error) {
@@ -438,6 +508,10 @@ var clusterCreateCommand = &cli.Command{
 				return err
 			}
 		}
+		pools, err := constructNodePoolsFromCLI(c)
+		if err != nil {
+			return clitools.FailureResponse(clitools.ExitOnInvalidOption(err.Error()))
+		}
 
 		clientSession, xerr := client.New(c.String("server"))
 		if xerr != nil {
@@ -456,6 +530,7 @@ var clusterCreateCommand = &cli.Command{
 			GatewaySizing: gatewaysDef,
 			MasterSizing:  mastersDef,
 			NodeSizing:    nodesDef,
+			NodePools:     pools,
 			// NodeCount:     uint32(c.Int("initial-node-count")),
 		}
 		res, err := clientSession.Cluster.Create(&req, temporal.GetLongOperationTimeout())
@@ -631,6 +706,10 @@ var clusterExpandCommand = &cli.Command{
 			Usage:   "Define the number of nodes wanted (default: 1)",
 			Value:   1,
 		},
+		&cli.StringFlag{
+			Name:  "pool",
+			Usage: "Define the node pool to expand (default: pool 'default')",
+		},
 		&cli.StringFlag{
 			Name:  "os",
 			Usage: "Define the Operating System wanted",
@@ -673,6 +752,7 @@ var clusterExpandCommand = &cli.Command{
 			Count:      int32(count),
 			NodeSizing: nodesDef,
 			ImageId:    los,
+			Pool:       c.String("pool"),
 		}
 
 		clientSession, xerr := client.New(c.String("server"))
@@ -702,6 +782,10 @@ var clusterShrinkCommand = &cli.Command{
 			Usage:   "Define the number of nodes to remove; default: 1",
 			Value:   1,
 		},
+		&cli.StringFlag{
+			Name:  "pool",
+			Usage: "Define the node pool to shrink (default: pool 'default')",
+		},
 		&cli.BoolFlag{
 			Name:    "assume-yes",
 			Aliases: []string{"yes", "y"},
@@ -745,6 +829,7 @@ var clusterShrinkCommand = &cli.Command{
 		req := protocol.ClusterResizeRequest{
 			Name:  clusterName,
 			Count: int32(count),
+			Pool:  c.String("pool"),
 		}
 
 		clientSession, xerr := client.New(c.String("server"))
@@ -760,6 +845,148 @@ var clusterShrinkCommand = &cli.Command{
 	},
 }
 
+// clusterAutoscaleCommand handles 'safescale cluster autoscale <clustername>'
+var clusterAutoscaleCommand = &cli.Command{
+	Name:      "autoscale",
+	Usage:     "autoscale CLUSTERNAME",
+	ArgsUsage: "CLUSTERNAME",
+
+	Flags: []cli.Flag{
+		&cli.UintFlag{
+			Name:  "min",
+			Usage: "Define the minimum number of nodes of the pool",
+		},
+		&cli.UintFlag{
+			Name:  "max",
+			Usage: "Define the maximum number of nodes of the pool",
+		},
+		&cli.StringFlag{
+			Name:  "pool",
+			Usage: "Define the node pool managed by the autoscaler (default: pool 'default')",
+		},
+		&cli.StringFlag{
+			Name:  "metric",
+			Value: "load",
+			Usage: `Define the metric driving the autoscaler:
+	load: average load per core of the nodes of the pool, collected over SSH
+	pods: number of pending pods reported by kubectl (flavor K8S only); nodes are removed when there is no pending pod and the load is low`,
+		},
+		&cli.DurationFlag{
+			Name:  "cooldown",
+			Value: 5 * time.Minute,
+			Usage: "Define the minimum delay between 2 scaling operations",
+		},
+		&cli.Float64Flag{
+			Name:  "scale-up-threshold",
+			Value: 0.8,
+			Usage: "Define the average load per core above which a node is added",
+		},
+		&cli.Float64Flag{
+			Name:  "scale-down-threshold",
+			Value: 0.2,
+			Usage: "Define the average load per core under which a node is removed",
+		},
+		&cli.BoolFlag{
+			Name:  "disable",
+			Usage: "Disable the autoscaling of the cluster",
+		},
+	},
+
+	Action: func(c *cli.Context) error {
+		logrus.Tracef("SafeScale command: {%s}, {%s} with args {%s}", clusterCmdLabel, c.Command.Name, c.Args())
+		err := extractClusterArgument(c)
+		if err != nil {
+			return clitools.FailureResponse(err)
+		}
+
+		policy := &protocol.ClusterAutoscalingPolicy{Enabled: !c.Bool("disable")}
+		if policy.Enabled {
+			if !c.IsSet("max") {
+				return clitools.FailureResponse(clitools.ExitOnInvalidOption("missing mandatory option --max"))
+			}
+			policy.Pool = c.String("pool")
+			policy.MinNodes = uint32(c.Uint("min"))
+			policy.MaxNodes = uint32(c.Uint("max"))
+			policy.Metric = c.String("metric")
+			policy.Cooldown = uint32(c.Duration("cooldown") / time.Second)
+			policy.ScaleUpThreshold = c.Float64("scale-up-threshold")
+			policy.ScaleDownThreshold = c.Float64("scale-down-threshold")
+		}
+		req := protocol.ClusterAutoscalingRequest{
+			Name:   clusterName,
+			Policy: policy,
+		}
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		if err = clientSession.Cluster.SetAutoscaling(&req, temporal.GetExecutionTimeout()); err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(err.Error()))
+		}
+		return clitools.SuccessResponse(nil)
+	},
+}
+
+// clusterUpgradeCommand handles 'safescale cluster upgrade <clustername>'
+var clusterUpgradeCommand = &cli.Command{
+	Name:      "upgrade",
+	Usage:     "upgrade CLUSTERNAME",
+	ArgsUsage: "CLUSTERNAME",
+
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "os-packages",
+			Usage: "Upgrade the OS packages of the nodes (Kubernetes packages excepted), rebooting them if needed",
+		},
+		&cli.StringFlag{
+			Name:  "image",
+			Usage: "Replace the nodes by new nodes created from this image",
+		},
+		&cli.StringFlag{
+			Name:  "pool",
+			Usage: "Upgrade only the nodes of this node pool (default: all the nodes)",
+		},
+		&cli.UintFlag{
+			Name:  "max-unavailable",
+			Value: 1,
+			Usage: "Define the maximum number of nodes upgraded at the same time",
+		},
+	},
+
+	Action: func(c *cli.Context) error {
+		logrus.Tracef("SafeScale command: {%s}, {%s} with args {%s}", clusterCmdLabel, c.Command.Name, c.Args())
+		err := extractClusterArgument(c)
+		if err != nil {
+			return clitools.FailureResponse(err)
+		}
+
+		if !c.Bool("os-packages") && c.String("image") == "" {
+			return clitools.FailureResponse(clitools.ExitOnInvalidOption("missing option --os-packages and/or --image"))
+		}
+		req := protocol.ClusterUpgradeRequest{
+			Name:           clusterName,
+			OsPackages:     c.Bool("os-packages"),
+			Image:          c.String("image"),
+			Pool:           c.String("pool"),
+			MaxUnavailable: uint32(c.Uint("max-unavailable")),
+		}
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		if err = clientSession.Cluster.Upgrade(&req, temporal.GetLongOperationTimeout()); err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(err.Error()))
+		}
+		return clitools.SuccessResponse(nil)
+	},
+}
+
 var clusterKubectlCommand = &cli.Command{
 	Name:      "kubectl",
 	Category:  "Administrative commands",
@@ -1045,6 +1272,22 @@ var clusterAddFeatureCommand = &cli.Command{
 			Name:  "skip-proxy",
 			Usage: "Disables reverse proxy rules",
 		},
+		&cli.BoolFlag{
+			Name:  "auto-expand",
+			Usage: "Adds nodes to the cluster if needed to meet the sizing requirements of the feature",
+		},
+		&cli.BoolFlag{
+			Name:  "plan",
+			Usage: "Displays the order of installation of the feature and its requirements, without installing anything",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Renders the scripts of the steps for each host, without executing them",
+		},
+		&cli.StringFlag{
+			Name:  "output-dir",
+			Usage: "With --dry-run, writes the rendered scripts in this folder instead of displaying them",
+		},
 	},
 
 	Action: clusterFeatureAddAction,
@@ -1082,6 +1325,38 @@ var clusterRemoveFeatureCommand = &cli.Command{
 	Action: clusterFeatureRemoveAction,
 }
 
+// clusterUpgradeFeatureCommand handles 'safescale cluster upgrade-feature CLUSTERNAME FEATURENAME'
+var clusterUpgradeFeatureCommand = &cli.Command{
+	Name:      "upgrade-feature",
+	Usage:     "upgrade-feature CLUSTERNAME FEATURENAME",
+	ArgsUsage: "CLUSTERNAME FEATURENAME",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "param",
+			Aliases: []string{"p"},
+			Usage:   "Allow to define content of feature parameters",
+		},
+		&cli.StringFlag{
+			Name:  "to-version",
+			Usage: "Version of the feature to upgrade to (default: the latest version available)",
+		},
+		&cli.BoolFlag{
+			Name:  "skip-proxy",
+			Usage: "Disable reverse proxy rules",
+		},
+	},
+	Action: clusterFeatureUpgradeAction,
+}
+
+// clusterFeatureHistoryCommand handles 'safescale cluster feature-history CLUSTERNAME [FEATURENAME]'
+var clusterFeatureHistoryCommand = &cli.Command{
+	Name:      "feature-history",
+	Aliases:   []string{"history"},
+	Usage:     "feature-history CLUSTERNAME [FEATURENAME]",
+	ArgsUsage: "CLUSTERNAME [FEATURENAME]",
+	Action:    clusterFeatureHistoryAction,
+}
+
 const clusterNodeCmdLabel = "node"
 
 // clusterNodeCommands handles 'safescale cluster node' commands
@@ -1347,6 +1622,8 @@ var clusterFeatureCommands = &cli.Command{
 		clusterCheckFeatureCommand,
 		clusterAddFeatureCommand,
 		clusterRemoveFeatureCommand,
+		clusterUpgradeFeatureCommand,
+		clusterFeatureHistoryCommand,
 	},
 }
 
@@ -1434,12 +1711,36 @@ func clusterFeatureAddAction(c *cli.Context) error {
 
 	settings := protocol.FeatureSettings{}
 	settings.SkipProxy = c.Bool("skip-proxy")
+	settings.AutoExpand = c.Bool("auto-expand")
 
 	clientSession, xerr := client.New(c.String("server"))
 	if xerr != nil {
 		return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
 	}
 
+	if c.Bool("plan") {
+		plan, err := clientSession.Cluster.PlanFeatures(clusterName, c.Args().Slice()[1:], values, &settings, 0)
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			msg := fmt.Sprintf("error planning addition of feature '%s' on cluster '%s': %s", featureName, clusterName, err.Error())
+			return clitools.FailureResponse(clitools.ExitOnRPC(msg))
+		}
+		return clitools.SuccessResponse(formatFeaturePlan(plan))
+	}
+	if c.Bool("dry-run") {
+		scripts, err := clientSession.Cluster.DryRunFeature(clusterName, featureName, values, &settings, 0)
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			msg := fmt.Sprintf("error rendering feature '%s' on cluster '%s': %s", featureName, clusterName, err.Error())
+			return clitools.FailureResponse(clitools.ExitOnRPC(msg))
+		}
+		out, err := outputFeatureScripts(scripts, c.String("output-dir"))
+		if err != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, err.Error()))
+		}
+		return clitools.SuccessResponse(out)
+	}
+
 	err = clientSession.Cluster.AddFeature(clusterName, featureName, values, &settings, 0)
 	if err != nil {
 		err = fail.FromGRPCStatus(err)
@@ -1556,3 +1857,64 @@ func clusterFeatureRemoveAction(c *cli.Context) error {
 	}
 	return clitools.SuccessResponse(nil)
 }
+
+func clusterFeatureUpgradeAction(c *cli.Context) error {
+	logrus.Tracef("SafeScale command: %s %s %s with args '%s'", clusterCmdLabel, clusterFeatureCmdLabel, c.Command.Name, c.Args())
+	err := extractClusterArgument(c)
+	if err != nil {
+		return clitools.FailureResponse(err)
+	}
+	err = extractFeatureArgument(c)
+	if err != nil {
+		return clitools.FailureResponse(err)
+	}
+	if version := c.String("to-version"); version != "" {
+		featureName += "@" + version
+	}
+
+	values := map[string]string{}
+	params := c.StringSlice("param")
+	for _, k := range params {
+		res := strings.Split(k, "=")
+		if len(res[0]) > 0 {
+			values[res[0]] = strings.Join(res[1:], "=")
+		}
+	}
+
+	settings := protocol.FeatureSettings{}
+	settings.SkipProxy = c.Bool("skip-proxy")
+
+	clientSession, xerr := client.New(c.String("server"))
+	if xerr != nil {
+		return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+	}
+
+	err = clientSession.Cluster.UpgradeFeature(clusterName, featureName, values, &settings, 0)
+	if err != nil {
+		err = fail.FromGRPCStatus(err)
+		msg := fmt.Sprintf("error upgrading feature '%s' on cluster '%s': %s", featureName, clusterName, err.Error())
+		return clitools.FailureResponse(clitools.ExitOnRPC(msg))
+	}
+	return clitools.SuccessResponse(nil)
+}
+
+func clusterFeatureHistoryAction(c *cli.Context) error {
+	logrus.Tracef("SafeScale command: %s %s with args '%s'", clusterCmdLabel, c.Command.Name, c.Args())
+	err := extractClusterArgument(c)
+	if err != nil {
+		return clitools.FailureResponse(err)
+	}
+
+	clientSession, xerr := client.New(c.String("server"))
+	if xerr != nil {
+		return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+	}
+
+	history, err := clientSession.Cluster.FeatureHistory(clusterName, c.Args().Get(1), 0)
+	if err != nil {
+		err = fail.FromGRPCStatus(err)
+		msg := fmt.Sprintf("error getting history of features of cluster '%s': %s", clusterName, err.Error())
+		return clitools.FailureResponse(clitools.ExitOnRPC(msg))
+	}
+	return clitools.SuccessResponse(formatFeatureHistory(history))
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+
+	"github.com/CS-SI/SafeScale/lib/client"
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/events"
+	clitools "github.com/CS-SI/SafeScale/lib/utils/cli"
+	"github.com/CS-SI/SafeScale/lib/utils/cli/enums/exitcode"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/strprocess"
+)
+
+var eventCmdName = "event"
+
+// EventCommand command
+var EventCommand = &cli.Command{
+	Name:  "event",
+	Usage: "event COMMAND",
+	Subcommands: []*cli.Command{
+		eventWatch,
+	},
+}
+
+var eventWatch = &cli.Command{
+	Name:  "watch",
+	Usage: "Prints the events of the resources of the current tenant as they happen, one JSON object per line, until interrupted",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "type",
+			Aliases: []string{"t"},
+			Usage:   "Prints only the events of type `TYPE` (host_created, host_state_changed, host_deleted, cluster_created, cluster_state_changed, cluster_expanded, cluster_shrunk, cluster_deleted, feature_installed, feature_removed, job_finished, job_failed); can be repeated",
+		},
+		&cli.StringSliceFlag{
+			Name:    "resource",
+			Aliases: []string{"r"},
+			Usage:   "Prints only the events of the resources whose name or id matches `PATTERN`; can be repeated",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		logrus.Tracef("SafeScale command: {%s}, {%s} with args {%s}", eventCmdName, c.Command.Name, c.Args())
+
+		req := &protocol.EventWatchRequest{Resources: c.StringSlice("resource")}
+		for _, name := range c.StringSlice("type") {
+			t, xerr := events.ParseType(name)
+			if xerr != nil {
+				return clitools.FailureResponse(clitools.ExitOnInvalidArgument(xerr.Error()))
+			}
+			req.Types = append(req.Types, t)
+		}
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		err := clientSession.Event.Watch(c.Context, req, func(e *protocol.Event) error {
+			content, err := json.Marshal(events.FromProtocol(e))
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(content))
+			return nil
+		})
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(strprocess.Capitalize(err.Error())))
+		}
+		return nil
+	},
+}
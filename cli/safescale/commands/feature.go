@@ -0,0 +1,339 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+
+	"github.com/CS-SI/SafeScale/lib/client"
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	clitools "github.com/CS-SI/SafeScale/lib/utils/cli"
+	"github.com/CS-SI/SafeScale/lib/utils/cli/enums/exitcode"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/strprocess"
+	"github.com/CS-SI/SafeScale/lib/utils/temporal"
+)
+
+var featureCmdName = "feature"
+
+// FeatureCommand command
+var FeatureCommand = &cli.Command{
+	Name:  "feature",
+	Usage: "feature COMMAND",
+	Subcommands: []*cli.Command{
+		featureLintCommand,
+		featureRepoCommands,
+	},
+}
+
+// featureLintCommand handles 'safescale feature lint <file|name>'
+var featureLintCommand = &cli.Command{
+	Name:      "lint",
+	Aliases:   []string{"validate"},
+	Usage:     "lint FILE|FEATURENAME",
+	ArgsUsage: "FILE|FEATURENAME",
+	Action: func(c *cli.Context) error {
+		logrus.Tracef("SafeScale command: {%s}, {%s} with args {%s}", featureCmdName, c.Command.Name, c.Args())
+		if c.NArg() != 1 {
+			_ = cli.ShowSubcommandHelp(c)
+			return clitools.FailureResponse(clitools.ExitOnInvalidArgument("Missing mandatory argument FILE|FEATURENAME."))
+		}
+
+		// If the argument is an existing file, its content is sent to safescaled; otherwise it's the name of a feature
+		// known by safescaled
+		name := c.Args().First()
+		content := ""
+		if _, err := os.Stat(name); err == nil {
+			b, err := ioutil.ReadFile(name)
+			if err != nil {
+				return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, fmt.Sprintf("failed to read file '%s': %s", name, err.Error())))
+			}
+			content = string(b)
+			if content == "" {
+				return clitools.FailureResponse(clitools.ExitOnInvalidArgument(fmt.Sprintf("file '%s' is empty", name)))
+			}
+			name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+		}
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		resp, err := clientSession.Feature.Validate(name, content, temporal.GetExecutionTimeout())
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(strprocess.Capitalize(client.DecorateTimeoutError(err, "validation of feature", false).Error())))
+		}
+
+		var (
+			issues []string
+			errors uint
+		)
+		for _, v := range resp.GetIssues() {
+			level := "error"
+			if v.GetWarning() {
+				level = "warning"
+			} else {
+				errors++
+			}
+			if v.GetKey() != "" {
+				issues = append(issues, fmt.Sprintf("%s: %s: %s", level, v.GetKey(), v.GetMessage()))
+			} else {
+				issues = append(issues, fmt.Sprintf("%s: %s", level, v.GetMessage()))
+			}
+		}
+		if errors > 0 {
+			msg := fmt.Sprintf("feature '%s' is invalid, %d error%s found:\n%s", name, errors, strprocess.Plural(errors), strings.Join(issues, "\n"))
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.InvalidArgument, msg))
+		}
+		return clitools.SuccessResponse(map[string]interface{}{"feature": name, "issues": issues})
+	},
+}
+
+const featureRepoCmdLabel = "repo"
+
+// featureRepoCommands handles 'safescale feature repo' commands
+var featureRepoCommands = &cli.Command{
+	Name:      featureRepoCmdLabel,
+	Aliases:   []string{"repository"},
+	Usage:     "manage feature repositories",
+	ArgsUsage: "COMMAND",
+	Subcommands: []*cli.Command{
+		featureRepoAddCommand,
+		featureRepoUpdateCommand,
+		featureRepoListCommand,
+	},
+}
+
+// featureRepoAddCommand handles 'safescale feature repo add <name> <url>'
+var featureRepoAddCommand = &cli.Command{
+	Name:      "add",
+	Usage:     "add NAME URL",
+	ArgsUsage: "NAME URL",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "type",
+			Usage: "Type of repository: git, http or local (default: deduced from URL)",
+		},
+		&cli.StringFlag{
+			Name:  "ref",
+			Usage: "Branch or tag to use (git repository only)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		logrus.Tracef("SafeScale command: {%s}, {%s}, {%s} with args {%s}", featureCmdName, featureRepoCmdLabel, c.Command.Name, c.Args())
+		switch c.NArg() {
+		case 0:
+			_ = cli.ShowSubcommandHelp(c)
+			return clitools.FailureResponse(clitools.ExitOnInvalidArgument("Missing mandatory argument NAME."))
+		case 1:
+			_ = cli.ShowSubcommandHelp(c)
+			return clitools.FailureResponse(clitools.ExitOnInvalidArgument("Missing mandatory argument URL."))
+		}
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		repo, err := clientSession.Feature.AddRepository(c.Args().Get(0), c.String("type"), c.Args().Get(1), c.String("ref"), temporal.GetExecutionTimeout())
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(strprocess.Capitalize(client.DecorateTimeoutError(err, "addition of feature repository", true).Error())))
+		}
+		return clitools.SuccessResponse(formatFeatureRepository(repo))
+	},
+}
+
+// featureRepoUpdateCommand handles 'safescale feature repo update [<name>]'
+var featureRepoUpdateCommand = &cli.Command{
+	Name:      "update",
+	Usage:     "update [NAME]",
+	ArgsUsage: "[NAME]",
+	Action: func(c *cli.Context) error {
+		logrus.Tracef("SafeScale command: {%s}, {%s}, {%s} with args {%s}", featureCmdName, featureRepoCmdLabel, c.Command.Name, c.Args())
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		resp, err := clientSession.Feature.UpdateRepositories(c.Args().First(), temporal.GetExecutionTimeout())
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(strprocess.Capitalize(client.DecorateTimeoutError(err, "update of feature repositories", true).Error())))
+		}
+		var result []map[string]interface{}
+		for _, v := range resp.GetRepositories() {
+			result = append(result, formatFeatureRepository(v))
+		}
+		return clitools.SuccessResponse(result)
+	},
+}
+
+// featureRepoListCommand handles 'safescale feature repo list'
+var featureRepoListCommand = &cli.Command{
+	Name:    "list",
+	Aliases: []string{"ls"},
+	Usage:   "list",
+	Action: func(c *cli.Context) error {
+		logrus.Tracef("SafeScale command: {%s}, {%s}, {%s} with args {%s}", featureCmdName, featureRepoCmdLabel, c.Command.Name, c.Args())
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		resp, err := clientSession.Feature.ListRepositories(temporal.GetExecutionTimeout())
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(strprocess.Capitalize(client.DecorateTimeoutError(err, "list of feature repositories", false).Error())))
+		}
+		var result []map[string]interface{}
+		for _, v := range resp.GetRepositories() {
+			result = append(result, formatFeatureRepository(v))
+		}
+		return clitools.SuccessResponse(result)
+	},
+}
+
+// formatFeatureRepository converts a feature repository to a map for output
+func formatFeatureRepository(repo *protocol.FeatureRepository) map[string]interface{} {
+	out := map[string]interface{}{
+		"name": repo.GetName(),
+		"type": repo.GetType(),
+		"url":  repo.GetUrl(),
+	}
+	if repo.GetRef() != "" {
+		out["ref"] = repo.GetRef()
+	}
+	if repo.GetUpdated() != nil {
+		if date, err := ptypes.Timestamp(repo.GetUpdated()); err == nil {
+			out["updated"] = date.Local().Format(time.RFC3339)
+		}
+	}
+	return out
+}
+
+// formatFeaturePlan converts a plan of installation of features to a map for output
+func formatFeaturePlan(plan *protocol.FeaturePlanResponse) map[string]interface{} {
+	steps := make([][]string, 0, len(plan.GetSteps()))
+	for _, v := range plan.GetSteps() {
+		steps = append(steps, v.GetFeatures())
+	}
+	present := plan.GetPresent()
+	if present == nil {
+		present = []string{}
+	}
+	return map[string]interface{}{
+		"steps":   steps,
+		"present": present,
+	}
+}
+
+// formatFeatureHistory converts the history of actions on features to a slice of maps for output
+func formatFeatureHistory(history *protocol.FeatureHistoryResponse) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(history.GetEntries()))
+	for _, v := range history.GetEntries() {
+		item := map[string]interface{}{
+			"feature": v.GetName(),
+			"action":  v.GetAction(),
+			"success": v.GetSuccess(),
+		}
+		if v.GetVersion() != "" {
+			item["version"] = v.GetVersion()
+		}
+		if len(v.GetParameters()) > 0 {
+			item["parameters"] = v.GetParameters()
+		}
+		if v.GetError() != "" {
+			item["error"] = v.GetError()
+		}
+		start, err := ptypes.Timestamp(v.GetStart())
+		if err == nil {
+			item["start"] = start.Local().Format(time.RFC3339)
+			if end, err := ptypes.Timestamp(v.GetEnd()); err == nil {
+				item["duration"] = temporal.FormatDuration(end.Sub(start))
+			}
+		}
+		steps := make([]map[string]interface{}, 0, len(v.GetSteps()))
+		for _, s := range v.GetSteps() {
+			step := map[string]interface{}{
+				"step":      s.GetStep(),
+				"host":      s.GetHost(),
+				"exit_code": s.GetExitCode(),
+				"success":   s.GetSuccess(),
+				"duration":  temporal.FormatDuration(time.Duration(s.GetDurationMs()) * time.Millisecond),
+			}
+			if s.GetError() != "" {
+				step["error"] = s.GetError()
+			}
+			if s.GetOutput() != "" {
+				step["output"] = s.GetOutput()
+			}
+			steps = append(steps, step)
+		}
+		item["steps"] = steps
+		out = append(out, item)
+	}
+	return out
+}
+
+// outputFeatureScripts returns the scripts rendered by a dry run for output, or writes them in folder 'dir' if not empty
+// (as '<index of step>_<step>_<host>.sh', to allow to diff the scripts of 2 versions of a feature) and returns the
+// paths of the files
+func outputFeatureScripts(response *protocol.FeatureDryRunResponse, dir string) (interface{}, error) {
+	if dir == "" {
+		out := make([]map[string]interface{}, 0, len(response.GetScripts()))
+		for _, v := range response.GetScripts() {
+			out = append(out, map[string]interface{}{
+				"step":   v.GetStep(),
+				"host":   v.GetHost(),
+				"script": v.GetScript(),
+			})
+		}
+		return out, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(response.GetScripts()))
+	index, previous := 0, ""
+	for _, v := range response.GetScripts() {
+		if v.GetStep() != previous {
+			index++
+			previous = v.GetStep()
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%02d_%s_%s.sh", index, v.GetStep(), v.GetHost()))
+		if err := ioutil.WriteFile(path, []byte(v.GetScript()), 0644); err != nil {
+			return nil, err
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
@@ -52,10 +52,12 @@ var HostCommand = &cli.Command{
 		hostReboot,
 		hostStart,
 		hostStop,
-		hostCheckFeatureCommand,  // Legacy, will be deprecated
-		hostAddFeatureCommand,    // Legacy, will be deprecated
-		hostRemoveFeatureCommand, // Legacy, will be deprecated
-		hostListFeaturesCommand,  // Legacy, will be deprecated
+		hostCheckFeatureCommand,   // Legacy, will be deprecated
+		hostAddFeatureCommand,     // Legacy, will be deprecated
+		hostRemoveFeatureCommand,  // Legacy, will be deprecated
+		hostUpgradeFeatureCommand, // Legacy, will be deprecated
+		hostListFeaturesCommand,   // Legacy, will be deprecated
+		hostFeatureHistoryCommand,
 		hostSecurityCommands,
 		hostFeatureCommands,
 	},
@@ -496,6 +498,18 @@ var hostAddFeatureCommand = &cli.Command{
 			Name:  "skip-proxy",
 			Usage: "Disable reverse proxy rules",
 		},
+		&cli.BoolFlag{
+			Name:  "plan",
+			Usage: "Displays the order of installation of the feature and its requirements, without installing anything",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Renders the scripts of the steps for each host, without executing them",
+		},
+		&cli.StringFlag{
+			Name:  "output-dir",
+			Usage: "With --dry-run, writes the rendered scripts in this folder instead of displaying them",
+		},
 	},
 
 	Action: hostFeatureAddAction,
@@ -537,6 +551,27 @@ var hostRemoveFeatureCommand = &cli.Command{
 	Action: hostFeatureRemoveAction,
 }
 
+// hostUpgradeFeatureCommand handles 'safescale host upgrade-feature <host name> <feature name>'
+var hostUpgradeFeatureCommand = &cli.Command{
+	Name:      "upgrade-feature",
+	Usage:     "Upgrade a feature installed on host.",
+	ArgsUsage: "HOSTNAME FEATURENAME",
+
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "param",
+			Aliases: []string{"p"},
+			Usage:   "Define value of feature parameter (can be used multiple times)",
+		},
+		&cli.StringFlag{
+			Name:  "to-version",
+			Usage: "Version of the feature to upgrade to (default: the latest version available)",
+		},
+	},
+
+	Action: hostFeatureUpgradeAction,
+}
+
 // hostSecurityCommands commands
 var hostSecurityCommands = &cli.Command{
 	Name:  securityCmdLabel,
@@ -724,7 +759,9 @@ var hostFeatureCommands = &cli.Command{
 		hostFeatureCheckCommand,
 		hostFeatureAddCommand,
 		hostFeatureRemoveCommand,
+		hostFeatureUpgradeCommand,
 		hostFeatureListCommand,
+		hostFeatureHistoryCommand,
 	},
 }
 
@@ -778,6 +815,18 @@ var hostFeatureAddCommand = &cli.Command{
 			Name:  "skip-proxy",
 			Usage: "Disable reverse proxy rules",
 		},
+		&cli.BoolFlag{
+			Name:  "plan",
+			Usage: "Displays the order of installation of the feature and its requirements, without installing anything",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Renders the scripts of the steps for each host, without executing them",
+		},
+		&cli.StringFlag{
+			Name:  "output-dir",
+			Usage: "With --dry-run, writes the rendered scripts in this folder instead of displaying them",
+		},
 	},
 
 	Action: hostFeatureAddAction,
@@ -823,6 +872,28 @@ func hostFeatureAddAction(c *cli.Context) error {
 		msg := fmt.Sprintf("failed to reach '%s': %s", hostName, client.DecorateTimeoutError(err, "waiting ssh on host", false))
 		return clitools.FailureResponse(clitools.ExitOnRPC(msg))
 	}
+	if c.Bool("plan") {
+		plan, err := clientSession.Host.PlanFeatures(hostInstance.Id, c.Args().Slice()[1:], values, &settings, 0)
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			msg := fmt.Sprintf("error planning addition of feature '%s' on host '%s': %s", featureName, hostName, err.Error())
+			return clitools.FailureResponse(clitools.ExitOnRPC(msg))
+		}
+		return clitools.SuccessResponse(formatFeaturePlan(plan))
+	}
+	if c.Bool("dry-run") {
+		scripts, err := clientSession.Host.DryRunFeature(hostInstance.Id, featureName, values, &settings, 0)
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			msg := fmt.Sprintf("error rendering feature '%s' on host '%s': %s", featureName, hostName, err.Error())
+			return clitools.FailureResponse(clitools.ExitOnRPC(msg))
+		}
+		out, err := outputFeatureScripts(scripts, c.String("output-dir"))
+		if err != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, err.Error()))
+		}
+		return clitools.SuccessResponse(out)
+	}
 	err = clientSession.Host.AddFeature(hostInstance.Id, featureName, values, &settings, 0)
 	if err != nil {
 		err = fail.FromGRPCStatus(err)
@@ -963,3 +1034,106 @@ func hostFeatureRemoveAction(c *cli.Context) error {
 	}
 	return clitools.SuccessResponse(nil)
 }
+
+// hostFeatureUpgradeCommand handles 'safescale host feature upgrade <host name> <feature name>'
+var hostFeatureUpgradeCommand = &cli.Command{
+	Name:      "upgrade",
+	Usage:     "Upgrade a feature installed on host.",
+	ArgsUsage: "HOSTNAME FEATURENAME",
+
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "param",
+			Aliases: []string{"p"},
+			Usage:   "Define value of feature parameter (can be used multiple times)",
+		},
+		&cli.StringFlag{
+			Name:  "to-version",
+			Usage: "Version of the feature to upgrade to (default: the latest version available)",
+		},
+	},
+
+	Action: hostFeatureUpgradeAction,
+}
+
+func hostFeatureUpgradeAction(c *cli.Context) error {
+	logrus.Tracef("SafeScale command: %s %s %s with args '%s'", hostCmdLabel, hostFeatureCmdLabel, c.Command.Name, c.Args())
+	err := extractHostArgument(c, 0)
+	if err != nil {
+		return clitools.FailureResponse(err)
+	}
+
+	err = extractFeatureArgument(c)
+	if err != nil {
+		return clitools.FailureResponse(err)
+	}
+	if version := c.String("to-version"); version != "" {
+		featureName += "@" + version
+	}
+
+	values := map[string]string{}
+	params := c.StringSlice("param")
+	for _, k := range params {
+		res := strings.Split(k, "=")
+		if len(res[0]) > 0 {
+			values[res[0]] = strings.Join(res[1:], "=")
+		}
+	}
+	settings := protocol.FeatureSettings{}
+
+	clientSession, xerr := client.New(c.String("server"))
+	if xerr != nil {
+		return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+	}
+	task, xerr := clientSession.GetTask()
+	if xerr != nil {
+		return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+	}
+
+	// Wait for SSH service on remote host first
+	err = clientSession.SSH.WaitReady(task, hostInstance.Id, temporal.GetConnectionTimeout())
+	if err != nil {
+		err = fail.FromGRPCStatus(err)
+		msg := fmt.Sprintf("failed to reach '%s': %s", hostName, client.DecorateTimeoutError(err, "waiting ssh on host", false))
+		return clitools.FailureResponse(clitools.ExitOnRPC(msg))
+	}
+
+	err = clientSession.Host.UpgradeFeature(hostInstance.Id, featureName, values, &settings, 0)
+	if err != nil {
+		err = fail.FromGRPCStatus(err)
+		msg := fmt.Sprintf("error upgrading feature '%s' on host '%s': %s", featureName, hostName, err.Error())
+		return clitools.FailureResponse(clitools.ExitOnRPC(msg))
+	}
+	return clitools.SuccessResponse(nil)
+}
+
+// hostFeatureHistoryCommand handles 'safescale host feature-history <host name> [<feature name>]'
+var hostFeatureHistoryCommand = &cli.Command{
+	Name:      "feature-history",
+	Aliases:   []string{"history"},
+	Usage:     "Lists the last actions on features of host, with the results of their steps",
+	ArgsUsage: "HOSTNAME [FEATURENAME]",
+
+	Action: hostFeatureHistoryAction,
+}
+
+func hostFeatureHistoryAction(c *cli.Context) error {
+	logrus.Tracef("SafeScale command: %s %s with args '%s'", hostCmdLabel, c.Command.Name, c.Args())
+	err := extractHostArgument(c, 0)
+	if err != nil {
+		return clitools.FailureResponse(err)
+	}
+
+	clientSession, xerr := client.New(c.String("server"))
+	if xerr != nil {
+		return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+	}
+
+	history, err := clientSession.Host.FeatureHistory(hostName, c.Args().Get(1), 0)
+	if err != nil {
+		err = fail.FromGRPCStatus(err)
+		msg := fmt.Sprintf("error getting history of features of host '%s': %s", hostName, err.Error())
+		return clitools.FailureResponse(clitools.ExitOnRPC(msg))
+	}
+	return clitools.SuccessResponse(formatFeatureHistory(history))
+}
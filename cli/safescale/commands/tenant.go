@@ -17,14 +17,25 @@
 package commands
 
 import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/CS-SI/SafeScale/lib/utils/cli/enums/exitcode"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
 	"github.com/CS-SI/SafeScale/lib/utils/strprocess"
 
 	"github.com/CS-SI/SafeScale/lib/client"
+	"github.com/CS-SI/SafeScale/lib/protocol"
 	clitools "github.com/CS-SI/SafeScale/lib/utils/cli"
 	"github.com/CS-SI/SafeScale/lib/utils/temporal"
 )
@@ -41,6 +52,11 @@ var TenantCommand = &cli.Command{
 		tenantSet,
 		tenantInspect,
 		tenantCleanup,
+		tenantScan,
+		tenantCredentials,
+		tenantValidate,
+		tenantAudit,
+		tenantQuota,
 	},
 }
 
@@ -161,3 +177,313 @@ var tenantCleanup = &cli.Command{
 		return clitools.SuccessResponse(nil)
 	},
 }
+
+var tenantScan = &cli.Command{
+	Name:      "scan",
+	Usage:     "Scan tenant templates to collect their real characteristics (only the templates not scanned yet by default)",
+	ArgsUsage: "[TENANT]",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "image",
+			Usage: "Defines an image usable by probe hosts, by order of preference (can be used several times)",
+		},
+		&cli.StringSliceFlag{
+			Name:    "template",
+			Aliases: []string{"t"},
+			Usage:   "Defines a regexp selecting the templates to scan (can be used several times)",
+		},
+		&cli.UintFlag{
+			Name:  "parallelism",
+			Usage: "Defines the number of probe hosts created simultaneously",
+		},
+		&cli.Float64Flag{
+			Name:  "budget",
+			Usage: "Defines the maximum cost of the scan, in the currency of the prices per hour of the tenant",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "Scans again the templates already scanned",
+		},
+	},
+	Subcommands: []*cli.Command{
+		tenantScanStatus,
+	},
+	Action: func(c *cli.Context) error {
+		logrus.Tracef("SafeScale command: {%s}, {%s} with args {%s}", tenantCmdName, c.Command.Name, c.Args())
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		req := &protocol.TenantScanRequest{
+			Name:        c.Args().First(),
+			Images:      c.StringSlice("image"),
+			Templates:   c.StringSlice("template"),
+			Parallelism: uint32(c.Uint("parallelism")),
+			Budget:      c.Float64("budget"),
+			Force:       c.Bool("force"),
+		}
+		resp, err := clientSession.Tenant.Scan(req, temporal.GetLongOperationTimeout())
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(strprocess.Capitalize(client.DecorateTimeoutError(err, "scan tenant", false).Error())))
+		}
+		return clitools.SuccessResponse(resp)
+	},
+}
+
+var tenantScanStatus = &cli.Command{
+	Name:      "status",
+	Usage:     "Show the data collected by the scanner",
+	ArgsUsage: "[TENANT]",
+	Action: func(c *cli.Context) error {
+		logrus.Tracef("SafeScale command: {%s}, {%s} with args {%s}", tenantCmdName, c.Command.Name, c.Args())
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		resp, err := clientSession.Tenant.ScanStatus(c.Args().First(), temporal.GetExecutionTimeout())
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(strprocess.Capitalize(client.DecorateTimeoutError(err, "get scan status of tenant", false).Error())))
+		}
+		return clitools.SuccessResponse(resp)
+	},
+}
+
+var tenantValidate = &cli.Command{
+	Name: "validate",
+	Usage: `Checks each tenant of a tenants file has the settings required by its provider
+	Without FILE, the tenants file used by safescaled is validated`,
+	ArgsUsage: "[FILE]",
+	Action: func(c *cli.Context) error {
+		if c.NArg() > 1 {
+			_ = cli.ShowSubcommandHelp(c)
+			return clitools.FailureResponse(clitools.ExitOnInvalidArgument("Too many arguments."))
+		}
+
+		logrus.Tracef("SafeScale command: {%s}, {%s} with args {%s}", tenantCmdName, c.Command.Name, c.Args())
+
+		var (
+			content []byte
+			format  string
+		)
+		if c.NArg() == 1 {
+			var err error
+			content, err = ioutil.ReadFile(c.Args().First())
+			if err != nil {
+				return clitools.FailureResponse(clitools.ExitOnInvalidArgument(fmt.Sprintf("Failed to read file '%s': %v", c.Args().First(), err)))
+			}
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(c.Args().First())), ".")
+			if format == "yml" {
+				format = "yaml"
+			}
+		}
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		resp, err := clientSession.Tenant.Validate(content, format, temporal.GetExecutionTimeout())
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(strprocess.Capitalize(client.DecorateTimeoutError(err, "validate tenants", false).Error())))
+		}
+
+		var invalid []string
+		for _, v := range resp.GetTenants() {
+			if !v.GetValid() {
+				invalid = append(invalid, fmt.Sprintf("tenant '%s': %s", v.GetName(), strings.Join(v.GetErrors(), ", ")))
+			}
+		}
+		if len(invalid) > 0 {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.InvalidArgument, fmt.Sprintf("Invalid tenants:\n%s", strings.Join(invalid, "\n"))))
+		}
+		return clitools.SuccessResponse(resp)
+	},
+}
+
+var tenantQuota = &cli.Command{
+	Name: "quota",
+	Usage: `Shows the use and the limits of the resources of the tenant (current tenant if TENANT is not set)
+	Limits are the ones of the tenant, of the caller and of the provider; a negative limit means no limit`,
+	ArgsUsage: "[TENANT]",
+	Action: func(c *cli.Context) error {
+		if c.NArg() > 1 {
+			_ = cli.ShowSubcommandHelp(c)
+			return clitools.FailureResponse(clitools.ExitOnInvalidArgument("Too many arguments."))
+		}
+
+		logrus.Tracef("SafeScale command: {%s}, {%s} with args {%s}", tenantCmdName, c.Command.Name, c.Args())
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		resp, err := clientSession.Tenant.Quota(c.Args().First(), temporal.GetExecutionTimeout())
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(strprocess.Capitalize(client.DecorateTimeoutError(err, "get quotas of tenant", false).Error())))
+		}
+		return clitools.SuccessResponse(resp)
+	},
+}
+
+var tenantAudit = &cli.Command{
+	Name:      "audit",
+	Usage:     "Lists the mutating operations recorded on the tenant (current tenant if TENANT is not set)",
+	ArgsUsage: "[TENANT]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "Lists the operations done after `TIME` (RFC 3339 date, YYYY-MM-DD or duration before now like 24h)",
+		},
+		&cli.StringFlag{
+			Name:  "until",
+			Usage: "Lists the operations done before `TIME` (RFC 3339 date, YYYY-MM-DD or duration before now like 24h)",
+		},
+		&cli.StringFlag{
+			Name:    "resource",
+			Aliases: []string{"r"},
+			Usage:   "Lists the operations on the resources whose name or id matches `PATTERN`",
+		},
+		&cli.UintFlag{
+			Name:  "limit",
+			Usage: "Lists only the `N` most recent operations",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() > 1 {
+			_ = cli.ShowSubcommandHelp(c)
+			return clitools.FailureResponse(clitools.ExitOnInvalidArgument("Too many arguments."))
+		}
+
+		logrus.Tracef("SafeScale command: {%s}, {%s} with args {%s}", tenantCmdName, c.Command.Name, c.Args())
+
+		req := &protocol.TenantAuditRequest{
+			Name:     c.Args().First(),
+			Resource: c.String("resource"),
+			Limit:    uint32(c.Uint("limit")),
+		}
+		now := time.Now()
+		for flag, value := range map[string]*int64{"since": &req.Since, "until": &req.Until} {
+			if c.String(flag) == "" {
+				continue
+			}
+			t, err := parseAuditTime(c.String(flag), now)
+			if err != nil {
+				return clitools.FailureResponse(clitools.ExitOnInvalidArgument(fmt.Sprintf("Invalid value of --%s: %v", flag, err)))
+			}
+			*value = t.Unix()
+		}
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		resp, err := clientSession.Tenant.Audit(req, temporal.GetExecutionTimeout())
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(strprocess.Capitalize(client.DecorateTimeoutError(err, "get audit events of tenant", false).Error())))
+		}
+
+		// Displays the parameters as JSON objects rather than strings
+		type auditEvent struct {
+			*protocol.AuditEvent
+			Parameters json.RawMessage `json:"parameters,omitempty"`
+		}
+		events := make([]auditEvent, 0, len(resp.GetEvents()))
+		for _, e := range resp.GetEvents() {
+			events = append(events, auditEvent{AuditEvent: e, Parameters: json.RawMessage(e.GetParameters())})
+		}
+		return clitools.SuccessResponse(map[string]interface{}{"name": resp.GetName(), "events": events})
+	},
+}
+
+// parseAuditTime parses a RFC 3339 date, a date YYYY-MM-DD or a duration before now
+func parseAuditTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is neither a date nor a duration", value)
+	}
+	return now.Add(-d), nil
+}
+
+var tenantCredentials = &cli.Command{
+	Name:  "credentials",
+	Usage: "Manages the credentials of tenants kept by safescaled in its encrypted credentials file",
+	Subcommands: []*cli.Command{
+		tenantCredentialsSet,
+	},
+}
+
+var tenantCredentialsSet = &cli.Command{
+	Name: "set",
+	Usage: `Stores credentials of a tenant in the encrypted credentials file of safescaled; they replace the values of the tenants file
+	Each CREDENTIAL is '<section>.<keyword>[=<value>]' (ie 'identity.Password=xxx'); if the value is omitted, it is asked for
+	(without echo if standard input is a terminal); an empty value removes the credential`,
+	ArgsUsage: "TENANT CREDENTIAL [CREDENTIAL...]",
+	Action: func(c *cli.Context) error {
+		if c.NArg() < 2 {
+			_ = cli.ShowSubcommandHelp(c)
+			return clitools.FailureResponse(clitools.ExitOnInvalidArgument("Missing mandatory arguments <tenant_name> and <credential>."))
+		}
+
+		logrus.Tracef("SafeScale command: {%s}, {%s} with args {%s}", tenantCmdName, c.Command.Name, c.Args())
+
+		credentials := map[string]string{}
+		reader := bufio.NewReader(os.Stdin)
+		for _, v := range c.Args().Tail() {
+			splitted := strings.SplitN(v, "=", 2)
+			if len(splitted) == 2 {
+				credentials[splitted[0]] = splitted[1]
+				continue
+			}
+			value, err := readCredential(reader, splitted[0])
+			if err != nil {
+				return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, err.Error()))
+			}
+			credentials[splitted[0]] = value
+		}
+
+		clientSession, xerr := client.New(c.String("server"))
+		if xerr != nil {
+			return clitools.FailureResponse(clitools.ExitOnErrorWithMessage(exitcode.Run, xerr.Error()))
+		}
+
+		err := clientSession.Tenant.SetCredentials(c.Args().First(), credentials, temporal.GetExecutionTimeout())
+		if err != nil {
+			err = fail.FromGRPCStatus(err)
+			return clitools.FailureResponse(clitools.ExitOnRPC(strprocess.Capitalize(client.DecorateTimeoutError(err, "set tenant credentials", false).Error())))
+		}
+		return clitools.SuccessResponse(nil)
+	},
+}
+
+// readCredential reads the value of a credential on standard input, without echo if it is a terminal
+func readCredential(reader *bufio.Reader, key string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "%s: ", key)
+		value, err := terminal.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(value), err
+	}
+	value, err := reader.ReadString('\n')
+	if err != nil && value == "" {
+		return "", fail.Wrap(err, "failed to read value of credential '%s'", key)
+	}
+	return strings.TrimRight(value, "\r\n"), nil
+}
@@ -25,7 +25,9 @@ import (
 	"github.com/denisbrodbeck/machineid"
 	"github.com/urfave/cli/v2"
 
+	"github.com/CS-SI/SafeScale/lib/protocol"
 	clitools "github.com/CS-SI/SafeScale/lib/utils/cli"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
 )
 
 // GenerateClientIdentity builds a string identifying the client
@@ -165,3 +167,65 @@ func constructHostDefinitionStringFromCLI(c *cli.Context, key string) (string, e
 	// }
 	// return &def, count, nil
 }
+
+// constructNodePoolsFromCLI builds the node pools from the flags --pool, --pool-os, --pool-label and --pool-taint
+func constructNodePoolsFromCLI(c *cli.Context) ([]*protocol.ClusterNodePool, fail.Error) {
+	var pools []*protocol.ClusterNodePool
+	byName := map[string]*protocol.ClusterNodePool{}
+	for _, v := range c.StringSlice("pool") {
+		splitted := strings.SplitN(v, ":", 2)
+		name := strings.TrimSpace(splitted[0])
+		if name == "" {
+			return nil, fail.SyntaxError("invalid value '%s' for --pool: name of the pool is missing", v)
+		}
+		if _, ok := byName[name]; ok {
+			return nil, fail.SyntaxError("invalid value '%s' for --pool: pool '%s' already defined", v, name)
+		}
+		pool := &protocol.ClusterNodePool{Name: name, Labels: map[string]string{}}
+		if len(splitted) > 1 {
+			pool.Sizing = splitted[1]
+		}
+		byName[name] = pool
+		pools = append(pools, pool)
+	}
+
+	// poolOfOption returns the pool targeted by a value of the flags --pool-*, and the rest of the value
+	poolOfOption := func(flag, value string) (*protocol.ClusterNodePool, string, fail.Error) {
+		splitted := strings.SplitN(value, ":", 2)
+		if len(splitted) != 2 || splitted[1] == "" {
+			return nil, "", fail.SyntaxError("invalid value '%s' for --%s: must be in the form '<pool name>:<value>'", value, flag)
+		}
+		pool, ok := byName[strings.TrimSpace(splitted[0])]
+		if !ok {
+			return nil, "", fail.SyntaxError("invalid value '%s' for --%s: pool '%s' is not defined with --pool", value, flag, splitted[0])
+		}
+		return pool, splitted[1], nil
+	}
+
+	for _, v := range c.StringSlice("pool-os") {
+		pool, value, xerr := poolOfOption("pool-os", v)
+		if xerr != nil {
+			return nil, xerr
+		}
+		pool.Image = value
+	}
+	for _, v := range c.StringSlice("pool-label") {
+		pool, value, xerr := poolOfOption("pool-label", v)
+		if xerr != nil {
+			return nil, xerr
+		}
+		splitted := strings.SplitN(value, "=", 2)
+		if len(splitted) != 2 {
+			return nil, fail.SyntaxError("invalid value '%s' for --pool-label: label must be in the form '<key>=<value>'", v)
+		}
+		pool.Labels[splitted[0]] = splitted[1]
+	}
+	for _, v := range c.StringSlice("pool-taint") {
+		pool, value, xerr := poolOfOption("pool-taint", v)
+		if xerr != nil {
+			return nil, xerr
+		}
+		pool.Taints = append(pool.Taints, value)
+	}
+	return pools, nil
+}
@@ -129,7 +129,31 @@ func main() {
 		&cli.StringFlag{
 			Name:    "tenant",
 			Aliases: []string{"T"},
-			Usage:   "Use tenant TENANT (default: none)",
+			Usage:   "Use tenant TENANT (default: content of SAFESCALE_TENANT, or else tenant selected with 'safescale tenant set', or else the only tenant of the daemon)",
+		},
+		&cli.BoolFlag{
+			Name:  "tls",
+			Usage: "Connects to daemon using TLS, verifying its certificate with the certificate authorities of the system (default: content of SAFESCALE_TLS)",
+		},
+		&cli.StringFlag{
+			Name:  "tls-ca",
+			Usage: "Connects to daemon using TLS, verifying its certificate with the certificate authorities in `FILE` (default: content of SAFESCALE_TLS_CA)",
+		},
+		&cli.StringFlag{
+			Name:  "tls-cert",
+			Usage: "Authenticates to daemon with the client certificate in `FILE` (default: content of SAFESCALE_TLS_CERT)",
+		},
+		&cli.StringFlag{
+			Name:  "tls-key",
+			Usage: "Private key of the client certificate in `FILE` (default: content of SAFESCALE_TLS_KEY)",
+		},
+		&cli.StringFlag{
+			Name:  "tls-server-name",
+			Usage: "Name expected in the certificate of the daemon, if different from the host of SERVER (default: content of SAFESCALE_TLS_SERVER_NAME)",
+		},
+		&cli.StringFlag{
+			Name:  "token",
+			Usage: "Authenticates to daemon with the bearer `TOKEN` (static token or JWT) (default: content of SAFESCALE_TOKEN)",
 		},
 	}
 
@@ -168,6 +192,25 @@ func main() {
 			}
 		}
 
+		// Command line options replace the TLS settings of the environment
+		tlsOptions := client.TLSOptionsFromEnv()
+		if c.IsSet("tls") {
+			tlsOptions.Enabled = c.Bool("tls")
+		}
+		for flag, value := range map[string]*string{"tls-ca": &tlsOptions.CAFile, "tls-cert": &tlsOptions.CertFile, "tls-key": &tlsOptions.KeyFile, "tls-server-name": &tlsOptions.ServerName} {
+			if c.IsSet(flag) {
+				*value = c.String(flag)
+				tlsOptions.Enabled = true
+			}
+		}
+		client.SetTLSOptions(tlsOptions)
+		if c.IsSet("token") {
+			client.SetToken(c.String("token"))
+		}
+		if c.IsSet("tenant") {
+			client.SetTenant(c.String("tenant"))
+		}
+
 		clientSession, err = client.New(c.String("server"))
 		return err
 	}
@@ -207,6 +250,12 @@ func main() {
 	app.Commands = append(app.Commands, commands.ClusterCommand)
 	sort.Sort(cli.CommandsByName(commands.ClusterCommand.Subcommands))
 
+	app.Commands = append(app.Commands, commands.FeatureCommand)
+	sort.Sort(cli.CommandsByName(commands.FeatureCommand.Subcommands))
+
+	app.Commands = append(app.Commands, commands.EventCommand)
+	sort.Sort(cli.CommandsByName(commands.EventCommand.Subcommands))
+
 	sort.Sort(cli.CommandsByName(app.Commands))
 
 	// Starts ctrl+c handler before app.RunContext()
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/CS-SI/SafeScale/lib/server/audit"
+	"github.com/CS-SI/SafeScale/lib/server/listeners"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// assembleAuditTrail returns the trail recording the mutating calls in the file given by parameter or environment
+func assembleAuditTrail(c *cli.Context) (*audit.Trail, fail.Error) {
+	path := c.String("audit-log")
+	if path == "" {
+		path = os.Getenv("SAFESCALED_AUDIT_LOG")
+	}
+	if path == "" {
+		path = audit.DefaultPath
+	}
+	trail, xerr := audit.NewTrail(path, listeners.CurrentTenantName)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	copyToObjectStorage := c.Bool("audit-object-storage")
+	if !c.IsSet("audit-object-storage") {
+		copyToObjectStorage, _ = strconv.ParseBool(os.Getenv("SAFESCALED_AUDIT_OBJECT_STORAGE"))
+	}
+	if copyToObjectStorage {
+		trail.CopyToObjectStorage(listeners.TenantService)
+	}
+	return trail, nil
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/CS-SI/SafeScale/lib/server/auth"
+	"github.com/CS-SI/SafeScale/lib/server/listeners"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// assembleAuthorizer returns the authorizer applying the policy file given by parameter or environment (nil if none is set)
+func assembleAuthorizer(c *cli.Context) (*auth.Authorizer, fail.Error) {
+	path := c.String("auth-policy")
+	if path == "" {
+		path = os.Getenv("SAFESCALED_AUTH_POLICY")
+	}
+	if path == "" {
+		return nil, nil
+	}
+	policy, xerr := auth.LoadPolicy(path)
+	if xerr != nil {
+		return nil, xerr
+	}
+	return auth.NewAuthorizer(policy, listeners.CurrentTenantName, listeners.ResourceLabels)
+}
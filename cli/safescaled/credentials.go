@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/CS-SI/SafeScale/lib/server/iaas"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// unlockCredentials unlocks the encrypted credentials file of the tenants, with the passphrase given by the environment
+// variable SAFESCALE_CREDENTIALS_PASSPHRASE or, if the file exists and standard input is a terminal, asked for
+func unlockCredentials() fail.Error {
+	path := iaas.CredentialsFile()
+	passphrase := os.Getenv(iaas.EnvCredentialsPassphrase)
+	if passphrase == "" {
+		if _, err := os.Stat(path); err != nil {
+			// No passphrase and no file, the credentials file is not used
+			return nil
+		}
+		fd := int(os.Stdin.Fd())
+		if !terminal.IsTerminal(fd) {
+			logrus.Warnf("Credentials file '%s' not unlocked: environment variable %s not set", path, iaas.EnvCredentialsPassphrase)
+			return nil
+		}
+		fmt.Printf("Passphrase of credentials file '%s': ", path)
+		content, err := terminal.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return fail.Wrap(err, "failed to read passphrase of credentials file")
+		}
+		passphrase = string(content)
+	}
+
+	if xerr := iaas.UnlockCredentials(path, passphrase); xerr != nil {
+		return xerr
+	}
+	logrus.Infof("Credentials file '%s' unlocked", path)
+	return nil
+}
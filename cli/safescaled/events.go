@@ -0,0 +1,48 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+
+	"github.com/CS-SI/SafeScale/lib/server/events"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// startWebhooks delivers the events to the webhooks of the file given by parameter or environment, if any
+func startWebhooks(c *cli.Context) fail.Error {
+	path := c.String("webhooks")
+	if path == "" {
+		path = os.Getenv("SAFESCALED_WEBHOOKS")
+	}
+	if path == "" {
+		return nil
+	}
+
+	hooks, xerr := events.LoadWebhooks(path)
+	if xerr != nil {
+		return xerr
+	}
+	dispatcher := events.NewDispatcher(hooks)
+	dispatcher.Start(events.DefaultBus())
+	events.SetDefaultDispatcher(dispatcher)
+	logrus.Infof("Delivering events to %d webhook(s) of file '%s'", len(hooks), path)
+	return nil
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"google.golang.org/grpc"
+
+	"github.com/CS-SI/SafeScale/lib/server/gateway"
+	"github.com/CS-SI/SafeScale/lib/server/listeners"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// gatewayServers returns the servers of the services exposed by the REST/JSON gateway, by service name
+func gatewayServers() map[string]interface{} {
+	return map[string]interface{}{
+		"BucketService":        &listeners.BucketListener{},
+		"ClusterService":       &listeners.ClusterListener{},
+		"HostService":          &listeners.HostListener{},
+		"FeatureService":       &listeners.FeatureListener{},
+		"ImageService":         &listeners.ImageListener{},
+		"JobService":           &listeners.JobManagerListener{},
+		"NetworkService":       &listeners.NetworkListener{},
+		"SubnetService":        &listeners.SubnetListener{},
+		"SecurityGroupService": &listeners.SecurityGroupListener{},
+		"ShareService":         &listeners.ShareListener{},
+		"SshService":           &listeners.SSHListener{},
+		"TemplateService":      &listeners.TemplateListener{},
+		"TenantService":        &listeners.TenantListener{},
+		"VolumeService":        &listeners.VolumeListener{},
+	}
+}
+
+// startGateway serves the REST/JSON gateway on the address given by parameter or environment, if any; the calls go
+// through interceptor, as the gRPC ones, and are secured by the TLS configuration of the daemon
+func startGateway(c *cli.Context, interceptor grpc.UnaryServerInterceptor, tlsConfig *tls.Config) fail.Error {
+	listen := c.String("gateway-listen")
+	if listen == "" {
+		listen = os.Getenv("SAFESCALED_GATEWAY_LISTEN")
+	}
+	if listen == "" {
+		return nil
+	}
+
+	g, xerr := gateway.New(gatewayServers(), interceptor)
+	if xerr != nil {
+		return xerr
+	}
+	lis, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fail.Wrap(err, "failed to listen on '%s' for the gateway", listen)
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		lis = tls.NewListener(lis, tlsConfig.Clone())
+		scheme = "https"
+	}
+	go func() {
+		if err := http.Serve(lis, g); err != nil {
+			logrus.Errorf("Failed to serve the gateway: %v", err)
+		}
+	}()
+	logrus.Infof("Serving REST/JSON gateway on '%s://%s/v1', described by '%s'", scheme, lis.Addr().String(), gateway.OpenAPIPath)
+	return nil
+}
+
+// openapiCommand prints the OpenAPI document of the REST/JSON gateway
+var openapiCommand = &cli.Command{
+	Name:  "openapi",
+	Usage: "Prints the OpenAPI document describing the REST/JSON gateway",
+	Action: func(c *cli.Context) error {
+		g, xerr := gateway.New(gatewayServers(), nil)
+		if xerr != nil {
+			return xerr
+		}
+		content, err := json.MarshalIndent(g.OpenAPI(), "", "  ")
+		if err != nil {
+			return fail.ToError(err)
+		}
+		fmt.Println(string(content))
+		return nil
+	},
+}
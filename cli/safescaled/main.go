@@ -26,20 +26,30 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/dlespiau/covertool/pkg/exit"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 
 	"github.com/CS-SI/SafeScale/lib/protocol"
-	_ "github.com/CS-SI/SafeScale/lib/server"
+	"github.com/CS-SI/SafeScale/lib/server"
+	"github.com/CS-SI/SafeScale/lib/server/audit"
+	"github.com/CS-SI/SafeScale/lib/server/events"
+	"github.com/CS-SI/SafeScale/lib/server/handlers"
 	"github.com/CS-SI/SafeScale/lib/server/iaas"
 	"github.com/CS-SI/SafeScale/lib/server/listeners"
+	srvutils "github.com/CS-SI/SafeScale/lib/server/utils"
 	app2 "github.com/CS-SI/SafeScale/lib/utils/app"
 	"github.com/CS-SI/SafeScale/lib/utils/debug"
 	"github.com/CS-SI/SafeScale/lib/utils/debug/tracing"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
+	"github.com/CS-SI/SafeScale/lib/utils/telemetry"
+	"github.com/CS-SI/SafeScale/lib/utils/temporal"
 )
 
 var profileCloseFunc = func() {}
@@ -54,9 +64,36 @@ func cleanup(onAbort bool) {
 		fmt.Println("Cleaning up...")
 	}
 	profileCloseFunc()
+	purgeScanProbes(true, temporal.GetHostCleanupTimeout())
+	if exporter := telemetry.DefaultExporter(); exporter != nil {
+		exporter.Shutdown()
+	}
+	if trail := audit.DefaultTrail(); trail != nil {
+		_ = trail.Close()
+	}
+	if dispatcher := events.DefaultDispatcher(); dispatcher != nil {
+		dispatcher.Stop()
+	}
 	exit.Exit(1)
 }
 
+// purgeScanProbes deletes the probe hosts left by interrupted scans (and those of the running scans if 'all' is true),
+// giving up after 'timeout' (the remaining ones being deleted at next start)
+func purgeScanProbes(all bool, timeout time.Duration) {
+	done := make(chan fail.Error, 1)
+	go func() {
+		done <- handlers.PurgeScanProbes(all)
+	}()
+	select {
+	case xerr := <-done:
+		if xerr != nil {
+			logrus.Warnf("failed to delete the probe hosts left by interrupted scans: %v", xerr)
+		}
+	case <-time.After(timeout):
+		logrus.Warnf("probe hosts left by interrupted scans not deleted after %s, giving up until next start", timeout)
+	}
+}
+
 // *** MAIN ***
 func work(c *cli.Context) {
 	signalCh := make(chan os.Signal)
@@ -73,14 +110,33 @@ func work(c *cli.Context) {
 		logrus.Errorf(err.Error())
 	}
 
+	if xerr := unlockCredentials(); xerr != nil {
+		logrus.Fatalf(xerr.Error())
+	}
+
 	logrus.Infoln("Checking configuration")
 	_, err = iaas.GetTenantNames()
 	if err != nil {
 		logrus.Fatalf(err.Error())
 	}
+	// Invalid tenants are reported now instead of failing at first use
+	if _, report, xerr := iaas.ValidateTenantsFile(); xerr == nil {
+		listeners.ReportTenantsValidation(report)
+	}
+	// Tenants are reloaded each time the tenants file changes
+	if xerr := iaas.WatchTenants(listeners.OnTenantsChange); xerr != nil {
+		logrus.Warnf("Tenants file will not be reloaded on change: %v", xerr)
+	}
+	// The probe hosts left by scans interrupted by a previous stop are deleted in background
+	go purgeScanProbes(false, temporal.GetLongOperationTimeout())
 
 	listen := assembleListenString(c)
 
+	tlsConfig, xerr := assembleTLSConfig(c)
+	if xerr != nil {
+		logrus.Fatalf(xerr.Error())
+	}
+
 	// DEV VAR
 	suffix := ""
 	if suffixCandidate := os.Getenv("SAFESCALE_METADATA_SUFFIX"); suffixCandidate != "" {
@@ -90,6 +146,9 @@ func work(c *cli.Context) {
 	envVars := os.Environ()
 	for _, envVar := range envVars {
 		if strings.HasPrefix(envVar, "SAFESCALE") {
+			if parts := strings.SplitN(envVar, "=", 2); strings.HasSuffix(parts[0], "_PASSPHRASE") {
+				envVar = parts[0] + "=" + secret.Masked
+			}
 			logrus.Infof("Using %s", envVar)
 		}
 	}
@@ -99,11 +158,77 @@ func work(c *cli.Context) {
 	if err != nil {
 		logrus.Fatalf("failed to listen: %v", err)
 	}
-	s := grpc.NewServer()
+	if xerr := startMetricsServer(c); xerr != nil {
+		logrus.Fatalf(xerr.Error())
+	}
+	exporter, xerr := assembleTelemetryExporter(c)
+	if xerr != nil {
+		logrus.Fatalf(xerr.Error())
+	}
+	if exporter != nil {
+		telemetry.SetDefaultExporter(exporter)
+		logrus.Infof("Tracing enabled, sending spans to '%s'", exporter.URL())
+	}
+	// The calls are counted and traced whatever their outcome, then the tenant of each call has to be known before
+	// authorizing it
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		srvutils.MetricsUnaryServerInterceptor(),
+		srvutils.TelemetryUnaryServerInterceptor(),
+		listeners.TenantUnaryServerInterceptor(),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		srvutils.MetricsStreamServerInterceptor(),
+		srvutils.TelemetryStreamServerInterceptor(),
+		listeners.TenantStreamServerInterceptor(),
+	}
+	authorizer, xerr := assembleAuthorizer(c)
+	if xerr != nil {
+		logrus.Fatalf(xerr.Error())
+	}
+	if authorizer != nil {
+		unaryInterceptors = append(unaryInterceptors, authorizer.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, authorizer.StreamServerInterceptor())
+		logrus.Infoln("Authentication and authorization of the callers enabled")
+	}
+	trail, xerr := assembleAuditTrail(c)
+	if xerr != nil {
+		logrus.Fatalf(xerr.Error())
+	}
+	audit.SetDefaultTrail(trail)
+	unaryInterceptors = append(unaryInterceptors, trail.UnaryServerInterceptor())
+	logrus.Infof("Recording mutating calls in audit file '%s'", trail.Path())
+	// The events published by the operations are attributed to the tenant of the call, the end of the jobs being
+	// published once the call answered
+	events.SetTenantFunc(listeners.CurrentTenantName)
+	unaryInterceptors = append(unaryInterceptors, server.JobEventsUnaryServerInterceptor())
+	if xerr := startWebhooks(c); xerr != nil {
+		logrus.Fatalf(xerr.Error())
+	}
+	// The calls made through the REST/JSON gateway go through the same interceptors as the gRPC ones
+	unaryInterceptor := srvutils.ChainUnaryServerInterceptors(unaryInterceptors...)
+	if xerr := startGateway(c, unaryInterceptor, tlsConfig); xerr != nil {
+		logrus.Fatalf(xerr.Error())
+	}
+	options := []grpc.ServerOption{
+		grpc.UnaryInterceptor(unaryInterceptor),
+		grpc.StreamInterceptor(srvutils.ChainStreamServerInterceptors(streamInterceptors...)),
+	}
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		if tlsConfig.ClientCAs != nil {
+			logrus.Infoln("TLS enabled, clients must present a certificate")
+		} else {
+			logrus.Infoln("TLS enabled")
+		}
+	} else if !strings.HasPrefix(listen, defaultDaemonHost+":") && !strings.HasPrefix(listen, "127.0.0.1:") {
+		logrus.Warnf("Listening on '%s' without TLS: the connections to safescaled are neither encrypted nor authenticated", listen)
+	}
+	s := grpc.NewServer(options...)
 
 	logrus.Infoln("Registering services")
 	protocol.RegisterBucketServiceServer(s, &listeners.BucketListener{})
 	protocol.RegisterClusterServiceServer(s, &listeners.ClusterListener{})
+	protocol.RegisterEventServiceServer(s, &listeners.EventListener{})
 	protocol.RegisterHostServiceServer(s, &listeners.HostListener{})
 	protocol.RegisterFeatureServiceServer(s, &listeners.FeatureListener{})
 	protocol.RegisterImageServiceServer(s, &listeners.ImageListener{})
@@ -117,6 +242,15 @@ func work(c *cli.Context) {
 	protocol.RegisterTenantServiceServer(s, &listeners.TenantListener{})
 	protocol.RegisterVolumeServiceServer(s, &listeners.VolumeListener{})
 
+	if period := assembleAutoscalerPeriod(c); period > 0 {
+		logrus.Infof("Starting cluster autoscaler, evaluating policies every %s", period.String())
+		autoscaler, xerr := handlers.NewAutoscaler(period, listeners.TenantService)
+		if xerr != nil {
+			logrus.Fatalf(xerr.Error())
+		}
+		autoscaler.Start()
+	}
+
 	// log.Println("Initializing service factory")
 	// commands.InitServiceFactory()
 
@@ -170,6 +304,22 @@ func assembleListenString(c *cli.Context) string {
 	return listen
 }
 
+// assembleAutoscalerPeriod returns the period of evaluation of the cluster autoscaling policies (0 meaning autoscaler disabled)
+func assembleAutoscalerPeriod(c *cli.Context) time.Duration {
+	if c.IsSet("autoscaler-period") {
+		return c.Duration("autoscaler-period")
+	}
+	if value := os.Getenv("SAFESCALED_AUTOSCALER_PERIOD"); value != "" {
+		period, err := time.ParseDuration(value)
+		if err != nil || period < 0 {
+			logrus.Warningf("Environment variable 'SAFESCALED_AUTOSCALER_PERIOD' contains invalid content ('%s'): ignored.", value)
+			return 0
+		}
+		return period
+	}
+	return 0
+}
+
 func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
@@ -215,6 +365,50 @@ func main() {
 			Aliases: []string{"l"},
 			Usage:   "Listen on specified port `IP:PORT` (default: localhost:50051)",
 		},
+		&cli.StringFlag{
+			Name:  "tls-cert",
+			Usage: "Enables TLS using the server certificate in `FILE` (default: content of SAFESCALED_TLS_CERT)",
+		},
+		&cli.StringFlag{
+			Name:  "tls-key",
+			Usage: "Private key of the server certificate in `FILE` (default: content of SAFESCALED_TLS_KEY)",
+		},
+		&cli.StringFlag{
+			Name:  "tls-client-ca",
+			Usage: "Requires client certificates signed by the certificate authorities in `FILE` (default: content of SAFESCALED_TLS_CLIENT_CA)",
+		},
+		&cli.StringFlag{
+			Name:  "auth-policy",
+			Usage: "Authenticates the callers and authorizes their calls following the policy in `FILE` (default: content of SAFESCALED_AUTH_POLICY)",
+		},
+		&cli.StringFlag{
+			Name:  "audit-log",
+			Usage: "Records the mutating calls in `FILE` (default: content of SAFESCALED_AUDIT_LOG, else " + audit.DefaultPath + ")",
+		},
+		&cli.BoolFlag{
+			Name:  "audit-object-storage",
+			Usage: "Copies the audit events in the metadata bucket of their tenant (default: content of SAFESCALED_AUDIT_OBJECT_STORAGE)",
+		},
+		&cli.StringFlag{
+			Name:  "metrics-listen",
+			Usage: "Serves the metrics in Prometheus format on 'http://IP:PORT/metrics' at `IP:PORT` (default: content of SAFESCALED_METRICS_LISTEN, else disabled)",
+		},
+		&cli.StringFlag{
+			Name:  "gateway-listen",
+			Usage: "Serves the API in REST/JSON on 'http(s)://IP:PORT/v1' at `IP:PORT` (default: content of SAFESCALED_GATEWAY_LISTEN, else disabled)",
+		},
+		&cli.StringFlag{
+			Name:  "webhooks",
+			Usage: "Delivers the events of the resources to the webhooks listed in `FILE` (default: content of SAFESCALED_WEBHOOKS, else disabled)",
+		},
+		&cli.StringFlag{
+			Name:  "otlp-endpoint",
+			Usage: "Enables tracing, sending the spans to the OpenTelemetry collector at `URL` with OTLP over HTTP (default: content of SAFESCALED_OTLP_ENDPOINT, else of OTEL_EXPORTER_OTLP_ENDPOINT, else disabled)",
+		},
+		&cli.DurationFlag{
+			Name:  "autoscaler-period",
+			Usage: "Enables the cluster autoscaler, evaluating the autoscaling policies of the clusters every `DURATION` (default: disabled)",
+		},
 	}
 
 	app.Before = func(c *cli.Context) error {
@@ -243,9 +437,25 @@ func main() {
 			}
 			app2.Debug = true
 		}
+
+		// Masks the values of the secrets in logs
+		logrus.AddHook(secret.MaskingHook{})
+
+		// Sets the secret store used to resolve the references to secrets in tenants file and feature templates
+		store, xerr := secret.NewStoreFromEnv()
+		if xerr != nil {
+			return xerr
+		}
+		secret.SetDefaultStore(store)
 		return nil
 	}
 
+	app.Commands = []*cli.Command{
+		secretCommand,
+		tlsCommand,
+		openapiCommand,
+	}
+
 	app.Action = func(c *cli.Context) error {
 		work(c)
 		return nil
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/metrics"
+)
+
+// startMetricsServer serves the metrics on '/metrics' of the address given by parameter or environment, if any
+func startMetricsServer(c *cli.Context) fail.Error {
+	listen := c.String("metrics-listen")
+	if listen == "" {
+		listen = os.Getenv("SAFESCALED_METRICS_LISTEN")
+	}
+	if listen == "" {
+		return nil
+	}
+
+	lis, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fail.Wrap(err, "failed to listen on '%s' for metrics", listen)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	go func() {
+		if err := http.Serve(lis, mux); err != nil {
+			logrus.Errorf("Failed to serve metrics: %v", err)
+		}
+	}()
+	logrus.Infof("Serving metrics on 'http://%s/metrics'", lis.Addr().String())
+	return nil
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
+)
+
+// secretCommand manages the content of the secret store configured by the environment (SAFESCALE_SECRET_STORE)
+var secretCommand = &cli.Command{
+	Name:  "secret",
+	Usage: "Manages the secrets of the secret store configured by environment variable " + secret.EnvStore,
+	Subcommands: []*cli.Command{
+		secretSetCommand,
+		secretGetCommand,
+		secretDeleteCommand,
+		secretListCommand,
+	},
+}
+
+var secretSetCommand = &cli.Command{
+	Name:      "set",
+	Usage:     "Stores a secret; if VALUE is omitted, it is read from standard input",
+	ArgsUsage: "PATH [VALUE]",
+	Action: func(c *cli.Context) error {
+		store, err := secretStore(c, 1, 2)
+		if err != nil {
+			return err
+		}
+		var value string
+		if c.NArg() == 2 {
+			value = c.Args().Get(1)
+		} else {
+			content, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			value = strings.TrimRight(string(content), "\r\n")
+		}
+		return store.Set(c.Args().First(), value)
+	},
+}
+
+var secretGetCommand = &cli.Command{
+	Name:      "get",
+	Usage:     "Displays the value of a secret",
+	ArgsUsage: "PATH",
+	Action: func(c *cli.Context) error {
+		store, err := secretStore(c, 1, 1)
+		if err != nil {
+			return err
+		}
+		value, xerr := store.Get(c.Args().First())
+		if xerr != nil {
+			return xerr
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var secretDeleteCommand = &cli.Command{
+	Name:      "delete",
+	Aliases:   []string{"rm", "remove"},
+	Usage:     "Deletes a secret",
+	ArgsUsage: "PATH",
+	Action: func(c *cli.Context) error {
+		store, err := secretStore(c, 1, 1)
+		if err != nil {
+			return err
+		}
+		return store.Delete(c.Args().First())
+	},
+}
+
+var secretListCommand = &cli.Command{
+	Name:    "list",
+	Aliases: []string{"ls"},
+	Usage:   "Lists the paths of the secrets",
+	Action: func(c *cli.Context) error {
+		store, err := secretStore(c, 0, 0)
+		if err != nil {
+			return err
+		}
+		list, xerr := store.List()
+		if xerr != nil {
+			return xerr
+		}
+		for _, v := range list {
+			fmt.Println(v)
+		}
+		return nil
+	},
+}
+
+// secretStore checks the number of arguments and returns the default secret store
+func secretStore(c *cli.Context, min, max int) (secret.Store, error) {
+	if c.NArg() < min || c.NArg() > max {
+		_ = cli.ShowSubcommandHelp(c)
+		return nil, fail.InvalidRequestError("invalid number of arguments")
+	}
+	store := secret.DefaultStore()
+	if store == nil {
+		return nil, fail.InvalidRequestError("no secret store configured (see environment variable %s)", secret.EnvStore)
+	}
+	return store, nil
+}
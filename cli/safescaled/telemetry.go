@@ -0,0 +1,42 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/telemetry"
+)
+
+// assembleTelemetryExporter returns the exporter of the spans to the OpenTelemetry collector given by parameter or
+// environment, nil if tracing is not enabled
+func assembleTelemetryExporter(c *cli.Context) (*telemetry.OTLPExporter, fail.Error) {
+	endpoint := c.String("otlp-endpoint")
+	if endpoint == "" {
+		endpoint = os.Getenv("SAFESCALED_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		return nil, nil
+	}
+	return telemetry.NewOTLPExporter(endpoint, "safescaled")
+}
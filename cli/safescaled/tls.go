@@ -0,0 +1,148 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/CS-SI/SafeScale/lib/server/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/crypt"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+const (
+	caCertFile = "ca.crt"
+	caKeyFile  = "ca.key"
+)
+
+// assembleTLSConfig returns the TLS configuration of the gRPC endpoint from parameters or environment (nil if TLS is disabled)
+func assembleTLSConfig(c *cli.Context) (*tls.Config, fail.Error) {
+	value := func(flag, env string) string {
+		if c.IsSet(flag) {
+			return c.String(flag)
+		}
+		return os.Getenv(env)
+	}
+	cert := value("tls-cert", "SAFESCALED_TLS_CERT")
+	key := value("tls-key", "SAFESCALED_TLS_KEY")
+	clientCA := value("tls-client-ca", "SAFESCALED_TLS_CLIENT_CA")
+	if cert == "" && key == "" {
+		if clientCA != "" {
+			return nil, fail.InvalidRequestError("client certificate authentication needs TLS: server certificate and private key must be set")
+		}
+		return nil, nil
+	}
+	return utils.ServerTLSConfig(cert, key, clientCA)
+}
+
+// tlsCommand helps to set up TLS between safescaled and its clients
+var tlsCommand = &cli.Command{
+	Name:  "tls",
+	Usage: "Manages the certificates used to secure the connections to safescaled",
+	Subcommands: []*cli.Command{
+		tlsGenerateCommand,
+	},
+}
+
+var tlsGenerateCommand = &cli.Command{
+	Name: "generate",
+	Usage: `Generates in DIRECTORY a certificate authority (ca.crt, ca.key; reused if present), a server certificate
+	(server.crt, server.key) valid for the hosts and a client certificate (<name>.crt, <name>.key) for each client`,
+	ArgsUsage: "DIRECTORY",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "host",
+			Usage: "DNS name or IP address of safescaled in the server certificate (can be repeated)",
+			Value: cli.NewStringSlice("localhost", "127.0.0.1"),
+		},
+		&cli.StringSliceFlag{
+			Name:  "client",
+			Usage: "Common name of a client certificate to generate (can be repeated)",
+		},
+		&cli.DurationFlag{
+			Name:  "validity",
+			Usage: "Validity of the certificates",
+			Value: 365 * 24 * time.Hour,
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			_ = cli.ShowSubcommandHelp(c)
+			return fail.InvalidRequestError("missing mandatory argument DIRECTORY")
+		}
+		dir := c.Args().First()
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+
+		caCert, caKey, xerr := loadOrGenerateCA(dir, c.Duration("validity"))
+		if xerr != nil {
+			return xerr
+		}
+		cert, key, xerr := crypt.GenerateCertificate(caCert, caKey, "safescaled", c.StringSlice("host"), crypt.ServerCertificate, c.Duration("validity"))
+		if xerr != nil {
+			return xerr
+		}
+		if xerr = writeCertificate(dir, "server", cert, key); xerr != nil {
+			return xerr
+		}
+		for _, name := range c.StringSlice("client") {
+			cert, key, xerr = crypt.GenerateCertificate(caCert, caKey, name, nil, crypt.ClientCertificate, c.Duration("validity"))
+			if xerr != nil {
+				return xerr
+			}
+			if xerr = writeCertificate(dir, name, cert, key); xerr != nil {
+				return xerr
+			}
+		}
+		fmt.Printf("Certificates written in '%s'\n", dir)
+		return nil
+	},
+}
+
+// loadOrGenerateCA reads the certificate authority of the directory, generating it if absent
+func loadOrGenerateCA(dir string, validity time.Duration) (string, string, fail.Error) {
+	cert, certErr := ioutil.ReadFile(filepath.Join(dir, caCertFile))
+	key, keyErr := ioutil.ReadFile(filepath.Join(dir, caKeyFile))
+	if certErr == nil && keyErr == nil {
+		return string(cert), string(key), nil
+	}
+
+	caCert, caKey, xerr := crypt.GenerateCertificateAuthority("SafeScale CA", validity)
+	if xerr != nil {
+		return "", "", xerr
+	}
+	return caCert, caKey, writeCertificate(dir, "ca", caCert, caKey)
+}
+
+// writeCertificate writes the certificate <name>.crt and its private key <name>.key in the directory
+func writeCertificate(dir, name, cert, key string) fail.Error {
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".crt"), []byte(cert), 0644); err != nil {
+		return fail.ToError(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".key"), []byte(key), 0600); err != nil {
+		return fail.ToError(err)
+	}
+	return nil
+}
@@ -17,6 +17,7 @@
 package client
 
 import (
+	"crypto/tls"
 	"fmt"
 	"os"
 	"strconv"
@@ -37,6 +38,8 @@ import (
 type Session struct {
 	Bucket        bucket
 	Cluster       cluster
+	Event         event
+	Feature       feature
 	Host          host
 	Image         image
 	JobManager    jobManager
@@ -50,6 +53,8 @@ type Session struct {
 	Volume        volume
 
 	server     string
+	tlsConfig  *tls.Config
+	token      string
 	connection *grpc.ClientConn
 
 	tenantName string
@@ -100,7 +105,10 @@ func New(server string) (_ *Session, xerr fail.Error) {
 		server = defaultServerHost + ":" + defaultServerPort
 	}
 
-	s := &Session{server: server}
+	s := &Session{server: server, token: currentToken(), tenantName: currentTenant()}
+	if s.tlsConfig, xerr = currentTLSOptions().config(); xerr != nil {
+		return nil, fail.Wrap(xerr, "TLS settings are invalid")
+	}
 	s.task, xerr = concurrency.VoidTask()
 	if xerr != nil {
 		return nil, xerr
@@ -108,6 +116,8 @@ func New(server string) (_ *Session, xerr fail.Error) {
 
 	s.Bucket = bucket{session: s}
 	s.Cluster = cluster{session: s}
+	s.Event = event{session: s}
+	s.Feature = feature{session: s}
 	s.Host = host{session: s}
 	s.Image = image{session: s}
 	s.Network = network{session: s}
@@ -152,7 +162,14 @@ func validateServerString(server string) (string, fail.Error) {
 // Connect establishes connection with safescaled
 func (s *Session) Connect() {
 	if s.connection == nil {
-		s.connection = utils.GetConnection(s.server)
+		opts := []grpc.DialOption{
+			grpc.WithChainUnaryInterceptor(traceUnaryInterceptor, s.tenantUnaryInterceptor),
+			grpc.WithChainStreamInterceptor(s.tenantStreamInterceptor),
+		}
+		if s.token != "" {
+			opts = append(opts, grpc.WithPerRPCCredentials(bearerToken(s.token)))
+		}
+		s.connection = utils.GetConnection(s.server, s.tlsConfig, opts...)
 	}
 }
 
@@ -232,6 +232,44 @@ func (c cluster) Shrink(req *protocol.ClusterResizeRequest, duration time.Durati
 	return service.Shrink(ctx, req)
 }
 
+// SetAutoscaling sets the autoscaling policy of a cluster
+func (c cluster) SetAutoscaling(req *protocol.ClusterAutoscalingRequest, duration time.Duration) error {
+	if req == nil {
+		return fail.InvalidParameterError("req", "cannot be nil")
+	}
+
+	c.session.Connect()
+	defer c.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return xerr
+	}
+
+	service := protocol.NewClusterServiceClient(c.session.connection)
+	_, err := service.SetAutoscaling(ctx, req)
+	return err
+}
+
+// Upgrade upgrades the nodes of a cluster
+func (c cluster) Upgrade(req *protocol.ClusterUpgradeRequest, duration time.Duration) error {
+	if req == nil {
+		return fail.InvalidParameterError("req", "cannot be nil")
+	}
+
+	c.session.Connect()
+	defer c.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return xerr
+	}
+
+	service := protocol.NewClusterServiceClient(c.session.connection)
+	_, err := service.Upgrade(ctx, req)
+	return err
+}
+
 // CheckFeature ...
 func (c cluster) CheckFeature(clusterName, featureName string, params map[string]string, settings *protocol.FeatureSettings, duration time.Duration) error {
 	// if c == nil {
@@ -295,6 +333,33 @@ func (c cluster) AddFeature(clusterName, featureName string, params map[string]s
 	return err
 }
 
+// PlanFeatures returns the order of installation of the features on the cluster, with their requirements
+func (c cluster) PlanFeatures(clusterName string, featureNames []string, params map[string]string, settings *protocol.FeatureSettings, duration time.Duration) (*protocol.FeaturePlanResponse, error) {
+	if clusterName == "" {
+		return nil, fail.InvalidParameterError("clusterName", "cannot be empty string")
+	}
+	if len(featureNames) == 0 {
+		return nil, fail.InvalidParameterError("featureNames", "cannot be empty slice")
+	}
+
+	c.session.Connect()
+	defer c.session.Disconnect()
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	req := &protocol.FeaturePlanRequest{
+		Names:      featureNames,
+		TargetType: protocol.FeatureTargetType_FT_CLUSTER,
+		TargetRef:  &protocol.Reference{Name: clusterName},
+		Variables:  params,
+		Settings:   settings,
+	}
+	service := protocol.NewFeatureServiceClient(c.session.connection)
+	return service.Plan(ctx, req)
+}
+
 // RemoveFeature ...
 func (c cluster) RemoveFeature(clusterName, featureName string, params map[string]string, settings *protocol.FeatureSettings, duration time.Duration) error {
 	// if c == nil {
@@ -327,6 +392,86 @@ func (c cluster) RemoveFeature(clusterName, featureName string, params map[strin
 	return err
 }
 
+// DryRunFeature renders the scripts of the addition of the feature on the cluster, without executing them
+func (c cluster) DryRunFeature(clusterName, featureName string, params map[string]string, settings *protocol.FeatureSettings, duration time.Duration) (*protocol.FeatureDryRunResponse, error) {
+	if clusterName == "" {
+		return nil, fail.InvalidParameterError("clusterName", "cannot be empty string")
+	}
+	if featureName == "" {
+		return nil, fail.InvalidParameterError("featureName", "cannot be empty string")
+	}
+
+	c.session.Connect()
+	defer c.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	req := &protocol.FeatureActionRequest{
+		Name:       featureName,
+		TargetType: protocol.FeatureTargetType_FT_CLUSTER,
+		TargetRef:  &protocol.Reference{Name: clusterName},
+		Variables:  params,
+		Settings:   settings,
+	}
+	service := protocol.NewFeatureServiceClient(c.session.connection)
+	return service.DryRun(ctx, req)
+}
+
+// UpgradeFeature upgrades a feature installed on the cluster
+func (c cluster) UpgradeFeature(clusterName, featureName string, params map[string]string, settings *protocol.FeatureSettings, duration time.Duration) error {
+	if clusterName == "" {
+		return fail.InvalidParameterError("clusterName", "cannot be empty string")
+	}
+	if featureName == "" {
+		return fail.InvalidParameterError("featureName", "cannot be empty string")
+	}
+
+	c.session.Connect()
+	defer c.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return xerr
+	}
+
+	req := &protocol.FeatureActionRequest{
+		Name:       featureName,
+		TargetType: protocol.FeatureTargetType_FT_CLUSTER,
+		TargetRef:  &protocol.Reference{Name: clusterName},
+		Variables:  params,
+		Settings:   settings,
+	}
+	service := protocol.NewFeatureServiceClient(c.session.connection)
+	_, err := service.Upgrade(ctx, req)
+	return err
+}
+
+// FeatureHistory returns the last actions on features of the cluster, on feature 'featureName' only if not empty
+func (c cluster) FeatureHistory(clusterName, featureName string, duration time.Duration) (*protocol.FeatureHistoryResponse, error) {
+	if clusterName == "" {
+		return nil, fail.InvalidParameterError("clusterName", "cannot be empty string")
+	}
+
+	c.session.Connect()
+	defer c.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	req := &protocol.FeatureHistoryRequest{
+		Name:       featureName,
+		TargetType: protocol.FeatureTargetType_FT_CLUSTER,
+		TargetRef:  &protocol.Reference{Name: clusterName},
+	}
+	service := protocol.NewFeatureServiceClient(c.session.connection)
+	return service.History(ctx, req)
+}
+
 // ListInstalledFeatures ...
 func (c cluster) ListInstalledFeatures(clusterName string, all bool, duration time.Duration) (*protocol.FeatureListResponse, error) {
 	// if c == nil {
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"io"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// event is the part of the safescale client handling the events of the resources
+type event struct {
+	session *Session
+}
+
+// Watch calls handler with each event of the current tenant selected by the request, until ctx is done, the server
+// ends the stream or handler returns an error
+func (c event) Watch(ctx context.Context, req *protocol.EventWatchRequest, handler func(*protocol.Event) error) error {
+	if ctx == nil {
+		return fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if handler == nil {
+		return fail.InvalidParameterError("handler", "cannot be nil")
+	}
+
+	c.session.Connect()
+	defer c.session.Disconnect()
+	service := protocol.NewEventServiceClient(c.session.connection)
+	callCtx, xerr := utils.GetContext(false)
+	if xerr != nil {
+		return xerr
+	}
+	callCtx, cancel := context.WithCancel(callCtx)
+	defer cancel()
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-callCtx.Done():
+		}
+	}()
+
+	stream, err := service.Watch(callCtx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		e, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := handler(e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"time"
+
+	googleprotobuf "github.com/golang/protobuf/ptypes/empty"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// feature is the part of the safescale client handling features
+type feature struct {
+	// session is not used currently
+	session *Session
+}
+
+// Validate checks the specification of a feature
+// If content is empty, the feature named 'name' known by safescaled is checked; otherwise content is checked as the
+// specification file of the feature 'name'
+func (f feature) Validate(name, content string, timeout time.Duration) (*protocol.FeatureValidateResponse, error) {
+	if name == "" {
+		return nil, fail.InvalidParameterError("name", "cannot be empty string")
+	}
+
+	f.session.Connect()
+	defer f.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	service := protocol.NewFeatureServiceClient(f.session.connection)
+	return service.Validate(ctx, &protocol.FeatureValidateRequest{Name: name, Content: content})
+}
+
+// AddRepository registers a new feature repository in safescaled
+// If repoType is empty, the type of repository is deduced from the URL
+func (f feature) AddRepository(name, repoType, url, ref string, timeout time.Duration) (*protocol.FeatureRepository, error) {
+	if name == "" {
+		return nil, fail.InvalidParameterError("name", "cannot be empty string")
+	}
+	if url == "" {
+		return nil, fail.InvalidParameterError("url", "cannot be empty string")
+	}
+
+	f.session.Connect()
+	defer f.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	service := protocol.NewFeatureServiceClient(f.session.connection)
+	return service.AddRepository(ctx, &protocol.FeatureRepository{Name: name, Type: repoType, Url: url, Ref: ref})
+}
+
+// UpdateRepositories retrieves the content of the feature repository named 'name', or of all of them if name is empty
+func (f feature) UpdateRepositories(name string, timeout time.Duration) (*protocol.FeatureRepositoryListResponse, error) {
+	f.session.Connect()
+	defer f.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	service := protocol.NewFeatureServiceClient(f.session.connection)
+	return service.UpdateRepositories(ctx, &protocol.FeatureRepositoryUpdateRequest{Name: name})
+}
+
+// ListRepositories lists the feature repositories registered in safescaled
+func (f feature) ListRepositories(timeout time.Duration) (*protocol.FeatureRepositoryListResponse, error) {
+	f.session.Connect()
+	defer f.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	service := protocol.NewFeatureServiceClient(f.session.connection)
+	return service.ListRepositories(ctx, &googleprotobuf.Empty{})
+}
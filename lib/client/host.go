@@ -285,6 +285,48 @@ func (h host) AddFeature(hostRef, featureName string, params map[string]string,
 	return err
 }
 
+// DryRunFeature renders the scripts of the addition of the feature on the host, without executing them
+func (h host) DryRunFeature(hostRef, featureName string, params map[string]string, settings *protocol.FeatureSettings, duration time.Duration) (*protocol.FeatureDryRunResponse, error) {
+	h.session.Connect()
+	defer h.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	req := &protocol.FeatureActionRequest{
+		Name:       featureName,
+		TargetType: protocol.FeatureTargetType_FT_HOST,
+		TargetRef:  &protocol.Reference{Name: hostRef},
+		Variables:  params,
+		Settings:   settings,
+	}
+	service := protocol.NewFeatureServiceClient(h.session.connection)
+	return service.DryRun(ctx, req)
+}
+
+// PlanFeatures returns the order of installation of the features on the host, with their requirements
+func (h host) PlanFeatures(hostRef string, featureNames []string, params map[string]string, settings *protocol.FeatureSettings, duration time.Duration) (*protocol.FeaturePlanResponse, error) {
+	h.session.Connect()
+	defer h.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	req := &protocol.FeaturePlanRequest{
+		Names:      featureNames,
+		TargetType: protocol.FeatureTargetType_FT_HOST,
+		TargetRef:  &protocol.Reference{Name: hostRef},
+		Variables:  params,
+		Settings:   settings,
+	}
+	service := protocol.NewFeatureServiceClient(h.session.connection)
+	return service.Plan(ctx, req)
+}
+
 // RemoveFeature ...
 func (h host) RemoveFeature(hostRef, featureName string, params map[string]string, settings *protocol.FeatureSettings, duration time.Duration) error {
 	h.session.Connect()
@@ -307,6 +349,47 @@ func (h host) RemoveFeature(hostRef, featureName string, params map[string]strin
 	return err
 }
 
+// UpgradeFeature upgrades a feature installed on the host
+func (h host) UpgradeFeature(hostRef, featureName string, params map[string]string, settings *protocol.FeatureSettings, duration time.Duration) error {
+	h.session.Connect()
+	defer h.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return xerr
+	}
+
+	req := &protocol.FeatureActionRequest{
+		Name:       featureName,
+		TargetType: protocol.FeatureTargetType_FT_HOST,
+		TargetRef:  &protocol.Reference{Name: hostRef},
+		Variables:  params,
+		Settings:   settings,
+	}
+	service := protocol.NewFeatureServiceClient(h.session.connection)
+	_, err := service.Upgrade(ctx, req)
+	return err
+}
+
+// FeatureHistory returns the last actions on features of the host, on feature 'featureName' only if not empty
+func (h host) FeatureHistory(hostRef, featureName string, duration time.Duration) (*protocol.FeatureHistoryResponse, error) {
+	h.session.Connect()
+	defer h.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	req := &protocol.FeatureHistoryRequest{
+		Name:       featureName,
+		TargetType: protocol.FeatureTargetType_FT_HOST,
+		TargetRef:  &protocol.Reference{Name: hostRef},
+	}
+	service := protocol.NewFeatureServiceClient(h.session.connection)
+	return service.History(ctx, req)
+}
+
 // BindSecurityGroup calls the gRPC server to bind a security group to a host
 func (h host) BindSecurityGroup(hostRef, sgRef string, enable bool, duration time.Duration) error {
 	h.session.Connect()
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/CS-SI/SafeScale/lib/server/utils"
+)
+
+// withTraceID returns the error of a call with the trace id given by the daemon appended to its message, so that the
+// failure can be linked to the trace of the call
+func withTraceID(err error) error {
+	id := utils.TraceIDFromError(err)
+	if id == "" {
+		return err
+	}
+	st := status.Convert(err)
+	return status.Error(st.Code(), st.Message()+" (trace id: "+id+")")
+}
+
+// traceUnaryInterceptor reports the trace id of the failed unary calls
+func traceUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return withTraceID(invoker(ctx, method, req, reply, cc, opts...))
+}
@@ -28,7 +28,6 @@ import (
 
 // tenant is the part of safescale client handling tenants
 type tenant struct {
-	// session is not used currently
 	session *Session
 }
 
@@ -47,7 +46,7 @@ func (t tenant) List(timeout time.Duration) (*protocol.TenantList, error) {
 
 }
 
-// Get ...
+// Get returns the tenant the session works with, as known by the daemon
 func (t tenant) Get(timeout time.Duration) (*protocol.TenantName, error) {
 	t.session.Connect()
 	defer t.session.Disconnect()
@@ -61,7 +60,8 @@ func (t tenant) Get(timeout time.Duration) (*protocol.TenantName, error) {
 	return service.Get(ctx, &googleprotobuf.Empty{})
 }
 
-// Set ...
+// Set makes the session work with the tenant, after checking with the daemon the tenant can be used,
+// and keeps it as the tenant to work with by default
 func (t tenant) Set(name string, timeout time.Duration) error {
 	t.session.Connect()
 	defer t.session.Disconnect()
@@ -72,8 +72,11 @@ func (t tenant) Set(name string, timeout time.Duration) error {
 	}
 
 	service := protocol.NewTenantServiceClient(t.session.connection)
-	_, err := service.Set(ctx, &protocol.TenantName{Name: name})
-	return fail.ToError(err)
+	if _, err := service.Set(ctx, &protocol.TenantName{Name: name}); err != nil {
+		return fail.ToError(err)
+	}
+	t.session.tenantName = name
+	return saveTenantPreference(name)
 }
 
 // Inspect ...
@@ -104,3 +107,89 @@ func (t tenant) Cleanup(name string, timeout time.Duration) error {
 	_, err := service.Cleanup(ctx, &protocol.TenantCleanupRequest{Name: name, Force: false})
 	return err
 }
+
+// SetCredentials stores the credentials of a tenant in the encrypted credentials file of the daemon
+func (t tenant) SetCredentials(name string, credentials map[string]string, timeout time.Duration) error {
+	t.session.Connect()
+	defer t.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return xerr
+	}
+
+	service := protocol.NewTenantServiceClient(t.session.connection)
+	_, err := service.SetCredentials(ctx, &protocol.TenantCredentialsRequest{Name: name, Credentials: credentials})
+	return err
+}
+
+// Validate checks a tenants file against the settings required by the providers
+// If content is empty, the tenants file used by the daemon is validated
+func (t tenant) Validate(content []byte, format string, timeout time.Duration) (*protocol.TenantValidateResponse, error) {
+	t.session.Connect()
+	defer t.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	service := protocol.NewTenantServiceClient(t.session.connection)
+	return service.Validate(ctx, &protocol.TenantValidateRequest{Content: content, Format: format})
+}
+
+// Scan ...
+func (t tenant) Scan(req *protocol.TenantScanRequest, timeout time.Duration) (*protocol.TenantScanResponse, error) {
+	t.session.Connect()
+	defer t.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	service := protocol.NewTenantServiceClient(t.session.connection)
+	return service.Scan(ctx, req)
+}
+
+// ScanStatus ...
+func (t tenant) ScanStatus(name string, timeout time.Duration) (*protocol.TenantScanStatus, error) {
+	t.session.Connect()
+	defer t.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	service := protocol.NewTenantServiceClient(t.session.connection)
+	return service.ScanStatus(ctx, &protocol.TenantName{Name: name})
+}
+
+// Quota returns the use and the limits of the resources of a tenant
+func (t tenant) Quota(name string, timeout time.Duration) (*protocol.TenantQuotaResponse, error) {
+	t.session.Connect()
+	defer t.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	service := protocol.NewTenantServiceClient(t.session.connection)
+	return service.Quota(ctx, &protocol.TenantName{Name: name})
+}
+
+// Audit returns the audit events of a tenant selected by the request
+func (t tenant) Audit(req *protocol.TenantAuditRequest, timeout time.Duration) (*protocol.TenantAuditResponse, error) {
+	t.session.Connect()
+	defer t.session.Disconnect()
+
+	ctx, xerr := utils.GetContext(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	service := protocol.NewTenantServiceClient(t.session.connection)
+	return service.Audit(ctx, req)
+}
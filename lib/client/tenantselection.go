@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/CS-SI/SafeScale/lib/server/utils"
+	libutils "github.com/CS-SI/SafeScale/lib/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// EnvTenant is the name of the environment variable containing the tenant to work with
+const EnvTenant = "SAFESCALE_TENANT"
+
+// tenantPreferenceFile is the file where the tenant selected with 'tenant set' is kept
+const tenantPreferenceFile = "$HOME/.safescale/tenant"
+
+var (
+	tenantName     *string
+	tenantNameLock sync.RWMutex
+)
+
+// SetTenant sets the tenant the sessions created afterwards work with, replacing the one of the environment and the
+// one selected with 'tenant set'
+func SetTenant(name string) {
+	tenantNameLock.Lock()
+	defer tenantNameLock.Unlock()
+	tenantName = &name
+}
+
+// currentTenant returns the tenant set by SetTenant, or else the one of the environment, or else the one selected with
+// 'tenant set'; empty string lets the daemon choose the tenant if it has only one
+func currentTenant() string {
+	tenantNameLock.RLock()
+	defer tenantNameLock.RUnlock()
+	if tenantName != nil {
+		return *tenantName
+	}
+	if name := os.Getenv(EnvTenant); name != "" {
+		return name
+	}
+	content, err := ioutil.ReadFile(libutils.AbsPathify(tenantPreferenceFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// saveTenantPreference keeps the tenant to work with for the next sessions
+func saveTenantPreference(name string) fail.Error {
+	path := libutils.AbsPathify(tenantPreferenceFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fail.Wrap(err, "failed to create folder of file '%s'", path)
+	}
+	if err := ioutil.WriteFile(path, []byte(name+"\n"), 0600); err != nil {
+		return fail.Wrap(err, "failed to write file '%s'", path)
+	}
+	return nil
+}
+
+// withTenant returns a copy of ctx whose metadata designates the tenant of the session
+func (s *Session) withTenant(ctx context.Context) context.Context {
+	if s.tenantName == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, utils.TenantMetadataKey, s.tenantName)
+}
+
+// tenantUnaryInterceptor sends the tenant of the session with each unary call
+func (s *Session) tenantUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(s.withTenant(ctx), method, req, reply, cc, opts...)
+}
+
+// tenantStreamInterceptor sends the tenant of the session with each streaming call
+func (s *Session) tenantStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(s.withTenant(ctx), desc, cc, method, opts...)
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"crypto/tls"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/CS-SI/SafeScale/lib/server/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+const (
+	// EnvTLS is the name of the environment variable enabling TLS with the certificate authorities of the system
+	EnvTLS = "SAFESCALE_TLS"
+	// EnvTLSCA is the name of the environment variable containing the path of the certificate authorities used to verify safescaled
+	EnvTLSCA = "SAFESCALE_TLS_CA"
+	// EnvTLSCert is the name of the environment variable containing the path of the client certificate (mutual TLS)
+	EnvTLSCert = "SAFESCALE_TLS_CERT"
+	// EnvTLSKey is the name of the environment variable containing the path of the private key of the client certificate
+	EnvTLSKey = "SAFESCALE_TLS_KEY"
+	// EnvTLSServerName is the name of the environment variable containing the name expected in the certificate of safescaled
+	EnvTLSServerName = "SAFESCALE_TLS_SERVER_NAME"
+)
+
+// TLSOptions contains the settings of the TLS connection to safescaled
+type TLSOptions struct {
+	Enabled    bool   // if false, the connection is not encrypted
+	CAFile     string // certificate authorities used to verify the certificate of safescaled (system ones if empty)
+	CertFile   string // client certificate, used if safescaled requires mutual TLS
+	KeyFile    string // private key of the client certificate
+	ServerName string // name expected in the certificate of safescaled, if different from the host of the server
+}
+
+var (
+	tlsOptions     *TLSOptions
+	tlsOptionsLock sync.RWMutex
+)
+
+// TLSOptionsFromEnv returns the TLS settings defined by the environment variables
+// TLS is enabled if SAFESCALE_TLS is true or if one of the other variables is set
+func TLSOptionsFromEnv() TLSOptions {
+	opts := TLSOptions{
+		CAFile:     os.Getenv(EnvTLSCA),
+		CertFile:   os.Getenv(EnvTLSCert),
+		KeyFile:    os.Getenv(EnvTLSKey),
+		ServerName: os.Getenv(EnvTLSServerName),
+	}
+	enabled, _ := strconv.ParseBool(os.Getenv(EnvTLS))
+	opts.Enabled = enabled || opts.CAFile != "" || opts.CertFile != "" || opts.KeyFile != "" || opts.ServerName != ""
+	return opts
+}
+
+// SetTLSOptions sets the TLS settings used by the sessions created afterwards, replacing the ones of the environment
+func SetTLSOptions(opts TLSOptions) {
+	tlsOptionsLock.Lock()
+	defer tlsOptionsLock.Unlock()
+	tlsOptions = &opts
+}
+
+// currentTLSOptions returns the TLS settings set by SetTLSOptions, or else the ones of the environment
+func currentTLSOptions() TLSOptions {
+	tlsOptionsLock.RLock()
+	defer tlsOptionsLock.RUnlock()
+	if tlsOptions != nil {
+		return *tlsOptions
+	}
+	return TLSOptionsFromEnv()
+}
+
+// config returns the TLS configuration corresponding to the options (nil if TLS is disabled)
+func (o TLSOptions) config() (*tls.Config, fail.Error) {
+	if !o.Enabled {
+		return nil, nil
+	}
+	return utils.ClientTLSConfig(o.CAFile, o.CertFile, o.KeyFile, o.ServerName)
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+// EnvToken is the name of the environment variable containing the bearer token (static token or JWT) sent to safescaled
+const EnvToken = "SAFESCALE_TOKEN"
+
+var (
+	token     *string
+	tokenLock sync.RWMutex
+)
+
+// SetToken sets the bearer token sent by the sessions created afterwards, replacing the one of the environment
+func SetToken(value string) {
+	tokenLock.Lock()
+	defer tokenLock.Unlock()
+	token = &value
+}
+
+// currentToken returns the token set by SetToken, or else the one of the environment
+func currentToken() string {
+	tokenLock.RLock()
+	defer tokenLock.RUnlock()
+	if token != nil {
+		return *token
+	}
+	return os.Getenv(EnvToken)
+}
+
+// bearerToken implements credentials.PerRPCCredentials, sending the token in the metadata of each call
+type bearerToken string
+
+// GetRequestMetadata returns the metadata carrying the token
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity tells the token can be sent on a connection without TLS (safescaled listening on localhost)
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
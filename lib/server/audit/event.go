@@ -0,0 +1,198 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/CS-SI/SafeScale/lib/server/auth"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
+)
+
+const (
+	// Success is the result of an operation that succeeded
+	Success = "success"
+	// Failure is the result of an operation that failed
+	Failure = "failure"
+)
+
+// Event records a call to a mutating RPC
+type Event struct {
+	Time           time.Time       `json:"time"`
+	Caller         string          `json:"caller,omitempty"`
+	Authentication string          `json:"authentication,omitempty"`
+	Address        string          `json:"address,omitempty"`
+	Tenant         string          `json:"tenant,omitempty"`
+	Operation      string          `json:"operation"`
+	Resource       string          `json:"resource,omitempty"`
+	Parameters     json.RawMessage `json:"parameters,omitempty"`
+	Result         string          `json:"result"`
+	Error          string          `json:"error,omitempty"`
+	DurationMs     int64           `json:"duration_ms"`
+}
+
+// unaudited contains the mutating RPCs that do not change anything on the daemon nor on the tenants
+var unaudited = map[string]bool{
+	"TenantService/Set": true, // the tenant to work with is a preference of the client
+}
+
+// Operation returns the operation of a gRPC full method name ('/<package>.<service>/<method>'), in the form '<service>/<method>'
+func Operation(fullMethod string) string {
+	operation := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.Index(operation, "/"); i >= 0 {
+		if j := strings.LastIndex(operation[:i], "."); j >= 0 {
+			operation = operation[j+1:]
+		}
+	}
+	return operation
+}
+
+// Audited tells if the calls to the RPC are recorded
+func Audited(fullMethod string) bool {
+	return !auth.ReadOnly(fullMethod) && !unaudited[Operation(fullMethod)]
+}
+
+// NewEvent returns the event recording the call of the RPC with the request, made by the caller carried by ctx
+func NewEvent(ctx context.Context, fullMethod, tenant string, req interface{}) *Event {
+	e := &Event{
+		Time:       time.Now().UTC(),
+		Tenant:     tenant,
+		Operation:  Operation(fullMethod),
+		Resource:   strings.Join(resourcesOf(req), ","),
+		Parameters: parametersOf(req),
+	}
+	if identity := auth.FromContext(ctx); identity != nil {
+		e.Caller = identity.Name
+		e.Authentication = identity.Method
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		e.Address = p.Addr.String()
+	}
+	return e
+}
+
+// Done sets the result of the operation
+func (e *Event) Done(err error) {
+	e.DurationMs = time.Since(e.Time).Milliseconds()
+	if err == nil {
+		e.Result = Success
+		return
+	}
+	e.Result = Failure
+	e.Error = secret.Mask(status.Convert(err).Message())
+}
+
+// Concerns tells if one of the resources of the event matches the pattern
+func (e *Event) Concerns(pattern string) bool {
+	for _, r := range strings.Split(e.Resource, ",") {
+		if r == pattern {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, r); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resourcesOf returns the ids (or else the names) of the resources designated by the request and by the messages it
+// contains directly
+func resourcesOf(req interface{}) []string {
+	if ref := referenceOf(req); ref != "" {
+		return []string{ref}
+	}
+
+	var refs []string
+	rv := reflect.ValueOf(req)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return refs
+	}
+	rv = rv.Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		if f := rv.Field(i); f.Kind() == reflect.Ptr && !f.IsNil() && f.CanInterface() {
+			if ref := referenceOf(f.Interface()); ref != "" {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// referenceOf returns the reference the handlers resolve (see srvutils.GetReference): the id if set, the name otherwise
+func referenceOf(v interface{}) string {
+	if identified, ok := v.(interface{ GetId() string }); ok && strings.TrimSpace(identified.GetId()) != "" {
+		return identified.GetId()
+	}
+	if named, ok := v.(interface{ GetName() string }); ok && strings.TrimSpace(named.GetName()) != "" {
+		return named.GetName()
+	}
+	return ""
+}
+
+// sensitiveKey matches the names of the parameters whose values are masked
+var sensitiveKey = regexp.MustCompile(`(?i)pass|secret|token|credential|private|key`)
+
+// parametersOf returns the request in JSON, the values of the sensitive parameters and the known secrets being masked
+func parametersOf(req interface{}) json.RawMessage {
+	if req == nil {
+		return nil
+	}
+	content, err := json.Marshal(req)
+	if err != nil {
+		return nil
+	}
+	var params interface{}
+	if err = json.Unmarshal(content, &params); err != nil {
+		return nil
+	}
+	if content, err = json.Marshal(mask(params)); err != nil {
+		return nil
+	}
+	return content
+}
+
+func mask(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			if sensitiveKey.MatchString(k) {
+				value[k] = secret.Masked
+			} else {
+				value[k] = mask(item)
+			}
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = mask(item)
+		}
+		return value
+	case string:
+		return secret.Mask(value)
+	default:
+		return value
+	}
+}
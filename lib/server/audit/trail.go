@@ -0,0 +1,256 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	"github.com/CS-SI/SafeScale/lib/server/auth"
+	"github.com/CS-SI/SafeScale/lib/server/iaas"
+	"github.com/CS-SI/SafeScale/lib/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// DefaultPath is the file where the audit events are recorded by default
+const DefaultPath = "$HOME/.safescale/safescaled-audit.jsonl"
+
+// objectStorageFolder is the folder of the metadata bucket of a tenant where the audit events of the tenant are copied
+const objectStorageFolder = "audit"
+
+// ServiceFunc returns the service of a tenant
+type ServiceFunc func(tenant string) (iaas.Service, fail.Error)
+
+// Trail records the audit events in an append-only file, one event in JSON per line, and optionally copies them
+// in the object storage of the tenants
+type Trail struct {
+	path      string
+	file      *os.File
+	lock      sync.Mutex
+	tenantOf  auth.TenantFunc
+	serviceOf ServiceFunc
+	copies    sync.WaitGroup
+}
+
+var (
+	defaultTrail     *Trail
+	defaultTrailLock sync.RWMutex
+)
+
+// SetDefaultTrail sets the trail used by the daemon
+func SetDefaultTrail(trail *Trail) {
+	defaultTrailLock.Lock()
+	defer defaultTrailLock.Unlock()
+	defaultTrail = trail
+}
+
+// DefaultTrail returns the trail used by the daemon, nil if none is set
+func DefaultTrail() *Trail {
+	defaultTrailLock.RLock()
+	defer defaultTrailLock.RUnlock()
+	return defaultTrail
+}
+
+// NewTrail opens the file recording the audit events, creating it if needed
+// tenantOf gives the tenant each call applies to
+func NewTrail(path string, tenantOf auth.TenantFunc) (*Trail, fail.Error) {
+	if path == "" {
+		return nil, fail.InvalidParameterError("path", "cannot be empty string")
+	}
+	if tenantOf == nil {
+		return nil, fail.InvalidParameterError("tenantOf", "cannot be nil")
+	}
+
+	path = utils.AbsPathify(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fail.Wrap(err, "failed to create folder of audit file '%s'", path)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fail.Wrap(err, "failed to open audit file '%s'", path)
+	}
+	return &Trail{path: path, file: file, tenantOf: tenantOf}, nil
+}
+
+// Path returns the path of the file recording the events
+func (t *Trail) Path() string {
+	return t.path
+}
+
+// CopyToObjectStorage makes the trail copy each event in the metadata bucket of its tenant, using the service given
+// by serviceOf
+func (t *Trail) CopyToObjectStorage(serviceOf ServiceFunc) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.serviceOf = serviceOf
+}
+
+// Record appends the event to the file and, if enabled, copies it in the object storage of its tenant
+func (t *Trail) Record(e *Event) fail.Error {
+	if t == nil {
+		return fail.InvalidInstanceError()
+	}
+	if e == nil {
+		return fail.InvalidParameterError("e", "cannot be nil")
+	}
+
+	content, err := json.Marshal(e)
+	if err != nil {
+		return fail.ToError(err)
+	}
+
+	t.lock.Lock()
+	_, err = t.file.Write(append(content, '\n'))
+	serviceOf := t.serviceOf
+	t.lock.Unlock()
+	if err != nil {
+		return fail.Wrap(err, "failed to write audit file '%s'", t.path)
+	}
+
+	if serviceOf != nil && e.Tenant != "" {
+		t.copies.Add(1)
+		go func() {
+			defer t.copies.Done()
+			if xerr := copyToObjectStorage(serviceOf, e, content); xerr != nil {
+				logrus.Errorf("Failed to copy audit event in object storage of tenant '%s': %v", e.Tenant, xerr)
+			}
+		}()
+	}
+	return nil
+}
+
+// copyToObjectStorage writes the event in an object of its own, the objects being not appendable
+func copyToObjectStorage(serviceOf ServiceFunc, e *Event, content []byte) fail.Error {
+	svc, xerr := serviceOf(e.Tenant)
+	if xerr != nil {
+		return xerr
+	}
+	bucket := svc.GetMetadataBucket().Name
+	if bucket == "" {
+		return fail.NotAvailableError("tenant has no metadata bucket")
+	}
+	name := objectStorageFolder + "/" + e.Time.Format("2006/01/02/150405.000000000") + "-" + strings.Replace(e.Operation, "/", "-", -1) + ".json"
+	_, xerr = svc.WriteObject(bucket, name, bytes.NewReader(content), int64(len(content)), nil)
+	return xerr
+}
+
+// Close waits for the copies in progress and closes the file
+func (t *Trail) Close() fail.Error {
+	if t == nil {
+		return fail.InvalidInstanceError()
+	}
+	t.copies.Wait()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if err := t.file.Close(); err != nil {
+		return fail.Wrap(err, "failed to close audit file '%s'", t.path)
+	}
+	return nil
+}
+
+// Query selects audit events
+type Query struct {
+	Tenant   string    // tenant of the events (all tenants if empty)
+	Since    time.Time // start of the time range (no start if zero)
+	Until    time.Time // end of the time range (no end if zero)
+	Resource string    // pattern of the resources concerned by the events (all if empty)
+	Limit    int       // maximum number of events, the most recent ones (no limit if 0)
+}
+
+func (q Query) matches(e *Event) bool {
+	if q.Tenant != "" && e.Tenant != q.Tenant {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && e.Time.After(q.Until) {
+		return false
+	}
+	return q.Resource == "" || e.Concerns(q.Resource)
+}
+
+// Search returns the recorded events selected by the query, in chronological order
+func (t *Trail) Search(q Query) ([]*Event, fail.Error) {
+	if t == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+
+	file, err := os.Open(t.path)
+	if err != nil {
+		return nil, fail.Wrap(err, "failed to open audit file '%s'", t.path)
+	}
+	defer func() { _ = file.Close() }()
+
+	var events []*Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		e := &Event{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			// The last line may be being written
+			logrus.Debugf("Ignoring invalid line in audit file '%s': %v", t.path, err)
+			continue
+		}
+		if !q.matches(e) {
+			continue
+		}
+		events = append(events, e)
+		if q.Limit > 0 && len(events) > q.Limit {
+			events = events[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fail.Wrap(err, "failed to read audit file '%s'", t.path)
+	}
+	return events, nil
+}
+
+// UnaryServerInterceptor returns the interceptor recording the calls to the mutating unary RPCs
+func (t *Trail) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !Audited(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		tenant := t.tenantOf(ctx)
+		if named, ok := req.(interface{ GetName() string }); ok && strings.HasPrefix(Operation(info.FullMethod), "TenantService/") {
+			// The requests of the tenant service designate the tenant itself, the current one if the name is empty
+			if name := named.GetName(); name != "" {
+				tenant = name
+			}
+		}
+		e := NewEvent(ctx, info.FullMethod, tenant, req)
+		resp, err := handler(ctx, req)
+		e.Done(err)
+		if xerr := t.Record(e); xerr != nil {
+			logrus.Errorf("Failed to record audit event of '%s': %v", e.Operation, xerr)
+		}
+		return resp, err
+	}
+}
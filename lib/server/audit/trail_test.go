@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/auth"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+func TestAudited(t *testing.T) {
+	assert.True(t, Audited("/HostService/Create"))
+	assert.True(t, Audited("/protocol.SecurityGroupService/AddRule"))
+	assert.True(t, Audited("/FeatureService/Remove"))
+	assert.False(t, Audited("/HostService/List"))
+	assert.False(t, Audited("/ClusterService/InspectNode"))
+	assert.False(t, Audited("/TenantService/Set"))
+	assert.False(t, Audited("/TenantService/Audit"))
+}
+
+func TestResourcesOf(t *testing.T) {
+	assert.Equal(t, []string{"dev-host"}, resourcesOf(&protocol.Reference{Name: "dev-host"}))
+	// the handlers use the id when both are set
+	assert.Equal(t, []string{"id-1"}, resourcesOf(&protocol.Reference{Name: "dev-host", Id: "id-1"}))
+	assert.Equal(t, []string{"dev-host"}, resourcesOf(&protocol.Reference{Name: "dev-host", Id: " "}))
+}
+
+func TestTrail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	trail, xerr := NewTrail(filepath.Join(dir, "audit.jsonl"), func(context.Context) string { return "ovh-dev" })
+	require.Nil(t, xerr)
+	interceptor := trail.UnaryServerInterceptor()
+	ctx := auth.NewContext(context.Background(), &auth.Identity{Name: "alice", Method: "token"})
+	call := func(method string, req interface{}, result error) {
+		_, _ = interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, interface{}) (interface{}, error) {
+			return nil, result
+		})
+	}
+
+	call("/HostService/Create", &protocol.HostDefinition{Name: "dev-host"}, nil)
+	call("/HostService/List", &protocol.HostListRequest{}, nil)
+	call("/HostService/Delete", &protocol.Reference{Name: "prod-host"}, fail.NotFoundError("host 'prod-host' not found"))
+	call("/TenantService/SetCredentials", &protocol.TenantCredentialsRequest{Name: "aws-prod", Credentials: map[string]string{"SecretKey": "s3cr3t"}}, nil)
+	call("/TenantService/Scan", &protocol.TenantScanRequest{}, nil)
+	require.Nil(t, trail.Close())
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "audit.jsonl"))
+	require.Nil(t, err)
+	assert.Equal(t, 4, strings.Count(string(content), "\n"))
+	assert.NotContains(t, string(content), "s3cr3t")
+
+	events, xerr := trail.Search(Query{Tenant: "ovh-dev"})
+	require.Nil(t, xerr)
+	require.Len(t, events, 3)
+	assert.Equal(t, "HostService/Create", events[0].Operation)
+	assert.Equal(t, "alice", events[0].Caller)
+	assert.Equal(t, "dev-host", events[0].Resource)
+	assert.Equal(t, Success, events[0].Result)
+	assert.Equal(t, Failure, events[1].Result)
+	assert.Contains(t, events[1].Error, "not found")
+	// The requests of the tenant service designating no tenant concern the current one
+	assert.Equal(t, "TenantService/Scan", events[2].Operation)
+
+	events, xerr = trail.Search(Query{Tenant: "ovh-dev", Resource: "dev-*"})
+	require.Nil(t, xerr)
+	assert.Len(t, events, 1)
+	events, xerr = trail.Search(Query{Tenant: "ovh-dev", Limit: 1})
+	require.Nil(t, xerr)
+	require.Len(t, events, 1)
+	assert.Equal(t, "TenantService/Scan", events[0].Operation)
+	events, xerr = trail.Search(Query{Since: time.Now().Add(time.Hour)})
+	require.Nil(t, xerr)
+	assert.Len(t, events, 0)
+
+	// The requests of the tenant service concern the tenant they designate
+	events, xerr = trail.Search(Query{Tenant: "aws-prod"})
+	require.Nil(t, xerr)
+	require.Len(t, events, 1)
+	assert.Contains(t, string(events[0].Parameters), `"credentials":"********"`)
+}
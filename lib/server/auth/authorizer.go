@@ -0,0 +1,364 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// TenantFunc returns the name of the tenant a call applies to when the request does not designate one
+type TenantFunc func(ctx context.Context) string
+
+// LabelsFunc returns the labels of the resource of a tenant designated by a call to a service ('<service>' being the
+// name of the gRPC service without package, 'ClusterService' for example)
+type LabelsFunc func(ctx context.Context, tenant, service, resource string) (map[string]string, fail.Error)
+
+// Authorizer authenticates the callers of the RPCs and checks their rights against the policy
+type Authorizer struct {
+	policy   *Policy
+	keys     map[string]crypto.PublicKey
+	tenantOf TenantFunc
+	labelsOf LabelsFunc
+}
+
+// NewAuthorizer creates an Authorizer applying the policy
+// labelsOf can be nil if no resource has labels, the rules restricted to labels covering no call in this case
+func NewAuthorizer(policy *Policy, tenantOf TenantFunc, labelsOf LabelsFunc) (*Authorizer, fail.Error) {
+	if policy == nil {
+		return nil, fail.InvalidParameterError("policy", "cannot be nil")
+	}
+	if tenantOf == nil {
+		return nil, fail.InvalidParameterError("tenantOf", "cannot be nil")
+	}
+
+	a := &Authorizer{policy: policy, tenantOf: tenantOf, labelsOf: labelsOf}
+	if o := policy.Authentication.OIDC; o != nil {
+		if o.Issuer == "" || o.Audience == "" {
+			return nil, fail.InvalidParameterError("policy", "must define the issuer and the audience of the OIDC tokens")
+		}
+		keys, xerr := loadJWKS(policy.Authentication.OIDC.JWKS)
+		if xerr != nil {
+			return nil, xerr
+		}
+		a.keys = keys
+	}
+	return a, nil
+}
+
+// adminMethods contains the RPCs reserved to admins, in the form '<service>/<method>'
+var adminMethods = map[string]bool{
+	"TenantService/Audit":               true,
+	"TenantService/Cleanup":             true,
+	"TenantService/Scan":                true,
+	"TenantService/SetCredentials":      true,
+	"FeatureService/AddRepository":      true,
+	"FeatureService/UpdateRepositories": true,
+}
+
+// operatorMethods contains the read-only RPCs reserved to operators, as they run commands on the hosts
+var operatorMethods = map[string]bool{
+	"FeatureService/Check":  true,
+	"FeatureService/DryRun": true,
+}
+
+// viewerPrefixes contains the prefixes of the names of the read-only RPCs
+var viewerPrefixes = []string{"Audit", "List", "Inspect", "Get", "Status", "State", "Check", "Plan", "History", "Validate", "Bonds", "DryRun", "Find", "ScanStatus", "Quota", "ServerReflectionInfo", "Watch"}
+
+// splitMethod returns the service (without package) and the method of a gRPC full method name ('/<package>.<service>/<method>')
+func splitMethod(fullMethod string) (string, string) {
+	parts := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	if len(parts) != 2 {
+		return "", fullMethod
+	}
+	service := parts[0]
+	if i := strings.LastIndex(service, "."); i >= 0 {
+		service = service[i+1:]
+	}
+	return service, parts[1]
+}
+
+// ReadOnly tells if the RPC only reads data, without modifying anything
+func ReadOnly(fullMethod string) bool {
+	_, method := splitMethod(fullMethod)
+	for _, p := range viewerPrefixes {
+		if strings.HasPrefix(method, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiredRole returns the role needed to call the RPC
+func RequiredRole(fullMethod string) Role {
+	service, method := splitMethod(fullMethod)
+	if adminMethods[service+"/"+method] {
+		return Admin
+	}
+	if operatorMethods[service+"/"+method] {
+		return Operator
+	}
+	if ReadOnly(fullMethod) {
+		return Viewer
+	}
+	if service == "" {
+		return Admin
+	}
+	return Operator
+}
+
+// Authenticate identifies the caller from its client certificate or from the bearer token of the metadata of the call
+func (a *Authorizer) Authenticate(ctx context.Context) (*Identity, fail.Error) {
+	if token := bearerToken(ctx); token != "" {
+		for _, t := range a.policy.Authentication.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(t.Token)) == 1 {
+				return &Identity{Name: t.Name, Groups: t.Groups, Method: "token"}, nil
+			}
+		}
+		if o := a.policy.Authentication.OIDC; o != nil && strings.Count(token, ".") == 2 {
+			claims, xerr := verifyJWT(token, a.keys, o.Issuer, o.Audience, time.Now())
+			if xerr != nil {
+				return nil, xerr
+			}
+			name, _ := claims[o.UsernameClaim].(string)
+			if name == "" {
+				return nil, fail.NotAuthenticatedError("token has no claim '%s'", o.UsernameClaim)
+			}
+			identity := &Identity{Name: name, Method: "oidc"}
+			if groups, ok := claims[o.GroupsClaim].([]interface{}); ok {
+				for _, g := range groups {
+					if str, ok := g.(string); ok {
+						identity.Groups = append(identity.Groups, str)
+					}
+				}
+			}
+			return identity, nil
+		}
+		return nil, fail.NotAuthenticatedError("invalid token")
+	}
+
+	if a.policy.Authentication.Certificates {
+		if p, ok := peer.FromContext(ctx); ok {
+			if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 && len(info.State.VerifiedChains[0]) > 0 {
+				if cn := info.State.VerifiedChains[0][0].Subject.CommonName; cn != "" {
+					return &Identity{Name: cn, Method: "certificate"}, nil
+				}
+			}
+		}
+	}
+	return nil, fail.NotAuthenticatedError("authentication required")
+}
+
+// bearerToken returns the token of the 'authorization' metadata of the call
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, v := range md.Get("authorization") {
+		if len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+			return strings.TrimSpace(v[7:])
+		}
+	}
+	return ""
+}
+
+// Authorize checks the identity has the role needed to call the RPC on the tenant and the resource
+func (a *Authorizer) Authorize(ctx context.Context, identity *Identity, fullMethod, tenant, resource string) fail.Error {
+	service, method := splitMethod(fullMethod)
+	required := RequiredRole(fullMethod)
+	if a.allowed(ctx, identity, required, service, tenant, resource) {
+		return nil
+	}
+
+	msg := "'" + identity.Name + "' is not allowed to call '" + method + "' (role '" + required.String() + "' needed"
+	if tenant != "" {
+		msg += " on tenant '" + tenant + "'"
+	}
+	if resource != "" {
+		msg += " for resource '" + resource + "'"
+	}
+	return fail.ForbiddenError(msg + ")")
+}
+
+// allowed tells if a rule gives the identity at least the role on the tenant and the resource of the service
+func (a *Authorizer) allowed(ctx context.Context, identity *Identity, role Role, service, tenant, resource string) bool {
+	// the labels of the resource are read only if a rule needs them, and once
+	var labels map[string]string
+	labelsOf := func() map[string]string {
+		if labels == nil {
+			labels = map[string]string{}
+			if a.labelsOf != nil && resource != "" {
+				found, xerr := a.labelsOf(ctx, tenant, service, resource)
+				if xerr != nil {
+					logrus.Warnf("failed to read labels of '%s', considered without labels: %v", resource, xerr)
+				} else if found != nil {
+					labels = found
+				}
+			}
+		}
+		return labels
+	}
+
+	for _, r := range a.policy.Authorization {
+		if r.role >= role && r.appliesTo(identity) && r.covers(tenant, resource, labelsOf) {
+			return true
+		}
+	}
+	return false
+}
+
+// check authenticates the caller of the RPC and authorizes the call, returning a context carrying the identity of the caller
+// redact is true if the caller is not an operator of the target of the call, the secrets having to be removed from the response
+func (a *Authorizer) check(ctx context.Context, fullMethod string, req interface{}) (_ context.Context, redact bool, _ fail.Error) {
+	identity, xerr := a.Authenticate(ctx)
+	if xerr != nil {
+		logrus.Warnf("Refused unauthenticated call to '%s': %v", fullMethod, xerr)
+		return ctx, false, xerr
+	}
+
+	tenant, resource := a.target(ctx, fullMethod, req)
+	if xerr = a.Authorize(ctx, identity, fullMethod, tenant, resource); xerr != nil {
+		logrus.Warnf("Refused call: %v", xerr)
+		return ctx, false, xerr
+	}
+	if RequiredRole(fullMethod) < Operator {
+		service, _ := splitMethod(fullMethod)
+		redact = !a.allowed(ctx, identity, Operator, service, tenant, resource)
+	}
+	return NewContext(ctx, identity), redact, nil
+}
+
+// target returns the tenant and the resource concerned by the request
+func (a *Authorizer) target(ctx context.Context, fullMethod string, req interface{}) (tenant string, resource string) {
+	service, _ := splitMethod(fullMethod)
+	named, _ := req.(interface{ GetName() string })
+	if service == "TenantService" {
+		// The requests of the tenant service designate the tenant itself, the current one if the name is empty; the
+		// others concern no tenant in particular
+		if named != nil {
+			if tenant = named.GetName(); tenant == "" {
+				tenant = a.tenantOf(ctx)
+			}
+			return tenant, ""
+		}
+		return "", ""
+	}
+
+	// the resource is the one the handlers use (see srvutils.GetReference): the id if set, the name otherwise
+	tenant = a.tenantOf(ctx)
+	if identified, ok := req.(interface{ GetId() string }); ok && strings.TrimSpace(identified.GetId()) != "" {
+		return tenant, identified.GetId()
+	}
+	if named != nil && strings.TrimSpace(named.GetName()) != "" {
+		resource = named.GetName()
+	}
+	return tenant, resource
+}
+
+// UnaryServerInterceptor returns the interceptor authenticating and authorizing the unary RPCs
+func (a *Authorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		defer fail.OnExitConvertToGRPCStatus(&err)
+
+		ctx, redact, xerr := a.check(ctx, info.FullMethod, req)
+		if xerr != nil {
+			return nil, xerr
+		}
+		resp, err := handler(ctx, req)
+		if redact {
+			redactSecrets(resp)
+		}
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns the interceptor authenticating and authorizing the streaming RPCs
+func (a *Authorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer fail.OnExitConvertToGRPCStatus(&err)
+
+		ctx, _, xerr := a.check(ss.Context(), info.FullMethod, nil)
+		if xerr != nil {
+			return xerr
+		}
+		return handler(srv, &identifiedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// identifiedStream is a grpc.ServerStream whose context carries the identity of the caller
+type identifiedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context carrying the identity of the caller
+func (s *identifiedStream) Context() context.Context {
+	return s.ctx
+}
+
+// redactSecrets removes the private keys and the passwords of the hosts and clusters of a response
+func redactSecrets(resp interface{}) {
+	switch r := resp.(type) {
+	case *protocol.Host:
+		if r != nil {
+			r.PrivateKey, r.Password = "", ""
+		}
+	case *protocol.HostList:
+		if r != nil {
+			for _, h := range r.Hosts {
+				redactSecrets(h)
+			}
+		}
+	case *protocol.ClusterNodeListResponse:
+		if r != nil {
+			for _, h := range r.Nodes {
+				redactSecrets(h)
+			}
+		}
+	case *protocol.ClusterResponse:
+		if r != nil {
+			if r.Identity != nil {
+				r.Identity.AdminPassword, r.Identity.PrivateKey = "", ""
+			}
+			for _, h := range r.Masters {
+				redactSecrets(h)
+			}
+			for _, h := range r.Nodes {
+				redactSecrets(h)
+			}
+		}
+	case *protocol.ClusterListResponse:
+		if r != nil {
+			for _, c := range r.Clusters {
+				redactSecrets(c)
+			}
+		}
+	}
+}
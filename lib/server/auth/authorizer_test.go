@@ -0,0 +1,360 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+const testPolicy = `
+authentication:
+  certificates: true
+  tokens:
+    - name: ci
+      token: ci-token
+    - name: bob
+      token: bob-token
+      groups: [ops]
+    - name: dave
+      token: dave-token
+  oidc:
+    issuer: https://sso.example.com
+    audience: safescale
+    jwks: jwks.json
+    usernameClaim: preferred_username
+authorization:
+  - subjects: [ci]
+    role: viewer
+  - subjects: ["group:ops"]
+    role: operator
+    tenants: [ovh-*]
+  - subjects: [alice]
+    role: operator
+    tenants: [ovh-dev]
+    resources: [dev-*]
+  - subjects: [dave]
+    role: operator
+    tenants: [ovh-*]
+    labels: ["env=dev*"]
+  - subjects: ["group:admins"]
+    role: admin
+`
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// pad returns the big-endian bytes of n on size bytes
+func pad(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	return append(make([]byte, size-len(b)), b...)
+}
+
+func signJWT(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.Nil(t, err)
+	payload, err := json.Marshal(claims)
+	require.Nil(t, err)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.Nil(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.Nil(t, err)
+		signature = append(pad(r, 32), pad(s, 32)...)
+	}
+	return signed + "." + b64(signature)
+}
+
+func newTestAuthorizer(t *testing.T, tenant string) (*Authorizer, *rsa.PrivateKey, *ecdsa.PrivateKey) {
+	dir, err := ioutil.TempDir("", "auth")
+	require.Nil(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	jwks := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": b64(pad(ecKey.X, 32)), "y": b64(pad(ecKey.Y, 32))},
+	}}
+	content, err := json.Marshal(jwks)
+	require.Nil(t, err)
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "jwks.json"), content, 0600))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "policy.yaml"), []byte(testPolicy), 0600))
+
+	policy, xerr := LoadPolicy(filepath.Join(dir, "policy.yaml"))
+	require.Nil(t, xerr)
+	labelsOf := func(_ context.Context, _, service, resource string) (map[string]string, fail.Error) {
+		if service != "ClusterService" {
+			return nil, nil
+		}
+		switch resource {
+		case "cluster-dev":
+			return map[string]string{"env": "development"}, nil
+		case "cluster-prod":
+			return map[string]string{"env": "production"}, nil
+		default:
+			return nil, fail.NotFoundError("failed to find cluster '%s'", resource)
+		}
+	}
+	a, xerr := NewAuthorizer(policy, func(context.Context) string { return tenant }, labelsOf)
+	require.Nil(t, xerr)
+	return a, rsaKey, ecKey
+}
+
+func TestRequiredRole(t *testing.T) {
+	assert.Equal(t, Viewer, RequiredRole("/HostService/List"))
+	assert.Equal(t, Viewer, RequiredRole("/protocol.ClusterService/InspectNode"))
+	assert.Equal(t, Operator, RequiredRole("/ClusterService/Delete"))
+	assert.Equal(t, Operator, RequiredRole("/TenantService/Set"))
+	assert.Equal(t, Admin, RequiredRole("/TenantService/SetCredentials"))
+	// the checks of features run scripts on the hosts
+	assert.Equal(t, Operator, RequiredRole("/protocol.FeatureService/DryRun"))
+	assert.Equal(t, Operator, RequiredRole("/protocol.FeatureService/Check"))
+	assert.True(t, ReadOnly("/protocol.FeatureService/DryRun"))
+	assert.Equal(t, Admin, RequiredRole("malformed"))
+}
+
+func TestAuthorize(t *testing.T) {
+	a, rsaKey, ecKey := newTestAuthorizer(t, "ovh-dev")
+
+	authenticate := func(token string) (*Identity, error) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+		identity, xerr := a.Authenticate(ctx)
+		if xerr != nil {
+			return nil, xerr
+		}
+		return identity, nil
+	}
+
+	ci, err := authenticate("ci-token")
+	require.Nil(t, err)
+	assert.Equal(t, "ci", ci.Name)
+	assert.Nil(t, a.Authorize(context.Background(), ci, "/HostService/List", "ovh-dev", ""))
+	assert.NotNil(t, a.Authorize(context.Background(), ci, "/HostService/Delete", "ovh-dev", "h1"))
+
+	bob, err := authenticate("bob-token")
+	require.Nil(t, err)
+	assert.Nil(t, a.Authorize(context.Background(), bob, "/HostService/Delete", "ovh-prod", "h1"))
+	assert.NotNil(t, a.Authorize(context.Background(), bob, "/HostService/Delete", "aws-prod", "h1"))
+	assert.NotNil(t, a.Authorize(context.Background(), bob, "/TenantService/Cleanup", "ovh-prod", ""))
+
+	_, err = authenticate("wrong-token")
+	assert.NotNil(t, err)
+	_, xerr := a.Authenticate(context.Background())
+	assert.NotNil(t, xerr)
+
+	now := time.Now().Unix()
+	alice, err := authenticate(signJWT(t, "RS256", "rsa1", rsaKey, map[string]interface{}{
+		"preferred_username": "alice", "iss": "https://sso.example.com", "aud": []string{"safescale"}, "exp": now + 300,
+	}))
+	require.Nil(t, err)
+	assert.Equal(t, "alice", alice.Name)
+	assert.Equal(t, "oidc", alice.Method)
+	assert.Nil(t, a.Authorize(context.Background(), alice, "/HostService/Delete", "ovh-dev", "dev-host"))
+	assert.NotNil(t, a.Authorize(context.Background(), alice, "/HostService/Delete", "ovh-dev", "prod-host"))
+	assert.NotNil(t, a.Authorize(context.Background(), alice, "/HostService/List", "ovh-dev", ""))
+
+	admin, err := authenticate(signJWT(t, "ES256", "ec1", ecKey, map[string]interface{}{
+		"preferred_username": "carol", "groups": []string{"admins"}, "iss": "https://sso.example.com", "aud": "safescale", "exp": now + 300,
+	}))
+	require.Nil(t, err)
+	assert.Nil(t, a.Authorize(context.Background(), admin, "/TenantService/SetCredentials", "aws-prod", ""))
+
+	// expired, wrong audience, no issuer, wrong key
+	_, err = authenticate(signJWT(t, "RS256", "rsa1", rsaKey, map[string]interface{}{
+		"preferred_username": "alice", "iss": "https://sso.example.com", "aud": "safescale", "exp": now - 3600,
+	}))
+	assert.NotNil(t, err)
+	_, err = authenticate(signJWT(t, "RS256", "rsa1", rsaKey, map[string]interface{}{
+		"preferred_username": "alice", "iss": "https://sso.example.com", "aud": "other", "exp": now + 300,
+	}))
+	assert.NotNil(t, err)
+	_, err = authenticate(signJWT(t, "RS256", "rsa1", rsaKey, map[string]interface{}{
+		"preferred_username": "alice", "aud": "safescale", "exp": now + 300,
+	}))
+	assert.NotNil(t, err)
+	_, err = authenticate(signJWT(t, "ES256", "rsa1", ecKey, map[string]interface{}{
+		"preferred_username": "alice", "iss": "https://sso.example.com", "aud": "safescale", "exp": now + 300,
+	}))
+	assert.NotNil(t, err)
+}
+
+func TestAuthorizeLabels(t *testing.T) {
+	a, _, _ := newTestAuthorizer(t, "ovh-dev")
+	dave := &Identity{Name: "dave", Method: "token"}
+
+	assert.Nil(t, a.Authorize(context.Background(), dave, "/ClusterService/Delete", "ovh-dev", "cluster-dev"))
+	assert.NotNil(t, a.Authorize(context.Background(), dave, "/ClusterService/Delete", "ovh-dev", "cluster-prod"))
+	assert.NotNil(t, a.Authorize(context.Background(), dave, "/ClusterService/Delete", "aws-dev", "cluster-dev"))
+	// resources without labels or unknown are not covered by rules restricted to labels
+	assert.NotNil(t, a.Authorize(context.Background(), dave, "/HostService/Delete", "ovh-dev", "cluster-dev"))
+	assert.NotNil(t, a.Authorize(context.Background(), dave, "/ClusterService/Delete", "ovh-dev", "unknown"))
+	assert.NotNil(t, a.Authorize(context.Background(), dave, "/ClusterService/List", "ovh-dev", ""))
+
+	policy := &Policy{Authorization: []Rule{{Subjects: []string{"dave"}, Role: "viewer", Labels: []string{"env"}}}}
+	assert.NotNil(t, policy.prepare(""))
+}
+
+func TestPolicyOIDC(t *testing.T) {
+	// the issuer and the audience are mandatory
+	policy := &Policy{Authentication: Authentication{OIDC: &OIDC{JWKS: "jwks.json", Audience: "safescale"}}}
+	assert.NotNil(t, policy.prepare(""))
+	policy = &Policy{Authentication: Authentication{OIDC: &OIDC{JWKS: "jwks.json", Issuer: "https://sso.example.com"}}}
+	assert.NotNil(t, policy.prepare(""))
+	_, xerr := NewAuthorizer(policy, func(context.Context) string { return "" }, nil)
+	assert.NotNil(t, xerr)
+}
+
+// namedRequest is a request designating a resource by name and/or by id
+type namedRequest struct {
+	name string
+	id   string
+}
+
+func (r namedRequest) GetName() string {
+	return r.name
+}
+
+func (r namedRequest) GetId() string {
+	return r.id
+}
+
+func TestCheckReference(t *testing.T) {
+	a, _, _ := newTestAuthorizer(t, "ovh-dev")
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer alice-token"))
+	a.policy.Authentication.Tokens = append(a.policy.Authentication.Tokens, StaticToken{Name: "alice", Token: "alice-token"})
+
+	_, _, xerr := a.check(ctx, "/protocol.HostService/Delete", namedRequest{name: "dev-host"})
+	assert.Nil(t, xerr)
+	// the handlers use the id when both are set, so the call is authorized on the id
+	_, _, xerr = a.check(ctx, "/protocol.HostService/Delete", namedRequest{name: "dev-host", id: "prod-host"})
+	assert.NotNil(t, xerr)
+	_, _, xerr = a.check(ctx, "/protocol.HostService/Delete", namedRequest{name: " ", id: "dev-host"})
+	assert.Nil(t, xerr)
+}
+
+func TestRedactSecrets(t *testing.T) {
+	a, _, _ := newTestAuthorizer(t, "ovh-dev")
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	}
+
+	// ci is only viewer, bob is operator on the tenant
+	_, redact, xerr := a.check(withToken("ci-token"), "/protocol.HostService/Inspect", namedRequest{name: "h1"})
+	require.Nil(t, xerr)
+	assert.True(t, redact)
+	_, redact, xerr = a.check(withToken("bob-token"), "/protocol.HostService/Inspect", namedRequest{name: "h1"})
+	require.Nil(t, xerr)
+	assert.False(t, redact)
+
+	host := &protocol.Host{Name: "h1", PrivateKey: "key", Password: "password"}
+	cluster := &protocol.ClusterResponse{
+		Identity: &protocol.ClusterIdentity{Name: "c1", AdminPassword: "password", PrivateKey: "key"},
+		Masters:  []*protocol.Host{{Name: "m1", PrivateKey: "key"}},
+		Nodes:    []*protocol.Host{{Name: "n1", Password: "password"}},
+	}
+	redactSecrets(&protocol.HostList{Hosts: []*protocol.Host{host}})
+	redactSecrets(&protocol.ClusterListResponse{Clusters: []*protocol.ClusterResponse{cluster}})
+	assert.Equal(t, &protocol.Host{Name: "h1"}, host)
+	assert.Equal(t, &protocol.ClusterIdentity{Name: "c1"}, cluster.Identity)
+	assert.Empty(t, cluster.Masters[0].PrivateKey)
+	assert.Empty(t, cluster.Nodes[0].Password)
+	redactSecrets((*protocol.Host)(nil))
+}
+
+func TestCheckTenantService(t *testing.T) {
+	// the current tenant of bob is not one of the tenants he has rights on
+	a, _, _ := newTestAuthorizer(t, "aws-prod")
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer bob-token"))
+
+	_, _, xerr := a.check(ctx, "/protocol.TenantService/Quota", namedRequest{name: "ovh-prod"})
+	assert.Nil(t, xerr)
+	_, _, xerr = a.check(ctx, "/protocol.TenantService/Quota", namedRequest{name: "aws-prod"})
+	assert.NotNil(t, xerr)
+	// an empty name designates the current tenant, not any tenant
+	_, _, xerr = a.check(ctx, "/protocol.TenantService/Quota", namedRequest{})
+	require.NotNil(t, xerr)
+	assert.Contains(t, xerr.Error(), "aws-prod")
+}
+
+func TestInterceptor(t *testing.T) {
+	a, _, _ := newTestAuthorizer(t, "ovh-dev")
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := grpc.NewServer(grpc.UnaryInterceptor(a.UnaryServerInterceptor()))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.Nil(t, err)
+	defer func() { _ = conn.Close() }()
+	client := healthpb.NewHealthClient(conn)
+
+	call := func(token string) codes.Code {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		return status.Code(err)
+	}
+	assert.Equal(t, codes.OK, call("ci-token"))
+	assert.Equal(t, codes.Unauthenticated, call(""))
+	assert.Equal(t, codes.Unauthenticated, call("wrong-token"))
+
+	// alice has only rights on resources, not on calls without resource
+	a.policy.Authentication.Tokens = append(a.policy.Authentication.Tokens, StaticToken{Name: "alice", Token: "alice-token"})
+	assert.Equal(t, codes.PermissionDenied, call("alice-token"))
+}
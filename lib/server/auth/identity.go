@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+)
+
+// Identity describes an authenticated caller
+type Identity struct {
+	Name   string   // name of the caller (common name of its certificate, name of its token or claim of its JWT)
+	Groups []string // groups of the caller
+	Method string   // how the caller has been authenticated ('certificate', 'token' or 'oidc')
+}
+
+// InGroup tells if the caller belongs to the group
+func (i *Identity) InGroup(group string) bool {
+	if i == nil {
+		return false
+	}
+	for _, g := range i.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of ctx carrying the identity
+func NewContext(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the identity carried by ctx, or nil if the caller has not been authenticated
+func FromContext(ctx context.Context) *Identity {
+	if ctx == nil {
+		return nil
+	}
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
@@ -0,0 +1,245 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/CS-SI/SafeScale/lib/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// clockSkew is the tolerance applied to the expiration and the start of validity of the tokens
+const clockSkew = time.Minute
+
+// jsonWebKey is a public key of a JSON Web Key Set (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadJWKS reads the public keys of a JSON Web Key Set file, indexed by key id
+func loadJWKS(path string) (map[string]crypto.PublicKey, fail.Error) {
+	content, err := ioutil.ReadFile(utils.AbsPathify(path))
+	if err != nil {
+		return nil, fail.Wrap(err, "failed to read JWKS file '%s'", path)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err = json.Unmarshal(content, &set); err != nil {
+		return nil, fail.SyntaxError("invalid content of JWKS file '%s': %s", path, err.Error())
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, xerr := k.publicKey()
+		if xerr != nil {
+			return nil, fail.Wrap(xerr, "invalid key '%s' in JWKS file '%s'", k.Kid, path)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fail.SyntaxError("no signing key found in JWKS file '%s'", path)
+	}
+	return keys, nil
+}
+
+// publicKey returns the RSA or EC public key described by the JWK
+func (k jsonWebKey) publicKey() (crypto.PublicKey, fail.Error) {
+	switch k.Kty {
+	case "RSA":
+		n, xerr := decodeBigInt(k.N)
+		if xerr != nil {
+			return nil, xerr
+		}
+		e, xerr := decodeBigInt(k.E)
+		if xerr != nil {
+			return nil, xerr
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fail.SyntaxError("unsupported curve '%s'", k.Crv)
+		}
+		x, xerr := decodeBigInt(k.X)
+		if xerr != nil {
+			return nil, xerr
+		}
+		y, xerr := decodeBigInt(k.Y)
+		if xerr != nil {
+			return nil, xerr
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fail.SyntaxError("point is not on curve '%s'", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fail.SyntaxError("unsupported key type '%s'", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, fail.Error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fail.SyntaxError("invalid base64url encoded integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// verifyJWT checks the signature and the validity of a JWT, and returns its claims
+// Only asymmetric algorithms (RS256/384/512, ES256/384/512) are accepted, and the issuer and the audience must match
+func verifyJWT(token string, keys map[string]crypto.PublicKey, issuer, audience string, now time.Time) (map[string]interface{}, fail.Error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fail.NotAuthenticatedError("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if xerr := decodeSegment(parts[0], &header); xerr != nil {
+		return nil, xerr
+	}
+	key, ok := keys[header.Kid]
+	if !ok {
+		if header.Kid != "" || len(keys) != 1 {
+			return nil, fail.NotAuthenticatedError("token signed with an unknown key")
+		}
+		for _, k := range keys {
+			key = k
+		}
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fail.NotAuthenticatedError("malformed token signature")
+	}
+	if xerr := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); xerr != nil {
+		return nil, xerr
+	}
+
+	var claims map[string]interface{}
+	if xerr := decodeSegment(parts[1], &claims); xerr != nil {
+		return nil, xerr
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fail.NotAuthenticatedError("token without expiration")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, fail.NotAuthenticatedError("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fail.NotAuthenticatedError("token not valid yet")
+	}
+	if issuer == "" || claims["iss"] != issuer {
+		return nil, fail.NotAuthenticatedError("token issued by an unexpected issuer")
+	}
+	if audience == "" || !hasAudience(claims["aud"], audience) {
+		return nil, fail.NotAuthenticatedError("token issued for another audience")
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, out interface{}) fail.Error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fail.NotAuthenticatedError("malformed token")
+	}
+	if err = json.Unmarshal(content, out); err != nil {
+		return fail.NotAuthenticatedError("malformed token")
+	}
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) fail.Error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fail.NotAuthenticatedError("unsupported token algorithm '%s'", alg)
+	}
+	h := hash.New()
+	_, _ = h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if alg[0] != 'R' || rsa.VerifyPKCS1v15(k, hash, digest, signature) != nil {
+			return fail.NotAuthenticatedError("invalid token signature")
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if alg[0] != 'E' || len(signature) != 2*size {
+			return fail.NotAuthenticatedError("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return fail.NotAuthenticatedError("invalid token signature")
+		}
+	default:
+		return fail.NotAuthenticatedError("unsupported key")
+	}
+	return nil
+}
+
+func hasAudience(claim interface{}, audience string) bool {
+	switch v := claim.(type) {
+	case string:
+		return v == audience
+	case []interface{}:
+		for _, a := range v {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/CS-SI/SafeScale/lib/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
+)
+
+// Role is the level of rights given to a subject
+type Role int
+
+const (
+	// NoRole gives no right
+	NoRole Role = iota
+	// Viewer can only call the read-only RPCs
+	Viewer
+	// Operator can also create, modify and delete resources
+	Operator
+	// Admin can also manage the tenants (cleanup, scan, credentials) and the feature repositories
+	Admin
+)
+
+var roleNames = map[Role]string{
+	NoRole:   "none",
+	Viewer:   "viewer",
+	Operator: "operator",
+	Admin:    "admin",
+}
+
+// String returns the name of the role
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseRole returns the role corresponding to the name
+func ParseRole(name string) (Role, fail.Error) {
+	for k, v := range roleNames {
+		if k != NoRole && strings.EqualFold(v, name) {
+			return k, nil
+		}
+	}
+	return NoRole, fail.SyntaxError("invalid role '%s': must be 'viewer', 'operator' or 'admin'", name)
+}
+
+// Policy contains the content of the policy file
+type Policy struct {
+	Authentication Authentication `mapstructure:"authentication"`
+	Authorization  []Rule         `mapstructure:"authorization"`
+}
+
+// Authentication contains the ways the callers can authenticate
+type Authentication struct {
+	Certificates bool          `mapstructure:"certificates"` // the common name of the client certificate identifies the caller
+	Tokens       []StaticToken `mapstructure:"tokens"`
+	OIDC         *OIDC         `mapstructure:"oidc"`
+}
+
+// StaticToken is a bearer token given to a subject
+type StaticToken struct {
+	Name   string   `mapstructure:"name"`
+	Token  string   `mapstructure:"token"` // can be a reference to a secret ({{secret "<path>"}})
+	Groups []string `mapstructure:"groups"`
+}
+
+// OIDC contains the settings used to validate the JWT issued by an OpenID Connect provider
+type OIDC struct {
+	Issuer        string `mapstructure:"issuer"`
+	Audience      string `mapstructure:"audience"`
+	JWKS          string `mapstructure:"jwks"`          // path of the file containing the public keys of the provider (JSON Web Key Set)
+	UsernameClaim string `mapstructure:"usernameclaim"` // default: 'sub'
+	GroupsClaim   string `mapstructure:"groupsclaim"`   // default: 'groups'
+}
+
+// Rule gives a role to subjects on tenants and, optionally, on some resources only
+type Rule struct {
+	Subjects  []string `mapstructure:"subjects"`  // names of users, 'group:<name>' or '*' for any authenticated caller
+	Role      string   `mapstructure:"role"`      // 'viewer', 'operator' or 'admin'
+	Tenants   []string `mapstructure:"tenants"`   // patterns of names of tenants (default: all)
+	Resources []string `mapstructure:"resources"` // patterns of names of resources (default: all)
+	Labels    []string `mapstructure:"labels"`    // labels the resource must have, in the form '<key>=<pattern of value>' (default: no condition)
+
+	role   Role
+	labels map[string]string // patterns of values indexed by label key
+}
+
+// LoadPolicy reads the policy file (in YAML, TOML or JSON, depending on its extension)
+func LoadPolicy(path string) (*Policy, fail.Error) {
+	if path == "" {
+		return nil, fail.InvalidParameterError("path", "cannot be empty string")
+	}
+
+	v := viper.New()
+	v.SetConfigFile(utils.AbsPathify(path))
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fail.SyntaxError("failed to read policy file '%s': %s", path, err.Error())
+	}
+	policy := &Policy{}
+	if err := v.Unmarshal(policy); err != nil {
+		return nil, fail.SyntaxError("invalid content of policy file '%s': %s", path, err.Error())
+	}
+	if xerr := policy.prepare(filepath.Dir(utils.AbsPathify(path))); xerr != nil {
+		return nil, fail.Wrap(xerr, "invalid content of policy file '%s'", path)
+	}
+	return policy, nil
+}
+
+// prepare validates the policy and resolves the references it contains
+func (p *Policy) prepare(dir string) fail.Error {
+	for i, t := range p.Authentication.Tokens {
+		if t.Name == "" {
+			return fail.SyntaxError("token #%d has no name", i+1)
+		}
+		token, xerr := secret.Resolve(t.Token)
+		if xerr != nil {
+			return fail.Wrap(xerr, "failed to resolve token '%s'", t.Name)
+		}
+		if token == "" {
+			return fail.SyntaxError("token '%s' is empty", t.Name)
+		}
+		secret.Register(token)
+		p.Authentication.Tokens[i].Token = token
+	}
+	if o := p.Authentication.OIDC; o != nil {
+		if o.JWKS == "" {
+			return fail.SyntaxError("missing 'jwks' in 'oidc'")
+		}
+		// without them, any token signed by the provider, for any application, would be accepted
+		if o.Issuer == "" {
+			return fail.SyntaxError("missing 'issuer' in 'oidc'")
+		}
+		if o.Audience == "" {
+			return fail.SyntaxError("missing 'audience' in 'oidc'")
+		}
+		// a relative path is relative to the policy file
+		if !filepath.IsAbs(o.JWKS) && !strings.HasPrefix(o.JWKS, "$") {
+			o.JWKS = filepath.Join(dir, o.JWKS)
+		}
+		if o.UsernameClaim == "" {
+			o.UsernameClaim = "sub"
+		}
+		if o.GroupsClaim == "" {
+			o.GroupsClaim = "groups"
+		}
+	}
+	for i := range p.Authorization {
+		r := &p.Authorization[i]
+		if len(r.Subjects) == 0 {
+			return fail.SyntaxError("authorization rule #%d has no subject", i+1)
+		}
+		role, xerr := ParseRole(r.Role)
+		if xerr != nil {
+			return fail.Wrap(xerr, "invalid authorization rule #%d", i+1)
+		}
+		r.role = role
+		r.labels = make(map[string]string, len(r.Labels))
+		for _, l := range r.Labels {
+			parts := strings.SplitN(l, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return fail.SyntaxError("invalid label '%s' in authorization rule #%d: must be '<key>=<pattern of value>'", l, i+1)
+			}
+			r.labels[parts[0]] = parts[1]
+		}
+		patterns := append(append([]string{}, r.Tenants...), r.Resources...)
+		for _, v := range r.labels {
+			patterns = append(patterns, v)
+		}
+		for _, pattern := range patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fail.SyntaxError("invalid pattern '%s' in authorization rule #%d", pattern, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// appliesTo tells if the rule concerns the identity
+func (r Rule) appliesTo(identity *Identity) bool {
+	for _, s := range r.Subjects {
+		switch {
+		case s == "*":
+			return true
+		case strings.HasPrefix(s, "group:"):
+			if identity.InGroup(strings.TrimPrefix(s, "group:")) {
+				return true
+			}
+		case s == identity.Name:
+			return true
+		}
+	}
+	return false
+}
+
+// covers tells if the rule concerns the tenant and the resource
+// An empty tenant means the call does not concern a particular tenant; an empty resource means the call does not
+// designate a resource, and is covered only by rules not restricted to resources. labelsOf returns the labels of the
+// resource; it is called only if the rule is restricted to labels.
+func (r Rule) covers(tenant, resource string, labelsOf func() map[string]string) bool {
+	if tenant != "" && !matchAny(r.Tenants, tenant) {
+		return false
+	}
+	if len(r.Resources) > 0 && (resource == "" || !matchAny(r.Resources, resource)) {
+		return false
+	}
+	if len(r.labels) > 0 {
+		if resource == "" {
+			return false
+		}
+		labels := labelsOf()
+		for k, pattern := range r.labels {
+			value, ok := labels[k]
+			if !ok {
+				return false
+			}
+			if matched, _ := filepath.Match(pattern, value); !matched {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchAny tells if value matches one of the patterns (an empty list matching everything)
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, value); ok {
+			return true
+		}
+	}
+	return false
+}
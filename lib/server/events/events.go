@@ -0,0 +1,270 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package events publishes the changes of the resources (host created, cluster expanded, feature installed, job
+// finished, ...) on an in-daemon bus, watched by the callers of EventService and delivered to webhooks
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	uuidpkg "github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/auth"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// Event is a change of a resource
+type Event struct {
+	ID           string             `json:"id"`
+	Time         time.Time          `json:"time"`
+	Type         protocol.EventType `json:"-"`
+	Tenant       string             `json:"tenant,omitempty"`
+	ResourceID   string             `json:"resource_id,omitempty"`
+	ResourceName string             `json:"resource_name,omitempty"`
+	State        string             `json:"state,omitempty"`   // new state of the resource, for a change of state
+	Details      map[string]string  `json:"details,omitempty"` // ex: 'nodes' added to a cluster, 'feature' installed on a host
+	Error        string             `json:"error,omitempty"`   // error of a failed job
+}
+
+// TypeName returns the name of the type of event, as used in JSON and in filters (ex: 'host_created')
+func TypeName(t protocol.EventType) string {
+	return strings.ToLower(strings.TrimPrefix(t.String(), "ET_"))
+}
+
+// ParseType returns the type of event designated by its name
+func ParseType(name string) (protocol.EventType, fail.Error) {
+	if v, ok := protocol.EventType_value["ET_"+strings.ToUpper(name)]; ok && v != int32(protocol.EventType_ET_UNKNOWN) {
+		return protocol.EventType(v), nil
+	}
+	return protocol.EventType_ET_UNKNOWN, fail.SyntaxError("invalid event type '%s'", name)
+}
+
+// MarshalJSON encodes the event in JSON, with its type by name
+func (e Event) MarshalJSON() ([]byte, error) {
+	type plain Event
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		plain
+	}{Type: TypeName(e.Type), plain: plain(e)})
+}
+
+// ToProtocol converts the event to its protocol message
+func (e *Event) ToProtocol() *protocol.Event {
+	out := &protocol.Event{
+		Id:           e.ID,
+		Type:         e.Type,
+		Tenant:       e.Tenant,
+		ResourceId:   e.ResourceID,
+		ResourceName: e.ResourceName,
+		State:        e.State,
+		Details:      e.Details,
+		Error:        e.Error,
+	}
+	out.Time, _ = ptypes.TimestampProto(e.Time)
+	return out
+}
+
+// FromProtocol converts the protocol message to an event
+func FromProtocol(in *protocol.Event) *Event {
+	out := &Event{
+		ID:           in.GetId(),
+		Type:         in.GetType(),
+		Tenant:       in.GetTenant(),
+		ResourceID:   in.GetResourceId(),
+		ResourceName: in.GetResourceName(),
+		State:        in.GetState(),
+		Details:      in.GetDetails(),
+		Error:        in.GetError(),
+	}
+	if in.GetTime() != nil {
+		out.Time, _ = ptypes.Timestamp(in.GetTime())
+	}
+	return out
+}
+
+// Filter selects events
+type Filter struct {
+	Types     []protocol.EventType // all types if empty
+	Tenants   []string             // patterns of the names of the tenants (all if empty)
+	Resources []string             // patterns of the names or ids of the resources (all if empty)
+}
+
+// Match tells if the event is selected by the filter
+func (f Filter) Match(e *Event) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == e.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !matchAny(f.Tenants, e.Tenant) {
+		return false
+	}
+	return len(f.Resources) == 0 || (e.ResourceName != "" && matchAny(f.Resources, e.ResourceName)) || (e.ResourceID != "" && matchAny(f.Resources, e.ResourceID))
+}
+
+// matchAny tells if value matches one of the patterns (an empty list matching everything)
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// subscriptionQueueSize is the number of events a subscriber can lag behind before events are dropped
+const subscriptionQueueSize = 256
+
+// Bus dispatches the published events to the subscribers
+type Bus struct {
+	lock          sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+}
+
+// NewBus creates a bus
+func NewBus() *Bus {
+	return &Bus{subscriptions: map[*Subscription]struct{}{}}
+}
+
+// Subscription receives the events of a bus selected by a filter
+type Subscription struct {
+	bus    *Bus
+	filter Filter
+	events chan *Event
+	once   sync.Once
+}
+
+// Subscribe subscribes to the events selected by filter; the subscription must be closed when no longer used
+func (b *Bus) Subscribe(filter Filter) *Subscription {
+	s := &Subscription{bus: b, filter: filter, events: make(chan *Event, subscriptionQueueSize)}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.subscriptions[s] = struct{}{}
+	return s
+}
+
+// Events returns the channel of the events, closed when the subscription is closed
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.lock.Lock()
+		defer s.bus.lock.Unlock()
+		delete(s.bus.subscriptions, s)
+		close(s.events)
+	})
+}
+
+// Publish dispatches the event to the subscribers it is selected by; the publisher is never blocked, the event being
+// dropped for the subscribers lagging too much behind
+func (b *Bus) Publish(e *Event) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for s := range b.subscriptions {
+		if !s.filter.Match(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			logrus.Warnf("Dropping event %s (%s of '%s') for a subscriber lagging behind", e.ID, TypeName(e.Type), e.ResourceName)
+		}
+	}
+}
+
+var defaultBus = NewBus()
+
+// DefaultBus returns the bus of the events of the daemon
+func DefaultBus() *Bus {
+	return defaultBus
+}
+
+var (
+	tenantOf     auth.TenantFunc
+	tenantOfLock sync.RWMutex
+)
+
+// SetTenantFunc sets the function giving the tenant of the events published on behalf of a call
+func SetTenantFunc(f auth.TenantFunc) {
+	tenantOfLock.Lock()
+	defer tenantOfLock.Unlock()
+	tenantOf = f
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a copy of ctx designating the tenant of the events published by the tasks using it, for
+// the operations not made on behalf of a call
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// tenantOfTask returns the tenant of the events published by the task
+func tenantOfTask(task concurrency.Task) string {
+	if task == nil || task.IsNull() {
+		return ""
+	}
+	ctx, xerr := task.GetContext()
+	if xerr != nil || ctx == nil {
+		return ""
+	}
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		return tenant
+	}
+	tenantOfLock.RLock()
+	f := tenantOf
+	tenantOfLock.RUnlock()
+	if f == nil {
+		return ""
+	}
+	return f(ctx)
+}
+
+// Publish publishes the event on the default bus, completing its id, its time and, if not set, its tenant (the one of
+// the call the task runs on behalf of)
+func Publish(task concurrency.Task, e Event) {
+	if id, err := uuidpkg.NewV4(); err == nil {
+		e.ID = id.String()
+	}
+	e.Time = time.Now()
+	if e.Tenant == "" {
+		e.Tenant = tenantOfTask(task)
+	}
+	defaultBus.Publish(&e)
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package events
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+)
+
+func TestTypeNames(t *testing.T) {
+	assert.Equal(t, "host_created", TypeName(protocol.EventType_ET_HOST_CREATED))
+	typ, xerr := ParseType("cluster_expanded")
+	require.Nil(t, xerr)
+	assert.Equal(t, protocol.EventType_ET_CLUSTER_EXPANDED, typ)
+	_, xerr = ParseType("unknown")
+	assert.NotNil(t, xerr)
+	_, xerr = ParseType("host_exploded")
+	assert.NotNil(t, xerr)
+}
+
+func TestFilter(t *testing.T) {
+	e := &Event{Type: protocol.EventType_ET_HOST_CREATED, Tenant: "ovh-dev", ResourceID: "42", ResourceName: "web-1"}
+	assert.True(t, Filter{}.Match(e))
+	assert.True(t, Filter{Types: []protocol.EventType{protocol.EventType_ET_HOST_DELETED, protocol.EventType_ET_HOST_CREATED}}.Match(e))
+	assert.False(t, Filter{Types: []protocol.EventType{protocol.EventType_ET_HOST_DELETED}}.Match(e))
+	assert.True(t, Filter{Tenants: []string{"ovh-*"}}.Match(e))
+	assert.False(t, Filter{Tenants: []string{"aws-*"}}.Match(e))
+	assert.True(t, Filter{Resources: []string{"web-*"}}.Match(e))
+	assert.True(t, Filter{Resources: []string{"42"}}.Match(e))
+	assert.False(t, Filter{Resources: []string{"db-*"}}.Match(e))
+}
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	hosts := bus.Subscribe(Filter{Types: []protocol.EventType{protocol.EventType_ET_HOST_CREATED}})
+	all := bus.Subscribe(Filter{})
+
+	bus.Publish(&Event{ID: "1", Type: protocol.EventType_ET_CLUSTER_CREATED})
+	bus.Publish(&Event{ID: "2", Type: protocol.EventType_ET_HOST_CREATED})
+	assert.Equal(t, "2", (<-hosts.Events()).ID)
+	assert.Equal(t, "1", (<-all.Events()).ID)
+	assert.Equal(t, "2", (<-all.Events()).ID)
+
+	// A subscriber lagging behind does not block the publisher, the events it cannot receive being dropped
+	for i := 0; i < subscriptionQueueSize+10; i++ {
+		bus.Publish(&Event{Type: protocol.EventType_ET_HOST_CREATED})
+	}
+	assert.Len(t, hosts.Events(), subscriptionQueueSize)
+
+	all.Close()
+	all.Close()
+	// The channel of a closed subscription ends once the events it still holds are received
+	n := 0
+	for range all.Events() {
+		n++
+	}
+	assert.Equal(t, subscriptionQueueSize, n)
+	hosts.Close()
+	bus.Publish(&Event{Type: protocol.EventType_ET_HOST_CREATED})
+}
+
+func TestEventJSON(t *testing.T) {
+	e := Event{ID: "1", Time: time.Unix(1600000000, 0).UTC(), Type: protocol.EventType_ET_FEATURE_INSTALLED, ResourceName: "web-1", Details: map[string]string{"feature": "docker"}}
+	content, err := json.Marshal(e)
+	require.Nil(t, err)
+	assert.JSONEq(t, `{"id": "1", "time": "2020-09-13T12:26:40Z", "type": "feature_installed", "resource_name": "web-1", "details": {"feature": "docker"}}`, string(content))
+	assert.Equal(t, &e, FromProtocol(e.ToProtocol()))
+}
+
+func TestDispatcher(t *testing.T) {
+	var calls int32
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first attempt fails, to be retried
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	hook := &Webhook{URL: server.URL, Secret: "s3cr3t", Types: []string{"host_deleted"}}
+	require.Nil(t, hook.prepare())
+	bus := NewBus()
+	d := NewDispatcher([]*Webhook{hook})
+	d.backoff = time.Millisecond
+	d.Start(bus)
+	defer d.Stop()
+
+	bus.Publish(&Event{ID: "1", Type: protocol.EventType_ET_HOST_CREATED, ResourceName: "web-1"})
+	bus.Publish(&Event{ID: "2", Type: protocol.EventType_ET_HOST_DELETED, ResourceName: "web-1"})
+	select {
+	case r := <-received:
+		body := <-bodies
+		assert.Equal(t, "host_deleted", r.Header.Get(EventTypeHeader))
+		assert.Equal(t, "2", r.Header.Get(DeliveryHeader))
+		assert.Equal(t, Sign("s3cr3t", r.Header.Get(TimestampHeader), "2", body), r.Header.Get(SignatureHeader))
+		assert.Nil(t, VerifySignature("s3cr3t", r.Header, body, SignatureTolerance))
+		var e map[string]interface{}
+		require.Nil(t, json.Unmarshal(body, &e))
+		assert.Equal(t, "web-1", e["resource_name"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not delivered")
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"type":"host_deleted"}`)
+	header := func(at time.Time, delivery string) http.Header {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		h := http.Header{}
+		h.Set(TimestampHeader, timestamp)
+		h.Set(DeliveryHeader, delivery)
+		h.Set(SignatureHeader, Sign("s3cr3t", timestamp, delivery, body))
+		return h
+	}
+
+	assert.Nil(t, VerifySignature("s3cr3t", header(time.Now(), "1"), body, SignatureTolerance))
+	assert.NotNil(t, VerifySignature("other", header(time.Now(), "1"), body, SignatureTolerance))
+	assert.NotNil(t, VerifySignature("s3cr3t", header(time.Now(), "1"), []byte(`{"type":"host_created"}`), SignatureTolerance))
+
+	// a replayed delivery is refused once out of the tolerance, and its timestamp or id cannot be changed
+	assert.NotNil(t, VerifySignature("s3cr3t", header(time.Now().Add(-time.Hour), "1"), body, SignatureTolerance))
+	h := header(time.Now().Add(-time.Hour), "1")
+	h.Set(TimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	assert.NotNil(t, VerifySignature("s3cr3t", h, body, SignatureTolerance))
+	h = header(time.Now(), "1")
+	h.Set(DeliveryHeader, "2")
+	assert.NotNil(t, VerifySignature("s3cr3t", h, body, SignatureTolerance))
+	h.Del(TimestampHeader)
+	assert.NotNil(t, VerifySignature("s3cr3t", h, body, SignatureTolerance))
+}
+
+func TestWebhookPrepare(t *testing.T) {
+	assert.NotNil(t, (&Webhook{URL: "ftp://example.com"}).prepare())
+	assert.NotNil(t, (&Webhook{URL: "https://example.com", Types: []string{"host_exploded"}}).prepare())
+	assert.Nil(t, (&Webhook{URL: "https://example.com", Types: []string{"job_failed"}}).prepare())
+}
@@ -0,0 +1,277 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/CS-SI/SafeScale/lib/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/retry"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
+)
+
+// Headers of the deliveries to the webhooks
+const (
+	// SignatureHeader carries 'sha256=<HMAC-SHA256 of '<timestamp>.<delivery>.<body>' with the secret of the webhook,
+	// in hexadecimal>', timestamp and delivery being the values of TimestampHeader and DeliveryHeader
+	SignatureHeader = "X-SafeScale-Signature"
+	// EventTypeHeader carries the name of the type of the event
+	EventTypeHeader = "X-SafeScale-Event"
+	// DeliveryHeader carries the id of the event, the same for all the attempts to deliver it
+	DeliveryHeader = "X-SafeScale-Delivery"
+	// TimestampHeader carries the time of the attempt to deliver the event, in seconds since the Unix epoch
+	TimestampHeader = "X-SafeScale-Timestamp"
+)
+
+// SignatureTolerance is the maximum difference between the time of a delivery and the time it is received that the
+// receivers should accept, to refuse the replays of old deliveries
+const SignatureTolerance = 5 * time.Minute
+
+const (
+	defaultAttempts = 5
+	defaultBackoff  = time.Second
+	deliveryTimeout = 10 * time.Second
+)
+
+// Webhook receives by HTTP POST the events selected by its filter, in JSON
+type Webhook struct {
+	URL       string   `mapstructure:"url"`
+	Secret    string   `mapstructure:"secret"`    // key of the signature of the deliveries; can be a reference to a secret ({{secret "<path>"}})
+	Types     []string `mapstructure:"types"`     // names of the types of the events (ex: 'host_created'; default: all)
+	Tenants   []string `mapstructure:"tenants"`   // patterns of names of tenants (default: all)
+	Resources []string `mapstructure:"resources"` // patterns of names or ids of resources (default: all)
+
+	filter Filter
+}
+
+// prepare validates the webhook and resolves the references it contains
+func (w *Webhook) prepare() fail.Error {
+	if !strings.HasPrefix(w.URL, "http://") && !strings.HasPrefix(w.URL, "https://") {
+		return fail.SyntaxError("invalid URL '%s': must be 'http(s)://...'", w.URL)
+	}
+	key, xerr := secret.Resolve(w.Secret)
+	if xerr != nil {
+		return fail.Wrap(xerr, "failed to resolve the secret of webhook '%s'", w.URL)
+	}
+	if key != "" {
+		secret.Register(key)
+	}
+	w.Secret = key
+	w.filter = Filter{Tenants: w.Tenants, Resources: w.Resources}
+	for _, name := range w.Types {
+		t, xerr := ParseType(name)
+		if xerr != nil {
+			return xerr
+		}
+		w.filter.Types = append(w.filter.Types, t)
+	}
+	return nil
+}
+
+// LoadWebhooks reads the webhooks file (in YAML, TOML or JSON, depending on its extension), listing the webhooks in
+// 'webhooks'
+func LoadWebhooks(path string) ([]*Webhook, fail.Error) {
+	if path == "" {
+		return nil, fail.InvalidParameterError("path", "cannot be empty string")
+	}
+
+	v := viper.New()
+	v.SetConfigFile(utils.AbsPathify(path))
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fail.SyntaxError("failed to read webhooks file '%s': %s", path, err.Error())
+	}
+	content := struct {
+		Webhooks []*Webhook `mapstructure:"webhooks"`
+	}{}
+	if err := v.Unmarshal(&content); err != nil {
+		return nil, fail.SyntaxError("invalid content of webhooks file '%s': %s", path, err.Error())
+	}
+	for i, w := range content.Webhooks {
+		if xerr := w.prepare(); xerr != nil {
+			return nil, fail.Wrap(xerr, "invalid webhook #%d in file '%s'", i+1, path)
+		}
+	}
+	return content.Webhooks, nil
+}
+
+// Sign returns the value of SignatureHeader for the body of the delivery 'delivery' made at 'timestamp' (value of
+// TimestampHeader)
+func Sign(key, timestamp, delivery string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	_, _ = mac.Write([]byte(timestamp + "." + delivery + "."))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks the signature of a delivery received with the headers 'header' and the body 'body', and that
+// it has been made less than 'tolerance' ago (or in the future)
+func VerifySignature(key string, header http.Header, body []byte, tolerance time.Duration) fail.Error {
+	timestamp := header.Get(TimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fail.InvalidRequestError("invalid header %s: '%s'", TimestampHeader, timestamp)
+	}
+	if d := time.Since(time.Unix(seconds, 0)); d > tolerance || d < -tolerance {
+		return fail.InvalidRequestError("delivery made at %s, outside the tolerance of %s", time.Unix(seconds, 0).UTC().Format(time.RFC3339), tolerance)
+	}
+	expected := Sign(key, timestamp, header.Get(DeliveryHeader), body)
+	if !hmac.Equal([]byte(expected), []byte(header.Get(SignatureHeader))) {
+		return fail.InvalidRequestError("invalid signature")
+	}
+	return nil
+}
+
+// Dispatcher delivers the events to the webhooks, in order for each webhook; a delivery failing on a network error,
+// a server error or a throttling is retried with exponential backoff
+type Dispatcher struct {
+	hooks    []*Webhook
+	client   *http.Client
+	attempts uint
+	backoff  time.Duration
+
+	subscriptions []*Subscription
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+}
+
+var (
+	defaultDispatcher     *Dispatcher
+	defaultDispatcherLock sync.RWMutex
+)
+
+// SetDefaultDispatcher sets the dispatcher used by the daemon
+func SetDefaultDispatcher(d *Dispatcher) {
+	defaultDispatcherLock.Lock()
+	defer defaultDispatcherLock.Unlock()
+	defaultDispatcher = d
+}
+
+// DefaultDispatcher returns the dispatcher used by the daemon, nil if none is set
+func DefaultDispatcher() *Dispatcher {
+	defaultDispatcherLock.RLock()
+	defer defaultDispatcherLock.RUnlock()
+	return defaultDispatcher
+}
+
+// NewDispatcher creates a dispatcher to the webhooks
+func NewDispatcher(hooks []*Webhook) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		hooks:    hooks,
+		client:   &http.Client{Timeout: deliveryTimeout},
+		attempts: defaultAttempts,
+		backoff:  defaultBackoff,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start delivers the events published on the bus from now on
+func (d *Dispatcher) Start(bus *Bus) {
+	for _, hook := range d.hooks {
+		s := bus.Subscribe(hook.filter)
+		d.subscriptions = append(d.subscriptions, s)
+		d.wg.Add(1)
+		go func(hook *Webhook) {
+			defer d.wg.Done()
+			for e := range s.Events() {
+				if xerr := d.deliver(hook, e); xerr != nil {
+					logrus.Warnf("Failed to deliver event %s to webhook '%s': %v", e.ID, hook.URL, xerr)
+				}
+			}
+		}(hook)
+	}
+}
+
+// Stop stops the deliveries, abandoning the events not delivered yet
+func (d *Dispatcher) Stop() {
+	for _, s := range d.subscriptions {
+		s.Close()
+	}
+	d.cancel()
+	d.wg.Wait()
+}
+
+// deliver posts the event to the webhook, retrying on transient failures
+func (d *Dispatcher) deliver(hook *Webhook, e *Event) fail.Error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fail.ToError(err)
+	}
+	return retry.Action(
+		func() error {
+			if d.ctx.Err() != nil {
+				return retry.StopRetryError(d.ctx.Err(), "dispatcher stopped")
+			}
+			return d.post(hook, e, body)
+		},
+		retry.Max(d.attempts),
+		retry.Exponential(d.backoff),
+		nil, nil, nil,
+	)
+}
+
+// post makes an attempt to deliver the event; returns a retry.ErrStopRetry if the failure is not transient
+func (d *Dispatcher) post(hook *Webhook, e *Event, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return retry.StopRetryError(err)
+	}
+	req = req.WithContext(d.ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventTypeHeader, TypeName(e.Type))
+	req.Header.Set(DeliveryHeader, e.ID)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(TimestampHeader, timestamp)
+	if hook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(hook.Secret, timestamp, e.ID, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fail.Wrap(err, "failed to post to '%s'", hook.URL)
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	switch {
+	case resp.StatusCode/100 == 2:
+		return nil
+	case resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout:
+		return fail.NewError("webhook '%s' answered %s", hook.URL, resp.Status)
+	default:
+		return retry.StopRetryError(fail.NewError("webhook '%s' answered %s", hook.URL, resp.Status))
+	}
+}
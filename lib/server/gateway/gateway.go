@@ -0,0 +1,447 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package gateway serves the API of the daemon in REST/JSON over HTTP: each route is mapped to a RPC, handled by the
+// listener of its service through the same interceptors as the gRPC calls (authentication, authorization, tenant
+// selection, audit, metrics and tracing)
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	uuidpkg "github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	srvutils "github.com/CS-SI/SafeScale/lib/server/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/telemetry"
+)
+
+const (
+	// OpenAPIPath is the path of the OpenAPI document describing the routes
+	OpenAPIPath = "/v1/openapi.json"
+	// JobsPath is the path of the jobs; the status of a long-running call is at '<JobsPath>/<job id>'
+	JobsPath = "/v1/jobs"
+
+	maxBodySize = 1 << 20
+)
+
+// route is a Route bound to the method of the server handling its RPC
+type route struct {
+	Route
+	segments []string
+	server   interface{}
+	handler  reflect.Value // method of the server
+	request  reflect.Type  // type of the request of the RPC (pointer to struct)
+	response reflect.Type  // type of the response of the RPC
+}
+
+// Gateway is the http.Handler serving the routes
+type Gateway struct {
+	routes      []*route
+	interceptor grpc.UnaryServerInterceptor
+	jobs        *jobs
+}
+
+// New creates a gateway calling the servers of the services (indexed by service name, ex: 'HostService') through
+// interceptor (which may be nil); the routes of the services without server are not served
+func New(servers map[string]interface{}, interceptor grpc.UnaryServerInterceptor) (*Gateway, fail.Error) {
+	g := &Gateway{interceptor: interceptor, jobs: newJobs()}
+	for _, r := range Routes {
+		service, method := splitRPC(r.RPC)
+		server, ok := servers[service]
+		if !ok {
+			continue
+		}
+		handler := reflect.ValueOf(server).MethodByName(method)
+		if !handler.IsValid() {
+			return nil, fail.InconsistentError("server of service '%s' has no method '%s'", service, method)
+		}
+		t := handler.Type()
+		if t.NumIn() != 2 || t.NumOut() != 2 || t.In(1).Kind() != reflect.Ptr || t.In(1).Elem().Kind() != reflect.Struct {
+			return nil, fail.InconsistentError("method '%s' of the server of service '%s' does not handle a unary RPC", method, service)
+		}
+		g.routes = append(g.routes, &route{
+			Route:    r,
+			segments: splitPath(r.Path),
+			server:   server,
+			handler:  handler,
+			request:  t.In(1),
+			response: t.Out(0),
+		})
+	}
+	return g, nil
+}
+
+// splitRPC returns the service and the method of the full name of a RPC
+func splitRPC(rpc string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(rpc, "/"), "/", 2)
+	if len(parts) != 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// variable returns the name of the variable of a path segment, empty string if the segment is literal
+func variable(segment string) string {
+	if len(segment) > 2 && segment[0] == '{' && segment[len(segment)-1] == '}' {
+		return segment[1 : len(segment)-1]
+	}
+	return ""
+}
+
+// match returns the route of the HTTP request and the values of the variables of its path; if no route matches,
+// returns the methods allowed on the path
+func (g *Gateway) match(method string, u *url.URL) (*route, map[string]string, []string) {
+	segments := splitPath(u.EscapedPath())
+	var allowed []string
+	for _, r := range g.routes {
+		if len(r.segments) != len(segments) {
+			continue
+		}
+		vars := map[string]string{}
+		matched := true
+		for i, s := range r.segments {
+			if name := variable(s); name != "" {
+				value, err := url.PathUnescape(segments[i])
+				if err != nil || value == "" {
+					matched = false
+					break
+				}
+				vars[name] = value
+			} else if s != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if r.Method == method {
+			return r, vars, nil
+		}
+		allowed = append(allowed, r.Method)
+	}
+	return nil, nil, allowed
+}
+
+// ServeHTTP handles the HTTP request
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == OpenAPIPath && r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, g.OpenAPI())
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, JobsPath+"/") && r.Method == http.MethodGet {
+		if id := strings.TrimPrefix(r.URL.Path, JobsPath+"/"); id != "" && !strings.Contains(id, "/") {
+			g.serveJob(w, r, id)
+			return
+		}
+	}
+
+	rt, vars, allowed := g.match(r.Method, r.URL)
+	if rt == nil {
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeError(w, status.Errorf(codes.Unimplemented, "method %s not allowed on '%s'", r.Method, r.URL.Path), http.StatusMethodNotAllowed)
+			return
+		}
+		writeError(w, status.Errorf(codes.NotFound, "no route for '%s'", r.URL.Path), 0)
+		return
+	}
+
+	req, xerr := rt.bind(r, vars)
+	if xerr != nil {
+		writeError(w, xerr.ToGRPCStatus(), 0)
+		return
+	}
+	id, err := uuidpkg.NewV4()
+	if err != nil {
+		writeError(w, fail.ToGRPCStatus(fail.Wrap(err, "failed to generate job id")), 0)
+		return
+	}
+
+	if rt.Async {
+		g.startJob(w, r, rt, req, vars[tenantVar], id.String())
+		return
+	}
+	resp, err := g.call(callContext(r.Context(), r, vars[tenantVar], id.String()), rt, req, nil)
+	if err != nil {
+		writeError(w, err, 0)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// callContext returns the context of a call made on behalf of the HTTP request, carrying the metadata and the peer
+// a gRPC call would carry: the job id, the tenant, the credentials of the caller and the parent span
+func callContext(ctx context.Context, r *http.Request, tenant, jobID string) context.Context {
+	md := metadata.Pairs("uuid", jobID)
+	if tenant != "" {
+		md.Set(srvutils.TenantMetadataKey, tenant)
+	}
+	if value := r.Header.Get("Authorization"); value != "" {
+		md.Set("authorization", value)
+	}
+	if value := r.Header.Get(telemetry.TraceparentKey); value != "" {
+		md.Set(telemetry.TraceparentKey, value)
+	}
+	ctx = metadata.NewIncomingContext(ctx, md)
+
+	p := &peer.Peer{Addr: &net.TCPAddr{}}
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		p.Addr = addr
+	}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{State: *r.TLS}
+	}
+	return peer.NewContext(ctx, p)
+}
+
+// call makes the RPC of the route through the interceptor; entered, if not nil, is called with the context given to
+// the handler when the interceptors let the call through
+func (g *Gateway) call(ctx context.Context, rt *route, req interface{}, entered func(context.Context)) (interface{}, error) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if entered != nil {
+			entered(ctx)
+		}
+		out := rt.handler.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(req)})
+		if err, _ := out[1].Interface().(error); err != nil {
+			return nil, err
+		}
+		return out[0].Interface(), nil
+	}
+	if g.interceptor == nil {
+		return handler(ctx, req)
+	}
+	return g.interceptor(ctx, req, &grpc.UnaryServerInfo{Server: rt.server, FullMethod: rt.RPC}, handler)
+}
+
+// bind returns the request of the RPC of the route: the JSON body of the HTTP request, whose fields are overridden by
+// the query parameters, then by the values of the route and the variables of the path
+func (rt *route) bind(r *http.Request, vars map[string]string) (interface{}, fail.Error) {
+	req := reflect.New(rt.request.Elem())
+	if r.Body != nil {
+		content, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize))
+		if err != nil {
+			return nil, fail.InvalidRequestError("failed to read request body: %v", err)
+		}
+		if len(bytes.TrimSpace(content)) > 0 {
+			if err := json.Unmarshal(content, req.Interface()); err != nil {
+				return nil, fail.InvalidRequestError("invalid request body: %v", err)
+			}
+		}
+	}
+	for name, values := range r.URL.Query() {
+		if xerr := setField(req, name, values); xerr != nil {
+			return nil, xerr
+		}
+	}
+	for name, value := range rt.Values {
+		if xerr := setField(req, name, []string{value}); xerr != nil {
+			return nil, xerr
+		}
+	}
+	for name, value := range vars {
+		if name == tenantVar {
+			continue
+		}
+		if xerr := setField(req, name, []string{value}); xerr != nil {
+			return nil, xerr
+		}
+	}
+	return req.Interface(), nil
+}
+
+// jsonName returns the JSON name of a field of a message, empty string if the field is not encoded
+func jsonName(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		return ""
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+	return f.Name
+}
+
+// setField sets the field of the message designated by its dotted JSON name from the string values, allocating the
+// messages containing it
+func setField(target reflect.Value, path string, values []string) fail.Error {
+	for _, name := range strings.Split(path, ".") {
+		for target.Kind() == reflect.Ptr {
+			if target.IsNil() {
+				target.Set(reflect.New(target.Type().Elem()))
+			}
+			target = target.Elem()
+		}
+		if target.Kind() != reflect.Struct {
+			return fail.InvalidRequestError("'%s' does not designate a field of the request", path)
+		}
+		found := false
+		for i := 0; i < target.NumField(); i++ {
+			if jsonName(target.Type().Field(i)) == name {
+				target = target.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fail.InvalidRequestError("'%s' does not designate a field of the request", path)
+		}
+	}
+	if target.Kind() == reflect.Slice && target.Type().Elem().Kind() != reflect.Uint8 {
+		for _, v := range values {
+			item := reflect.New(target.Type().Elem()).Elem()
+			if xerr := setValue(item, v); xerr != nil {
+				return fail.InvalidRequestError("invalid value '%s' for '%s': %v", v, path, xerr)
+			}
+			target.Set(reflect.Append(target, item))
+		}
+		return nil
+	}
+	v := values[len(values)-1]
+	if xerr := setValue(target, v); xerr != nil {
+		return fail.InvalidRequestError("invalid value '%s' for '%s': %v", v, path, xerr)
+	}
+	return nil
+}
+
+// setValue sets a scalar field from its string representation
+func setValue(target reflect.Value, v string) error {
+	if target.Kind() == reflect.Ptr {
+		target.Set(reflect.New(target.Type().Elem()))
+		target = target.Elem()
+	}
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(v)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		target.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(v, 10, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(v, 10, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(v, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetFloat(f)
+	default:
+		return fail.InvalidRequestError("field cannot be set from the path or the query, only from the body")
+	}
+	return nil
+}
+
+// Error is the body of the responses of the failed calls
+type Error struct {
+	Code    string `json:"code"` // gRPC code of the error, ex: 'NotFound'
+	Message string `json:"message"`
+	TraceID string `json:"trace_id,omitempty"` // trace of the call, if tracing is enabled
+}
+
+// newError returns the body describing the error of a call
+func newError(err error) *Error {
+	st := status.Convert(err)
+	return &Error{Code: st.Code().String(), Message: st.Message(), TraceID: srvutils.TraceIDFromError(err)}
+}
+
+// writeError answers the error of a call with httpStatus, or with the HTTP status of its gRPC code if 0
+func writeError(w http.ResponseWriter, err error, httpStatus int) {
+	if httpStatus == 0 {
+		httpStatus = HTTPStatus(status.Code(err))
+	}
+	writeJSON(w, httpStatus, newError(err))
+}
+
+func writeJSON(w http.ResponseWriter, httpStatus int, v interface{}) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		logrus.Errorf("Failed to encode response: %v", err)
+		httpStatus = http.StatusInternalServerError
+		content, _ = json.Marshal(&Error{Code: codes.Internal.String(), Message: "failed to encode response"})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	_, _ = w.Write(content)
+}
+
+// HTTPStatus returns the HTTP status corresponding to the gRPC code
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // client closed request
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/auth"
+	srvutils "github.com/CS-SI/SafeScale/lib/server/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+type fakeHostServer struct {
+	protocol.UnimplementedHostServiceServer
+	created chan *protocol.HostDefinition
+}
+
+func (s *fakeHostServer) Inspect(ctx context.Context, in *protocol.Reference) (*protocol.Host, error) {
+	if in.GetName() != "web" {
+		return nil, fail.NotFoundError("failed to find host '%s'", in.GetName()).ToGRPCStatus()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return &protocol.Host{Name: in.GetName(), Id: md.Get(srvutils.TenantMetadataKey)[0]}, nil
+}
+
+func (s *fakeHostServer) List(ctx context.Context, in *protocol.HostListRequest) (*protocol.HostList, error) {
+	if !in.GetAll() {
+		return &protocol.HostList{}, nil
+	}
+	return &protocol.HostList{Hosts: []*protocol.Host{{Name: "web"}, {Name: "db"}}}, nil
+}
+
+func (s *fakeHostServer) Create(ctx context.Context, in *protocol.HostDefinition) (*protocol.Host, error) {
+	s.created <- in
+	return &protocol.Host{Name: in.GetName()}, nil
+}
+
+// authenticate is an interceptor accepting the calls with a token, whose value is the name of the caller
+func authenticate(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+		return nil, fail.NotAuthenticatedError("missing token").ToGRPCStatus()
+	}
+	return handler(auth.NewContext(ctx, &auth.Identity{Name: strings.TrimPrefix(values[0], "Bearer ")}), req)
+}
+
+func TestGateway(t *testing.T) {
+	hosts := &fakeHostServer{created: make(chan *protocol.HostDefinition, 1)}
+	g, xerr := New(map[string]interface{}{"HostService": hosts}, authenticate)
+	require.Nil(t, xerr)
+	server := httptest.NewServer(g)
+	defer server.Close()
+
+	call := func(method, path, token, body string, out interface{}) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.Nil(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		if out != nil {
+			require.Nil(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp
+	}
+
+	var host protocol.Host
+	resp := call(http.MethodGet, "/v1/tenants/ovh-dev/hosts/web", "alice", "", &host)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "web", host.Name)
+	assert.Equal(t, "ovh-dev", host.Id)
+
+	var list protocol.HostList
+	resp = call(http.MethodGet, "/v1/tenants/ovh-dev/hosts?all=true", "alice", "", &list)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, list.Hosts, 2)
+
+	var e Error
+	resp = call(http.MethodGet, "/v1/tenants/ovh-dev/hosts/db", "alice", "", &e)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "NotFound", e.Code)
+	resp = call(http.MethodGet, "/v1/tenants/ovh-dev/hosts/web", "", "", &e)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = call(http.MethodGet, "/v1/tenants/ovh-dev/hosts?unknown=1", "alice", "", &e)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = call(http.MethodPut, "/v1/tenants/ovh-dev/hosts/web", "alice", "", &e)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	resp = call(http.MethodGet, "/v1/tenants/ovh-dev/volumes", "alice", "", &e)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Long-running call: answered once accepted, its outcome being polled
+	var accepted Accepted
+	resp = call(http.MethodPost, "/v1/tenants/ovh-dev/hosts", "alice", `{"name": "gw", "public": true}`, &accepted)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, JobsPath+"/"+accepted.Job, resp.Header.Get("Location"))
+	created := <-hosts.created
+	assert.Equal(t, "gw", created.Name)
+	assert.True(t, created.Public)
+
+	var job Job
+	require.Eventually(t, func() bool {
+		call(http.MethodGet, accepted.Href, "alice", "", &job)
+		return job.Status != JobRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, JobSucceeded, job.Status)
+	assert.Equal(t, "/HostService/Create", job.RPC)
+	// Only the caller having made the call sees its status
+	resp = call(http.MethodGet, accepted.Href, "bob", "", &e)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	// A call rejected by the interceptors is not started
+	resp = call(http.MethodPost, "/v1/tenants/ovh-dev/hosts", "", `{"name": "gw"}`, &e)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Len(t, hosts.created, 0)
+}
+
+func TestOpenAPI(t *testing.T) {
+	g, xerr := New(map[string]interface{}{"HostService": &fakeHostServer{}}, nil)
+	require.Nil(t, xerr)
+	server := httptest.NewServer(g)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + OpenAPIPath)
+	require.Nil(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	var doc struct {
+		OpenAPI    string                            `json:"openapi"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Paths["/v1/tenants/{tenant}/hosts/{name}"], "get")
+	assert.Contains(t, doc.Paths["/v1/tenants/{tenant}/hosts"], "post")
+	assert.Contains(t, doc.Paths, JobsPath+"/{uuid}")
+	assert.NotContains(t, doc.Paths, "/v1/tenants/{tenant}/volumes")
+	assert.Contains(t, doc.Components.Schemas, "HostDefinition")
+	assert.Contains(t, doc.Components.Schemas, "Error")
+}
+
+var (
+	protoServiceRegexp = regexp.MustCompile(`(?s)\nservice\s+(\w+)\s*\{(.*?)\n\}`)
+	protoRPCRegexp     = regexp.MustCompile(`\brpc\s+(\w+)\s*\(`)
+)
+
+// TestRoutesCoverProto checks that each RPC of safescale.proto has a route, unless deliberately not served
+func TestRoutesCoverProto(t *testing.T) {
+	content, err := ioutil.ReadFile("../../protocol/safescale.proto")
+	require.Nil(t, err)
+
+	rpcs := map[string]bool{}
+	for _, service := range protoServiceRegexp.FindAllStringSubmatch(string(content), -1) {
+		for _, rpc := range protoRPCRegexp.FindAllStringSubmatch(service[2], -1) {
+			rpcs["/"+service[1]+"/"+rpc[1]] = true
+		}
+	}
+	require.NotEmpty(t, rpcs)
+
+	routed := map[string]bool{}
+	for _, r := range Routes {
+		assert.True(t, rpcs[r.RPC], "route %s %s designates the RPC %s, unknown in safescale.proto", r.Method, r.Path, r.RPC)
+		routed[r.RPC] = true
+	}
+	for rpc, reason := range unroutedRPCs {
+		assert.True(t, rpcs[rpc], "RPC %s listed as not served (%s) is unknown in safescale.proto", rpc, reason)
+		assert.False(t, routed[rpc], "RPC %s listed as not served (%s) has a route", rpc, reason)
+	}
+	for rpc := range rpcs {
+		if _, ok := unroutedRPCs[rpc]; !ok {
+			assert.True(t, routed[rpc], "RPC %s of safescale.proto has no route in the gateway", rpc)
+		}
+	}
+}
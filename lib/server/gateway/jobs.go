@@ -0,0 +1,193 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	googleprotobuf "github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/CS-SI/SafeScale/lib/server/auth"
+)
+
+// Status of the long-running calls
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// jobRetention is how long the outcome of a long-running call is kept once ended
+const jobRetention = 24 * time.Hour
+
+// Job is the status of a long-running call made by the gateway, identified by the id of the job of the daemon making
+// it (which can be stopped with the job API)
+type Job struct {
+	ID      string      `json:"id"`
+	RPC     string      `json:"rpc"`
+	Status  string      `json:"status"`
+	Started time.Time   `json:"started"`
+	Ended   *time.Time  `json:"ended,omitempty"`
+	Result  interface{} `json:"result,omitempty"` // response of the RPC, if succeeded
+	Error   *Error      `json:"error,omitempty"`  // error of the RPC, if failed
+
+	owner string // caller having made the call, empty if the callers are not authenticated
+}
+
+// Accepted is the body of the responses to the long-running calls
+type Accepted struct {
+	Job  string `json:"job"`  // id of the job making the call
+	Href string `json:"href"` // URL of the status of the call
+}
+
+// jobs contains the long-running calls in progress or recently ended
+type jobs struct {
+	lock  sync.Mutex
+	items map[string]*Job
+}
+
+func newJobs() *jobs {
+	return &jobs{items: map[string]*Job{}}
+}
+
+// add registers a call, forgetting the calls ended for more than jobRetention
+func (j *jobs) add(job *Job) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	limit := time.Now().Add(-jobRetention)
+	for id, item := range j.items {
+		if item.Ended != nil && item.Ended.Before(limit) {
+			delete(j.items, id)
+		}
+	}
+	j.items[job.ID] = job
+}
+
+func (j *jobs) remove(id string) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	delete(j.items, id)
+}
+
+// get returns a copy of the status of the call
+func (j *jobs) get(id string) (Job, bool) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if item, ok := j.items[id]; ok {
+		return *item, true
+	}
+	return Job{}, false
+}
+
+func (j *jobs) setOwner(id, owner string) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if item, ok := j.items[id]; ok {
+		item.owner = owner
+	}
+}
+
+// finish records the outcome of the call
+func (j *jobs) finish(id string, resp interface{}, err error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	item, ok := j.items[id]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	item.Ended = &now
+	if err != nil {
+		item.Status, item.Error = JobFailed, newError(err)
+	} else {
+		item.Status, item.Result = JobSucceeded, resp
+	}
+}
+
+// startJob makes the long-running call in the background, answering 202 Accepted with the URL of its status once the
+// interceptors let it through; if they reject it, answers the error
+func (g *Gateway) startJob(w http.ResponseWriter, r *http.Request, rt *route, req interface{}, tenant, id string) {
+	g.jobs.add(&Job{ID: id, RPC: rt.RPC, Status: JobRunning, Started: time.Now()})
+
+	// The call outlives the HTTP request, so does its context
+	ctx := callContext(context.Background(), r, tenant, id)
+	entered := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		resp, err := g.call(ctx, rt, req, func(ctx context.Context) {
+			if identity := auth.FromContext(ctx); identity != nil {
+				g.jobs.setOwner(id, identity.Name)
+			}
+			close(entered)
+		})
+		g.jobs.finish(id, resp, err)
+		done <- err
+	}()
+
+	select {
+	case <-entered:
+	case err := <-done:
+		select {
+		case <-entered:
+		default:
+			g.jobs.remove(id)
+			writeError(w, err, 0)
+			return
+		}
+	}
+	w.Header().Set("Location", JobsPath+"/"+id)
+	writeJSON(w, http.StatusAccepted, &Accepted{Job: id, Href: JobsPath + "/" + id})
+}
+
+// serveJob answers the status of a long-running call; the caller must be allowed to list the jobs and, if the callers
+// are authenticated, be the one having made the call
+func (g *Gateway) serveJob(w http.ResponseWriter, r *http.Request, id string) {
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		job, ok := g.jobs.get(id)
+		if ok && job.owner != "" {
+			if identity := auth.FromContext(ctx); identity == nil || identity.Name != job.owner {
+				ok = false
+			}
+		}
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "no job identified by '%s' found", id)
+		}
+		return &job, nil
+	}
+
+	ctx := callContext(r.Context(), r, "", id)
+	var (
+		resp interface{}
+		err  error
+	)
+	if g.interceptor == nil {
+		resp, err = handler(ctx, nil)
+	} else {
+		resp, err = g.interceptor(ctx, &googleprotobuf.Empty{}, &grpc.UnaryServerInfo{FullMethod: "/JobService/List"}, handler)
+	}
+	if err != nil {
+		writeError(w, err, 0)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gateway
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// object is a JSON object of the OpenAPI document
+type object = map[string]interface{}
+
+// schemas builds the schemas of the messages, in the components of the document
+type schemas struct {
+	components object
+}
+
+func refTo(name string) object {
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+// of returns the schema of the JSON encoding of the type
+func (s *schemas) of(t reflect.Type) object {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return object{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return object{"type": "string"}
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return object{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return object{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return object{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return object{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return object{"type": "string", "format": "byte"}
+		}
+		return object{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return object{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := s.components[name]; !ok {
+			properties := object{}
+			s.components[name] = object{"type": "object", "properties": properties}
+			for i := 0; i < t.NumField(); i++ {
+				if field := jsonName(t.Field(i)); field != "" {
+					properties[field] = s.of(t.Field(i).Type)
+				}
+			}
+		}
+		return refTo(name)
+	default:
+		// interface{}: any value
+		return object{}
+	}
+}
+
+// queryFields returns the fields of the request settable from the query, by dotted JSON name: the scalars and lists of
+// scalars of the request and of the messages it contains directly
+func queryFields(t reflect.Type, prefix string, depth int) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := jsonName(f)
+		if name == "" {
+			continue
+		}
+		ft := f.Type
+		switch {
+		case ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct:
+			if depth > 0 {
+				for k, v := range queryFields(ft, prefix+name+".", depth-1) {
+					fields[k] = v
+				}
+			}
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8:
+			if ft.Elem().Kind() != reflect.Ptr && ft.Elem().Kind() != reflect.Struct && ft.Elem().Kind() != reflect.Slice {
+				fields[prefix+name] = ft
+			}
+		case ft.Kind() != reflect.Map && ft.Kind() != reflect.Struct && ft.Kind() != reflect.Interface:
+			fields[prefix+name] = ft
+		}
+	}
+	return fields
+}
+
+// OpenAPI returns the OpenAPI 3 document describing the routes served by the gateway
+func (g *Gateway) OpenAPI() map[string]interface{} {
+	s := &schemas{components: object{}}
+	errorResponse := object{
+		"description": "Error of the call",
+		"content":     object{"application/json": object{"schema": s.of(reflect.TypeOf(Error{}))}},
+	}
+	paths := object{}
+	operationIDs := map[string]int{}
+
+	for _, rt := range g.routes {
+		service, method := splitRPC(rt.RPC)
+		operationID := service + "_" + method
+		if operationIDs[operationID]++; operationIDs[operationID] > 1 {
+			operationID += "_" + strconv.Itoa(operationIDs[operationID])
+		}
+
+		bound := map[string]bool{}
+		for name := range rt.Values {
+			bound[name] = true
+		}
+		var parameters []interface{}
+		for _, segment := range rt.segments {
+			name := variable(segment)
+			if name == "" {
+				continue
+			}
+			bound[name] = true
+			parameter := object{"name": name, "in": "path", "required": true, "schema": object{"type": "string"}}
+			if name == tenantVar {
+				parameter["description"] = "Tenant the call applies to"
+			}
+			parameters = append(parameters, parameter)
+		}
+
+		operation := object{
+			"operationId": operationID,
+			"tags":        []string{service},
+			"summary":     "RPC " + rt.RPC,
+		}
+		if rt.Method == http.MethodGet {
+			fields := queryFields(rt.request, "", 1)
+			names := make([]string, 0, len(fields))
+			for name := range fields {
+				if !bound[name] {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				parameters = append(parameters, object{"name": name, "in": "query", "schema": s.of(fields[name])})
+			}
+		} else {
+			operation["requestBody"] = object{
+				"content": object{"application/json": object{"schema": s.of(rt.request)}},
+			}
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if rt.Async {
+			operation["description"] = "Long-running call, made in a job whose status is at '" + JobsPath + "/{uuid}'"
+			operation["responses"] = object{
+				"202": object{
+					"description": "Call accepted",
+					"content":     object{"application/json": object{"schema": s.of(reflect.TypeOf(Accepted{}))}},
+				},
+				"default": errorResponse,
+			}
+		} else {
+			operation["responses"] = object{
+				"200": object{
+					"description": "Response of the call",
+					"content":     object{"application/json": object{"schema": s.of(rt.response)}},
+				},
+				"default": errorResponse,
+			}
+		}
+
+		item, ok := paths[rt.Path].(object)
+		if !ok {
+			item = object{}
+			paths[rt.Path] = item
+		}
+		item[strings.ToLower(rt.Method)] = operation
+	}
+
+	item, ok := paths[JobsPath+"/{uuid}"].(object)
+	if !ok {
+		item = object{}
+		paths[JobsPath+"/{uuid}"] = item
+	}
+	item["get"] = object{
+		"operationId": "JobService_Status",
+		"tags":        []string{"JobService"},
+		"summary":     "Status of a long-running call",
+		"parameters":  []interface{}{object{"name": "uuid", "in": "path", "required": true, "schema": object{"type": "string"}}},
+		"responses": object{
+			"200": object{
+				"description": "Status of the call",
+				"content":     object{"application/json": object{"schema": s.of(reflect.TypeOf(Job{}))}},
+			},
+			"default": errorResponse,
+		},
+	}
+
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":       "SafeScale",
+			"description": "REST/JSON gateway of the API of safescaled",
+			"version":     "v1",
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": s.components,
+			"securitySchemes": object{
+				"bearer": object{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{object{}, object{"bearer": []string{}}},
+	}
+}
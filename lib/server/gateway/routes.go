@@ -0,0 +1,182 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+)
+
+// Route maps the HTTP requests with a method and a path to a RPC
+type Route struct {
+	Method string // HTTP method
+	// Path is the pattern of the path; a segment '{<field>}' sets the field of the request of the RPC designated by
+	// its JSON name (dotted for the fields of the messages contained in the request, ex: '{host.name}'), except
+	// '{tenant}' designating the tenant the call applies to
+	Path   string
+	RPC    string            // full name of the RPC, '/<service>/<method>'
+	Values map[string]string // fields of the request set by the route, whatever the HTTP request
+	Async  bool              // the RPC is long-running: it is made in a job, the HTTP request being answered 202 Accepted
+}
+
+const (
+	tenantVar  = "tenant"
+	tenantPath = "/v1/tenants/{tenant}"
+)
+
+// Routes are the routes served by the gateway, looked up in order
+var Routes = append(append([]Route{
+	{Method: http.MethodGet, Path: "/v1/jobs", RPC: "/JobService/List"},
+	{Method: http.MethodDelete, Path: "/v1/jobs/{uuid}", RPC: "/JobService/Stop"},
+
+	{Method: http.MethodGet, Path: "/v1/tenants", RPC: "/TenantService/List"},
+	{Method: http.MethodPost, Path: "/v1/tenants/validate", RPC: "/TenantService/Validate"},
+	{Method: http.MethodGet, Path: "/v1/tenants/{name}", RPC: "/TenantService/Inspect"},
+	{Method: http.MethodPut, Path: "/v1/tenants/{name}/credentials", RPC: "/TenantService/SetCredentials"},
+	{Method: http.MethodGet, Path: "/v1/tenants/{name}/audit", RPC: "/TenantService/Audit"},
+	{Method: http.MethodGet, Path: "/v1/tenants/{name}/quota", RPC: "/TenantService/Quota"},
+	{Method: http.MethodPost, Path: "/v1/tenants/{name}/cleanup", RPC: "/TenantService/Cleanup", Async: true},
+	{Method: http.MethodPost, Path: "/v1/tenants/{name}/scan", RPC: "/TenantService/Scan", Async: true},
+	{Method: http.MethodGet, Path: "/v1/tenants/{name}/scan", RPC: "/TenantService/ScanStatus"},
+
+	{Method: http.MethodGet, Path: "/v1/features/repositories", RPC: "/FeatureService/ListRepositories"},
+	{Method: http.MethodPost, Path: "/v1/features/repositories", RPC: "/FeatureService/AddRepository"},
+	{Method: http.MethodPost, Path: "/v1/features/repositories/update", RPC: "/FeatureService/UpdateRepositories"},
+	{Method: http.MethodPost, Path: "/v1/features/validate", RPC: "/FeatureService/Validate"},
+
+	{Method: http.MethodGet, Path: tenantPath + "/images", RPC: "/ImageService/List"},
+	{Method: http.MethodGet, Path: tenantPath + "/templates", RPC: "/TemplateService/List"},
+
+	{Method: http.MethodGet, Path: tenantPath + "/networks", RPC: "/NetworkService/List"},
+	{Method: http.MethodPost, Path: tenantPath + "/networks", RPC: "/NetworkService/Create", Async: true},
+	{Method: http.MethodGet, Path: tenantPath + "/networks/{name}", RPC: "/NetworkService/Inspect"},
+	{Method: http.MethodDelete, Path: tenantPath + "/networks/{name}", RPC: "/NetworkService/Delete", Async: true},
+	{Method: http.MethodGet, Path: tenantPath + "/networks/{network.name}/subnets", RPC: "/SubnetService/List"},
+	{Method: http.MethodPost, Path: tenantPath + "/networks/{network.name}/subnets", RPC: "/SubnetService/Create", Async: true},
+	{Method: http.MethodGet, Path: tenantPath + "/networks/{network.name}/subnets/{subnet.name}", RPC: "/SubnetService/Inspect"},
+	{Method: http.MethodDelete, Path: tenantPath + "/networks/{network.name}/subnets/{subnet.name}", RPC: "/SubnetService/Delete", Async: true},
+	{Method: http.MethodGet, Path: tenantPath + "/networks/{network.name}/subnets/{subnet.name}/security-groups", RPC: "/SubnetService/ListSecurityGroups"},
+	{Method: http.MethodPost, Path: tenantPath + "/networks/{network.name}/subnets/{subnet.name}/security-groups/{group.name}/bind", RPC: "/SubnetService/BindSecurityGroup"},
+	{Method: http.MethodPost, Path: tenantPath + "/networks/{network.name}/subnets/{subnet.name}/security-groups/{group.name}/unbind", RPC: "/SubnetService/UnbindSecurityGroup"},
+	{Method: http.MethodPost, Path: tenantPath + "/networks/{network.name}/subnets/{subnet.name}/security-groups/{group.name}/enable", RPC: "/SubnetService/EnableSecurityGroup"},
+	{Method: http.MethodPost, Path: tenantPath + "/networks/{network.name}/subnets/{subnet.name}/security-groups/{group.name}/disable", RPC: "/SubnetService/DisableSecurityGroup"},
+
+	{Method: http.MethodGet, Path: tenantPath + "/security-groups", RPC: "/SecurityGroupService/List"},
+	{Method: http.MethodPost, Path: tenantPath + "/security-groups", RPC: "/SecurityGroupService/Create"},
+	{Method: http.MethodGet, Path: tenantPath + "/security-groups/{name}", RPC: "/SecurityGroupService/Inspect"},
+	{Method: http.MethodDelete, Path: tenantPath + "/security-groups/{group.name}", RPC: "/SecurityGroupService/Delete"},
+	{Method: http.MethodPost, Path: tenantPath + "/security-groups/{name}/clear", RPC: "/SecurityGroupService/Clear"},
+	{Method: http.MethodPost, Path: tenantPath + "/security-groups/{name}/reset", RPC: "/SecurityGroupService/Reset"},
+	{Method: http.MethodPost, Path: tenantPath + "/security-groups/{name}/sanitize", RPC: "/SecurityGroupService/Sanitize"},
+	{Method: http.MethodPost, Path: tenantPath + "/security-groups/{group.name}/rules", RPC: "/SecurityGroupService/AddRule"},
+	{Method: http.MethodDelete, Path: tenantPath + "/security-groups/{group.name}/rules", RPC: "/SecurityGroupService/DeleteRule"},
+	{Method: http.MethodGet, Path: tenantPath + "/security-groups/{target.name}/bonds", RPC: "/SecurityGroupService/Bonds"},
+
+	{Method: http.MethodGet, Path: tenantPath + "/hosts", RPC: "/HostService/List"},
+	{Method: http.MethodPost, Path: tenantPath + "/hosts", RPC: "/HostService/Create", Async: true},
+	{Method: http.MethodGet, Path: tenantPath + "/hosts/{name}", RPC: "/HostService/Inspect"},
+	{Method: http.MethodDelete, Path: tenantPath + "/hosts/{name}", RPC: "/HostService/Delete", Async: true},
+	{Method: http.MethodGet, Path: tenantPath + "/hosts/{name}/status", RPC: "/HostService/Status"},
+	{Method: http.MethodGet, Path: tenantPath + "/hosts/{name}/ssh", RPC: "/HostService/SSH"},
+	{Method: http.MethodPost, Path: tenantPath + "/hosts/{name}/start", RPC: "/HostService/Start", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/hosts/{name}/stop", RPC: "/HostService/Stop", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/hosts/{name}/reboot", RPC: "/HostService/Reboot", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/hosts/{name}/resize", RPC: "/HostService/Resize", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/hosts/{host.name}/run", RPC: "/SshService/Run", Async: true},
+	{Method: http.MethodGet, Path: tenantPath + "/hosts/{host.name}/security-groups", RPC: "/HostService/ListSecurityGroups"},
+	{Method: http.MethodPost, Path: tenantPath + "/hosts/{host.name}/security-groups/{group.name}/bind", RPC: "/HostService/BindSecurityGroup"},
+	{Method: http.MethodPost, Path: tenantPath + "/hosts/{host.name}/security-groups/{group.name}/unbind", RPC: "/HostService/UnbindSecurityGroup"},
+	{Method: http.MethodPost, Path: tenantPath + "/hosts/{host.name}/security-groups/{group.name}/enable", RPC: "/HostService/EnableSecurityGroup"},
+	{Method: http.MethodPost, Path: tenantPath + "/hosts/{host.name}/security-groups/{group.name}/disable", RPC: "/HostService/DisableSecurityGroup"},
+	{Method: http.MethodPost, Path: tenantPath + "/copy", RPC: "/SshService/Copy", Async: true},
+
+	{Method: http.MethodGet, Path: tenantPath + "/volumes", RPC: "/VolumeService/List"},
+	{Method: http.MethodPost, Path: tenantPath + "/volumes", RPC: "/VolumeService/Create", Async: true},
+	{Method: http.MethodGet, Path: tenantPath + "/volumes/{name}", RPC: "/VolumeService/Inspect"},
+	{Method: http.MethodDelete, Path: tenantPath + "/volumes/{name}", RPC: "/VolumeService/Delete", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/volumes/{volume.name}/attach", RPC: "/VolumeService/Attach", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/volumes/{volume.name}/detach", RPC: "/VolumeService/Detach", Async: true},
+
+	{Method: http.MethodGet, Path: tenantPath + "/buckets", RPC: "/BucketService/List"},
+	{Method: http.MethodPost, Path: tenantPath + "/buckets", RPC: "/BucketService/Create"},
+	{Method: http.MethodGet, Path: tenantPath + "/buckets/{name}", RPC: "/BucketService/Inspect"},
+	{Method: http.MethodDelete, Path: tenantPath + "/buckets/{name}", RPC: "/BucketService/Delete"},
+	{Method: http.MethodPost, Path: tenantPath + "/buckets/{bucket}/mount", RPC: "/BucketService/Mount", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/buckets/{bucket}/unmount", RPC: "/BucketService/Unmount", Async: true},
+
+	{Method: http.MethodGet, Path: tenantPath + "/shares", RPC: "/ShareService/List"},
+	{Method: http.MethodPost, Path: tenantPath + "/shares", RPC: "/ShareService/Create", Async: true},
+	{Method: http.MethodGet, Path: tenantPath + "/shares/{name}", RPC: "/ShareService/Inspect"},
+	{Method: http.MethodDelete, Path: tenantPath + "/shares/{name}", RPC: "/ShareService/Delete", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/shares/{share.name}/mount", RPC: "/ShareService/Mount", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/shares/{share.name}/unmount", RPC: "/ShareService/Unmount", Async: true},
+
+	{Method: http.MethodGet, Path: tenantPath + "/clusters", RPC: "/ClusterService/List"},
+	{Method: http.MethodPost, Path: tenantPath + "/clusters", RPC: "/ClusterService/Create", Async: true},
+	{Method: http.MethodGet, Path: tenantPath + "/clusters/{name}", RPC: "/ClusterService/Inspect"},
+	{Method: http.MethodDelete, Path: tenantPath + "/clusters/{name}", RPC: "/ClusterService/Delete", Async: true},
+	{Method: http.MethodGet, Path: tenantPath + "/clusters/{name}/state", RPC: "/ClusterService/State"},
+	{Method: http.MethodPost, Path: tenantPath + "/clusters/{name}/start", RPC: "/ClusterService/Start", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/clusters/{name}/stop", RPC: "/ClusterService/Stop", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/clusters/{name}/expand", RPC: "/ClusterService/Expand", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/clusters/{name}/shrink", RPC: "/ClusterService/Shrink", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/clusters/{name}/upgrade", RPC: "/ClusterService/Upgrade", Async: true},
+	{Method: http.MethodPut, Path: tenantPath + "/clusters/{name}/autoscaling", RPC: "/ClusterService/SetAutoscaling"},
+	{Method: http.MethodGet, Path: tenantPath + "/clusters/{name}/masters", RPC: "/ClusterService/ListMasters"},
+	{Method: http.MethodGet, Path: tenantPath + "/clusters/{name}/masters/available", RPC: "/ClusterService/FindAvailableMaster"},
+	{Method: http.MethodGet, Path: tenantPath + "/clusters/{name}/masters/{host.name}", RPC: "/ClusterService/InspectMaster"},
+	{Method: http.MethodGet, Path: tenantPath + "/clusters/{name}/nodes", RPC: "/ClusterService/ListNodes"},
+	{Method: http.MethodGet, Path: tenantPath + "/clusters/{name}/nodes/{host.name}", RPC: "/ClusterService/InspectNode"},
+	{Method: http.MethodDelete, Path: tenantPath + "/clusters/{name}/nodes/{host.name}", RPC: "/ClusterService/DeleteNode", Async: true},
+	{Method: http.MethodGet, Path: tenantPath + "/clusters/{name}/nodes/{host.name}/state", RPC: "/ClusterService/StateNode"},
+	{Method: http.MethodPost, Path: tenantPath + "/clusters/{name}/nodes/{host.name}/start", RPC: "/ClusterService/StartNode", Async: true},
+	{Method: http.MethodPost, Path: tenantPath + "/clusters/{name}/nodes/{host.name}/stop", RPC: "/ClusterService/StopNode", Async: true},
+}, featureRoutes("hosts", protocol.FeatureTargetType_FT_HOST)...), featureRoutes("clusters", protocol.FeatureTargetType_FT_CLUSTER)...)
+
+// unroutedRPCs are the RPCs of safescale.proto deliberately not served by the gateway, with the reason; any other RPC
+// must have a route
+var unroutedRPCs = map[string]string{
+	"/EventService/Watch":      "streaming RPC",
+	"/ImageService/Create":     "not implemented by safescaled",
+	"/ImageService/Delete":     "not implemented by safescaled",
+	"/PublicIPService/Bind":    "service not served by safescaled",
+	"/PublicIPService/Create":  "service not served by safescaled",
+	"/PublicIPService/Delete":  "service not served by safescaled",
+	"/PublicIPService/Inspect": "service not served by safescaled",
+	"/PublicIPService/List":    "service not served by safescaled",
+	"/PublicIPService/Unbind":  "service not served by safescaled",
+	"/TenantService/Get":       "the tenant of a route is given by its path",
+	"/TenantService/Set":       "the tenant of a route is given by its path",
+}
+
+// featureRoutes returns the routes of the features of the hosts or of the clusters
+func featureRoutes(collection string, targetType protocol.FeatureTargetType) []Route {
+	path := tenantPath + "/" + collection + "/{target_ref.name}/features"
+	values := map[string]string{"target_type": strconv.Itoa(int(targetType))}
+	return []Route{
+		{Method: http.MethodGet, Path: path, RPC: "/FeatureService/List", Values: values},
+		{Method: http.MethodGet, Path: path + "/history", RPC: "/FeatureService/History", Values: values},
+		{Method: http.MethodPost, Path: path + "/plan", RPC: "/FeatureService/Plan", Values: values},
+		{Method: http.MethodPost, Path: path + "/{name}", RPC: "/FeatureService/Add", Values: values, Async: true},
+		{Method: http.MethodDelete, Path: path + "/{name}", RPC: "/FeatureService/Remove", Values: values, Async: true},
+		{Method: http.MethodPost, Path: path + "/{name}/check", RPC: "/FeatureService/Check", Values: values, Async: true},
+		{Method: http.MethodPost, Path: path + "/{name}/upgrade", RPC: "/FeatureService/Upgrade", Values: values, Async: true},
+		{Method: http.MethodPost, Path: path + "/{name}/dry-run", RPC: "/FeatureService/DryRun", Values: values},
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/CS-SI/SafeScale/lib/server/events"
+	"github.com/CS-SI/SafeScale/lib/server/iaas"
+	clusterfactory "github.com/CS-SI/SafeScale/lib/server/resources/factories/cluster"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// Autoscaler periodically evaluates the autoscaling policies of the clusters of every tenant
+type Autoscaler struct {
+	period     time.Duration
+	getService func(string) (iaas.Service, fail.Error) // returns the service of a tenant, shared with the requests
+	running    map[string]bool                         // clusters currently evaluated, indexed by '<tenant>/<cluster>'
+	lock       sync.Mutex
+}
+
+// NewAutoscaler creates an Autoscaler evaluating the policies every 'period', using the services of the tenants
+// returned by 'getService'
+// The service is requested on every evaluation, so that a service recreated after a change of the tenants is used
+func NewAutoscaler(period time.Duration, getService func(string) (iaas.Service, fail.Error)) (*Autoscaler, fail.Error) {
+	if period <= 0 {
+		return nil, fail.InvalidParameterError("period", "must be greater than 0")
+	}
+	if getService == nil {
+		return nil, fail.InvalidParameterError("getService", "cannot be nil")
+	}
+	return &Autoscaler{
+		period:     period,
+		getService: getService,
+		running:    map[string]bool{},
+	}, nil
+}
+
+// Start launches the periodic evaluation in background
+func (as *Autoscaler) Start() {
+	go func() {
+		for {
+			as.evaluate()
+			time.Sleep(as.period)
+		}
+	}()
+}
+
+// evaluate starts the evaluation of the policies of the clusters of every tenant
+func (as *Autoscaler) evaluate() {
+	tenants, xerr := iaas.GetTenantNames()
+	if xerr != nil {
+		logrus.Errorf("autoscaler: failed to list tenants: %s", xerr.Error())
+		return
+	}
+	for tenant := range tenants {
+		svc, xerr := as.getService(tenant)
+		if xerr != nil {
+			logrus.Errorf("autoscaler: failed to use tenant '%s': %s", tenant, xerr.Error())
+			continue
+		}
+		as.evaluateTenant(tenant, svc)
+	}
+}
+
+// evaluateTenant starts the evaluation of the policy of each cluster of the tenant not already evaluated
+// Each cluster is evaluated in its own goroutine, a scaling operation being potentially long
+func (as *Autoscaler) evaluateTenant(tenant string, svc iaas.Service) {
+	task, xerr := concurrency.NewTaskWithContext(events.ContextWithTenant(context.Background(), tenant), nil)
+	if xerr != nil {
+		logrus.Errorf("autoscaler: failed to create task: %s", xerr.Error())
+		return
+	}
+	list, xerr := clusterfactory.List(task, svc)
+	if xerr != nil {
+		logrus.Errorf("autoscaler: failed to list clusters of tenant '%s': %s", svc.GetName(), xerr.Error())
+		return
+	}
+
+	for _, v := range list {
+		key := svc.GetName() + "/" + v.Name
+		as.lock.Lock()
+		if as.running[key] {
+			as.lock.Unlock()
+			continue
+		}
+		as.running[key] = true
+		as.lock.Unlock()
+
+		go func(name string) {
+			defer func() {
+				as.lock.Lock()
+				delete(as.running, key)
+				as.lock.Unlock()
+			}()
+
+			if xerr := autoscaleCluster(tenant, svc, name); xerr != nil {
+				logrus.Errorf("autoscaler: failed to autoscale cluster '%s' of tenant '%s': %s", name, svc.GetName(), xerr.Error())
+			}
+		}(v.Name)
+	}
+}
+
+// autoscaleCluster evaluates the autoscaling policy of a cluster; the events of the scaling are published on behalf of
+// the tenant
+func autoscaleCluster(tenant string, svc iaas.Service, name string) (xerr fail.Error) {
+	defer fail.OnPanic(&xerr)
+
+	task, xerr := concurrency.NewTaskWithContext(events.ContextWithTenant(context.Background(), tenant), nil)
+	if xerr != nil {
+		return xerr
+	}
+	rc, xerr := clusterfactory.Load(task, svc, name)
+	if xerr != nil {
+		return xerr
+	}
+	return rc.Autoscale(task)
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/server/iaas"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+func TestNewAutoscaler(t *testing.T) {
+	getService := func(string) (iaas.Service, fail.Error) { return nil, fail.NotAvailableError() }
+
+	_, xerr := NewAutoscaler(0, getService)
+	assert.NotNil(t, xerr)
+	_, xerr = NewAutoscaler(time.Minute, nil)
+	assert.NotNil(t, xerr)
+	as, xerr := NewAutoscaler(time.Minute, getService)
+	require.Nil(t, xerr)
+	assert.NotNil(t, as)
+}
+
+func TestAutoscalerEvaluateGetsServiceEachTime(t *testing.T) {
+	// the tenants file is looked for in $HOME/.safescale
+	dir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, ".safescale"), 0700))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, ".safescale", "tenants.toml"), []byte("[[tenants]]\nclient = \"ovh\"\nname = \"TestAutoscaler\"\n"), 0600))
+	home := os.Getenv("HOME")
+	require.Nil(t, os.Setenv("HOME", dir))
+	defer func() { _ = os.Setenv("HOME", home) }()
+
+	var calls []string
+	as, xerr := NewAutoscaler(time.Minute, func(tenant string) (iaas.Service, fail.Error) {
+		calls = append(calls, tenant)
+		return nil, fail.NotAvailableError("tenant '%s' is not available", tenant)
+	})
+	require.Nil(t, xerr)
+
+	// the service is not kept between evaluations, a service recreated after a change of the tenant must be used
+	as.evaluate()
+	as.evaluate()
+	assert.Equal(t, []string{"TestAutoscaler", "TestAutoscaler"}, calls)
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"github.com/CS-SI/SafeScale/lib/server"
+	"github.com/CS-SI/SafeScale/lib/server/auth"
+	"github.com/CS-SI/SafeScale/lib/server/iaas"
+	"github.com/CS-SI/SafeScale/lib/server/resources/operations"
+	"github.com/CS-SI/SafeScale/lib/utils/debug"
+	"github.com/CS-SI/SafeScale/lib/utils/debug/tracing"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// QuotaStatus contains the use and the limits of a kind of resource; a negative limit means no limit
+type QuotaStatus struct {
+	Resource         iaas.QuotaResource
+	Used             float64 // amount used on the tenant
+	Limit            float64 // limit of the tenant
+	UserUsed         float64 // amount used by the caller
+	UserLimit        float64 // limit of the caller
+	ProviderReported bool    // tells if the provider reports its quota of the resource
+	ProviderUsed     float64 // amount used on the account of the provider
+	ProviderLimit    float64 // limit set by the provider
+}
+
+// QuotaReport contains the use and the limits of all the kinds of resources of the tenant
+type QuotaReport struct {
+	User          string // name of the caller (empty without authentication)
+	Quotas        []QuotaStatus
+	ProviderError string // reason why the quotas of the provider are not reported, if any
+}
+
+// QuotaHandler defines API to consult quotas
+type QuotaHandler interface {
+	Report() (*QuotaReport, fail.Error)
+}
+
+// quotaHandler quota service
+type quotaHandler struct {
+	job server.Job
+}
+
+// NewQuotaHandler creates a quota service
+func NewQuotaHandler(job server.Job) QuotaHandler {
+	return &quotaHandler{job: job}
+}
+
+// Report returns the use and the limits of the resources of the tenant, for the tenant, the caller and the provider
+func (handler *quotaHandler) Report() (_ *QuotaReport, xerr fail.Error) {
+	if handler == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if handler.job == nil {
+		return nil, fail.InvalidInstanceContentError("handler.job", "cannot be nil")
+	}
+
+	task := handler.job.GetTask()
+	tracer := debug.NewTracer(task, tracing.ShouldTrace("handlers.tenant")).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&xerr, tracer.TraceMessage())
+
+	svc := handler.job.GetService()
+	usage, xerr := operations.ComputeTenantUsage(task, svc)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	report := &QuotaReport{}
+	if ctx, xerr := task.GetContext(); xerr == nil {
+		if identity := auth.FromContext(ctx); identity != nil {
+			report.User = identity.Name
+		}
+	}
+	quotas := svc.GetQuotas()
+	userLimits := quotas.ForUser(report.User)
+	providerQuotas, xerr := svc.GetProviderQuotas()
+	if xerr != nil {
+		report.ProviderError = xerr.Error()
+	}
+
+	for _, r := range iaas.QuotaResources {
+		status := QuotaStatus{
+			Resource:  r,
+			Used:      usage.Total[r],
+			Limit:     -1,
+			UserUsed:  usage.ByOwner[report.User][r],
+			UserLimit: -1,
+		}
+		if limit, ok := quotas.Tenant[r]; ok {
+			status.Limit = limit
+		}
+		if limit, ok := userLimits[r]; ok {
+			status.UserLimit = limit
+		}
+		if quota, ok := providerQuotas[string(r)]; ok {
+			status.ProviderReported = true
+			status.ProviderUsed = quota.Used
+			status.ProviderLimit = quota.Limit
+		}
+		report.Quotas = append(report.Quotas, status)
+	}
+	return report, nil
+}
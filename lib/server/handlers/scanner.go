@@ -17,20 +17,27 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	scribble "github.com/nanobox-io/golang-scribble"
+	uuid "github.com/satori/go.uuid"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
 
 	"github.com/CS-SI/SafeScale/lib/server"
+	"github.com/CS-SI/SafeScale/lib/server/events"
+	"github.com/CS-SI/SafeScale/lib/server/iaas"
+	"github.com/CS-SI/SafeScale/lib/server/iaas/scannerdb"
 	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
 	hostfactory "github.com/CS-SI/SafeScale/lib/server/resources/factories/host"
 	"github.com/CS-SI/SafeScale/lib/utils"
@@ -43,49 +50,12 @@ import (
 
 //go:generate mockgen -destination=../mocks/mock_imageapi.go -package=mocks github.com/CS-SI/SafeScale/lib/server/handlers ScannerHandler
 
-// PriceInfo stores price information
-type PriceInfo struct {
-	Devise        string  `json:"device"`                   // contains the device of the price info
-	DurationLabel string  `json:"duration_label,omitempty"` // contains a label for the duration "Per Hour" for example
-	Duration      uint    `json:"duration"`                 // number of seconds of the duration
-	Price         float64 `json:"price"`                    // price in the devise for the duration
-}
-
-// CPUInfo stores CPU properties
-type CPUInfo struct {
-	TenantName     string      `json:"tenant_name,omitempty"`
-	TemplateID     string      `json:"template_id,omitempty"`
-	TemplateName   string      `json:"template_name,omitempty"`
-	ImageID        string      `json:"image_id,omitempty"`
-	ImageName      string      `json:"image_name,omitempty"`
-	LastUpdated    string      `json:"last_updated,omitempty"`
-	NumberOfCPU    int         `json:"number_of_cpu,omitempty"`
-	NumberOfCore   int         `json:"number_of_core,omitempty"`
-	NumberOfSocket int         `json:"number_of_socket,omitempty"`
-	CPUFrequency   float64     `json:"cpu_frequency_Ghz,omitempty"`
-	CPUArch        string      `json:"cpu_arch,omitempty"`
-	Hypervisor     string      `json:"hypervisor,omitempty"`
-	CPUModel       string      `json:"cpu_model,omitempty"`
-	RAMSize        float64     `json:"ram_size_Gb,omitempty"`
-	RAMFreq        float64     `json:"ram_freq,omitempty"`
-	GPU            int         `json:"gpu,omitempty"`
-	GPUModel       string      `json:"gpu_model,omitempty"`
-	DiskSize       int64       `json:"disk_size_Gb,omitempty"`
-	MainDiskType   string      `json:"main_disk_type"`
-	MainDiskSpeed  float64     `json:"main_disk_speed_MBps"`
-	SampleNetSpeed float64     `json:"sample_net_speed_KBps"`
-	EphDiskSize    int64       `json:"eph_disk_size_Gb"`
-	PricePerSecond float64     `json:"price_in_dollars_second"` // DEPRECATED, use field Prices
-	PricePerHour   float64     `json:"price_in_dollars_hour"`   // DEPRECATED, use field Prices
-	Prices         []PriceInfo `json:"prices,omitempty"`
-}
+const probeHostPrefix = "scanhost-"
 
-// StoredCPUInfo ...
-type StoredCPUInfo struct {
-	ID string `bow:"key"`
-	CPUInfo
-}
+// main disk is the first disk reported by lsblk
+const cmdMainDisk string = "lsblk -n -d -o NAME,TYPE | awk '$2 == \"disk\" { print $1; exit }'"
 
+// probe commands, indexed by the json name of the field of abstract.StoredCPUInfo they fill
 const cmdNumberOfCPU string = "lscpu | grep 'CPU(s):' | grep -v 'NUMA' | tr -d '[:space:]' | cut -d: -f2"
 const cmdNumberOfCorePerSocket string = "lscpu | grep 'Core(s) per socket' | tr -d '[:space:]' | cut -d: -f2"
 const cmdNumberOfSocket string = "lscpu | grep 'Socket(s)' | tr -d '[:space:]' | cut -d: -f2"
@@ -97,36 +67,78 @@ const cmdCPUModelName string = "lscpu | grep 'Model name' | cut -d: -f2 | sed -e
 const cmdTotalRAM string = "cat /proc/meminfo | grep MemTotal | cut -d: -f2 | sed -e 's/^[[:space:]]*//' | cut -d' ' -f1"
 const cmdRAMFreq string = "sudo dmidecode -t memory | grep Speed | head -1 | cut -d' ' -f2"
 
-const cmdGPU string = "lspci | egrep -i 'VGA|3D' | grep -i nvidia | cut -d: -f3 | sed 's/.*controller://g' | tr '\n' '%'"
-const cmdDiskSize string = "lsblk -b --output SIZE -n -d /dev/sda"
-const cmdEphemeralDiskSize string = "lsblk -o name,type,mountpoint | grep disk | awk {'print $1'} | grep -v sda | xargs -i'{}' lsblk -b --output SIZE -n -d /dev/'{}'"
-const cmdRotational string = "cat /sys/block/sda/queue/rotational"
-const cmdDiskSpeed string = "sudo hdparm -t --direct /dev/sda | grep MB | awk '{print $11}'"
-const cmdNetSpeed string = "URL=\"http://www.google.com\";curl -L --w \"$URL\nDNS %{time_namelookup}s conn %{time_connect}s time %{time_total}s\nSpeed %{speed_download}bps Size %{size_download}bytes\n\" -o/dev/null -s $URL | grep bps | awk '{ print $2}' | cut -d '.' -f 1"
-
-var cmd = fmt.Sprintf("export LANG=C;echo $(%s)î$(%s)î$(%s)î$(%s)î$(%s)î$(%s)î$(%s)î$(%s)î$(%s)î$(%s)î$(%s)î$(%s)î$(%s)î$(%s)î$(%s)",
-	cmdNumberOfCPU,
-	cmdNumberOfCorePerSocket,
-	cmdNumberOfSocket,
-	cmdCPUFreq,
-	cmdArch,
-	cmdHypervisor,
-	cmdCPUModelName,
-	cmdTotalRAM,
-	cmdRAMFreq,
-	cmdGPU,
-	cmdDiskSize,
-	cmdEphemeralDiskSize,
-	cmdDiskSpeed,
-	cmdRotational,
-	cmdNetSpeed,
-)
+const cmdGPU string = "lspci | egrep -i 'VGA|3D' | grep -i nvidia | cut -d: -f3 | sed 's/.*controller://g' | tr '\\n' '%'"
+const cmdDiskSize string = "lsblk -b --output SIZE -n -d /dev/$DISK"
+const cmdEphemeralDiskSize string = "lsblk -b -n -d -o NAME,TYPE,SIZE | awk -v disk=$DISK '$1 != disk && $2 == \"disk\" { s += $3 } END { print s + 0 }'"
+const cmdRotational string = "cat /sys/block/$DISK/queue/rotational"
+const cmdDiskSpeed string = "sudo hdparm -t --direct /dev/$DISK | grep MB | awk '{print $11}'"
+const cmdNetSpeed string = "curl -L -s -o /dev/null -w '%%{speed_download}' '%s' | cut -d'.' -f1"
+
+var probeCommands = []struct{ key, cmd string }{
+	{"number_of_cpu", cmdNumberOfCPU},
+	{"number_of_core", cmdNumberOfCorePerSocket},
+	{"number_of_socket", cmdNumberOfSocket},
+	{"cpu_frequency_Ghz", cmdCPUFreq},
+	{"cpu_arch", cmdArch},
+	{"hypervisor", cmdHypervisor},
+	{"cpu_model", cmdCPUModelName},
+	{"ram_size_Gb", cmdTotalRAM},
+	{"ram_freq", cmdRAMFreq},
+	{"gpu", cmdGPU},
+	{"disk_size_Gb", cmdDiskSize},
+	{"eph_disk_size_Gb", cmdEphemeralDiskSize},
+	{"main_disk_speed_MBps", cmdDiskSpeed},
+	{"main_disk_type", cmdRotational},
+}
+
+// probeScript returns the script run on probe hosts; each probe outputs a line '<key>=<value>'
+// Network speed is sampled by downloading netSpeedURL, and not sampled if netSpeedURL is empty
+func probeScript(netSpeedURL string) string {
+	var b strings.Builder
+	b.WriteString("export LANG=C\n")
+	b.WriteString("DISK=$(" + cmdMainDisk + ")\n")
+	for _, v := range probeCommands {
+		b.WriteString(fmt.Sprintf("echo \"%s=$(%s)\"\n", v.key, v.cmd))
+	}
+	if netSpeedURL != "" {
+		b.WriteString(fmt.Sprintf("echo \"sample_net_speed_KBps=$(%s)\"\n", fmt.Sprintf(cmdNetSpeed, netSpeedURL)))
+	}
+	return b.String()
+}
+
+// ScanRequest tells what the scanner has to do; empty fields take the value configured in tenant
+type ScanRequest struct {
+	Images      []string // names of the images usable by probe hosts, by order of preference
+	Templates   []string // regexps selecting the templates to scan
+	Parallelism uint     // number of probe hosts created simultaneously
+	Budget      float64  // maximum cost of the scan, in the currency of the prices per hour
+	Force       bool     // if true, scans again the templates already scanned
+}
+
+// ScanReport contains the outcome of a scan
+type ScanReport struct {
+	Region  string
+	Image   string
+	Cost    float64                  // estimated cost of the scan, each probe host being charged one hour
+	Scanned []abstract.StoredCPUInfo // data collected during the scan
+	Skipped map[string]string        // templates not scanned, with the reason
+	Failed  map[string]string        // templates whose scan failed, with the reason
+}
+
+// ScanStatus contains the data collected by the scanner for the tenant
+type ScanStatus struct {
+	Region  string
+	Scanned []abstract.StoredCPUInfo // data already collected
+	Pending []string                 // names of the templates not scanned yet
+	Probes  []scannerdb.Probe        // probe hosts left by an interrupted scan
+}
 
 // TODO At service level, ve need to log before returning, because it's the last chance to track the real issue in server side
 
 // ScannerHandler defines API to manipulate images
 type ScannerHandler interface {
-	Scan() fail.Error
+	Scan(ScanRequest) (*ScanReport, fail.Error)
+	Status() (*ScanStatus, fail.Error)
 }
 
 // scannerHandler service
@@ -139,13 +151,50 @@ func NewScannerHandler(job server.Job) ScannerHandler {
 	return &scannerHandler{job: job}
 }
 
-// Scan scans the tenant and update the database
-func (handler *scannerHandler) Scan() (xerr fail.Error) {
+// activeScans contains the identifiers of the scans running in the daemon, indexed by tenant; only one scan of a
+// tenant runs at a time, and the probe hosts of a running scan are not purged
+var (
+	activeScans     = map[string]string{}
+	activeScansLock sync.Mutex
+)
+
+// startScan registers a new scan of the tenant and returns its identifier, failing if a scan of the tenant is running
+func startScan(tenant string) (string, fail.Error) {
+	activeScansLock.Lock()
+	defer activeScansLock.Unlock()
+	if _, ok := activeScans[tenant]; ok {
+		return "", fail.NotAvailableError("a scan of tenant '%s' is already running", tenant)
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", fail.ToError(err)
+	}
+	activeScans[tenant] = id.String()
+	return id.String(), nil
+}
+
+// endScan unregisters the running scan of the tenant
+func endScan(tenant string) {
+	activeScansLock.Lock()
+	defer activeScansLock.Unlock()
+	delete(activeScans, tenant)
+}
+
+// isStaleProbe tells if the probe host does not belong to the running scan of the tenant
+func isStaleProbe(tenant string, probe scannerdb.Probe) bool {
+	activeScansLock.Lock()
+	defer activeScansLock.Unlock()
+	id, ok := activeScans[tenant]
+	return !ok || probe.ScanID != id
+}
+
+// Scan scans the templates of the tenant not scanned yet and updates the database
+func (handler *scannerHandler) Scan(req ScanRequest) (_ *ScanReport, xerr fail.Error) {
 	if handler == nil {
-		return fail.InvalidInstanceError()
+		return nil, fail.InvalidInstanceError()
 	}
 	if handler.job == nil {
-		return fail.InvalidInstanceContentError("handler.job", "cannot be nil")
+		return nil, fail.InvalidInstanceContentError("handler.job", "cannot be nil")
 	}
 
 	tracer := debug.NewTracer(handler.job.GetTask(), tracing.ShouldTrace("handlers.tenant")).WithStopwatch().Entering()
@@ -153,238 +202,421 @@ func (handler *scannerHandler) Scan() (xerr fail.Error) {
 	defer fail.OnExitLogError(&xerr, tracer.TraceMessage())
 
 	svc := handler.job.GetService()
+	cfg := svc.GetScannerConfig()
+	if !cfg.Scannable {
+		return nil, fail.ForbiddenError("tenant '%s' is not scannable (keyword 'Scannable' of section 'compute' is not set to true)", svc.GetName())
+	}
+	req = completeScanRequest(req, cfg)
 
-	// FIXME: Check if tenant is scannable
-
-	// for _, tenant := range theProviders {
-	// 	isScannable, err := isTenantScannable(tenant.(map[string]interface{}))
-	// 	if err != nil {
-	// 		panic(fmt.Sprint(err.Error()))
-	// 	}
-	// 	if isScannable {
-	// 		tenantName, found := tenant.(map[string]interface{})["name"].(string)
-	// 		if !found {
-	// 			panic(fmt.Sprintf("There is a scannable tenant without name"))
-	// 		}
-	// 		targetedProviders = append(targetedProviders, tenantName)
-	// 	}
-	// }
-
-	if err := handler.analyze(); err != nil {
-		return err
+	scanID, xerr := startScan(svc.GetName())
+	if xerr != nil {
+		return nil, xerr
 	}
-	if err := handler.collect(); err != nil {
-		return fail.Wrap(err, "failed to save scanned info for tenant '%s'", svc.GetName())
+	defer endScan(svc.GetName())
+
+	db, region, xerr := handler.openDB()
+	if xerr != nil {
+		return nil, xerr
 	}
-	return nil
-}
 
-func (handler *scannerHandler) analyze() (xerr fail.Error) {
-	svc := handler.job.GetService()
-	tenantName := svc.GetName()
+	// Removes the probe hosts left by a previous scan that has been interrupted
+	if xerr = handler.purgeProbes(db, false); xerr != nil {
+		return nil, xerr
+	}
 
 	if xerr = handler.dumpImages(); xerr != nil {
-		return xerr
+		return nil, xerr
 	}
-
 	if xerr = handler.dumpTemplates(); xerr != nil {
-		return xerr
+		return nil, xerr
 	}
 
-	templates, xerr := svc.ListTemplates(true)
+	img, xerr := handler.selectImage(req.Images)
 	if xerr != nil {
-		return xerr
+		return nil, xerr
 	}
-	img, xerr := svc.SearchImage("Ubuntu 18.04")
-	if xerr != nil {
-		logrus.Warnf("No image here...")
-		return xerr
+
+	report := &ScanReport{
+		Region:  region,
+		Image:   img.Name,
+		Skipped: map[string]string{},
+		Failed:  map[string]string{},
 	}
+	templates, xerr := handler.selectTemplates(db, req, report)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+	)
+	sem := make(chan struct{}, req.Parallelism)
+	for _, v := range templates {
+		if handler.job.Aborted() {
+			lock.Lock()
+			report.Skipped[v.Name] = "scan aborted"
+			lock.Unlock()
+			continue
+		}
 
-	task := handler.job.GetTask()
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(template abstract.HostTemplate) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			info, xerr := handler.probe(db, scanID, *img, template, cfg.NetSpeedURL)
+			lock.Lock()
+			defer lock.Unlock()
+			if xerr != nil {
+				logrus.Warnf("tenant '%s', template '%s': scan failed: %v", svc.GetName(), template.Name, xerr)
+				report.Failed[template.Name] = xerr.Error()
+				return
+			}
+			report.Scanned = append(report.Scanned, *info)
+		}(v)
+	}
+	wg.Wait()
 
-	// Prepare network if needed
-	//netName := "net-safescale" // FIXME: Hardcoded string
-	//network, xerr := networkfactory.Load(task, svc, netName)
-	//if xerr != nil {
-	//	if _, ok := xerr.(*fail.ErrNotFound); !ok {
-	//		return xerr
-	//	}
-	//	network, xerr := networkfactory.New(svc)
-	//	if xerr != nil {
-	//		return xerr
-	//	}
-	//	req := abstract.NetworkRequest{
-	//		Name:      netName,
-	//		IPVersion: ipversion.IPv4,
-	//		CIDR:      "192.168.0.0/24",
-	//	}
-	//	if xerr = network.Create(task, req, "", nil); xerr != nil {
-	//		return xerr
-	//	}
-	//
-	//	defer func() {
-	//		derr := network.Delete(task)
-	//		if derr != nil {
-	//			logrus.Warnf("Error deleting network '%s'", network.GetID())
-	//		}
-	//		_ = xerr.AddConsequence(derr)
-	//	}()
-	//}
+	if handler.job.Aborted() {
+		return report, fail.AbortedError(nil, "scan of tenant '%s' aborted", svc.GetName())
+	}
+	return report, nil
+}
 
-	if err := os.MkdirAll(utils.AbsPathify("$HOME/.safescale/scanner"), 0777); err != nil {
-		return fail.ToError(err)
+// completeScanRequest fills the empty fields of the request with the values configured in tenant
+func completeScanRequest(req ScanRequest, cfg iaas.ScannerConfig) ScanRequest {
+	if len(req.Images) == 0 {
+		req.Images = cfg.Images
+	}
+	if len(req.Templates) == 0 {
+		req.Templates = cfg.Templates
+	}
+	if req.Parallelism == 0 {
+		req.Parallelism = cfg.Parallelism
+		if req.Parallelism == 0 {
+			req.Parallelism = iaas.DefaultScannerParallelism
+		}
 	}
+	if req.Budget == 0 {
+		req.Budget = cfg.Budget
+	}
+	return req
+}
 
-	var wg sync.WaitGroup
+// Status returns the data collected by the scanner for the tenant
+func (handler *scannerHandler) Status() (_ *ScanStatus, xerr fail.Error) {
+	if handler == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if handler.job == nil {
+		return nil, fail.InvalidInstanceContentError("handler.job", "cannot be nil")
+	}
 
-	concurrency := math.Min(4, float64(len(templates)/2))
-	sem := make(chan bool, int(concurrency))
+	tracer := debug.NewTracer(handler.job.GetTask(), tracing.ShouldTrace("handlers.tenant")).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&xerr, tracer.TraceMessage())
 
-	hostAnalysis := func(template abstract.HostTemplate) fail.Error {
-		defer wg.Done()
-		//if network != nil {
-		// Limit scanner tests for integration test purposes
-		testSubset := ""
+	db, region, xerr := handler.openDB()
+	if xerr != nil {
+		return nil, xerr
+	}
 
-		// FIXME: move SCANNER_SUBSET to safescale CLI and pass the value by protobuf to the handler
-		if testSubsetCandidate := os.Getenv("SCANNER_SUBSET"); testSubsetCandidate != "" {
-			testSubset = testSubsetCandidate
+	status := &ScanStatus{Region: region}
+	if status.Scanned, xerr = db.ReadAll(); xerr != nil {
+		return nil, xerr
+	}
+	probes, xerr := db.ListProbes()
+	if xerr != nil {
+		return nil, xerr
+	}
+	for _, v := range probes {
+		if isStaleProbe(handler.job.GetService().GetName(), v) {
+			status.Probes = append(status.Probes, v)
 		}
+	}
 
-		if len(testSubset) > 0 {
-			if !strings.Contains(template.Name, testSubset) {
-				return nil
-			}
+	scanned := make(map[string]bool, len(status.Scanned))
+	for _, v := range status.Scanned {
+		scanned[v.TemplateName] = true
+	}
+	templates, xerr := handler.job.GetService().ListTemplates(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+	for _, v := range templates {
+		if !scanned[v.Name] {
+			status.Pending = append(status.Pending, v.Name)
 		}
+	}
+	sort.Strings(status.Pending)
+	return status, nil
+}
 
-		// TODO: If there is a file with today's date, skip it...
-		fileCandidate := utils.AbsPathify("$HOME/.safescale/scanner/" + tenantName + "#" + template.Name + ".json")
-		if _, err := os.Stat(fileCandidate); !os.IsNotExist(err) {
-			return nil
-		}
+// openDB opens the scanner database of the tenant in its region
+func (handler *scannerHandler) openDB() (*scannerdb.Store, string, fail.Error) {
+	svc := handler.job.GetService()
+	authOpts, xerr := svc.GetAuthenticationOptions()
+	if xerr != nil {
+		return nil, "", xerr
+	}
+	region := authOpts.GetString("Region")
+	if region == "" {
+		return nil, "", fail.InvalidRequestError("'Region' not set in tenant 'compute' section")
+	}
+
+	db, xerr := scannerdb.Open(svc.GetName(), region)
+	if xerr != nil {
+		return nil, "", xerr
+	}
+	return db, region, nil
+}
 
-		logrus.Infof("Checking template %s", template.Name)
-
-		//var an *abstract.Network
-		//xerr = network.Inspect(task, func(clonable data.Clonable, _ *serialize.JSONProperties) fail.Error {
-		//	var ok bool
-		//	an, ok = clonable.(*abstract.Network)
-		//	if !ok {
-		//		return fail.InconsistentError("'*abstract.Network' expected, '%s' provided", reflect.TypeOf(clonable).String())
-		//	}
-		//	return nil
-		//})
-		//if xerr != nil {
-		//	return xerr
-		//}
-		hostName := "scanhost-" + template.Name
-		host, xerr := hostfactory.New(svc)
+// selectImage returns the first image found among candidates, or the default image of the tenant if there is no candidate
+func (handler *scannerHandler) selectImage(candidates []string) (*abstract.Image, fail.Error) {
+	svc := handler.job.GetService()
+	if len(candidates) == 0 {
+		cfg, xerr := svc.GetConfigurationOptions()
 		if xerr != nil {
-			return xerr
+			return nil, xerr
 		}
-
-		req := abstract.HostRequest{
-			ResourceName: hostName,
-			//Subnets:      []*abstract.Network{an},
-			PublicIP:   false,
-			TemplateID: template.ID,
+		if image := cfg.GetString("DefaultImage"); image != "" {
+			candidates = []string{image}
 		}
-		def := abstract.HostSizingRequirements{
-			Image: "Ubuntu 18.04",
+	}
+	if len(candidates) == 0 {
+		return nil, fail.InvalidRequestError("no image to create probe hosts: set keyword 'Images' in section 'scanner' or 'DefaultImage' in section 'compute' of tenant '%s'", svc.GetName())
+	}
+
+	for _, v := range candidates {
+		img, xerr := svc.SearchImage(v)
+		if xerr == nil {
+			return img, nil
 		}
-		if _, xerr = host.Create(task, req, def); xerr != nil {
-			return fail.Wrap(xerr, "template [%s] host '%s': error creation", template.Name, hostName)
+		logrus.Debugf("image '%s' not usable by scanner: %v", v, xerr)
+	}
+	return nil, fail.NotFoundError("none of the images '%s' has been found in tenant '%s'", strings.Join(candidates, "', '"), svc.GetName())
+}
+
+// selectTemplates returns the templates to scan, and registers in report the ones skipped
+func (handler *scannerHandler) selectTemplates(db *scannerdb.Store, req ScanRequest, report *ScanReport) ([]abstract.HostTemplate, fail.Error) {
+	svc := handler.job.GetService()
+	templates, xerr := svc.ListTemplates(true)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	var subset []*regexp.Regexp
+	for _, v := range req.Templates {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fail.SyntaxError("invalid template regexp '%s': %s", v, err.Error())
 		}
+		subset = append(subset, re)
+	}
 
-		defer func() {
-			logrus.Infof("Trying to delete host '%s' with ID '%s'", hostName, host.GetID())
-			derr := host.Delete(task)
-			if derr != nil {
-				logrus.Warnf("Error deleting host '%s'", hostName)
+	var out []abstract.HostTemplate
+	for _, v := range templates {
+		if !matchAnyRegexp(subset, v.Name) {
+			continue
+		}
+		if !req.Force {
+			if _, xerr = db.Read(v.Name); xerr == nil {
+				report.Skipped[v.Name] = "already scanned"
+				continue
 			}
-		}()
-
-		// sshSvc := handlers.NewSSHHandler(job)
-		// ssh, err := sshSvc.GetConfig(host.GetID())
-		// if err != nil {
-		// 	logrus.Warnf("template [%s] host '%s': error reading SSHConfig: %v", template.GetName, hostName, err.Error())
-		// 	return err
-		// }
-		// _, nerr := ssh.WaitServerReady(job.GetTask(), "ready", time.Duration(6+concurrency-1)*time.Minute)
-		// if nerr != nil {
-		// 	logrus.Warnf("template [%s]: Error waiting for server ready: %v", template.GetName, nerr)
-		// 	return nerr
-		// }
-
-		// c, err := ssh.NewCommand(job.GetTask(), cmd)
-		// if err != nil {
-		// 	logrus.Warnf("template [%s]: Problem creating ssh command: %v", template.GetName, err)
-		// 	return err
-		// }
-		// _, cout, _, err := c.RunWithTimeout(nil, outputs.COLLECT, 8*time.Minute) // FIXME Hardcoded timeout
-		// if err != nil {
-		// 	logrus.Warnf("template [%s]: Problem running ssh command: %v", template.GetName, err)
-		// 	return err
-		// }
-		_, cout, _, xerr := host.Run(task, cmd, outputs.COLLECT, temporal.GetConnectionTimeout(), 8*time.Minute) // FIXME: hardcoded timeout
-		if xerr != nil {
-			return fail.Wrap(xerr, "template [%s] host '%s': failed to run collection script", template.Name, hostName)
 		}
-		daCPU, xerr := createCPUInfo(cout)
-		if xerr != nil {
-			return fail.Wrap(xerr, "template [%s]: Problem building cpu info", template.Name)
+		if req.Budget > 0 {
+			price, ok := svc.GetTemplatePrice(v.Name)
+			if !ok {
+				report.Skipped[v.Name] = "price unknown, cannot honor budget"
+				continue
+			}
+			if report.Cost+price > req.Budget {
+				report.Skipped[v.Name] = "budget exceeded"
+				continue
+			}
+			report.Cost += price
 		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func matchAnyRegexp(list []*regexp.Regexp, name string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, re := range list {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
 
-		daCPU.TemplateName = template.Name
-		daCPU.TemplateID = template.ID
-		daCPU.ImageID = img.ID
-		daCPU.ImageName = img.Name
-		daCPU.TenantName = tenantName
-		daCPU.LastUpdated = time.Now().Format(time.RFC850)
+// probe creates a host with template, runs the probe script on it and stores the result in database
+// The probe host is journaled in database with the identifier of the scan until its deletion, to be removed by the
+// next scan if the daemon is interrupted
+func (handler *scannerHandler) probe(db *scannerdb.Store, scanID string, img abstract.Image, template abstract.HostTemplate, netSpeedURL string) (_ *abstract.StoredCPUInfo, xerr fail.Error) {
+	svc := handler.job.GetService()
+	task := handler.job.GetTask()
+	hostName := probeHostPrefix + template.Name
 
-		daOut, err := json.MarshalIndent(daCPU, "", "\t")
-		if err != nil {
-			logrus.Warnf("tenant '%s', template '%s' : Problem marshaling json data: %v", tenantName, template.Name, err)
-			return fail.ToError(err)
+	logrus.Infof("Checking template %s", template.Name)
+
+	host, xerr := hostfactory.New(svc)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	xerr = db.RecordProbe(scannerdb.Probe{HostName: hostName, TemplateName: template.Name, Created: time.Now().Format(time.RFC3339), ScanID: scanID})
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	req := abstract.HostRequest{
+		ResourceName: hostName,
+		PublicIP:     false,
+		TemplateID:   template.ID,
+		ImageID:      img.ID,
+	}
+	def := abstract.HostSizingRequirements{
+		Image: img.Name,
+	}
+	if _, xerr = host.Create(task, req, def); xerr != nil {
+		if derr := handler.deleteProbe(db, hostName); derr != nil {
+			_ = xerr.AddConsequence(derr)
 		}
+		return nil, fail.Wrap(xerr, "template [%s] host '%s': error creation", template.Name, hostName)
+	}
 
-		nerr := ioutil.WriteFile(utils.AbsPathify("$HOME/.safescale/scanner/"+tenantName+"#"+template.Name+".json"), daOut, 0666)
-		if nerr != nil {
-			logrus.Warnf("tenant '%s', template '%s' : Error writing file: %v", tenantName, template.Name, nerr)
-			return fail.ToError(nerr)
+	defer func() {
+		if derr := handler.deleteProbe(db, hostName); derr != nil {
+			logrus.Warnf("Error deleting host '%s': %v", hostName, derr)
 		}
-		logrus.Infof("tenant '%s', template '%s': Stored in file: %s", tenantName, template.Name, "$HOME/.safescale/scanner/"+tenantName+"#"+template.Name+".json")
-		//} else {
-		//	return fail.NewError("no gateway network")
-		//}
+	}()
 
-		return nil
+	_, cout, _, xerr := host.Run(task, probeScript(netSpeedURL), outputs.COLLECT, temporal.GetConnectionTimeout(), temporal.GetLongOperationTimeout())
+	if xerr != nil {
+		return nil, fail.Wrap(xerr, "template [%s] host '%s': failed to run probe script", template.Name, hostName)
+	}
+	info, xerr := parseProbeOutput(cout, netSpeedURL != "")
+	if xerr != nil {
+		return nil, fail.Wrap(xerr, "template [%s]: Problem building cpu info", template.Name)
 	}
 
-	wg.Add(len(templates))
+	info.TemplateName = template.Name
+	info.TemplateID = template.ID
+	info.ImageID = img.ID
+	info.ImageName = img.Name
+	info.TenantName = svc.GetName()
+	info.LastUpdated = time.Now().Format(time.RFC850)
+	info.ID = img.ID
+	if price, ok := svc.GetTemplatePrice(template.Name); ok {
+		info.PricePerHour = price
+	}
+	for k, v := range info.Errors {
+		logrus.Warnf("tenant '%s', template '%s': failed to collect '%s': %s", info.TenantName, template.Name, k, v)
+	}
 
-	for _, target := range templates {
-		sem <- true
-		localTarget := target
-		go func(inner abstract.HostTemplate) {
-			defer func() { <-sem }()
-			lerr := hostAnalysis(inner)
-			if lerr != nil {
-				logrus.Warnf("Error running scanner: %+v", lerr)
-			}
-		}(localTarget)
+	if xerr = db.Write(*info); xerr != nil {
+		return nil, xerr
 	}
+	logrus.Infof("tenant '%s', template '%s': scanned data stored", info.TenantName, template.Name)
+	return info, nil
+}
 
-	for i := 0; i < cap(sem); i++ {
-		sem <- true
+// deleteProbe deletes the probe host named 'hostName' if it exists, then removes it from journal
+func (handler *scannerHandler) deleteProbe(db *scannerdb.Store, hostName string) fail.Error {
+	task := handler.job.GetTask()
+	logrus.Infof("Trying to delete probe host '%s'", hostName)
+	host, xerr := hostfactory.Load(task, handler.job.GetService(), hostName)
+	if xerr != nil {
+		if _, ok := xerr.(*fail.ErrNotFound); !ok {
+			return xerr
+		}
+	} else if xerr = host.Delete(task); xerr != nil {
+		return xerr
 	}
+	return db.ForgetProbe(hostName)
+}
 
-	wg.Wait()
+// purgeProbes deletes the probe hosts remaining in journal; the ones of the running scan of the tenant are kept unless
+// 'all' is true
+func (handler *scannerHandler) purgeProbes(db *scannerdb.Store, all bool) fail.Error {
+	probes, xerr := db.ListProbes()
+	if xerr != nil {
+		return xerr
+	}
+	tenant := handler.job.GetService().GetName()
+	for _, v := range probes {
+		if !all && !isStaleProbe(tenant, v) {
+			continue
+		}
+		if xerr = handler.deleteProbe(db, v.HostName); xerr != nil {
+			return fail.Wrap(xerr, "failed to delete probe host '%s' left by a previous scan", v.HostName)
+		}
+	}
+	return nil
+}
 
+// PurgeScanProbes deletes the probe hosts left by interrupted scans on every scannable tenant, and those of the running
+// scans if 'all' is true
+// Called when safescaled starts (without 'all', scans may already run) and stops (with 'all', the running scans being
+// interrupted), so that the probe hosts do not wait for the next scan to be removed
+func PurgeScanProbes(all bool) fail.Error {
+	tenants, xerr := iaas.GetTenantNames()
+	if xerr != nil {
+		return xerr
+	}
+
+	var errors []error
+	for tenant := range tenants {
+		if xerr := purgeTenantScanProbes(tenant, all); xerr != nil {
+			errors = append(errors, fail.Wrap(xerr, "failed to purge probe hosts of tenant '%s'", tenant))
+		}
+	}
+	if len(errors) > 0 {
+		return fail.NewErrorList(errors)
+	}
 	return nil
 }
 
+// purgeTenantScanProbes deletes the probe hosts left by interrupted scans on the tenant, if it is scannable
+func purgeTenantScanProbes(tenant string, all bool) fail.Error {
+	svc, xerr := iaas.UseService(tenant)
+	if xerr != nil {
+		return xerr
+	}
+	if !svc.GetScannerConfig().Scannable {
+		return nil
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return fail.ToError(err)
+	}
+	ctx := metadata.NewIncomingContext(events.ContextWithTenant(context.Background(), tenant), metadata.Pairs("uuid", id.String()))
+	ctx, cancel := context.WithCancel(ctx)
+	job, xerr := server.NewJob(ctx, cancel, svc, "purge of scanner probe hosts")
+	if xerr != nil {
+		cancel()
+		return xerr
+	}
+	defer job.Close()
+
+	handler := &scannerHandler{job: job}
+	db, _, xerr := handler.openDB()
+	if xerr != nil {
+		return xerr
+	}
+	return handler.purgeProbes(db, all)
+}
+
 func (handler *scannerHandler) dumpTemplates() (xerr fail.Error) {
 	err := os.MkdirAll(utils.AbsPathify("$HOME/.safescale/scanner"), 0777)
 	if err != nil {
@@ -404,7 +636,7 @@ func (handler *scannerHandler) dumpTemplates() (xerr fail.Error) {
 	content, err := json.Marshal(TemplateList{
 		Templates: templates,
 	})
-	if xerr != nil {
+	if err != nil {
 		return fail.ToError(err)
 	}
 
@@ -450,140 +682,96 @@ func (handler *scannerHandler) dumpImages() (xerr fail.Error) {
 	return nil
 }
 
-func createCPUInfo(output string) (_ *CPUInfo, xerr fail.Error) {
-	str := strings.TrimSpace(output)
-
-	tokens := strings.Split(str, "î")
-	if len(tokens) < 9 {
-		return nil, fail.SyntaxError("parsing error: '%s'", str)
-	}
-	info := CPUInfo{}
-	var err error
-	if info.NumberOfCPU, err = strconv.Atoi(tokens[0]); err != nil {
-		return nil, fail.SyntaxError("parsing error: NumberOfCPU='%s' (from '%s')", tokens[0], str)
-	}
-	if info.NumberOfCore, err = strconv.Atoi(tokens[1]); err != nil {
-		return nil, fail.SyntaxError("parsing error: NumberOfCore='%s' (from '%s')", tokens[1], str)
-	}
-	if info.NumberOfSocket, err = strconv.Atoi(tokens[2]); err != nil {
-		return nil, fail.SyntaxError("parsing error: NumberOfSocket='%s' (from '%s')", tokens[2], str)
-	}
-	info.NumberOfCore *= info.NumberOfSocket
-	if info.CPUFrequency, err = strconv.ParseFloat(tokens[3], 64); err != nil {
-		return nil, fail.SyntaxError("parsing error: CpuFrequency='%s' (from '%s')", tokens[3], str)
-	}
-	info.CPUFrequency = math.Floor(info.CPUFrequency*100) / 100000
-
-	info.CPUArch = tokens[4]
-	info.Hypervisor = tokens[5]
-	info.CPUModel = tokens[6]
-	if info.RAMSize, err = strconv.ParseFloat(tokens[7], 64); err != nil {
-		return nil, fail.SyntaxError("parsing error: RAMSize='%s' (from '%s')", tokens[7], str)
-	}
-
-	memInGb := info.RAMSize / 1024 / 1024
-	info.RAMSize = math.Floor(memInGb*100) / 100
-	if info.RAMFreq, err = strconv.ParseFloat(tokens[8], 64); err != nil {
-		info.RAMFreq = 0
-	}
-	gpuTokens := strings.Split(tokens[9], "%")
-	nb := len(gpuTokens)
-	if nb > 1 {
-		info.GPUModel = strings.TrimSpace(gpuTokens[0])
-		info.GPU = nb - 1
-	}
-
-	if info.DiskSize, err = strconv.ParseInt(tokens[10], 10, 64); err != nil {
-		info.DiskSize = 0
-	}
-	info.DiskSize = info.DiskSize / 1024 / 1024 / 1024
-
-	if info.EphDiskSize, err = strconv.ParseInt(tokens[11], 10, 64); err != nil {
-		info.EphDiskSize = 0
-	}
-	info.EphDiskSize = info.EphDiskSize / 1024 / 1024 / 1024
+// probeValues gives access to the values output by the probe script, registering in errors the reason of
+// a failure to parse a value
+type probeValues struct {
+	values map[string]string
+	errors map[string]string
+}
 
-	if info.MainDiskSpeed, err = strconv.ParseFloat(tokens[12], 64); err != nil {
-		info.MainDiskSpeed = 0
+// text returns the value of key
+func (p probeValues) text(key string) string {
+	v, ok := p.values[key]
+	if !ok {
+		p.errors[key] = "not probed"
 	}
+	return v
+}
 
-	rotational, err := strconv.ParseInt(tokens[13], 10, 64)
-	if err != nil {
-		info.MainDiskType = ""
-	} else {
-		if rotational == 1 {
-			info.MainDiskType = "HDD"
-		} else {
-			info.MainDiskType = "SSD"
+// number returns the value of key as a float; an empty value is valid only if 'optional' is true
+func (p probeValues) number(key string, optional bool) float64 {
+	v, ok := p.values[key]
+	switch {
+	case !ok:
+		p.errors[key] = "not probed"
+		return 0
+	case v == "":
+		if !optional {
+			p.errors[key] = "no value"
 		}
+		return 0
 	}
-
-	nsp, err := strconv.ParseFloat(tokens[14], 64)
+	out, err := strconv.ParseFloat(v, 64)
 	if err != nil {
-		info.SampleNetSpeed = 0
-	} else {
-		info.SampleNetSpeed = nsp / 1000 / 8
+		p.errors[key] = fmt.Sprintf("invalid value '%s'", v)
+		return 0
 	}
-
-	info.PricePerHour = 0
-
-	return &info, nil
+	return out
 }
 
-func (handler *scannerHandler) collect() (xerr fail.Error) {
-	svc := handler.job.GetService()
-
-	authOpts, xerr := svc.GetAuthenticationOptions()
-	if xerr != nil {
-		return xerr
+// parseProbeOutput builds the scanned data from the output of the probe script
+// The fields that cannot be collected are left empty, with the reason registered in field Errors; the network speed is
+// expected only if the probe script measured it ('netSpeedProbed')
+func parseProbeOutput(output string, netSpeedProbed bool) (*abstract.StoredCPUInfo, fail.Error) {
+	values := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(parts) == 2 {
+			values[parts[0]] = strings.TrimSpace(parts[1])
+		}
 	}
-	region, ok := authOpts.Get("Region")
-	if !ok {
-		return fail.InvalidRequestError("'Region' not set in tenant 'compute' section")
+	if len(values) == 0 {
+		return nil, fail.SyntaxError("parsing error: no probe value in '%s'", output)
 	}
 
-	folder := fmt.Sprintf("images/%s/%s", svc.GetName(), region)
+	info := abstract.StoredCPUInfo{Errors: map[string]string{}}
+	p := probeValues{values: values, errors: info.Errors}
 
-	if err := os.MkdirAll(utils.AbsPathify("$HOME/.safescale/scanner"), 0777); err != nil {
-		return fail.ToError(err)
-	}
+	info.NumberOfCPU = int(p.number("number_of_cpu", false))
+	info.NumberOfSocket = int(p.number("number_of_socket", false))
+	info.NumberOfCore = int(p.number("number_of_core", false)) * info.NumberOfSocket
+	info.CPUFrequency = math.Floor(p.number("cpu_frequency_Ghz", false)*100) / 100000
+	info.CPUArch = p.text("cpu_arch")
+	info.Hypervisor = p.text("hypervisor")
+	info.CPUModel = p.text("cpu_model")
+	info.RAMSize = math.Floor(p.number("ram_size_Gb", false)/1024/1024*100) / 100
+	info.RAMFreq = p.number("ram_freq", true)
 
-	db, err := scribble.New(utils.AbsPathify("$HOME/.safescale/scanner/db"), nil)
-	if err != nil {
-		return fail.ToError(err)
+	if gpus := p.text("gpu"); gpus != "" {
+		gpuTokens := strings.Split(gpus, "%")
+		info.GPUModel = strings.TrimSpace(gpuTokens[0])
+		info.GPU = len(gpuTokens) - 1
 	}
 
-	files, err := ioutil.ReadDir(utils.AbsPathify("$HOME/.safescale/scanner"))
-	if err != nil {
-		return fail.ToError(err)
+	info.DiskSize = int64(p.number("disk_size_Gb", false)) / 1024 / 1024 / 1024
+	info.EphDiskSize = int64(p.number("eph_disk_size_Gb", true)) / 1024 / 1024 / 1024
+	info.MainDiskSpeed = p.number("main_disk_speed_MBps", false)
+	switch rotational := p.text("main_disk_type"); rotational {
+	case "1":
+		info.MainDiskType = "HDD"
+	case "0":
+		info.MainDiskType = "SSD"
+	case "":
+	default:
+		info.Errors["main_disk_type"] = fmt.Sprintf("invalid value '%s'", rotational)
+	}
+	if netSpeedProbed {
+		// curl reports the speed in bytes/s
+		info.SampleNetSpeed = p.number("sample_net_speed_KBps", false) / 1e6
 	}
 
-	for _, file := range files {
-		acpu := StoredCPUInfo{}
-		theFile := utils.AbsPathify(fmt.Sprintf("$HOME/.safescale/scanner/%s", file.Name()))
-		if strings.Contains(file.Name(), svc.GetName()+"#") {
-			logrus.Infof("Storing: %s", file.Name())
-
-			byteValue, err := ioutil.ReadFile(theFile)
-			if err != nil {
-				return fail.ToError(err)
-			}
-
-			if err = json.Unmarshal(byteValue, &acpu); err != nil {
-				return fail.ToError(err)
-			}
-
-			acpu.ID = acpu.ImageID
-
-			if err = db.Write(folder, acpu.TemplateName, acpu); err != nil {
-				return fail.ToError(err)
-			}
-		}
-		if !file.IsDir() {
-			if err = os.Remove(theFile); err != nil {
-				logrus.Infof("Error Supressing %s : %s", file.Name(), err.Error())
-			}
-		}
+	if len(info.Errors) == 0 {
+		info.Errors = nil
 	}
-	return nil
+	return &info, nil
 }
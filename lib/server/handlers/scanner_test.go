@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/server/iaas/scannerdb"
+)
+
+func TestProbeScript(t *testing.T) {
+	script := probeScript("")
+	assert.False(t, strings.Contains(script, "sample_net_speed_KBps"))
+	assert.False(t, strings.Contains(script, "google"))
+
+	script = probeScript("http://mirror.example.org/10MB.bin")
+	assert.True(t, strings.Contains(script, "echo \"sample_net_speed_KBps=$(curl -L -s -o /dev/null -w '%{speed_download}' 'http://mirror.example.org/10MB.bin'"))
+}
+
+func TestParseProbeOutput(t *testing.T) {
+	output := `number_of_cpu=8
+number_of_core=2
+number_of_socket=2
+cpu_frequency_Ghz=2394.454
+cpu_arch=x86_64
+hypervisor=KVM
+cpu_model=Intel Core Processor (Haswell, no TSX)
+ram_size_Gb=32939560
+ram_freq=
+gpu=GV100GL [Tesla V100 PCIe 16GB] (rev a1)%
+disk_size_Gb=53687091200
+eph_disk_size_Gb=0
+main_disk_speed_MBps=
+main_disk_type=0
+sample_net_speed_KBps=11534336`
+
+	info, xerr := parseProbeOutput(output, true)
+	require.Nil(t, xerr)
+	assert.Equal(t, 8, info.NumberOfCPU)
+	assert.Equal(t, 4, info.NumberOfCore)
+	assert.Equal(t, 2.39445, info.CPUFrequency)
+	assert.Equal(t, "Intel Core Processor (Haswell, no TSX)", info.CPUModel)
+	assert.Equal(t, 31.41, info.RAMSize)
+	assert.Equal(t, 0.0, info.RAMFreq)
+	assert.Equal(t, 1, info.GPU)
+	assert.Equal(t, "GV100GL [Tesla V100 PCIe 16GB] (rev a1)", info.GPUModel)
+	assert.Equal(t, int64(50), info.DiskSize)
+	assert.Equal(t, "SSD", info.MainDiskType)
+	assert.InDelta(t, 11.534336, info.SampleNetSpeed, 1e-9)
+	assert.Equal(t, map[string]string{"main_disk_speed_MBps": "no value"}, info.Errors)
+}
+
+func TestParseProbeOutputWithErrors(t *testing.T) {
+	output := `number_of_cpu=two
+number_of_core=1
+number_of_socket=1
+cpu_frequency_Ghz=2000
+main_disk_type=2`
+
+	info, xerr := parseProbeOutput(output, true)
+	require.Nil(t, xerr)
+	assert.Equal(t, 1, info.NumberOfCore)
+	assert.Equal(t, 0, info.NumberOfCPU)
+	assert.Equal(t, "invalid value 'two'", info.Errors["number_of_cpu"])
+	assert.Equal(t, "invalid value '2'", info.Errors["main_disk_type"])
+	assert.Equal(t, "not probed", info.Errors["sample_net_speed_KBps"])
+	assert.Equal(t, "not probed", info.Errors["ram_size_Gb"])
+
+	_, xerr = parseProbeOutput("bash: lscpu: command not found", true)
+	assert.NotNil(t, xerr)
+}
+
+func TestParseProbeOutputWithoutNetSpeed(t *testing.T) {
+	// Without NetSpeedURL, the probe script does not measure the network speed: it's not an error
+	output := `number_of_cpu=2
+number_of_core=1
+number_of_socket=2
+cpu_frequency_Ghz=2000
+cpu_arch=x86_64
+hypervisor=KVM
+cpu_model=Intel Core Processor (Haswell, no TSX)
+ram_size_Gb=8232960
+ram_freq=
+gpu=
+disk_size_Gb=53687091200
+eph_disk_size_Gb=0
+main_disk_speed_MBps=412
+main_disk_type=0`
+
+	info, xerr := parseProbeOutput(output, false)
+	require.Nil(t, xerr)
+	assert.Nil(t, info.Errors)
+	assert.Equal(t, 0.0, info.SampleNetSpeed)
+
+	info, xerr = parseProbeOutput(output, true)
+	require.Nil(t, xerr)
+	assert.Equal(t, map[string]string{"sample_net_speed_KBps": "not probed"}, info.Errors)
+}
+
+func TestActiveScans(t *testing.T) {
+	id, xerr := startScan("tenant")
+	require.Nil(t, xerr)
+	_, xerr = startScan("tenant")
+	assert.NotNil(t, xerr)
+
+	assert.False(t, isStaleProbe("tenant", scannerdb.Probe{HostName: "scanhost-s1", ScanID: id}))
+	assert.True(t, isStaleProbe("tenant", scannerdb.Probe{HostName: "scanhost-s1", ScanID: "previous"}))
+	assert.True(t, isStaleProbe("tenant", scannerdb.Probe{HostName: "scanhost-s1"}))
+	assert.True(t, isStaleProbe("other", scannerdb.Probe{HostName: "scanhost-s1", ScanID: id}))
+
+	endScan("tenant")
+	assert.True(t, isStaleProbe("tenant", scannerdb.Probe{HostName: "scanhost-s1", ScanID: id}))
+	_, xerr = startScan("tenant")
+	assert.Nil(t, xerr)
+	endScan("tenant")
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iaas
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/CS-SI/SafeScale/lib/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
+)
+
+const (
+	// EnvCredentialsFile is the name of the environment variable containing the path of the encrypted credentials file
+	EnvCredentialsFile = "SAFESCALE_CREDENTIALS_FILE"
+	// EnvCredentialsPassphrase is the name of the environment variable containing the passphrase of the encrypted credentials file
+	EnvCredentialsPassphrase = "SAFESCALE_CREDENTIALS_PASSPHRASE"
+
+	defaultCredentialsFile = "$HOME/.safescale/credentials.vault"
+)
+
+var (
+	// credentialsSections contains the sections of a tenant that can receive credentials
+	credentialsSections = map[string]bool{
+		"identity":      true,
+		"compute":       true,
+		"network":       true,
+		"objectstorage": true,
+		"metadata":      true,
+	}
+
+	credentials     secret.Store
+	credentialsLock sync.RWMutex
+)
+
+// CredentialsFile returns the path of the encrypted credentials file
+func CredentialsFile() string {
+	path := os.Getenv(EnvCredentialsFile)
+	if path == "" {
+		path = defaultCredentialsFile
+	}
+	return utils.AbsPathify(path)
+}
+
+// UnlockCredentials opens the encrypted credentials file 'path' with the passphrase; the credentials it contains
+// complete the tenants of the tenants file
+func UnlockCredentials(path, passphrase string) fail.Error {
+	secret.Register(passphrase)
+	store, xerr := secret.NewFileStore(path, passphrase)
+	if xerr != nil {
+		return xerr
+	}
+
+	credentialsLock.Lock()
+	defer credentialsLock.Unlock()
+	credentials = store
+	return nil
+}
+
+// SetTenantCredentials stores in the encrypted credentials file the credentials of a tenant
+// values is indexed by '<section>.<keyword>' (ie 'identity.Password'); an empty value removes the credential
+func SetTenantCredentials(tenantName string, values map[string]string) fail.Error {
+	if tenantName == "" {
+		return fail.InvalidParameterError("tenantName", "cannot be empty string")
+	}
+	if len(values) == 0 {
+		return fail.InvalidParameterError("values", "cannot be empty")
+	}
+
+	credentialsLock.RLock()
+	defer credentialsLock.RUnlock()
+	if credentials == nil {
+		return fail.NotAvailableError("the encrypted credentials file is not unlocked (see environment variable %s)", EnvCredentialsPassphrase)
+	}
+
+	names, xerr := GetTenantNames()
+	if xerr != nil {
+		return xerr
+	}
+	if _, ok := names[tenantName]; !ok {
+		return fail.NotFoundError("failed to find tenant '%s' in tenants file", tenantName)
+	}
+
+	for k := range values {
+		if xerr = validateCredentialKey(k); xerr != nil {
+			return xerr
+		}
+	}
+	for k, v := range values {
+		path := tenantName + "/" + k
+		if v == "" {
+			if xerr = credentials.Delete(path); xerr != nil {
+				if _, ok := xerr.(*fail.ErrNotFound); !ok {
+					return xerr
+				}
+			}
+			continue
+		}
+		if xerr = credentials.Set(path, v); xerr != nil {
+			return xerr
+		}
+	}
+	return nil
+}
+
+// validateCredentialKey checks the key of a credential is in the form '<section>.<keyword>'
+func validateCredentialKey(key string) fail.Error {
+	parts := strings.Split(key, ".")
+	if len(parts) != 2 || parts[1] == "" {
+		return fail.SyntaxError("invalid credential '%s': must be in the form '<section>.<keyword>' (ie 'identity.Password')", key)
+	}
+	if !credentialsSections[parts[0]] {
+		return fail.SyntaxError("invalid section '%s' of credential '%s'", parts[0], key)
+	}
+	return nil
+}
+
+// resolveTenantCredentials returns a copy of the tenant where the references to secrets, environment variables or files
+// ({{secret "<path>"}}, {{env "<name>"}}, {{file "<path>"}}) are replaced by their values, completed by the credentials
+// of the encrypted credentials file
+func resolveTenantCredentials(tenantName string, tenant map[string]interface{}) (map[string]interface{}, fail.Error) {
+	resolved, xerr := secret.ResolveMap(tenant)
+	if xerr != nil {
+		return nil, fail.Wrap(xerr, "failed to resolve credentials of tenant '%s'", tenantName)
+	}
+	return applyTenantCredentials(tenantName, resolved)
+}
+
+// applyTenantCredentials returns a copy of the tenant completed by the credentials of the encrypted credentials file
+// The credentials of the file replace the values of the tenants file
+func applyTenantCredentials(tenantName string, tenant map[string]interface{}) (map[string]interface{}, fail.Error) {
+	credentialsLock.RLock()
+	defer credentialsLock.RUnlock()
+	if credentials == nil {
+		return tenant, nil
+	}
+
+	prefix := tenantName + "/"
+	values, xerr := secret.Values(credentials, prefix)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	out := make(map[string]interface{}, len(tenant))
+	for k, v := range tenant {
+		out[k] = v
+	}
+	for path, value := range values {
+		parts := strings.SplitN(strings.TrimPrefix(path, prefix), ".", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		secret.Register(value)
+
+		section := map[string]interface{}{}
+		if current, ok := out[parts[0]].(map[string]interface{}); ok {
+			for k, v := range current {
+				section[k] = v
+			}
+		}
+		section[parts[1]] = value
+		out[parts[0]] = section
+	}
+	return out, nil
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iaas
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+func TestTenantCredentials(t *testing.T) {
+	// the tenants file is looked for in $HOME/.safescale
+	dir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, ".safescale"), 0700))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, ".safescale", "tenants.toml"), []byte("[[tenants]]\nclient = \"ovh\"\nname = \"TestCredentials\"\n"), 0600))
+	home := os.Getenv("HOME")
+	require.Nil(t, os.Setenv("HOME", dir))
+	defer func() { _ = os.Setenv("HOME", home) }()
+	defer func() { credentials = nil }()
+
+	xerr := SetTenantCredentials("TestCredentials", map[string]string{"identity.Password": "x"})
+	assert.IsType(t, &fail.ErrNotAvailable{}, xerr)
+
+	require.Nil(t, UnlockCredentials(filepath.Join(dir, "credentials.vault"), "passphrase"))
+
+	xerr = SetTenantCredentials("Unknown", map[string]string{"identity.Password": "x"})
+	assert.IsType(t, &fail.ErrNotFound{}, xerr)
+	xerr = SetTenantCredentials("TestCredentials", map[string]string{"Password": "x"})
+	assert.IsType(t, &fail.ErrSyntax{}, xerr)
+	xerr = SetTenantCredentials("TestCredentials", map[string]string{"unknown.Password": "x"})
+	assert.IsType(t, &fail.ErrSyntax{}, xerr)
+
+	require.Nil(t, SetTenantCredentials("TestCredentials", map[string]string{
+		"identity.OpenstackPassword": "0vhPa55",
+		"objectstorage.SecretKey":    "S3cr3tK3y",
+		"compute.Region":             "GRA5",
+	}))
+	require.Nil(t, SetTenantCredentials("TestCredentials", map[string]string{"compute.Region": ""}))
+
+	require.Nil(t, os.Setenv("SAFESCALE_TEST_OPENSTACK_ID", "user"))
+	defer func() { _ = os.Unsetenv("SAFESCALE_TEST_OPENSTACK_ID") }()
+	tenant := map[string]interface{}{
+		"name":     "TestCredentials",
+		"identity": map[string]interface{}{"OpenstackID": `{{env "SAFESCALE_TEST_OPENSTACK_ID"}}`, "OpenstackPassword": "in clear"},
+		"compute":  map[string]interface{}{"Region": "SBG5"},
+	}
+	out, xerr := resolveTenantCredentials("TestCredentials", tenant)
+	require.Nil(t, xerr)
+	assert.Equal(t, map[string]interface{}{"OpenstackID": "user", "OpenstackPassword": "0vhPa55"}, out["identity"])
+	assert.Equal(t, map[string]interface{}{"SecretKey": "S3cr3tK3y"}, out["objectstorage"])
+	assert.Equal(t, map[string]interface{}{"Region": "SBG5"}, out["compute"])
+	assert.Equal(t, "in clear", tenant["identity"].(map[string]interface{})["OpenstackPassword"])
+
+	// the credentials are kept only in the encrypted file
+	content, err := ioutil.ReadFile(filepath.Join(dir, "credentials.vault"))
+	require.Nil(t, err)
+	assert.NotContains(t, string(content), "0vhPa55")
+}
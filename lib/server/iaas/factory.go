@@ -20,8 +20,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"regexp/syntax"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -30,6 +32,7 @@ import (
 	"github.com/CS-SI/SafeScale/lib/server/iaas/providers"
 	"github.com/CS-SI/SafeScale/lib/server/iaas/stacks"
 	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/templateselection"
 	"github.com/CS-SI/SafeScale/lib/utils/crypt"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
 )
@@ -37,6 +40,7 @@ import (
 var (
 	allProviders = map[string]Service{}
 	allTenants   = map[string]string{}
+	tenantsLock  sync.RWMutex
 )
 
 // Register a Client referenced by the provider name. Ex: "ovh", ovh.New()
@@ -54,6 +58,8 @@ func Register(name string, provider providers.Provider) {
 // GetTenantNames returns all known tenants names
 func GetTenantNames() (map[string]string, fail.Error) {
 	err := loadConfig()
+	tenantsLock.RLock()
+	defer tenantsLock.RUnlock()
 	return allTenants, err
 }
 
@@ -97,6 +103,12 @@ func UseService(tenantName string) (newService Service, xerr fail.Error) {
 		}
 
 		tenantInCfg = true
+
+		tenant, xerr = resolveTenantCredentials(tenantName, tenant)
+		if xerr != nil {
+			return NullService(), xerr
+		}
+
 		provider, found = tenant["provider"].(string)
 		if !found {
 			provider, found = tenant["client"].(string)
@@ -236,7 +248,20 @@ func UseService(tenantName string) (newService Service, xerr fail.Error) {
 			metadataBucket: metadataBucket,
 			metadataKey:    metadataCryptKey,
 		}
-		return newS, validateRegexps(newS /*tenantClient*/, tenant)
+		if xerr = validateRegexps(newS /*tenantClient*/, tenant); xerr != nil {
+			return newS, xerr
+		}
+		if xerr = validateTemplateSelection(newS, tenant); xerr != nil {
+			return newS, xerr
+		}
+		if xerr = validateScannerConfig(newS, tenant); xerr != nil {
+			return newS, xerr
+		}
+		if xerr = validateRateLimits(newS, tenant); xerr != nil {
+			return newS, xerr
+		}
+		newS.Provider = providers.NewRateLimitedProvider(providerInstance, tenantName, newS.rateLimits)
+		return newS, validateQuotas(newS, tenant)
 	}
 
 	if !tenantInCfg {
@@ -279,14 +304,52 @@ func validateRegexps(svc *service, tenant map[string]interface{}) fail.Error {
 	return nil
 }
 
+// validateTemplateSelection validates the template selection policy and the template prices from tenants file
+func validateTemplateSelection(svc *service, tenant map[string]interface{}) fail.Error {
+	compute, ok := tenant["compute"].(map[string]interface{})
+	if !ok {
+		return fail.InvalidParameterError("tenant['compute']", "is not a map")
+	}
+
+	if anon, ok := compute["TemplateSelectionPolicy"]; ok {
+		str, ok := anon.(string)
+		if !ok {
+			return fail.SyntaxError("invalid value for keyword 'TemplateSelectionPolicy': must be a string")
+		}
+		policy, xerr := templateselection.Parse(str)
+		if xerr != nil {
+			return fail.SyntaxError("invalid value '%s' for keyword 'TemplateSelectionPolicy': must be 'drf', 'cheapest', 'fastest' or 'best-value'", str)
+		}
+		svc.templateSelection = policy
+	}
+
+	if anon, ok := compute["TemplatePrices"]; ok {
+		list, ok := anon.(map[string]interface{})
+		if !ok {
+			return fail.SyntaxError("invalid value for keyword 'TemplatePrices': must be a table of prices per hour indexed by template name")
+		}
+		svc.templatePrices = make(map[string]float64, len(list))
+		for k, v := range list {
+			price, ok := numberOfKeyword(v)
+			if !ok {
+				return fail.SyntaxError("invalid price '%v' of template '%s' in keyword 'TemplatePrices': must be a number", v, k)
+			}
+			svc.templatePrices[k] = price
+		}
+	}
+
+	return nil
+}
+
 // validateRegexpsOfKeyword reads the content of the keyword passed as parameter and returns an array of compiled regexps
+// The errors do not repeat the invalid values, which may come from references to secrets
 func validateRegexpsOfKeyword(keyword string, content interface{}) (out []*regexp.Regexp, _ fail.Error) {
 	var emptySlice []*regexp.Regexp
 
 	if str, ok := content.(string); ok {
 		re, err := regexp.Compile(str)
 		if err != nil {
-			return emptySlice, fail.SyntaxError("invalid value '%s' for keyword '%s': %s", str, keyword, err.Error())
+			return emptySlice, fail.SyntaxError("invalid value for keyword '%s': %s", keyword, regexpErrorReason(err))
 		}
 		out = append(out, re)
 		return out, nil
@@ -294,9 +357,13 @@ func validateRegexpsOfKeyword(keyword string, content interface{}) (out []*regex
 
 	if list, ok := content.([]interface{}); ok {
 		for _, v := range list {
-			re, err := regexp.Compile(v.(string))
+			str, ok := v.(string)
+			if !ok {
+				return emptySlice, fail.SyntaxError("invalid value for keyword '%s': must be a string or a list of strings", keyword)
+			}
+			re, err := regexp.Compile(str)
 			if err != nil {
-				return emptySlice, fail.SyntaxError("invalid value '%s' for keyword '%s': %s", v, keyword, err.Error())
+				return emptySlice, fail.SyntaxError("invalid value for keyword '%s': %s", keyword, regexpErrorReason(err))
 			}
 			out = append(out, re)
 		}
@@ -306,6 +373,14 @@ func validateRegexpsOfKeyword(keyword string, content interface{}) (out []*regex
 	return out, nil
 }
 
+// regexpErrorReason returns the reason of the failure of the compilation of a regexp, without the regexp itself
+func regexpErrorReason(err error) string {
+	if serr, ok := err.(*syntax.Error); ok {
+		return string(serr.Code)
+	}
+	return "invalid regular expression"
+}
+
 // initObjectStorageLocationConfig initializes objectstorage.Config struct with map
 func initObjectStorageLocationConfig(authOpts providers.Config, tenant map[string]interface{}) (objectstorage.Config, fail.Error) {
 	var (
@@ -599,11 +674,13 @@ func loadConfig() fail.Error {
 	if err != nil {
 		return err
 	}
+	// the list is rebuilt from scratch, tenants removed from the file must disappear
+	tenants := make(map[string]string, len(tenantsCfg))
 	for _, t := range tenantsCfg {
 		tenant, _ := t.(map[string]interface{})
 		if name, ok := tenant["name"].(string); ok {
 			if provider, ok := tenant["client"].(string); ok {
-				allTenants[name] = provider
+				tenants[name] = provider
 			} else {
 				return fail.SyntaxError("invalid configuration file '%s'. Tenant '%s' has no client type", v.ConfigFileUsed(), name)
 			}
@@ -611,6 +688,10 @@ func loadConfig() fail.Error {
 			return fail.SyntaxError("invalid configuration file. A tenant has no 'name' entry in '%s'", v.ConfigFileUsed())
 		}
 	}
+
+	tenantsLock.Lock()
+	defer tenantsLock.Unlock()
+	allTenants = tenants
 	return nil
 }
 
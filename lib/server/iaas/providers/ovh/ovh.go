@@ -384,6 +384,18 @@ func (p provider) GetTenantParameters() map[string]interface{} {
 	return p.tenantParameters
 }
 
+// GetProviderQuotas returns the quotas of the account reported by the stack
+// satisfies interface providers.QuotaReporter
+func (p provider) GetProviderQuotas() (abstract.ProviderQuotas, fail.Error) {
+	if p.IsNull() {
+		return nil, fail.InvalidInstanceError()
+	}
+	if reporter, ok := p.Stack.(providers.QuotaReporter); ok {
+		return reporter.GetProviderQuotas()
+	}
+	return nil, fail.NotImplementedError("quotas of the provider are not available")
+}
+
 // GetCapabilities returns the capabilities of the provider
 func (p provider) GetCapabilities() providers.Capabilities {
 	return providers.Capabilities{
@@ -53,3 +53,9 @@ type Provider interface {
 	// GetTenantParameters returns the tenant parameters as read
 	GetTenantParameters() map[string]interface{}
 }
+
+// QuotaReporter is implemented by the providers able to report the quotas set on the account by the provider
+type QuotaReporter interface {
+	// GetProviderQuotas returns the quotas of the account, for the kinds of resources the provider can report
+	GetProviderQuotas() (abstract.ProviderQuotas, fail.Error)
+}
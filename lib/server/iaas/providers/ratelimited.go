@@ -0,0 +1,647 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package providers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/CS-SI/SafeScale/lib/server/iaas/stacks"
+	"github.com/CS-SI/SafeScale/lib/server/iaas/userdata"
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/hoststate"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/metrics"
+	"github.com/CS-SI/SafeScale/lib/utils/telemetry"
+	"github.com/CS-SI/SafeScale/lib/utils/temporal"
+)
+
+var (
+	providerCalls        = metrics.NewCounterVec("safescale_provider_api_calls_total", "Number of calls to the provider APIs, by provider, method and kind of error ('' on success)", "provider", "method", "error_kind")
+	providerCallDuration = metrics.NewHistogramVec("safescale_provider_api_call_duration_seconds", "Duration of the calls to the provider APIs, by provider and method", nil, "provider", "method")
+
+	// tenantLimiters contains the limiters of the calls of each tenant, indexed by label; they are shared by the
+	// RateLimitedProvider of the tenant, so that the rates and pauses survive the recreation of its service
+	tenantLimiters     = map[string]*[operationClassCount]*classLimiter{}
+	tenantLimitersLock sync.Mutex
+)
+
+// useLimiters returns the limiters of the calls of the tenant 'label', created on first use; the limits of existing
+// limiters are updated if they changed
+func useLimiters(label string, limits RateLimits) [operationClassCount]*classLimiter {
+	tenantLimitersLock.Lock()
+	defer tenantLimitersLock.Unlock()
+
+	limiters, ok := tenantLimiters[label]
+	if !ok {
+		limiters = &[operationClassCount]*classLimiter{}
+		for i := range limiters {
+			limiters[i] = newClassLimiter(OperationClass(i), limits)
+		}
+		tenantLimiters[label] = limiters
+		return *limiters
+	}
+	for _, l := range limiters {
+		l.reconfigure(limits)
+	}
+	return *limiters
+}
+
+// RateLimitedProvider is a Provider limiting the rate of the calls to the API of the provider it wraps, by class of
+// operations, and suspending the calls of a class when the provider keeps on throttling them
+// Throttling is reported by the stacks with *fail.ErrThrottled, which is also the error returned when a call is not
+// done because of the limits; RetryableRemoteCall does not retry them inside the stacks, the limiter pausing the calls of
+// the class instead. The throttled Read operations are retried at this level after the pause, until the communication
+// timeout; the other operations, made of several calls to the API, are not idempotent and are not retried
+// The calls are made on behalf of the context set with WithContext: its cancellation interrupts the waits for the limits,
+// and the spans of the calls are children of its span
+type RateLimitedProvider struct {
+	Provider
+	Label    string
+	name     string
+	ctx      context.Context
+	limiters [operationClassCount]*classLimiter
+}
+
+// NewRateLimitedProvider wraps the provider 'inner' in a RateLimitedProvider applying 'limits' to the calls of the tenant
+// 'label'; the limiters are shared with the other instances of the tenant, 'limits' replacing their settings
+func NewRateLimitedProvider(inner Provider, label string, limits RateLimits) *RateLimitedProvider {
+	return &RateLimitedProvider{
+		Provider: inner,
+		Label:    label,
+		name:     inner.GetName(),
+		ctx:      context.Background(),
+		limiters: useLimiters(label, limits),
+	}
+}
+
+// IsNull tells if the instance is a null value
+func (p *RateLimitedProvider) IsNull() bool {
+	return p == nil || p.Provider == nil || p.Provider.IsNull()
+}
+
+// WithContext returns a copy of the instance making its calls on behalf of 'ctx', sharing the limits of the original
+func (p *RateLimitedProvider) WithContext(ctx context.Context) *RateLimitedProvider {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	out := *p
+	out.ctx = ctx
+	return &out
+}
+
+// call executes 'fn', doing the call 'method' to the provider, when the limits of the class of operations allow it, and
+// records its outcome
+func (p *RateLimitedProvider) call(class OperationClass, method string, fn func() fail.Error) (xerr fail.Error) {
+	span := telemetry.FromContext(p.ctx).StartChild(p.name+"."+method, telemetry.KindClient)
+	span.SetAttribute("provider", p.name)
+	span.SetAttribute("method", method)
+	span.SetAttribute("tenant", p.Label)
+	defer func() {
+		span.End(xerr)
+	}()
+
+	limiter := p.limiters[class]
+	deadline := time.Now().Add(temporal.GetCommunicationTimeout())
+	for retries := 0; ; retries++ {
+		if retries > 0 {
+			span.SetAttribute("retries", retries)
+		}
+		wait, xerr := limiter.reserve(time.Until(deadline))
+		if xerr != nil {
+			logrus.Debugf("call to provider of tenant '%s' not done: %s", p.Label, xerr.Error())
+			return xerr
+		}
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-p.ctx.Done():
+				timer.Stop()
+				limiter.cancel()
+				return fail.AbortedError(p.ctx.Err(), "call to provider of tenant '%s' cancelled while waiting for the rate limits", p.Label)
+			}
+		}
+		start := time.Now()
+		xerr = fn()
+		providerCallDuration.Observe(time.Since(start).Seconds(), p.name, method)
+		providerCalls.Inc(p.name, method, metrics.ErrorKind(xerr))
+		limiter.done(xerr)
+
+		// a throttled read is done again after the pause decided by the limiter, if it ends before the deadline
+		if class != ReadOperation || throttledCause(xerr) == nil || !time.Now().Before(deadline) {
+			return xerr
+		}
+	}
+}
+
+// ListAvailabilityZones calls ListAvailabilityZones of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) ListAvailabilityZones() (out map[string]bool, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "ListAvailabilityZones", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ListAvailabilityZones()
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// ListRegions calls ListRegions of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) ListRegions() (out []string, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "ListRegions", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ListRegions()
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// InspectImage calls InspectImage of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) InspectImage(id string) (out abstract.Image, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "InspectImage", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.InspectImage(id)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// InspectTemplate calls InspectTemplate of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) InspectTemplate(id string) (out abstract.HostTemplate, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "InspectTemplate", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.InspectTemplate(id)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// CreateKeyPair calls CreateKeyPair of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) CreateKeyPair(name string) (out *abstract.KeyPair, xerr fail.Error) {
+	xerr = p.call(WriteOperation, "CreateKeyPair", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.CreateKeyPair(name)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// InspectKeyPair calls InspectKeyPair of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) InspectKeyPair(id string) (out *abstract.KeyPair, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "InspectKeyPair", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.InspectKeyPair(id)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// ListKeyPairs calls ListKeyPairs of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) ListKeyPairs() (out []abstract.KeyPair, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "ListKeyPairs", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ListKeyPairs()
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// DeleteKeyPair calls DeleteKeyPair of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) DeleteKeyPair(id string) fail.Error {
+	return p.call(WriteOperation, "DeleteKeyPair", func() fail.Error {
+		return p.Provider.DeleteKeyPair(id)
+	})
+}
+
+// ListSecurityGroups calls ListSecurityGroups of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) ListSecurityGroups(networkRef string) (out []*abstract.SecurityGroup, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "ListSecurityGroups", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ListSecurityGroups(networkRef)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// CreateSecurityGroup calls CreateSecurityGroup of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) CreateSecurityGroup(networkRef, name, description string, rules []abstract.SecurityGroupRule) (out *abstract.SecurityGroup, xerr fail.Error) {
+	xerr = p.call(WriteOperation, "CreateSecurityGroup", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.CreateSecurityGroup(networkRef, name, description, rules)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// InspectSecurityGroup calls InspectSecurityGroup of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) InspectSecurityGroup(sgParam stacks.SecurityGroupParameter) (out *abstract.SecurityGroup, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "InspectSecurityGroup", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.InspectSecurityGroup(sgParam)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// ClearSecurityGroup calls ClearSecurityGroup of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) ClearSecurityGroup(sgParam stacks.SecurityGroupParameter) (out *abstract.SecurityGroup, xerr fail.Error) {
+	xerr = p.call(WriteOperation, "ClearSecurityGroup", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ClearSecurityGroup(sgParam)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// DeleteSecurityGroup calls DeleteSecurityGroup of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) DeleteSecurityGroup(asg *abstract.SecurityGroup) fail.Error {
+	return p.call(WriteOperation, "DeleteSecurityGroup", func() fail.Error {
+		return p.Provider.DeleteSecurityGroup(asg)
+	})
+}
+
+// AddRuleToSecurityGroup calls AddRuleToSecurityGroup of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) AddRuleToSecurityGroup(sgParam stacks.SecurityGroupParameter, rule abstract.SecurityGroupRule) (out *abstract.SecurityGroup, xerr fail.Error) {
+	xerr = p.call(WriteOperation, "AddRuleToSecurityGroup", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.AddRuleToSecurityGroup(sgParam, rule)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// DeleteRuleFromSecurityGroup calls DeleteRuleFromSecurityGroup of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) DeleteRuleFromSecurityGroup(sgParam stacks.SecurityGroupParameter, rule abstract.SecurityGroupRule) (out *abstract.SecurityGroup, xerr fail.Error) {
+	xerr = p.call(WriteOperation, "DeleteRuleFromSecurityGroup", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.DeleteRuleFromSecurityGroup(sgParam, rule)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// EnableSecurityGroup calls EnableSecurityGroup of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) EnableSecurityGroup(asg *abstract.SecurityGroup) fail.Error {
+	return p.call(WriteOperation, "EnableSecurityGroup", func() fail.Error {
+		return p.Provider.EnableSecurityGroup(asg)
+	})
+}
+
+// DisableSecurityGroup calls DisableSecurityGroup of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) DisableSecurityGroup(asg *abstract.SecurityGroup) fail.Error {
+	return p.call(WriteOperation, "DisableSecurityGroup", func() fail.Error {
+		return p.Provider.DisableSecurityGroup(asg)
+	})
+}
+
+// CreateNetwork calls CreateNetwork of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) CreateNetwork(req abstract.NetworkRequest) (out *abstract.Network, xerr fail.Error) {
+	xerr = p.call(WriteOperation, "CreateNetwork", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.CreateNetwork(req)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// InspectNetwork calls InspectNetwork of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) InspectNetwork(id string) (out *abstract.Network, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "InspectNetwork", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.InspectNetwork(id)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// InspectNetworkByName calls InspectNetworkByName of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) InspectNetworkByName(name string) (out *abstract.Network, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "InspectNetworkByName", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.InspectNetworkByName(name)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// ListNetworks calls ListNetworks of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) ListNetworks() (out []*abstract.Network, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "ListNetworks", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ListNetworks()
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// DeleteNetwork calls DeleteNetwork of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) DeleteNetwork(id string) fail.Error {
+	return p.call(WriteOperation, "DeleteNetwork", func() fail.Error {
+		return p.Provider.DeleteNetwork(id)
+	})
+}
+
+// GetDefaultNetwork calls GetDefaultNetwork of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) GetDefaultNetwork() (out *abstract.Network, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "GetDefaultNetwork", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.GetDefaultNetwork()
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// CreateSubnet calls CreateSubnet of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) CreateSubnet(req abstract.SubnetRequest) (out *abstract.Subnet, xerr fail.Error) {
+	xerr = p.call(WriteOperation, "CreateSubnet", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.CreateSubnet(req)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// InspectSubnet calls InspectSubnet of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) InspectSubnet(id string) (out *abstract.Subnet, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "InspectSubnet", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.InspectSubnet(id)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// InspectSubnetByName calls InspectSubnetByName of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) InspectSubnetByName(networkID, name string) (out *abstract.Subnet, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "InspectSubnetByName", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.InspectSubnetByName(networkID, name)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// ListSubnets calls ListSubnets of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) ListSubnets(networkID string) (out []*abstract.Subnet, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "ListSubnets", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ListSubnets(networkID)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// DeleteSubnet calls DeleteSubnet of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) DeleteSubnet(id string) fail.Error {
+	return p.call(WriteOperation, "DeleteSubnet", func() fail.Error {
+		return p.Provider.DeleteSubnet(id)
+	})
+}
+
+// BindSecurityGroupToSubnet calls BindSecurityGroupToSubnet of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) BindSecurityGroupToSubnet(sgParam stacks.SecurityGroupParameter, subnetID string) fail.Error {
+	return p.call(WriteOperation, "BindSecurityGroupToSubnet", func() fail.Error {
+		return p.Provider.BindSecurityGroupToSubnet(sgParam, subnetID)
+	})
+}
+
+// UnbindSecurityGroupFromSubnet calls UnbindSecurityGroupFromSubnet of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) UnbindSecurityGroupFromSubnet(sgParam stacks.SecurityGroupParameter, subnetID string) fail.Error {
+	return p.call(WriteOperation, "UnbindSecurityGroupFromSubnet", func() fail.Error {
+		return p.Provider.UnbindSecurityGroupFromSubnet(sgParam, subnetID)
+	})
+}
+
+// CreateVIP calls CreateVIP of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) CreateVIP(networkID, subnetID, name string, securityGroups []string) (out *abstract.VirtualIP, xerr fail.Error) {
+	xerr = p.call(WriteOperation, "CreateVIP", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.CreateVIP(networkID, subnetID, name, securityGroups)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// AddPublicIPToVIP calls AddPublicIPToVIP of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) AddPublicIPToVIP(vip *abstract.VirtualIP) fail.Error {
+	return p.call(WriteOperation, "AddPublicIPToVIP", func() fail.Error {
+		return p.Provider.AddPublicIPToVIP(vip)
+	})
+}
+
+// BindHostToVIP calls BindHostToVIP of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) BindHostToVIP(vip *abstract.VirtualIP, hostID string) fail.Error {
+	return p.call(WriteOperation, "BindHostToVIP", func() fail.Error {
+		return p.Provider.BindHostToVIP(vip, hostID)
+	})
+}
+
+// UnbindHostFromVIP calls UnbindHostFromVIP of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) UnbindHostFromVIP(vip *abstract.VirtualIP, hostID string) fail.Error {
+	return p.call(WriteOperation, "UnbindHostFromVIP", func() fail.Error {
+		return p.Provider.UnbindHostFromVIP(vip, hostID)
+	})
+}
+
+// DeleteVIP calls DeleteVIP of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) DeleteVIP(vip *abstract.VirtualIP) fail.Error {
+	return p.call(WriteOperation, "DeleteVIP", func() fail.Error {
+		return p.Provider.DeleteVIP(vip)
+	})
+}
+
+// CreateHost calls CreateHost of the wrapped provider as a Host operation
+func (p *RateLimitedProvider) CreateHost(request abstract.HostRequest) (out1 *abstract.HostFull, out2 *userdata.Content, xerr fail.Error) {
+	xerr = p.call(HostOperation, "CreateHost", func() (innerXErr fail.Error) {
+		out1, out2, innerXErr = p.Provider.CreateHost(request)
+		return innerXErr
+	})
+	return out1, out2, xerr
+}
+
+// ClearHostStartupScript calls ClearHostStartupScript of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) ClearHostStartupScript(hostParam stacks.HostParameter) fail.Error {
+	return p.call(WriteOperation, "ClearHostStartupScript", func() fail.Error {
+		return p.Provider.ClearHostStartupScript(hostParam)
+	})
+}
+
+// InspectHost calls InspectHost of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) InspectHost(hostParam stacks.HostParameter) (out *abstract.HostFull, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "InspectHost", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.InspectHost(hostParam)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// GetHostState calls GetHostState of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) GetHostState(hostParam stacks.HostParameter) (out hoststate.Enum, xerr fail.Error) {
+	out = hoststate.UNKNOWN
+	xerr = p.call(ReadOperation, "GetHostState", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.GetHostState(hostParam)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// ListHosts calls ListHosts of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) ListHosts(details bool) (out abstract.HostList, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "ListHosts", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ListHosts(details)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// DeleteHost calls DeleteHost of the wrapped provider as a Host operation
+func (p *RateLimitedProvider) DeleteHost(hostParam stacks.HostParameter) fail.Error {
+	return p.call(HostOperation, "DeleteHost", func() fail.Error {
+		return p.Provider.DeleteHost(hostParam)
+	})
+}
+
+// StopHost calls StopHost of the wrapped provider as a Host operation
+func (p *RateLimitedProvider) StopHost(hostParam stacks.HostParameter) fail.Error {
+	return p.call(HostOperation, "StopHost", func() fail.Error {
+		return p.Provider.StopHost(hostParam)
+	})
+}
+
+// StartHost calls StartHost of the wrapped provider as a Host operation
+func (p *RateLimitedProvider) StartHost(hostParam stacks.HostParameter) fail.Error {
+	return p.call(HostOperation, "StartHost", func() fail.Error {
+		return p.Provider.StartHost(hostParam)
+	})
+}
+
+// RebootHost calls RebootHost of the wrapped provider as a Host operation
+func (p *RateLimitedProvider) RebootHost(hostParam stacks.HostParameter) fail.Error {
+	return p.call(HostOperation, "RebootHost", func() fail.Error {
+		return p.Provider.RebootHost(hostParam)
+	})
+}
+
+// ResizeHost calls ResizeHost of the wrapped provider as a Host operation
+func (p *RateLimitedProvider) ResizeHost(hostParam stacks.HostParameter, request abstract.HostSizingRequirements) (out *abstract.HostFull, xerr fail.Error) {
+	xerr = p.call(HostOperation, "ResizeHost", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ResizeHost(hostParam, request)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// WaitHostReady calls WaitHostReady of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) WaitHostReady(hostParam stacks.HostParameter, timeout time.Duration) (out *abstract.HostCore, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "WaitHostReady", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.WaitHostReady(hostParam, timeout)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// BindSecurityGroupToHost calls BindSecurityGroupToHost of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) BindSecurityGroupToHost(sgParam stacks.SecurityGroupParameter, hostParam stacks.HostParameter) fail.Error {
+	return p.call(WriteOperation, "BindSecurityGroupToHost", func() fail.Error {
+		return p.Provider.BindSecurityGroupToHost(sgParam, hostParam)
+	})
+}
+
+// UnbindSecurityGroupFromHost calls UnbindSecurityGroupFromHost of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) UnbindSecurityGroupFromHost(sgParam stacks.SecurityGroupParameter, hostParam stacks.HostParameter) fail.Error {
+	return p.call(WriteOperation, "UnbindSecurityGroupFromHost", func() fail.Error {
+		return p.Provider.UnbindSecurityGroupFromHost(sgParam, hostParam)
+	})
+}
+
+// CreateVolume calls CreateVolume of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) CreateVolume(request abstract.VolumeRequest) (out *abstract.Volume, xerr fail.Error) {
+	xerr = p.call(WriteOperation, "CreateVolume", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.CreateVolume(request)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// InspectVolume calls InspectVolume of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) InspectVolume(id string) (out *abstract.Volume, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "InspectVolume", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.InspectVolume(id)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// ListVolumes calls ListVolumes of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) ListVolumes() (out []abstract.Volume, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "ListVolumes", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ListVolumes()
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// DeleteVolume calls DeleteVolume of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) DeleteVolume(id string) fail.Error {
+	return p.call(WriteOperation, "DeleteVolume", func() fail.Error {
+		return p.Provider.DeleteVolume(id)
+	})
+}
+
+// CreateVolumeAttachment calls CreateVolumeAttachment of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) CreateVolumeAttachment(request abstract.VolumeAttachmentRequest) (out string, xerr fail.Error) {
+	xerr = p.call(WriteOperation, "CreateVolumeAttachment", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.CreateVolumeAttachment(request)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// InspectVolumeAttachment calls InspectVolumeAttachment of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) InspectVolumeAttachment(serverID, id string) (out *abstract.VolumeAttachment, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "InspectVolumeAttachment", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.InspectVolumeAttachment(serverID, id)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// ListVolumeAttachments calls ListVolumeAttachments of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) ListVolumeAttachments(serverID string) (out []abstract.VolumeAttachment, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "ListVolumeAttachments", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ListVolumeAttachments(serverID)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// DeleteVolumeAttachment calls DeleteVolumeAttachment of the wrapped provider as a Write operation
+func (p *RateLimitedProvider) DeleteVolumeAttachment(serverID, id string) fail.Error {
+	return p.call(WriteOperation, "DeleteVolumeAttachment", func() fail.Error {
+		return p.Provider.DeleteVolumeAttachment(serverID, id)
+	})
+}
+
+// ListImages calls ListImages of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) ListImages(all bool) (out []abstract.Image, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "ListImages", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ListImages(all)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// ListTemplates calls ListTemplates of the wrapped provider as a Read operation
+func (p *RateLimitedProvider) ListTemplates(all bool) (out []abstract.HostTemplate, xerr fail.Error) {
+	xerr = p.call(ReadOperation, "ListTemplates", func() (innerXErr fail.Error) {
+		out, innerXErr = p.Provider.ListTemplates(all)
+		return innerXErr
+	})
+	return out, xerr
+}
+
+// GetProviderQuotas calls GetProviderQuotas of the wrapped provider as a Read operation, if it is a QuotaReporter
+func (p *RateLimitedProvider) GetProviderQuotas() (out abstract.ProviderQuotas, xerr fail.Error) {
+	reporter, ok := p.Provider.(QuotaReporter)
+	if !ok {
+		return nil, fail.NotImplementedError("provider does not report its quotas")
+	}
+	xerr = p.call(ReadOperation, "GetProviderQuotas", func() (innerXErr fail.Error) {
+		out, innerXErr = reporter.GetProviderQuotas()
+		return innerXErr
+	})
+	return out, xerr
+}
@@ -0,0 +1,267 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package providers
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// OperationClass designates a class of operations on the provider API sharing the same rate limit
+type OperationClass int
+
+const (
+	// ReadOperation designates the operations reading resources (Inspect..., List..., Get...)
+	ReadOperation OperationClass = iota
+	// WriteOperation designates the operations creating, updating or deleting resources other than hosts
+	WriteOperation
+	// HostOperation designates the operations creating, deleting, resizing or changing the state of hosts
+	HostOperation
+
+	operationClassCount = iota
+)
+
+// String returns the name of the class of operations, as used in tenants file
+func (c OperationClass) String() string {
+	switch c {
+	case ReadOperation:
+		return "Read"
+	case WriteOperation:
+		return "Write"
+	case HostOperation:
+		return "Host"
+	default:
+		return "Unknown"
+	}
+}
+
+const (
+	// DefaultBreakerThreshold is the number of consecutive throttled calls opening the circuit breaker, when not set
+	DefaultBreakerThreshold = 5
+	// DefaultBreakerCooldown is the duration the circuit breaker stays open, when not set
+	DefaultBreakerCooldown = 30 * time.Second
+
+	// minimumRateRatio is the ratio of the configured rate under which the adaptive rate does not go
+	minimumRateRatio = 0.1
+	// maximumBackoff is the longest pause applied to a class of operations after a throttled call without Retry-After
+	maximumBackoff = time.Minute
+)
+
+// RateLimits contains the settings of the client-side rate limiting of the calls to the provider API
+type RateLimits struct {
+	Read             float64       // requests per second allowed for ReadOperation; 0 means unlimited
+	Write            float64       // requests per second allowed for WriteOperation; 0 means unlimited
+	Host             float64       // requests per second allowed for HostOperation; 0 means unlimited
+	Burst            int           // number of requests allowed at once after a period of inactivity; 0 means the rate rounded up
+	BreakerThreshold int           // number of consecutive throttled calls opening the circuit breaker; 0 means DefaultBreakerThreshold, negative disables the breaker
+	BreakerCooldown  time.Duration // duration the circuit breaker stays open before letting a trial call pass; 0 means DefaultBreakerCooldown
+}
+
+// Rate returns the requests per second allowed for the class of operations (0 meaning unlimited)
+func (rl RateLimits) Rate(class OperationClass) float64 {
+	switch class {
+	case ReadOperation:
+		return rl.Read
+	case WriteOperation:
+		return rl.Write
+	case HostOperation:
+		return rl.Host
+	default:
+		return 0
+	}
+}
+
+// classLimiter limits the rate of the calls of a class of operations with a token bucket, adapting the rate when
+// the provider throttles the calls, and stops the calls with a circuit breaker when the provider keeps on throttling
+type classLimiter struct {
+	lock        sync.Mutex
+	class       OperationClass
+	limits      RateLimits // rate limits the settings come from
+	limit       float64    // configured rate, 0 meaning unlimited
+	rate        float64    // current rate, lowered when the provider throttles the calls
+	burst       float64
+	tokens      float64
+	last        time.Time // time of the last refill of the bucket
+	pausedUntil time.Time // no call is done before this time, after a throttled call
+	backoff     time.Duration
+	throttled   int       // number of consecutive throttled calls
+	openedUntil time.Time // while the circuit breaker is open, the calls fail fast
+	trial       bool      // a trial call is in progress while the circuit breaker is half-open
+	threshold   int
+	cooldown    time.Duration
+	now         func() time.Time
+}
+
+// newClassLimiter creates a classLimiter for the class of operations from the rate limits
+func newClassLimiter(class OperationClass, limits RateLimits) *classLimiter {
+	l := &classLimiter{
+		class: class,
+		now:   time.Now,
+	}
+	l.apply(limits)
+	l.tokens = l.burst
+	return l
+}
+
+// apply sets the settings of the limiter from the rate limits, the current rate going back to the configured one
+func (l *classLimiter) apply(limits RateLimits) {
+	l.limits = limits
+	l.limit = limits.Rate(l.class)
+	if l.limit < 0 {
+		l.limit = 0
+	}
+	l.rate = l.limit
+	l.burst = float64(limits.Burst)
+	if l.burst <= 0 {
+		l.burst = math.Max(1, math.Ceil(l.limit))
+	}
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.threshold = limits.BreakerThreshold
+	if l.threshold == 0 {
+		l.threshold = DefaultBreakerThreshold
+	}
+	l.cooldown = limits.BreakerCooldown
+	if l.cooldown <= 0 {
+		l.cooldown = DefaultBreakerCooldown
+	}
+}
+
+// reconfigure applies new rate limits to the limiter, keeping the pause and the state of the circuit breaker due to
+// the previous calls; does nothing if the rate limits did not change
+func (l *classLimiter) reconfigure(limits RateLimits) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if limits != l.limits {
+		l.apply(limits)
+	}
+}
+
+// reserve books the right to do a call and returns the delay to wait before doing it
+// Returns *fail.ErrThrottled if the circuit breaker is open or if the delay would be longer than 'maxWait'; in this
+// case nothing is booked
+func (l *classLimiter) reserve(maxWait time.Duration) (time.Duration, fail.Error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	trial := false
+	if l.threshold > 0 && l.throttled >= l.threshold {
+		if now.Before(l.openedUntil) {
+			return 0, fail.ThrottledError(l.openedUntil.Sub(now), "too many throttled calls to the provider, %s operations suspended", l.class)
+		}
+		if l.trial {
+			return 0, fail.ThrottledError(time.Second, "too many throttled calls to the provider, waiting for the result of a trial %s operation", l.class)
+		}
+		trial = true
+	}
+
+	var wait time.Duration
+	if now.Before(l.pausedUntil) {
+		wait = l.pausedUntil.Sub(now)
+	}
+	tokens, last := l.tokens, l.last
+	if l.rate > 0 {
+		at := now.Add(wait)
+		if at.After(last) {
+			tokens = math.Min(l.burst, tokens+at.Sub(last).Seconds()*l.rate)
+			last = at
+		}
+		tokens--
+		if tokens < 0 {
+			wait += time.Duration(-tokens / l.rate * float64(time.Second))
+		}
+	}
+	if maxWait > 0 && wait > maxWait {
+		return 0, fail.ThrottledError(wait, "rate of %s operations on the provider exceeded", l.class)
+	}
+
+	l.tokens, l.last = tokens, last
+	l.trial = l.trial || trial
+	return wait, nil
+}
+
+// cancel records that a call booked with reserve has not been done
+func (l *classLimiter) cancel() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.trial = false
+}
+
+// throttledCause returns the *fail.ErrThrottled found in the chain of causes of 'err', the stacks wrapping the errors
+// of the provider with the context of the call
+func throttledCause(err error) *fail.ErrThrottled {
+	for err != nil {
+		if throttled, ok := err.(*fail.ErrThrottled); ok {
+			return throttled
+		}
+		xerr, ok := err.(fail.Error)
+		if !ok {
+			return nil
+		}
+		err = xerr.Cause()
+	}
+	return nil
+}
+
+// done records the result of a call, adapting the rate and the state of the circuit breaker
+func (l *classLimiter) done(err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.trial = false
+	throttled := throttledCause(err)
+	if throttled == nil {
+		// The provider answered (even with an error), the rate can be raised back progressively
+		l.throttled = 0
+		l.backoff = 0
+		if l.limit > 0 && l.rate < l.limit {
+			l.rate = math.Min(l.limit, l.rate+l.limit*minimumRateRatio)
+		}
+		return
+	}
+
+	now := l.now()
+	l.throttled++
+	if l.backoff == 0 {
+		l.backoff = time.Second
+	} else if l.backoff < maximumBackoff {
+		l.backoff *= 2
+		if l.backoff > maximumBackoff {
+			l.backoff = maximumBackoff
+		}
+	}
+	pause := l.backoff
+	if d := throttled.RetryAfter(); d > pause {
+		pause = d
+	}
+	if until := now.Add(pause); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+	if l.limit > 0 {
+		l.rate = math.Max(l.rate/2, l.limit*minimumRateRatio)
+	}
+	if l.threshold > 0 && l.throttled >= l.threshold {
+		l.openedUntil = now.Add(l.cooldown)
+	}
+}
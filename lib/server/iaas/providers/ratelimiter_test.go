@@ -0,0 +1,297 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/server/iaas/stacks"
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/retry"
+)
+
+type fakeClock struct {
+	current time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.current
+}
+
+func newTestLimiter(limits RateLimits) (*classLimiter, *fakeClock) {
+	clock := &fakeClock{current: time.Unix(1600000000, 0)}
+	l := newClassLimiter(ReadOperation, limits)
+	l.now = clock.now
+	l.last = clock.current
+	return l, clock
+}
+
+func TestClassLimiterRate(t *testing.T) {
+	l, clock := newTestLimiter(RateLimits{Read: 2, Burst: 2})
+
+	for i := 0; i < 2; i++ {
+		wait, xerr := l.reserve(0)
+		require.Nil(t, xerr)
+		assert.Equal(t, time.Duration(0), wait)
+	}
+	wait, xerr := l.reserve(0)
+	require.Nil(t, xerr)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// Calls waiting longer than allowed are refused and not booked
+	_, xerr = l.reserve(100 * time.Millisecond)
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrThrottled{}, xerr)
+
+	clock.current = clock.current.Add(10 * time.Second)
+	wait, xerr = l.reserve(0)
+	require.Nil(t, xerr)
+	assert.Equal(t, time.Duration(0), wait)
+}
+
+func TestClassLimiterUnlimited(t *testing.T) {
+	l, _ := newTestLimiter(RateLimits{})
+	for i := 0; i < 100; i++ {
+		wait, xerr := l.reserve(0)
+		require.Nil(t, xerr)
+		assert.Equal(t, time.Duration(0), wait)
+	}
+}
+
+func TestClassLimiterAdaptive(t *testing.T) {
+	l, clock := newTestLimiter(RateLimits{Read: 10})
+
+	_, xerr := l.reserve(0)
+	require.Nil(t, xerr)
+	l.done(fail.ThrottledError(3*time.Second, "slow down"))
+	assert.Equal(t, float64(5), l.rate)
+
+	// Retry-After is honored
+	wait, xerr := l.reserve(0)
+	require.Nil(t, xerr)
+	assert.Equal(t, 3*time.Second, wait)
+
+	// Without Retry-After, the pause follows the backoff
+	clock.current = clock.current.Add(3 * time.Second)
+	l.done(fail.ThrottledError(0, "slow down"))
+	assert.Equal(t, 2*time.Second, l.pausedUntil.Sub(clock.current))
+
+	// The rate is raised back progressively on success, up to the configured one
+	clock.current = clock.current.Add(time.Minute)
+	for i := 0; i < 20; i++ {
+		l.done(nil)
+	}
+	assert.Equal(t, float64(10), l.rate)
+	assert.Equal(t, time.Duration(0), l.backoff)
+}
+
+func TestClassLimiterWrappedThrottled(t *testing.T) {
+	l, _ := newTestLimiter(RateLimits{Read: 10})
+
+	// the stacks wrap the errors of the provider with the context of the call
+	l.done(fail.ExecutionError(fail.ThrottledError(3*time.Second, "slow down"), "failed to inspect host"))
+	assert.Equal(t, float64(5), l.rate)
+	assert.Equal(t, 1, l.throttled)
+
+	l.done(retry.StopRetryError(fail.ExecutionError(fail.ThrottledError(0, "slow down"), "failed to inspect host")))
+	assert.Equal(t, 2, l.throttled)
+
+	l.done(fail.ExecutionError(fail.NotFoundError("not throttled"), "failed to inspect host"))
+	assert.Equal(t, 0, l.throttled)
+}
+
+func TestClassLimiterBreaker(t *testing.T) {
+	l, clock := newTestLimiter(RateLimits{BreakerThreshold: 2, BreakerCooldown: 10 * time.Second})
+
+	l.done(fail.ThrottledError(0, "slow down"))
+	l.done(fail.NotFoundError("not throttled"))
+	l.done(fail.ThrottledError(0, "slow down"))
+	clock.current = clock.current.Add(time.Minute)
+	_, xerr := l.reserve(0)
+	require.Nil(t, xerr, "the breaker must count only consecutive throttled calls")
+	l.done(fail.ThrottledError(0, "slow down"))
+
+	// Opened: the calls fail fast
+	_, xerr = l.reserve(0)
+	require.NotNil(t, xerr)
+	if throttled, ok := xerr.(*fail.ErrThrottled); assert.True(t, ok) {
+		assert.Equal(t, 10*time.Second, throttled.RetryAfter())
+	}
+
+	// Half-open: one trial call passes, the others fail fast
+	clock.current = clock.current.Add(time.Minute)
+	_, xerr = l.reserve(0)
+	require.Nil(t, xerr)
+	_, xerr = l.reserve(0)
+	require.NotNil(t, xerr)
+
+	// Failed trial opens the breaker again
+	l.done(fail.ThrottledError(0, "slow down"))
+	_, xerr = l.reserve(0)
+	require.NotNil(t, xerr)
+
+	// Successful trial closes it
+	clock.current = clock.current.Add(time.Minute)
+	_, xerr = l.reserve(0)
+	require.Nil(t, xerr)
+	l.done(nil)
+	_, xerr = l.reserve(0)
+	require.Nil(t, xerr)
+	_, xerr = l.reserve(0)
+	require.Nil(t, xerr)
+}
+
+func TestClassLimiterBreakerDisabled(t *testing.T) {
+	l, clock := newTestLimiter(RateLimits{BreakerThreshold: -1})
+	for i := 0; i < 10; i++ {
+		l.done(fail.ThrottledError(0, "slow down"))
+	}
+	clock.current = clock.current.Add(time.Hour)
+	_, xerr := l.reserve(0)
+	assert.Nil(t, xerr)
+}
+
+type fakeProvider struct {
+	Provider
+	err       fail.Error
+	throttles int // number of calls throttled before err is returned
+	calls     int
+}
+
+func (p *fakeProvider) GetName() string {
+	return "fake"
+}
+
+func (p *fakeProvider) InspectHost(stacks.HostParameter) (*abstract.HostFull, fail.Error) {
+	p.calls++
+	if p.throttles > 0 {
+		p.throttles--
+		return nil, fail.ThrottledError(0, "slow down")
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return abstract.NewHostFull(), nil
+}
+
+func (p *fakeProvider) DeleteHost(stacks.HostParameter) fail.Error {
+	p.calls++
+	if p.throttles > 0 {
+		p.throttles--
+		return fail.ThrottledError(0, "slow down")
+	}
+	return p.err
+}
+
+func TestRateLimitedProvider(t *testing.T) {
+	inner := &fakeProvider{}
+	p := NewRateLimitedProvider(inner, "test", RateLimits{BreakerThreshold: 1, BreakerCooldown: time.Hour})
+
+	ahf, xerr := p.InspectHost("host")
+	require.Nil(t, xerr)
+	assert.NotNil(t, ahf)
+
+	inner.err = fail.ThrottledError(0, "slow down")
+	_, xerr = p.InspectHost("host")
+	require.NotNil(t, xerr)
+
+	// The breaker of Read operations is open: the provider is not called anymore
+	_, xerr = p.InspectHost("host")
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrThrottled{}, xerr)
+	assert.Equal(t, 2, inner.calls)
+	assert.Equal(t, float64(1), providerCalls.Value("fake", "InspectHost", ""))
+	assert.Equal(t, float64(1), providerCalls.Value("fake", "InspectHost", "throttled"))
+	assert.Equal(t, uint64(2), providerCallDuration.Count("fake", "InspectHost"))
+
+	// Other classes of operations are not affected
+	wait, xerr := p.limiters[HostOperation].reserve(0)
+	require.Nil(t, xerr)
+	assert.Equal(t, time.Duration(0), wait)
+
+	// Waits for the limits are interrupted by the cancellation of the context of the calls
+	ctx, cancel := context.WithCancel(context.Background())
+	inner.err = nil
+	bound := NewRateLimitedProvider(inner, "test-bound", RateLimits{Read: 0.1}).WithContext(ctx)
+	_, xerr = bound.InspectHost("host")
+	require.Nil(t, xerr)
+	cancel()
+	_, xerr = bound.InspectHost("host")
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrAborted{}, xerr)
+	assert.Equal(t, 3, inner.calls)
+
+	// A throttled call is retried after the pause of its class of operations
+	inner.throttles = 1
+	retried := NewRateLimitedProvider(inner, "test-retried", RateLimits{})
+	start := time.Now()
+	_, xerr = retried.InspectHost("host")
+	require.Nil(t, xerr)
+	assert.Equal(t, 5, inner.calls)
+	assert.True(t, time.Since(start) >= time.Second)
+
+	// The other operations are not idempotent, a throttled one is returned to the caller
+	inner.throttles = 1
+	xerr = retried.DeleteHost("host")
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrThrottled{}, xerr)
+	assert.Equal(t, 6, inner.calls)
+
+	_, xerr = p.GetProviderQuotas()
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrNotImplemented{}, xerr)
+}
+
+func TestRateLimitedProviderSharedLimiters(t *testing.T) {
+	inner := &fakeProvider{err: fail.ThrottledError(0, "slow down")}
+	limits := RateLimits{Read: 10, BreakerThreshold: 1, BreakerCooldown: time.Hour}
+	first := NewRateLimitedProvider(inner, "test-shared", limits)
+	_, xerr := first.InspectHost("host")
+	require.NotNil(t, xerr)
+
+	// A new instance for the tenant, as created when its service is recreated, keeps the state of the limiters
+	second := NewRateLimitedProvider(inner, "test-shared", limits)
+	assert.Equal(t, first.limiters, second.limiters)
+	_, xerr = second.InspectHost("host")
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrThrottled{}, xerr)
+	assert.Equal(t, 1, inner.calls)
+
+	// Other tenants have their own limiters
+	other := NewRateLimitedProvider(inner, "test-shared-other", limits)
+	assert.NotEqual(t, first.limiters, other.limiters)
+
+	// A change of the limits resets the configured rates, not the circuit breaker
+	l := first.limiters[ReadOperation]
+	l.rate = 1
+	NewRateLimitedProvider(inner, "test-shared", limits)
+	assert.Equal(t, float64(1), l.rate, "the adapted rate is kept while the limits do not change")
+	NewRateLimitedProvider(inner, "test-shared", RateLimits{Read: 20, Burst: 5, BreakerThreshold: 1, BreakerCooldown: time.Hour})
+	assert.Equal(t, float64(20), l.limit)
+	assert.Equal(t, float64(20), l.rate)
+	assert.Equal(t, float64(5), l.burst)
+	assert.Equal(t, 1, l.throttled)
+	_, xerr = second.InspectHost("host")
+	require.NotNil(t, xerr)
+	assert.Equal(t, 1, inner.calls)
+}
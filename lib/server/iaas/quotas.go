@@ -0,0 +1,162 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iaas
+
+import (
+	"sort"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// QuotaResource designates a kind of resource limited by quotas; its value is the keyword used in tenants file
+type QuotaResource string
+
+const (
+	// QuotaHosts is the number of hosts
+	QuotaHosts QuotaResource = "Hosts"
+	// QuotaCores is the total number of cores of the hosts
+	QuotaCores QuotaResource = "Cores"
+	// QuotaRAM is the total RAM of the hosts, in GB
+	QuotaRAM QuotaResource = "RAM"
+	// QuotaGPUs is the total number of GPUs of the hosts
+	QuotaGPUs QuotaResource = "GPUs"
+	// QuotaVolumeGB is the total size of the volumes, in GB
+	QuotaVolumeGB QuotaResource = "VolumeGB"
+	// QuotaPublicIPs is the number of public IP addresses
+	QuotaPublicIPs QuotaResource = "PublicIPs"
+	// QuotaClusters is the number of clusters
+	QuotaClusters QuotaResource = "Clusters"
+)
+
+// QuotaResources lists the kinds of resources limited by quotas, in display order
+var QuotaResources = []QuotaResource{QuotaHosts, QuotaCores, QuotaRAM, QuotaGPUs, QuotaVolumeGB, QuotaPublicIPs, QuotaClusters}
+
+// QuotaLimits contains the limits of the kinds of resources; a kind of resource absent from the map is not limited
+type QuotaLimits map[QuotaResource]float64
+
+// Quotas contains the limits of the resources of a tenant, read from section 'quotas' of tenants file
+type Quotas struct {
+	Tenant  QuotaLimits            // limits of the whole tenant (keywords of section 'quotas')
+	PerUser QuotaLimits            // limits of each authenticated caller (keywords of section 'quotas.PerUser')
+	Users   map[string]QuotaLimits // limits of specific callers, overriding 'PerUser' keyword by keyword (sections 'quotas.Users.<name>')
+}
+
+// IsEmpty tells if no quota is configured
+func (q Quotas) IsEmpty() bool {
+	if len(q.Tenant) > 0 || len(q.PerUser) > 0 {
+		return false
+	}
+	for _, v := range q.Users {
+		if len(v) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ForUser returns the limits applying to the resources created by the caller 'name' (no limit if name is empty, ie
+// without authentication)
+func (q Quotas) ForUser(name string) QuotaLimits {
+	out := QuotaLimits{}
+	if name == "" {
+		return out
+	}
+	for k, v := range q.PerUser {
+		out[k] = v
+	}
+	for k, v := range q.Users[name] {
+		out[k] = v
+	}
+	return out
+}
+
+// validateQuotas validates the quotas from tenants file
+func validateQuotas(svc *service, tenant map[string]interface{}) (xerr fail.Error) {
+	svc.quotas = Quotas{}
+	quotas, ok := tenant["quotas"].(map[string]interface{})
+	if !ok {
+		if _, ok := tenant["quotas"]; ok {
+			return fail.SyntaxError("invalid section 'quotas': must be a table")
+		}
+		return nil
+	}
+
+	if svc.quotas.Tenant, xerr = quotaLimitsOfSection("quotas", quotas); xerr != nil {
+		return xerr
+	}
+	if anon, ok := quotas["PerUser"]; ok {
+		section, ok := anon.(map[string]interface{})
+		if !ok {
+			return fail.SyntaxError("invalid section 'quotas.PerUser': must be a table")
+		}
+		if svc.quotas.PerUser, xerr = quotaLimitsOfSection("quotas.PerUser", section); xerr != nil {
+			return xerr
+		}
+	}
+	if anon, ok := quotas["Users"]; ok {
+		users, ok := anon.(map[string]interface{})
+		if !ok {
+			return fail.SyntaxError("invalid section 'quotas.Users': must be a table indexed by user name")
+		}
+		names := make([]string, 0, len(users))
+		for k := range users {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		svc.quotas.Users = make(map[string]QuotaLimits, len(users))
+		for _, name := range names {
+			path := "quotas.Users." + name
+			section, ok := users[name].(map[string]interface{})
+			if !ok {
+				return fail.SyntaxError("invalid section '%s': must be a table", path)
+			}
+			if svc.quotas.Users[name], xerr = quotaLimitsOfSection(path, section); xerr != nil {
+				return xerr
+			}
+		}
+	}
+	return nil
+}
+
+// quotaLimitsOfSection reads the limits set by the keywords of a section of quotas; subsections are ignored
+func quotaLimitsOfSection(path string, section map[string]interface{}) (QuotaLimits, fail.Error) {
+	out := QuotaLimits{}
+	for k, anon := range section {
+		if _, ok := anon.(map[string]interface{}); ok {
+			continue
+		}
+		known := false
+		for _, r := range QuotaResources {
+			if string(r) == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fail.SyntaxError("unknown keyword '%s' in section '%s'", k, path)
+		}
+		value, ok := numberOfKeyword(anon)
+		if !ok || value < 0 {
+			return nil, fail.SyntaxError("invalid value '%v' for keyword '%s' of section '%s': must be a positive number", anon, k, path)
+		}
+		if QuotaResource(k) != QuotaRAM && value != float64(int64(value)) {
+			return nil, fail.SyntaxError("invalid value '%v' for keyword '%s' of section '%s': must be an integer", anon, k, path)
+		}
+		out[QuotaResource(k)] = value
+	}
+	return out, nil
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iaas
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateQuotas(t *testing.T) {
+	svc := &service{}
+	require.Nil(t, validateQuotas(svc, map[string]interface{}{}))
+	assert.True(t, svc.quotas.IsEmpty())
+
+	tenant := map[string]interface{}{
+		"quotas": map[string]interface{}{
+			"Hosts":    int64(20),
+			"RAM":      96.5,
+			"GPUs":     0,
+			"VolumeGB": int64(2000),
+			"PerUser": map[string]interface{}{
+				"Hosts":    int64(5),
+				"Clusters": int64(1),
+			},
+			"Users": map[string]interface{}{
+				"alice": map[string]interface{}{
+					"Hosts": int64(10),
+				},
+			},
+		},
+	}
+	require.Nil(t, validateQuotas(svc, tenant))
+	assert.False(t, svc.quotas.IsEmpty())
+	assert.Equal(t, QuotaLimits{QuotaHosts: 20, QuotaRAM: 96.5, QuotaGPUs: 0, QuotaVolumeGB: 2000}, svc.quotas.Tenant)
+	assert.Equal(t, QuotaLimits{QuotaHosts: 10, QuotaClusters: 1}, svc.quotas.ForUser("alice"))
+	assert.Equal(t, QuotaLimits{QuotaHosts: 5, QuotaClusters: 1}, svc.quotas.ForUser("bob"))
+	assert.Empty(t, svc.quotas.ForUser(""))
+
+	invalids := []map[string]interface{}{
+		{"quotas": "all"},
+		{"quotas": map[string]interface{}{"Instances": int64(3)}},
+		{"quotas": map[string]interface{}{"Hosts": -1}},
+		{"quotas": map[string]interface{}{"Cores": 2.5}},
+		{"quotas": map[string]interface{}{"PerUser": "none"}},
+		{"quotas": map[string]interface{}{"Users": map[string]interface{}{"alice": 3}}},
+	}
+	for _, v := range invalids {
+		assert.NotNil(t, validateQuotas(svc, v), v)
+	}
+}
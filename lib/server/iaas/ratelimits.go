@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iaas
+
+import (
+	"time"
+
+	"github.com/CS-SI/SafeScale/lib/server/iaas/providers"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// validateRateLimits validates the limits of the rate of the calls to the provider API from tenants file
+func validateRateLimits(svc *service, tenant map[string]interface{}) fail.Error {
+	svc.rateLimits = providers.RateLimits{}
+	section, ok := tenant["ratelimit"].(map[string]interface{})
+	if !ok {
+		if _, ok := tenant["ratelimit"]; ok {
+			return fail.SyntaxError("invalid section 'ratelimit': must be a table")
+		}
+		return nil
+	}
+
+	for k, anon := range section {
+		value, ok := numberOfKeyword(anon)
+		if !ok || value < 0 {
+			return fail.SyntaxError("invalid value '%v' for keyword '%s' of section 'ratelimit': must be a positive number", anon, k)
+		}
+		if (k == "Burst" || k == "BreakerThreshold") && value != float64(int64(value)) {
+			return fail.SyntaxError("invalid value '%v' for keyword '%s' of section 'ratelimit': must be an integer", anon, k)
+		}
+		switch k {
+		case "Read":
+			svc.rateLimits.Read = value
+		case "Write":
+			svc.rateLimits.Write = value
+		case "Host":
+			svc.rateLimits.Host = value
+		case "Burst":
+			svc.rateLimits.Burst = int(value)
+		case "BreakerThreshold":
+			// In tenants file, 0 disables the circuit breaker; in providers.RateLimits, 0 means the default threshold
+			if value == 0 {
+				svc.rateLimits.BreakerThreshold = -1
+			} else {
+				svc.rateLimits.BreakerThreshold = int(value)
+			}
+		case "BreakerCooldown":
+			svc.rateLimits.BreakerCooldown = time.Duration(value * float64(time.Second))
+		default:
+			return fail.SyntaxError("unknown keyword '%s' in section 'ratelimit'", k)
+		}
+	}
+	return nil
+}
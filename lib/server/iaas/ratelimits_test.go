@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iaas
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/server/iaas/providers"
+)
+
+func TestValidateRateLimits(t *testing.T) {
+	svc := &service{}
+	require.Nil(t, validateRateLimits(svc, map[string]interface{}{}))
+	assert.Equal(t, providers.RateLimits{}, svc.rateLimits)
+
+	tenant := map[string]interface{}{
+		"ratelimit": map[string]interface{}{
+			"Read":             int64(20),
+			"Write":            2.5,
+			"Host":             0.2,
+			"Burst":            int64(5),
+			"BreakerThreshold": int64(0),
+			"BreakerCooldown":  int64(60),
+		},
+	}
+	require.Nil(t, validateRateLimits(svc, tenant))
+	assert.Equal(t, providers.RateLimits{Read: 20, Write: 2.5, Host: 0.2, Burst: 5, BreakerThreshold: -1, BreakerCooldown: time.Minute}, svc.rateLimits)
+
+	invalids := []map[string]interface{}{
+		{"ratelimit": "slow"},
+		{"ratelimit": map[string]interface{}{"Delete": int64(3)}},
+		{"ratelimit": map[string]interface{}{"Read": -1}},
+		{"ratelimit": map[string]interface{}{"Burst": 2.5}},
+		{"ratelimit": map[string]interface{}{"BreakerCooldown": "30s"}},
+	}
+	for _, v := range invalids {
+		assert.NotNil(t, validateRateLimits(svc, v), v)
+	}
+}
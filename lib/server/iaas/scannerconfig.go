@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iaas
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// DefaultScannerParallelism is the number of probe hosts created simultaneously by the scanner if not configured
+const DefaultScannerParallelism uint = 4
+
+// ScannerConfig contains the settings of the tenant scanner, read from tenants file
+type ScannerConfig struct {
+	Scannable   bool     // tells if the tenant may be scanned (keyword 'Scannable' of section 'compute')
+	Images      []string // names of the images usable by probe hosts, by order of preference
+	Templates   []string // regexps selecting the templates to scan (all templates if empty)
+	Parallelism uint     // number of probe hosts created simultaneously
+	Budget      float64  // maximum cost of a scan, in the currency of the prices per hour (no limit if 0)
+	NetSpeedURL string   // URL downloaded by probe hosts to measure network speed (not measured if empty)
+}
+
+// validateScannerConfig validates the scanner settings from tenants file
+func validateScannerConfig(svc *service, tenant map[string]interface{}) fail.Error {
+	compute, ok := tenant["compute"].(map[string]interface{})
+	if !ok {
+		return fail.InvalidParameterError("tenant['compute']", "is not a map")
+	}
+
+	svc.scannerConfig = ScannerConfig{Parallelism: DefaultScannerParallelism}
+	if anon, ok := compute["Scannable"]; ok {
+		if svc.scannerConfig.Scannable, ok = anon.(bool); !ok {
+			return fail.SyntaxError("invalid value for keyword 'Scannable': must be a boolean")
+		}
+	}
+
+	scanner, ok := tenant["scanner"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var xerr fail.Error
+	if svc.scannerConfig.Images, xerr = stringsOfKeyword("Images", scanner["Images"]); xerr != nil {
+		return xerr
+	}
+	if svc.scannerConfig.Templates, xerr = stringsOfKeyword("Templates", scanner["Templates"]); xerr != nil {
+		return xerr
+	}
+	for _, v := range svc.scannerConfig.Templates {
+		if _, err := regexp.Compile(v); err != nil {
+			return fail.SyntaxError("invalid value '%s' for keyword 'Templates': %s", v, err.Error())
+		}
+	}
+	if anon, ok := scanner["Parallelism"]; ok {
+		value, ok := numberOfKeyword(anon)
+		if !ok || value < 1 || value != float64(uint(value)) {
+			return fail.SyntaxError("invalid value '%v' for keyword 'Parallelism': must be an integer greater than 0", anon)
+		}
+		svc.scannerConfig.Parallelism = uint(value)
+	}
+	if anon, ok := scanner["Budget"]; ok {
+		if svc.scannerConfig.Budget, ok = numberOfKeyword(anon); !ok || svc.scannerConfig.Budget < 0 {
+			return fail.SyntaxError("invalid value '%v' for keyword 'Budget': must be a positive number", anon)
+		}
+	}
+	if anon, ok := scanner["NetSpeedURL"]; ok {
+		if svc.scannerConfig.NetSpeedURL, ok = anon.(string); !ok {
+			return fail.SyntaxError("invalid value for keyword 'NetSpeedURL': must be a string")
+		}
+		if _, err := url.ParseRequestURI(svc.scannerConfig.NetSpeedURL); err != nil || strings.ContainsAny(svc.scannerConfig.NetSpeedURL, "'\"") {
+			return fail.SyntaxError("invalid value '%s' for keyword 'NetSpeedURL': must be a valid URL", svc.scannerConfig.NetSpeedURL)
+		}
+	}
+	return nil
+}
+
+// numberOfKeyword converts the content of a numerical keyword, whatever the format of the tenants file
+func numberOfKeyword(content interface{}) (float64, bool) {
+	switch value := content.(type) {
+	case float64:
+		return value, true
+	case int64:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	default:
+		return 0, false
+	}
+}
+
+// stringsOfKeyword reads the content of a keyword accepting a string or a list of strings
+func stringsOfKeyword(keyword string, content interface{}) ([]string, fail.Error) {
+	switch value := content.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{value}, nil
+	case []interface{}:
+		out := make([]string, 0, len(value))
+		for _, v := range value {
+			str, ok := v.(string)
+			if !ok {
+				return nil, fail.SyntaxError("invalid value '%v' in keyword '%s': must be a string", v, keyword)
+			}
+			out = append(out, str)
+		}
+		return out, nil
+	default:
+		return nil, fail.SyntaxError("invalid value for keyword '%s': must be a string or a list of strings", keyword)
+	}
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package scannerdb persists, per tenant and region, the host template data collected by the tenant scanner
+package scannerdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	scribble "github.com/nanobox-io/golang-scribble"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// DefaultPath is the folder containing the scanner database by default
+const DefaultPath = "$HOME/.safescale/scanner/db"
+
+// Predicate tells if a scanned record has to be kept by Query
+type Predicate func(abstract.StoredCPUInfo) bool
+
+// Probe is the journal entry of a probe host created by the scanner, kept until the host is deleted
+type Probe struct {
+	HostName     string `json:"host_name"`
+	TemplateName string `json:"template_name"`
+	Created      string `json:"created"`
+	ScanID       string `json:"scan_id,omitempty"` // identifies the scan owning the probe host
+}
+
+// Store gives access to the scanned data of a tenant in a region
+type Store struct {
+	driver       *scribble.Driver
+	folder       string
+	probesFolder string
+}
+
+// Open opens the store of tenant 'tenant' in region 'region' located in DefaultPath
+func Open(tenant, region string) (*Store, fail.Error) {
+	return OpenAt(utils.AbsPathify(DefaultPath), tenant, region)
+}
+
+// OpenAt opens the store of tenant 'tenant' in region 'region' located in folder 'path'
+func OpenAt(path, tenant, region string) (*Store, fail.Error) {
+	if path == "" {
+		return nil, fail.InvalidParameterError("path", "cannot be empty string")
+	}
+	if tenant == "" {
+		return nil, fail.InvalidParameterError("tenant", "cannot be empty string")
+	}
+	if region == "" {
+		return nil, fail.InvalidParameterError("region", "cannot be empty string")
+	}
+
+	if err := os.MkdirAll(path, 0777); err != nil {
+		return nil, fail.ToError(err)
+	}
+	driver, err := scribble.New(path, nil)
+	if err != nil {
+		return nil, fail.Wrap(err, "failed to open scanner database")
+	}
+	return &Store{
+		driver:       driver,
+		folder:       fmt.Sprintf("images/%s/%s", tenant, region),
+		probesFolder: fmt.Sprintf("probes/%s/%s", tenant, region),
+	}, nil
+}
+
+// Write stores (or replaces) the scanned data of a template
+func (s *Store) Write(info abstract.StoredCPUInfo) fail.Error {
+	if s == nil {
+		return fail.InvalidInstanceError()
+	}
+	if info.TemplateName == "" {
+		return fail.InvalidParameterError("info.TemplateName", "cannot be empty string")
+	}
+
+	if err := s.driver.Write(s.folder, info.TemplateName, info); err != nil {
+		return fail.Wrap(err, "failed to store scanned data of template '%s'", info.TemplateName)
+	}
+	return nil
+}
+
+// Read returns the scanned data of the template named 'name'
+func (s *Store) Read(name string) (*abstract.StoredCPUInfo, fail.Error) {
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if name == "" {
+		return nil, fail.InvalidParameterError("name", "cannot be empty string")
+	}
+
+	var info abstract.StoredCPUInfo
+	if err := s.driver.Read(s.folder, name, &info); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fail.NotFoundError("no scanned data found for template '%s'", name)
+		}
+		return nil, fail.Wrap(err, "failed to read scanned data of template '%s'", name)
+	}
+	return &info, nil
+}
+
+// Delete removes the scanned data of the template named 'name'
+func (s *Store) Delete(name string) fail.Error {
+	if s == nil {
+		return fail.InvalidInstanceError()
+	}
+	if name == "" {
+		return fail.InvalidParameterError("name", "cannot be empty string")
+	}
+
+	if err := s.driver.Delete(s.folder, name); err != nil {
+		return fail.NotFoundError("no scanned data found for template '%s'", name)
+	}
+	return nil
+}
+
+// ReadAll returns all the scanned data of the tenant (empty slice if the tenant has never been scanned)
+func (s *Store) ReadAll() ([]abstract.StoredCPUInfo, fail.Error) {
+	return s.Query(nil)
+}
+
+// Query returns the scanned data satisfying the predicate (all of them if predicate is nil)
+func (s *Store) Query(predicate Predicate) ([]abstract.StoredCPUInfo, fail.Error) {
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+
+	records, err := s.driver.ReadAll(s.folder)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []abstract.StoredCPUInfo{}, nil
+		}
+		return nil, fail.Wrap(err, "failed to read scanner database")
+	}
+
+	out := make([]abstract.StoredCPUInfo, 0, len(records))
+	for _, r := range records {
+		var info abstract.StoredCPUInfo
+		if err := json.Unmarshal([]byte(r), &info); err != nil {
+			return nil, fail.Wrap(err, "failed to decode scanned data")
+		}
+		if predicate == nil || predicate(info) {
+			out = append(out, info)
+		}
+	}
+	return out, nil
+}
+
+// IndexByTemplateID returns the scanned data indexed by template ID
+func (s *Store) IndexByTemplateID() (map[string]abstract.StoredCPUInfo, fail.Error) {
+	list, xerr := s.ReadAll()
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	out := make(map[string]abstract.StoredCPUInfo, len(list))
+	for _, v := range list {
+		out[v.TemplateID] = v
+	}
+	return out, nil
+}
+
+// RecordProbe journals a probe host, allowing its removal if the scan is interrupted
+func (s *Store) RecordProbe(probe Probe) fail.Error {
+	if s == nil {
+		return fail.InvalidInstanceError()
+	}
+	if probe.HostName == "" {
+		return fail.InvalidParameterError("probe.HostName", "cannot be empty string")
+	}
+
+	if err := s.driver.Write(s.probesFolder, probe.HostName, probe); err != nil {
+		return fail.Wrap(err, "failed to journal probe host '%s'", probe.HostName)
+	}
+	return nil
+}
+
+// ForgetProbe removes a probe host from the journal
+func (s *Store) ForgetProbe(hostName string) fail.Error {
+	if s == nil {
+		return fail.InvalidInstanceError()
+	}
+	if hostName == "" {
+		return fail.InvalidParameterError("hostName", "cannot be empty string")
+	}
+
+	var probe Probe
+	if err := s.driver.Read(s.probesFolder, hostName, &probe); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fail.Wrap(err, "failed to read probe hosts journal")
+	}
+	if err := s.driver.Delete(s.probesFolder, hostName); err != nil {
+		return fail.Wrap(err, "failed to remove probe host '%s' from journal", hostName)
+	}
+	return nil
+}
+
+// ListProbes returns the probe hosts present in the journal
+func (s *Store) ListProbes() ([]Probe, fail.Error) {
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+
+	records, err := s.driver.ReadAll(s.probesFolder)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Probe{}, nil
+		}
+		return nil, fail.Wrap(err, "failed to read probe hosts journal")
+	}
+
+	out := make([]Probe, 0, len(records))
+	for _, r := range records {
+		var probe Probe
+		if err := json.Unmarshal([]byte(r), &probe); err != nil {
+			return nil, fail.Wrap(err, "failed to decode probe hosts journal")
+		}
+		out = append(out, probe)
+	}
+	return out, nil
+}
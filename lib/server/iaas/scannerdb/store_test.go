@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scannerdb
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scannerdb")
+	require.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	store, xerr := OpenAt(dir, "tenant", "region")
+	require.Nil(t, xerr)
+
+	// never scanned tenant
+	list, xerr := store.ReadAll()
+	require.Nil(t, xerr)
+	require.Empty(t, list)
+
+	require.Nil(t, store.Write(abstract.StoredCPUInfo{TemplateID: "1", TemplateName: "s1-2", GPU: 0}))
+	require.Nil(t, store.Write(abstract.StoredCPUInfo{TemplateID: "2", TemplateName: "g1-8", GPU: 1}))
+
+	list, xerr = store.Query(func(info abstract.StoredCPUInfo) bool { return info.GPU > 0 })
+	require.Nil(t, xerr)
+	require.Len(t, list, 1)
+	require.Equal(t, "g1-8", list[0].TemplateName)
+
+	index, xerr := store.IndexByTemplateID()
+	require.Nil(t, xerr)
+	require.Contains(t, index, "1")
+	require.Contains(t, index, "2")
+
+	require.Nil(t, store.Delete("s1-2"))
+	_, xerr = store.Read("s1-2")
+	require.NotNil(t, xerr)
+	_, ok := xerr.(*fail.ErrNotFound)
+	require.True(t, ok)
+
+	// another tenant does not see the data
+	other, xerr := OpenAt(dir, "other", "region")
+	require.Nil(t, xerr)
+	list, xerr = other.ReadAll()
+	require.Nil(t, xerr)
+	require.Empty(t, list)
+}
+
+func TestProbesJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scannerdb")
+	require.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	store, xerr := OpenAt(dir, "tenant", "region")
+	require.Nil(t, xerr)
+
+	require.Nil(t, store.RecordProbe(Probe{HostName: "scanhost-s1-2", TemplateName: "s1-2"}))
+	probes, xerr := store.ListProbes()
+	require.Nil(t, xerr)
+	require.Len(t, probes, 1)
+	require.Equal(t, "s1-2", probes[0].TemplateName)
+
+	require.Nil(t, store.ForgetProbe("scanhost-s1-2"))
+	require.Nil(t, store.ForgetProbe("scanhost-s1-2"))
+	probes, xerr = store.ListProbes()
+	require.Nil(t, xerr)
+	require.Empty(t, probes)
+}
@@ -17,30 +17,29 @@
 package iaas
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"github.com/CS-SI/SafeScale/lib/utils/data"
 	"math"
-	"os"
 	"regexp"
 	"sort"
 	"strings"
 	"time"
 
-	scribble "github.com/nanobox-io/golang-scribble"
 	uuid "github.com/satori/go.uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/xrash/smetrics"
 
 	"github.com/CS-SI/SafeScale/lib/server/iaas/objectstorage"
 	"github.com/CS-SI/SafeScale/lib/server/iaas/providers"
+	"github.com/CS-SI/SafeScale/lib/server/iaas/scannerdb"
 	"github.com/CS-SI/SafeScale/lib/server/iaas/userdata"
 	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
 	imagefilters "github.com/CS-SI/SafeScale/lib/server/resources/abstract/filters/images"
 	templatefilters "github.com/CS-SI/SafeScale/lib/server/resources/abstract/filters/templates"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/hoststate"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/templateselection"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/volumestate"
-	"github.com/CS-SI/SafeScale/lib/utils"
 	"github.com/CS-SI/SafeScale/lib/utils/crypt"
 	"github.com/CS-SI/SafeScale/lib/utils/debug"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
@@ -61,6 +60,10 @@ type Service interface {
 	FindTemplateByName(string) (*abstract.HostTemplate, fail.Error)
 	GetMetadataBucket() abstract.ObjectStorageBucket
 	GetMetadataKey() (*crypt.Key, fail.Error)
+	GetProviderQuotas() (abstract.ProviderQuotas, fail.Error)
+	GetQuotas() Quotas
+	GetScannerConfig() ScannerConfig
+	GetTemplatePrice(string) (float64, bool)
 	InspectHostByName(string) (*abstract.HostFull, fail.Error)
 	InspectSecurityGroupByName(networkID string, name string) (*abstract.SecurityGroup, fail.Error)
 	ListHostsByName(bool) (map[string]*abstract.HostFull, fail.Error)
@@ -69,6 +72,7 @@ type Service interface {
 	TenantCleanup(bool) fail.Error // cleans up the data relative to SafeScale from tenant (not implemented yet)
 	WaitHostState(string, hoststate.Enum, time.Duration) fail.Error
 	WaitVolumeState(string, volumestate.Enum, time.Duration) (*abstract.Volume, fail.Error)
+	WithContext(context.Context) Service // returns a copy of the service making its calls to the provider on behalf of the context
 
 	// --- from interface iaas.Providers ---
 	providers.Provider
@@ -92,6 +96,12 @@ type service struct {
 	blacklistTemplateREs []*regexp.Regexp
 	whitelistImageREs    []*regexp.Regexp
 	blacklistImageREs    []*regexp.Regexp
+
+	templateSelection templateselection.Enum
+	templatePrices    map[string]float64
+	scannerConfig     ScannerConfig
+	quotas            Quotas
+	rateLimits        providers.RateLimits
 }
 
 const (
@@ -166,6 +176,55 @@ func (svc service) GetMetadataKey() (*crypt.Key, fail.Error) {
 	return svc.metadataKey, nil
 }
 
+// GetProviderQuotas returns the quotas set on the account by the provider, if the provider can report them
+func (svc service) GetProviderQuotas() (abstract.ProviderQuotas, fail.Error) {
+	if svc.IsNull() {
+		return nil, fail.InvalidInstanceError()
+	}
+	if reporter, ok := svc.Provider.(providers.QuotaReporter); ok {
+		return reporter.GetProviderQuotas()
+	}
+	return nil, fail.NotImplementedError("quotas of provider '%s' are not available", svc.GetName())
+}
+
+// GetQuotas returns the quotas of the tenant
+func (svc service) GetQuotas() Quotas {
+	if svc.IsNull() {
+		return Quotas{}
+	}
+	return svc.quotas
+}
+
+// WithContext returns a copy of the service whose calls to the provider are made on behalf of 'ctx'; the cancellation
+// of 'ctx' interrupts the calls waiting for the rate limits of the tenant
+func (svc *service) WithContext(ctx context.Context) Service {
+	if svc.IsNull() {
+		return svc
+	}
+	out := *svc
+	if rlp, ok := svc.Provider.(*providers.RateLimitedProvider); ok {
+		out.Provider = rlp.WithContext(ctx)
+	}
+	return &out
+}
+
+// GetScannerConfig returns the settings of the tenant scanner
+func (svc service) GetScannerConfig() ScannerConfig {
+	if svc.IsNull() {
+		return ScannerConfig{}
+	}
+	return svc.scannerConfig
+}
+
+// GetTemplatePrice returns the price per hour of the template named 'name' configured in tenant
+func (svc service) GetTemplatePrice(name string) (float64, bool) {
+	if svc.IsNull() {
+		return 0, false
+	}
+	price, ok := svc.templatePrices[name]
+	return price, ok
+}
+
 // ChangeProvider allows to change provider interface of service object (mainly for test purposes)
 func (svc *service) ChangeProvider(provider providers.Provider) fail.Error {
 	if svc.IsNull() {
@@ -371,93 +430,40 @@ func (svc service) ListTemplatesBySizing(sizing abstract.HostSizingRequirements,
 	tracer := debug.NewTracer(nil, true, "").Entering()
 	defer tracer.Exiting()
 
+	policy := svc.templateSelection
+	if sizing.Policy != "" {
+		if policy, rerr = templateselection.Parse(sizing.Policy); rerr != nil {
+			return nil, fail.InvalidParameterError("sizing.Policy", rerr.Error())
+		}
+	}
+
 	allTpls, rerr := svc.ListTemplates(false)
-	scannerTpls := map[string]bool{}
 	if rerr != nil {
 		return nil, rerr
 	}
 
 	// FIXME: Prevent GPUs when user sends a 0
 	askedForSpecificScannerInfo := sizing.MinGPU >= 0 || sizing.MinCPUFreq != 0
-	if askedForSpecificScannerInfo {
-		_ = os.MkdirAll(utils.AbsPathify("$HOME/.safescale/scanner"), 0777)
-		db, err := scribble.New(utils.AbsPathify("$HOME/.safescale/scanner/db"), nil)
-		if err != nil {
-			if force {
-				logrus.Warnf("Problem creating / accessing Scanner database, ignoring GPU and Freq parameters for now...: %v", err)
-			} else {
-				var noHostError string
-				if sizing.MinCPUFreq <= 0 {
-					noHostError = fmt.Sprintf("unable to create a host with '%d' GPUs, problem accessing Scanner database: %v", sizing.MinGPU, err)
-				} else {
-					noHostError = fmt.Sprintf("unable to create a host with '%d' GPUs and '%.01f' MHz clock frequency, problem accessing Scanner database: %v", sizing.MinGPU, sizing.MinCPUFreq, err)
-				}
+	var scannedTpls map[string]abstract.StoredCPUInfo
+	if askedForSpecificScannerInfo || policy != templateselection.DRF {
+		scannedTpls, rerr = svc.readScannedTemplates()
+		if rerr != nil {
+			if askedForSpecificScannerInfo && !force {
+				noHostError := scannerErrorMessage(sizing, "problem accessing Scanner database: "+rerr.Error())
+				logrus.Error(noHostError)
 				return nil, fail.NewError(noHostError)
 			}
-		} else {
-			authOpts, rerr := svc.GetAuthenticationOptions()
-			if rerr != nil {
-				return nil, rerr
-			}
-			region, ok := authOpts.Get("Region")
-			if !ok {
-				return nil, fail.SyntaxError("region value unset")
-			}
-			folder := fmt.Sprintf("images/%s/%s", svc.GetName(), region)
-
-			imageList, err := db.ReadAll(folder)
-			if err != nil {
-				if force {
-					logrus.Warnf("Problem creating / accessing Scanner database, ignoring GPU and Freq parameters for now...: %v", err)
-				} else {
-					var noHostError string
-					if sizing.MinCPUFreq <= 0 {
-						noHostError = fmt.Sprintf("Unable to create a host with '%d' GPUs, problem accessing Scanner database: %v", sizing.MinGPU, err)
-					} else {
-						noHostError = fmt.Sprintf("Unable to create a host with '%d' GPUs and '%.01f' MHz clock frequency, problem accessing Scanner database: %v", sizing.MinGPU, sizing.MinCPUFreq, err)
-					}
-					logrus.Error(noHostError)
-					return nil, fail.NewError(noHostError)
-				}
-			} else {
-				var images []abstract.StoredCPUInfo
-				for _, f := range imageList {
-					imageFound := abstract.StoredCPUInfo{}
-					if err := json.Unmarshal([]byte(f), &imageFound); err != nil {
-						return nil, fail.Wrap(err, "error unmarshalling image '%s'")
-					}
-
-					// if the user asked explicitly no gpu
-					if sizing.MinGPU == 0 && imageFound.GPU != 0 {
-						continue
-					}
-
-					if imageFound.GPU < sizing.MinGPU {
-						continue
-					}
-
-					if imageFound.CPUFrequency < float64(sizing.MinCPUFreq) {
-						continue
-					}
-
-					images = append(images, imageFound)
-				}
-
-				if !force && (len(images) == 0) {
-					var noHostError string
-					if sizing.MinCPUFreq <= 0 {
-						noHostError = fmt.Sprintf("Unable to create a host with '%d' GPUs, no images matching requirements", sizing.MinGPU)
-					} else {
-						noHostError = fmt.Sprintf("Unable to create a host with '%d' GPUs and a CPU clock frequencyof '%.01f MHz', no images matching requirements", sizing.MinGPU, sizing.MinCPUFreq)
-					}
-					logrus.Error(noHostError)
-					return nil, fail.NewError(noHostError)
-				}
-
-				for _, image := range images {
-					scannerTpls[image.TemplateID] = true
-				}
-			}
+			logrus.Warnf("Problem creating / accessing Scanner database, ignoring GPU and Freq parameters for now...: %v", rerr)
+			askedForSpecificScannerInfo = false
+		}
+	}
+	scannerTpls := map[string]bool{}
+	if askedForSpecificScannerInfo {
+		scannerTpls = filterScannedTemplates(sizing, scannedTpls)
+		if !force && len(scannerTpls) == 0 {
+			noHostError := scannerErrorMessage(sizing, "no images matching requirements")
+			logrus.Error(noHostError)
+			return nil, fail.NewError(noHostError)
 		}
 	}
 
@@ -529,10 +535,55 @@ func (svc service) ListTemplatesBySizing(sizing abstract.HostSizingRequirements,
 		}
 	}
 
-	sort.Sort(ByRankDRF(selectedTpls))
+	rankTemplates(selectedTpls, policy, scannedTpls, svc.templatePrices)
 	return selectedTpls, nil
 }
 
+// readScannedTemplates returns the data collected by the scanner on the tenant, indexed by template ID
+func (svc service) readScannedTemplates() (map[string]abstract.StoredCPUInfo, fail.Error) {
+	authOpts, xerr := svc.GetAuthenticationOptions()
+	if xerr != nil {
+		return nil, xerr
+	}
+	region := authOpts.GetString("Region")
+	if region == "" {
+		return nil, fail.SyntaxError("region value unset")
+	}
+
+	store, xerr := scannerdb.Open(svc.GetName(), region)
+	if xerr != nil {
+		return nil, xerr
+	}
+	return store.IndexByTemplateID()
+}
+
+// filterScannedTemplates returns the IDs of the templates whose scanned data satisfy GPU and CPU frequency requirements
+func filterScannedTemplates(sizing abstract.HostSizingRequirements, scanned map[string]abstract.StoredCPUInfo) map[string]bool {
+	out := map[string]bool{}
+	for _, v := range scanned {
+		// if the user asked explicitly no gpu
+		if sizing.MinGPU == 0 && v.GPU != 0 {
+			continue
+		}
+		if v.GPU < sizing.MinGPU {
+			continue
+		}
+		if v.CPUFrequency < float64(sizing.MinCPUFreq) {
+			continue
+		}
+		out[v.TemplateID] = true
+	}
+	return out
+}
+
+// scannerErrorMessage builds the message explaining why no template can satisfy GPU and CPU frequency requirements
+func scannerErrorMessage(sizing abstract.HostSizingRequirements, reason string) string {
+	if sizing.MinCPUFreq <= 0 {
+		return fmt.Sprintf("unable to create a host with '%d' GPUs, %s", sizing.MinGPU, reason)
+	}
+	return fmt.Sprintf("unable to create a host with '%d' GPUs and a CPU clock frequency of '%.01f MHz', %s", sizing.MinGPU, sizing.MinCPUFreq, reason)
+}
+
 type scoredImage struct {
 	abstract.Image
 	score float64
@@ -54,6 +54,8 @@ func normalizeError(err error) fail.Error {
 			return fail.OverloadError(cerr.Message())
 		case "DependencyViolation":
 			return fail.NotAvailableError(cerr.Message())
+		case "Throttling", "ThrottlingException", "RequestLimitExceeded":
+			return fail.ThrottledError(0, cerr.Message())
 		default:
 			switch cerr := err.(type) {
 			case awserr.RequestFailure:
@@ -75,7 +77,7 @@ func normalizeError(err error) fail.Error {
 				case 425:
 					return fail.OverloadError(err.Error())
 				case 429:
-					return fail.OverloadError(err.Error())
+					return fail.ThrottledError(0, err.Error())
 				case 500:
 					return fail.ExecutionError(nil, err.Error())
 				case 503:
@@ -31,6 +31,7 @@ import (
 	az "github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/availabilityzones"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/floatingips"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/keypairs"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/limits"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/startstop"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
@@ -132,6 +133,46 @@ func (s Stack) ListAvailabilityZones() (list map[string]bool, xerr fail.Error) {
 	return azList, nil
 }
 
+// GetProviderQuotas returns the quotas of the project set on the compute service
+func (s Stack) GetProviderQuotas() (_ abstract.ProviderQuotas, xerr fail.Error) {
+	if s.IsNull() {
+		return nil, fail.InvalidInstanceError()
+	}
+
+	tracer := debug.NewTracer(nil, tracing.ShouldTrace("Stack.openstack") || tracing.ShouldTrace("stacks.compute"), "").Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&xerr, tracer.TraceMessage(""))
+
+	var content *limits.Limits
+	xerr = stacks.RetryableRemoteCall(
+		func() (innerErr error) {
+			content, innerErr = limits.Get(s.ComputeClient, nil).Extract()
+			return innerErr
+		},
+		NormalizeError,
+	)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	// openstack uses -1 to tell there is no limit, and counts RAM in MB
+	absolute := content.Absolute
+	ramLimit := float64(absolute.MaxTotalRAMSize)
+	if ramLimit > 0 {
+		ramLimit /= 1024.0
+	}
+	out := abstract.ProviderQuotas{
+		"Hosts": {Limit: float64(absolute.MaxTotalInstances), Used: float64(absolute.TotalInstancesUsed)},
+		"Cores": {Limit: float64(absolute.MaxTotalCores), Used: float64(absolute.TotalCoresUsed)},
+		"RAM":   {Limit: ramLimit, Used: float64(absolute.TotalRAMUsed) / 1024.0},
+	}
+	// Public IP addresses are floating IPs only if the tenant uses them
+	if s.cfgOpts.UseFloatingIP {
+		out["PublicIPs"] = abstract.ProviderQuota{Limit: float64(absolute.MaxTotalFloatingIps), Used: float64(absolute.TotalFloatingIpsUsed)}
+	}
+	return out, nil
+}
+
 // ListImages lists available OS images
 func (s Stack) ListImages() (imgList []abstract.Image, xerr fail.Error) {
 	var emptySlice []abstract.Image
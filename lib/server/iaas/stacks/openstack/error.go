@@ -100,10 +100,10 @@ func NormalizeError(err error) fail.Error {
 	case *gophercloud.ErrDefault409: // conflict
 		// It may be a NeutronError, to be parsed
 		return reduceOpenstackError("Duplicate", e.Body)
-	case gophercloud.ErrDefault429: // too many requests
-		return fail.OverloadError(string(e.Body))
+	case gophercloud.ErrDefault429: // too many requests; gophercloud does not keep the response headers, so no Retry-After
+		return fail.ThrottledError(0, string(e.Body))
 	case *gophercloud.ErrDefault429: // too many requests
-		return fail.OverloadError(string(e.Body))
+		return fail.ThrottledError(0, string(e.Body))
 	case gophercloud.ErrDefault500: // internal server error
 		return fail.ExecutionError(nil, string(e.Body))
 	case *gophercloud.ErrDefault500: // internal server error
@@ -232,7 +232,7 @@ func qualifyGophercloudResponseCode(err *gophercloud.ErrUnexpectedResponseCode)
 	case 408:
 		newError = &gophercloud.ErrDefault408{ErrUnexpectedResponseCode: *err}
 	case 429:
-		newError = &gophercloud.ErrDefault409{ErrUnexpectedResponseCode: *err}
+		newError = &gophercloud.ErrDefault429{ErrUnexpectedResponseCode: *err}
 	case 500:
 		newError = &gophercloud.ErrDefault500{ErrUnexpectedResponseCode: *err}
 	case 503:
@@ -19,18 +19,46 @@ package outscale
 
 import (
 	"encoding/json"
+	"net/http"
 	"reflect"
 
 	"github.com/outscale/osc-sdk-go/osc"
 
+	"github.com/CS-SI/SafeScale/lib/server/iaas/stacks"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
 )
 
+// responseError keeps the HTTP response received with an error returned by the outscale SDK
+type responseError struct {
+	error
+	resp *http.Response
+}
+
+// withResponse associates the HTTP response to the error returned by a call to the outscale SDK
+func withResponse(resp *http.Response, err error) error {
+	if err == nil || resp == nil {
+		return err
+	}
+	return responseError{error: err, resp: resp}
+}
+
 func normalizeError(err error) fail.Error {
 	if err == nil {
 		return nil
 	}
 
+	if realErr, ok := err.(responseError); ok {
+		switch realErr.resp.StatusCode {
+		case http.StatusTooManyRequests:
+			return fail.ThrottledError(stacks.ParseRetryAfter(realErr.resp.Header.Get("Retry-After")), realErr.Error())
+		case http.StatusServiceUnavailable:
+			if retryAfter := realErr.resp.Header.Get("Retry-After"); retryAfter != "" {
+				return fail.ThrottledError(stacks.ParseRetryAfter(retryAfter), realErr.Error())
+			}
+		}
+		err = realErr.error
+	}
+
 	switch realErr := err.(type) {
 	case osc.GenericOpenAPIError:
 		switch model := realErr.Model().(type) {
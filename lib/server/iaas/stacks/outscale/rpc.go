@@ -17,6 +17,8 @@
 package outscale
 
 import (
+	"net/http"
+
 	"github.com/antihax/optional"
 
 	"github.com/outscale/osc-sdk-go/osc"
@@ -38,9 +40,9 @@ func (s stack) rpcReadSecurityGroups(networkID string, sgIDs []string) ([]osc.Se
 	var resp osc.ReadSecurityGroupsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (innerErr error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, innerErr = s.client.SecurityGroupApi.ReadSecurityGroups(s.auth, &opts)
-			return innerErr
+			var httpResp *http.Response
+			resp, httpResp, innerErr = s.client.SecurityGroupApi.ReadSecurityGroups(s.auth, &opts)
+			return withResponse(httpResp, innerErr)
 		},
 		normalizeError,
 	)
@@ -101,9 +103,9 @@ func (s stack) rpcReadSecurityGroupByName(networkID, name string) (osc.SecurityG
 	var resp osc.ReadSecurityGroupsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (innerErr error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, innerErr = s.client.SecurityGroupApi.ReadSecurityGroups(s.auth, &opts)
-			return innerErr
+			var httpResp *http.Response
+			resp, httpResp, innerErr = s.client.SecurityGroupApi.ReadSecurityGroups(s.auth, &opts)
+			return withResponse(httpResp, innerErr)
 		},
 		normalizeError,
 	)
@@ -132,9 +134,9 @@ func (s stack) rpcReadVms(vmIDs []string) ([]osc.Vm, fail.Error) {
 	var resp osc.ReadVmsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (innerErr error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, innerErr = s.client.VmApi.ReadVms(s.auth, &query)
-			return innerErr
+			var httpResp *http.Response
+			resp, httpResp, innerErr = s.client.VmApi.ReadVms(s.auth, &query)
+			return withResponse(httpResp, innerErr)
 		},
 		normalizeError,
 	)
@@ -215,9 +217,9 @@ func (s stack) rpcReadVmByName(name string) (osc.Vm, fail.Error) {
 	var resp osc.ReadVmsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.VmApi.ReadVms(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.VmApi.ReadVms(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -254,9 +256,8 @@ func (s stack) rpcDeleteVms(vmIDs []string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, innerErr := s.client.VmApi.DeleteVms(s.auth, &opts)
-			return innerErr
+			_, httpResp, innerErr := s.client.VmApi.DeleteVms(s.auth, &opts)
+			return withResponse(httpResp, innerErr)
 		},
 		normalizeError,
 	)
@@ -276,9 +277,9 @@ func (s stack) rpcCreateNetwork(name, cidr string) (osc.Net, fail.Error) {
 	var resp osc.CreateNetResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.NetApi.CreateNet(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.NetApi.CreateNet(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -314,9 +315,8 @@ func (s stack) rpcUpdateNet(networkID, dhcpOptionsSetID string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.NetApi.UpdateNet(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.NetApi.UpdateNet(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -334,9 +334,8 @@ func (s stack) rpcDeleteNetwork(id string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, innerErr := s.client.NetApi.DeleteNet(s.auth, &opts)
-			return innerErr
+			_, httpResp, innerErr := s.client.NetApi.DeleteNet(s.auth, &opts)
+			return withResponse(httpResp, innerErr)
 		},
 		normalizeError,
 	)
@@ -358,9 +357,8 @@ func (s stack) rpcCreateTags(id string, tags map[string]string) ([]osc.ResourceT
 	}
 	xerr := stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, innerErr := s.client.TagApi.CreateTags(s.auth, &opts)
-			return innerErr
+			_, httpResp, innerErr := s.client.TagApi.CreateTags(s.auth, &opts)
+			return withResponse(httpResp, innerErr)
 		},
 		normalizeError,
 	)
@@ -382,9 +380,8 @@ func (s stack) rpcDeleteSubnet(id string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.SubnetApi.DeleteSubnet(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.SubnetApi.DeleteSubnet(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -411,9 +408,9 @@ func (s stack) rpcCreateSubnet(name, vpcID, cidr string) (osc.Subnet, fail.Error
 	var resp osc.CreateSubnetResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.SubnetApi.CreateSubnet(s.auth, &createRequest)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.SubnetApi.CreateSubnet(s.auth, &createRequest)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -437,9 +434,8 @@ func (s stack) rpcCreateSubnet(name, vpcID, cidr string) (osc.Subnet, fail.Error
 	}
 	xerr = stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.SubnetApi.UpdateSubnet(s.auth, &updateRequest)
-			return err
+			_, httpResp, err := s.client.SubnetApi.UpdateSubnet(s.auth, &updateRequest)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -474,9 +470,9 @@ func (s stack) rpcReadSubnets(networkID string, ids []string) ([]osc.Subnet, fai
 	var resp osc.ReadSubnetsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.SubnetApi.ReadSubnets(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.SubnetApi.ReadSubnets(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -529,9 +525,9 @@ func (s stack) rpcReadTagsOfResource(resourceID string) (map[string]string, fail
 	var resp osc.ReadTagsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.TagApi.ReadTags(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.TagApi.ReadTags(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -560,9 +556,9 @@ func (s stack) rpcReadNics(subnetID, hostID string) ([]osc.Nic, fail.Error) {
 	var resp osc.ReadNicsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.NicApi.ReadNics(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.NicApi.ReadNics(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -606,9 +602,9 @@ func (s stack) rpcCreateNic(subnetID, name, description string, sgs []string) (o
 	var resp osc.CreateNicResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.NicApi.CreateNic(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.NicApi.CreateNic(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -647,9 +643,8 @@ func (s stack) rpcDeleteNic(id string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.NicApi.DeleteNic(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.NicApi.DeleteNic(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -672,9 +667,8 @@ func (s stack) rpcLinkNic(vmID, nicID string, order int32) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.NicApi.LinkNic(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.NicApi.LinkNic(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -690,9 +684,9 @@ func (s stack) rpcCreateDhcpOptions(name string, dnsServers, ntpServers []string
 	var resp osc.CreateDhcpOptionsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.DhcpOptionApi.CreateDhcpOptions(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.DhcpOptionApi.CreateDhcpOptions(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -725,9 +719,9 @@ func (s stack) rpcReadDhcpOptionsByID(id string) ([]osc.DhcpOptionsSet, fail.Err
 	var resp osc.ReadDhcpOptionsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.DhcpOptionApi.ReadDhcpOptions(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.DhcpOptionApi.ReadDhcpOptions(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -752,9 +746,8 @@ func (s stack) rpcDeleteDhcpOptions(id string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.DhcpOptionApi.DeleteDhcpOptions(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.DhcpOptionApi.DeleteDhcpOptions(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -772,9 +765,9 @@ func (s stack) rpcCreateFlexibleGpu(model string) (osc.FlexibleGpu, fail.Error)
 	var resp osc.CreateFlexibleGpuResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.FlexibleGpuApi.CreateFlexibleGpu(s.auth, &createFlexibleGpuOpts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.FlexibleGpuApi.CreateFlexibleGpu(s.auth, &createFlexibleGpuOpts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -793,9 +786,8 @@ func (s stack) rpcLinkFlexibleGpu(gpuID, vmID string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.FlexibleGpuApi.LinkFlexibleGpu(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.FlexibleGpuApi.LinkFlexibleGpu(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -813,9 +805,8 @@ func (s stack) rpcDeleteFlexibleGpu(id string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.FlexibleGpuApi.DeleteFlexibleGpu(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.FlexibleGpuApi.DeleteFlexibleGpu(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -838,9 +829,8 @@ func (s stack) rpcUpdateVmSecurityGroups(id string, sgs []string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.VmApi.UpdateVm(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.VmApi.UpdateVm(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -859,9 +849,9 @@ func (s stack) rpcCreateVolume(name string, size int32, iops int32, speed string
 	var resp osc.CreateVolumeResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.VolumeApi.CreateVolume(s.auth, &createVolumeOpts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.VolumeApi.CreateVolume(s.auth, &createVolumeOpts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -902,9 +892,9 @@ func (s stack) rpcReadVolumes(ids []string) ([]osc.Volume, fail.Error) {
 	var resp osc.ReadVolumesResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.VolumeApi.ReadVolumes(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.VolumeApi.ReadVolumes(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -957,9 +947,9 @@ func (s stack) rpcReadVolumeByName(name string) (osc.Volume, fail.Error) {
 	var resp osc.ReadVolumesResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.VolumeApi.ReadVolumes(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.VolumeApi.ReadVolumes(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -987,9 +977,8 @@ func (s stack) rpcDeleteVolume(id string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.VolumeApi.DeleteVolume(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.VolumeApi.DeleteVolume(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1014,9 +1003,8 @@ func (s stack) rpcLinkVolume(volumeID, hostID, deviceName string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.VolumeApi.LinkVolume(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.VolumeApi.LinkVolume(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1034,9 +1022,8 @@ func (s stack) rpcUnlinkVolume(id string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.VolumeApi.UnlinkVolume(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.VolumeApi.UnlinkVolume(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1058,9 +1045,8 @@ func (s stack) rpcLinkInternetService(networkID, internetServiceID string) fail.
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.InternetServiceApi.LinkInternetService(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.InternetServiceApi.LinkInternetService(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1078,9 +1064,8 @@ func (s stack) rpcDeleteInternetService(id string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.InternetServiceApi.DeleteInternetService(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.InternetServiceApi.DeleteInternetService(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1094,9 +1079,9 @@ func (s stack) rpcCreateInternetService(name string) (osc.InternetService, fail.
 	var resp osc.CreateInternetServiceResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.InternetServiceApi.CreateInternetService(s.auth, nil)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.InternetServiceApi.CreateInternetService(s.auth, nil)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1138,9 +1123,8 @@ func (s stack) rpcUnlinkInternetService(networkID, internetServiceID string) fai
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.InternetServiceApi.UnlinkInternetService(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.InternetServiceApi.UnlinkInternetService(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1159,9 +1143,9 @@ func (s stack) rpcReadInternetServices(ids []string) ([]osc.InternetService, fai
 	var resp osc.ReadInternetServicesResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.InternetServiceApi.ReadInternetServices(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.InternetServiceApi.ReadInternetServices(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1189,9 +1173,8 @@ func (s stack) rpcCreateRoute(internetServiceID, routeTableID, destination strin
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.RouteApi.CreateRoute(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.RouteApi.CreateRoute(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1210,9 +1193,9 @@ func (s stack) rpcReadRouteTablesOfNetworks(networkIDs []string) ([]osc.RouteTab
 	var resp osc.ReadRouteTablesResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.RouteTableApi.ReadRouteTables(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.RouteTableApi.ReadRouteTables(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1266,9 +1249,9 @@ func (s stack) rpcReadNets(ids []string) ([]osc.Net, fail.Error) {
 	)
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.NetApi.ReadNets(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.NetApi.ReadNets(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1319,9 +1302,9 @@ func (s stack) rpcReadNetByName(name string) (osc.Net, fail.Error) {
 	var resp osc.ReadNetsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.NetApi.ReadNets(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.NetApi.ReadNets(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1356,9 +1339,9 @@ func (s stack) rpcCreateSecurityGroup(networkID, name, description string) (osc.
 	var resp osc.CreateSecurityGroupResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.SecurityGroupApi.CreateSecurityGroup(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.SecurityGroupApi.CreateSecurityGroup(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1380,9 +1363,8 @@ func (s stack) rpcDeleteSecurityGroup(id string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.SecurityGroupApi.DeleteSecurityGroup(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.SecurityGroupApi.DeleteSecurityGroup(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1408,9 +1390,8 @@ func (s stack) rpcCreateSecurityGroupRules(id, flow string, rules []osc.Security
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.SecurityGroupRuleApi.CreateSecurityGroupRule(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.SecurityGroupRuleApi.CreateSecurityGroupRule(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1440,9 +1421,8 @@ func (s stack) rpcDeleteSecurityGroupRules(id, flow string, rules []osc.Security
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.SecurityGroupRuleApi.DeleteSecurityGroupRule(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.SecurityGroupRuleApi.DeleteSecurityGroupRule(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1464,9 +1444,8 @@ func (s stack) rpcCreateKeypair(name string, publicKey string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.KeypairApi.CreateKeypair(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.KeypairApi.CreateKeypair(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1485,9 +1464,9 @@ func (s stack) rpcReadKeypairs(names []string) ([]osc.Keypair, fail.Error) {
 	var resp osc.ReadKeypairsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.KeypairApi.ReadKeypairs(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.KeypairApi.ReadKeypairs(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1535,9 +1514,8 @@ func (s stack) rpcDeleteKeypair(name string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.KeypairApi.DeleteKeypair(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.KeypairApi.DeleteKeypair(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1556,9 +1534,9 @@ func (s stack) rpcReadImages(ids []string) ([]osc.Image, fail.Error) {
 	var resp osc.ReadImagesResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.ImageApi.ReadImages(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.ImageApi.ReadImages(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1610,9 +1588,8 @@ func (s stack) rpcLinkPublicIp(ipID, nicID string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.PublicIpApi.LinkPublicIp(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.PublicIpApi.LinkPublicIp(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1630,9 +1607,8 @@ func (s stack) rpcDeletePublicIpByID(id string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.PublicIpApi.DeletePublicIp(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.PublicIpApi.DeletePublicIp(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1646,9 +1622,8 @@ func (s stack) rpcDeletePublicIpByIP(ip string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.PublicIpApi.DeletePublicIp(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.PublicIpApi.DeletePublicIp(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1658,9 +1633,9 @@ func (s stack) rpcCreatePublicIp() (osc.PublicIp, fail.Error) {
 	var resp osc.CreatePublicIpResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.PublicIpApi.CreatePublicIp(s.auth, nil)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.PublicIpApi.CreatePublicIp(s.auth, nil)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1674,11 +1649,11 @@ func (s stack) rpcCreateVms(request osc.CreateVmsRequest) ([]osc.Vm, fail.Error)
 	var resp osc.CreateVmsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (innerErr error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, innerErr = s.client.VmApi.CreateVms(s.auth, &osc.CreateVmsOpts{
+			var httpResp *http.Response
+			resp, httpResp, innerErr = s.client.VmApi.CreateVms(s.auth, &osc.CreateVmsOpts{
 				CreateVmsRequest: optional.NewInterface(request),
 			})
-			return innerErr
+			return withResponse(httpResp, innerErr)
 		},
 		normalizeError,
 	)
@@ -1704,9 +1679,9 @@ func (s stack) rpcReadPublicIpsOfVm(id string) ([]osc.PublicIp, fail.Error) {
 	var resp osc.ReadPublicIpsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.PublicIpApi.ReadPublicIps(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.PublicIpApi.ReadPublicIps(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1732,9 +1707,8 @@ func (s stack) rpcStopVms(ids []string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.VmApi.StopVms(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.VmApi.StopVms(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1752,9 +1726,8 @@ func (s stack) rpcStartVms(ids []string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.VmApi.StartVms(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.VmApi.StartVms(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1773,9 +1746,8 @@ func (s stack) rpcRebootVms(ids []string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.VmApi.RebootVms(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.VmApi.RebootVms(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1797,9 +1769,8 @@ func (s stack) rpcUpdateVmType(id string, typ string) fail.Error {
 	}
 	return stacks.RetryableRemoteCall(
 		func() error {
-			// FIXME: *http.Response must be taken into account for retries
-			_, _, err := s.client.VmApi.UpdateVm(s.auth, &opts)
-			return err
+			_, httpResp, err := s.client.VmApi.UpdateVm(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1816,9 +1787,9 @@ func (s stack) rpcReadNicsOfVm(id string) ([]osc.Nic, fail.Error) {
 	var resp osc.ReadNicsResponse
 	xerr := stacks.RetryableRemoteCall(
 		func() (err error) {
-			// FIXME: *http.Response must be taken into account for retries
-			resp, _, err = s.client.NicApi.ReadNics(s.auth, &opts)
-			return err
+			var httpResp *http.Response
+			resp, httpResp, err = s.client.NicApi.ReadNics(s.auth, &opts)
+			return withResponse(httpResp, err)
 		},
 		normalizeError,
 	)
@@ -1,6 +1,9 @@
 package stacks
 
 import (
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
@@ -27,11 +30,17 @@ func RetryableRemoteCall(callback func() error, convertError func(error) fail.Er
 	// xerr := netutils.WhileCommunicationUnsuccessfulDelay1Second(
 	xerr := netutils.WhileCommunicationUnsuccessful(
 		func() error {
-			if innerErr := callback(); innerErr != nil {
+			innerErr := callback()
+			if innerErr != nil {
 				innerErr = normalizeError(innerErr)
+			}
+			if innerErr != nil {
 				switch innerErr.(type) {
 				case *fail.ErrNotFound:
 					return retry.StopRetryError(innerErr)
+				case *fail.ErrThrottled:
+					// Not retried here: the rate limiter of the provider pauses the calls and retries them
+					return retry.StopRetryError(innerErr)
 				}
 				return innerErr
 			}
@@ -52,3 +61,26 @@ func RetryableRemoteCall(callback func() error, convertError func(error) fail.Er
 	}
 	return nil
 }
+
+// ParseRetryAfter returns the delay expressed by the value of a 'Retry-After' HTTP header, either in seconds or as an
+// HTTP date; returns 0 if the value is empty, invalid or in the past
+func ParseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	when, err := http.ParseTime(value)
+	if err != nil {
+		return 0
+	}
+	if d := time.Until(when); d > 0 {
+		return d
+	}
+	return 0
+}
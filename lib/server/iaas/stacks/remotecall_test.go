@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package stacks
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+func TestRetryableRemoteCallThrottled(t *testing.T) {
+	// The throttled calls are not retried here, the rate limiter of the provider pauses and retries them
+	tries := 0
+	xerr := RetryableRemoteCall(
+		func() error {
+			tries++
+			return fail.ThrottledError(10*time.Millisecond, "slow down")
+		},
+		nil,
+	)
+	if assert.NotNil(t, xerr) {
+		_, ok := xerr.(*fail.ErrThrottled)
+		assert.True(t, ok)
+		assert.Equal(t, 1, tries)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), ParseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("soon"))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("-3"))
+	assert.Equal(t, 5*time.Second, ParseRetryAfter(" 5 "))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)))
+
+	d := ParseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, d > 50*time.Second && d <= time.Minute)
+}
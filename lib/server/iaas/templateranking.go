@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iaas
+
+import (
+	"sort"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/templateselection"
+)
+
+const (
+	// CPUPerfWeight is the performance weight of a core running at 1 GHz
+	CPUPerfWeight float64 = 1.0
+	// DiskSpeedPerfWeight is the performance weight of 1 MB/s of main disk throughput (100 MB/s weigh half a core at 1 GHz)
+	DiskSpeedPerfWeight float64 = 1.0 / 200.0
+	// NetSpeedPerfWeight is the performance weight of 1 MB/s of sampled network throughput (100 MB/s weigh a quarter of a core at 1 GHz)
+	NetSpeedPerfWeight float64 = 1.0 / 400.0
+)
+
+// RankPerformance computes the performance score of an host template, refined by scanned data if available (info may be nil)
+// Disk and network speeds of the scanned data are in MB/s
+func RankPerformance(t *abstract.HostTemplate, info *abstract.StoredCPUInfo) float64 {
+	cores := float64(t.Cores)
+	freq := float64(t.CPUFreq)
+	var diskSpeed, netSpeed float64
+	if info != nil {
+		if info.NumberOfCore > 0 {
+			cores = float64(info.NumberOfCore)
+		}
+		if info.CPUFrequency > 0 {
+			freq = info.CPUFrequency
+		}
+		diskSpeed = info.MainDiskSpeed
+		netSpeed = info.SampleNetSpeed
+	}
+	if freq <= 0 {
+		freq = 1.0
+	}
+	return cores*freq*CPUPerfWeight + diskSpeed*DiskSpeedPerfWeight + netSpeed*NetSpeedPerfWeight
+}
+
+// rankedTemplate carries the scores of a template used by the selection policies
+type rankedTemplate struct {
+	template *abstract.HostTemplate
+	perf     float64
+	price    float64 // price per hour, 0 if unknown
+}
+
+// before tells if r has to be ranked before o using policy
+// Templates with unknown price are ranked after the ones with known price by policies involving price
+func (r rankedTemplate) before(o rankedTemplate, policy templateselection.Enum) bool {
+	switch policy {
+	case templateselection.Cheapest:
+		if r.price > 0 && o.price > 0 {
+			return r.price < o.price
+		}
+		return r.price > 0 && o.price == 0
+	case templateselection.Fastest:
+		return r.perf > o.perf
+	case templateselection.BestValue:
+		if r.price > 0 && o.price > 0 {
+			return r.perf/r.price > o.perf/o.price
+		}
+		return r.price > 0 && o.price == 0
+	default:
+		return false
+	}
+}
+
+// rankTemplates sorts templates using policy; DRF rank is used to break ties
+// 'scanned' contains the scanned data indexed by template ID, 'prices' the configured prices per hour indexed by template name
+func rankTemplates(tpls []*abstract.HostTemplate, policy templateselection.Enum, scanned map[string]abstract.StoredCPUInfo, prices map[string]float64) {
+	sort.Stable(ByRankDRF(tpls))
+	if policy == templateselection.DRF || len(tpls) < 2 {
+		return
+	}
+
+	ranked := make([]rankedTemplate, 0, len(tpls))
+	for _, t := range tpls {
+		item := rankedTemplate{template: t}
+		if info, ok := scanned[t.ID]; ok {
+			item.perf = RankPerformance(t, &info)
+			item.price = info.PricePerHour
+		} else {
+			item.perf = RankPerformance(t, nil)
+		}
+		if item.price <= 0 {
+			item.price = prices[t.Name]
+		}
+		ranked = append(ranked, item)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].before(ranked[j], policy)
+	})
+	for k, v := range ranked {
+		tpls[k] = v.template
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iaas
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/templateselection"
+)
+
+func rankingFixture() ([]*abstract.HostTemplate, map[string]abstract.StoredCPUInfo) {
+	tpls := []*abstract.HostTemplate{
+		{ID: "big", Name: "big", Cores: 8, RAMSize: 32},
+		{ID: "small", Name: "small", Cores: 4, RAMSize: 16},
+		{ID: "fast", Name: "fast", Cores: 4, RAMSize: 16, DiskSize: 50},
+	}
+	scanned := map[string]abstract.StoredCPUInfo{
+		"big":   {TemplateID: "big", NumberOfCore: 8, CPUFrequency: 2.0, PricePerHour: 0.40},
+		"small": {TemplateID: "small", NumberOfCore: 4, CPUFrequency: 2.0, PricePerHour: 0.10},
+		"fast":  {TemplateID: "fast", NumberOfCore: 4, CPUFrequency: 3.5, MainDiskSpeed: 800},
+	}
+	return tpls, scanned
+}
+
+func names(tpls []*abstract.HostTemplate) []string {
+	var out []string
+	for _, v := range tpls {
+		out = append(out, v.Name)
+	}
+	return out
+}
+
+func TestRankTemplates(t *testing.T) {
+	tpls, scanned := rankingFixture()
+	rankTemplates(tpls, templateselection.DRF, scanned, nil)
+	assert.Equal(t, []string{"small", "fast", "big"}, names(tpls))
+
+	tpls, scanned = rankingFixture()
+	rankTemplates(tpls, templateselection.Cheapest, scanned, nil)
+	assert.Equal(t, []string{"small", "big", "fast"}, names(tpls))
+
+	tpls, scanned = rankingFixture()
+	rankTemplates(tpls, templateselection.Fastest, scanned, nil)
+	assert.Equal(t, []string{"fast", "big", "small"}, names(tpls))
+
+	// configured price is used when the scanner did not collect one
+	tpls, scanned = rankingFixture()
+	rankTemplates(tpls, templateselection.BestValue, scanned, map[string]float64{"fast": 0.05})
+	assert.Equal(t, []string{"fast", "small", "big"}, names(tpls))
+}
+
+func TestRankPerformance(t *testing.T) {
+	// Disk and network speeds are in MB/s, 100 MB/s of disk weighing twice as much as 100 MB/s of network
+	tpl := &abstract.HostTemplate{Cores: 2}
+	assert.Equal(t, 2.0, RankPerformance(tpl, nil))
+	assert.InDelta(t, 8.0+4.0, RankPerformance(tpl, &abstract.StoredCPUInfo{NumberOfCore: 4, CPUFrequency: 2.0, MainDiskSpeed: 800}), 1e-9)
+	assert.InDelta(t, 8.0+2.5, RankPerformance(tpl, &abstract.StoredCPUInfo{NumberOfCore: 4, CPUFrequency: 2.0, SampleNetSpeed: 1000}), 1e-9)
+
+	tpls := []*abstract.HostTemplate{
+		{ID: "cpu", Name: "cpu", Cores: 4},
+		{ID: "net", Name: "net", Cores: 4},
+		{ID: "disk", Name: "disk", Cores: 4},
+	}
+	scanned := map[string]abstract.StoredCPUInfo{
+		"cpu":  {NumberOfCore: 4, CPUFrequency: 2.5},                       // 10
+		"net":  {NumberOfCore: 4, CPUFrequency: 2.0, SampleNetSpeed: 1200}, // 8 + 3
+		"disk": {NumberOfCore: 4, CPUFrequency: 2.0, MainDiskSpeed: 500},   // 8 + 2.5
+	}
+	rankTemplates(tpls, templateselection.Fastest, scanned, nil)
+	assert.Equal(t, []string{"net", "disk", "cpu"}, names(tpls))
+}
@@ -0,0 +1,282 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iaas
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
+)
+
+// TenantValidation contains the result of the validation of a tenant of a tenants file
+type TenantValidation struct {
+	Name     string
+	Provider string
+	Errors   []string
+}
+
+// Valid tells if no error has been found in the tenant
+func (tv TenantValidation) Valid() bool {
+	return len(tv.Errors) == 0
+}
+
+// providerRequirements contains, by provider, the settings needed by the Build of the provider
+// Each requirement is a list of alternatives, in the form '<section>.<keyword>'
+var providerRequirements = map[string][][]string{
+	"aws": {
+		{"identity.AccessKeyID"}, {"identity.SecretAccessKey"},
+		{"compute.Region"}, {"compute.Zone"},
+	},
+	"cloudferro": {
+		{"identity.Username"}, {"identity.Password"}, {"identity.DomainName"},
+		{"compute.ProjectName", "compute.ProjectID"}, {"compute.Region"},
+	},
+	"flexibleengine": {
+		{"identity.Username"}, {"identity.Password"}, {"identity.DomainName"},
+		{"compute.ProjectID"}, {"compute.Region"}, {"compute.AvailabilityZone"},
+	},
+	"gcp": {
+		{"identity.project_id"}, {"identity.private_key_id"}, {"identity.private_key"}, {"identity.client_email"},
+		{"identity.client_id"}, {"identity.auth_uri"}, {"identity.token_uri"}, {"identity.auth_provider_x509_cert_url"},
+		{"identity.client_x509_cert_url"},
+		{"compute.Region"}, {"compute.Zone"},
+	},
+	"local": {
+		{"compute.uri"}, {"compute.imagesJSONPath"}, {"compute.templatesJSONPath"}, {"compute.libvirtStorage"},
+	},
+	"openstack": {
+		{"identity.IdentityEndpoint"}, {"identity.Username"}, {"identity.Password"},
+		{"compute.TenantName", "compute.TenantID"}, {"compute.Region"},
+	},
+	"opentelekom": {
+		{"identity.Username"}, {"identity.Password"}, {"identity.DomainName"},
+		{"compute.ProjectID"}, {"compute.Region"}, {"compute.AvailabilityZone"},
+	},
+	"outscale": {
+		{"identity.AccessKey"}, {"identity.SecretKey"}, {"identity.UserID", "metadata.Bucket"},
+		{"compute.Region"},
+	},
+	"ovh": {
+		{"identity.ApplicationKey"}, {"identity.OpenstackID"}, {"identity.OpenstackPassword"},
+		{"compute.ProjectName"}, {"compute.Region"},
+	},
+	"vclouddirector": {
+		{"identity.User"}, {"identity.Password"}, {"identity.Org"}, {"identity.EntryPoint"},
+		{"compute.Vdc"}, {"compute.Region"},
+	},
+}
+
+// objectStorageRequirements contains the settings needed by initObjectStorageLocationConfig
+var objectStorageRequirements = [][]string{
+	{"objectstorage.Type"},
+	{"objectstorage.AccessKey", "objectstorage.OpenStackID", "objectstorage.Username", "identity.OpenstackID", "identity.Username"},
+	{"objectstorage.SecretKey", "objectstorage.OpenstackPassword", "objectstorage.Password", "identity.SecretKey", "identity.OpenstackPassword", "identity.Password"},
+}
+
+// metadataRequirements contains the settings needed by initMetadataLocationConfig
+var metadataRequirements = [][]string{
+	{"metadata.Type", "objectstorage.Type"},
+	{"metadata.AccessKey", "metadata.OpenstackID", "metadata.Username", "objectstorage.AccessKey", "objectstorage.OpenStackID", "objectstorage.Username", "identity.Username", "identity.OpenstackID"},
+	{"metadata.SecretKey", "metadata.AccessPassword", "metadata.OpenstackPassword", "metadata.Password", "objectstorage.SecretKey", "objectstorage.AccessPassword", "objectstorage.OpenstackPassword", "objectstorage.Password", "identity.SecretKey", "identity.AccessPassword", "identity.Password", "identity.OpenstackPassword"},
+}
+
+// ValidateTenantsFile validates the tenants file used by safescaled, and returns its path with the result of the validation of each tenant
+func ValidateTenantsFile() (string, []TenantValidation, fail.Error) {
+	tenants, v, xerr := getTenantsFromCfg()
+	if xerr != nil {
+		return v.ConfigFileUsed(), nil, xerr
+	}
+	return v.ConfigFileUsed(), validateTenants(tenants, true), nil
+}
+
+// ValidateTenantsContent validates the content of a tenants file in format 'format' ('toml', 'yaml' or 'json')
+// The content being sent by a client, the syntax of its references to secrets, environment variables and files is
+// checked but the references are not resolved
+func ValidateTenantsContent(content []byte, format string) ([]TenantValidation, fail.Error) {
+	v := viper.New()
+	v.SetConfigType(format)
+	if err := v.ReadConfig(bytes.NewReader(content)); err != nil {
+		return nil, fail.SyntaxError("invalid content of tenants file: %s", err.Error())
+	}
+	tenants, ok := v.AllSettings()["tenants"].([]interface{})
+	if !ok {
+		return nil, fail.SyntaxError("invalid content of tenants file: no 'tenants' entry")
+	}
+	return validateTenants(tenants, false), nil
+}
+
+// validateTenants validates the tenants read from a tenants file
+// If resolve is true, the references to secrets, environment variables and files are resolved
+func validateTenants(tenants []interface{}, resolve bool) []TenantValidation {
+	out := make([]TenantValidation, 0, len(tenants))
+	names := map[string]bool{}
+	for i, t := range tenants {
+		tenant, ok := t.(map[string]interface{})
+		if !ok {
+			out = append(out, TenantValidation{Name: fmt.Sprintf("#%d", i+1), Errors: []string{"tenant is not a map"}})
+			continue
+		}
+		tv := validateTenant(tenant, resolve)
+		if tv.Name == "" {
+			tv.Name = fmt.Sprintf("#%d", i+1)
+		} else if names[tv.Name] {
+			tv.Errors = append(tv.Errors, "another tenant has the same name")
+		}
+		names[tv.Name] = true
+		out = append(out, tv)
+	}
+	return out
+}
+
+// validateTenant validates a tenant against the requirements of its provider
+func validateTenant(tenant map[string]interface{}, resolve bool) TenantValidation {
+	tv := TenantValidation{}
+	tv.Name, _ = tenant["name"].(string)
+	if tv.Name == "" {
+		tv.Errors = append(tv.Errors, "missing setting 'name'")
+	}
+	if provider, ok := tenant["provider"].(string); ok {
+		tv.Provider = provider
+	} else {
+		tv.Provider, _ = tenant["client"].(string)
+	}
+	if tv.Provider == "" {
+		tv.Errors = append(tv.Errors, "missing setting 'client'")
+	} else if _, ok := allProviders[tv.Provider]; !ok {
+		tv.Errors = append(tv.Errors, fmt.Sprintf("unknown client '%s'", tv.Provider))
+	}
+
+	// References to secrets, environment variables and files must be resolvable
+	if resolve {
+		if resolved, xerr := resolveTenantCredentials(tv.Name, tenant); xerr != nil {
+			tv.Errors = append(tv.Errors, xerr.Error())
+		} else {
+			tenant = resolved
+		}
+	} else if xerr := secret.CheckMap(tenant); xerr != nil {
+		tv.Errors = append(tv.Errors, xerr.Error())
+	}
+
+	requirements := providerRequirements[tv.Provider]
+	_, objectStorageFound := tenant["objectstorage"]
+	_, metadataFound := tenant["metadata"]
+	if objectStorageFound {
+		requirements = append(requirements, objectStorageRequirements[0])
+		if !tenantSettingIs(tenant, "objectstorage.Type", "google") {
+			requirements = append(requirements, objectStorageRequirements[1:]...)
+		}
+	}
+	if objectStorageFound || metadataFound {
+		requirements = append(requirements, metadataRequirements[0])
+		if !tenantSettingIs(tenant, "metadata.Type", "google") && !tenantSettingIs(tenant, "objectstorage.Type", "google") {
+			requirements = append(requirements, metadataRequirements[1:]...)
+		}
+	}
+	for _, r := range requirements {
+		if !tenantHasSetting(tenant, r) {
+			msg := fmt.Sprintf("missing setting '%s'", r[0])
+			if len(r) > 1 {
+				msg += fmt.Sprintf(" (or '%s')", strings.Join(r[1:], "', '"))
+			}
+			tv.Errors = append(tv.Errors, msg)
+		}
+	}
+
+	if _, ok := tenant["compute"].(map[string]interface{}); ok {
+		if xerr := validateRegexps(&service{}, tenant); xerr != nil {
+			tv.Errors = append(tv.Errors, xerr.Error())
+		}
+		if xerr := validateTemplateSelection(&service{}, tenant); xerr != nil {
+			tv.Errors = append(tv.Errors, xerr.Error())
+		}
+	}
+	if xerr := validateQuotas(&service{}, tenant); xerr != nil {
+		tv.Errors = append(tv.Errors, xerr.Error())
+	}
+	if xerr := validateRateLimits(&service{}, tenant); xerr != nil {
+		tv.Errors = append(tv.Errors, xerr.Error())
+	}
+
+	// the resolved values must not appear in the errors
+	for i, v := range tv.Errors {
+		tv.Errors[i] = secret.Mask(v)
+	}
+	return tv
+}
+
+// tenantSetting returns the value of the setting '<section>.<keyword>' of the tenant
+func tenantSetting(tenant map[string]interface{}, setting string) (interface{}, bool) {
+	parts := strings.SplitN(setting, ".", 2)
+	section, ok := tenant[parts[0]].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	value, ok := section[parts[1]]
+	return value, ok
+}
+
+// tenantHasSetting tells if one of the settings is set to a non-empty value in the tenant
+func tenantHasSetting(tenant map[string]interface{}, settings []string) bool {
+	for _, s := range settings {
+		if value, ok := tenantSetting(tenant, s); ok {
+			if str, ok := value.(string); !ok || str != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tenantSettingIs tells if the setting of the tenant has the value
+func tenantSettingIs(tenant map[string]interface{}, setting, value string) bool {
+	current, _ := tenantSetting(tenant, setting)
+	str, _ := current.(string)
+	return strings.EqualFold(str, value)
+}
+
+// WatchTenants watches the tenants file used by safescaled; each time it changes, the tenants are reloaded and
+// onChange is called with the result of their validation
+func WatchTenants(onChange func([]TenantValidation)) fail.Error {
+	_, v, xerr := getTenantsFromCfg()
+	if xerr != nil {
+		return xerr
+	}
+	v.OnConfigChange(func(e fsnotify.Event) {
+		logrus.Infof("Tenants file '%s' changed, reloading tenants", e.Name)
+		if xerr := loadConfig(); xerr != nil {
+			logrus.Errorf("failed to reload tenants: %v", xerr)
+			return
+		}
+		_, report, xerr := ValidateTenantsFile()
+		if xerr != nil {
+			logrus.Errorf("failed to validate tenants: %v", xerr)
+			return
+		}
+		if onChange != nil {
+			onChange(report)
+		}
+	})
+	v.WatchConfig()
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package iaas
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validationTenants = `
+[[tenants]]
+    name = "valid"
+    client = "ovh"
+
+    [tenants.identity]
+        ApplicationKey = "key"
+        OpenstackID = "user"
+        OpenstackPassword = "password"
+
+    [tenants.compute]
+        ProjectName = "project"
+        Region = "GRA5"
+
+    [tenants.objectstorage]
+        Type = "swift"
+
+[[tenants]]
+    name = "incomplete"
+    client = "ovh"
+
+    [tenants.identity]
+        ApplicationKey = "key"
+
+    [tenants.compute]
+        Region = "GRA5"
+        WhitelistTemplateRegexp = "(unbalanced"
+
+[[tenants]]
+    name = "unknown"
+    client = "nowhere"
+
+[[tenants]]
+    name = "valid"
+    client = "ovh"
+
+    [tenants.identity]
+        ApplicationKey = "key"
+        OpenstackID = "user"
+        OpenstackPassword = "{{env \"SAFESCALE_TEST_UNSET\" | b64enc}}"
+
+    [tenants.compute]
+        ProjectName = "project"
+        Region = "GRA5"
+
+[[tenants]]
+    name = "references"
+    client = "ovh"
+
+    [tenants.identity]
+        ApplicationKey = "key"
+        OpenstackID = "{{env \"SAFESCALE_TEST_VALIDATION\"}}"
+        OpenstackPassword = "{{secret \"tenants/references/password\"}}"
+
+    [tenants.compute]
+        ProjectName = "project"
+        Region = "GRA5"
+`
+
+func TestValidateTenants(t *testing.T) {
+	if _, ok := allProviders["ovh"]; !ok {
+		Register("ovh", nil)
+		defer delete(allProviders, "ovh")
+	}
+	require.Nil(t, os.Setenv("SAFESCALE_TEST_VALIDATION", "(unbalanced"))
+	defer func() { _ = os.Unsetenv("SAFESCALE_TEST_VALIDATION") }()
+
+	report, xerr := ValidateTenantsContent([]byte(validationTenants), "toml")
+	require.Nil(t, xerr)
+	require.Len(t, report, 5)
+
+	assert.Equal(t, "valid", report[0].Name)
+	assert.Equal(t, "ovh", report[0].Provider)
+	assert.True(t, report[0].Valid(), report[0].Errors)
+
+	assert.Equal(t, "incomplete", report[1].Name)
+	assert.False(t, report[1].Valid())
+	assert.Contains(t, report[1].Errors, "missing setting 'identity.OpenstackID'")
+	assert.Contains(t, report[1].Errors, "missing setting 'identity.OpenstackPassword'")
+	assert.Contains(t, report[1].Errors, "missing setting 'compute.ProjectName'")
+	assert.Contains(t, report[1].Errors, "invalid value for keyword 'WhilelistTemplateRegexp': missing closing )")
+	assert.Len(t, report[1].Errors, 4)
+
+	assert.Equal(t, []string{"unknown client 'nowhere'"}, report[2].Errors)
+
+	// same name as the first one, and invalid reference
+	assert.Len(t, report[3].Errors, 2)
+	assert.Contains(t, report[3].Errors, "another tenant has the same name")
+
+	// the references of a submitted content are not resolved
+	assert.True(t, report[4].Valid(), report[4].Errors)
+
+	_, xerr = ValidateTenantsContent([]byte("not = [toml"), "toml")
+	assert.NotNil(t, xerr)
+	_, xerr = ValidateTenantsContent([]byte("other = 1"), "toml")
+	assert.NotNil(t, xerr)
+}
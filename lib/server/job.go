@@ -30,6 +30,7 @@ import (
 	"github.com/CS-SI/SafeScale/lib/server/iaas"
 	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/metrics"
 )
 
 // Job is the interface of a daemon job
@@ -109,7 +110,7 @@ func NewJob(ctx context.Context, cancel context.CancelFunc, svc iaas.Service, de
 		uuid:        id,
 		task:        task,
 		cancel:      cancel,
-		service:     svc,
+		service:     svc.WithContext(ctx),
 		tenant:      svc.GetName(),
 		startTime:   time.Now(),
 	}
@@ -117,6 +118,7 @@ func NewJob(ctx context.Context, cancel context.CancelFunc, svc iaas.Service, de
 	if xerr != nil {
 		return nil, xerr
 	}
+	recordJob(ctx, &nj)
 	return &nj, nil
 }
 
@@ -188,6 +190,14 @@ var (
 	mutexJobManager sync.Mutex
 )
 
+func init() {
+	metrics.NewGaugeFunc("safescale_jobs_active", "Number of jobs in progress in safescaled", func() float64 {
+		mutexJobManager.Lock()
+		defer mutexJobManager.Unlock()
+		return float64(len(jobMap))
+	})
+}
+
 // register ...
 func register(job Job) fail.Error {
 	mutexJobManager.Lock()
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/auth"
+	"github.com/CS-SI/SafeScale/lib/server/events"
+)
+
+type jobRecordKey struct{}
+
+// jobRecord keeps the job created to serve a call
+type jobRecord struct {
+	lock sync.Mutex
+	job  *job
+}
+
+// recordJob keeps the job in the record carried by ctx, if any
+func recordJob(ctx context.Context, j *job) {
+	if r, ok := ctx.Value(jobRecordKey{}).(*jobRecord); ok {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		r.job = j
+	}
+}
+
+// JobEventsUnaryServerInterceptor returns the interceptor publishing the end of the jobs of the mutating unary RPCs, as
+// events 'job_finished' or 'job_failed'
+func JobEventsUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if auth.ReadOnly(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		record := &jobRecord{}
+		resp, err := handler(context.WithValue(ctx, jobRecordKey{}, record), req)
+
+		record.lock.Lock()
+		j := record.job
+		record.lock.Unlock()
+		if j == nil {
+			return resp, err
+		}
+		e := events.Event{
+			Type:         protocol.EventType_ET_JOB_FINISHED,
+			ResourceID:   j.uuid,
+			ResourceName: j.description,
+			Details: map[string]string{
+				"operation": strings.TrimPrefix(info.FullMethod, "/"),
+				"duration":  j.GetDuration().String(),
+			},
+		}
+		if err != nil {
+			e.Type = protocol.EventType_ET_JOB_FAILED
+			e.Error = status.Convert(err).Message()
+		}
+		events.Publish(j.task, e)
+		return resp, err
+	}
+}
@@ -27,12 +27,14 @@ import (
 	"google.golang.org/grpc/status"
 
 	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterproperty"
 	clusterfactory "github.com/CS-SI/SafeScale/lib/server/resources/factories/cluster"
 	hostfactory "github.com/CS-SI/SafeScale/lib/server/resources/factories/host"
 	"github.com/CS-SI/SafeScale/lib/server/resources/operations/converters"
 	propertiesv3 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v3"
 	srvutils "github.com/CS-SI/SafeScale/lib/server/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
 	"github.com/CS-SI/SafeScale/lib/utils/data"
 	"github.com/CS-SI/SafeScale/lib/utils/debug"
 	"github.com/CS-SI/SafeScale/lib/utils/debug/tracing"
@@ -369,7 +371,7 @@ func (s *ClusterListener) Expand(ctx context.Context, in *protocol.ClusterResize
 	defer job.Close()
 	task := job.GetTask()
 
-	tracer := debug.NewTracer(task, tracing.ShouldTrace("listeners.host"), "('%s')", ref).WithStopwatch().Entering()
+	tracer := debug.NewTracer(task, tracing.ShouldTrace("listeners.host"), "('%s', '%s')", ref, in.GetPool()).WithStopwatch().Entering()
 	defer tracer.Exiting()
 	defer fail.OnExitLogError(&err, tracer.TraceMessage())
 
@@ -387,7 +389,7 @@ func (s *ClusterListener) Expand(ctx context.Context, in *protocol.ClusterResize
 		return nil, xerr
 	}
 
-	resp, xerr := rc.AddNodes(task, uint(in.Count), *sizing)
+	resp, xerr := rc.AddNodes(task, in.GetPool(), uint(in.Count), *sizing)
 	if xerr != nil {
 		return nil, xerr
 	}
@@ -436,7 +438,7 @@ func (s *ClusterListener) Shrink(ctx context.Context, in *protocol.ClusterResize
 	task := job.GetTask()
 	svc := job.GetService()
 
-	tracer := debug.NewTracer(job.GetTask(), tracing.ShouldTrace("listeners.cluster"), "('%s')", clusterName).WithStopwatch().Entering()
+	tracer := debug.NewTracer(job.GetTask(), tracing.ShouldTrace("listeners.cluster"), "('%s', '%s')", clusterName, in.GetPool()).WithStopwatch().Entering()
 	defer tracer.Exiting()
 	defer fail.OnExitLogError(&err, tracer.TraceMessage())
 
@@ -450,8 +452,10 @@ func (s *ClusterListener) Shrink(ctx context.Context, in *protocol.ClusterResize
 		return nil, fail.InvalidParameterError("count", "must be greater than 0")
 	}
 
-	removedNodes, xerr := instance.Shrink(task, count)
-
+	removedNodes, xerr := instance.Shrink(task, in.GetPool(), count)
+	if xerr != nil {
+		return nil, xerr
+	}
 
 	out := &protocol.ClusterNodeListResponse{}
 	out.Nodes = fromClusterNodes(removedNodes)
@@ -910,3 +914,115 @@ func (s *ClusterListener) InspectMaster(ctx context.Context, in *protocol.Cluste
 	}
 	return out, nil
 }
+
+// SetAutoscaling records the autoscaling policy of a cluster
+func (s *ClusterListener) SetAutoscaling(ctx context.Context, in *protocol.ClusterAutoscalingRequest) (empty *googleprotobuf.Empty, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot set autoscaling policy of cluster")
+
+	empty = &googleprotobuf.Empty{}
+	if s == nil {
+		return empty, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return empty, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return empty, fail.InvalidParameterError("in", "cannot be nil")
+	}
+
+	if ok, err := govalidator.ValidateStruct(in); err != nil || !ok {
+		logrus.Warnf("Structure validation failure: %v", in) // FIXME: Generate json tags in protobuf
+	}
+
+	clusterName := in.GetName()
+	if clusterName == "" {
+		return empty, fail.InvalidRequestError("cluster name is missing")
+	}
+
+	job, xerr := PrepareJob(ctx, in.GetTenantId(), "cluster set autoscaling")
+	if xerr != nil {
+		return empty, xerr
+	}
+	defer job.Close()
+	task := job.GetTask()
+
+	policy := converters.ClusterAutoscalingPolicyFromProtocolToProperty(in.GetPolicy())
+	tracer := debug.NewTracer(task, tracing.ShouldTrace("listeners.cluster"), "('%s', %v)", clusterName, policy.Enabled).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	rc, xerr := clusterfactory.Load(task, job.GetService(), clusterName)
+	if xerr != nil {
+		return empty, xerr
+	}
+	return empty, rc.SetAutoscaling(task, policy)
+}
+
+// Upgrade upgrades the nodes of a cluster, a few nodes at a time
+func (s *ClusterListener) Upgrade(ctx context.Context, in *protocol.ClusterUpgradeRequest) (empty *googleprotobuf.Empty, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot upgrade cluster")
+
+	empty = &googleprotobuf.Empty{}
+	if s == nil {
+		return empty, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return empty, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return empty, fail.InvalidParameterError("in", "cannot be nil")
+	}
+
+	if ok, err := govalidator.ValidateStruct(in); err != nil || !ok {
+		logrus.Warnf("Structure validation failure: %v", in) // FIXME: Generate json tags in protobuf
+	}
+
+	clusterName := in.GetName()
+	if clusterName == "" {
+		return empty, fail.InvalidRequestError("cluster name is missing")
+	}
+
+	job, xerr := PrepareJob(ctx, in.GetTenantId(), "cluster upgrade")
+	if xerr != nil {
+		return empty, xerr
+	}
+	defer job.Close()
+	task := job.GetTask()
+
+	req := abstract.ClusterUpgradeRequest{
+		OSPackages:     in.GetOsPackages(),
+		Image:          in.GetImage(),
+		Pool:           in.GetPool(),
+		MaxUnavailable: uint(in.GetMaxUnavailable()),
+	}
+	tracer := debug.NewTracer(task, tracing.ShouldTrace("listeners.cluster"), "('%s', %v, '%s', '%s', %d)", clusterName, req.OSPackages, req.Image, req.Pool, req.MaxUnavailable).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	rc, xerr := clusterfactory.Load(task, job.GetService(), clusterName)
+	if xerr != nil {
+		return empty, xerr
+	}
+	return empty, rc.Upgrade(task, req)
+}
+
+// ResourceLabels returns the labels of the resource of a tenant designated by a call to a service, checked by the
+// authorization rules restricted to labels
+// Only the clusters have labels, being the labels shared by all their node pools
+func ResourceLabels(ctx context.Context, tenant, service, resource string) (map[string]string, fail.Error) {
+	if service != "ClusterService" || tenant == "" || resource == "" {
+		return map[string]string{}, nil
+	}
+
+	svc, xerr := TenantService(tenant)
+	if xerr != nil {
+		return nil, xerr
+	}
+	task, xerr := concurrency.NewTaskWithContext(ctx, nil)
+	if xerr != nil {
+		return nil, xerr
+	}
+	return clusterfactory.Labels(task, svc, resource)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package listeners
+
+import (
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/events"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// EventListener is the service server gRPC streaming the events of the resources
+type EventListener struct{}
+
+// Watch streams the events of the tenant of the call selected by the request, until the caller ends the call
+func (s *EventListener) Watch(in *protocol.EventWatchRequest, stream protocol.EventService_WatchServer) (err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot watch events")
+
+	if s == nil {
+		return fail.InvalidInstanceError()
+	}
+	if in == nil {
+		return fail.InvalidParameterError("in", "cannot be nil")
+	}
+	if stream == nil {
+		return fail.InvalidParameterError("stream", "cannot be nil")
+	}
+
+	// The call is authorized on the tenant designated by its metadata, the request cannot designate another one
+	ctx := stream.Context()
+	tenant := CurrentTenantName(ctx)
+	if tenant == "" {
+		return fail.InvalidRequestError("no tenant set")
+	}
+	if in.GetTenantId() != "" && in.GetTenantId() != tenant {
+		return fail.InvalidRequestError("request designates tenant '%s' but the call is made on tenant '%s'", in.GetTenantId(), tenant)
+	}
+
+	subscription := events.DefaultBus().Subscribe(events.Filter{
+		Types:     in.GetTypes(),
+		Tenants:   []string{tenant},
+		Resources: in.GetResources(),
+	})
+	defer subscription.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-subscription.Events():
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(e.ToProtocol()); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -25,15 +25,19 @@ import (
 	googleprotobuf "github.com/golang/protobuf/ptypes/empty"
 
 	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/resources"
 	clusterfactory "github.com/CS-SI/SafeScale/lib/server/resources/factories/cluster"
 	featurefactory "github.com/CS-SI/SafeScale/lib/server/resources/factories/feature"
 	hostfactory "github.com/CS-SI/SafeScale/lib/server/resources/factories/host"
+	"github.com/CS-SI/SafeScale/lib/server/resources/operations"
 	"github.com/CS-SI/SafeScale/lib/server/resources/operations/converters"
 	srvutils "github.com/CS-SI/SafeScale/lib/server/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
 	"github.com/CS-SI/SafeScale/lib/utils/data"
 	"github.com/CS-SI/SafeScale/lib/utils/debug"
 	_ "github.com/CS-SI/SafeScale/lib/utils/debug/tracing"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/template"
 )
 
 // FeatureListener feature service server grpc
@@ -180,9 +184,19 @@ func (s *FeatureListener) Check(ctx context.Context, in *protocol.FeatureActionR
 	return empty, fail.Wrap(fail.InconsistentError("reach theoretically unreachable point"), "cannot check feature")
 }
 
+// The values are supplied by the client, so they cannot use the template functions giving access to secrets or to the
+// environment of the daemon (only the feature files can).
 func convertVariablesToDataMap(in map[string]string) (data.Map, fail.Error) {
 	var out data.Map
 	if len(in) > 0 {
+		for k, v := range in {
+			if v == "" {
+				continue
+			}
+			if _, xerr := template.ParseRestricted("parameter", v); xerr != nil {
+				return data.Map{}, fail.InvalidRequestError("invalid value of parameter '%s': %s", k, xerr.Error())
+			}
+		}
 		jsoned, err := json.Marshal(in)
 		if err != nil {
 			return data.Map{}, fail.Wrap(err, "failed to check feature: failed to convert variables to json")
@@ -233,11 +247,6 @@ func (s *FeatureListener) Add(ctx context.Context, in *protocol.FeatureActionReq
 	defer tracer.Exiting()
 	defer fail.OnExitLogError(&err, tracer.TraceMessage())
 
-	feat, xerr := featurefactory.New(task, featureName)
-	if xerr != nil {
-		return empty, xerr
-	}
-
 	switch targetType {
 	case protocol.FeatureTargetType_FT_HOST:
 		rh, xerr := hostfactory.Load(task, svc, targetRef)
@@ -245,7 +254,7 @@ func (s *FeatureListener) Add(ctx context.Context, in *protocol.FeatureActionReq
 			return empty, xerr
 		}
 
-		results, xerr := feat.Add(rh, featureVariables, featureSettings)
+		results, xerr := rh.AddFeature(task, featureName, featureVariables, featureSettings)
 		if xerr != nil {
 			return empty, xerr
 		}
@@ -259,7 +268,7 @@ func (s *FeatureListener) Add(ctx context.Context, in *protocol.FeatureActionReq
 			return empty, xerr
 		}
 
-		results, xerr := feat.Add(rc, featureVariables, featureSettings)
+		results, xerr := rc.AddFeature(task, featureName, featureVariables, featureSettings)
 		if xerr != nil {
 			return empty, xerr
 		}
@@ -311,11 +320,6 @@ func (s *FeatureListener) Remove(ctx context.Context, in *protocol.FeatureAction
 	defer tracer.Exiting()
 	defer fail.OnExitLogError(&err, tracer.TraceMessage())
 
-	feat, xerr := featurefactory.New(task, featureName)
-	if xerr != nil {
-		return empty, xerr
-	}
-
 	switch targetType {
 	case protocol.FeatureTargetType_FT_HOST:
 		rh, xerr := hostfactory.Load(task, svc, targetRef)
@@ -323,7 +327,7 @@ func (s *FeatureListener) Remove(ctx context.Context, in *protocol.FeatureAction
 			return empty, xerr
 		}
 
-		results, xerr := feat.Remove(rh, featureVariables, featureSettings)
+		results, xerr := rh.DeleteFeature(task, featureName, featureVariables, featureSettings)
 		if xerr != nil {
 			return empty, fail.Wrap(xerr, "cannot remove feature")
 		}
@@ -337,7 +341,7 @@ func (s *FeatureListener) Remove(ctx context.Context, in *protocol.FeatureAction
 			return empty, xerr
 		}
 
-		results, xerr := feat.Remove(rc, featureVariables, featureSettings)
+		results, xerr := rc.RemoveFeature(task, featureName, featureVariables, featureSettings)
 		if xerr != nil {
 			return empty, fail.Wrap(xerr, "cannot remove feature")
 		}
@@ -350,3 +354,408 @@ func (s *FeatureListener) Remove(ctx context.Context, in *protocol.FeatureAction
 	// Should not reach this
 	return empty, fail.Wrap(fail.InconsistentError("reach theoretically unreachable point"), "cannot remove feature")
 }
+
+// Upgrade upgrades a Feature installed on a target
+func (s *FeatureListener) Upgrade(ctx context.Context, in *protocol.FeatureActionRequest) (empty *googleprotobuf.Empty, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnPanic(&err)
+
+	empty = &googleprotobuf.Empty{}
+	if s == nil {
+		return empty, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return empty, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+
+	targetType := in.GetTargetType()
+	targetRef, targetRefLabel := srvutils.GetReference(in.GetTargetRef())
+	if targetRef == "" {
+		return empty, fail.InvalidRequestError("target reference is missing")
+	}
+	featureName := in.GetName()
+	featureVariables, xerr := convertVariablesToDataMap(in.GetVariables())
+	if xerr != nil {
+		return empty, fail.Wrap(xerr, "failed to upgrade feature")
+	}
+	featureSettings := converters.FeatureSettingsFromProtocolToResource(in.GetSettings())
+
+	job, err := PrepareJob(ctx, in.GetTenantId(), "feature upgrade")
+	if err != nil {
+		return empty, err
+	}
+	defer job.Close()
+	task := job.GetTask()
+	svc := job.GetService()
+
+	tracer := debug.NewTracer(task, true /*tracing.ShouldTrace("listeners.feature")*/, "(%d, %s, %s)", targetType, targetRefLabel, featureName).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	switch targetType {
+	case protocol.FeatureTargetType_FT_HOST:
+		rh, xerr := hostfactory.Load(task, svc, targetRef)
+		if xerr != nil {
+			return empty, xerr
+		}
+
+		results, xerr := rh.UpgradeFeature(task, featureName, featureVariables, featureSettings)
+		if xerr != nil {
+			return empty, fail.Wrap(xerr, "cannot upgrade feature")
+		}
+		if results.Successful() {
+			return empty, nil
+		}
+		return empty, fail.ExecutionError(nil, "failed to upgrade feature '%s' on Host '%s' (%s)", featureName, targetRefLabel, results.AllErrorMessages())
+	case protocol.FeatureTargetType_FT_CLUSTER:
+		rc, xerr := clusterfactory.Load(task, svc, targetRef)
+		if xerr != nil {
+			return empty, xerr
+		}
+
+		results, xerr := rc.UpgradeFeature(task, featureName, featureVariables, featureSettings)
+		if xerr != nil {
+			return empty, fail.Wrap(xerr, "cannot upgrade feature")
+		}
+		if results.Successful() {
+			return empty, nil
+		}
+		return empty, fail.ExecutionError(nil, "failed to upgrade feature '%s' on Cluster '%s' (%s)", featureName, targetRefLabel, results.AllErrorMessages())
+	}
+
+	// Should not reach this
+	return empty, fail.Wrap(fail.InconsistentError("reach theoretically unreachable point"), "cannot upgrade feature")
+}
+
+// DryRun renders the scripts of the addition of a Feature on a target, without executing them
+func (s *FeatureListener) DryRun(ctx context.Context, in *protocol.FeatureActionRequest) (_ *protocol.FeatureDryRunResponse, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot render feature scripts")
+
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return nil, fail.InvalidParameterError("in", "cannot be nil")
+	}
+	targetType := in.GetTargetType()
+	targetRef, targetRefLabel := srvutils.GetReference(in.GetTargetRef())
+	if targetRef == "" {
+		return nil, fail.InvalidRequestError("target reference is missing")
+	}
+	featureName := in.GetName()
+	featureVariables, xerr := convertVariablesToDataMap(in.GetVariables())
+	if xerr != nil {
+		return nil, xerr
+	}
+	featureSettings := converters.FeatureSettingsFromProtocolToResource(in.GetSettings())
+	featureSettings.DryRun = true
+
+	job, err := PrepareJob(ctx, in.GetTenantId(), "feature dry-run")
+	if err != nil {
+		return nil, err
+	}
+	defer job.Close()
+	task := job.GetTask()
+	svc := job.GetService()
+
+	tracer := debug.NewTracer(task, true /*tracing.ShouldTrace("listeners.feature")*/, "(%d, %s, %s)", targetType, targetRefLabel, featureName).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	var results resources.Results
+	switch targetType {
+	case protocol.FeatureTargetType_FT_HOST:
+		rh, xerr := hostfactory.Load(task, svc, targetRef)
+		if xerr != nil {
+			return nil, xerr
+		}
+		if results, xerr = rh.AddFeature(task, featureName, featureVariables, featureSettings); xerr != nil {
+			return nil, xerr
+		}
+	case protocol.FeatureTargetType_FT_CLUSTER:
+		rc, xerr := clusterfactory.Load(task, svc, targetRef)
+		if xerr != nil {
+			return nil, xerr
+		}
+		if results, xerr = rc.AddFeature(task, featureName, featureVariables, featureSettings); xerr != nil {
+			return nil, xerr
+		}
+	default:
+		return nil, fail.InvalidParameterError("in.TargetType", "invalid value '%d'", targetType)
+	}
+
+	out := &protocol.FeatureDryRunResponse{}
+	for _, v := range operations.DryRunScripts(results) {
+		out.Scripts = append(out.Scripts, &protocol.FeatureScript{Step: v.Step, Host: v.Host, Script: v.Script})
+	}
+	return out, nil
+}
+
+// Plan resolves the dependencies of features and returns the order of installation
+func (s *FeatureListener) Plan(ctx context.Context, in *protocol.FeaturePlanRequest) (_ *protocol.FeaturePlanResponse, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot plan installation of features")
+
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return nil, fail.InvalidParameterError("in", "cannot be nil")
+	}
+	targetType := in.GetTargetType()
+	if targetType != protocol.FeatureTargetType_FT_HOST && targetType != protocol.FeatureTargetType_FT_CLUSTER {
+		return nil, fail.InvalidParameterError("in.TargetType", "invalid value '%d'", targetType)
+	}
+	targetRef, targetRefLabel := srvutils.GetReference(in.GetTargetRef())
+	if targetRef == "" {
+		return nil, fail.InvalidRequestError("target reference is missing")
+	}
+	featureNames := in.GetNames()
+	if len(featureNames) == 0 {
+		return nil, fail.InvalidRequestError("feature names are missing")
+	}
+	featureVariables, xerr := convertVariablesToDataMap(in.GetVariables())
+	if xerr != nil {
+		return nil, xerr
+	}
+	featureSettings := converters.FeatureSettingsFromProtocolToResource(in.GetSettings())
+
+	job, err := PrepareJob(ctx, in.GetTenantId(), "feature plan")
+	if err != nil {
+		return nil, err
+	}
+	defer job.Close()
+	task := job.GetTask()
+	svc := job.GetService()
+
+	tracer := debug.NewTracer(task, true /*tracing.ShouldTrace("listeners.feature")*/, "(%d, %s, %s)", targetType, targetRefLabel, strings.Join(featureNames, ",")).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	var target resources.Targetable
+	switch targetType {
+	case protocol.FeatureTargetType_FT_HOST:
+		if target, xerr = hostfactory.Load(task, svc, targetRef); xerr != nil {
+			return nil, xerr
+		}
+	case protocol.FeatureTargetType_FT_CLUSTER:
+		if target, xerr = clusterfactory.Load(task, svc, targetRef); xerr != nil {
+			return nil, xerr
+		}
+	}
+
+	graph, xerr := operations.NewFeatureGraph(task, target, featureNames, featureVariables, featureSettings)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	out := &protocol.FeaturePlanResponse{Present: graph.Present()}
+	for _, v := range graph.Plan() {
+		out.Steps = append(out.Steps, &protocol.FeaturePlanStep{Features: v})
+	}
+	return out, nil
+}
+
+// History returns the last actions on features of a target
+func (s *FeatureListener) History(ctx context.Context, in *protocol.FeatureHistoryRequest) (_ *protocol.FeatureHistoryResponse, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot get history of features")
+
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return nil, fail.InvalidParameterError("in", "cannot be nil")
+	}
+	targetType := in.GetTargetType()
+	if targetType != protocol.FeatureTargetType_FT_HOST && targetType != protocol.FeatureTargetType_FT_CLUSTER {
+		return nil, fail.InvalidParameterError("in.TargetType", "invalid value '%d'", targetType)
+	}
+	targetRef, targetRefLabel := srvutils.GetReference(in.GetTargetRef())
+	if targetRef == "" {
+		return nil, fail.InvalidRequestError("target reference is missing")
+	}
+
+	job, err := PrepareJob(ctx, in.GetTenantId(), "feature history")
+	if err != nil {
+		return nil, err
+	}
+	defer job.Close()
+	task := job.GetTask()
+	svc := job.GetService()
+
+	tracer := debug.NewTracer(task, true /*tracing.ShouldTrace("listeners.feature")*/, "(%d, %s, %s)", targetType, targetRefLabel, in.GetName()).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	var (
+		target resources.Targetable
+		xerr   fail.Error
+	)
+	switch targetType {
+	case protocol.FeatureTargetType_FT_HOST:
+		if target, xerr = hostfactory.Load(task, svc, targetRef); xerr != nil {
+			return nil, xerr
+		}
+	case protocol.FeatureTargetType_FT_CLUSTER:
+		if target, xerr = clusterfactory.Load(task, svc, targetRef); xerr != nil {
+			return nil, xerr
+		}
+	}
+
+	history, xerr := target.GetFeatureHistory(task)
+	if xerr != nil {
+		return nil, xerr
+	}
+	return converters.FeatureHistoryFromPropertyToProtocol(*history, in.GetName()), nil
+}
+
+// Validate checks the specification of a feature
+func (s *FeatureListener) Validate(ctx context.Context, in *protocol.FeatureValidateRequest) (_ *protocol.FeatureValidateResponse, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot validate feature")
+
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return nil, fail.InvalidParameterError("in", "cannot be nil")
+	}
+	featureName := in.GetName()
+	if featureName == "" {
+		return nil, fail.InvalidRequestError("feature name is missing")
+	}
+
+	// Validation does not need a tenant, so there is no job
+	task, xerr := concurrency.NewTaskWithContext(ctx, nil)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	tracer := debug.NewTracer(task, true /*tracing.ShouldTrace("listeners.feature")*/, "(%s)", featureName).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	issues, xerr := operations.LintFeature(task, featureName, in.GetContent())
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	out := &protocol.FeatureValidateResponse{Name: featureName}
+	for _, v := range issues {
+		out.Issues = append(out.Issues, &protocol.FeatureLintIssue{Key: v.Key, Message: v.Message, Warning: v.Warning})
+	}
+	return out, nil
+}
+
+// AddRepository registers a new feature repository
+func (s *FeatureListener) AddRepository(ctx context.Context, in *protocol.FeatureRepository) (_ *protocol.FeatureRepository, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot add feature repository")
+
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return nil, fail.InvalidParameterError("in", "cannot be nil")
+	}
+	repoName := in.GetName()
+	if repoName == "" {
+		return nil, fail.InvalidRequestError("feature repository name is missing")
+	}
+
+	// Feature repositories do not depend on tenant, so there is no job
+	task, xerr := concurrency.NewTaskWithContext(ctx, nil)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	tracer := debug.NewTracer(task, true /*tracing.ShouldTrace("listeners.feature")*/, "(%s, %s)", repoName, in.GetUrl()).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	repo, xerr := operations.AddFeatureRepository(task, operations.FeatureRepository{
+		Name: repoName,
+		Type: in.GetType(),
+		URL:  in.GetUrl(),
+		Ref:  in.GetRef(),
+	})
+	if xerr != nil {
+		return nil, xerr
+	}
+	return repo.ToProtocol(), nil
+}
+
+// UpdateRepositories retrieves the content of one or all feature repositories
+func (s *FeatureListener) UpdateRepositories(ctx context.Context, in *protocol.FeatureRepositoryUpdateRequest) (_ *protocol.FeatureRepositoryListResponse, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot update feature repositories")
+
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return nil, fail.InvalidParameterError("in", "cannot be nil")
+	}
+
+	task, xerr := concurrency.NewTaskWithContext(ctx, nil)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	tracer := debug.NewTracer(task, true /*tracing.ShouldTrace("listeners.feature")*/, "(%s)", in.GetName()).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	list, xerr := operations.UpdateFeatureRepositories(task, in.GetName())
+	if xerr != nil {
+		return nil, xerr
+	}
+	out := &protocol.FeatureRepositoryListResponse{}
+	for _, v := range list {
+		out.Repositories = append(out.Repositories, v.ToProtocol())
+	}
+	return out, nil
+}
+
+// ListRepositories lists the feature repositories
+func (s *FeatureListener) ListRepositories(ctx context.Context, in *googleprotobuf.Empty) (_ *protocol.FeatureRepositoryListResponse, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot list feature repositories")
+
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+
+	list, xerr := operations.ListFeatureRepositories()
+	if xerr != nil {
+		return nil, xerr
+	}
+	out := &protocol.FeatureRepositoryListResponse{}
+	for _, v := range list {
+		out.Repositories = append(out.Repositories, v.ToProtocol())
+	}
+	return out, nil
+}
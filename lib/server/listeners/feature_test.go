@@ -0,0 +1,49 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package listeners
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+func TestConvertVariablesToDataMap(t *testing.T) {
+	out, xerr := convertVariablesToDataMap(map[string]string{
+		"Version":  "1.2",
+		"Password": `{{ randAlphaNum 16 }}`,
+		"Empty":    "",
+	})
+	require.Nil(t, xerr)
+	assert.Equal(t, "1.2", out["Version"])
+	assert.Equal(t, `{{ randAlphaNum 16 }}`, out["Password"])
+
+	for _, v := range []string{
+		`{{secret "tenants/ovh/password"}}`,
+		`{{secret "tenants/ovh/password" | b64enc}}`,
+		`{{env "SAFESCALE_SECRET_PASSPHRASE"}}`,
+		`{{expandenv "$HOME"}}`,
+		`{{getHostByName "localhost"}}`,
+	} {
+		_, xerr = convertVariablesToDataMap(map[string]string{"Password": v})
+		require.NotNil(t, xerr, v)
+		assert.IsType(t, &fail.ErrInvalidRequest{}, xerr, v)
+	}
+}
@@ -25,31 +25,27 @@ import (
 
 	"github.com/CS-SI/SafeScale/lib/protocol"
 	"github.com/CS-SI/SafeScale/lib/server"
-	"github.com/CS-SI/SafeScale/lib/server/iaas"
 	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
 	"github.com/CS-SI/SafeScale/lib/utils/debug"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
 )
 
-// PrepareJob creates a new job
+// PrepareJob creates a new job working on the tenant tenantID or, if empty, on the tenant the call applies to
 func PrepareJob(ctx context.Context, tenantID string, jobDescription string) (server.Job, fail.Error) {
 	if ctx == nil {
 		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
 	}
 
-	var tenant *Tenant
-	if tenantID != "" {
-		service, xerr := iaas.UseService(tenantID)
-		if xerr != nil {
-			return nil, xerr
-		}
-		tenant = &Tenant{name: tenantID, Service: service}
-	} else {
-		tenant = GetCurrentTenant()
-		if tenant == nil {
+	if tenantID == "" {
+		tenantID = CurrentTenantName(ctx)
+		if tenantID == "" {
 			return nil, fail.NotFoundError("no tenant set")
 		}
 	}
+	tenant, xerr := useTenant(tenantID)
+	if xerr != nil {
+		return nil, xerr
+	}
 	newctx, cancel := context.WithCancel(ctx)
 
 	job, xerr := server.NewJob(newctx, cancel, tenant.Service, jobDescription)
@@ -18,14 +18,17 @@ package listeners
 
 import (
 	"context"
+	"time"
 
 	"github.com/asaskevich/govalidator"
 	googleprotobuf "github.com/golang/protobuf/ptypes/empty"
 	"github.com/sirupsen/logrus"
 
 	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/audit"
 	"github.com/CS-SI/SafeScale/lib/server/handlers"
 	"github.com/CS-SI/SafeScale/lib/server/iaas"
+	"github.com/CS-SI/SafeScale/lib/server/resources/operations/converters"
 	"github.com/CS-SI/SafeScale/lib/utils/debug"
 	"github.com/CS-SI/SafeScale/lib/utils/debug/tracing"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
@@ -37,33 +40,6 @@ type Tenant struct {
 	Service iaas.Service
 }
 
-var (
-	currentTenant *Tenant
-)
-
-// GetCurrentTenant contains the current tenant
-var GetCurrentTenant = getCurrentTenant
-
-// getCurrentTenant returns the tenant used for commands or, if not set, set the tenant to use if it is the only one registered
-func getCurrentTenant() *Tenant {
-	if currentTenant == nil {
-		tenants, err := iaas.GetTenantNames()
-		if err != nil || len(tenants) != 1 {
-			return nil
-		}
-		// Set unique tenant as selected
-		logrus.Println("Unique tenant set")
-		for name := range tenants {
-			service, err := iaas.UseService(name)
-			if err != nil {
-				return nil
-			}
-			currentTenant = &Tenant{name: name, Service: service}
-		}
-	}
-	return currentTenant
-}
-
 // TenantListener server is used to implement SafeScale.safescale.
 type TenantListener struct{}
 
@@ -102,7 +78,7 @@ func (s *TenantListener) List(ctx context.Context, in *googleprotobuf.Empty) (_
 	return &protocol.TenantList{Tenants: list}, nil
 }
 
-// Get returns the name of the current tenant used
+// Get returns the name of the tenant the call applies to
 func (s *TenantListener) Get(ctx context.Context, in *googleprotobuf.Empty) (_ *protocol.TenantName, err error) {
 	defer fail.OnExitConvertToGRPCStatus(&err)
 
@@ -119,14 +95,21 @@ func (s *TenantListener) Get(ctx context.Context, in *googleprotobuf.Empty) (_ *
 
 	defer fail.OnExitLogError(&err)
 
-	getCurrentTenant()
-	if currentTenant == nil {
+	name := CurrentTenantName(ctx)
+	if name == "" {
 		return nil, fail.NotFoundError("no tenant set")
 	}
-	return &protocol.TenantName{Name: currentTenant.name}, nil
+	tenants, xerr := iaas.GetTenantNames()
+	if xerr != nil {
+		return nil, xerr
+	}
+	if _, ok := tenants[name]; !ok {
+		return nil, fail.NotFoundError("tenant '%s' not found", name)
+	}
+	return &protocol.TenantName{Name: name}, nil
 }
 
-// Set the the tenant to use for each command
+// Set checks the tenant can be used; the tenant to work with is a preference of each client, sent with each of its calls
 func (s *TenantListener) Set(ctx context.Context, in *protocol.TenantName) (empty *googleprotobuf.Empty, err error) {
 	defer fail.OnExitConvertToGRPCStatus(&err)
 	defer fail.OnExitWrapError(&err, "cannot set tenant")
@@ -149,18 +132,190 @@ func (s *TenantListener) Set(ctx context.Context, in *protocol.TenantName) (empt
 
 	defer fail.OnExitLogError(&err)
 
-	if currentTenant != nil && currentTenant.name == in.GetName() {
-		return empty, nil
+	if _, xerr := useTenant(in.GetName()); xerr != nil {
+		return empty, xerr
 	}
+	return empty, nil
+}
 
-	service, xerr := iaas.UseService(in.GetName())
-	if xerr != nil {
+// SetCredentials stores the credentials of a tenant in the encrypted credentials file
+func (s *TenantListener) SetCredentials(ctx context.Context, in *protocol.TenantCredentialsRequest) (empty *googleprotobuf.Empty, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot set tenant credentials")
+
+	empty = &googleprotobuf.Empty{}
+	if s == nil {
+		return empty, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return empty, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return empty, fail.InvalidParameterError("in", "cannot be nil")
+	}
+
+	name := in.GetName()
+	tracer := debug.NewTracer(nil, tracing.ShouldTrace("listeners.tenant"), "('%s')", name).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	if xerr := iaas.SetTenantCredentials(name, in.GetCredentials()); xerr != nil {
 		return empty, xerr
 	}
-	currentTenant = &Tenant{name: in.GetName(), Service: service}
+
+	// The next calls on the tenant will use the new credentials
+	forgetTenant(name, nil)
 	return empty, nil
 }
 
+// Validate checks the tenants of a tenants file against the settings required by their providers
+// If no content is provided, validates the tenants file used by safescaled
+func (s *TenantListener) Validate(ctx context.Context, in *protocol.TenantValidateRequest) (_ *protocol.TenantValidateResponse, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot validate tenants")
+
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return nil, fail.InvalidParameterError("in", "cannot be nil")
+	}
+
+	tracer := debug.NewTracer(nil, tracing.ShouldTrace("listeners.tenant"), "('%s')", in.GetFormat()).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	var (
+		file   string
+		report []iaas.TenantValidation
+		xerr   fail.Error
+	)
+	if len(in.GetContent()) > 0 {
+		format := in.GetFormat()
+		if format == "" {
+			format = "toml"
+		}
+		report, xerr = iaas.ValidateTenantsContent(in.GetContent(), format)
+	} else {
+		file, report, xerr = iaas.ValidateTenantsFile()
+	}
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	out := &protocol.TenantValidateResponse{File: file, Tenants: make([]*protocol.TenantValidation, 0, len(report))}
+	for _, v := range report {
+		out.Tenants = append(out.Tenants, &protocol.TenantValidation{
+			Name:     v.Name,
+			Provider: v.Provider,
+			Valid:    v.Valid(),
+			Errors:   v.Errors,
+		})
+	}
+	return out, nil
+}
+
+// Audit returns the audit events recorded for a tenant
+func (s *TenantListener) Audit(ctx context.Context, in *protocol.TenantAuditRequest) (_ *protocol.TenantAuditResponse, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot get audit events of tenant")
+
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return nil, fail.InvalidParameterError("in", "cannot be nil")
+	}
+
+	name := in.GetName()
+	if name == "" {
+		if name = CurrentTenantName(ctx); name == "" {
+			return nil, fail.NotFoundError("no tenant set")
+		}
+	}
+
+	tracer := debug.NewTracer(nil, tracing.ShouldTrace("listeners.tenant"), "('%s')", name).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	trail := audit.DefaultTrail()
+	if trail == nil {
+		return nil, fail.NotAvailableError("audit trail is not enabled")
+	}
+	query := audit.Query{Tenant: name, Resource: in.GetResource(), Limit: int(in.GetLimit())}
+	if in.GetSince() > 0 {
+		query.Since = time.Unix(in.GetSince(), 0)
+	}
+	if in.GetUntil() > 0 {
+		query.Until = time.Unix(in.GetUntil(), 0)
+	}
+	events, xerr := trail.Search(query)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	out := &protocol.TenantAuditResponse{Name: name, Events: make([]*protocol.AuditEvent, 0, len(events))}
+	for _, e := range events {
+		out.Events = append(out.Events, &protocol.AuditEvent{
+			Time:           e.Time.Format(time.RFC3339Nano),
+			Caller:         e.Caller,
+			Authentication: e.Authentication,
+			Address:        e.Address,
+			Tenant:         e.Tenant,
+			Operation:      e.Operation,
+			Resource:       e.Resource,
+			Parameters:     string(e.Parameters),
+			Result:         e.Result,
+			Error:          e.Error,
+			DurationMs:     e.DurationMs,
+		})
+	}
+	return out, nil
+}
+
+// OnTenantsChange is called when the tenants file has been reloaded; it reports the invalid tenants and
+// makes the next calls use the new configuration of the tenants in use
+func OnTenantsChange(report []iaas.TenantValidation) {
+	ReportTenantsValidation(report)
+
+	valid := make(map[string]bool, len(report))
+	for _, v := range report {
+		valid[v.Name] = v.Valid()
+	}
+	for _, name := range usedTenantNames() {
+		isValid, ok := valid[name]
+		switch {
+		case !ok:
+			logrus.Warnf("Tenant '%s' has been removed from the tenants file, it cannot be used anymore", name)
+			forgetTenant(name, nil)
+		case !isValid:
+			logrus.Warnf("Tenant '%s' is invalid in the new tenants file, keeping its previous configuration", name)
+		default:
+			forgetTenant(name, nil)
+			logrus.Infof("Tenant '%s' reloaded", name)
+		}
+	}
+}
+
+// ReportTenantsValidation logs the tenants that cannot be used, with the reasons
+func ReportTenantsValidation(report []iaas.TenantValidation) {
+	for _, v := range report {
+		if v.Valid() {
+			logrus.Debugf("Tenant '%s' (%s) is valid", v.Name, v.Provider)
+			continue
+		}
+		for _, e := range v.Errors {
+			logrus.Warnf("Tenant '%s' is invalid: %s", v.Name, e)
+		}
+	}
+}
+
 // Cleanup removes everything corresponding to SafeScale from tenant (metadata in particular)
 func (s *TenantListener) Cleanup(ctx context.Context, in *protocol.TenantCleanupRequest) (empty *googleprotobuf.Empty, err error) {
 	defer fail.OnExitConvertToGRPCStatus(&err)
@@ -195,52 +350,171 @@ func (s *TenantListener) Cleanup(ctx context.Context, in *protocol.TenantCleanup
 	defer tracer.Exiting()
 	defer fail.OnExitLogError(&err, tracer.TraceMessage())
 
-	if currentTenant != nil && currentTenant.name == in.GetName() {
+	if CurrentTenantName(ctx) == in.GetName() {
 		return empty, nil
 	}
 
-	service, xerr := iaas.UseService(in.GetName())
+	tenant, xerr := useTenant(in.GetName())
 	if xerr != nil {
 		return empty, xerr
 	}
 
-	xerr = service.TenantCleanup(in.Force)
+	xerr = tenant.Service.TenantCleanup(in.Force)
 	return empty, xerr
 }
 
 // Scan proceeds a scan of host corresponding to each template to gather real data(metadata in particular)
-func (s *TenantListener) Scan(ctx context.Context, in *googleprotobuf.Empty) (empty *googleprotobuf.Empty, err error) {
+func (s *TenantListener) Scan(ctx context.Context, in *protocol.TenantScanRequest) (_ *protocol.TenantScanResponse, err error) {
 	defer fail.OnExitConvertToGRPCStatus(&err)
 	defer fail.OnExitWrapError(&err, "cannot scan tenant")
 
-	empty = &googleprotobuf.Empty{}
 	if s == nil {
-		return empty, fail.InvalidInstanceError()
+		return nil, fail.InvalidInstanceError()
 	}
 	if ctx == nil {
-		return empty, fail.InvalidParameterError("ctx", "cannot be nil")
+		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return nil, fail.InvalidParameterError("in", "cannot be nil")
 	}
 
-	job, xerr := PrepareJob(ctx, "", "tenant scan")
+	job, xerr := PrepareJob(ctx, in.GetName(), "tenant scan")
 	if xerr != nil {
 		return nil, xerr
 	}
 	defer job.Close()
 
-	getCurrentTenant()
-	if currentTenant == nil {
-		return nil, fail.NotFoundError("no tenant set")
+	name := job.GetService().GetName()
+	tracer := debug.NewTracer(job.GetTask(), tracing.ShouldTrace("listeners.tenant"), "('%s')", name).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	handler := handlers.NewScannerHandler(job)
+	report, xerr := handler.Scan(handlers.ScanRequest{
+		Images:      in.GetImages(),
+		Templates:   in.GetTemplates(),
+		Parallelism: uint(in.GetParallelism()),
+		Budget:      in.GetBudget(),
+		Force:       in.GetForce(),
+	})
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	out := &protocol.TenantScanResponse{
+		Name:    name,
+		Region:  report.Region,
+		Image:   report.Image,
+		Cost:    report.Cost,
+		Skipped: report.Skipped,
+		Failed:  report.Failed,
+	}
+	for _, v := range report.Scanned {
+		out.Scanned = append(out.Scanned, converters.StoredCPUInfoFromAbstractToProtocol(v))
+	}
+	return out, nil
+}
+
+// ScanStatus returns the data collected by the scanner for a tenant
+func (s *TenantListener) ScanStatus(ctx context.Context, in *protocol.TenantName) (_ *protocol.TenantScanStatus, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot get scan status of tenant")
+
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return nil, fail.InvalidParameterError("in", "cannot be nil")
 	}
 
-	name := currentTenant.name
+	job, xerr := PrepareJob(ctx, in.GetName(), "tenant scan status")
+	if xerr != nil {
+		return nil, xerr
+	}
+	defer job.Close()
+
+	name := job.GetService().GetName()
 	tracer := debug.NewTracer(job.GetTask(), tracing.ShouldTrace("listeners.tenant"), "('%s')", name).WithStopwatch().Entering()
 	defer tracer.Exiting()
 	defer fail.OnExitLogError(&err, tracer.TraceMessage())
 
-	handler := handlers.NewScannerHandler(job)
+	status, xerr := handlers.NewScannerHandler(job).Status()
+	if xerr != nil {
+		return nil, xerr
+	}
 
-	xerr = handler.Scan()
-	return empty, xerr
+	out := &protocol.TenantScanStatus{
+		Name:    name,
+		Region:  status.Region,
+		Pending: status.Pending,
+	}
+	for _, v := range status.Scanned {
+		out.Scanned = append(out.Scanned, converters.StoredCPUInfoFromAbstractToProtocol(v))
+	}
+	for _, v := range status.Probes {
+		out.Probes = append(out.Probes, v.HostName)
+	}
+	return out, nil
+}
+
+// Quota returns the use and the limits of the resources of a tenant
+func (s *TenantListener) Quota(ctx context.Context, in *protocol.TenantName) (_ *protocol.TenantQuotaResponse, err error) {
+	defer fail.OnExitConvertToGRPCStatus(&err)
+	defer fail.OnExitWrapError(&err, "cannot get quotas of tenant")
+
+	if s == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if ctx == nil {
+		return nil, fail.InvalidParameterError("ctx", "cannot be nil")
+	}
+	if in == nil {
+		return nil, fail.InvalidParameterError("in", "cannot be nil")
+	}
+
+	name := in.GetName()
+	if name == "" {
+		if name = CurrentTenantName(ctx); name == "" {
+			return nil, fail.NotFoundError("no tenant set")
+		}
+	}
+
+	job, xerr := PrepareJob(ctx, name, "tenant quota")
+	if xerr != nil {
+		return nil, xerr
+	}
+	defer job.Close()
+
+	tracer := debug.NewTracer(job.GetTask(), tracing.ShouldTrace("listeners.tenant"), "('%s')", name).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&err, tracer.TraceMessage())
+
+	report, xerr := handlers.NewQuotaHandler(job).Report()
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	out := &protocol.TenantQuotaResponse{
+		Name:          name,
+		User:          report.User,
+		ProviderError: report.ProviderError,
+	}
+	for _, v := range report.Quotas {
+		out.Quotas = append(out.Quotas, &protocol.TenantQuota{
+			Resource:         string(v.Resource),
+			Used:             v.Used,
+			Limit:            v.Limit,
+			UserUsed:         v.UserUsed,
+			UserLimit:        v.UserLimit,
+			ProviderReported: v.ProviderReported,
+			ProviderUsed:     v.ProviderUsed,
+			ProviderLimit:    v.ProviderLimit,
+		})
+	}
+	return out, nil
 }
 
 // Inspect returns information about a tenant
@@ -0,0 +1,222 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package listeners
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/CS-SI/SafeScale/lib/server/iaas"
+	srvutils "github.com/CS-SI/SafeScale/lib/server/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// tenantEntry is the service of a tenant, loaded once and shared by the concurrent calls on the tenant
+type tenantEntry struct {
+	once   sync.Once
+	tenant *Tenant
+	err    fail.Error
+}
+
+var (
+	tenantEntries     = map[string]*tenantEntry{}
+	tenantEntriesLock sync.Mutex
+)
+
+// useTenant returns the tenant named name, creating its service on first use
+func useTenant(name string) (*Tenant, fail.Error) {
+	if name == "" {
+		return nil, fail.InvalidParameterError("name", "cannot be empty string")
+	}
+
+	tenantEntriesLock.Lock()
+	entry, ok := tenantEntries[name]
+	if !ok {
+		entry = &tenantEntry{}
+		tenantEntries[name] = entry
+	}
+	tenantEntriesLock.Unlock()
+
+	entry.once.Do(func() {
+		service, xerr := iaas.UseService(name)
+		if xerr != nil {
+			entry.err = xerr
+			return
+		}
+		entry.tenant = &Tenant{name: name, Service: service}
+	})
+	if entry.err != nil {
+		// Does not keep the failure, the next call will try again
+		forgetTenant(name, entry)
+		return nil, entry.err
+	}
+	return entry.tenant, nil
+}
+
+// TenantService returns the service of the tenant, shared with the calls on the tenant
+func TenantService(name string) (iaas.Service, fail.Error) {
+	tenant, xerr := useTenant(name)
+	if xerr != nil {
+		return nil, xerr
+	}
+	return tenant.Service, nil
+}
+
+// forgetTenant removes the service of the tenant from the cache, so that the next call recreates it with the current
+// configuration; if entry is not nil, the service is removed only if it is still the cached one
+func forgetTenant(name string, entry *tenantEntry) {
+	tenantEntriesLock.Lock()
+	defer tenantEntriesLock.Unlock()
+	if current, ok := tenantEntries[name]; ok && (entry == nil || current == entry) {
+		delete(tenantEntries, name)
+	}
+}
+
+// usedTenantNames returns the names of the tenants whose service is cached
+func usedTenantNames() []string {
+	tenantEntriesLock.Lock()
+	defer tenantEntriesLock.Unlock()
+	names := make([]string, 0, len(tenantEntries))
+	for name := range tenantEntries {
+		names = append(names, name)
+	}
+	return names
+}
+
+type tenantKey struct{}
+
+// CurrentTenantName returns the name of the tenant the call applies to: the tenant designated by the call, or, if
+// none is, the tenant of the daemon if it is the only one registered (empty string if no tenant applies)
+func CurrentTenantName(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if name, ok := ctx.Value(tenantKey{}).(string); ok {
+		return name
+	}
+	if name := metadataTenantName(ctx); name != "" {
+		return name
+	}
+	return defaultTenantName()
+}
+
+// metadataTenantName returns the tenant carried by the metadata of the call
+func metadataTenantName(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(srvutils.TenantMetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// defaultTenantName returns the name of the tenant if only one is registered
+func defaultTenantName() string {
+	tenants, xerr := iaas.GetTenantNames()
+	if xerr != nil || len(tenants) != 1 {
+		return ""
+	}
+	for name := range tenants {
+		return name
+	}
+	return ""
+}
+
+// requestTenantIDs returns the non-empty 'tenant_id' fields of the request and of the messages it contains directly
+func requestTenantIDs(req interface{}) []string {
+	var ids []string
+	add := func(v interface{}) {
+		if m, ok := v.(interface{ GetTenantId() string }); ok {
+			if id := m.GetTenantId(); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	add(req)
+	rv := reflect.ValueOf(req)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ids
+	}
+	rv = rv.Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		if f := rv.Field(i); f.Kind() == reflect.Ptr && !f.IsNil() && f.CanInterface() {
+			add(f.Interface())
+		}
+	}
+	return ids
+}
+
+// withRequestTenant returns a copy of ctx carrying the name of the tenant designated by the metadata of the call or by
+// the request; fails if they designate different tenants
+func withRequestTenant(ctx context.Context, req interface{}) (context.Context, fail.Error) {
+	name := metadataTenantName(ctx)
+	for _, id := range requestTenantIDs(req) {
+		if name == "" {
+			name = id
+		} else if id != name {
+			return ctx, fail.InvalidRequestError("request designates tenant '%s' but the call is made on tenant '%s'", id, name)
+		}
+	}
+	if name == "" {
+		name = defaultTenantName()
+	}
+	return context.WithValue(ctx, tenantKey{}, name), nil
+}
+
+// TenantUnaryServerInterceptor returns the interceptor determining the tenant each unary RPC applies to
+func TenantUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		defer fail.OnExitConvertToGRPCStatus(&err)
+
+		ctx, xerr := withRequestTenant(ctx, req)
+		if xerr != nil {
+			return nil, xerr
+		}
+		return handler(ctx, req)
+	}
+}
+
+// TenantStreamServerInterceptor returns the interceptor determining the tenant each streaming RPC applies to
+func TenantStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer fail.OnExitConvertToGRPCStatus(&err)
+
+		ctx, xerr := withRequestTenant(ss.Context(), nil)
+		if xerr != nil {
+			return xerr
+		}
+		return handler(srv, &tenantStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// tenantStream is a grpc.ServerStream whose context carries the tenant of the call
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context carrying the tenant of the call
+func (s *tenantStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package listeners
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	srvutils "github.com/CS-SI/SafeScale/lib/server/utils"
+)
+
+func TestWithRequestTenant(t *testing.T) {
+	withHeader := func(name string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(srvutils.TenantMetadataKey, name))
+	}
+
+	// tenant from the metadata of the call
+	ctx, xerr := withRequestTenant(withHeader("ovh-dev"), &protocol.Reference{Name: "host1"})
+	require.Nil(t, xerr)
+	assert.Equal(t, "ovh-dev", CurrentTenantName(ctx))
+
+	// tenant from the request, directly or in a contained message
+	ctx, xerr = withRequestTenant(context.Background(), &protocol.Reference{TenantId: "aws-prod", Name: "host1"})
+	require.Nil(t, xerr)
+	assert.Equal(t, "aws-prod", CurrentTenantName(ctx))
+	req := &protocol.VolumeAttachmentRequest{Volume: &protocol.Reference{TenantId: "aws-prod"}, Host: &protocol.Reference{Name: "host1"}}
+	ctx, xerr = withRequestTenant(context.Background(), req)
+	require.Nil(t, xerr)
+	assert.Equal(t, "aws-prod", CurrentTenantName(ctx))
+
+	// consistent or conflicting designations
+	_, xerr = withRequestTenant(withHeader("aws-prod"), req)
+	assert.Nil(t, xerr)
+	_, xerr = withRequestTenant(withHeader("ovh-dev"), req)
+	assert.NotNil(t, xerr)
+	req.Host.TenantId = "ovh-dev"
+	_, xerr = withRequestTenant(context.Background(), req)
+	assert.NotNil(t, xerr)
+
+	// each call keeps its own tenant
+	assert.Equal(t, "ovh-dev", CurrentTenantName(withHeader("ovh-dev")))
+	assert.Equal(t, "aws-prod", CurrentTenantName(withHeader("aws-prod")))
+}
@@ -27,21 +27,41 @@ import (
 
 // ClusterRequest defines what kind of Cluster is wanted
 type ClusterRequest struct {
-	Name                    string                 // contains the name of the cluster wanted
-	CIDR                    string                 // defines the network to create
-	Domain                  string                 // ...
-	Complexity              clustercomplexity.Enum // is the implementation wanted, can be Small, Normal or Large
-	Flavor                  clusterflavor.Enum     // tells what kind of cluster to create
-	NetworkID               string                 // is the ID of the network to use; may be empty and in this case a new Network will be created
-	Tenant                  string                 // contains the name of the tenant
-	KeepOnFailure           bool                   // tells if resources have to be kept in case of failure (for further analysis)
-	GatewaysDef             HostSizingRequirements // sizing of gateways
-	MastersDef              HostSizingRequirements // sizing of Masters
-	NodesDef                HostSizingRequirements // sizing of nodes
-	InitialNodeCount        uint                   // contains the initial count of nodes to create (cannot be less than flavor requirement)
-	OS                      string                 // contains the name of the linux distribution wanted
-	DisabledDefaultFeatures map[string]struct{}    // contains the list of features that should be installed by default but we don't want actually
+	Name                    string                   // contains the name of the cluster wanted
+	CIDR                    string                   // defines the network to create
+	Domain                  string                   // ...
+	Complexity              clustercomplexity.Enum   // is the implementation wanted, can be Small, Normal or Large
+	Flavor                  clusterflavor.Enum       // tells what kind of cluster to create
+	NetworkID               string                   // is the ID of the network to use; may be empty and in this case a new Network will be created
+	Tenant                  string                   // contains the name of the tenant
+	KeepOnFailure           bool                     // tells if resources have to be kept in case of failure (for further analysis)
+	GatewaysDef             HostSizingRequirements   // sizing of gateways
+	MastersDef              HostSizingRequirements   // sizing of Masters
+	NodesDef                HostSizingRequirements   // sizing of nodes
+	InitialNodeCount        uint                     // contains the initial count of nodes to create (cannot be less than flavor requirement)
+	OS                      string                   // contains the name of the linux distribution wanted
+	DisabledDefaultFeatures map[string]struct{}      // contains the list of features that should be installed by default but we don't want actually
+	NodePools               []ClusterNodePoolRequest // contains the node pools to create in addition to (or in replacement of) the default one
+}
+
+// DefaultClusterNodePool is the name of the node pool containing the nodes created without explicit pool
+const DefaultClusterNodePool = "default"
+
+// ClusterNodePoolRequest defines a group of nodes sharing the same settings wanted in a Cluster
+type ClusterNodePoolRequest struct {
+	Name   string                 // name of the pool
+	Sizing HostSizingRequirements // sizing of the nodes of the pool; field Image overrides the image of the cluster
+	Count  uint                   // number of nodes of the pool to create
+	Labels map[string]string      // labels to apply to the nodes (if the flavor supports it)
+	Taints []string               // taints to apply to the nodes, in the form 'key[=value]:effect' (if the flavor supports it)
+}
 
+// ClusterUpgradeRequest defines what has to be upgraded on the nodes of a Cluster
+type ClusterUpgradeRequest struct {
+	OSPackages     bool   // upgrades the OS packages of the nodes
+	Image          string // replaces the nodes by nodes using this image (no replacement if empty)
+	Pool           string // restricts the upgrade to the nodes of this node pool (all nodes if empty)
+	MaxUnavailable uint   // number of nodes upgraded simultaneously
 }
 
 // ClusterIdentity contains the bare minimum information about a cluster
@@ -52,6 +72,8 @@ type ClusterIdentity struct {
 	Keypair    *KeyPair               `json:"keypair"`    // Keypair contains the key-pair used inside the Cluster
 	// AdminPassword contains the password of 'cladm' account. This password is used to connect via Guacamole, but cannot be used with SSH (by choice)
 	AdminPassword string `json:"admin_password"`
+	// Owner contains the name of the authenticated caller who created the Cluster (empty without authentication)
+	Owner string `json:"owner,omitempty"`
 }
 
 // NewClusterIdentity ...
@@ -81,6 +81,7 @@ type HostSizingRequirements struct {
 	Replaceable bool // Tells if we accept server that could be removed without notice (AWS proposes such kind of server with SPOT
 	Image       string
 	Template    string // if != "", describes the template to use and disables the use of other fields
+	Policy      string // if != "", overrides the template selection policy of the tenant (cf. templateselection.Parse)
 }
 
 func (hsr HostSizingRequirements) Equals(in HostSizingRequirements) bool {
@@ -132,9 +133,11 @@ type StoredCPUInfo struct {
 	DiskSize       int64   `json:"disk_size_Gb,omitempty"`
 	MainDiskType   string  `json:"main_disk_type"`
 	MainDiskSpeed  float64 `json:"main_disk_speed_MBps"`
-	SampleNetSpeed float64 `json:"sample_net_speed_KBps"`
+	SampleNetSpeed float64 `json:"sample_net_speed_KBps"` // in MB/s despite the JSON key, kept for compatibility
 	EphDiskSize    int64   `json:"eph_disk_size_Gb"`
 	PricePerHour   float64 `json:"price_in_dollars_hour"`
+
+	Errors map[string]string `json:"errors,omitempty"` // reasons why fields could not be collected, indexed by field json name
 }
 
 // Image represents an OS image
@@ -173,6 +176,7 @@ type HostRequest struct {
 	KeepOnFailure    bool                // KeepOnFailure tells if resource must be kept on failure
 	Preemptible      bool                // Use spot-like instance
 	SecurityGroupIDs map[string]struct{} // List of Security Groups to attach to IPAddress (using map as dict)
+	QuotaReservation string              // QuotaReservation contains the ID of the reservation of quotas covering the host (made by the cluster creating it); if empty, the quotas are checked
 
 }
 
@@ -0,0 +1,27 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package abstract
+
+// ProviderQuota contains a limit set by the provider on a kind of resource, and its current use
+type ProviderQuota struct {
+	Limit float64 `json:"limit"` // negative if the provider sets no limit
+	Used  float64 `json:"used"`
+}
+
+// ProviderQuotas contains the quotas of the provider, indexed by the keyword designating the kind of resource in the
+// section 'quotas' of tenants file ('Hosts', 'Cores', 'RAM', ...)
+type ProviderQuotas map[string]ProviderQuota
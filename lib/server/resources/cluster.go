@@ -22,6 +22,7 @@ import (
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clustercomplexity"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterflavor"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterstate"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
 	propertiesv3 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v3"
 	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
 	"github.com/CS-SI/SafeScale/lib/utils/data"
@@ -39,34 +40,38 @@ type Cluster interface {
 	Browse(task concurrency.Task, callback func(*abstract.ClusterIdentity) fail.Error) fail.Error // ...
 	Create(task concurrency.Task, req abstract.ClusterRequest) fail.Error                         // Create creates a new cluster and save its metadata
 	GetIdentity(task concurrency.Task) (abstract.ClusterIdentity, fail.Error)
-	GetFlavor(task concurrency.Task) (clusterflavor.Enum, fail.Error)                                                // Flavor returns the flavor of the cluster
-	GetComplexity(task concurrency.Task) (clustercomplexity.Enum, fail.Error)                                        // Complexity returns the complexity of the cluster
-	GetAdminPassword(task concurrency.Task) (string, fail.Error)                                                     // AdminPassword returns the password of the cluster admin account
-	GetKeyPair(task concurrency.Task) (abstract.KeyPair, fail.Error)                                                 // KeyPair returns the key pair used in the cluster
-	GetNetworkConfig(task concurrency.Task) (*propertiesv3.ClusterNetwork, fail.Error)                               // NetworkConfig returns network configuration of the cluster
-	GetState(task concurrency.Task) (clusterstate.Enum, fail.Error)                                                  // returns the current state of the cluster
-	Start(task concurrency.Task) fail.Error                                                                          // starts the cluster
-	Stop(task concurrency.Task) fail.Error                                                                           // stops the cluster
-	AddNode(task concurrency.Task, def abstract.HostSizingRequirements) (Host, fail.Error)                           // adds a node
-	AddNodes(task concurrency.Task, count uint, def abstract.HostSizingRequirements) ([]Host, fail.Error)            // adds several nodes
-	DeleteLastNode(task concurrency.Task) (*propertiesv3.ClusterNode, fail.Error)                                    // deletes the last added node and returns its name
-	DeleteSpecificNode(task concurrency.Task, hostID string, selectedMasterID string) fail.Error                     // deletes a node identified by its ID
-	ListMasters(task concurrency.Task) (IndexedListOfClusterNodes, fail.Error)                                       // lists the node instances corresponding to masters (if there is such masters in the flavor...)
-	ListMasterNames(task concurrency.Task) (data.IndexedListOfStrings, fail.Error)                                   // lists the names of the master nodes in the Cluster
-	ListMasterIDs(task concurrency.Task) (data.IndexedListOfStrings, fail.Error)                                     // lists the IDs of masters (if there is such masters in the flavor...)
-	ListMasterIPs(task concurrency.Task) (data.IndexedListOfStrings, fail.Error)                                     // lists the IPs of masters (if there is such masters in the flavor...)
-	FindAvailableMaster(task concurrency.Task) (Host, fail.Error)                                                    // returns ID of the first master available to execute order
-	ListNodes(task concurrency.Task) (IndexedListOfClusterNodes, fail.Error)                                         // lists node instances corresponding to the nodes in the cluster
-	ListNodeNames(task concurrency.Task) (data.IndexedListOfStrings, fail.Error)                                     // lists the names of the nodes in the Cluster
-	ListNodeIDs(task concurrency.Task) (data.IndexedListOfStrings, fail.Error)                                       // lists the IDs of the nodes in the cluster
-	ListNodeIPs(task concurrency.Task) (data.IndexedListOfStrings, fail.Error)                                       // lists the IPs of the nodes in the cluster
-	FindAvailableNode(task concurrency.Task) (Host, fail.Error)                                                      // returns node instance of the first node available to execute order
-	LookupNode(task concurrency.Task, ref string) (bool, fail.Error)                                                 // tells if the ID of the host passed as parameter is a node
-	CountNodes(task concurrency.Task) (uint, fail.Error)                                                             // counts the nodes of the cluster
-	CheckFeature(task concurrency.Task, name string, vars data.Map, settings FeatureSettings) (Results, fail.Error)  // checks feature on cluster
-	AddFeature(task concurrency.Task, name string, vars data.Map, settings FeatureSettings) (Results, fail.Error)    // adds feature on cluster
-	RemoveFeature(task concurrency.Task, name string, vars data.Map, settings FeatureSettings) (Results, fail.Error) // removes feature from cluster
-	Shrink(task concurrency.Task, count uint) ([]*propertiesv3.ClusterNode, fail.Error)                              // reduce the size of the cluster of 'count' nodes (the last created)
-	ListInstalledFeatures(task concurrency.Task) ([]Feature, fail.Error)                                             // returns the list of installed features
+	GetFlavor(task concurrency.Task) (clusterflavor.Enum, fail.Error)                                                  // Flavor returns the flavor of the cluster
+	GetComplexity(task concurrency.Task) (clustercomplexity.Enum, fail.Error)                                          // Complexity returns the complexity of the cluster
+	GetAdminPassword(task concurrency.Task) (string, fail.Error)                                                       // AdminPassword returns the password of the cluster admin account
+	GetKeyPair(task concurrency.Task) (abstract.KeyPair, fail.Error)                                                   // KeyPair returns the key pair used in the cluster
+	GetNetworkConfig(task concurrency.Task) (*propertiesv3.ClusterNetwork, fail.Error)                                 // NetworkConfig returns network configuration of the cluster
+	GetState(task concurrency.Task) (clusterstate.Enum, fail.Error)                                                    // returns the current state of the cluster
+	Start(task concurrency.Task) fail.Error                                                                            // starts the cluster
+	Stop(task concurrency.Task) fail.Error                                                                             // stops the cluster
+	AddNode(task concurrency.Task, def abstract.HostSizingRequirements) (Host, fail.Error)                             // adds a node
+	AddNodes(task concurrency.Task, pool string, count uint, def abstract.HostSizingRequirements) ([]Host, fail.Error) // adds several nodes in a node pool ("" meaning the default pool)
+	DeleteLastNode(task concurrency.Task) (*propertiesv3.ClusterNode, fail.Error)                                      // deletes the last added node and returns its name
+	DeleteSpecificNode(task concurrency.Task, hostID string, selectedMasterID string) fail.Error                       // deletes a node identified by its ID
+	ListMasters(task concurrency.Task) (IndexedListOfClusterNodes, fail.Error)                                         // lists the node instances corresponding to masters (if there is such masters in the flavor...)
+	ListMasterNames(task concurrency.Task) (data.IndexedListOfStrings, fail.Error)                                     // lists the names of the master nodes in the Cluster
+	ListMasterIDs(task concurrency.Task) (data.IndexedListOfStrings, fail.Error)                                       // lists the IDs of masters (if there is such masters in the flavor...)
+	ListMasterIPs(task concurrency.Task) (data.IndexedListOfStrings, fail.Error)                                       // lists the IPs of masters (if there is such masters in the flavor...)
+	FindAvailableMaster(task concurrency.Task) (Host, fail.Error)                                                      // returns ID of the first master available to execute order
+	ListNodes(task concurrency.Task) (IndexedListOfClusterNodes, fail.Error)                                           // lists node instances corresponding to the nodes in the cluster
+	ListNodeNames(task concurrency.Task) (data.IndexedListOfStrings, fail.Error)                                       // lists the names of the nodes in the Cluster
+	ListNodeIDs(task concurrency.Task) (data.IndexedListOfStrings, fail.Error)                                         // lists the IDs of the nodes in the cluster
+	ListNodeIPs(task concurrency.Task) (data.IndexedListOfStrings, fail.Error)                                         // lists the IPs of the nodes in the cluster
+	FindAvailableNode(task concurrency.Task) (Host, fail.Error)                                                        // returns node instance of the first node available to execute order
+	LookupNode(task concurrency.Task, ref string) (bool, fail.Error)                                                   // tells if the ID of the host passed as parameter is a node
+	CountNodes(task concurrency.Task) (uint, fail.Error)                                                               // counts the nodes of the cluster
+	CheckFeature(task concurrency.Task, name string, vars data.Map, settings FeatureSettings) (Results, fail.Error)    // checks feature on cluster
+	AddFeature(task concurrency.Task, name string, vars data.Map, settings FeatureSettings) (Results, fail.Error)      // adds feature on cluster
+	RemoveFeature(task concurrency.Task, name string, vars data.Map, settings FeatureSettings) (Results, fail.Error)   // removes feature from cluster
+	UpgradeFeature(task concurrency.Task, name string, vars data.Map, settings FeatureSettings) (Results, fail.Error)  // upgrades feature installed on cluster
+	Shrink(task concurrency.Task, pool string, count uint) ([]*propertiesv3.ClusterNode, fail.Error)                   // reduce the size of a node pool of the cluster of 'count' nodes (the last created)
+	ListInstalledFeatures(task concurrency.Task) ([]Feature, fail.Error)                                               // returns the list of installed features
+	SetAutoscaling(task concurrency.Task, policy propertiesv1.ClusterAutoscalingPolicy) fail.Error                     // records the autoscaling policy of the cluster
+	Autoscale(task concurrency.Task) fail.Error                                                                        // evaluates the autoscaling policy and adds or removes nodes accordingly
+	Upgrade(task concurrency.Task, req abstract.ClusterUpgradeRequest) fail.Error                                      // upgrades the nodes of the cluster, a few nodes at a time
 	ToProtocol(concurrency.Task) (*protocol.ClusterResponse, fail.Error)
 }
@@ -52,4 +52,10 @@ const (
 	NetworkV3 = "13"
 	// NodesV3 contains optional additional info about network of the cluster
 	NodesV3 = "14"
+	// NodePoolsV1 contains optional additional info describing the node pools of the cluster
+	NodePoolsV1 = "15"
+	// AutoscalingV1 contains optional additional info describing the autoscaling policy of the cluster and its last decisions
+	AutoscalingV1 = "16"
+	// FeatureHistoryV1 contains optional additional info describing the last actions on features of the cluster
+	FeatureHistoryV1 = "17"
 )
@@ -35,6 +35,8 @@ const (
 	Add
 	// Remove represents a remove action, to remove a feature
 	Remove
+	// Upgrade represents an upgrade action, to upgrade an installed feature to the version of the specification
+	Upgrade
 
 	// // NextEnum marks the next value (or the max, depending the use)
 	// NextEnum
@@ -42,15 +44,17 @@ const (
 
 var (
 	stringMap = map[string]Enum{
-		"check":  Check,
-		"add":    Add,
-		"remove": Remove,
+		"check":   Check,
+		"add":     Add,
+		"remove":  Remove,
+		"upgrade": Upgrade,
 	}
 
 	enumMap = map[Enum]string{
-		Check:  "Check",
-		Add:    "Add",
-		Remove: "Remove",
+		Check:   "Check",
+		Add:     "Add",
+		Remove:  "Remove",
+		Upgrade: "Upgrade",
 	}
 )
 
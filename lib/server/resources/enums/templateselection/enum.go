@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package templateselection defines an enum to represent the policy used to choose a host template among the ones satisfying sizing requirements
+package templateselection
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// Enum represents the policy used to rank host templates
+type Enum int
+
+const (
+	// DRF ranks templates by Dominant Resource Fairness (the smallest template satisfying the requirements comes first)
+	DRF Enum = iota
+	// Cheapest ranks templates by price per hour
+	Cheapest
+	// Fastest ranks templates by performance measured by the scanner
+	Fastest
+	// BestValue ranks templates by performance per price unit
+	BestValue
+)
+
+var (
+	stringMap = map[string]Enum{
+		"drf":        DRF,
+		"cheapest":   Cheapest,
+		"fastest":    Fastest,
+		"best-value": BestValue,
+	}
+
+	enumMap = map[Enum]string{
+		DRF:       "DRF",
+		Cheapest:  "CHEAPEST",
+		Fastest:   "FASTEST",
+		BestValue: "BEST-VALUE",
+	}
+)
+
+// Parse returns a Enum corresponding to the string parameter
+// If the string doesn't correspond to any Enum, returns an error (nil otherwise)
+// This function is intended to be used to parse user input; '_' is accepted in place of '-' (as in sizing strings).
+func Parse(v string) (Enum, fail.Error) {
+	var (
+		e  Enum
+		ok bool
+	)
+	lowered := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(v)), "_", "-")
+	if e, ok = stringMap[lowered]; !ok {
+		return e, fail.NotFoundError("failed to find a template selection policy matching with '%s'", v)
+	}
+	return e, nil
+}
+
+// String returns a string representation of an Enum
+func (e Enum) String() string {
+	if str, found := enumMap[e]; found {
+		return str
+	}
+	panic(fmt.Sprintf("failed to find a string matching with template selection policy '%d'!", e))
+}
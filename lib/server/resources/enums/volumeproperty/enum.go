@@ -24,4 +24,6 @@ const (
 	DescriptionV1 = "1"
 	// AttachedV1 contains additional information about hosts attaching the volume
 	AttachedV1 = "2"
+	// DescriptionV2 specifies additional info describing volume (purpose, owner, ...)
+	DescriptionV2 = "3"
 )
@@ -67,3 +67,15 @@ func Load(task concurrency.Task, svc iaas.Service, name string) (_ resources.Clu
 
 	return operations.LoadCluster(task, svc, name)
 }
+
+// Labels returns the labels of a cluster, being the labels shared by all its node pools
+func Labels(task concurrency.Task, svc iaas.Service, name string) (map[string]string, fail.Error) {
+	if task.IsNull() {
+		return nil, fail.InvalidParameterError("task", "cannot be null value")
+	}
+	if svc == nil {
+		return nil, fail.InvalidParameterError("svc", "cannot be nil")
+	}
+
+	return operations.ClusterLabels(task, svc, name)
+}
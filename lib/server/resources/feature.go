@@ -19,6 +19,7 @@ package resources
 import (
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/featuretargettype"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/installmethod"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
 	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
 	"github.com/CS-SI/SafeScale/lib/utils/data"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
@@ -36,6 +37,10 @@ type Targetable interface {
 	InstalledFeatures(concurrency.Task) []string
 	// ComplementFeatureParameters adds parameters corresponding to the target in preparation of feature installation
 	ComplementFeatureParameters(t concurrency.Task, v data.Map) fail.Error
+	// RecordFeatureHistory records in metadata an action on a feature of the target
+	RecordFeatureHistory(t concurrency.Task, entry *propertiesv1.FeatureHistoryEntry) fail.Error
+	// GetFeatureHistory returns the last actions on features of the target
+	GetFeatureHistory(t concurrency.Task) (*propertiesv1.FeatureHistory, fail.Error)
 }
 
 // Feature defines the interface of feature
@@ -44,6 +49,8 @@ type Feature interface {
 	data.Identifiable
 	data.NullValue
 
+	// GetVersion returns the version of the feature (empty string if not versioned)
+	GetVersion() string
 	// GetFilename returns the filename of the feature
 	GetFilename() string
 	// GetDisplayFilename displays the filename of display (optionally adding '[embedded]' for embedded features)
@@ -58,6 +65,8 @@ type Feature interface {
 	Add(t Targetable, v data.Map, fs FeatureSettings) (Results, fail.Error)
 	// Remove uninstalls the feature from the target
 	Remove(t Targetable, v data.Map, fs FeatureSettings) (Results, fail.Error)
+	// Upgrade upgrades the feature installed on the target in version 'fromVersion' (empty if unknown)
+	Upgrade(t Targetable, fromVersion string, v data.Map, fs FeatureSettings) (Results, fail.Error)
 }
 
 // FeatureSettings are used to tune the feature
@@ -74,4 +83,9 @@ type FeatureSettings struct {
 	AddUnconditionally bool
 	// IgnoreSuitability allows to not check if the feature is suitable for the target
 	IgnoreSuitability bool
+	// AutoExpand tells to add nodes to the cluster if needed to meet the sizing requirements (no effect for check or removal)
+	AutoExpand bool
+	// DryRun tells to render the scripts of the steps for each host without executing them; the output of each
+	// step is its rendered script
+	DryRun bool
 }
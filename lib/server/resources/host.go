@@ -38,9 +38,12 @@ type Host interface {
 	Targetable
 	data.NullValue
 
+	AddFeature(task concurrency.Task, name string, vars data.Map, settings FeatureSettings) (Results, fail.Error)                                  // adds feature on host
 	BindSecurityGroup(task concurrency.Task, sg SecurityGroup, enable SecurityGroupActivation) fail.Error                                          // Binds a security group to host
 	Browse(task concurrency.Task, callback func(*abstract.HostCore) fail.Error) fail.Error                                                         // ...
+	CheckFeature(task concurrency.Task, name string, vars data.Map, settings FeatureSettings) (Results, fail.Error)                                // checks feature on host
 	Create(task concurrency.Task, hostReq abstract.HostRequest, hostDef abstract.HostSizingRequirements) (*userdata.Content, fail.Error)           // creates a new host and its metadata
+	DeleteFeature(task concurrency.Task, name string, vars data.Map, settings FeatureSettings) (Results, fail.Error)                               // removes feature from host
 	DisableSecurityGroup(task concurrency.Task, sg SecurityGroup) fail.Error                                                                       // disables a binded security group on host
 	EnableSecurityGroup(task concurrency.Task, sg SecurityGroup) fail.Error                                                                        // enables a binded security group on host
 	ForceGetState(task concurrency.Task) (hoststate.Enum, fail.Error)                                                                              // returns the real current state of the host, with error handling
@@ -69,5 +72,6 @@ type Host interface {
 	Stop(task concurrency.Task) fail.Error                                                                                                         // stops the host
 	ToProtocol(task concurrency.Task) (*protocol.Host, fail.Error)                                                                                 // converts a host to equivalent gRPC message
 	UnbindSecurityGroup(task concurrency.Task, sg SecurityGroup) fail.Error                                                                        // Unbinds a security group from host
+	UpgradeFeature(task concurrency.Task, name string, vars data.Map, settings FeatureSettings) (Results, fail.Error)                              // upgrades feature installed on host
 	WaitSSHReady(task concurrency.Task, timeout time.Duration) (status string, err fail.Error)                                                     // Wait for remote SSH to respond
 }
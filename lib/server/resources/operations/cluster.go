@@ -30,6 +30,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/events"
 	"github.com/CS-SI/SafeScale/lib/server/iaas"
 	"github.com/CS-SI/SafeScale/lib/server/resources"
 	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
@@ -54,6 +55,7 @@ import (
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
 	netutils "github.com/CS-SI/SafeScale/lib/utils/net"
 	"github.com/CS-SI/SafeScale/lib/utils/retry"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
 	"github.com/CS-SI/SafeScale/lib/utils/serialize"
 	"github.com/CS-SI/SafeScale/lib/utils/strprocess"
 	"github.com/CS-SI/SafeScale/lib/utils/template"
@@ -474,12 +476,14 @@ func (c *cluster) Create(task concurrency.Task, req abstract.ClusterRequest) (xe
 		return xerr
 	}
 
-	if req.InitialNodeCount == 0 {
-		req.InitialNodeCount = privateNodeCount
-	}
-	if req.InitialNodeCount > 0 && req.InitialNodeCount < privateNodeCount {
-		logrus.Warnf("[cluster %s] cannot create less than required minimum of workers by the Flavor (%d requested, minimum being %d for flavor '%s')", req.Name, req.InitialNodeCount, privateNodeCount, req.Flavor.String())
-		req.InitialNodeCount = privateNodeCount
+	if len(req.NodePools) == 0 {
+		if req.InitialNodeCount == 0 {
+			req.InitialNodeCount = privateNodeCount
+		}
+		if req.InitialNodeCount > 0 && req.InitialNodeCount < privateNodeCount {
+			logrus.Warnf("[cluster %s] cannot create less than required minimum of workers by the Flavor (%d requested, minimum being %d for flavor '%s')", req.Name, req.InitialNodeCount, privateNodeCount, req.Flavor.String())
+			req.InitialNodeCount = privateNodeCount
+		}
 	}
 
 	// Define the sizing requirements for cluster hosts
@@ -488,8 +492,21 @@ func (c *cluster) Create(task concurrency.Task, req abstract.ClusterRequest) (xe
 		return xerr
 	}
 
+	// Define the node pools
+	pools, xerr := c.determineNodePools(task, req, *nodesDef, privateNodeCount)
+	if xerr != nil {
+		return xerr
+	}
+
+	// Reserves the quotas before creating anything; the hosts of the cluster are covered by the reservation
+	reservation, xerr := c.reserveQuotas(task, req, gatewaysDef, mastersDef, pools)
+	if xerr != nil {
+		return xerr
+	}
+	defer reservation.release()
+
 	// Create the Network and Subnet
-	rn, rs, xerr := c.createNetworkingResources(task, req, gatewaysDef)
+	rn, rs, xerr := c.createNetworkingResources(task, req, gatewaysDef, reservation.id)
 	if xerr != nil {
 		return xerr
 	}
@@ -516,7 +533,7 @@ func (c *cluster) Create(task concurrency.Task, req abstract.ClusterRequest) (xe
 	}()
 
 	// Creates and configures hosts
-	if xerr = c.createHostResources(task, rs, *mastersDef, *nodesDef, req.InitialNodeCount, req.KeepOnFailure); xerr != nil {
+	if xerr = c.createHostResources(task, rs, *mastersDef, pools, req.KeepOnFailure, reservation.id); xerr != nil {
 		return xerr
 	}
 
@@ -576,8 +593,13 @@ func (c *cluster) Create(task concurrency.Task, req abstract.ClusterRequest) (xe
 		return xerr
 	}
 
+	// applies the settings of the node pools to the nodes joined to the cluster
+	if xerr = c.configureNodePools(task); xerr != nil {
+		return xerr
+	}
+
 	// Sets nominal state of the new cluster in metadata
-	return c.Alter(task, func(clonable data.Clonable, props *serialize.JSONProperties) fail.Error {
+	xerr = c.Alter(task, func(clonable data.Clonable, props *serialize.JSONProperties) fail.Error {
 		return props.Alter(task, clusterproperty.StateV1, func(clonable data.Clonable) fail.Error {
 			stateV1, ok := clonable.(*propertiesv1.ClusterState)
 			if !ok {
@@ -587,6 +609,12 @@ func (c *cluster) Create(task concurrency.Task, req abstract.ClusterRequest) (xe
 			return nil
 		})
 	})
+	if xerr != nil {
+		return xerr
+	}
+
+	c.publishEvent(task, protocol.EventType_ET_CLUSTER_CREATED, clusterstate.Nominal.String(), nil)
+	return nil
 }
 
 // firstLight contains the code leading to cluster first metadata written
@@ -606,6 +634,7 @@ func (c *cluster) firstLight(task concurrency.Task, req abstract.ClusterRequest)
 	ci.Name = req.Name
 	ci.Flavor = req.Flavor
 	ci.Complexity = req.Complexity
+	ci.Owner = ownerOfTask(task)
 	if xerr := c.Carry(task, ci); xerr != nil {
 		return xerr
 	}
@@ -688,7 +717,11 @@ func (c *cluster) firstLight(task concurrency.Task, req abstract.ClusterRequest)
 		if innerErr != nil {
 			return fail.ToError(innerErr)
 		}
-		aci.AdminPassword = cladmPassword
+		// Keeps the password in the secret store if there is one, the identity containing then only the reference to it
+		aci.AdminPassword, innerXErr = secret.Protect(fmt.Sprintf("clusters/%s/admin-password", req.Name), cladmPassword)
+		if innerXErr != nil {
+			return innerXErr
+		}
 
 		// Links maker based on Flavor
 		return c.Bootstrap(task, aci.Flavor)
@@ -821,21 +854,62 @@ func (c *cluster) determineSizingRequirements(task concurrency.Task, req abstrac
 	return gatewaysDef, mastersDef, nodesDef, nil
 }
 
+// gatewayFailoverDisabled tells if the cluster requested has a single gateway
+func (c *cluster) gatewayFailoverDisabled(req abstract.ClusterRequest) bool {
+	caps := c.service.GetCapabilities()
+	if req.Complexity == clustercomplexity.Small || !caps.PrivateVirtualIP {
+		return true
+	}
+	_, ok := req.DisabledDefaultFeatures["gateway-failover"]
+	return ok
+}
+
+// reserveQuotas reserves the quotas needed by the hosts of the cluster requested, failing if they would be exceeded
+func (c *cluster) reserveQuotas(task concurrency.Task, req abstract.ClusterRequest, gatewaysDef, mastersDef *abstract.HostSizingRequirements, pools []nodePoolSizing) (*quotaReservation, fail.Error) {
+	masterCount, _, _, xerr := c.determineRequiredNodes(task)
+	if xerr != nil {
+		return nil, xerr
+	}
+	gatewayCount := uint(2)
+	if c.gatewayFailoverDisabled(req) {
+		gatewayCount = 1
+	}
+
+	svc := c.GetService()
+	requested := QuotaUsage{iaas.QuotaClusters: 1}
+	usage, xerr := sizingQuotaUsage(svc, *gatewaysDef, true, gatewayCount)
+	if xerr != nil {
+		return nil, xerr
+	}
+	requested.add(usage)
+	if usage, xerr = sizingQuotaUsage(svc, *mastersDef, false, masterCount); xerr != nil {
+		return nil, xerr
+	}
+	requested.add(usage)
+	for _, v := range pools {
+		if usage, xerr = sizingQuotaUsage(svc, v.nodeDef, false, v.count); xerr != nil {
+			return nil, xerr
+		}
+		requested.add(usage)
+	}
+
+	// The metadata of the cluster are already written, it must not be counted twice
+	reservation, xerr := reserveQuotas(task, svc, requested, req.Name)
+	if xerr != nil {
+		return nil, xerr
+	}
+	reservation.consume(QuotaUsage{iaas.QuotaClusters: 1})
+	return reservation, nil
+}
+
 // createNetworkingResources creates the network and subnet for the cluster
-func (c *cluster) createNetworkingResources(task concurrency.Task, req abstract.ClusterRequest, gatewaysDef *abstract.HostSizingRequirements) (_ resources.Network, _ resources.Subnet, xerr fail.Error) {
+func (c *cluster) createNetworkingResources(task concurrency.Task, req abstract.ClusterRequest, gatewaysDef *abstract.HostSizingRequirements, quotaReservation string) (_ resources.Network, _ resources.Subnet, xerr fail.Error) {
 	if task.Aborted() {
 		return nil, nil, fail.AbortedError(nil, "aborted")
 	}
 
 	// Determine if getGateway Failover must be set
-	caps := c.service.GetCapabilities()
-	gwFailoverDisabled := req.Complexity == clustercomplexity.Small || !caps.PrivateVirtualIP
-	for k := range req.DisabledDefaultFeatures {
-		if k == "gateway-failover" {
-			gwFailoverDisabled = true
-			break
-		}
-	}
+	gwFailoverDisabled := c.gatewayFailoverDisabled(req)
 
 	req.Name = strings.ToLower(strings.TrimSpace(req.Name))
 
@@ -892,12 +966,13 @@ func (c *cluster) createNetworkingResources(task concurrency.Task, req abstract.
 	// Creates Subnet
 	logrus.Debugf("[cluster %s] creating Subnet '%s'", req.Name, req.Name)
 	subnetReq := abstract.SubnetRequest{
-		Name:          req.Name,
-		NetworkID:     rn.GetID(),
-		CIDR:          req.CIDR,
-		HA:            !gwFailoverDisabled,
-		Image:         gatewaysDef.Image,
-		KeepOnFailure: false, // We consider subnet and its gateways as a whole; if any error occurs during the creation of the whole, do keep nothing
+		Name:             req.Name,
+		NetworkID:        rn.GetID(),
+		CIDR:             req.CIDR,
+		HA:               !gwFailoverDisabled,
+		Image:            gatewaysDef.Image,
+		KeepOnFailure:    false, // We consider subnet and its gateways as a whole; if any error occurs during the creation of the whole, do keep nothing
+		QuotaReservation: quotaReservation,
 	}
 
 	rs, xerr := NewSubnet(c.service)
@@ -1004,9 +1079,9 @@ func (c *cluster) createHostResources(
 	task concurrency.Task,
 	subnet resources.Subnet,
 	mastersDef abstract.HostSizingRequirements,
-	nodesDef abstract.HostSizingRequirements,
-	initialNodeCount uint,
+	pools []nodePoolSizing,
 	keepOnFailure bool,
+	quotaReservation string,
 ) (xerr fail.Error) {
 
 	if task.Aborted() {
@@ -1098,9 +1173,10 @@ func (c *cluster) createHostResources(
 	}
 
 	mastersTask, xerr := task.StartInSubtask(c.taskCreateMasters, taskCreateMastersParameters{
-		count:         masterCount,
-		mastersDef:    mastersDef,
-		keepOnFailure: keepOnFailure,
+		count:            masterCount,
+		mastersDef:       mastersDef,
+		keepOnFailure:    keepOnFailure,
+		quotaReservation: quotaReservation,
 	})
 	if xerr != nil {
 		return xerr
@@ -1116,10 +1192,10 @@ func (c *cluster) createHostResources(
 	}()
 
 	privateNodesTask, xerr := task.StartInSubtask(c.taskCreateNodes, taskCreateNodesParameters{
-		count:         initialNodeCount,
-		public:        false,
-		nodesDef:      nodesDef,
-		keepOnFailure: keepOnFailure,
+		pools:            pools,
+		public:           false,
+		keepOnFailure:    keepOnFailure,
+		quotaReservation: quotaReservation,
 	})
 	if xerr != nil {
 		return xerr
@@ -1281,6 +1357,9 @@ func complementSizingRequirements(req *abstract.HostSizingRequirements, def abst
 		if finalDef.MinCPUFreq == 0 && def.MinCPUFreq > 0 {
 			finalDef.MinCPUFreq = def.MinCPUFreq
 		}
+		if finalDef.Policy == "" {
+			finalDef.Policy = def.Policy
+		}
 		if finalDef.MinCores <= 0 {
 			finalDef.MinCores = 2
 		}
@@ -1466,7 +1545,7 @@ func (c cluster) GetAdminPassword(task concurrency.Task) (adminPassword string,
 	if xerr != nil {
 		return "", xerr
 	}
-	return aci.AdminPassword, nil
+	return secret.Resolve(aci.AdminPassword)
 }
 
 // GetKeyPair returns the key pair used in the cluster
@@ -1681,7 +1760,7 @@ func (c *cluster) Start(task concurrency.Task) (xerr fail.Error) {
 		return xerr
 	}
 
-	return c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+	xerr = c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
 		return props.Alter(task, clusterproperty.StateV1, func(clonable data.Clonable) fail.Error {
 			stateV1, ok := clonable.(*propertiesv1.ClusterState)
 			if !ok {
@@ -1691,6 +1770,12 @@ func (c *cluster) Start(task concurrency.Task) (xerr fail.Error) {
 			return nil
 		})
 	})
+	if xerr != nil {
+		return xerr
+	}
+
+	c.publishEvent(task, protocol.EventType_ET_CLUSTER_STATE_CHANGED, clusterstate.Nominal.String(), nil)
+	return nil
 }
 
 // Stop stops the cluster
@@ -1768,7 +1853,7 @@ func (c *cluster) Stop(task concurrency.Task) (xerr fail.Error) {
 	}
 
 	// Then stop it and mark it as STOPPED on success
-	return c.Alter(task, func(clonable data.Clonable, props *serialize.JSONProperties) fail.Error {
+	xerr = c.Alter(task, func(clonable data.Clonable, props *serialize.JSONProperties) fail.Error {
 		var (
 			nodes                         []string
 			masters                       []string
@@ -1863,6 +1948,12 @@ func (c *cluster) Stop(task concurrency.Task) (xerr fail.Error) {
 			return nil
 		})
 	})
+	if xerr != nil {
+		return xerr
+	}
+
+	c.publishEvent(task, protocol.EventType_ET_CLUSTER_STATE_CHANGED, clusterstate.Stopped.String(), nil)
+	return nil
 }
 
 // GetState returns the current state of the Cluster
@@ -1929,7 +2020,7 @@ func (c *cluster) AddNode(task concurrency.Task, def abstract.HostSizingRequirem
 	defer tracer.Exiting()
 	defer fail.OnExitLogError(&xerr, tracer.TraceMessage())
 
-	nodes, xerr := c.AddNodes(task, 1, def)
+	nodes, xerr := c.AddNodes(task, abstract.DefaultClusterNodePool, 1, def)
 	if xerr != nil {
 		return nullHost(), xerr
 	}
@@ -1937,8 +2028,8 @@ func (c *cluster) AddNode(task concurrency.Task, def abstract.HostSizingRequirem
 	return nodes[0], nil
 }
 
-// AddNodes adds several nodes
-func (c *cluster) AddNodes(task concurrency.Task, count uint, def abstract.HostSizingRequirements) (_ []resources.Host, xerr fail.Error) {
+// AddNodes adds several nodes in a node pool ("" meaning the default pool)
+func (c *cluster) AddNodes(task concurrency.Task, pool string, count uint, def abstract.HostSizingRequirements) (_ []resources.Host, xerr fail.Error) {
 	if c.IsNull() {
 		return nil, fail.InvalidInstanceError()
 	}
@@ -1949,7 +2040,11 @@ func (c *cluster) AddNodes(task concurrency.Task, count uint, def abstract.HostS
 		return nil, fail.InvalidParameterError("count", "must be an int > 0")
 	}
 
-	tracer := debug.NewTracer(task, tracing.ShouldTrace("resources.cluster"), "(%d)", count)
+	if pool == "" {
+		pool = abstract.DefaultClusterNodePool
+	}
+
+	tracer := debug.NewTracer(task, tracing.ShouldTrace("resources.cluster"), "('%s', %d)", pool, count)
 	defer tracer.Entering().Exiting()
 	defer fail.OnExitLogError(&xerr, tracer.TraceMessage())
 
@@ -1961,7 +2056,6 @@ func (c *cluster) AddNodes(task concurrency.Task, count uint, def abstract.HostS
 		return nil, fail.NotAvailableError("cluster is being removed")
 	}
 
-	var hostImage string
 	// xerr = c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
 	// 	if !props.Lookup(clusterproperty.DefaultsV2) {
 	// 		// If property.DefaultsV2 is not found but there is a property.DefaultsV1, converts it to DefaultsV2
@@ -1986,27 +2080,27 @@ func (c *cluster) AddNodes(task concurrency.Task, count uint, def abstract.HostS
 	// 	return nil, xerr
 	// }
 
-	var nodeDefaultDefinition *propertiesv1.HostSizingRequirements
-	xerr = c.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
-		return props.Inspect(task, clusterproperty.DefaultsV2, func(clonable data.Clonable) fail.Error {
-			defaultsV2, ok := clonable.(*propertiesv2.ClusterDefaults)
-			if !ok {
-				return fail.InconsistentError("'*propertiesv2.ClusterDefaults' expected, '%s' provided", reflect.TypeOf(clonable).String())
-			}
-			nodeDefaultDefinition = &defaultsV2.NodeSizing
-			hostImage = defaultsV2.Image
-			return nil
-		})
-	})
+	nodeDefaultDefinition, hostImage, xerr := c.nodePoolSizingDefinition(task, pool)
 	if xerr != nil {
 		return nil, xerr
 	}
 
-	nodeDef := complementHostDefinition(def, *nodeDefaultDefinition)
+	nodeDef := complementHostDefinition(def, nodeDefaultDefinition)
 	if nodeDef.Image == "" {
 		nodeDef.Image = hostImage
 	}
 
+	// Reserves the quotas before creating anything; the nodes are covered by the reservation
+	requested, xerr := sizingQuotaUsage(c.GetService(), nodeDef, false, count)
+	if xerr != nil {
+		return nil, xerr
+	}
+	reservation, xerr := reserveQuotas(task, c.GetService(), requested, "")
+	if xerr != nil {
+		return nil, xerr
+	}
+	defer reservation.release()
+
 	var (
 		nodeTypeStr string
 		errors      []string
@@ -2018,10 +2112,12 @@ func (c *cluster) AddNodes(task concurrency.Task, count uint, def abstract.HostS
 	var subtasks []concurrency.Task
 	for i := uint(0); i < count; i++ {
 		subtask, xerr := task.StartInSubtask(c.taskCreateNode, taskCreateNodeParameters{
-			index:         i + 1,
-			nodeDef:       nodeDef,
-			timeout:       timeout,
-			keepOnFailure: false,
+			index:            i + 1,
+			pool:             pool,
+			nodeDef:          nodeDef,
+			timeout:          timeout,
+			keepOnFailure:    false,
+			quotaReservation: reservation.id,
 		})
 		if xerr != nil {
 			return nil, xerr
@@ -2066,11 +2162,22 @@ func (c *cluster) AddNodes(task concurrency.Task, count uint, def abstract.HostS
 		return nil, xerr
 	}
 
+	// and applies the settings of the node pool
+	if xerr = c.configureNodePool(task, pool, hosts); xerr != nil {
+		return nil, xerr
+	}
+
+	names := make([]string, 0, len(hosts))
+	for _, v := range hosts {
+		names = append(names, v.GetName())
+	}
+	c.publishEvent(task, protocol.EventType_ET_CLUSTER_EXPANDED, "", map[string]string{"pool": pool, "nodes": strings.Join(names, ",")})
 	return hosts, nil
 }
 
 // complementHostDefinition complements req with default values if needed
 func complementHostDefinition(req abstract.HostSizingRequirements, def propertiesv1.HostSizingRequirements) abstract.HostSizingRequirements {
+	maxCoresRequested, maxRAMSizeRequested := req.MaxCores > 0, req.MaxRAMSize > 0.0
 	if def.MinCores > 0 && req.MinCores == 0 {
 		req.MinCores = def.MinCores
 	}
@@ -2107,6 +2214,14 @@ func complementHostDefinition(req abstract.HostSizingRequirements, def propertie
 	if req.MinDiskSize <= 0 {
 		req.MinDiskSize = 50
 	}
+	// A requested minimum above the maximum not requested leaves the maximum unbounded, instead of giving a sizing that
+	// no template can satisfy
+	if !maxCoresRequested && req.MinCores > req.MaxCores {
+		req.MaxCores = 0
+	}
+	if !maxRAMSizeRequested && req.MinRAMSize > req.MaxRAMSize {
+		req.MaxRAMSize = 0.0
+	}
 
 	return req
 }
@@ -2160,12 +2275,17 @@ func (c *cluster) DeleteLastNode(task concurrency.Task) (node *propertiesv3.Clus
 		return nil, xerr
 	}
 
+	master, ok := selectedMaster.(*host)
+	if !ok {
+		return nil, fail.InconsistentError("'*operations.host' expected, '%s' provided", reflect.TypeOf(selectedMaster).String())
+	}
+
 	rh, xerr := LoadHost(task, c.service, node.ID)
 	if xerr != nil {
 		return nil, xerr
 	}
 
-	if xerr = c.deleteNode(task, rh, selectedMaster.(*host)); xerr != nil {
+	if xerr = c.deleteNode(task, rh, master); xerr != nil {
 		return nil, xerr
 	}
 
@@ -2205,13 +2325,23 @@ func (c *cluster) DeleteSpecificNode(task concurrency.Task, hostID string, selec
 	if xerr != nil {
 		return xerr
 	}
+	master, ok := selectedMaster.(*host)
+	if !ok {
+		return fail.InconsistentError("'*operations.host' expected, '%s' provided", reflect.TypeOf(selectedMaster).String())
+	}
 
 	rh, xerr := LoadHost(task, c.service, hostID)
 	if xerr != nil {
 		return xerr
 	}
 
-	return c.deleteNode(task, rh, selectedMaster.(*host))
+	nodeName := rh.GetName()
+	if xerr = c.deleteNode(task, rh, master); xerr != nil {
+		return xerr
+	}
+
+	c.publishEvent(task, protocol.EventType_ET_CLUSTER_SHRUNK, "", map[string]string{"nodes": nodeName})
+	return nil
 }
 
 // ListMasters lists the node instances corresponding to masters (if there is such masters in the flavor...)
@@ -2867,9 +2997,12 @@ func (c *cluster) deleteNode(task concurrency.Task, host resources.Host, master
 	// defer fail.OnExitLogError(&xerr, tracer.TraceMessage())
 
 	// Identify the node to delete and remove it preventively from metadata
-	var node *propertiesv3.ClusterNode
+	var (
+		node *propertiesv3.ClusterNode
+		pool string
+	)
 	xerr = c.Alter(task, func(clonable data.Clonable, props *serialize.JSONProperties) fail.Error {
-		return props.Alter(task, clusterproperty.NodesV3, func(clonable data.Clonable) fail.Error {
+		innerXErr := props.Alter(task, clusterproperty.NodesV3, func(clonable data.Clonable) fail.Error {
 			nodesV3, ok := clonable.(*propertiesv3.ClusterNodes)
 			if !ok {
 				return fail.InconsistentError("'*propertiesv3.ClusterNodes' expected, '%s' provided", reflect.TypeOf(clonable).String())
@@ -2893,6 +3026,23 @@ func (c *cluster) deleteNode(task concurrency.Task, host resources.Host, master
 			delete(nodesV3.PrivateNodeByName, node.Name)
 			return nil
 		})
+		if innerXErr != nil {
+			return innerXErr
+		}
+
+		return props.Alter(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+			poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			if pool = poolsV1.PoolOfNode(node.NumericalID); pool != "" {
+				item := poolsV1.ByName[pool]
+				if found, indexInSlice := containsClusterNode(item.Nodes, node.NumericalID); found {
+					item.Nodes = append(item.Nodes[:indexInSlice], item.Nodes[indexInSlice+1:]...)
+				}
+			}
+			return nil
+		})
 	})
 	if xerr != nil {
 		return xerr
@@ -2902,7 +3052,7 @@ func (c *cluster) deleteNode(task concurrency.Task, host resources.Host, master
 	defer func() {
 		if xerr != nil {
 			derr := c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
-				return props.Alter(task, clusterproperty.NodesV3, func(clonable data.Clonable) fail.Error {
+				innerXErr := props.Alter(task, clusterproperty.NodesV3, func(clonable data.Clonable) fail.Error {
 					nodesV3, ok := clonable.(*propertiesv3.ClusterNodes)
 					if !ok {
 						return fail.InconsistentError("'*propertiesv3.ClusterNodes' expected, '%s' provided", reflect.TypeOf(clonable).String())
@@ -2913,6 +3063,20 @@ func (c *cluster) deleteNode(task concurrency.Task, host resources.Host, master
 					nodesV3.ByNumericalID[node.NumericalID] = node
 					return nil
 				})
+				if innerXErr != nil || pool == "" {
+					return innerXErr
+				}
+
+				return props.Alter(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+					poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+					if !ok {
+						return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+					}
+					if item, ok := poolsV1.ByName[pool]; ok {
+						item.Nodes = append(item.Nodes, node.NumericalID)
+					}
+					return nil
+				})
 			})
 			if derr != nil {
 				logrus.Errorf("failed to restore node ownership in cluster")
@@ -3179,8 +3343,21 @@ func (c *cluster) Delete(task concurrency.Task) (xerr fail.Error) {
 		logrus.Infof("Network '%s' successfully deleted.", networkName)
 	}
 
+	// --- Delete the admin password from the secret store, if it is kept there ---
+	if aci, innerXErr := c.GetIdentity(task); innerXErr == nil {
+		if derr := secret.Discard(aci.AdminPassword); derr != nil {
+			logrus.Warnf("failed to delete the admin password of Cluster '%s' from the secret store: %v", c.GetName(), derr)
+		}
+	}
+
 	// --- Delete metadata ---
-	return c.core.Delete(task)
+	clusterID, clusterName := c.GetID(), c.GetName()
+	if xerr = c.core.Delete(task); xerr != nil {
+		return xerr
+	}
+
+	events.Publish(task, events.Event{Type: protocol.EventType_ET_CLUSTER_DELETED, ResourceID: clusterID, ResourceName: clusterName})
+	return nil
 }
 
 // extractNetworkingInfo returns the ID of the network from properties, taking care of ascending compatibility
@@ -3365,7 +3542,7 @@ func (c *cluster) joinNodesFromList(task concurrency.Task, hosts []resources.Hos
 
 	// Joins to cluster is done sequentially, experience shows too many join at the same time
 	// may fail (depending of the cluster Flavor)
-	if c.makers.JoinMasterToCluster != nil {
+	if c.makers.JoinNodeToCluster != nil {
 		for _, host := range hosts {
 			if xerr := c.makers.JoinNodeToCluster(task, c, host); xerr != nil {
 				return xerr
@@ -3455,8 +3632,13 @@ func (c cluster) ToProtocol(task concurrency.Task) (_ *protocol.ClusterResponse,
 			return fail.InconsistentError("'*abstract.ClusterIdentity' expected, '%s' provided", reflect.TypeOf(clonable).String())
 		}
 		out.Identity = converters.ClusterIdentityFromAbstractToProtocol(*ci)
+		adminPassword, innerXErr := secret.Resolve(ci.AdminPassword)
+		if innerXErr != nil {
+			return innerXErr
+		}
+		out.Identity.AdminPassword = adminPassword
 
-		innerXErr := props.Inspect(task, clusterproperty.ControlPlaneV1, func(clonable data.Clonable) fail.Error {
+		innerXErr = props.Inspect(task, clusterproperty.ControlPlaneV1, func(clonable data.Clonable) fail.Error {
 			controlplaneV1, ok := clonable.(*propertiesv1.ClusterControlplane)
 			if !ok {
 				return fail.InconsistentError("'*propertiesv1.ClusterControlplane' expected, '%s' provided", reflect.TypeOf(clonable).String())
@@ -3532,7 +3714,15 @@ func (c cluster) ToProtocol(task concurrency.Task) (_ *protocol.ClusterResponse,
 
 			out.Nodes = convertClusterNodes(nodesV3.PrivateNodes)
 			out.Masters = convertClusterNodes(nodesV3.Masters)
-			return nil
+
+			return props.Inspect(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+				poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+				if !ok {
+					return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+				}
+				out.NodePools = nodePoolsToProtocol(nodesV3, poolsV1)
+				return nil
+			})
 		})
 		if innerXErr != nil {
 			return innerXErr
@@ -3550,6 +3740,18 @@ func (c cluster) ToProtocol(task concurrency.Task) (_ *protocol.ClusterResponse,
 			return innerXErr
 		}
 
+		innerXErr = props.Inspect(task, clusterproperty.AutoscalingV1, func(clonable data.Clonable) fail.Error {
+			autoscalingV1, ok := clonable.(*propertiesv1.ClusterAutoscaling)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterAutoscaling' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			out.Autoscaling = converters.ClusterAutoscalingFromPropertyToProtocol(*autoscalingV1)
+			return nil
+		})
+		if innerXErr != nil {
+			return innerXErr
+		}
+
 		return props.Inspect(task, clusterproperty.StateV1, func(clonable data.Clonable) fail.Error {
 			stateV1, ok := clonable.(*propertiesv1.ClusterState)
 			if !ok {
@@ -3565,7 +3767,8 @@ func (c cluster) ToProtocol(task concurrency.Task) (_ *protocol.ClusterResponse,
 	return out, nil
 }
 
-func (c *cluster) Shrink(task concurrency.Task, count uint) (_ []*propertiesv3.ClusterNode, xerr fail.Error) {
+// Shrink reduces the size of a node pool ("" meaning the default pool) of 'count' nodes (the last created)
+func (c *cluster) Shrink(task concurrency.Task, pool string, count uint) (_ []*propertiesv3.ClusterNode, xerr fail.Error) {
 	var emptySlice []*propertiesv3.ClusterNode
 	if c.IsNull() {
 		return emptySlice, fail.InvalidInstanceError()
@@ -3576,81 +3779,74 @@ func (c *cluster) Shrink(task concurrency.Task, count uint) (_ []*propertiesv3.C
 	if count == 0 {
 		return emptySlice, fail.InvalidParameterError("count", "cannot be 0")
 	}
+	if pool == "" {
+		pool = abstract.DefaultClusterNodePool
+	}
+
+	tracer := debug.NewTracer(task, tracing.ShouldTrace("resources.cluster"), "('%s', %d)", pool, count)
+	defer tracer.Entering().Exiting()
+	defer fail.OnExitLogError(&xerr, tracer.TraceMessage())
 
 	var state clusterstate.Enum
 	if state, xerr = c.GetState(task); xerr != nil {
-		return nil, xerr
+		return emptySlice, xerr
 	}
 	if state == clusterstate.Removed {
-		return nil, fail.NotAvailableError("cluster is being removed")
+		return emptySlice, fail.NotAvailableError("cluster is being removed")
 	}
 
-	tg, xerr := concurrency.NewTaskGroup(task)
-	if xerr != nil {
-		return nil, xerr
-	}
-
-	var (
-		removedNodes []*propertiesv3.ClusterNode
-		errors       []error
-		toRemove     []uint
-	)
-	xerr = c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
-		return props.Alter(task, clusterproperty.NodesV3, func(clonable data.Clonable) (innerXErr fail.Error) {
+	var toRemove []*propertiesv3.ClusterNode
+	xerr = c.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Inspect(task, clusterproperty.NodesV3, func(clonable data.Clonable) fail.Error {
 			nodesV3, ok := clonable.(*propertiesv3.ClusterNodes)
 			if !ok {
 				return fail.InconsistentError("'*propertiesv3.ClusterNodes' expected, '%s' provided", reflect.TypeOf(clonable).String())
 			}
+			return props.Inspect(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+				poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+				if !ok {
+					return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+				}
+				if _, ok := poolsV1.ByName[pool]; !ok && pool != abstract.DefaultClusterNodePool {
+					return fail.NotFoundError("failed to find node pool '%s' in cluster '%s'", pool, c.GetName())
+				}
 
-			length := uint(len(nodesV3.PrivateNodes))
-			if length < count {
-				return fail.InvalidRequestError("cannot shrink by %d node%s, only %d node%s available", count, strprocess.Plural(count), length, strprocess.Plural(length))
-			}
-
-			first := length - count
-			toRemove = nodesV3.PrivateNodes[first:]
-			nodesV3.PrivateNodes = nodesV3.PrivateNodes[:first-1]
-			for _, v := range toRemove {
-				if node, ok := nodesV3.ByNumericalID[v]; ok {
-					removedNodes = append(removedNodes, node)
-					delete(nodesV3.ByNumericalID, v)
-					delete(nodesV3.PrivateNodeByID, node.ID)
-					delete(nodesV3.PrivateNodeByName, node.Name)
+				list := nodesOfPool(nodesV3, poolsV1, pool)
+				length := uint(len(list))
+				if length < count {
+					return fail.InvalidRequestError("cannot shrink node pool '%s' by %d node%s, only %d node%s available", pool, count, strprocess.Plural(count), length, strprocess.Plural(length))
 				}
-			}
-			return nil
+				for _, v := range list[length-count:] {
+					if node, ok := nodesV3.ByNumericalID[v]; ok {
+						item := *node
+						toRemove = append(toRemove, &item)
+					}
+				}
+				return nil
+			})
 		})
 	})
 	if xerr != nil {
-		return emptySlice, nil
+		return emptySlice, xerr
 	}
 
-	defer func() {
-		if xerr != nil {
-			derr := c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
-				return props.Alter(task, clusterproperty.NodesV3, func(clonable data.Clonable) (innerXErr fail.Error) {
-					nodesV3, ok := clonable.(*propertiesv3.ClusterNodes)
-					if !ok {
-						return fail.InconsistentError("'*propertiesv3.ClusterNodes' expected, '%s' provided", reflect.TypeOf(clonable).String())
-					}
+	selectedMaster, xerr := c.FindAvailableMaster(task)
+	if xerr != nil {
+		return emptySlice, xerr
+	}
+	master, ok := selectedMaster.(*host)
+	if !ok {
+		return emptySlice, fail.InconsistentError("'*operations.host' expected, '%s' provided", reflect.TypeOf(selectedMaster).String())
+	}
 
-					nodesV3.PrivateNodes = append(nodesV3.PrivateNodes, toRemove...)
-					for _, v := range removedNodes {
-						nodesV3.ByNumericalID[v.NumericalID] = v
-						nodesV3.PrivateNodeByName[v.Name] = v.NumericalID
-						nodesV3.PrivateNodeByID[v.ID] = v.NumericalID
-					}
-					return nil
-				})
-			})
-			if derr != nil {
-				_ = xerr.AddConsequence(fail.Wrap(derr, "cleaning up on failure, failed to restore cluster nodes metadata"))
-			}
-		}
-	}()
+	tg, xerr := concurrency.NewTaskGroup(task)
+	if xerr != nil {
+		return emptySlice, xerr
+	}
 
-	for _, v := range removedNodes {
-		if _, xerr = tg.Start(c.taskDeleteNode, taskDeleteNodeParameters{node: v, master: nil}); xerr != nil {
+	var errors []error
+	for _, v := range toRemove {
+		if _, xerr = tg.Start(c.taskDeleteNode, taskDeleteNodeParameters{node: v, master: master}); xerr != nil {
 			errors = append(errors, xerr)
 		}
 	}
@@ -3661,5 +3857,15 @@ func (c *cluster) Shrink(task concurrency.Task, count uint) (_ []*propertiesv3.C
 		return emptySlice, fail.NewErrorList(errors)
 	}
 
-	return removedNodes, nil
+	names := make([]string, 0, len(toRemove))
+	for _, v := range toRemove {
+		names = append(names, v.Name)
+	}
+	c.publishEvent(task, protocol.EventType_ET_CLUSTER_SHRUNK, "", map[string]string{"pool": pool, "nodes": strings.Join(names, ",")})
+	return toRemove, nil
+}
+
+// publishEvent publishes an event about the cluster
+func (c *cluster) publishEvent(task concurrency.Task, t protocol.EventType, state string, details map[string]string) {
+	events.Publish(task, events.Event{Type: t, ResourceID: c.GetID(), ResourceName: c.GetName(), State: state, Details: details})
 }
@@ -0,0 +1,434 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterproperty"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterstate"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
+	propertiesv3 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v3"
+	"github.com/CS-SI/SafeScale/lib/utils/cli/enums/outputs"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/data"
+	"github.com/CS-SI/SafeScale/lib/utils/debug"
+	"github.com/CS-SI/SafeScale/lib/utils/debug/tracing"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/serialize"
+	"github.com/CS-SI/SafeScale/lib/utils/strprocess"
+	"github.com/CS-SI/SafeScale/lib/utils/temporal"
+)
+
+const (
+	autoscalingActionNone   = "none"
+	autoscalingActionExpand = "expand"
+	autoscalingActionShrink = "shrink"
+
+	defaultAutoscalingCooldown           = 5 * time.Minute
+	defaultAutoscalingScaleUpThreshold   = 0.8
+	defaultAutoscalingScaleDownThreshold = 0.2
+
+	// prints the load average of the last minute and the number of cores
+	cmdNodeLoad = "echo $(cut -d' ' -f1 /proc/loadavg) $(nproc)"
+)
+
+// autoscalingMetrics contains the measures used by the autoscaler to take a decision
+type autoscalingMetrics struct {
+	load        float64 // average load per core of the nodes of the pool; negative if unknown
+	pendingPods int     // number of pods waiting to be scheduled on the pool; negative if unknown
+}
+
+// nodeLoad contains the load per core of a node
+type nodeLoad struct {
+	node propertiesv3.ClusterNode
+	load float64
+}
+
+// SetAutoscaling validates and records the autoscaling policy of the cluster
+// If policy.Enabled is false, the autoscaling is disabled and the previous settings are kept
+func (c *cluster) SetAutoscaling(task concurrency.Task, policy propertiesv1.ClusterAutoscalingPolicy) (xerr fail.Error) {
+	if c.IsNull() {
+		return fail.InvalidInstanceError()
+	}
+	if task.IsNull() {
+		return fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+
+	tracer := debug.NewTracer(task, tracing.ShouldTrace("resources.cluster"), "(%v)", policy.Enabled).Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&xerr, tracer.TraceMessage())
+
+	if policy.Enabled {
+		if xerr = c.validateAutoscalingPolicy(task, &policy); xerr != nil {
+			return xerr
+		}
+	}
+
+	return c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Alter(task, clusterproperty.AutoscalingV1, func(clonable data.Clonable) fail.Error {
+			autoscalingV1, ok := clonable.(*propertiesv1.ClusterAutoscaling)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterAutoscaling' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			if policy.Enabled {
+				autoscalingV1.Policy = policy
+			} else {
+				autoscalingV1.Policy.Enabled = false
+			}
+			return nil
+		})
+	})
+}
+
+// validateAutoscalingPolicy checks the content of the policy and sets default values
+func (c *cluster) validateAutoscalingPolicy(task concurrency.Task, policy *propertiesv1.ClusterAutoscalingPolicy) fail.Error {
+	switch policy.Metric {
+	case "":
+		policy.Metric = propertiesv1.ClusterAutoscalingMetricLoad
+	case propertiesv1.ClusterAutoscalingMetricLoad:
+	case propertiesv1.ClusterAutoscalingMetricPods:
+		if c.makers.CountPendingPods == nil {
+			return fail.InvalidRequestError("metric '%s' is not supported by the Flavor of the cluster", policy.Metric)
+		}
+	default:
+		return fail.InvalidRequestError("invalid metric '%s': must be '%s' or '%s'", policy.Metric, propertiesv1.ClusterAutoscalingMetricLoad, propertiesv1.ClusterAutoscalingMetricPods)
+	}
+	if policy.MaxNodes == 0 {
+		return fail.InvalidRequestError("maximum number of nodes must be greater than 0")
+	}
+	if policy.MinNodes > policy.MaxNodes {
+		return fail.InvalidRequestError("minimum number of nodes (%d) cannot be greater than maximum number of nodes (%d)", policy.MinNodes, policy.MaxNodes)
+	}
+	if policy.Cooldown == 0 {
+		policy.Cooldown = defaultAutoscalingCooldown
+	}
+	if policy.ScaleUpThreshold == 0 {
+		policy.ScaleUpThreshold = defaultAutoscalingScaleUpThreshold
+	}
+	if policy.ScaleDownThreshold == 0 {
+		policy.ScaleDownThreshold = defaultAutoscalingScaleDownThreshold
+	}
+	if policy.ScaleUpThreshold < 0 || policy.ScaleDownThreshold < 0 || policy.ScaleDownThreshold >= policy.ScaleUpThreshold {
+		return fail.InvalidRequestError("scale down threshold (%.2f) must be positive and lower than scale up threshold (%.2f)", policy.ScaleDownThreshold, policy.ScaleUpThreshold)
+	}
+
+	if policy.Pool == "" {
+		policy.Pool = abstract.DefaultClusterNodePool
+	}
+	if policy.Pool == abstract.DefaultClusterNodePool {
+		return nil
+	}
+	return c.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Inspect(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+			poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			if _, ok := poolsV1.ByName[policy.Pool]; !ok {
+				return fail.NotFoundError("failed to find node pool '%s' in cluster '%s'", policy.Pool, c.GetName())
+			}
+			return nil
+		})
+	})
+}
+
+// Autoscale evaluates the autoscaling policy of the cluster and adds or removes nodes accordingly
+// Does nothing if the autoscaling is not enabled, if the cluster is not in a stable state or is being upgraded, or during
+// the cooldown following the last scaling operation.
+func (c *cluster) Autoscale(task concurrency.Task) (xerr fail.Error) {
+	if c.IsNull() {
+		return fail.InvalidInstanceError()
+	}
+	if task.IsNull() {
+		return fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+
+	tracer := debug.NewTracer(task, tracing.ShouldTrace("resources.cluster")).Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&xerr, tracer.TraceMessage())
+
+	// the nodes must not be added or removed by the autoscaler while an upgrade replaces or drains them
+	done, xerr := c.startExclusiveOperation("autoscaling")
+	if xerr != nil {
+		logrus.Debugf("[cluster %s] autoscaling skipped: %s", c.GetName(), xerr.Error())
+		return nil
+	}
+	defer done()
+
+	var (
+		policy      propertiesv1.ClusterAutoscalingPolicy
+		lastScaling time.Time
+	)
+	xerr = c.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Inspect(task, clusterproperty.AutoscalingV1, func(clonable data.Clonable) fail.Error {
+			autoscalingV1, ok := clonable.(*propertiesv1.ClusterAutoscaling)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterAutoscaling' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			policy = autoscalingV1.Policy
+			lastScaling = autoscalingV1.LastScaling
+			return nil
+		})
+	})
+	if xerr != nil {
+		return xerr
+	}
+	if !policy.Enabled {
+		return nil
+	}
+	if !lastScaling.IsZero() && time.Since(lastScaling) < policy.Cooldown {
+		logrus.Debugf("[cluster %s] autoscaling in cooldown until %s", c.GetName(), lastScaling.Add(policy.Cooldown).String())
+		return nil
+	}
+
+	state, xerr := c.GetState(task)
+	if xerr != nil {
+		return xerr
+	}
+	switch state {
+	case clusterstate.Nominal, clusterstate.Degraded, clusterstate.Created:
+	default:
+		logrus.Debugf("[cluster %s] autoscaling skipped, cluster is in state '%s'", c.GetName(), state.String())
+		return nil
+	}
+
+	nodes, xerr := c.listNodePool(task, policy.Pool)
+	if xerr != nil {
+		return xerr
+	}
+
+	metrics := autoscalingMetrics{load: -1, pendingPods: -1}
+	loads, xerr := c.collectNodeLoads(task, nodes)
+	if xerr != nil {
+		logrus.Warnf("[cluster %s] failed to collect load of node pool '%s': %s", c.GetName(), policy.Pool, xerr.Error())
+	} else if len(loads) > 0 {
+		var sum float64
+		for _, v := range loads {
+			sum += v.load
+		}
+		metrics.load = sum / float64(len(loads))
+	}
+	if policy.Metric == propertiesv1.ClusterAutoscalingMetricPods {
+		if c.makers.CountPendingPods == nil {
+			return fail.InvalidRequestError("metric '%s' is not supported by the Flavor of the cluster", policy.Metric)
+		}
+		count, xerr := c.makers.CountPendingPods(task, c, policy.Pool)
+		if xerr != nil {
+			return xerr
+		}
+		metrics.pendingPods = int(count)
+	}
+
+	decision := decideAutoscaling(policy, uint(len(nodes)), metrics)
+	switch decision.Action {
+	case autoscalingActionExpand:
+		logrus.Infof("[cluster %s] autoscaler adds %d node%s to pool '%s': %s", c.GetName(), decision.Count, strprocess.Plural(uint(decision.Count)), policy.Pool, decision.Reason)
+		_, xerr = c.AddNodes(task, policy.Pool, uint(decision.Count), abstract.HostSizingRequirements{})
+	case autoscalingActionShrink:
+		logrus.Infof("[cluster %s] autoscaler removes %d node%s from pool '%s': %s", c.GetName(), decision.Count, strprocess.Plural(uint(decision.Count)), policy.Pool, decision.Reason)
+		xerr = c.removeIdlestNodes(task, nodes, loads, uint(decision.Count))
+	}
+	if xerr != nil {
+		decision.Error = xerr.Error()
+	}
+
+	recordXErr := c.recordAutoscalingDecision(task, decision)
+	if xerr != nil {
+		return xerr
+	}
+	return recordXErr
+}
+
+// decideAutoscaling determines what the autoscaler has to do on a pool of 'count' nodes with the measures in 'metrics'
+func decideAutoscaling(policy propertiesv1.ClusterAutoscalingPolicy, count uint, metrics autoscalingMetrics) propertiesv1.ClusterAutoscalingDecision {
+	decision := propertiesv1.ClusterAutoscalingDecision{Date: time.Now(), Action: autoscalingActionNone}
+	switch {
+	case count < policy.MinNodes:
+		decision.Action = autoscalingActionExpand
+		decision.Count = int(policy.MinNodes - count)
+		decision.Reason = fmt.Sprintf("%d node%s in pool, minimum is %d", count, strprocess.Plural(count), policy.MinNodes)
+	case count > policy.MaxNodes:
+		decision.Action = autoscalingActionShrink
+		decision.Count = int(count - policy.MaxNodes)
+		decision.Reason = fmt.Sprintf("%d node%s in pool, maximum is %d", count, strprocess.Plural(count), policy.MaxNodes)
+	case policy.Metric == propertiesv1.ClusterAutoscalingMetricPods && metrics.pendingPods > 0:
+		decision.Reason = fmt.Sprintf("%d pending pod%s", metrics.pendingPods, strprocess.Plural(uint(metrics.pendingPods)))
+		if count < policy.MaxNodes {
+			decision.Action = autoscalingActionExpand
+			decision.Count = 1
+		} else {
+			decision.Reason += ", maximum number of nodes reached"
+		}
+	case policy.Metric == propertiesv1.ClusterAutoscalingMetricLoad && metrics.load > policy.ScaleUpThreshold:
+		decision.Reason = fmt.Sprintf("average load %.2f above %.2f", metrics.load, policy.ScaleUpThreshold)
+		if count < policy.MaxNodes {
+			decision.Action = autoscalingActionExpand
+			decision.Count = 1
+		} else {
+			decision.Reason += ", maximum number of nodes reached"
+		}
+	case metrics.load >= 0 && metrics.load < policy.ScaleDownThreshold && metrics.pendingPods <= 0:
+		decision.Reason = fmt.Sprintf("average load %.2f under %.2f", metrics.load, policy.ScaleDownThreshold)
+		if count > policy.MinNodes {
+			decision.Action = autoscalingActionShrink
+			decision.Count = 1
+		} else {
+			decision.Reason += ", minimum number of nodes reached"
+		}
+	case metrics.load < 0:
+		decision.Reason = "load of nodes unknown"
+	default:
+		decision.Reason = fmt.Sprintf("average load %.2f", metrics.load)
+	}
+	return decision
+}
+
+// listNodePool returns a copy of the nodes of a pool, ordered by creation
+func (c *cluster) listNodePool(task concurrency.Task, pool string) ([]propertiesv3.ClusterNode, fail.Error) {
+	var list []propertiesv3.ClusterNode
+	xerr := c.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Inspect(task, clusterproperty.NodesV3, func(clonable data.Clonable) fail.Error {
+			nodesV3, ok := clonable.(*propertiesv3.ClusterNodes)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv3.ClusterNodes' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			return props.Inspect(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+				poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+				if !ok {
+					return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+				}
+				for _, v := range nodesOfPool(nodesV3, poolsV1, pool) {
+					if node, ok := nodesV3.ByNumericalID[v]; ok {
+						list = append(list, *node)
+					}
+				}
+				return nil
+			})
+		})
+	})
+	return list, xerr
+}
+
+// collectNodeLoads collects over SSH the load per core of the nodes; the nodes not responding are ignored
+func (c *cluster) collectNodeLoads(task concurrency.Task, nodes []propertiesv3.ClusterNode) ([]nodeLoad, fail.Error) {
+	loads := make([]nodeLoad, 0, len(nodes))
+	for _, v := range nodes {
+		rh, xerr := LoadHost(task, c.GetService(), v.ID)
+		if xerr != nil {
+			logrus.Warnf("[cluster %s] failed to load node '%s': %s", c.GetName(), v.Name, xerr.Error())
+			continue
+		}
+		retcode, stdout, _, xerr := rh.Run(task, cmdNodeLoad, outputs.COLLECT, temporal.GetConnectionTimeout(), temporal.GetExecutionTimeout())
+		if xerr != nil || retcode != 0 {
+			logrus.Warnf("[cluster %s] failed to collect load of node '%s'", c.GetName(), v.Name)
+			continue
+		}
+		load, err := parseNodeLoad(stdout)
+		if err != nil {
+			logrus.Warnf("[cluster %s] failed to collect load of node '%s': %s", c.GetName(), v.Name, err.Error())
+			continue
+		}
+		loads = append(loads, nodeLoad{node: v, load: load})
+	}
+	if len(nodes) > 0 && len(loads) == 0 {
+		return nil, fail.NotAvailableError("no node responded")
+	}
+	return loads, nil
+}
+
+// parseNodeLoad converts the output of cmdNodeLoad to a load per core
+func parseNodeLoad(out string) (float64, fail.Error) {
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 0, fail.SyntaxError("unexpected output '%s'", out)
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fail.SyntaxError("invalid load average '%s'", fields[0])
+	}
+	cores, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || cores <= 0 {
+		return 0, fail.SyntaxError("invalid number of cores '%s'", fields[1])
+	}
+	return load / cores, nil
+}
+
+// removeIdlestNodes removes 'count' nodes from the cluster, starting with the least loaded ones, then the last created ones
+func (c *cluster) removeIdlestNodes(task concurrency.Task, nodes []propertiesv3.ClusterNode, loads []nodeLoad, count uint) fail.Error {
+	sorted := make([]nodeLoad, len(loads))
+	copy(sorted, loads)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].load < sorted[j].load })
+
+	candidates := make([]string, 0, len(nodes))
+	for _, v := range sorted {
+		candidates = append(candidates, v.node.ID)
+	}
+	for i := len(nodes) - 1; i >= 0; i-- {
+		found := false
+		for _, v := range loads {
+			if v.node.ID == nodes[i].ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			candidates = append(candidates, nodes[i].ID)
+		}
+	}
+	if uint(len(candidates)) < count {
+		count = uint(len(candidates))
+	}
+
+	for _, v := range candidates[:count] {
+		if xerr := c.DeleteSpecificNode(task, v, ""); xerr != nil {
+			return xerr
+		}
+	}
+	return nil
+}
+
+// recordAutoscalingDecision stores the decision in metadata; consecutive decisions to do nothing are merged to keep the history meaningful
+func (c *cluster) recordAutoscalingDecision(task concurrency.Task, decision propertiesv1.ClusterAutoscalingDecision) fail.Error {
+	return c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Alter(task, clusterproperty.AutoscalingV1, func(clonable data.Clonable) fail.Error {
+			autoscalingV1, ok := clonable.(*propertiesv1.ClusterAutoscaling)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterAutoscaling' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			if decision.Action != autoscalingActionNone {
+				autoscalingV1.LastScaling = decision.Date
+			}
+			if length := len(autoscalingV1.Decisions); length > 0 {
+				if decision.Action == autoscalingActionNone && autoscalingV1.Decisions[length-1].Action == autoscalingActionNone {
+					autoscalingV1.Decisions[length-1] = decision
+					return nil
+				}
+			}
+			autoscalingV1.AddDecision(decision)
+			return nil
+		})
+	})
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
+)
+
+func Test_decideAutoscaling(t *testing.T) {
+	load := propertiesv1.ClusterAutoscalingPolicy{
+		Enabled:            true,
+		MinNodes:           2,
+		MaxNodes:           4,
+		Metric:             propertiesv1.ClusterAutoscalingMetricLoad,
+		ScaleUpThreshold:   0.8,
+		ScaleDownThreshold: 0.2,
+	}
+	pods := load
+	pods.Metric = propertiesv1.ClusterAutoscalingMetricPods
+
+	cases := []struct {
+		name    string
+		policy  propertiesv1.ClusterAutoscalingPolicy
+		count   uint
+		metrics autoscalingMetrics
+		action  string
+		delta   int
+	}{
+		{"below minimum", load, 0, autoscalingMetrics{load: -1, pendingPods: -1}, autoscalingActionExpand, 2},
+		{"above maximum", load, 6, autoscalingMetrics{load: 0.5, pendingPods: -1}, autoscalingActionShrink, 2},
+		{"high load", load, 3, autoscalingMetrics{load: 0.9, pendingPods: -1}, autoscalingActionExpand, 1},
+		{"high load at maximum", load, 4, autoscalingMetrics{load: 0.9, pendingPods: -1}, autoscalingActionNone, 0},
+		{"low load", load, 3, autoscalingMetrics{load: 0.1, pendingPods: -1}, autoscalingActionShrink, 1},
+		{"low load at minimum", load, 2, autoscalingMetrics{load: 0.1, pendingPods: -1}, autoscalingActionNone, 0},
+		{"normal load", load, 3, autoscalingMetrics{load: 0.5, pendingPods: -1}, autoscalingActionNone, 0},
+		{"unknown load", load, 3, autoscalingMetrics{load: -1, pendingPods: -1}, autoscalingActionNone, 0},
+		{"pending pods", pods, 3, autoscalingMetrics{load: 0.1, pendingPods: 5}, autoscalingActionExpand, 1},
+		{"no pending pod and low load", pods, 3, autoscalingMetrics{load: 0.1, pendingPods: 0}, autoscalingActionShrink, 1},
+		{"no pending pod and high load", pods, 3, autoscalingMetrics{load: 0.9, pendingPods: 0}, autoscalingActionNone, 0},
+	}
+	for _, c := range cases {
+		decision := decideAutoscaling(c.policy, c.count, c.metrics)
+		require.Equal(t, c.action, decision.Action, c.name)
+		require.Equal(t, c.delta, decision.Count, c.name)
+		require.NotEmpty(t, decision.Reason, c.name)
+	}
+}
+
+func Test_parseNodeLoad(t *testing.T) {
+	load, xerr := parseNodeLoad("3.00 4\n")
+	require.Nil(t, xerr)
+	require.Equal(t, 0.75, load)
+
+	_, xerr = parseNodeLoad("3.00")
+	require.NotNil(t, xerr)
+
+	_, xerr = parseNodeLoad("3.00 0")
+	require.NotNil(t, xerr)
+}
@@ -18,6 +18,9 @@ package k8s
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -25,11 +28,17 @@ import (
 	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clustercomplexity"
 	flavors "github.com/CS-SI/SafeScale/lib/server/resources/operations/clusterflavors"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
+	"github.com/CS-SI/SafeScale/lib/utils/cli/enums/outputs"
 	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
 	"github.com/CS-SI/SafeScale/lib/utils/data"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/temporal"
 )
 
+// nodePoolLabel is the label set on every kubernetes node, containing the name of its node pool
+const nodePoolLabel = "safescale.io/node-pool"
+
 var (
 
 	// Makers initializes a control.Makers struct to construct a BOH Cluster
@@ -41,7 +50,11 @@ var (
 		DefaultImage:           defaultImage,
 		// GetGlobalSystemRequirements: flavors.GetGlobalSystemRequirements,
 		// GetNodeInstallationScript: getNodeInstallationScript,
-		ConfigureCluster: configureCluster,
+		ConfigureCluster:  configureCluster,
+		ConfigureNodePool: configureNodePool,
+		CountPendingPods:  countPendingPods,
+		DrainNode:         drainNode,
+		UncordonNode:      uncordonNode,
 	}
 )
 
@@ -103,7 +116,9 @@ func configureCluster(task concurrency.Task, c resources.Cluster) fail.Error {
 	// 	return fmt.Errorf("failed to prepare feature 'kubernetes': %s : %s", fmt.Sprintf("[cluster %s] failed to instantiate feature 'kubernetes': %v", clusterName, err), err.Error()
 	// }
 	// results, err := feat.Add(c, data.Map{}, resources.FeatureSettings{})
-	results, xerr := c.AddFeature(task, "kubernetes", data.Map{}, resources.FeatureSettings{})
+	// The flavor sizes the cluster itself (and configureCluster is also used to make new nodes join the cluster), so
+	// the sizing requirements of the feature are not checked here
+	results, xerr := c.AddFeature(task, "kubernetes", data.Map{}, resources.FeatureSettings{SkipSizingRequirements: true})
 	if xerr != nil {
 		return fail.Wrap(xerr, "[cluster %s] failed to add feature 'kubernetes'", clusterName)
 	}
@@ -116,6 +131,120 @@ func configureCluster(task concurrency.Task, c resources.Cluster) fail.Error {
 	return nil
 }
 
+// configureNodePool labels and taints the kubernetes nodes corresponding to hosts with the settings of the pool
+func configureNodePool(task concurrency.Task, c resources.Cluster, pool propertiesv1.ClusterNodePool, hosts []resources.Host) fail.Error {
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	master, xerr := c.FindAvailableMaster(task)
+	if xerr != nil {
+		return xerr
+	}
+
+	labels := make([]string, 0, len(pool.Labels)+1)
+	labels = append(labels, nodePoolLabel+"="+pool.Name)
+	for k, v := range pool.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels[1:])
+
+	clusterName := c.GetName()
+	for _, h := range hosts {
+		logrus.Debugf("[cluster %s] applying settings of node pool '%s' to node '%s'...", clusterName, pool.Name, h.GetName())
+		cmd := fmt.Sprintf("sudo -u cladm -i kubectl label node %s --overwrite %s", h.GetName(), strings.Join(labels, " "))
+		if len(pool.Taints) > 0 {
+			cmd += fmt.Sprintf(" && sudo -u cladm -i kubectl taint node %s --overwrite %s", h.GetName(), strings.Join(pool.Taints, " "))
+		}
+		if _, xerr := runOnMaster(task, master, cmd, temporal.GetExecutionTimeout()); xerr != nil {
+			return fail.Wrap(xerr, "[cluster %s] failed to apply settings of node pool '%s' to node '%s'", clusterName, pool.Name, h.GetName())
+		}
+	}
+	return nil
+}
+
+// drainNode evicts the pods of the node and makes it unschedulable
+func drainNode(task concurrency.Task, c resources.Cluster, host resources.Host, selectedMaster resources.Host) (xerr fail.Error) {
+	if selectedMaster == nil || selectedMaster.IsNull() {
+		if selectedMaster, xerr = c.FindAvailableMaster(task); xerr != nil {
+			return xerr
+		}
+	}
+
+	timeout := temporal.GetHostTimeout()
+	cmd := fmt.Sprintf("sudo -u cladm -i kubectl drain %s --ignore-daemonsets --delete-local-data --force --timeout=%ds", host.GetName(), int(timeout.Seconds()))
+	if _, xerr = runOnMaster(task, selectedMaster, cmd, timeout+temporal.GetExecutionTimeout()); xerr != nil {
+		return fail.Wrap(xerr, "[cluster %s] failed to drain node '%s'", c.GetName(), host.GetName())
+	}
+	return nil
+}
+
+// uncordonNode makes the node schedulable again and waits for it to be ready
+func uncordonNode(task concurrency.Task, c resources.Cluster, host resources.Host) fail.Error {
+	master, xerr := c.FindAvailableMaster(task)
+	if xerr != nil {
+		return xerr
+	}
+
+	timeout := temporal.GetHostTimeout()
+	cmd := fmt.Sprintf("sudo -u cladm -i kubectl uncordon %s && sudo -u cladm -i kubectl wait --for=condition=Ready node/%s --timeout=%ds", host.GetName(), host.GetName(), int(timeout.Seconds()))
+	if _, xerr = runOnMaster(task, master, cmd, timeout+temporal.GetExecutionTimeout()); xerr != nil {
+		return fail.Wrap(xerr, "[cluster %s] failed to wait for node '%s' to be ready", c.GetName(), host.GetName())
+	}
+	return nil
+}
+
+// runOnMaster executes a command on a master and returns its stdout; a command ending with a retcode other than 0 gives a *fail.ErrExecution
+func runOnMaster(task concurrency.Task, master resources.Host, cmd string, timeout time.Duration) (string, fail.Error) {
+	retcode, stdout, stderr, xerr := master.Run(task, cmd, outputs.COLLECT, temporal.GetConnectionTimeout(), timeout)
+	if xerr != nil {
+		return "", xerr
+	}
+	if retcode != 0 {
+		output := stdout
+		if output != "" && stderr != "" {
+			output += "\n" + stderr
+		} else if stderr != "" {
+			output = stderr
+		}
+		return "", fail.ExecutionError(nil, "command failed (retcode=%d): %s", retcode, output)
+	}
+	return stdout, nil
+}
+
+// countPendingPods returns the number of pods that cannot be scheduled, and would be scheduled on the node pool if it had more room
+// Pods selecting explicitly another pool with nodeSelector are not counted.
+func countPendingPods(task concurrency.Task, c resources.Cluster, pool string) (uint, fail.Error) {
+	master, xerr := c.FindAvailableMaster(task)
+	if xerr != nil {
+		return 0, xerr
+	}
+
+	cmd := fmt.Sprintf("sudo -u cladm -i kubectl get pods --all-namespaces --field-selector=status.phase=Pending -o go-template='{{range .items}}pod={{with .spec.nodeSelector}}{{index . \"%s\"}}{{end}}{{\"\\n\"}}{{end}}'", nodePoolLabel)
+	stdout, xerr := runOnMaster(task, master, cmd, temporal.GetExecutionTimeout())
+	if xerr != nil {
+		return 0, fail.Wrap(xerr, "[cluster %s] failed to list pending pods", c.GetName())
+	}
+
+	var count uint
+	for _, line := range strings.Split(stdout, "\n") {
+		// each pending pod gives a line 'pod=<pool selected by the pod>', the pool being empty or "<no value>" if the pod does not select a pool
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "pod=") {
+			continue
+		}
+		switch selected := strings.TrimPrefix(line, "pod="); selected {
+		case pool:
+			count++
+		case "", "<no value>":
+			if pool == abstract.DefaultClusterNodePool {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
 //
 // // VPL: eventually this part will be removed (some things have to be included in node_install_requirements
 // func getNodeInstallationScript(task concurrency.Task, _ resources.Cluster, nodeType clusternodetype.Enum) (string, data.Map) {
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8s
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources"
+	"github.com/CS-SI/SafeScale/lib/utils/cli/enums/outputs"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// fakeHost is a resources.Host recording the commands executed on it
+type fakeHost struct {
+	resources.Host
+	name     string
+	retcode  int
+	commands []string
+}
+
+func (h *fakeHost) GetName() string {
+	return h.name
+}
+
+func (h *fakeHost) IsNull() bool {
+	return h == nil
+}
+
+func (h *fakeHost) Run(_ concurrency.Task, cmd string, _ outputs.Enum, _, _ time.Duration) (int, string, string, fail.Error) {
+	h.commands = append(h.commands, cmd)
+	return h.retcode, "", "error", nil
+}
+
+type fakeCluster struct {
+	resources.Cluster
+	master *fakeHost
+}
+
+func (c *fakeCluster) GetName() string {
+	return "mycluster"
+}
+
+func (c *fakeCluster) FindAvailableMaster(concurrency.Task) (resources.Host, fail.Error) {
+	return c.master, nil
+}
+
+func TestMakersDoNotChangeNodeMembership(t *testing.T) {
+	// the expansion and the deletion of nodes rely on ConfigureCluster only, as before the upgrade of nodes
+	require.Nil(t, Makers.JoinNodeToCluster)
+	require.Nil(t, Makers.LeaveNodeFromCluster)
+	require.Nil(t, Makers.UnconfigureNode)
+	require.NotNil(t, Makers.DrainNode)
+	require.NotNil(t, Makers.UncordonNode)
+}
+
+func TestDrainNode(t *testing.T) {
+	task, xerr := concurrency.NewTask()
+	require.Nil(t, xerr)
+
+	master := &fakeHost{name: "master-1"}
+	c := &fakeCluster{master: master}
+	node := &fakeHost{name: "node-1"}
+
+	// without selected master, an available master is used
+	xerr = drainNode(task, c, node, nil)
+	require.Nil(t, xerr)
+	require.Len(t, master.commands, 1)
+	require.True(t, strings.Contains(master.commands[0], "kubectl drain node-1 --ignore-daemonsets"), master.commands[0])
+	require.Empty(t, node.commands)
+
+	selected := &fakeHost{name: "master-2", retcode: 1}
+	xerr = drainNode(task, c, node, selected)
+	require.NotNil(t, xerr)
+	require.IsType(t, &fail.ErrExecution{}, fail.RootCause(xerr))
+	require.Len(t, selected.commands, 1)
+	require.Len(t, master.commands, 1)
+}
+
+func TestUncordonNode(t *testing.T) {
+	task, xerr := concurrency.NewTask()
+	require.Nil(t, xerr)
+
+	master := &fakeHost{name: "master-1"}
+	c := &fakeCluster{master: master}
+	xerr = uncordonNode(task, c, &fakeHost{name: "node-1"})
+	require.Nil(t, xerr)
+	require.Len(t, master.commands, 1)
+	require.True(t, strings.Contains(master.commands[0], "kubectl uncordon node-1 && "), master.commands[0])
+	require.True(t, strings.Contains(master.commands[0], "kubectl wait --for=condition=Ready node/node-1"), master.commands[0])
+}
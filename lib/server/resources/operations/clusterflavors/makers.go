@@ -25,8 +25,10 @@ import (
 	"github.com/CS-SI/SafeScale/lib/server/resources"
 	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterstate"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
 	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
 	"github.com/CS-SI/SafeScale/lib/utils/template"
 )
 
@@ -57,6 +59,10 @@ type Makers struct {
 	JoinNodeToCluster      func(task concurrency.Task, c resources.Cluster, host resources.Host) fail.Error
 	LeaveMasterFromCluster func(task concurrency.Task, c resources.Cluster, host resources.Host) fail.Error
 	LeaveNodeFromCluster   func(task concurrency.Task, c resources.Cluster, host resources.Host, selectedMaster resources.Host) fail.Error
+	ConfigureNodePool      func(task concurrency.Task, c resources.Cluster, pool propertiesv1.ClusterNodePool, hosts []resources.Host) fail.Error // applies settings of a node pool (labels, taints, ...) to nodes joined to the cluster
+	CountPendingPods       func(task concurrency.Task, c resources.Cluster, pool string) (uint, fail.Error)                                       // returns the number of pods waiting for resources to be scheduled on the node pool
+	DrainNode              func(task concurrency.Task, c resources.Cluster, host resources.Host, selectedMaster resources.Host) fail.Error        // evicts the workload of a node and makes it unschedulable before a maintenance (upgrade)
+	UncordonNode           func(task concurrency.Task, c resources.Cluster, host resources.Host) fail.Error                                       // makes a node drained by DrainNode schedulable again and waits for it to be ready
 	GetState               func(task concurrency.Task, c resources.Cluster) (clusterstate.Enum, fail.Error)
 }
 
@@ -103,11 +109,15 @@ func GetGlobalSystemRequirements(task concurrency.Task, c resources.Cluster) (st
 	if err != nil {
 		return "", fail.Wrap(err, "error parsing script template")
 	}
+	adminPassword, xerr := secret.Resolve(identity.AdminPassword)
+	if xerr != nil {
+		return "", xerr
+	}
 	dataBuffer := bytes.NewBufferString("")
 	err = tmplPrepared.Execute(dataBuffer, map[string]interface{}{
 		"IPRanges":             netCfg.CIDR,
 		"ClusterAdminUsername": "cladm",
-		"ClusterAdminPassword": identity.AdminPassword,
+		"ClusterAdminPassword": adminPassword,
 		"SSHPublicKey":         identity.Keypair.PublicKey,
 		"SSHPrivateKey":        identity.Keypair.PrivateKey,
 	})
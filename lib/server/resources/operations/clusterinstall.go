@@ -47,6 +47,7 @@ import (
 	"github.com/CS-SI/SafeScale/lib/utils/debug"
 	"github.com/CS-SI/SafeScale/lib/utils/debug/tracing"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
 	"github.com/CS-SI/SafeScale/lib/utils/serialize"
 	"github.com/CS-SI/SafeScale/lib/utils/strprocess"
 	"github.com/CS-SI/SafeScale/lib/utils/temporal"
@@ -118,6 +119,56 @@ func (c *cluster) InstalledFeatures(task concurrency.Task) []string {
 	return list
 }
 
+// RecordFeatureHistory records in metadata an action on a feature of the cluster
+func (c *cluster) RecordFeatureHistory(task concurrency.Task, entry *propertiesv1.FeatureHistoryEntry) fail.Error {
+	if c == nil {
+		return fail.InvalidInstanceError()
+	}
+	if task.IsNull() {
+		return fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+	if entry == nil {
+		return fail.InvalidParameterError("entry", "cannot be nil")
+	}
+
+	return c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Alter(task, clusterproperty.FeatureHistoryV1, func(clonable data.Clonable) fail.Error {
+			historyV1, ok := clonable.(*propertiesv1.FeatureHistory)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.FeatureHistory' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			historyV1.AddEntry(entry)
+			return nil
+		})
+	})
+}
+
+// GetFeatureHistory returns the last actions on features of the cluster
+func (c *cluster) GetFeatureHistory(task concurrency.Task) (*propertiesv1.FeatureHistory, fail.Error) {
+	if c == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if task.IsNull() {
+		return nil, fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+
+	var history *propertiesv1.FeatureHistory
+	xerr := c.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Inspect(task, clusterproperty.FeatureHistoryV1, func(clonable data.Clonable) fail.Error {
+			historyV1, ok := clonable.(*propertiesv1.FeatureHistory)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.FeatureHistory' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			history = historyV1.Clone().(*propertiesv1.FeatureHistory)
+			return nil
+		})
+	})
+	if xerr != nil {
+		return nil, xerr
+	}
+	return history, nil
+}
+
 // FIXME: include the cluster part of setImplicitParameters() from feature
 // ComplementFeatureParameters configures parameters that are implicitely defined, based on target
 func (c *cluster) ComplementFeatureParameters(task concurrency.Task, v data.Map) fail.Error {
@@ -245,7 +296,31 @@ func (c *cluster) AddFeature(task concurrency.Task, name string, vars data.Map,
 	if xerr != nil {
 		return nil, xerr
 	}
-	return feat.Add(c, vars, settings)
+	results, xerr := feat.Add(c, vars, settings)
+	if xerr != nil || !results.Successful() || settings.DryRun {
+		return results, xerr
+	}
+
+	// updates ClusterFeatures property of the cluster
+	requires, xerr := feat.GetRequirements()
+	if xerr != nil {
+		return results, xerr
+	}
+	xerr = c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Alter(task, clusterproperty.FeaturesV1, func(clonable data.Clonable) fail.Error {
+			featuresV1, ok := clonable.(*propertiesv1.ClusterFeatures)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterFeatures' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			featuresV1.Installed[feat.GetName()] = &propertiesv1.ClusterInstalledFeature{
+				Requires: requires,
+				Version:  feat.GetVersion(),
+			}
+			delete(featuresV1.Disabled, feat.GetName())
+			return nil
+		})
+	})
+	return results, xerr
 }
 
 // CheckFeature tells if a feature is installed on the cluster
@@ -268,6 +343,68 @@ func (c *cluster) CheckFeature(task concurrency.Task, name string, vars data.Map
 	return feat.Check(c, vars, settings)
 }
 
+// UpgradeFeature upgrades a feature installed on the cluster
+func (c *cluster) UpgradeFeature(task concurrency.Task, name string, vars data.Map, settings resources.FeatureSettings) (resources.Results, fail.Error) {
+	if c == nil {
+		return nil, fail.InvalidInstanceError()
+	}
+	if task.IsNull() {
+		return nil, fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+	if name == "" {
+		return nil, fail.InvalidParameterError("name", "cannot be empty string")
+	}
+
+	featureName, _ := splitFeatureReference(name)
+	var (
+		installed string
+		recorded  bool
+	)
+	xerr := c.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Inspect(task, clusterproperty.FeaturesV1, func(clonable data.Clonable) fail.Error {
+			featuresV1, ok := clonable.(*propertiesv1.ClusterFeatures)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterFeatures' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			if item, ok := featuresV1.Installed[featureName]; ok {
+				installed, recorded = item.Version, true
+			}
+			return nil
+		})
+	})
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	feat, results, xerr := upgradeFeatureOnTarget(task, c, name, installed, recorded, vars, settings)
+	if xerr != nil || feat == nil || !results.Successful() || settings.DryRun {
+		return results, xerr
+	}
+
+	// updates ClusterFeatures property of the cluster
+	requires, xerr := feat.GetRequirements()
+	if xerr != nil {
+		return results, xerr
+	}
+	xerr = c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Alter(task, clusterproperty.FeaturesV1, func(clonable data.Clonable) fail.Error {
+			featuresV1, ok := clonable.(*propertiesv1.ClusterFeatures)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterFeatures' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			item, ok := featuresV1.Installed[feat.GetName()]
+			if !ok {
+				item = &propertiesv1.ClusterInstalledFeature{}
+				featuresV1.Installed[feat.GetName()] = item
+			}
+			item.Requires = requires
+			item.Version = feat.GetVersion()
+			return nil
+		})
+	})
+	return results, xerr
+}
+
 // RemoveFeature uninstalls a feature from the cluster
 func (c *cluster) RemoveFeature(task concurrency.Task, name string, vars data.Map, settings resources.FeatureSettings) (resources.Results, fail.Error) {
 	if c == nil {
@@ -285,7 +422,23 @@ func (c *cluster) RemoveFeature(task concurrency.Task, name string, vars data.Ma
 		return nil, xerr
 	}
 
-	return feat.Remove(c, vars, settings)
+	results, xerr := feat.Remove(c, vars, settings)
+	if xerr != nil || !results.Successful() || settings.DryRun {
+		return results, xerr
+	}
+
+	// updates ClusterFeatures property of the cluster
+	xerr = c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Alter(task, clusterproperty.FeaturesV1, func(clonable data.Clonable) fail.Error {
+			featuresV1, ok := clonable.(*propertiesv1.ClusterFeatures)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterFeatures' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			delete(featuresV1.Installed, feat.GetName())
+			return nil
+		})
+	})
+	return results, xerr
 }
 
 // ExecuteScript executes the script template with the parameters on target IPAddress
@@ -485,7 +638,9 @@ func (c *cluster) installNodeRequirements(task concurrency.Task, nodeType cluste
 	}
 	params["MasterIPs"] = list
 	params["ClusterAdminUsername"] = "cladm"
-	params["ClusterAdminPassword"] = identity.AdminPassword
+	if params["ClusterAdminPassword"], xerr = secret.Resolve(identity.AdminPassword); xerr != nil {
+		return xerr
+	}
 	params["DefaultRouteIP"] = netCfg.DefaultRouteIP
 	params["EndpointIP"] = netCfg.EndpointIP
 	params["IPRanges"] = netCfg.CIDR
@@ -579,7 +734,10 @@ func (c *cluster) installRemoteDesktop(task concurrency.Task) (xerr fail.Error)
 	if !disabled {
 		logrus.Debugf("[cluster %s] adding feature 'remotedesktop'", clusterName)
 
-		adminPassword := identity.AdminPassword
+		adminPassword, xerr := secret.Resolve(identity.AdminPassword)
+		if xerr != nil {
+			return xerr
+		}
 
 		feat, xerr := NewEmbeddedFeature(task, "remotedesktop")
 		if xerr != nil {
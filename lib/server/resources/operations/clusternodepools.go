@@ -0,0 +1,387 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/iaas"
+	"github.com/CS-SI/SafeScale/lib/server/resources"
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterproperty"
+	"github.com/CS-SI/SafeScale/lib/server/resources/operations/converters"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
+	propertiesv2 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v2"
+	propertiesv3 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v3"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/data"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/serialize"
+	"github.com/CS-SI/SafeScale/lib/utils/strprocess"
+)
+
+var (
+	nodePoolNameRegexp   = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	nodeLabelKeyRegexp   = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	nodeLabelValueRegexp = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+	nodeTaintRegexp      = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?(=([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?)?:(NoSchedule|PreferNoSchedule|NoExecute)$`)
+)
+
+// nodePoolSizing contains what is needed to create the nodes of a pool
+type nodePoolSizing struct {
+	name    string
+	count   uint
+	nodeDef abstract.HostSizingRequirements
+}
+
+// validateNodePoolRequest checks the name, the labels and the taints of a node pool request
+func validateNodePoolRequest(req abstract.ClusterNodePoolRequest) fail.Error {
+	if !nodePoolNameRegexp.MatchString(req.Name) {
+		return fail.InvalidRequestError("invalid node pool name '%s': must contain only lowercase alphanumeric characters or '-', and start and end with an alphanumeric character", req.Name)
+	}
+	for k, v := range req.Labels {
+		if !nodeLabelKeyRegexp.MatchString(k) {
+			return fail.InvalidRequestError("invalid label key '%s' in node pool '%s'", k, req.Name)
+		}
+		if !nodeLabelValueRegexp.MatchString(v) {
+			return fail.InvalidRequestError("invalid value '%s' of label '%s' in node pool '%s'", v, k, req.Name)
+		}
+	}
+	for _, v := range req.Taints {
+		if !nodeTaintRegexp.MatchString(v) {
+			return fail.InvalidRequestError("invalid taint '%s' in node pool '%s': must be in the form 'key[=value]:effect', effect being NoSchedule, PreferNoSchedule or NoExecute", v, req.Name)
+		}
+	}
+	return nil
+}
+
+// nodePoolHostnameCore returns the core of the hostname of the nodes of a pool
+func nodePoolHostnameCore(pool string) string {
+	if pool == "" || pool == abstract.DefaultClusterNodePool {
+		return "node"
+	}
+	return pool + "-node"
+}
+
+// nodesOfPool returns the numerical IDs of the nodes of a pool, in order of creation
+// The nodes of the default pool are the private nodes not belonging to another pool
+func nodesOfPool(nodesV3 *propertiesv3.ClusterNodes, poolsV1 *propertiesv1.ClusterNodePools, pool string) []uint {
+	if pool != "" && pool != abstract.DefaultClusterNodePool {
+		if item, ok := poolsV1.ByName[pool]; ok {
+			return item.Nodes
+		}
+		return []uint{}
+	}
+
+	list := make([]uint, 0, len(nodesV3.PrivateNodes))
+	for _, v := range nodesV3.PrivateNodes {
+		if poolsV1.PoolOfNode(v) == "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// determineNodePools defines the node pools of the cluster from the request and records them in metadata
+// 'minimum' is the count of nodes required by the Flavor; if the pools do not provide enough nodes, the default pool is enlarged
+func (c *cluster) determineNodePools(task concurrency.Task, req abstract.ClusterRequest, nodesDef abstract.HostSizingRequirements, minimum uint) ([]nodePoolSizing, fail.Error) {
+	var (
+		total     uint
+		pools     []nodePoolSizing
+		named     = map[string]abstract.ClusterNodePoolRequest{}
+		svc       = c.GetService()
+		hasCustom bool
+	)
+	defaultPool := nodePoolSizing{name: abstract.DefaultClusterNodePool, count: req.InitialNodeCount, nodeDef: nodesDef}
+	defaultReq := abstract.ClusterNodePoolRequest{Name: abstract.DefaultClusterNodePool}
+
+	for _, v := range req.NodePools {
+		if xerr := validateNodePoolRequest(v); xerr != nil {
+			return nil, xerr
+		}
+		if _, ok := named[v.Name]; ok {
+			return nil, fail.InvalidRequestError("node pool '%s' is defined several times", v.Name)
+		}
+		named[v.Name] = v
+
+		def := complementSizingRequirements(&v.Sizing, nodesDef)
+		if def.Image == "" {
+			def.Image = nodesDef.Image
+		}
+		if def.Template == "" {
+			if def.Equals(nodesDef) {
+				def.Template = nodesDef.Template
+			} else {
+				tmpl, xerr := svc.FindTemplateBySizing(*def)
+				if xerr != nil {
+					return nil, fail.Wrap(xerr, "failed to find a template for node pool '%s'", v.Name)
+				}
+				def.Template = tmpl.Name
+			}
+		}
+
+		if v.Name == abstract.DefaultClusterNodePool {
+			defaultPool = nodePoolSizing{name: v.Name, count: v.Count, nodeDef: *def}
+			defaultReq = v
+			continue
+		}
+		hasCustom = true
+		pools = append(pools, nodePoolSizing{name: v.Name, count: v.Count, nodeDef: *def})
+		total += v.Count
+	}
+	total += defaultPool.count
+	if total < minimum {
+		if hasCustom {
+			logrus.Warnf("[cluster %s] node pools contain less than required minimum of workers by the Flavor (%d requested, minimum being %d for flavor '%s'); adding %d node%s to pool '%s'",
+				req.Name, total, minimum, req.Flavor.String(), minimum-total, strprocess.Plural(minimum-total), abstract.DefaultClusterNodePool)
+		}
+		defaultPool.count += minimum - total
+	}
+	pools = append([]nodePoolSizing{defaultPool}, pools...)
+
+	// Records the node pools in metadata
+	xerr := c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Alter(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+			poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			for _, v := range pools {
+				item := propertiesv1.NewClusterNodePool()
+				item.Name = v.name
+				item.Sizing = *converters.HostSizingRequirementsFromAbstractToPropertyV1(v.nodeDef)
+				item.Image = v.nodeDef.Image
+				item.Count = v.count
+				pr := named[v.name]
+				if v.name == abstract.DefaultClusterNodePool {
+					pr = defaultReq
+				}
+				for k, l := range pr.Labels {
+					item.Labels[k] = l
+				}
+				item.Taints = append(item.Taints, pr.Taints...)
+				poolsV1.ByName[v.name] = item
+			}
+			return nil
+		})
+	})
+	if xerr != nil {
+		return nil, xerr
+	}
+	return pools, nil
+}
+
+// nodePoolSizingDefinition returns the sizing and the image to use to create new nodes in a pool
+func (c *cluster) nodePoolSizingDefinition(task concurrency.Task, pool string) (sizing propertiesv1.HostSizingRequirements, image string, xerr fail.Error) {
+	xerr = c.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		innerXErr := props.Inspect(task, clusterproperty.DefaultsV2, func(clonable data.Clonable) fail.Error {
+			defaultsV2, ok := clonable.(*propertiesv2.ClusterDefaults)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv2.ClusterDefaults' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			sizing = defaultsV2.NodeSizing
+			image = defaultsV2.Image
+			return nil
+		})
+		if innerXErr != nil {
+			return innerXErr
+		}
+
+		return props.Inspect(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+			poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			item, ok := poolsV1.ByName[pool]
+			if !ok {
+				if pool == abstract.DefaultClusterNodePool {
+					return nil
+				}
+				return fail.NotFoundError("failed to find node pool '%s' in cluster '%s'", pool, c.GetName())
+			}
+			sizing = item.Sizing
+			if item.Image != "" {
+				image = item.Image
+			}
+			return nil
+		})
+	})
+	return sizing, image, xerr
+}
+
+// configureNodePool applies the settings of a pool to some of its nodes, if the Flavor supports it
+func (c *cluster) configureNodePool(task concurrency.Task, pool string, hosts []resources.Host) fail.Error {
+	if c.makers.ConfigureNodePool == nil || len(hosts) == 0 {
+		return nil
+	}
+
+	settings := propertiesv1.NewClusterNodePool()
+	settings.Name = pool
+	xerr := c.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Inspect(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+			poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			if item, ok := poolsV1.ByName[pool]; ok {
+				_ = settings.Replace(item)
+			}
+			return nil
+		})
+	})
+	if xerr != nil {
+		return xerr
+	}
+
+	return c.makers.ConfigureNodePool(task, c, *settings, hosts)
+}
+
+// configureNodePools applies the settings of every pool to its nodes, if the Flavor supports it
+func (c *cluster) configureNodePools(task concurrency.Task) fail.Error {
+	if c.makers.ConfigureNodePool == nil {
+		return nil
+	}
+
+	byPool := map[string][]string{}
+	xerr := c.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Inspect(task, clusterproperty.NodesV3, func(clonable data.Clonable) fail.Error {
+			nodesV3, ok := clonable.(*propertiesv3.ClusterNodes)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv3.ClusterNodes' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			return props.Inspect(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+				poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+				if !ok {
+					return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+				}
+				for _, v := range nodesV3.PrivateNodes {
+					if node, ok := nodesV3.ByNumericalID[v]; ok {
+						pool := poolsV1.PoolOfNode(v)
+						if pool == "" {
+							pool = abstract.DefaultClusterNodePool
+						}
+						byPool[pool] = append(byPool[pool], node.ID)
+					}
+				}
+				return nil
+			})
+		})
+	})
+	if xerr != nil {
+		return xerr
+	}
+
+	for pool, ids := range byPool {
+		hosts := make([]resources.Host, 0, len(ids))
+		for _, id := range ids {
+			rh, xerr := LoadHost(task, c.GetService(), id)
+			if xerr != nil {
+				return xerr
+			}
+			hosts = append(hosts, rh)
+		}
+		if xerr = c.configureNodePool(task, pool, hosts); xerr != nil {
+			return xerr
+		}
+	}
+	return nil
+}
+
+// nodePoolsToProtocol converts the node pools of the cluster to protocol, with the names of their nodes
+func nodePoolsToProtocol(nodesV3 *propertiesv3.ClusterNodes, poolsV1 *propertiesv1.ClusterNodePools) []*protocol.ClusterNodePool {
+	names := make([]string, 0, len(poolsV1.ByName))
+	for k := range poolsV1.ByName {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	out := make([]*protocol.ClusterNodePool, 0, len(names))
+	for _, k := range names {
+		ids := nodesOfPool(nodesV3, poolsV1, k)
+		nodes := make([]string, 0, len(ids))
+		for _, v := range ids {
+			if node, ok := nodesV3.ByNumericalID[v]; ok {
+				nodes = append(nodes, node.Name)
+			}
+		}
+		out = append(out, converters.ClusterNodePoolFromPropertyToProtocol(*poolsV1.ByName[k], nodes))
+	}
+	return out
+}
+
+// ClusterLabels returns the labels of a cluster, being the labels shared by all its node pools
+func ClusterLabels(task concurrency.Task, svc iaas.Service, name string) (map[string]string, fail.Error) {
+	rc, xerr := LoadCluster(task, svc, name)
+	if xerr != nil {
+		return nil, xerr
+	}
+	var labels map[string]string
+	xerr = rc.(*cluster).inspectNodePools(task, func(nodesV3 *propertiesv3.ClusterNodes, poolsV1 *propertiesv1.ClusterNodePools) fail.Error {
+		labels = sharedNodePoolLabels(nodesV3, poolsV1)
+		return nil
+	})
+	return labels, xerr
+}
+
+// inspectNodePools calls the callback with the nodes and the node pools of the cluster
+func (c *cluster) inspectNodePools(task concurrency.Task, callback func(*propertiesv3.ClusterNodes, *propertiesv1.ClusterNodePools) fail.Error) fail.Error {
+	return c.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Inspect(task, clusterproperty.NodesV3, func(clonable data.Clonable) fail.Error {
+			nodesV3, ok := clonable.(*propertiesv3.ClusterNodes)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv3.ClusterNodes' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			return props.Inspect(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+				poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+				if !ok {
+					return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+				}
+				return callback(nodesV3, poolsV1)
+			})
+		})
+	})
+}
+
+// sharedNodePoolLabels returns the labels having the same value in all the node pools; the nodes out of the recorded
+// pools belonging to the default pool, without labels
+func sharedNodePoolLabels(nodesV3 *propertiesv3.ClusterNodes, poolsV1 *propertiesv1.ClusterNodePools) map[string]string {
+	labels := map[string]string{}
+	if len(poolsV1.ByName) == 0 || len(nodesOfPool(nodesV3, poolsV1, abstract.DefaultClusterNodePool)) > 0 {
+		return labels
+	}
+	first := true
+	for _, pool := range poolsV1.ByName {
+		if first {
+			for k, v := range pool.Labels {
+				labels[k] = v
+			}
+			first = false
+			continue
+		}
+		for k, v := range labels {
+			if value, ok := pool.Labels[k]; !ok || value != v {
+				delete(labels, k)
+			}
+		}
+	}
+	return labels
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
+	propertiesv3 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v3"
+)
+
+func Test_sharedNodePoolLabels(t *testing.T) {
+	nodesV3 := &propertiesv3.ClusterNodes{PrivateNodes: []uint{1, 2, 3}}
+	poolsV1 := &propertiesv1.ClusterNodePools{ByName: map[string]*propertiesv1.ClusterNodePool{
+		"cpu": {Name: "cpu", Labels: map[string]string{"env": "dev", "team": "a"}, Nodes: []uint{1, 2}},
+		"gpu": {Name: "gpu", Labels: map[string]string{"env": "dev", "team": "b", "accelerator": "nvidia"}, Nodes: []uint{3}},
+	}}
+	require.Equal(t, map[string]string{"env": "dev"}, sharedNodePoolLabels(nodesV3, poolsV1))
+
+	// nodes of the default pool have no label
+	nodesV3.PrivateNodes = append(nodesV3.PrivateNodes, 4)
+	require.Empty(t, sharedNodePoolLabels(nodesV3, poolsV1))
+
+	require.Empty(t, sharedNodePoolLabels(&propertiesv3.ClusterNodes{}, &propertiesv1.ClusterNodePools{}))
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusternodetype"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterproperty"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
 	propertiesv3 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v3"
 	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
 	"github.com/CS-SI/SafeScale/lib/utils/data"
@@ -155,9 +156,10 @@ func (c cluster) taskConfigureGateway(task concurrency.Task, params concurrency.
 }
 
 type taskCreateMastersParameters struct {
-	count         uint
-	mastersDef    abstract.HostSizingRequirements
-	keepOnFailure bool
+	count            uint
+	mastersDef       abstract.HostSizingRequirements
+	keepOnFailure    bool
+	quotaReservation string // ID of the reservation of quotas covering the masters
 }
 
 // taskCreateMasters creates masters
@@ -197,10 +199,11 @@ func (c cluster) taskCreateMasters(task concurrency.Task, params concurrency.Tas
 	var i uint
 	for ; i < p.count; i++ {
 		subtask, xerr := task.StartInSubtask(c.taskCreateMaster, taskCreateMasterParameters{
-			index:         i + 1,
-			masterDef:     p.mastersDef,
-			timeout:       timeout,
-			keepOnFailure: p.keepOnFailure,
+			index:            i + 1,
+			masterDef:        p.mastersDef,
+			timeout:          timeout,
+			keepOnFailure:    p.keepOnFailure,
+			quotaReservation: p.quotaReservation,
 		})
 		if xerr != nil {
 			return nil, xerr
@@ -224,10 +227,11 @@ func (c cluster) taskCreateMasters(task concurrency.Task, params concurrency.Tas
 }
 
 type taskCreateMasterParameters struct {
-	index         uint
-	masterDef     abstract.HostSizingRequirements
-	timeout       time.Duration
-	keepOnFailure bool
+	index            uint
+	masterDef        abstract.HostSizingRequirements
+	timeout          time.Duration
+	keepOnFailure    bool
+	quotaReservation string // ID of the reservation of quotas covering the master
 }
 
 // taskCreateMaster creates one master
@@ -337,6 +341,7 @@ func (c *cluster) taskCreateMaster(task concurrency.Task, params concurrency.Tas
 
 	hostReq.PublicIP = false
 	hostReq.KeepOnFailure = p.keepOnFailure
+	hostReq.QuotaReservation = p.quotaReservation
 
 	rh, xerr := NewHost(c.GetService())
 	if xerr != nil {
@@ -628,10 +633,10 @@ func (c *cluster) taskConfigureMaster(task concurrency.Task, params concurrency.
 }
 
 type taskCreateNodesParameters struct {
-	count         uint
-	public        bool
-	nodesDef      abstract.HostSizingRequirements
-	keepOnFailure bool
+	pools            []nodePoolSizing // count and sizing of the nodes to create, by pool
+	public           bool
+	keepOnFailure    bool
+	quotaReservation string // ID of the reservation of quotas covering the nodes
 }
 
 // taskCreateNodes creates nodes
@@ -650,36 +655,43 @@ func (c *cluster) taskCreateNodes(task concurrency.Task, params concurrency.Task
 		return nil, fail.InvalidParameterError("params", "must be a 'taskCreateNodesParameters'")
 	}
 
-	if p.count < 1 {
-		return nil, fail.InvalidParameterError("params.count", "cannot be an integer less than 1")
+	var count uint
+	for _, v := range p.pools {
+		count += v.count
+	}
+	if count < 1 {
+		return nil, fail.InvalidParameterError("params.pools", "cannot contain less than 1 node")
 	}
 
-	tracer := debug.NewTracer(task, tracing.ShouldTrace("resources.cluster"), "(%d, %v)", p.count, p.public).WithStopwatch().Entering()
+	tracer := debug.NewTracer(task, tracing.ShouldTrace("resources.cluster"), "(%d, %v)", count, p.public).WithStopwatch().Entering()
 	defer tracer.Exiting()
 	defer fail.OnExitLogError(&xerr, tracer.TraceMessage())
 
 	clusterName := c.GetName()
+	logrus.Debugf("[cluster %s] creating %d node%s...", clusterName, count, strprocess.Plural(count))
 
-	if p.count == 0 {
-		logrus.Debugf("[cluster %s] no nodes to create.", clusterName)
-		return nil, nil
-	}
-	logrus.Debugf("[cluster %s] creating %d node%s...", clusterName, p.count, strprocess.Plural(p.count))
+	timeout := temporal.GetContextTimeout() + time.Duration(count)*time.Minute
+	var (
+		subtasks []concurrency.Task
+		index    uint
+	)
+	for _, pool := range p.pools {
+		for i := uint(1); i <= pool.count; i++ {
+			index++
+			subtask, xerr := task.StartInSubtask(c.taskCreateNode, taskCreateNodeParameters{
+				index:            index,
+				pool:             pool.name,
+				nodeDef:          pool.nodeDef,
+				timeout:          timeout,
+				keepOnFailure:    p.keepOnFailure,
+				quotaReservation: p.quotaReservation,
+			})
+			if xerr != nil {
+				return nil, xerr
+			}
 
-	timeout := temporal.GetContextTimeout() + time.Duration(p.count)*time.Minute
-	var subtasks []concurrency.Task
-	for i := uint(1); i <= p.count; i++ {
-		subtask, xerr := task.StartInSubtask(c.taskCreateNode, taskCreateNodeParameters{
-			index:         i,
-			nodeDef:       p.nodesDef,
-			timeout:       timeout,
-			keepOnFailure: p.keepOnFailure,
-		})
-		if xerr != nil {
-			return nil, xerr
+			subtasks = append(subtasks, subtask)
 		}
-
-		subtasks = append(subtasks, subtask)
 	}
 
 	var errs []error
@@ -693,15 +705,17 @@ func (c *cluster) taskCreateNodes(task concurrency.Task, params concurrency.Task
 		return nil, fail.NewErrorList(errs)
 	}
 
-	logrus.Debugf("[cluster %s] %d node%s creation successful.", clusterName, p.count, strprocess.Plural(p.count))
+	logrus.Debugf("[cluster %s] %d node%s creation successful.", clusterName, count, strprocess.Plural(count))
 	return nil, nil
 }
 
 type taskCreateNodeParameters struct {
-	index         uint
-	nodeDef       abstract.HostSizingRequirements
-	timeout       time.Duration // Not used currently
-	keepOnFailure bool
+	index            uint
+	pool             string // name of the node pool of the node ("" for the default pool)
+	nodeDef          abstract.HostSizingRequirements
+	timeout          time.Duration // Not used currently
+	keepOnFailure    bool
+	quotaReservation string // ID of the reservation of quotas covering the node
 }
 
 // taskCreateNode creates a node in the Cluster
@@ -734,7 +748,7 @@ func (c *cluster) taskCreateNode(task concurrency.Task, params concurrency.TaskP
 	logrus.Debugf("[%s] starting Host creation...", hostLabel)
 
 	hostReq := abstract.HostRequest{}
-	hostReq.ResourceName, xerr = c.buildHostname(task, "node", clusternodetype.Node)
+	hostReq.ResourceName, xerr = c.buildHostname(task, nodePoolHostnameCore(p.pool), clusternodetype.Node)
 	if xerr != nil {
 		return nil, xerr
 	}
@@ -766,7 +780,7 @@ func (c *cluster) taskCreateNode(task concurrency.Task, params concurrency.TaskP
 	defer func() {
 		if xerr != nil && !p.keepOnFailure {
 			derr := c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
-				return props.Alter(task, clusterproperty.NodesV3, func(clonable data.Clonable) fail.Error {
+				innerXErr := props.Alter(task, clusterproperty.NodesV3, func(clonable data.Clonable) fail.Error {
 					nodesV3, ok := clonable.(*propertiesv3.ClusterNodes)
 					if !ok {
 						return fail.InconsistentError("'*propertiesv3.ClusterNodes' expected, '%s' provided", reflect.TypeOf(clonable).String())
@@ -775,6 +789,22 @@ func (c *cluster) taskCreateNode(task concurrency.Task, params concurrency.TaskP
 					delete(nodesV3.ByNumericalID, nodeIdx)
 					return nil
 				})
+				if innerXErr != nil {
+					return innerXErr
+				}
+
+				return props.Alter(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+					poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+					if !ok {
+						return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+					}
+					if pool, ok := poolsV1.ByName[p.pool]; ok {
+						if found, indexInSlice := containsClusterNode(pool.Nodes, nodeIdx); found {
+							pool.Nodes = append(pool.Nodes[:indexInSlice], pool.Nodes[indexInSlice+1:]...)
+						}
+					}
+					return nil
+				})
 			})
 			if derr != nil {
 				_ = xerr.AddConsequence(fail.Wrap(derr, "cleaning up on failure, failed to remove master from Cluster metadata"))
@@ -812,6 +842,7 @@ func (c *cluster) taskCreateNode(task concurrency.Task, params concurrency.TaskP
 
 	hostReq.PublicIP = false
 	hostReq.KeepOnFailure = p.keepOnFailure
+	hostReq.QuotaReservation = p.quotaReservation
 
 	rh, xerr := NewHost(c.GetService())
 	if xerr != nil {
@@ -831,7 +862,7 @@ func (c *cluster) taskCreateNode(task concurrency.Task, params concurrency.TaskP
 	}()
 
 	xerr = c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
-		return props.Alter(task, clusterproperty.NodesV3, func(clonable data.Clonable) (innerXErr fail.Error) {
+		innerXErr := props.Alter(task, clusterproperty.NodesV3, func(clonable data.Clonable) (innerXErr fail.Error) {
 			nodesV3, ok := clonable.(*propertiesv3.ClusterNodes)
 			if !ok {
 				return fail.InconsistentError("'*propertiesv3.ClusterNodes' expected, '%s' provided", reflect.TypeOf(clonable).String())
@@ -889,6 +920,25 @@ func (c *cluster) taskCreateNode(task concurrency.Task, params concurrency.TaskP
 			// nodesV3.PrivateNodeByID[node.ID] = node.NumericalID
 			// return nil
 		})
+		if innerXErr != nil {
+			return innerXErr
+		}
+		if p.pool == "" || p.pool == abstract.DefaultClusterNodePool {
+			return nil
+		}
+
+		return props.Alter(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+			poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			pool, ok := poolsV1.ByName[p.pool]
+			if !ok {
+				return fail.NotFoundError("failed to find node pool '%s'", p.pool)
+			}
+			pool.Nodes = append(pool.Nodes, nodeIdx)
+			return nil
+		})
 	})
 	if xerr != nil {
 		return nil, fail.Wrap(xerr, "[%s] creation failed", hostLabel)
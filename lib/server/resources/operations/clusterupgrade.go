@@ -0,0 +1,502 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources"
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterproperty"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterstate"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
+	propertiesv2 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v2"
+	propertiesv3 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v3"
+	"github.com/CS-SI/SafeScale/lib/utils/cli/enums/outputs"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/data"
+	"github.com/CS-SI/SafeScale/lib/utils/debug"
+	"github.com/CS-SI/SafeScale/lib/utils/debug/tracing"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/retry"
+	"github.com/CS-SI/SafeScale/lib/utils/serialize"
+	"github.com/CS-SI/SafeScale/lib/utils/strprocess"
+	"github.com/CS-SI/SafeScale/lib/utils/temporal"
+)
+
+const (
+	// upgrades the OS packages (held packages excepted) and exits with retcodeRebootNeeded if the node has to be rebooted
+	cmdUpgradeOSPackages = `sudo bash -c '
+if which apt-get >/dev/null 2>&1; then
+    export DEBIAN_FRONTEND=noninteractive
+    apt-get update && apt-get -y -o Dpkg::Options::="--force-confdef" -o Dpkg::Options::="--force-confold" dist-upgrade || exit 1
+    [ -f /var/run/reboot-required ] && exit 100
+elif which dnf >/dev/null 2>&1; then
+    dnf -y upgrade || exit 1
+    which needs-restarting >/dev/null 2>&1 && { needs-restarting -r >/dev/null 2>&1 || exit 100; }
+else
+    yum -y update || exit 1
+    which needs-restarting >/dev/null 2>&1 && { needs-restarting -r >/dev/null 2>&1 || exit 100; }
+fi
+exit 0'`
+	retcodeRebootNeeded = 100
+
+	cmdReadBootID = "cat /proc/sys/kernel/random/boot_id"
+)
+
+var (
+	// clusterOperations contains the operations in progress that cannot run at the same time on a cluster (the upgrade
+	// and the autoscaling both add and remove nodes), indexed by '<tenant>/<cluster>'
+	clusterOperations     = map[string]string{}
+	clusterOperationsLock sync.Mutex
+)
+
+// startExclusiveOperation records that the operation 'op' is in progress on the cluster, and returns the function to
+// call when it ends; fails with fail.NotAvailableError if another operation is already in progress
+func (c *cluster) startExclusiveOperation(op string) (func(), fail.Error) {
+	key := c.GetService().GetName() + "/" + c.GetName()
+
+	clusterOperationsLock.Lock()
+	defer clusterOperationsLock.Unlock()
+
+	if current, ok := clusterOperations[key]; ok {
+		return nil, fail.NotAvailableError("cluster '%s' is busy, %s in progress", c.GetName(), current)
+	}
+	clusterOperations[key] = op
+	return func() {
+		clusterOperationsLock.Lock()
+		defer clusterOperationsLock.Unlock()
+		delete(clusterOperations, key)
+	}, nil
+}
+
+// upgradedNode identifies a node to upgrade and its pool
+type upgradedNode struct {
+	node propertiesv3.ClusterNode
+	pool string
+}
+
+// Upgrade upgrades the nodes of the cluster, by batches of req.MaxUnavailable nodes
+// Each node of a batch is drained and upgraded in place then made schedulable again, or replaced by a node using the new
+// image (created before the node is drained and deleted); the next batch starts when the nodes of the current batch are healthy.
+// The autoscaling of the cluster is suspended during the upgrade.
+func (c *cluster) Upgrade(task concurrency.Task, req abstract.ClusterUpgradeRequest) (xerr fail.Error) {
+	if c.IsNull() {
+		return fail.InvalidInstanceError()
+	}
+	if task.IsNull() {
+		return fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+	if !req.OSPackages && req.Image == "" {
+		return fail.InvalidRequestError("nothing to upgrade, OS packages and/or image must be requested")
+	}
+	if req.MaxUnavailable == 0 {
+		req.MaxUnavailable = 1
+	}
+
+	tracer := debug.NewTracer(task, tracing.ShouldTrace("resources.cluster"), "(%v, '%s', '%s', %d)", req.OSPackages, req.Image, req.Pool, req.MaxUnavailable).Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&xerr, tracer.TraceMessage())
+
+	done, xerr := c.startExclusiveOperation("upgrade")
+	if xerr != nil {
+		return xerr
+	}
+	defer done()
+
+	state, xerr := c.GetState(task)
+	if xerr != nil {
+		return xerr
+	}
+	switch state {
+	case clusterstate.Nominal, clusterstate.Degraded, clusterstate.Created:
+	default:
+		return fail.NotAvailableError("cannot upgrade cluster in state '%s'", state.String())
+	}
+
+	nodes, xerr := c.listNodesToUpgrade(task, req.Pool)
+	if xerr != nil {
+		return xerr
+	}
+
+	if req.Image != "" {
+		if _, xerr = c.GetService().SearchImage(req.Image); xerr != nil {
+			return fail.Wrap(xerr, "failed to find image '%s'", req.Image)
+		}
+		// new nodes of the pools, including the replacing ones, will use the new image
+		if xerr = c.setNodePoolsImage(task, req.Pool, req.Image); xerr != nil {
+			return xerr
+		}
+	}
+
+	master, xerr := c.FindAvailableMaster(task)
+	if xerr != nil {
+		return xerr
+	}
+
+	total := uint(len(nodes))
+	for start := uint(0); start < total; start += req.MaxUnavailable {
+		end := start + req.MaxUnavailable
+		if end > total {
+			end = total
+		}
+
+		var hosts []resources.Host
+		if req.Image != "" {
+			hosts, xerr = c.replaceNodes(task, nodes[start:end], master.(*host))
+		} else {
+			hosts, xerr = c.loadUpgradedNodes(task, nodes[start:end])
+		}
+		if xerr == nil && req.OSPackages {
+			xerr = c.upgradeNodesPackages(task, hosts, master)
+		}
+		if xerr != nil {
+			return fail.Wrap(xerr, "failed to upgrade cluster, %d node%s out of %d upgraded", start, strprocess.Plural(start), total)
+		}
+		logrus.Infof("[cluster %s] %d node%s out of %d upgraded", c.GetName(), end, strprocess.Plural(end), total)
+	}
+	return nil
+}
+
+// listNodesToUpgrade lists the nodes of a pool, or all the nodes of the cluster if pool is empty
+func (c *cluster) listNodesToUpgrade(task concurrency.Task, pool string) ([]upgradedNode, fail.Error) {
+	var list []upgradedNode
+	xerr := c.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Inspect(task, clusterproperty.NodesV3, func(clonable data.Clonable) fail.Error {
+			nodesV3, ok := clonable.(*propertiesv3.ClusterNodes)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv3.ClusterNodes' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			return props.Inspect(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+				poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+				if !ok {
+					return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+				}
+
+				ids := nodesV3.PrivateNodes
+				if pool != "" {
+					if _, ok := poolsV1.ByName[pool]; !ok && pool != abstract.DefaultClusterNodePool {
+						return fail.NotFoundError("failed to find node pool '%s' in cluster '%s'", pool, c.GetName())
+					}
+					ids = nodesOfPool(nodesV3, poolsV1, pool)
+				}
+				for _, v := range ids {
+					if node, ok := nodesV3.ByNumericalID[v]; ok {
+						item := upgradedNode{node: *node, pool: poolsV1.PoolOfNode(v)}
+						if item.pool == "" {
+							item.pool = abstract.DefaultClusterNodePool
+						}
+						list = append(list, item)
+					}
+				}
+				return nil
+			})
+		})
+	})
+	return list, xerr
+}
+
+// setNodePoolsImage records the image to use for the new nodes of a pool, or of all the pools if pool is empty
+func (c *cluster) setNodePoolsImage(task concurrency.Task, pool, image string) fail.Error {
+	return c.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		var nodeSizing propertiesv1.HostSizingRequirements
+		innerXErr := props.Alter(task, clusterproperty.DefaultsV2, func(clonable data.Clonable) fail.Error {
+			defaultsV2, ok := clonable.(*propertiesv2.ClusterDefaults)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv2.ClusterDefaults' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			if pool == "" {
+				defaultsV2.Image = image
+			}
+			nodeSizing = defaultsV2.NodeSizing
+			return nil
+		})
+		if innerXErr != nil {
+			return innerXErr
+		}
+
+		return props.Alter(task, clusterproperty.NodePoolsV1, func(clonable data.Clonable) fail.Error {
+			poolsV1, ok := clonable.(*propertiesv1.ClusterNodePools)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.ClusterNodePools' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			if pool == "" {
+				for _, v := range poolsV1.ByName {
+					v.Image = image
+				}
+				return nil
+			}
+
+			item, ok := poolsV1.ByName[pool]
+			if !ok {
+				if pool != abstract.DefaultClusterNodePool {
+					return fail.NotFoundError("failed to find node pool '%s' in cluster '%s'", pool, c.GetName())
+				}
+				// default pool not recorded (cluster created before node pools), records it to override its image only
+				item = propertiesv1.NewClusterNodePool()
+				item.Name = pool
+				item.Sizing = nodeSizing
+				poolsV1.ByName[pool] = item
+			}
+			item.Image = image
+			return nil
+		})
+	})
+}
+
+// loadUpgradedNodes returns the instances of the nodes
+func (c *cluster) loadUpgradedNodes(task concurrency.Task, nodes []upgradedNode) ([]resources.Host, fail.Error) {
+	hosts := make([]resources.Host, 0, len(nodes))
+	for _, v := range nodes {
+		rh, xerr := LoadHost(task, c.GetService(), v.node.ID)
+		if xerr != nil {
+			return nil, xerr
+		}
+		hosts = append(hosts, rh)
+	}
+	return hosts, nil
+}
+
+// replaceNodes creates in their pools as many nodes as the nodes to replace, then drains and deletes the replaced nodes;
+// returns the new nodes
+// The replaced nodes are deleted only once their replacements joined the cluster, so that a failure leaves the cluster
+// with its capacity (the quotas of the tenant have to allow the additional nodes of a batch).
+func (c *cluster) replaceNodes(task concurrency.Task, nodes []upgradedNode, master *host) ([]resources.Host, fail.Error) {
+	countByPool := map[string]uint{}
+	for _, v := range nodes {
+		countByPool[v.pool]++
+	}
+	pools := make([]string, 0, len(countByPool))
+	for k := range countByPool {
+		pools = append(pools, k)
+	}
+	sort.Strings(pools)
+
+	// nodes are added pool by pool, AddNodes() taking care of joining them to the cluster and waiting for them to be ready
+	var hosts []resources.Host
+	for _, pool := range pools {
+		list, xerr := c.AddNodes(task, pool, countByPool[pool], abstract.HostSizingRequirements{})
+		if xerr != nil {
+			// the replaced nodes are still in service, the replacements already created are not needed anymore
+			for _, rh := range hosts {
+				if derr := c.deleteNode(task, rh, master); derr != nil {
+					_ = xerr.AddConsequence(fail.Wrap(derr, "cleaning up on failure, failed to delete node '%s'", rh.GetName()))
+				}
+			}
+			return nil, xerr
+		}
+		hosts = append(hosts, list...)
+	}
+
+	replaced, xerr := c.loadUpgradedNodes(task, nodes)
+	if xerr != nil {
+		return nil, xerr
+	}
+	if xerr = c.drainNodes(task, replaced, master); xerr != nil {
+		return nil, xerr
+	}
+
+	tg, xerr := concurrency.NewTaskGroup(task)
+	if xerr != nil {
+		return nil, xerr
+	}
+	var errors []error
+	for _, v := range nodes {
+		node := v.node
+		if _, xerr = tg.Start(c.taskDeleteNode, taskDeleteNodeParameters{node: &node, master: master}); xerr != nil {
+			errors = append(errors, xerr)
+		}
+	}
+	if _, xerr = tg.Wait(); xerr != nil {
+		errors = append(errors, xerr)
+	}
+	if len(errors) > 0 {
+		return nil, fail.NewErrorList(errors)
+	}
+	return hosts, nil
+}
+
+// drainNodes makes the flavor of the cluster drain the nodes, one at a time; on failure, the nodes already drained are
+// made schedulable again
+func (c *cluster) drainNodes(task concurrency.Task, hosts []resources.Host, master resources.Host) (xerr fail.Error) {
+	if c.makers.DrainNode == nil {
+		return nil
+	}
+
+	var drained []resources.Host
+	defer func() {
+		if xerr != nil && c.makers.UncordonNode != nil {
+			for _, rh := range drained {
+				if derr := c.makers.UncordonNode(task, c, rh); derr != nil {
+					_ = xerr.AddConsequence(fail.Wrap(derr, "cleaning up on failure, failed to make node '%s' schedulable again", rh.GetName()))
+				}
+			}
+		}
+	}()
+
+	for _, rh := range hosts {
+		if xerr = c.makers.DrainNode(task, c, rh, master); xerr != nil {
+			return xerr
+		}
+		drained = append(drained, rh)
+	}
+	return nil
+}
+
+// upgradeNodesPackages upgrades simultaneously the OS packages of the nodes
+func (c *cluster) upgradeNodesPackages(task concurrency.Task, hosts []resources.Host, master resources.Host) fail.Error {
+	tg, xerr := concurrency.NewTaskGroup(task)
+	if xerr != nil {
+		return xerr
+	}
+
+	var errors []error
+	for _, v := range hosts {
+		if _, xerr = tg.Start(c.taskUpgradeNodePackages, taskUpgradeNodePackagesParameters{host: v, master: master}); xerr != nil {
+			errors = append(errors, xerr)
+		}
+	}
+	if _, xerr = tg.Wait(); xerr != nil {
+		errors = append(errors, xerr)
+	}
+	if len(errors) > 0 {
+		return fail.NewErrorList(errors)
+	}
+	return nil
+}
+
+type taskUpgradeNodePackagesParameters struct {
+	host   resources.Host
+	master resources.Host
+}
+
+// taskUpgradeNodePackages drains a node, upgrades its OS packages, reboots it if needed then makes it join the cluster again
+func (c *cluster) taskUpgradeNodePackages(task concurrency.Task, params concurrency.TaskParameters) (_ concurrency.TaskResult, xerr fail.Error) {
+	if c.IsNull() {
+		return nil, fail.InvalidInstanceError()
+	}
+	if task.IsNull() {
+		return nil, fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+
+	p, ok := params.(taskUpgradeNodePackagesParameters)
+	if !ok {
+		return nil, fail.InvalidParameterError("params", "must be a 'taskUpgradeNodePackagesParameters'")
+	}
+	if p.host.IsNull() {
+		return nil, fail.InvalidParameterError("params.host", "cannot be null value of 'resources.Host'")
+	}
+
+	hostName := p.host.GetName()
+	logrus.Debugf("[cluster %s] upgrading OS packages of node '%s'...", c.GetName(), hostName)
+
+	if xerr = c.drainNodes(task, []resources.Host{p.host}, p.master); xerr != nil {
+		return nil, xerr
+	}
+
+	// Starting from here, makes the node schedulable again if exiting with error, to restore the capacity of the cluster
+	defer func() {
+		if xerr != nil && c.makers.UncordonNode != nil {
+			if derr := c.makers.UncordonNode(task, c, p.host); derr != nil {
+				_ = xerr.AddConsequence(fail.Wrap(derr, "cleaning up on failure, failed to make node '%s' schedulable again", hostName))
+			}
+		}
+	}()
+
+	// the boot ID changes at each boot, telling when the node restarted after a reboot
+	bootID, xerr := readBootID(task, p.host)
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	retcode, stdout, stderr, xerr := p.host.Run(task, cmdUpgradeOSPackages, outputs.COLLECT, temporal.GetConnectionTimeout(), temporal.GetLongOperationTimeout())
+	if xerr != nil {
+		return nil, fail.Wrap(xerr, "failed to upgrade OS packages of node '%s'", hostName)
+	}
+	switch retcode {
+	case 0:
+	case retcodeRebootNeeded:
+		logrus.Debugf("[cluster %s] rebooting node '%s'...", c.GetName(), hostName)
+		if xerr = p.host.Reboot(task); xerr != nil {
+			return nil, xerr
+		}
+		if xerr = waitHostRebooted(task, p.host, bootID, temporal.GetHostTimeout()); xerr != nil {
+			return nil, xerr
+		}
+	default:
+		output := stdout
+		if output != "" && stderr != "" {
+			output += "\n" + stderr
+		} else if stderr != "" {
+			output = stderr
+		}
+		return nil, fail.ExecutionError(nil, "failed to upgrade OS packages of node '%s' (retcode=%d): %s", hostName, retcode, output)
+	}
+
+	if c.makers.UncordonNode != nil {
+		if xerr = c.makers.UncordonNode(task, c, p.host); xerr != nil {
+			return nil, xerr
+		}
+	}
+
+	logrus.Debugf("[cluster %s] OS packages of node '%s' upgraded", c.GetName(), hostName)
+	return nil, nil
+}
+
+// readBootID returns the boot ID of the host, changing at each boot
+func readBootID(task concurrency.Task, rh resources.Host) (string, fail.Error) {
+	retcode, stdout, stderr, xerr := rh.Run(task, cmdReadBootID, outputs.COLLECT, temporal.GetConnectionTimeout(), temporal.GetExecutionTimeout())
+	if xerr != nil {
+		return "", fail.Wrap(xerr, "failed to read boot ID of host '%s'", rh.GetName())
+	}
+	if retcode != 0 {
+		return "", fail.ExecutionError(nil, "failed to read boot ID of host '%s' (retcode=%d): %s", rh.GetName(), retcode, stderr)
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+// waitHostRebooted waits for the host to answer with a boot ID other than 'bootID', meaning it restarted
+// Waiting only for SSH to answer is not enough: SSH may answer before the host goes down.
+func waitHostRebooted(task concurrency.Task, rh resources.Host, bootID string, timeout time.Duration) fail.Error {
+	xerr := retry.WhileUnsuccessful(
+		func() error {
+			if task.Aborted() {
+				return retry.StopRetryError(fail.AbortedError(nil, "aborted"))
+			}
+			current, innerXErr := readBootID(task, rh)
+			if innerXErr != nil {
+				return innerXErr
+			}
+			if current == bootID {
+				return fail.NotAvailableError("host '%s' not yet rebooted", rh.GetName())
+			}
+			return nil
+		},
+		temporal.GetMinDelay(),
+		timeout,
+	)
+	if xerr != nil {
+		return fail.Wrap(xerr, "failed to wait for host '%s' to reboot", rh.GetName())
+	}
+	return nil
+}
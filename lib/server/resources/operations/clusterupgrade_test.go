@@ -0,0 +1,189 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/server/iaas"
+	"github.com/CS-SI/SafeScale/lib/server/resources"
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterstate"
+	flavors "github.com/CS-SI/SafeScale/lib/server/resources/operations/clusterflavors"
+	"github.com/CS-SI/SafeScale/lib/utils/cli/enums/outputs"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// rebootingHost is a resources.Host answering to the reading of its boot ID with the successive values of bootIDs,
+// an empty value meaning SSH does not answer
+type rebootingHost struct {
+	resources.Host
+	name    string
+	bootIDs []string
+	reads   int
+}
+
+func (rh *rebootingHost) GetName() string {
+	return rh.name
+}
+
+func (rh *rebootingHost) Run(_ concurrency.Task, cmd string, _ outputs.Enum, _, _ time.Duration) (int, string, string, fail.Error) {
+	if cmd != cmdReadBootID {
+		return 1, "", "unexpected command", nil
+	}
+	if rh.reads >= len(rh.bootIDs) {
+		return 0, rh.bootIDs[len(rh.bootIDs)-1] + "\n", "", nil
+	}
+	id := rh.bootIDs[rh.reads]
+	rh.reads++
+	if id == "" {
+		return 0, "", "", fail.TimeoutError(nil, time.Second, "SSH not ready")
+	}
+	return 0, id + "\n", "", nil
+}
+
+func Test_waitHostRebooted(t *testing.T) {
+	task, xerr := concurrency.NewTask()
+	require.Nil(t, xerr)
+
+	// SSH answers before the host goes down, then the host reboots
+	rh := &rebootingHost{name: "node-1", bootIDs: []string{"before", "before", "", "after"}}
+	id, xerr := readBootID(task, rh)
+	require.Nil(t, xerr)
+	require.Equal(t, "before", id)
+	xerr = waitHostRebooted(task, rh, id, time.Minute)
+	require.Nil(t, xerr)
+	require.Equal(t, 4, rh.reads)
+
+	// host never rebooting
+	rh = &rebootingHost{name: "node-2", bootIDs: []string{"before"}}
+	xerr = waitHostRebooted(task, rh, "before", 2*time.Second)
+	require.NotNil(t, xerr)
+}
+
+func Test_cluster_drainNodes(t *testing.T) {
+	task, xerr := concurrency.NewTask()
+	require.Nil(t, xerr)
+
+	var drained, uncordoned []string
+	c := &cluster{
+		makers: flavors.Makers{
+			DrainNode: func(_ concurrency.Task, _ resources.Cluster, host resources.Host, _ resources.Host) fail.Error {
+				if host.GetName() == "node-3" {
+					return fail.ExecutionError(nil, "failed to drain")
+				}
+				drained = append(drained, host.GetName())
+				return nil
+			},
+			UncordonNode: func(_ concurrency.Task, _ resources.Cluster, host resources.Host) fail.Error {
+				uncordoned = append(uncordoned, host.GetName())
+				return nil
+			},
+		},
+	}
+
+	hosts := []resources.Host{&rebootingHost{name: "node-1"}, &rebootingHost{name: "node-2"}}
+	xerr = c.drainNodes(task, hosts, nil)
+	require.Nil(t, xerr)
+	require.Equal(t, []string{"node-1", "node-2"}, drained)
+	require.Empty(t, uncordoned)
+
+	// on failure, the nodes already drained are made schedulable again
+	drained = nil
+	hosts = append(hosts, &rebootingHost{name: "node-3"})
+	xerr = c.drainNodes(task, hosts, nil)
+	require.NotNil(t, xerr)
+	require.Equal(t, []string{"node-1", "node-2"}, uncordoned)
+
+	// flavors without DrainNode do nothing
+	c = &cluster{}
+	require.Nil(t, c.drainNodes(task, hosts, nil))
+}
+
+// namedService is an iaas.Service only knowing its name
+type namedService struct {
+	iaas.Service
+	name string
+}
+
+func (s *namedService) IsNull() bool {
+	return false
+}
+
+func (s *namedService) GetName() string {
+	return s.name
+}
+
+func Test_cluster_UpgradeSuspendsAutoscaling(t *testing.T) {
+	task, xerr := concurrency.NewTask()
+	require.Nil(t, xerr)
+
+	// the upgrade is held while it reads the state of the cluster
+	reading, release := make(chan struct{}), make(chan struct{})
+	c := &cluster{
+		core: &core{kind: "cluster", folder: folder{service: &namedService{name: "tenant"}}},
+		makers: flavors.Makers{
+			GetState: func(concurrency.Task, resources.Cluster) (clusterstate.Enum, fail.Error) {
+				close(reading)
+				<-release
+				return clusterstate.Unknown, fail.NotAvailableError("state not available")
+			},
+		},
+	}
+	c.name.Store("cluster")
+
+	upgraded := make(chan fail.Error)
+	go func() {
+		upgraded <- c.Upgrade(task, abstract.ClusterUpgradeRequest{OSPackages: true})
+	}()
+	<-reading
+
+	// the autoscaling is skipped without reading the policy (the cluster has no metadata), and the upgrade cannot
+	// be started twice
+	require.Nil(t, c.Autoscale(task))
+	xerr = c.Upgrade(task, abstract.ClusterUpgradeRequest{OSPackages: true})
+	require.NotNil(t, xerr)
+	require.IsType(t, &fail.ErrNotAvailable{}, xerr)
+	require.Contains(t, xerr.Error(), "upgrade in progress")
+
+	close(release)
+	xerr = <-upgraded
+	require.NotNil(t, xerr)
+	require.Contains(t, xerr.Error(), "state not available")
+
+	// the upgrade is refused while the autoscaler scales the cluster
+	done, xerr := c.startExclusiveOperation("autoscaling")
+	require.Nil(t, xerr)
+	xerr = c.Upgrade(task, abstract.ClusterUpgradeRequest{OSPackages: true})
+	require.NotNil(t, xerr)
+	require.Contains(t, xerr.Error(), "autoscaling in progress")
+	done()
+
+	// the same cluster name on another tenant is not concerned
+	other := &cluster{core: &core{kind: "cluster", folder: folder{service: &namedService{name: "other"}}}}
+	other.name.Store("cluster")
+	done, xerr = c.startExclusiveOperation("autoscaling")
+	require.Nil(t, xerr)
+	otherDone, xerr := other.startExclusiveOperation("upgrade")
+	require.Nil(t, xerr)
+	otherDone()
+	done()
+}
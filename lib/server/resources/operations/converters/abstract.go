@@ -91,6 +91,29 @@ func HostTemplateFromAbstractToProtocol(in abstract.HostTemplate) *protocol.Host
 	}
 }
 
+// StoredCPUInfoFromAbstractToProtocol converts scanned data of a template to a protocol.ScannedTemplate
+func StoredCPUInfoFromAbstractToProtocol(in abstract.StoredCPUInfo) *protocol.ScannedTemplate {
+	return &protocol.ScannedTemplate{
+		TemplateId:   in.TemplateID,
+		TemplateName: in.TemplateName,
+		ImageName:    in.ImageName,
+		LastUpdated:  in.LastUpdated,
+		CpuCount:     int32(in.NumberOfCPU),
+		CoreCount:    int32(in.NumberOfCore),
+		CpuFreq:      in.CPUFrequency,
+		CpuModel:     in.CPUModel,
+		RamSize:      in.RAMSize,
+		GpuCount:     int32(in.GPU),
+		GpuModel:     in.GPUModel,
+		DiskSize:     in.DiskSize,
+		DiskType:     in.MainDiskType,
+		DiskSpeed:    in.MainDiskSpeed,
+		NetSpeed:     in.SampleNetSpeed,
+		PricePerHour: in.PricePerHour,
+		Errors:       in.Errors,
+	}
+}
+
 // ImageFromAbstractToProtocol ...
 func ImageFromAbstractToProtocol(in *abstract.Image) *protocol.Image {
 	return &protocol.Image{
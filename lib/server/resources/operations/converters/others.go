@@ -28,6 +28,7 @@ import (
 
 	"github.com/CS-SI/SafeScale/lib/protocol"
 	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/templateselection"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
 )
 
@@ -181,6 +182,16 @@ func HostSizingRequirementsFromStringToAbstract(in string) (*abstract.HostSizing
 			return nil, 0, xerr
 		}
 	}
+	if t, ok := tokens["policy"]; ok {
+		policy, _, xerr := t.Validate()
+		if xerr != nil {
+			return nil, 0, xerr
+		}
+		if _, xerr = templateselection.Parse(policy); xerr != nil {
+			return nil, 0, fail.SyntaxError("invalid value '%s' for 'policy': must be 'drf', 'cheapest', 'fastest' or 'best_value'", policy)
+		}
+		out.Policy = policy
+	}
 	return &out, count, nil
 }
 
@@ -278,8 +289,8 @@ func (t *sizingToken) Validate() (string, string, fail.Error) {
 		if keyword == "count" {
 			return "", "", fail.InvalidRequestError("'count' can only use '='")
 		}
-		if keyword == "template" {
-			return "", "", fail.InvalidRequestError("'template' can only use '='")
+		if keyword == "template" || keyword == "policy" {
+			return "", "", fail.InvalidRequestError("'%s' can only use '='", keyword)
 		}
 
 		vali, err := strconv.Atoi(value)
@@ -292,7 +303,7 @@ func (t *sizingToken) Validate() (string, string, fail.Error) {
 		}
 		return fmt.Sprintf("%d", vali), fmt.Sprintf("%d", 2*vali), nil
 	case "=":
-		if keyword == "template" {
+		if keyword == "template" || keyword == "policy" {
 			return value, "", nil
 		}
 		if keyword != "count" {
@@ -332,8 +343,8 @@ func (t *sizingToken) Validate() (string, string, fail.Error) {
 		if keyword == "count" {
 			return "", "", fail.InvalidRequestError("'count' can only use '='")
 		}
-		if keyword == "template" {
-			return "", "", fail.InvalidRequestError("'template' can only use '='")
+		if keyword == "template" || keyword == "policy" {
+			return "", "", fail.InvalidRequestError("'%s' can only use '='", keyword)
 		}
 
 		vali, err := strconv.Atoi(value)
@@ -352,8 +363,8 @@ func (t *sizingToken) Validate() (string, string, fail.Error) {
 		if keyword == "count" {
 			return "", "", fail.InvalidRequestError("'count' can only use '='")
 		}
-		if keyword == "template" {
-			return "", "", fail.InvalidRequestError("'template' can only use '='")
+		if keyword == "template" || keyword == "policy" {
+			return "", "", fail.InvalidRequestError("'%s' can only use '='", keyword)
 		}
 
 		_, err := strconv.Atoi(value)
@@ -371,8 +382,8 @@ func (t *sizingToken) Validate() (string, string, fail.Error) {
 		if keyword == "count" {
 			return "", "", fail.InvalidRequestError("'count' can only use '='")
 		}
-		if keyword == "template" {
-			return "", "", fail.InvalidRequestError("'template' can only use '='")
+		if keyword == "template" || keyword == "policy" {
+			return "", "", fail.InvalidRequestError("'%s' can only use '='", keyword)
 		}
 
 		vali, err := strconv.Atoi(value)
@@ -391,6 +402,9 @@ func (t *sizingToken) Validate() (string, string, fail.Error) {
 		if keyword == "count" {
 			return "", "", fail.InvalidRequestError("'count' can only use '='")
 		}
+		if keyword == "template" || keyword == "policy" {
+			return "", "", fail.InvalidRequestError("'%s' can only use '='", keyword)
+		}
 
 		_, err := strconv.Atoi(value)
 		if err != nil {
@@ -19,7 +19,11 @@ package converters
 // Contains functions that are used to convert from property
 
 import (
+	"fmt"
 	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
 
 	"github.com/CS-SI/SafeScale/lib/protocol"
 	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
@@ -194,3 +198,122 @@ func ClusterNodeFromPropertyToProtocol(in propertiesv3.ClusterNode) *protocol.Ho
 		PrivateIp: in.PrivateIP,
 	}
 }
+
+// HostSizingRequirementsFromPropertyToString converts a propertiesv1.HostSizingRequirements to a string using the syntax of sizing parameters
+func HostSizingRequirementsFromPropertyToString(in propertiesv1.HostSizingRequirements) string {
+	var list []string
+	if in.MinCores > 0 || in.MaxCores > 0 {
+		list = append(list, rangeToSizingToken("cpu", fmt.Sprintf("%d", in.MinCores), fmt.Sprintf("%d", in.MaxCores), in.MinCores > 0, in.MaxCores > 0))
+	}
+	if in.MinRAMSize > 0 || in.MaxRAMSize > 0 {
+		list = append(list, rangeToSizingToken("ram", fmt.Sprintf("%.1f", in.MinRAMSize), fmt.Sprintf("%.1f", in.MaxRAMSize), in.MinRAMSize > 0, in.MaxRAMSize > 0))
+	}
+	if in.MinDiskSize > 0 {
+		list = append(list, fmt.Sprintf("disk>=%d", in.MinDiskSize))
+	}
+	if in.MinGPU > 0 {
+		list = append(list, fmt.Sprintf("gpu>=%d", in.MinGPU))
+	}
+	if in.MinCPUFreq > 0 {
+		list = append(list, fmt.Sprintf("cpufreq>=%.1f", in.MinCPUFreq))
+	}
+	return strings.Join(list, ",")
+}
+
+// rangeToSizingToken builds a sizing token from optional min and max values
+func rangeToSizingToken(keyword, min, max string, withMin, withMax bool) string {
+	switch {
+	case withMin && withMax:
+		return fmt.Sprintf("%s=[%s-%s]", keyword, min, max)
+	case withMin:
+		return fmt.Sprintf("%s>=%s", keyword, min)
+	default:
+		return fmt.Sprintf("%s<=%s", keyword, max)
+	}
+}
+
+// ClusterNodePoolFromPropertyToProtocol converts a propertiesv1.ClusterNodePool to a protocol.ClusterNodePool; nodes contains the names of the nodes of the pool
+func ClusterNodePoolFromPropertyToProtocol(in propertiesv1.ClusterNodePool, nodes []string) *protocol.ClusterNodePool {
+	out := &protocol.ClusterNodePool{
+		Name:   in.Name,
+		Sizing: HostSizingRequirementsFromPropertyToString(in.Sizing),
+		Image:  in.Image,
+		Count:  uint32(in.Count),
+		Labels: make(map[string]string, len(in.Labels)),
+		Taints: make([]string, len(in.Taints)),
+		Nodes:  nodes,
+	}
+	for k, v := range in.Labels {
+		out.Labels[k] = v
+	}
+	copy(out.Taints, in.Taints)
+	return out
+}
+
+// ClusterAutoscalingFromPropertyToProtocol converts a propertiesv1.ClusterAutoscaling to a protocol.ClusterAutoscaling
+func ClusterAutoscalingFromPropertyToProtocol(in propertiesv1.ClusterAutoscaling) *protocol.ClusterAutoscaling {
+	out := &protocol.ClusterAutoscaling{
+		Policy: &protocol.ClusterAutoscalingPolicy{
+			Enabled:            in.Policy.Enabled,
+			Pool:               in.Policy.Pool,
+			MinNodes:           uint32(in.Policy.MinNodes),
+			MaxNodes:           uint32(in.Policy.MaxNodes),
+			Cooldown:           uint32(in.Policy.Cooldown / time.Second),
+			Metric:             in.Policy.Metric,
+			ScaleUpThreshold:   in.Policy.ScaleUpThreshold,
+			ScaleDownThreshold: in.Policy.ScaleDownThreshold,
+		},
+		Decisions: make([]*protocol.ClusterAutoscalingDecision, 0, len(in.Decisions)),
+	}
+	for _, v := range in.Decisions {
+		date, _ := ptypes.TimestampProto(v.Date)
+		out.Decisions = append(out.Decisions, &protocol.ClusterAutoscalingDecision{
+			Date:   date,
+			Action: v.Action,
+			Count:  int32(v.Count),
+			Reason: v.Reason,
+			Error:  v.Error,
+		})
+	}
+	return out
+}
+
+// FeatureHistoryFromPropertyToProtocol converts a propertiesv1.FeatureHistory to a protocol.FeatureHistoryResponse,
+// keeping only the entries of feature 'name' if not empty
+func FeatureHistoryFromPropertyToProtocol(in propertiesv1.FeatureHistory, name string) *protocol.FeatureHistoryResponse {
+	out := &protocol.FeatureHistoryResponse{
+		Entries: make([]*protocol.FeatureHistoryEntry, 0, len(in.Entries)),
+	}
+	for _, v := range in.Entries {
+		if name != "" && v.Feature != name {
+			continue
+		}
+		start, _ := ptypes.TimestampProto(v.Start)
+		end, _ := ptypes.TimestampProto(v.End)
+		item := &protocol.FeatureHistoryEntry{
+			Name:       v.Feature,
+			Version:    v.Version,
+			Action:     v.Action,
+			Parameters: v.Parameters,
+			Start:      start,
+			End:        end,
+			Success:    v.Success,
+			Error:      v.Error,
+			Steps:      make([]*protocol.FeatureHistoryStep, 0, len(v.Steps)),
+		}
+		for _, s := range v.Steps {
+			item.Steps = append(item.Steps, &protocol.FeatureHistoryStep{
+				Step:       s.Step,
+				Host:       s.Host,
+				ExitCode:   int32(s.ExitCode),
+				Completed:  s.Completed,
+				Success:    s.Success,
+				Output:     s.Output,
+				Error:      s.Error,
+				DurationMs: int64(s.Duration / time.Millisecond),
+			})
+		}
+		out.Entries = append(out.Entries, item)
+	}
+	return out
+}
@@ -22,12 +22,14 @@ import (
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/ipversion"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/securitygroupruledirection"
 	"strings"
+	"time"
 
 	"github.com/CS-SI/SafeScale/lib/protocol"
 	"github.com/CS-SI/SafeScale/lib/server/resources"
 	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clustercomplexity"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterflavor"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
 	"github.com/CS-SI/SafeScale/lib/system"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
 )
@@ -73,6 +75,8 @@ func FeatureSettingsFromProtocolToResource(in *protocol.FeatureSettings) resourc
 		SkipFeatureRequirements: in.IgnoreFeatureRequirements,
 		SkipSizingRequirements:  in.IgnoreSizingRequirements,
 		AddUnconditionally:      in.AddUnconditionally,
+		AutoExpand:              in.AutoExpand,
+		DryRun:                  in.DryRun,
 	}
 }
 
@@ -176,6 +180,15 @@ func ClusterRequestFromProtocolToAbstract(in *protocol.ClusterCreateRequest) (_
 		disabled[v] = struct{}{}
 	}
 
+	pools := make([]abstract.ClusterNodePoolRequest, 0, len(in.NodePools))
+	for _, v := range in.NodePools {
+		pool, xerr := ClusterNodePoolFromProtocolToAbstract(v)
+		if xerr != nil {
+			return nullCR, xerr
+		}
+		pools = append(pools, pool)
+	}
+
 	out := abstract.ClusterRequest{
 		Name:                    in.Name,
 		CIDR:                    in.Cidr,
@@ -189,10 +202,58 @@ func ClusterRequestFromProtocolToAbstract(in *protocol.ClusterCreateRequest) (_
 		KeepOnFailure:           in.KeepOnFailure,
 		DisabledDefaultFeatures: disabled,
 		InitialNodeCount:        uint(nodeCount),
+		NodePools:               pools,
+	}
+	return out, nil
+}
+
+// ClusterNodePoolFromProtocolToAbstract converts a protocol.ClusterNodePool to abstract.ClusterNodePoolRequest
+func ClusterNodePoolFromProtocolToAbstract(in *protocol.ClusterNodePool) (abstract.ClusterNodePoolRequest, fail.Error) {
+	if in == nil {
+		return abstract.ClusterNodePoolRequest{}, fail.InvalidParameterError("in", "cannot be nil")
+	}
+
+	out := abstract.ClusterNodePoolRequest{
+		Name:   in.Name,
+		Count:  uint(in.Count),
+		Labels: in.Labels,
+		Taints: in.Taints,
+		Sizing: abstract.HostSizingRequirements{MinGPU: -1},
+	}
+	if in.Sizing != "" {
+		sizing, count, xerr := HostSizingRequirementsFromStringToAbstract(in.Sizing)
+		if xerr != nil {
+			return abstract.ClusterNodePoolRequest{}, fail.Wrap(xerr, "invalid sizing of node pool '%s'", in.Name)
+		}
+		out.Sizing = *sizing
+		if out.Count == 0 && count > 0 {
+			out.Count = uint(count)
+		}
+	}
+	out.Sizing.Image = in.Image
+	if out.Labels == nil {
+		out.Labels = map[string]string{}
 	}
 	return out, nil
 }
 
+// ClusterAutoscalingPolicyFromProtocolToProperty converts a protocol.ClusterAutoscalingPolicy to propertiesv1.ClusterAutoscalingPolicy
+func ClusterAutoscalingPolicyFromProtocolToProperty(in *protocol.ClusterAutoscalingPolicy) propertiesv1.ClusterAutoscalingPolicy {
+	if in == nil {
+		return propertiesv1.ClusterAutoscalingPolicy{}
+	}
+	return propertiesv1.ClusterAutoscalingPolicy{
+		Enabled:            in.Enabled,
+		Pool:               in.Pool,
+		MinNodes:           uint(in.MinNodes),
+		MaxNodes:           uint(in.MaxNodes),
+		Cooldown:           time.Duration(in.Cooldown) * time.Second,
+		Metric:             in.Metric,
+		ScaleUpThreshold:   in.ScaleUpThreshold,
+		ScaleDownThreshold: in.ScaleDownThreshold,
+	}
+}
+
 // SecurityGroupRuleFromProtocolToAbstract does what the name says
 func SecurityGroupRuleFromProtocolToAbstract(in *protocol.SecurityGroupRule) (abstract.SecurityGroupRule, fail.Error) {
 	var out abstract.SecurityGroupRule
@@ -20,13 +20,17 @@ import (
 	"fmt"
 	"io/ioutil"
 	"strings"
+	"time"
 
+	"github.com/Masterminds/semver"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
 	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/events"
 	"github.com/CS-SI/SafeScale/lib/server/resources"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/featuretargettype"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/installaction"
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/installmethod"
 	"github.com/CS-SI/SafeScale/lib/utils"
 	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
@@ -96,6 +100,22 @@ func ListFeatures(task concurrency.Task, suitableFor string) ([]interface{}, fai
 		}
 	}
 
+	names, xerr := listFeaturesInRepositories()
+	if xerr != nil {
+		return nil, xerr
+	}
+	for _, name := range names {
+		if _, ok := allEmbeddedFeaturesMap[name]; ok {
+			continue
+		}
+		feat, xerr := NewFeature(task, name)
+		if xerr != nil {
+			logrus.Error(xerr)
+			continue
+		}
+		allEmbeddedFeaturesMap[name] = feat.(*feature)
+	}
+
 	for _, feat := range features {
 		switch suitableFor {
 		case "host":
@@ -131,6 +151,9 @@ func ListFeatures(task concurrency.Task, suitableFor string) ([]interface{}, fai
 
 // NewFeature searches for a spec file name 'name' and initializes a new Feature object
 // with its content
+// 'name' may be suffixed by '@<version>' to use a specific version of the feature; without version, the highest
+// version available is used
+// The feature is searched in local folders, then in feature repositories, then in embedded features
 // error contains :
 //    - fail.ErrNotFound if no feature is found by its name
 //    - fail.ErrSyntax if feature found contains syntax error
@@ -142,10 +165,15 @@ func NewFeature(task concurrency.Task, name string) (_ resources.Feature, xerr f
 		return nullFeature(), fail.InvalidParameterError("name", "cannot be empty string")
 	}
 
-	tracer := debug.NewTracer(task, tracing.ShouldTrace("resources.features")).WithStopwatch().Entering()
+	tracer := debug.NewTracer(task, tracing.ShouldTrace("resources.features"), "(%s)", name).WithStopwatch().Entering()
 	defer tracer.Exiting()
 	defer fail.OnExitLogError(&xerr, tracer.TraceMessage(""))
 
+	name, version := splitFeatureReference(name)
+	if name == "" {
+		return nullFeature(), fail.InvalidParameterError("name", "cannot be empty string")
+	}
+
 	v := viper.New()
 	v.AddConfigPath(".")
 	v.AddConfigPath("$HOME/.safescale/features")
@@ -156,33 +184,50 @@ func NewFeature(task concurrency.Task, name string) (_ resources.Feature, xerr f
 	casted := nullFeature()
 	err := v.ReadInConfig()
 	if err != nil {
-		switch err.(type) {
-		case viper.ConfigFileNotFoundError:
-			// Failed to find a spec file on filesystem, trying with embedded ones
-			xerr = nil
-			var ok bool
-			if _, ok = allEmbeddedFeaturesMap[name]; !ok {
-				xerr = fail.NotFoundError("failed to find a feature named '%s'", name)
-			} else {
-				casted = allEmbeddedFeaturesMap[name].Clone().(*feature)
-				casted.task = task
-				casted.displayFileName = name + ".yml [embedded]"
-				xerr = nil
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return casted, fail.SyntaxError("failed to read the specification file of feature called '%s': %s", name, err.Error())
+		}
+	} else if version == "" || v.GetString("feature.version") == version {
+		if v.IsSet("feature") {
+			casted = &feature{
+				fileName:        v.ConfigFileUsed(),
+				displayFileName: v.ConfigFileUsed(),
+				displayName:     name,
+				specs:           v,
+				task:            task,
 			}
-		default:
-			xerr = fail.SyntaxError("failed to read the specification file of feature called '%s': %s", name, err.Error())
 		}
-	} else if v.IsSet("feature") {
+		return casted, nil
+	}
+
+	// Failed to find a suitable spec file on filesystem, trying with feature repositories
+	path, specs, xerr := findFeatureInRepositories(name, version)
+	if xerr == nil {
 		casted = &feature{
-			fileName:        v.ConfigFileUsed(),
-			displayFileName: v.ConfigFileUsed(),
+			fileName:        path,
+			displayFileName: path,
 			displayName:     name,
-			specs:           v,
+			specs:           specs,
 			task:            task,
 		}
-		xerr = nil
+		return casted, nil
+	}
+	if _, ok := xerr.(*fail.ErrNotFound); !ok {
+		return casted, xerr
+	}
+
+	// then with embedded ones
+	embedded, ok := allEmbeddedFeaturesMap[name]
+	if !ok {
+		return casted, fail.NotFoundError("failed to find a feature named '%s'", name)
+	}
+	if version != "" && embedded.GetVersion() != version {
+		return casted, fail.NotFoundError("failed to find version '%s' of feature '%s'", version, name)
 	}
-	return casted, xerr
+	casted = embedded.Clone().(*feature)
+	casted.task = task
+	casted.displayFileName = name + ".yml [embedded]"
+	return casted, nil
 }
 
 // NewEmbeddedFeature searches for an embedded featured named 'name' and initializes a new Feature object
@@ -251,6 +296,14 @@ func (f feature) GetID() string {
 	return f.GetName()
 }
 
+// GetVersion returns the version of the feature (empty string if the feature is not versioned)
+func (f feature) GetVersion() string {
+	if f.IsNull() {
+		return ""
+	}
+	return f.specs.GetString("feature.version")
+}
+
 // GetFilename returns the filename of the feature definition, with error handling
 func (f feature) GetFilename() string {
 	if f.IsNull() {
@@ -333,6 +386,9 @@ func (f feature) Check(target resources.Targetable, v data.Map, s resources.Feat
 	// 	return anon.(Results), nil
 	// }
 
+	// Checks don't modify the target, they are executed even in dry run
+	s.DryRun = false
+
 	methods := target.InstallMethods(f.task)
 	var installer Installer
 	for _, meth := range methods {
@@ -448,7 +504,9 @@ func (f feature) Add(target resources.Targetable, v data.Map, s resources.Featur
 		return nil, xerr
 	}
 
-	if !s.AddUnconditionally {
+	// In dry run, the scripts are rendered even if the feature is already installed, and requirements are not
+	// processed
+	if !s.AddUnconditionally && !s.DryRun {
 		results, xerr := f.Check(target, v, s)
 		if xerr != nil {
 			return nil, fail.Wrap(xerr, "failed to check feature '%s'", featureName)
@@ -459,15 +517,22 @@ func (f feature) Add(target resources.Targetable, v data.Map, s resources.Featur
 		}
 	}
 
-	if !s.SkipFeatureRequirements {
+	if !s.SkipFeatureRequirements && !s.DryRun {
 		if xerr = f.installRequirements(target, v, s); xerr != nil {
 			return nil, fail.Wrap(xerr, "failed to install requirements")
 		}
 	}
+	start := time.Now()
 	results, xerr := installer.Add(&f, target, myV, s)
+	if !s.DryRun {
+		f.recordHistory(target, newFeatureHistoryEntry(f, installaction.Add, myV, start, results, xerr))
+	}
 	if xerr != nil {
 		return nil, xerr
 	}
+	if !s.DryRun && results != nil && results.Successful() {
+		f.publishEvent(protocol.EventType_ET_FEATURE_INSTALLED, target)
+	}
 	// _ = checkCache.ForceSet(featureName()+"@"+targetName, results)
 	return results, xerr
 }
@@ -530,59 +595,198 @@ func (f feature) Remove(target resources.Targetable, v data.Map, s resources.Fea
 		return nil, xerr
 	}
 
+	start := time.Now()
 	results, xerr = installer.Remove(&f, target, myV, s)
+	if !s.DryRun {
+		f.recordHistory(target, newFeatureHistoryEntry(f, installaction.Remove, myV, start, results, xerr))
+	}
 	// if xerr == nil {
 	// 	checkCache.Reset(f.DisplayName() + "@" + targetName)
 	// }
+	if xerr == nil && !s.DryRun && results != nil && results.Successful() {
+		f.publishEvent(protocol.EventType_ET_FEATURE_REMOVED, target)
+	}
+	return results, xerr
+}
+
+// publishEvent publishes an event about the feature on the target, the resource of the event being the target
+func (f feature) publishEvent(t protocol.EventType, target resources.Targetable) {
+	events.Publish(f.task, events.Event{
+		Type:         t,
+		ResourceID:   target.GetID(),
+		ResourceName: target.GetName(),
+		Details:      map[string]string{"feature": f.GetName(), "target": target.TargetType().String()},
+	})
+}
+
+// Upgrade upgrades the feature installed on the target in version 'fromVersion' (empty if unknown) to the version
+// of the feature
+func (f feature) Upgrade(target resources.Targetable, fromVersion string, v data.Map, s resources.FeatureSettings) (_ resources.Results, xerr fail.Error) {
+	defer fail.OnPanic(&xerr)
+
+	if f.IsNull() {
+		return nil, fail.InvalidInstanceError()
+	}
+	if target == nil {
+		return nil, fail.InvalidParameterError("target", "cannot be nil")
+	}
+
+	featureName := f.GetName()
+	targetName := target.GetName()
+	targetType := target.TargetType().String()
+
+	tracer := debug.NewTracer(f.task, tracing.ShouldTrace("resources.features"), "(): '%s' on %s '%s'", featureName, targetType, targetName).WithStopwatch().Entering()
+	defer tracer.Exiting()
+	defer fail.OnExitLogError(&xerr, tracer.TraceMessage(""))
+
+	methods := target.InstallMethods(f.task)
+	var (
+		installer Installer
+		yamlKey   string
+		i         uint8
+	)
+	for i = 1; i <= uint8(len(methods)); i++ {
+		yamlKey = "feature.install." + strings.ToLower(methods[i].String()) + ".upgrade"
+		if f.specs.IsSet(yamlKey) {
+			installer = f.installerOfMethod(methods[i])
+			if installer != nil {
+				break
+			}
+		}
+	}
+	if installer == nil {
+		return nil, fail.NotAvailableError("failed to find a way to upgrade '%s': no upgrade action defined", featureName)
+	}
+	if xerr = checkUpgradeFromVersion(f.specs.GetString(yamlKey+".fromVersion"), fromVersion); xerr != nil {
+		return nil, fail.Wrap(xerr, "cannot upgrade feature '%s'", featureName)
+	}
+
+	defer temporal.NewStopwatch().OnExitLogInfo(
+		fmt.Sprintf("Starting upgrade of feature '%s' on %s '%s'...", featureName, targetType, targetName),
+		fmt.Sprintf("Ending upgrade of feature '%s' on %s '%s'", featureName, targetType, targetName),
+	)()
+
+	// 'v' may be updated by parallel tasks, so use copy of it
+	myV := v.Clone()
+
+	// Inits target parameters
+	if xerr = target.ComplementFeatureParameters(f.task, myV); xerr != nil {
+		return nil, xerr
+	}
+	myV["FromVersion"] = fromVersion
+	myV["ToVersion"] = f.GetVersion()
+
+	// Checks required parameters have value
+	if xerr = checkParameters(f, myV); xerr != nil {
+		return nil, xerr
+	}
+
+	start := time.Now()
+	results, xerr := installer.Upgrade(&f, target, myV, s)
+	if !s.DryRun {
+		f.recordHistory(target, newFeatureHistoryEntry(f, installaction.Upgrade, myV, start, results, xerr))
+	}
 	return results, xerr
 }
 
+// upgradeFeatureOnTarget upgrades the feature 'name' (as 'name' or 'name@version') installed on target in version
+// 'installed'; 'recorded' tells if the feature is recorded as installed in the metadata of the target
+// Returns the feature upgraded, or nil if the version installed is already the one requested
+func upgradeFeatureOnTarget(task concurrency.Task, target resources.Targetable, name, installed string, recorded bool, vars data.Map, settings resources.FeatureSettings) (resources.Feature, resources.Results, fail.Error) {
+	feat, xerr := NewFeature(task, name)
+	if xerr != nil {
+		return nil, nil, xerr
+	}
+
+	if !recorded {
+		// The feature may have been installed before the tracking of features, checks it is present
+		results, xerr := feat.Check(target, vars, settings)
+		if xerr != nil {
+			return nil, nil, fail.Wrap(xerr, "failed to check feature '%s'", feat.GetName())
+		}
+		if !results.Successful() {
+			return nil, nil, fail.NotFoundError("feature '%s' is not installed on %s '%s'", feat.GetName(), target.TargetType().String(), target.GetName())
+		}
+	}
+
+	version := feat.GetVersion()
+	if installed != "" && version != "" {
+		switch compareFeatureVersions(installed, version) {
+		case 0:
+			logrus.Infof("feature '%s' is already installed in version '%s' on %s '%s'", feat.GetName(), version, target.TargetType().String(), target.GetName())
+			return nil, &results{}, nil
+		case 1:
+			return nil, nil, fail.InvalidRequestError("cannot upgrade feature '%s' from version '%s' to older version '%s'", feat.GetName(), installed, version)
+		}
+	}
+
+	outcomes, xerr := feat.Upgrade(target, installed, vars, settings)
+	return feat, outcomes, xerr
+}
+
+// checkUpgradeFromVersion checks the version installed satisfies the constraint 'fromVersion' of the upgrade action
+func checkUpgradeFromVersion(constraint, fromVersion string) fail.Error {
+	if constraint == "" {
+		return nil
+	}
+	if fromVersion == "" {
+		return fail.InvalidRequestError("the version installed is unknown, the upgrade requires a version satisfying '%s'", constraint)
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return fail.SyntaxError("invalid 'fromVersion' constraint '%s': %s", constraint, err.Error())
+	}
+	version, err := semver.NewVersion(fromVersion)
+	if err != nil {
+		return fail.InvalidRequestError("the version installed '%s' is not a semantic version, the upgrade requires a version satisfying '%s'", fromVersion, constraint)
+	}
+	if !c.Check(version) {
+		return fail.InvalidRequestError("the version installed '%s' does not satisfy '%s'", fromVersion, constraint)
+	}
+	return nil
+}
+
 // GetRequirements returns a list of features needed as requirements
 func (f *feature) GetRequirements() ([]string, fail.Error) {
 	if f.IsNull() {
 		return nil, fail.InvalidInstanceError()
 	}
-	return nil, fail.NotImplementedError("GetRequirements() is not yet implemented")
+	return f.specs.GetStringSlice("feature.requirements.features"), nil
 }
 
-// installRequirements walks through requirements and installs them if needed
+// installRequirements resolves the graph of requirements and installs the missing ones
+// Requirements not depending on each other are installed concurrently
 func (f *feature) installRequirements(t resources.Targetable, v data.Map, s resources.FeatureSettings) fail.Error {
-	yamlKey := "feature.requirements.features"
-	if f.specs.IsSet(yamlKey) {
-		{
-			msgHead := fmt.Sprintf("Checking requirements of feature '%s'", f.GetName())
-			var msgTail string
-			switch t.TargetType() {
-			case featuretargettype.HOST:
-				msgTail = fmt.Sprintf("on host '%s'", t.(data.Identifiable).GetName())
-			case featuretargettype.NODE:
-				msgTail = fmt.Sprintf("on cluster node '%s'", t.(data.Identifiable).GetName())
-			case featuretargettype.CLUSTER:
-				msgTail = fmt.Sprintf("on cluster '%s'", t.(data.Identifiable).GetName())
-			}
-			logrus.Debugf("%s %s...", msgHead, msgTail)
-		}
-		for _, requirement := range f.specs.GetStringSlice(yamlKey) {
-			needed, xerr := NewFeature(f.task, requirement)
-			if xerr != nil {
-				return fail.Wrap(xerr, "failed to find required feature '%s'", requirement)
-			}
-			results, xerr := needed.Check(t, v, s)
-			if xerr != nil {
-				return fail.Wrap(xerr, "failed to check required feature '%s' for feature '%s'", requirement, f.GetName())
-			}
-			if !results.Successful() {
-				results, xerr := needed.Add(t, v, s)
-				if xerr != nil {
-					return fail.Wrap(xerr, "failed to install required feature '%s'", requirement)
-				}
-				if !results.Successful() {
-					return fail.NewError("failed to install required feature '%s':\n%s", requirement, results.AllErrorMessages())
-				}
-			}
+	requirements, xerr := f.GetRequirements()
+	if xerr != nil {
+		return xerr
+	}
+	if len(requirements) == 0 {
+		return nil
+	}
+
+	{
+		msgHead := fmt.Sprintf("Checking requirements of feature '%s'", f.GetName())
+		var msgTail string
+		switch t.TargetType() {
+		case featuretargettype.HOST:
+			msgTail = fmt.Sprintf("on host '%s'", t.(data.Identifiable).GetName())
+		case featuretargettype.NODE:
+			msgTail = fmt.Sprintf("on cluster node '%s'", t.(data.Identifiable).GetName())
+		case featuretargettype.CLUSTER:
+			msgTail = fmt.Sprintf("on cluster '%s'", t.(data.Identifiable).GetName())
 		}
+		logrus.Debugf("%s %s...", msgHead, msgTail)
 	}
-	return nil
+
+	// requirements are checked even if the feature is added unconditionally
+	rs := s
+	rs.AddUnconditionally = false
+	graph, xerr := newFeatureGraph(f.task, t, requirements, []string{f.GetName()}, v, rs)
+	if xerr != nil {
+		return xerr
+	}
+	return graph.Install()
 }
 
 // ToProtocol converts a feature to *protocol.FeatureResponse
@@ -590,6 +794,7 @@ func (f feature) ToProtocol() *protocol.FeatureResponse {
 	out := &protocol.FeatureResponse{
 		Name:     f.GetName(),
 		FileName: f.GetDisplayFilename(),
+		Version:  f.GetVersion(),
 	}
 	return out
 }
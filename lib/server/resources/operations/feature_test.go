@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/installaction"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
+	"github.com/CS-SI/SafeScale/lib/utils/data"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+func Test_checkUpgradeFromVersion(t *testing.T) {
+	assert.Nil(t, checkUpgradeFromVersion("", ""))
+	assert.Nil(t, checkUpgradeFromVersion("", "1.0.0"))
+	assert.Nil(t, checkUpgradeFromVersion(">= 1.2, < 2.0.0", "1.10.0"))
+
+	xerr := checkUpgradeFromVersion(">= 1.2, < 2.0.0", "2.0.1")
+	assert.IsType(t, &fail.ErrInvalidRequest{}, xerr)
+	assert.Contains(t, xerr.Error(), "does not satisfy")
+
+	xerr = checkUpgradeFromVersion(">= 1.2", "")
+	assert.IsType(t, &fail.ErrInvalidRequest{}, xerr)
+
+	xerr = checkUpgradeFromVersion(">= 1.2", "latest")
+	assert.IsType(t, &fail.ErrInvalidRequest{}, xerr)
+
+	xerr = checkUpgradeFromVersion("not a constraint", "1.0")
+	assert.IsType(t, &fail.ErrSyntax{}, xerr)
+}
+
+func Test_newFeatureHistoryEntry(t *testing.T) {
+	specs := viper.New()
+	specs.SetConfigType("yaml")
+	require.Nil(t, specs.ReadConfig(bytes.NewBufferString(`
+feature:
+    version: "1.2"
+    parameters:
+        - Port=8080
+        - AdminPassword
+        - APIToken
+`)))
+	f := feature{displayName: "history", specs: specs}
+
+	r := &results{}
+	require.Nil(t, r.AddOne("install", "host2", stepResult{completed: true, success: true, output: "ok", duration: 2 * time.Second}))
+	require.Nil(t, r.AddOne("install", "host1", stepResult{completed: true, retcode: 3, output: strings.Repeat("x", propertiesv1.MaxFeatureHistoryOutputLength+10)}))
+	require.Nil(t, r.AddOne("check", "host1", stepResult{err: errors.New("connection refused")}))
+
+	v := data.Map{"Port": 8080, "AdminPassword": "s3cr3t", "APIToken": "abc", "HostIP": "10.0.0.1"}
+	start := time.Now()
+	entry := newFeatureHistoryEntry(f, installaction.Add, v, start, r, nil)
+
+	assert.Equal(t, "history", entry.Feature)
+	assert.Equal(t, "1.2", entry.Version)
+	assert.Equal(t, "add", entry.Action)
+	assert.False(t, entry.Success)
+	assert.Equal(t, map[string]string{"Port": "8080", "AdminPassword": maskedFeatureParameter, "APIToken": maskedFeatureParameter}, entry.Parameters)
+	assert.Equal(t, start, entry.Start)
+
+	// steps are kept in order of execution, hosts are sorted
+	require.Len(t, entry.Steps, 3)
+	assert.Equal(t, "install", entry.Steps[0].Step)
+	assert.Equal(t, "host1", entry.Steps[0].Host)
+	assert.Equal(t, 3, entry.Steps[0].ExitCode)
+	assert.Equal(t, "exited with error 3", entry.Steps[0].Error)
+	assert.True(t, strings.HasPrefix(entry.Steps[0].Output, "[...]"))
+	assert.Equal(t, "host2", entry.Steps[1].Host)
+	assert.True(t, entry.Steps[1].Success)
+	assert.Equal(t, "ok", entry.Steps[1].Output)
+	assert.Equal(t, 2*time.Second, entry.Steps[1].Duration)
+	assert.Equal(t, "check", entry.Steps[2].Step)
+	assert.Equal(t, "connection refused", entry.Steps[2].Error)
+
+	entry = newFeatureHistoryEntry(f, installaction.Remove, v, start, nil, fail.NotAvailableError("no way to remove"))
+	assert.Equal(t, "remove", entry.Action)
+	assert.False(t, entry.Success)
+	assert.Contains(t, entry.Error, "no way to remove")
+	assert.Empty(t, entry.Steps)
+}
+
+func Test_DryRunScripts(t *testing.T) {
+	assert.Empty(t, DryRunScripts(nil))
+
+	r := &results{}
+	require.Nil(t, r.AddOne("install", "host2", stepResult{completed: true, success: true, output: "echo install2"}))
+	require.Nil(t, r.AddOne("install", "host1", stepResult{completed: true, success: true, output: "echo install1"}))
+	require.Nil(t, r.AddOne("config", "host1", stepResult{completed: true, success: true, output: "echo config1"}))
+
+	assert.Equal(t, []FeatureScript{
+		{Step: "install", Host: "host1", Script: "echo install1"},
+		{Step: "install", Host: "host2", Script: "echo install2"},
+		{Step: "config", Host: "host1", Script: "echo config1"},
+	}, DryRunScripts(r))
+}
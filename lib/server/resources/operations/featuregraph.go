@@ -0,0 +1,298 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/data"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// FeatureGraph is the graph of the dependencies between features to install on a target
+// The features are installed by steps: the features of a step depend only on features of the previous steps,
+// so they are installed concurrently
+type FeatureGraph struct {
+	task     concurrency.Task
+	target   resources.Targetable
+	vars     data.Map
+	settings resources.FeatureSettings
+	nodes    map[string]*featureGraphNode
+	pinned   map[string]string // versions explicitly requested, indexed by feature name
+	checked  map[string]bool   // results of checks, indexed by feature reference
+	restart  bool              // tells the resolution has to restart, a version of feature having been pinned
+	steps    [][]string
+}
+
+// featureGraphNode is a feature of the graph
+type featureGraphNode struct {
+	feature  resources.Feature
+	requires []string // names of the required features
+	present  bool     // tells if the feature is already installed on the target
+}
+
+// NewFeatureGraph resolves the dependencies of the features 'names' (as 'name' or 'name@version') to install on target
+// errors returned :
+// - fail.ErrNotFound if a feature cannot be found
+// - fail.ErrInconsistent if a cycle exists in the requirements, or if different versions of a feature are requested
+func NewFeatureGraph(task concurrency.Task, target resources.Targetable, names []string, vars data.Map, settings resources.FeatureSettings) (*FeatureGraph, fail.Error) {
+	if task.IsNull() {
+		return nil, fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+	if target == nil && !settings.AddUnconditionally {
+		return nil, fail.InvalidParameterError("target", "cannot be nil")
+	}
+	if len(names) == 0 {
+		return nil, fail.InvalidParameterError("names", "cannot be empty slice")
+	}
+
+	return newFeatureGraph(task, target, names, nil, vars, settings)
+}
+
+// newFeatureGraph resolves the dependencies of the features 'refs'; 'path' contains the names of the features requiring them
+func newFeatureGraph(task concurrency.Task, target resources.Targetable, refs, path []string, vars data.Map, settings resources.FeatureSettings) (*FeatureGraph, fail.Error) {
+	g := &FeatureGraph{
+		task:     task,
+		target:   target,
+		vars:     vars,
+		settings: settings,
+		pinned:   map[string]string{},
+		checked:  map[string]bool{},
+	}
+
+	// Each restart pins a new version, so the loop ends
+	for restart := true; restart; restart = g.restart {
+		g.nodes = map[string]*featureGraphNode{}
+		g.restart = false
+		for _, ref := range refs {
+			if xerr := g.resolve(ref, path); xerr != nil {
+				return nil, xerr
+			}
+		}
+	}
+
+	g.steps = g.computeSteps()
+	return g, nil
+}
+
+// resolve adds the feature 'ref' and its requirements to the graph
+func (g *FeatureGraph) resolve(ref string, path []string) fail.Error {
+	name, version := splitFeatureReference(ref)
+	for i, v := range path {
+		if v == name {
+			return fail.InconsistentError("cycle detected in feature requirements: %s", strings.Join(append(path[i:], name), " -> "))
+		}
+	}
+
+	if version != "" {
+		if pinned, ok := g.pinned[name]; ok && pinned != version {
+			return fail.InconsistentError("conflicting versions of feature '%s' requested: '%s' and '%s'", name, pinned, version)
+		}
+	} else {
+		version = g.pinned[name]
+	}
+
+	if node, ok := g.nodes[name]; ok {
+		if version == "" || node.feature.GetVersion() == version {
+			return nil
+		}
+		// The feature has been resolved with the highest version available, which is not the one requested
+		g.pinned[name] = version
+		g.restart = true
+		return nil
+	}
+	if version != "" {
+		g.pinned[name] = version
+		ref = name + "@" + version
+	}
+
+	feat, xerr := NewFeature(g.task, ref)
+	if xerr != nil {
+		if len(path) > 0 {
+			return fail.Wrap(xerr, "failed to find feature '%s' required by feature '%s'", ref, path[len(path)-1])
+		}
+		return xerr
+	}
+	requirements, xerr := feat.GetRequirements()
+	if xerr != nil {
+		return xerr
+	}
+
+	node := &featureGraphNode{feature: feat}
+	g.nodes[name] = node
+	if !g.settings.AddUnconditionally {
+		if node.present, xerr = g.check(ref, feat); xerr != nil {
+			return xerr
+		}
+		if node.present {
+			// Requirements of an installed feature are considered as installed
+			return nil
+		}
+	}
+
+	path = append(path[:len(path):len(path)], name)
+	for _, v := range requirements {
+		if xerr = g.resolve(v, path); xerr != nil {
+			return xerr
+		}
+		required, _ := splitFeatureReference(v)
+		node.requires = append(node.requires, required)
+	}
+	return nil
+}
+
+// check tells if the feature is installed on the target, keeping the result across restarts of resolution
+func (g *FeatureGraph) check(ref string, feat resources.Feature) (bool, fail.Error) {
+	if present, ok := g.checked[ref]; ok {
+		return present, nil
+	}
+	results, xerr := feat.Check(g.target, g.vars, g.settings)
+	if xerr != nil {
+		return false, fail.Wrap(xerr, "failed to check feature '%s'", feat.GetName())
+	}
+	g.checked[ref] = results.Successful()
+	return g.checked[ref], nil
+}
+
+// computeSteps orders the features to install by steps; the graph has no cycle
+func (g *FeatureGraph) computeSteps() [][]string {
+	levels := map[string]int{}
+	var levelOf func(name string) int
+	levelOf = func(name string) int {
+		if level, ok := levels[name]; ok {
+			return level
+		}
+		level := 0
+		for _, v := range g.nodes[name].requires {
+			if g.nodes[v].present {
+				continue
+			}
+			if l := levelOf(v) + 1; l > level {
+				level = l
+			}
+		}
+		levels[name] = level
+		return level
+	}
+
+	var steps [][]string
+	for name, node := range g.nodes {
+		if node.present {
+			continue
+		}
+		level := levelOf(name)
+		for len(steps) <= level {
+			steps = append(steps, []string{})
+		}
+		steps[level] = append(steps[level], name)
+	}
+	for _, v := range steps {
+		sort.Strings(v)
+	}
+	return steps
+}
+
+// reference returns the reference 'name@version' of the feature named 'name', or 'name' if the feature is not versioned
+func (g FeatureGraph) reference(name string) string {
+	if version := g.nodes[name].feature.GetVersion(); version != "" {
+		return name + "@" + version
+	}
+	return name
+}
+
+// Plan returns the references of the features to install, by step
+func (g FeatureGraph) Plan() [][]string {
+	out := make([][]string, 0, len(g.steps))
+	for _, step := range g.steps {
+		refs := make([]string, 0, len(step))
+		for _, v := range step {
+			refs = append(refs, g.reference(v))
+		}
+		out = append(out, refs)
+	}
+	return out
+}
+
+// Present returns the references of the features already installed, that will be skipped
+func (g FeatureGraph) Present() []string {
+	var out []string
+	for name, node := range g.nodes {
+		if node.present {
+			out = append(out, g.reference(name))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Install installs the features step by step, the features of a step being installed concurrently
+// Installation stops at the end of the first step in failure
+func (g *FeatureGraph) Install() fail.Error {
+	settings := g.settings
+	settings.SkipFeatureRequirements = true
+	settings.AddUnconditionally = true
+
+	for i, step := range g.steps {
+		logrus.Debugf("Installing features step %d/%d: %s", i+1, len(g.steps), strings.Join(step, ", "))
+
+		tg, xerr := concurrency.NewTaskGroup(g.task)
+		if xerr != nil {
+			return xerr
+		}
+		var errors []error
+		for _, name := range step {
+			params := taskInstallFeatureParameters{feature: g.nodes[name].feature, settings: settings}
+			if _, xerr = tg.Start(g.taskInstallFeature, params); xerr != nil {
+				errors = append(errors, xerr)
+			}
+		}
+		if _, xerr = tg.Wait(); xerr != nil {
+			errors = append(errors, xerr)
+		}
+		if len(errors) > 0 {
+			return fail.NewErrorList(errors)
+		}
+	}
+	return nil
+}
+
+type taskInstallFeatureParameters struct {
+	feature  resources.Feature
+	settings resources.FeatureSettings
+}
+
+// taskInstallFeature installs a feature of the graph
+func (g *FeatureGraph) taskInstallFeature(task concurrency.Task, params concurrency.TaskParameters) (_ concurrency.TaskResult, xerr fail.Error) {
+	p, ok := params.(taskInstallFeatureParameters)
+	if !ok {
+		return nil, fail.InvalidParameterError("params", "must be a 'taskInstallFeatureParameters'")
+	}
+
+	results, xerr := p.feature.Add(g.target, g.vars, p.settings)
+	if xerr != nil {
+		return nil, fail.Wrap(xerr, "failed to install feature '%s'", p.feature.GetName())
+	}
+	if !results.Successful() {
+		return nil, fail.NewError("failed to install feature '%s':\n%s", p.feature.GetName(), results.AllErrorMessages())
+	}
+	return nil, nil
+}
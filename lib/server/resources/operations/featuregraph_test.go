@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+const graphFeature = `
+feature:
+    version: "%s"
+    suitableFor:
+        host: yes
+    requirements:
+        features: [%s]
+    install:
+        bash:
+            add:
+                pace: install
+                steps:
+                    install:
+                        targets:
+                            hosts: yes
+                        run: |
+                            echo installed
+`
+
+// setupFeatureGraph registers a local repository containing the features 'specs' (requirements indexed by 'name@version')
+func setupFeatureGraph(t *testing.T, task concurrency.Task, specs map[string][]string) func() {
+	root, err := ioutil.TempDir("", "safescale-feature-graph")
+	require.Nil(t, err)
+
+	previous := featureRepositoriesFolder
+	featureRepositoriesFolder = filepath.Join(root, "config")
+
+	repo := filepath.Join(root, "repo")
+	for ref, requires := range specs {
+		name, version := splitFeatureReference(ref)
+		require.Nil(t, os.MkdirAll(filepath.Join(repo, name), 0755))
+		content := fmt.Sprintf(graphFeature, version, strings.Join(requires, ", "))
+		require.Nil(t, ioutil.WriteFile(filepath.Join(repo, name, version+featureFileExt), []byte(content), 0644))
+	}
+	_, xerr := AddFeatureRepository(task, FeatureRepository{Name: "graph", URL: repo})
+	require.Nil(t, xerr)
+
+	return func() {
+		featureRepositoriesFolder = previous
+		_ = os.RemoveAll(root)
+	}
+}
+
+func Test_NewFeatureGraph(t *testing.T) {
+	task, xerr := concurrency.NewTask()
+	require.Nil(t, xerr)
+
+	cleanup := setupFeatureGraph(t, task, map[string][]string{
+		"app@1.0":    {"web", "db"},
+		"web@1.0":    {"base"},
+		"db@1.0":     {"base@1.0"},
+		"base@1.0":   {},
+		"base@2.0":   {},
+		"other@1.0":  {"base@2.0"},
+		"loop-a@1.0": {"loop-b"},
+		"loop-b@1.0": {"loop-c"},
+		"loop-c@1.0": {"loop-a"},
+	})
+	defer cleanup()
+
+	settings := resources.FeatureSettings{AddUnconditionally: true}
+
+	graph, xerr := NewFeatureGraph(task, nil, []string{"app"}, nil, settings)
+	require.Nil(t, xerr)
+	assert.Equal(t, [][]string{{"base@1.0"}, {"db@1.0", "web@1.0"}, {"app@1.0"}}, graph.Plan())
+	assert.Empty(t, graph.Present())
+
+	graph, xerr = NewFeatureGraph(task, nil, []string{"web", "base"}, nil, settings)
+	require.Nil(t, xerr)
+	assert.Equal(t, [][]string{{"base@2.0"}, {"web@1.0"}}, graph.Plan())
+
+	_, xerr = NewFeatureGraph(task, nil, []string{"app", "other"}, nil, settings)
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrInconsistent{}, xerr)
+	assert.Contains(t, xerr.Error(), "conflicting versions of feature 'base'")
+
+	_, xerr = NewFeatureGraph(task, nil, []string{"loop-a"}, nil, settings)
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrInconsistent{}, xerr)
+	assert.Contains(t, xerr.Error(), "loop-a -> loop-b -> loop-c -> loop-a")
+
+	_, xerr = newFeatureGraph(task, nil, []string{"app"}, []string{"base"}, nil, settings)
+	require.NotNil(t, xerr)
+	assert.Contains(t, xerr.Error(), "base -> app -> web -> base")
+
+	_, xerr = NewFeatureGraph(task, nil, []string{"missing"}, nil, settings)
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrNotFound{}, xerr)
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/installaction"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
+	"github.com/CS-SI/SafeScale/lib/utils/data"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
+)
+
+const maskedFeatureParameter = secret.Masked
+
+// secretFeatureParameter matches the names of the parameters whose value must not be kept in the history
+var secretFeatureParameter = regexp.MustCompile(`(?i)(pass|secret|token|credential|key)`)
+
+// featureHistoryParameters returns the values of the parameters declared by the feature, the secrets being masked
+func featureHistoryParameters(f feature, v data.Map) map[string]string {
+	out := map[string]string{}
+	for _, k := range f.specs.GetStringSlice("feature.parameters") {
+		name := strings.Split(k, "=")[0]
+		value, ok := v[name]
+		if !ok {
+			continue
+		}
+		if secretFeatureParameter.MatchString(name) {
+			out[name] = maskedFeatureParameter
+			continue
+		}
+		out[name] = fmt.Sprintf("%v", value)
+	}
+	return out
+}
+
+// newFeatureHistoryEntry builds the record of the action 'action' on the feature, from its results
+func newFeatureHistoryEntry(f feature, action installaction.Enum, v data.Map, start time.Time, r resources.Results, xerr fail.Error) *propertiesv1.FeatureHistoryEntry {
+	entry := &propertiesv1.FeatureHistoryEntry{
+		Feature:    f.GetName(),
+		Version:    f.GetVersion(),
+		Action:     strings.ToLower(action.String()),
+		Parameters: featureHistoryParameters(f, v),
+		Start:      start,
+		End:        time.Now(),
+		Steps:      []propertiesv1.FeatureStepResult{},
+	}
+	if xerr != nil {
+		entry.Error = xerr.Error()
+	}
+	if r == nil {
+		return entry
+	}
+
+	entry.Success = xerr == nil && r.Successful()
+	for _, step := range r.Keys() {
+		urs, ok := r.ResultsOfKey(step).(*unitResults)
+		if !ok {
+			continue
+		}
+		hosts := make([]string, 0, len(*urs))
+		for k := range *urs {
+			hosts = append(hosts, k)
+		}
+		sort.Strings(hosts)
+		for _, h := range hosts {
+			ur := (*urs)[h]
+			item := propertiesv1.FeatureStepResult{
+				Step:      step,
+				Host:      h,
+				Completed: ur.Completed(),
+				Success:   ur.Successful(),
+				Error:     ur.ErrorMessage(),
+			}
+			if sr, ok := ur.(stepResult); ok {
+				item.ExitCode = sr.retcode
+				item.Output = propertiesv1.TruncateFeatureOutput(sr.output)
+				item.Duration = sr.duration
+			}
+			entry.Steps = append(entry.Steps, item)
+		}
+	}
+	return entry
+}
+
+// recordHistory records the action on the feature in the metadata of the target
+// A failure to record is logged but does not fail the action
+func (f feature) recordHistory(target resources.Targetable, entry *propertiesv1.FeatureHistoryEntry) {
+	if xerr := target.RecordFeatureHistory(f.task, entry); xerr != nil {
+		logrus.Warnf("failed to record %s of feature '%s' in history of %s '%s': %v", entry.Action, entry.Feature, target.TargetType().String(), target.GetName(), xerr)
+	}
+}
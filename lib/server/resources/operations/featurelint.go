@@ -0,0 +1,574 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"github.com/Masterminds/semver"
+	"github.com/spf13/viper"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterflavor"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/installaction"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/installmethod"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/template"
+)
+
+// FeatureLintIssue describes a problem found in the specification of a feature
+type FeatureLintIssue struct {
+	Key     string // yaml key concerned by the issue
+	Message string
+	Warning bool // if false, the issue is an error preventing the feature to work
+}
+
+// String returns a human readable form of the issue
+func (i FeatureLintIssue) String() string {
+	level := "error"
+	if i.Warning {
+		level = "warning"
+	}
+	if i.Key == "" {
+		return level + ": " + i.Message
+	}
+	return fmt.Sprintf("%s: %s: %s", level, i.Key, i.Message)
+}
+
+// FeatureLintIssues is a list of FeatureLintIssue
+type FeatureLintIssues []FeatureLintIssue
+
+// HasErrors tells if at least one issue is an error
+func (l FeatureLintIssues) HasErrors() bool {
+	for _, v := range l {
+		if !v.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// featureKnownKeys lists the keys allowed directly under 'feature'
+	featureKnownKeys = map[string]bool{"suitableFor": true, "requirements": true, "parameters": true, "install": true, "proxy": true, "service": true, "version": true}
+	// featureStepKnownKeys lists the keys allowed in a step
+	featureStepKnownKeys = map[string]bool{yamlTargetsKeyword: true, yamlRunKeyword: true, yamlPackageKeyword: true, yamlOptionsKeyword: true, yamlTimeoutKeyword: true, yamlSerialKeyword: true}
+	// featureBuiltinVariables lists the variables set by SafeScale, usable in feature scripts without being declared as parameters
+	featureBuiltinVariables = map[string]bool{
+		"CIDR": true, "ClusterAdminPassword": true, "ClusterAdminUsername": true, "ClusterComplexity": true,
+		"ClusterControlplaneEndpointIP": true, "ClusterControlplaneUsesVIP": true, "ClusterFlavor": true,
+		"ClusterMasterIDs": true, "ClusterMasterIPs": true, "ClusterMasterNames": true, "ClusterMasters": true,
+		"ClusterName": true, "ClusterNodeIDs": true, "ClusterNodeIPs": true, "ClusterNodeNames": true, "ClusterNodes": true,
+		"DefaultRouteIP": true, "EndpointIP": true, "FromVersion": true, "GatewayIP": true, "HostIP": true, "Hostname": true, "IPRanges": true,
+		"NetworkUsesVIP": true, "Password": true, "PrimaryGatewayIP": true, "PrimaryPublicIP": true, "PublicIP": true,
+		"SecondaryGatewayIP": true, "SecondaryPublicIP": true, "ShortHostname": true, "ToVersion": true, "Username": true, "options": true,
+	}
+)
+
+// LintFeature checks the specification of a feature and returns the issues found
+// If content is not empty, it's used as the content of the specification file of the feature named 'name'; otherwise
+// the feature is searched like NewFeature() does.
+// An error is returned only if the feature cannot be found; syntax errors are reported as issues.
+func LintFeature(task concurrency.Task, name, content string) (FeatureLintIssues, fail.Error) {
+	if task.IsNull() {
+		return nil, fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+	if name == "" {
+		return nil, fail.InvalidParameterError("name", "cannot be empty string")
+	}
+
+	var specs *viper.Viper
+	if content != "" {
+		specs = viper.New()
+		specs.SetConfigType("yaml")
+		if err := specs.ReadConfig(bytes.NewBufferString(content)); err != nil {
+			return FeatureLintIssues{{Message: fmt.Sprintf("invalid YAML: %s", err.Error())}}, nil
+		}
+	} else {
+		feat, xerr := NewFeature(task, name)
+		if xerr != nil {
+			switch xerr.(type) {
+			case *fail.ErrSyntax:
+				return FeatureLintIssues{{Message: xerr.Error()}}, nil
+			default:
+				return nil, xerr
+			}
+		}
+		specs = feat.(*feature).Specs()
+	}
+
+	l := featureLinter{task: task, name: name, specs: specs}
+	l.lint()
+	return l.issues, nil
+}
+
+// featureLinter contains the context of the checks of a feature specification
+type featureLinter struct {
+	task   concurrency.Task
+	name   string
+	specs  *viper.Viper
+	issues FeatureLintIssues
+	// ruleNames contains the names of the proxy rules, usable as variables (set to the ID of the rule once applied)
+	ruleNames map[string]bool
+}
+
+func (l *featureLinter) error(key, format string, args ...interface{}) {
+	l.issues = append(l.issues, FeatureLintIssue{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *featureLinter) warning(key, format string, args ...interface{}) {
+	l.issues = append(l.issues, FeatureLintIssue{Key: key, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// lint runs all the checks
+func (l *featureLinter) lint() {
+	if !l.specs.IsSet("feature") {
+		l.error("", "specification file must begin with 'feature:'")
+		return
+	}
+	for k := range l.specs.GetStringMap("feature") {
+		if !featureKnownKeys[k] && !featureKnownKeys[lowerCamelCase(k)] {
+			l.warning("feature."+k, "unknown key")
+		}
+	}
+
+	l.lintSuitableFor()
+	usedVariables := l.lintInstall()
+	l.lintProxy(usedVariables)
+	l.lintParameters(usedVariables)
+	l.lintRequirements()
+}
+
+// lowerCamelCase returns the key of featureKnownKeys corresponding to k, viper lowering the keys
+func lowerCamelCase(k string) string {
+	for v := range featureKnownKeys {
+		if strings.ToLower(v) == k {
+			return v
+		}
+	}
+	return k
+}
+
+// lintSuitableFor checks the values of 'feature.suitableFor'
+func (l *featureLinter) lintSuitableFor() {
+	yamlKey := "feature.suitableFor.host"
+	if l.specs.IsSet(yamlKey) {
+		switch strings.ToLower(l.specs.GetString(yamlKey)) {
+		case "ok", "yes", "true", "1", "no", "false", "0":
+		default:
+			l.error(yamlKey, "invalid value '%s', must be 'yes' or 'no'", l.specs.GetString(yamlKey))
+		}
+	}
+
+	yamlKey = "feature.suitableFor.cluster"
+	if !l.specs.IsSet(yamlKey) {
+		l.warning("feature.suitableFor", "no key 'cluster', the feature is not suitable for any cluster")
+		return
+	}
+	for _, v := range strings.Split(l.specs.GetString(yamlKey), ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		switch v {
+		case "all", "no", "false", "none":
+			continue
+		}
+		if _, err := clusterflavor.Parse(v); err != nil {
+			l.error(yamlKey, "unknown cluster flavor '%s'", v)
+		}
+	}
+}
+
+// lintInstall checks the installers, their actions and their steps; returns the variables used in the scripts
+func (l *featureLinter) lintInstall() map[string]string {
+	used := map[string]string{}
+	methods := l.specs.GetStringMap("feature.install")
+	if len(methods) == 0 {
+		l.error("feature.install", "missing or empty key, at least one install method must be defined")
+		return used
+	}
+
+	for _, method := range sortedKeys(methods) {
+		methodKey := "feature.install." + method
+		m, err := installmethod.Parse(method)
+		if err != nil {
+			l.error(methodKey, "unknown install method")
+			continue
+		}
+		actions := l.specs.GetStringMap(methodKey)
+		for _, action := range sortedKeys(actions) {
+			if _, err := installaction.Parse(action); err != nil {
+				l.error(methodKey+"."+action, "unknown action, must be 'check', 'add', 'remove' or 'upgrade'")
+			}
+		}
+		if _, ok := actions["upgrade"]; ok {
+			l.lintUpgrade(methodKey+".upgrade", m, used)
+		}
+		for _, action := range []string{"check", "add", "remove"} {
+			if _, ok := actions[action]; !ok {
+				if action == "remove" {
+					l.warning(methodKey+"."+action, "missing action, the feature cannot be removed")
+				} else {
+					l.error(methodKey+"."+action, "missing action")
+				}
+				continue
+			}
+			l.lintAction(methodKey+"."+action, m, used)
+		}
+	}
+	return used
+}
+
+// lintUpgrade checks the upgrade action, which is optional
+func (l *featureLinter) lintUpgrade(actionKey string, method installmethod.Enum, used map[string]string) {
+	if l.specs.GetString("feature.version") == "" {
+		l.warning(actionKey, "upgrade action defined but no 'feature.version', the version installed cannot be tracked")
+	}
+	fromVersionKey := actionKey + ".fromVersion"
+	if l.specs.IsSet(fromVersionKey) {
+		if _, err := semver.NewConstraint(l.specs.GetString(fromVersionKey)); err != nil {
+			l.error(fromVersionKey, "invalid version constraint: %s", err.Error())
+		}
+	}
+	l.lintAction(actionKey, method, used)
+}
+
+// lintAction checks the consistency of pace and steps of an action
+func (l *featureLinter) lintAction(actionKey string, method installmethod.Enum, used map[string]string) {
+	paceKey := actionKey + "." + yamlPaceKeyword
+	pace := l.specs.GetString(paceKey)
+	if pace == "" {
+		l.error(paceKey, "missing or empty key")
+	}
+	stepsKey := actionKey + "." + yamlStepsKeyword
+	steps := l.specs.GetStringMap(stepsKey)
+	if len(steps) == 0 {
+		l.error(stepsKey, "missing or empty key")
+		return
+	}
+
+	inPace := map[string]bool{}
+	if pace != "" {
+		for _, v := range strings.Split(pace, ",") {
+			v = strings.ToLower(strings.TrimSpace(v))
+			if v == "" {
+				l.error(paceKey, "empty step name in '%s'", pace)
+				continue
+			}
+			if inPace[v] {
+				l.warning(paceKey, "step '%s' listed more than once", v)
+			}
+			inPace[v] = true
+			if _, ok := steps[v]; !ok {
+				l.error(paceKey, "step '%s' not defined in '%s'", v, stepsKey)
+			}
+		}
+	}
+
+	scriptKeyword := yamlRunKeyword
+	switch method {
+	case installmethod.Apt, installmethod.Yum, installmethod.Dnf:
+		scriptKeyword = yamlPackageKeyword
+	}
+	forCluster := l.suitableForCluster()
+	for _, name := range sortedKeys(steps) {
+		stepKey := stepsKey + "." + name
+		if !inPace[name] {
+			l.warning(stepKey, "step not listed in '%s', never executed", paceKey)
+		}
+		step, ok := steps[name].(map[string]interface{})
+		if !ok {
+			l.error(stepKey, "step must be a map")
+			continue
+		}
+		for k := range step {
+			if !featureStepKnownKeys[k] {
+				l.warning(stepKey+"."+k, "unknown key")
+			}
+		}
+
+		if anon, ok := step[yamlTargetsKeyword]; ok {
+			l.lintTargets(stepKey+"."+yamlTargetsKeyword, anon)
+		} else if forCluster {
+			l.error(stepKey, "missing key '%s', mandatory for a feature suitable for clusters", yamlTargetsKeyword)
+		}
+
+		script, ok := step[scriptKeyword].(string)
+		if !ok || strings.TrimSpace(script) == "" {
+			l.error(stepKey, "missing or empty key '%s'", scriptKeyword)
+		} else {
+			l.lintTemplate(stepKey+"."+scriptKeyword, script, used, true)
+		}
+
+		if anon, ok := step[yamlTimeoutKeyword]; ok {
+			switch v := anon.(type) {
+			case int:
+			case string:
+				if _, err := strconv.Atoi(v); err != nil {
+					l.error(stepKey+"."+yamlTimeoutKeyword, "invalid value '%s', must be a number of minutes", v)
+				}
+			default:
+				l.error(stepKey+"."+yamlTimeoutKeyword, "invalid value '%v', must be a number of minutes", anon)
+			}
+		}
+		if anon, ok := step[yamlSerialKeyword]; ok {
+			switch v := anon.(type) {
+			case bool:
+			case string:
+				switch strings.ToLower(v) {
+				case "yes", "no", "true", "false":
+				default:
+					l.error(stepKey+"."+yamlSerialKeyword, "invalid value '%s', must be 'yes' or 'no'", v)
+				}
+			default:
+				l.error(stepKey+"."+yamlSerialKeyword, "invalid value '%v', must be 'yes' or 'no'", anon)
+			}
+		}
+	}
+}
+
+// suitableForCluster tells if the feature is suitable for at least one cluster flavor
+func (l *featureLinter) suitableForCluster() bool {
+	switch strings.ToLower(l.specs.GetString("feature.suitableFor.cluster")) {
+	case "", "no", "false", "none":
+		return false
+	}
+	return true
+}
+
+// lintTargets checks the keywords and values of the targets of a step or of a proxy rule
+func (l *featureLinter) lintTargets(key string, anon interface{}) {
+	targets := stepTargets{}
+	switch list := anon.(type) {
+	case map[string]interface{}:
+		for k, v := range list {
+			targets[k] = targetValueToString(v)
+		}
+	case map[interface{}]interface{}:
+		for k, v := range list {
+			targets[fmt.Sprintf("%v", k)] = targetValueToString(v)
+		}
+	default:
+		l.error(key, "targets must be a map")
+		return
+	}
+
+	for k := range targets {
+		switch k {
+		case targetHosts, targetMasters, targetNodes, targetGateways:
+		default:
+			l.error(key+"."+k, "unknown target, must be one of '%s', '%s', '%s' or '%s'", targetHosts, targetMasters, targetNodes, targetGateways)
+			delete(targets, k)
+		}
+	}
+	if _, _, _, _, xerr := targets.parse(); xerr != nil {
+		l.error(key, xerr.Error())
+	}
+}
+
+// targetValueToString converts the value of a target like the worker does
+func targetValueToString(v interface{}) string {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// lintTemplate checks the syntax of a template and collects the variables it uses
+func (l *featureLinter) lintTemplate(key, content string, used map[string]string, reportErrors bool) {
+	tmpl, xerr := template.Parse(key, content)
+	if xerr != nil {
+		if reportErrors {
+			l.error(key, "invalid template: %s", xerr.Error())
+		}
+		return
+	}
+	fields := map[string]bool{}
+	collectTemplateFields(tmpl.Tree.Root, true, fields)
+	for k := range fields {
+		if _, ok := used[k]; !ok {
+			used[k] = key
+		}
+	}
+}
+
+// collectTemplateFields collects the names of the fields of the data passed to the template
+// Fields are collected only where dot is the data itself (not inside 'range' or 'with'), or through '$'
+func collectTemplateFields(node parse.Node, topScope bool, fields map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, v := range n.Nodes {
+			collectTemplateFields(v, topScope, fields)
+		}
+	case *parse.ActionNode:
+		collectTemplateFields(n.Pipe, topScope, fields)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, v := range n.Cmds {
+			collectTemplateFields(v, topScope, fields)
+		}
+	case *parse.CommandNode:
+		for _, v := range n.Args {
+			collectTemplateFields(v, topScope, fields)
+		}
+	case *parse.ChainNode:
+		collectTemplateFields(n.Node, topScope, fields)
+	case *parse.FieldNode:
+		if topScope && len(n.Ident) > 0 {
+			fields[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			fields[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		collectTemplateFields(n.Pipe, topScope, fields)
+		collectTemplateFields(n.List, topScope, fields)
+		collectTemplateFields(n.ElseList, topScope, fields)
+	case *parse.RangeNode:
+		collectTemplateFields(n.Pipe, topScope, fields)
+		collectTemplateFields(n.List, false, fields)
+		collectTemplateFields(n.ElseList, topScope, fields)
+	case *parse.WithNode:
+		collectTemplateFields(n.Pipe, topScope, fields)
+		collectTemplateFields(n.List, false, fields)
+		collectTemplateFields(n.ElseList, topScope, fields)
+	case *parse.TemplateNode:
+		collectTemplateFields(n.Pipe, topScope, fields)
+	}
+}
+
+// lintProxy checks the targets of the reverse proxy rules and collects the variables used in the rules
+func (l *featureLinter) lintProxy(used map[string]string) {
+	rules, ok := l.specs.Get("feature.proxy.rules").([]interface{})
+	if !ok {
+		return
+	}
+	for i, r := range rules {
+		ruleKey := fmt.Sprintf("feature.proxy.rules[%d]", i)
+		rule, ok := r.(map[interface{}]interface{})
+		if !ok {
+			l.error(ruleKey, "rule must be a map")
+			continue
+		}
+		if anon, ok := rule["targets"]; ok {
+			l.lintTargets(ruleKey+".targets", anon)
+		}
+		if name, ok := rule["name"].(string); ok && !strings.Contains(name, "{{") {
+			if l.ruleNames == nil {
+				l.ruleNames = map[string]bool{}
+			}
+			l.ruleNames[strings.TrimSpace(name)] = true
+		}
+		for k, v := range rule {
+			if s, ok := v.(string); ok && strings.Contains(s, "{{") {
+				l.lintTemplate(fmt.Sprintf("%s.%v", ruleKey, k), s, used, true)
+			}
+		}
+	}
+}
+
+// lintParameters checks the declarations of the parameters against their use in the templates
+func (l *featureLinter) lintParameters(used map[string]string) {
+	declared := map[string]bool{}
+	for _, v := range l.specs.GetStringSlice("feature.parameters") {
+		name := strings.TrimSpace(strings.SplitN(v, "=", 2)[0])
+		if name == "" {
+			l.error("feature.parameters", "invalid parameter declaration '%s'", v)
+			continue
+		}
+		if declared[name] {
+			l.warning("feature.parameters", "parameter '%s' declared more than once", name)
+		}
+		declared[name] = true
+	}
+
+	for _, k := range sortedKeys(used) {
+		if !declared[k] && !featureBuiltinVariables[k] && !l.ruleNames[k] {
+			l.error(used[k], "variable '%s' used but not declared in 'feature.parameters'", k)
+		}
+	}
+	for _, k := range sortedKeys(declared) {
+		if _, ok := used[k]; !ok {
+			l.warning("feature.parameters", "parameter '%s' declared but never used", k)
+		}
+	}
+}
+
+// lintRequirements checks that the required features exist and that there is no cycle in requirements
+func (l *featureLinter) lintRequirements() {
+	var walk func(name string, requirements []string, path []string)
+	visited := map[string]bool{}
+	walk = func(name string, requirements []string, path []string) {
+		path = append(path, name)
+		for _, r := range requirements {
+			for _, p := range path {
+				if p == r {
+					l.error("feature.requirements.features", "cycle in requirements: %s -> %s", strings.Join(path, " -> "), r)
+					return
+				}
+			}
+			if visited[r] {
+				continue
+			}
+			visited[r] = true
+			feat, xerr := NewFeature(l.task, r)
+			if xerr != nil {
+				l.error("feature.requirements.features", "failed to find required feature '%s' (required by '%s')", r, name)
+				continue
+			}
+			walk(r, feat.(*feature).Specs().GetStringSlice("feature.requirements.features"), path)
+		}
+	}
+	walk(l.name, l.specs.GetStringSlice("feature.requirements.features"), nil)
+}
+
+// sortedKeys returns the keys of a map sorted, to produce reproducible reports
+func sortedKeys(m interface{}) []string {
+	var out []string
+	switch m := m.(type) {
+	case map[string]interface{}:
+		for k := range m {
+			out = append(out, k)
+		}
+	case map[string]string:
+		for k := range m {
+			out = append(out, k)
+		}
+	case map[string]bool:
+		for k := range m {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
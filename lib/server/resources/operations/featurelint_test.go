@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+)
+
+const lintedFeature = `
+feature:
+    suitableFor:
+        host: yes
+        cluster: k8s,swarm
+    requirements:
+        features:
+            - linted
+    parameters:
+        - Port=8080
+        - Unused
+    install:
+        bash:
+            check:
+                pace: check
+                steps:
+                    check:
+                        targets:
+                            hosts: yes
+                            masters: all
+                        run: |
+                            curl http://{{ .HostIP }}:{{ .Port }}/
+            add:
+                pace: install,strat
+                steps:
+                    install:
+                        targets:
+                            host: yes
+                            nodes: some
+                        run: |
+                            {{ range .ClusterNodeIPs }}echo {{ .Name }}{{ end }}
+                            echo {{ .Undeclared }} {{ $.Other }}
+                    start:
+                        targets:
+                            hosts: yes
+                        run: |
+                            systemctl start linted
+`
+
+func lintMessages(issues FeatureLintIssues) string {
+	var list []string
+	for _, v := range issues {
+		list = append(list, v.String())
+	}
+	return strings.Join(list, "\n")
+}
+
+func Test_LintFeature(t *testing.T) {
+	task, xerr := concurrency.NewTask()
+	require.Nil(t, xerr)
+
+	issues, xerr := LintFeature(task, "linted", lintedFeature)
+	require.Nil(t, xerr)
+	require.True(t, issues.HasErrors())
+	report := lintMessages(issues)
+
+	require.Contains(t, report, "error: feature.suitableFor.cluster: unknown cluster flavor 'swarm'")
+	require.Contains(t, report, "cycle in requirements: linted -> linted")
+	require.Contains(t, report, "error: feature.install.bash.add.pace: step 'strat' not defined")
+	require.Contains(t, report, "warning: feature.install.bash.add.steps.start: step not listed")
+	require.Contains(t, report, "warning: feature.install.bash.remove: missing action")
+	require.Contains(t, report, "error: feature.install.bash.add.steps.install.targets.host: unknown target")
+	require.Contains(t, report, "invalid value 'some' for target 'nodes'")
+	require.Contains(t, report, "variable 'Undeclared' used but not declared")
+	require.Contains(t, report, "variable 'Other' used but not declared")
+	require.Contains(t, report, "parameter 'Unused' declared but never used")
+	require.NotContains(t, report, "'Name'")
+	require.NotContains(t, report, "'HostIP'")
+	require.NotContains(t, report, "'Port'")
+}
+
+func Test_LintFeature_InvalidYAML(t *testing.T) {
+	task, xerr := concurrency.NewTask()
+	require.Nil(t, xerr)
+
+	issues, xerr := LintFeature(task, "broken", "feature:\n  install: [\n")
+	require.Nil(t, xerr)
+	require.True(t, issues.HasErrors())
+
+	issues, xerr = LintFeature(task, "empty", "other: true\n")
+	require.Nil(t, xerr)
+	require.True(t, issues.HasErrors())
+}
+
+const upgradedFeature = `
+feature:
+    suitableFor:
+        host: yes
+    install:
+        bash:
+            check:
+                pace: check
+                steps:
+                    check:
+                        targets:
+                            hosts: yes
+                        run: |
+                            which upgraded
+            add:
+                pace: install
+                steps:
+                    install:
+                        targets:
+                            hosts: yes
+                        run: |
+                            echo install
+            remove:
+                pace: remove
+                steps:
+                    remove:
+                        targets:
+                            hosts: yes
+                        run: |
+                            echo remove
+            upgrade:
+                fromVersion: "from 1.0"
+                pace: upgrade
+                steps:
+                    upgrade:
+                        targets:
+                            hosts: yes
+                        run: |
+                            echo {{ .FromVersion }} {{ .ToVersion }}
+            downgrade:
+                pace: downgrade
+`
+
+func Test_LintFeature_Upgrade(t *testing.T) {
+	task, xerr := concurrency.NewTask()
+	require.Nil(t, xerr)
+
+	issues, xerr := LintFeature(task, "upgraded", upgradedFeature)
+	require.Nil(t, xerr)
+	report := lintMessages(issues)
+
+	require.Contains(t, report, "warning: feature.install.bash.upgrade: upgrade action defined but no 'feature.version'")
+	require.Contains(t, report, "error: feature.install.bash.upgrade.fromVersion: invalid version constraint")
+	require.Contains(t, report, "error: feature.install.bash.downgrade: unknown action")
+	require.NotContains(t, report, "'FromVersion'")
+	require.NotContains(t, report, "'ToVersion'")
+}
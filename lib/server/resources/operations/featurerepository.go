@@ -0,0 +1,555 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/temporal"
+)
+
+const (
+	// FeatureRepositoryTypeGit is the type of a repository cloned from a git URL
+	FeatureRepositoryTypeGit = "git"
+	// FeatureRepositoryTypeHTTP is the type of a repository described by an HTTP index
+	FeatureRepositoryTypeHTTP = "http"
+	// FeatureRepositoryTypeLocal is the type of a repository using directly a local folder
+	FeatureRepositoryTypeLocal = "local"
+
+	featureRepositoriesFile = "repositories.json"
+)
+
+var (
+	// featureRepositoriesFolder is the folder containing the configuration of the repositories and their local copies
+	featureRepositoriesFolder = "$HOME/.safescale/feature-repositories"
+	featureRepositoriesLock   sync.Mutex
+
+	featureRepositoryNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+// FeatureRepository describes a repository of features
+// The features are stored in the repository as '<name>.yml' (version given by key 'feature.version')
+// or as '<name>/<version>.yml' if several versions are available
+type FeatureRepository struct {
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	URL     string    `json:"url"`
+	Ref     string    `json:"ref,omitempty"` // branch or tag to use for a git repository
+	Updated time.Time `json:"updated,omitempty"`
+}
+
+// featureRepositoryIndex is the content of the index of an HTTP repository
+// The URL of each feature is relative to the URL of the index, or absolute
+type featureRepositoryIndex struct {
+	Features []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		URL     string `json:"url"`
+	} `json:"features"`
+}
+
+// folder returns the folder containing the features of the repository
+func (fr FeatureRepository) folder() string {
+	if fr.Type == FeatureRepositoryTypeLocal {
+		return strings.TrimPrefix(fr.URL, "file://")
+	}
+	return filepath.Join(utils.AbsPathify(featureRepositoriesFolder), fr.Name)
+}
+
+// ToProtocol converts a FeatureRepository to *protocol.FeatureRepository
+func (fr FeatureRepository) ToProtocol() *protocol.FeatureRepository {
+	out := &protocol.FeatureRepository{
+		Name: fr.Name,
+		Type: fr.Type,
+		Url:  fr.URL,
+		Ref:  fr.Ref,
+	}
+	if !fr.Updated.IsZero() {
+		out.Updated, _ = ptypes.TimestampProto(fr.Updated)
+	}
+	return out
+}
+
+// splitFeatureReference splits a reference 'name@version' in its name and version parts
+func splitFeatureReference(ref string) (string, string) {
+	parts := strings.SplitN(ref, "@", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// ListFeatureRepositories returns the feature repositories, in the order of their addition (which is the order of search)
+func ListFeatureRepositories() ([]FeatureRepository, fail.Error) {
+	featureRepositoriesLock.Lock()
+	defer featureRepositoriesLock.Unlock()
+
+	return loadFeatureRepositories()
+}
+
+func loadFeatureRepositories() ([]FeatureRepository, fail.Error) {
+	content, err := ioutil.ReadFile(filepath.Join(utils.AbsPathify(featureRepositoriesFolder), featureRepositoriesFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []FeatureRepository{}, nil
+		}
+		return nil, fail.Wrap(err, "failed to read feature repositories")
+	}
+	var list []FeatureRepository
+	if err = json.Unmarshal(content, &list); err != nil {
+		return nil, fail.SyntaxError("failed to decode feature repositories: %s", err.Error())
+	}
+	return list, nil
+}
+
+func saveFeatureRepositories(list []FeatureRepository) fail.Error {
+	folder := utils.AbsPathify(featureRepositoriesFolder)
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return fail.Wrap(err, "failed to create folder '%s'", folder)
+	}
+	content, err := json.MarshalIndent(list, "", "    ")
+	if err != nil {
+		return fail.ToError(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(folder, featureRepositoriesFile), content, 0644); err != nil {
+		return fail.Wrap(err, "failed to write feature repositories")
+	}
+	return nil
+}
+
+// AddFeatureRepository registers a new feature repository and retrieves its content
+// If repo.Type is empty, it's deduced from repo.URL
+func AddFeatureRepository(task concurrency.Task, repo FeatureRepository) (_ *FeatureRepository, xerr fail.Error) {
+	if task.IsNull() {
+		return nil, fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+	if !featureRepositoryNameRegexp.MatchString(repo.Name) {
+		return nil, fail.InvalidParameterError("repo.Name", "must contain only letters, digits, '.', '_' or '-'")
+	}
+	if repo.URL == "" {
+		return nil, fail.InvalidParameterError("repo.URL", "cannot be empty string")
+	}
+	if repo.Type == "" {
+		repo.Type = guessFeatureRepositoryType(repo.URL)
+	}
+	switch repo.Type {
+	case FeatureRepositoryTypeGit, FeatureRepositoryTypeHTTP, FeatureRepositoryTypeLocal:
+	default:
+		return nil, fail.InvalidParameterError("repo.Type", "must be '%s', '%s' or '%s'", FeatureRepositoryTypeGit, FeatureRepositoryTypeHTTP, FeatureRepositoryTypeLocal)
+	}
+
+	featureRepositoriesLock.Lock()
+	defer featureRepositoriesLock.Unlock()
+
+	list, xerr := loadFeatureRepositories()
+	if xerr != nil {
+		return nil, xerr
+	}
+	for _, v := range list {
+		if v.Name == repo.Name {
+			return nil, fail.DuplicateError("a feature repository named '%s' already exists", repo.Name)
+		}
+	}
+
+	if xerr = syncFeatureRepository(task, &repo); xerr != nil {
+		return nil, xerr
+	}
+	list = append(list, repo)
+	if xerr = saveFeatureRepositories(list); xerr != nil {
+		return nil, xerr
+	}
+	return &repo, nil
+}
+
+// guessFeatureRepositoryType deduces the type of a repository from its URL
+func guessFeatureRepositoryType(u string) string {
+	switch {
+	case strings.HasPrefix(u, "file://") || strings.HasPrefix(u, "/"):
+		return FeatureRepositoryTypeLocal
+	case strings.HasSuffix(u, ".git") || strings.HasPrefix(u, "git@") || strings.HasPrefix(u, "ssh://") || strings.HasPrefix(u, "git://"):
+		return FeatureRepositoryTypeGit
+	default:
+		return FeatureRepositoryTypeHTTP
+	}
+}
+
+// UpdateFeatureRepositories retrieves the content of the repository named 'name', or of all the repositories if name is empty
+// Returns the updated repositories
+func UpdateFeatureRepositories(task concurrency.Task, name string) (_ []FeatureRepository, xerr fail.Error) {
+	if task.IsNull() {
+		return nil, fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+
+	featureRepositoriesLock.Lock()
+	defer featureRepositoriesLock.Unlock()
+
+	list, xerr := loadFeatureRepositories()
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	var (
+		updated []FeatureRepository
+		errors  []error
+	)
+	for i := range list {
+		if name != "" && list[i].Name != name {
+			continue
+		}
+		if xerr = syncFeatureRepository(task, &list[i]); xerr != nil {
+			errors = append(errors, xerr)
+			continue
+		}
+		updated = append(updated, list[i])
+	}
+	if name != "" && len(updated) == 0 && len(errors) == 0 {
+		return nil, fail.NotFoundError("failed to find a feature repository named '%s'", name)
+	}
+	if len(updated) > 0 {
+		if xerr = saveFeatureRepositories(list); xerr != nil {
+			return nil, xerr
+		}
+	}
+	if len(errors) > 0 {
+		return updated, fail.NewErrorList(errors)
+	}
+	return updated, nil
+}
+
+// syncFeatureRepository retrieves the content of the repository in its local folder
+func syncFeatureRepository(task concurrency.Task, repo *FeatureRepository) fail.Error {
+	folder := repo.folder()
+	var xerr fail.Error
+	switch repo.Type {
+	case FeatureRepositoryTypeLocal:
+		if info, err := os.Stat(folder); err != nil || !info.IsDir() {
+			xerr = fail.NotFoundError("folder '%s' does not exist", folder)
+		}
+	case FeatureRepositoryTypeGit:
+		xerr = syncGitFeatureRepository(task, *repo, folder)
+	case FeatureRepositoryTypeHTTP:
+		xerr = syncHTTPFeatureRepository(task, *repo, folder)
+	default:
+		xerr = fail.InvalidParameterError("repo.Type", "unknown feature repository type '%s'", repo.Type)
+	}
+	if xerr != nil {
+		return fail.Wrap(xerr, "failed to update feature repository '%s'", repo.Name)
+	}
+
+	repo.Updated = time.Now()
+	logrus.Infof("feature repository '%s' updated from '%s'", repo.Name, repo.URL)
+	return nil
+}
+
+// syncGitFeatureRepository clones the git repository, or updates the clone if it exists
+func syncGitFeatureRepository(task concurrency.Task, repo FeatureRepository, folder string) fail.Error {
+	// git would take values starting with '-' as options
+	if strings.HasPrefix(repo.URL, "-") {
+		return fail.InvalidParameterError("repo.URL", "cannot start with '-'")
+	}
+	if strings.HasPrefix(repo.Ref, "-") {
+		return fail.InvalidParameterError("repo.Ref", "cannot start with '-'")
+	}
+
+	if _, err := os.Stat(filepath.Join(folder, ".git")); err == nil {
+		ref := repo.Ref
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if xerr := runGit(task, "-C", folder, "fetch", "--depth", "1", "--", "origin", ref); xerr != nil {
+			return xerr
+		}
+		return runGit(task, "-C", folder, "reset", "--hard", "FETCH_HEAD")
+	}
+
+	if err := os.RemoveAll(folder); err != nil {
+		return fail.ToError(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(folder), 0755); err != nil {
+		return fail.ToError(err)
+	}
+	args := []string{"clone", "--depth", "1"}
+	if repo.Ref != "" {
+		args = append(args, "--branch", repo.Ref)
+	}
+	return runGit(task, append(args, "--", repo.URL, folder)...)
+}
+
+// runGit executes the git command with arguments
+func runGit(task concurrency.Task, args ...string) fail.Error {
+	ctx, xerr := task.GetContext()
+	if xerr != nil {
+		return xerr
+	}
+	out, err := exec.CommandContext(ctx, "git", args...).CombinedOutput()
+	if err != nil {
+		return fail.ExecutionError(err, "'git %s' failed: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// syncHTTPFeatureRepository downloads the index and the features it references, replacing the local copy once everything is downloaded
+func syncHTTPFeatureRepository(task concurrency.Task, repo FeatureRepository, folder string) fail.Error {
+	base, err := url.Parse(repo.URL)
+	if err != nil {
+		return fail.InvalidParameterError("repo.URL", "invalid URL: %s", err.Error())
+	}
+	content, xerr := httpGet(task, repo.URL)
+	if xerr != nil {
+		return xerr
+	}
+	var index featureRepositoryIndex
+	if err = json.Unmarshal(content, &index); err != nil {
+		return fail.SyntaxError("invalid index '%s': %s", repo.URL, err.Error())
+	}
+
+	tmpFolder, err := ioutil.TempDir(filepath.Dir(folder), repo.Name+".")
+	if err != nil {
+		if err = os.MkdirAll(filepath.Dir(folder), 0755); err == nil {
+			tmpFolder, err = ioutil.TempDir(filepath.Dir(folder), repo.Name+".")
+		}
+		if err != nil {
+			return fail.ToError(err)
+		}
+	}
+	defer func() { _ = os.RemoveAll(tmpFolder) }()
+
+	for _, v := range index.Features {
+		if v.Name == "" || v.Version == "" || v.URL == "" {
+			return fail.SyntaxError("invalid entry in index '%s': name, version and url are mandatory", repo.URL)
+		}
+		if !featureRepositoryNameRegexp.MatchString(v.Name) || !featureRepositoryNameRegexp.MatchString(v.Version) {
+			return fail.SyntaxError("invalid entry in index '%s': name and version must start with a letter or a digit, followed by letters, digits, '_', '.' or '-'", repo.URL)
+		}
+		featureFolder := filepath.Join(tmpFolder, v.Name)
+		featurePath := filepath.Join(featureFolder, v.Version+featureFileExt)
+		if rel, err := filepath.Rel(tmpFolder, featurePath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fail.SyntaxError("invalid entry in index '%s': feature '%s' version '%s' out of the repository folder", repo.URL, v.Name, v.Version)
+		}
+		ref, err := base.Parse(v.URL)
+		if err != nil {
+			return fail.SyntaxError("invalid url '%s' of feature '%s' in index '%s'", v.URL, v.Name, repo.URL)
+		}
+		content, xerr := httpGet(task, ref.String())
+		if xerr != nil {
+			return xerr
+		}
+		if err = os.MkdirAll(featureFolder, 0755); err != nil {
+			return fail.ToError(err)
+		}
+		if err = ioutil.WriteFile(featurePath, content, 0644); err != nil {
+			return fail.ToError(err)
+		}
+	}
+
+	if err = os.RemoveAll(folder); err != nil {
+		return fail.ToError(err)
+	}
+	if err = os.Rename(tmpFolder, folder); err != nil {
+		return fail.ToError(err)
+	}
+	return nil
+}
+
+// httpGet returns the content of the URL
+func httpGet(task concurrency.Task, u string) ([]byte, fail.Error) {
+	ctx, xerr := task.GetContext()
+	if xerr != nil {
+		return nil, xerr
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fail.ToError(err)
+	}
+	client := http.Client{Timeout: temporal.GetExecutionTimeout()}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fail.Wrap(err, "failed to get '%s'", u)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fail.NewError("failed to get '%s': %s", u, resp.Status)
+	}
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fail.Wrap(err, "failed to read '%s'", u)
+	}
+	return content, nil
+}
+
+// findFeatureInRepositories searches the feature in the repositories, in their order of addition
+// If version is empty, the highest version available in the first repository containing the feature is returned
+// Returns the path of the specification file and its content
+func findFeatureInRepositories(name, version string) (string, *viper.Viper, fail.Error) {
+	list, xerr := ListFeatureRepositories()
+	if xerr != nil {
+		return "", nil, xerr
+	}
+	for _, repo := range list {
+		path, specs, xerr := findFeatureInFolder(repo.folder(), name, version)
+		if xerr != nil {
+			switch xerr.(type) {
+			case *fail.ErrNotFound:
+				continue
+			default:
+				return "", nil, fail.Wrap(xerr, "in feature repository '%s'", repo.Name)
+			}
+		}
+		return path, specs, nil
+	}
+	if version != "" {
+		return "", nil, fail.NotFoundError("failed to find version '%s' of feature '%s' in feature repositories", version, name)
+	}
+	return "", nil, fail.NotFoundError("failed to find feature '%s' in feature repositories", name)
+}
+
+// listFeaturesInRepositories returns the names of the features available in the repositories
+func listFeaturesInRepositories() ([]string, fail.Error) {
+	list, xerr := ListFeatureRepositories()
+	if xerr != nil {
+		return nil, xerr
+	}
+	var names []string
+	found := map[string]bool{}
+	for _, repo := range list {
+		files, err := ioutil.ReadDir(repo.folder())
+		if err != nil {
+			logrus.Warnf("failed to read content of feature repository '%s': %v", repo.Name, err)
+			continue
+		}
+		for _, f := range files {
+			name := f.Name()
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+			if !f.IsDir() {
+				if !strings.HasSuffix(name, featureFileExt) {
+					continue
+				}
+				name = strings.TrimSuffix(name, featureFileExt)
+			}
+			if !found[name] {
+				found[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// findFeatureInFolder searches the feature in the folder of a repository
+func findFeatureInFolder(folder, name, version string) (string, *viper.Viper, fail.Error) {
+	// the name is used in paths, it cannot designate a file outside of the folder
+	if !featureRepositoryNameRegexp.MatchString(name) {
+		return "", nil, fail.InvalidParameterError("name", "must contain only letters, digits, '.', '_' or '-'")
+	}
+
+	candidates := map[string]string{} // path indexed by version
+	if files, err := ioutil.ReadDir(filepath.Join(folder, name)); err == nil {
+		for _, f := range files {
+			if !f.IsDir() && strings.HasSuffix(f.Name(), featureFileExt) {
+				candidates[strings.TrimSuffix(f.Name(), featureFileExt)] = filepath.Join(folder, name, f.Name())
+			}
+		}
+	}
+
+	path := filepath.Join(folder, name+featureFileExt)
+	if _, err := os.Stat(path); err == nil {
+		specs, xerr := readFeatureSpecFile(path)
+		if xerr != nil {
+			return "", nil, xerr
+		}
+		if _, ok := candidates[specs.GetString("feature.version")]; !ok {
+			candidates[specs.GetString("feature.version")] = path
+		}
+	}
+
+	if version == "" {
+		versions := make([]string, 0, len(candidates))
+		for k := range candidates {
+			versions = append(versions, k)
+		}
+		if len(versions) == 0 {
+			return "", nil, fail.NotFoundError("failed to find feature '%s'", name)
+		}
+		sort.Slice(versions, func(i, j int) bool { return compareFeatureVersions(versions[i], versions[j]) < 0 })
+		version = versions[len(versions)-1]
+	}
+
+	path, ok := candidates[version]
+	if !ok {
+		return "", nil, fail.NotFoundError("failed to find version '%s' of feature '%s'", version, name)
+	}
+	specs, xerr := readFeatureSpecFile(path)
+	if xerr != nil {
+		return "", nil, xerr
+	}
+	if specs.GetString("feature.version") == "" {
+		specs.Set("feature.version", version)
+	}
+	return path, specs, nil
+}
+
+// readFeatureSpecFile reads a feature specification file
+func readFeatureSpecFile(path string) (*viper.Viper, fail.Error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fail.SyntaxError("failed to read feature specification file '%s': %s", path, err.Error())
+	}
+	if !v.IsSet("feature") {
+		return nil, fail.SyntaxError("feature specification file '%s' must begin with 'feature:'", path)
+	}
+	return v, nil
+}
+
+// compareFeatureVersions compares 2 versions, using semantic versioning if possible
+// Returns -1 if a < b, 0 if a == b, 1 if a > b
+func compareFeatureVersions(a, b string) int {
+	va, erra := semver.NewVersion(a)
+	vb, errb := semver.NewVersion(b)
+	switch {
+	case erra == nil && errb == nil:
+		return va.Compare(vb)
+	case erra == nil:
+		return 1
+	case errb == nil:
+		return -1
+	default:
+		return strings.Compare(a, b)
+	}
+}
@@ -0,0 +1,232 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+const repositoryFeature = `
+feature:
+    version: "%s"
+    suitableFor:
+        host: yes
+    install:
+        bash:
+            add:
+                pace: install
+                steps:
+                    install:
+                        targets:
+                            hosts: yes
+                        run: |
+                            echo installed
+`
+
+// setupFeatureRepository creates a local repository containing 'repofeat' in versions 1.2.0 and 1.10.0, and 'single'
+// in version 0.1
+func setupFeatureRepository(t *testing.T) (string, func()) {
+	root, err := ioutil.TempDir("", "safescale-feature-repositories")
+	require.Nil(t, err)
+
+	previous := featureRepositoriesFolder
+	featureRepositoriesFolder = filepath.Join(root, "config")
+
+	repo := filepath.Join(root, "repo")
+	require.Nil(t, os.MkdirAll(filepath.Join(repo, "repofeat"), 0755))
+	for _, v := range []string{"1.2.0", "1.10.0"} {
+		require.Nil(t, ioutil.WriteFile(filepath.Join(repo, "repofeat", v+featureFileExt), []byte(fmt.Sprintf(repositoryFeature, v)), 0644))
+	}
+	require.Nil(t, ioutil.WriteFile(filepath.Join(repo, "single"+featureFileExt), []byte(fmt.Sprintf(repositoryFeature, "0.1")), 0644))
+
+	return repo, func() {
+		featureRepositoriesFolder = previous
+		_ = os.RemoveAll(root)
+	}
+}
+
+func Test_FeatureRepository(t *testing.T) {
+	folder, cleanup := setupFeatureRepository(t)
+	defer cleanup()
+
+	task, xerr := concurrency.NewTask()
+	require.Nil(t, xerr)
+
+	repo, xerr := AddFeatureRepository(task, FeatureRepository{Name: "test", URL: "file://" + folder})
+	require.Nil(t, xerr)
+	assert.Equal(t, FeatureRepositoryTypeLocal, repo.Type)
+	assert.False(t, repo.Updated.IsZero())
+
+	_, xerr = AddFeatureRepository(task, FeatureRepository{Name: "test", URL: folder})
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrDuplicate{}, xerr)
+
+	_, xerr = AddFeatureRepository(task, FeatureRepository{Name: "missing", URL: filepath.Join(folder, "missing")})
+	require.NotNil(t, xerr)
+
+	list, xerr := ListFeatureRepositories()
+	require.Nil(t, xerr)
+	require.Len(t, list, 1)
+	assert.Equal(t, "test", list[0].Name)
+
+	updated, xerr := UpdateFeatureRepositories(task, "")
+	require.Nil(t, xerr)
+	assert.Len(t, updated, 1)
+
+	_, xerr = UpdateFeatureRepositories(task, "unknown")
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrNotFound{}, xerr)
+
+	names, xerr := listFeaturesInRepositories()
+	require.Nil(t, xerr)
+	assert.ElementsMatch(t, []string{"repofeat", "single"}, names)
+
+	feat, xerr := NewFeature(task, "repofeat")
+	require.Nil(t, xerr)
+	assert.Equal(t, "repofeat", feat.GetName())
+	assert.Equal(t, "1.10.0", feat.GetVersion())
+
+	feat, xerr = NewFeature(task, "repofeat@1.2.0")
+	require.Nil(t, xerr)
+	assert.Equal(t, "1.2.0", feat.GetVersion())
+	assert.Equal(t, filepath.Join(folder, "repofeat", "1.2.0"+featureFileExt), feat.GetFilename())
+
+	feat, xerr = NewFeature(task, "single@0.1")
+	require.Nil(t, xerr)
+	assert.Equal(t, "0.1", feat.GetVersion())
+
+	_, xerr = NewFeature(task, "repofeat@2.0.0")
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrNotFound{}, xerr)
+}
+
+func Test_FeatureRepository_HTTP(t *testing.T) {
+	folder, cleanup := setupFeatureRepository(t)
+	defer cleanup()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"features": [{"name": "repofeat", "version": "1.2.0", "url": "files/repofeat/1.2.0.yml"}]}`)
+	})
+	mux.HandleFunc("/traversal-name.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"features": [{"name": "..", "version": "escaped", "url": "files/repofeat/1.2.0.yml"}]}`)
+	})
+	mux.HandleFunc("/traversal-version.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"features": [{"name": "repofeat", "version": "..", "url": "files/repofeat/1.2.0.yml"}]}`)
+	})
+	mux.Handle("/files/", http.StripPrefix("/files/", http.FileServer(http.Dir(folder))))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	task, xerr := concurrency.NewTask()
+	require.Nil(t, xerr)
+
+	repo, xerr := AddFeatureRepository(task, FeatureRepository{Name: "remote", URL: server.URL + "/index.json"})
+	require.Nil(t, xerr)
+	assert.Equal(t, FeatureRepositoryTypeHTTP, repo.Type)
+
+	feat, xerr := NewFeature(task, "repofeat")
+	require.Nil(t, xerr)
+	assert.Equal(t, "1.2.0", feat.GetVersion())
+	assert.Equal(t, filepath.Join(repo.folder(), "repofeat", "1.2.0"+featureFileExt), feat.GetFilename())
+
+	// entries designating files out of the folder of the repository are refused
+	for _, v := range []string{"traversal-name", "traversal-version"} {
+		_, xerr = AddFeatureRepository(task, FeatureRepository{Name: v, URL: server.URL + "/" + v + ".json"})
+		require.NotNil(t, xerr)
+		assert.IsType(t, &fail.ErrSyntax{}, xerr)
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(repo.folder()), "escaped"+featureFileExt))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_compareFeatureVersions(t *testing.T) {
+	assert.Equal(t, -1, compareFeatureVersions("1.2.0", "1.10.0"))
+	assert.Equal(t, 1, compareFeatureVersions("v2", "1.10.0"))
+	assert.Equal(t, 0, compareFeatureVersions("1.0", "1.0.0"))
+	assert.Equal(t, 1, compareFeatureVersions("1.0", "latest"))
+	assert.Equal(t, -1, compareFeatureVersions("alpha", "beta"))
+}
+
+func Test_splitFeatureReference(t *testing.T) {
+	name, version := splitFeatureReference("docker@19.03")
+	assert.Equal(t, "docker", name)
+	assert.Equal(t, "19.03", version)
+
+	name, version = splitFeatureReference("docker")
+	assert.Equal(t, "docker", name)
+	assert.Equal(t, "", version)
+}
+
+func Test_FeatureRepository_Git(t *testing.T) {
+	folder, cleanup := setupFeatureRepository(t)
+	defer cleanup()
+
+	git := func(args ...string) {
+		out, err := exec.Command("git", append([]string{"-C", folder, "-c", "user.name=test", "-c", "user.email=test@example.org"}, args...)...).CombinedOutput()
+		require.Nil(t, err, string(out))
+	}
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "features")
+
+	task, xerr := concurrency.NewTask()
+	require.Nil(t, xerr)
+
+	// values starting with '-' would be taken as options by git
+	_, xerr = AddFeatureRepository(task, FeatureRepository{Name: "option", URL: "--upload-pack=touch /tmp/pwned", Type: FeatureRepositoryTypeGit})
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrInvalidParameter{}, xerr)
+	_, xerr = AddFeatureRepository(task, FeatureRepository{Name: "option", URL: folder, Ref: "--upload-pack=touch /tmp/pwned", Type: FeatureRepositoryTypeGit})
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrInvalidParameter{}, xerr)
+
+	repo, xerr := AddFeatureRepository(task, FeatureRepository{Name: "git", URL: folder, Type: FeatureRepositoryTypeGit})
+	require.Nil(t, xerr)
+	_, xerr = UpdateFeatureRepositories(task, "git")
+	require.Nil(t, xerr)
+
+	feat, xerr := NewFeature(task, "single")
+	require.Nil(t, xerr)
+	assert.Equal(t, filepath.Join(repo.folder(), "single"+featureFileExt), feat.GetFilename())
+}
+
+func Test_findFeatureInFolder(t *testing.T) {
+	folder, cleanup := setupFeatureRepository(t)
+	defer cleanup()
+
+	_, _, xerr := findFeatureInFolder(filepath.Join(folder, "repofeat"), "../single", "")
+	require.NotNil(t, xerr)
+	assert.IsType(t, &fail.ErrInvalidParameter{}, xerr)
+
+	path, _, xerr := findFeatureInFolder(folder, "single", "")
+	require.Nil(t, xerr)
+	assert.Equal(t, filepath.Join(folder, "single"+featureFileExt), path)
+}
@@ -17,6 +17,7 @@
 package operations
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/CS-SI/SafeScale/lib/server/resources"
@@ -111,41 +112,49 @@ func (urs unitResults) Completed() bool {
 	return true
 }
 
-// results ...
-type results map[string]resources.UnitResults
+// results contains the results of the steps of an action, indexed by step name
+// The steps are kept in the order they have been added, which is the order of execution
+type results struct {
+	keys  []string
+	items map[string]resources.UnitResults
+}
 
 // Add ...
 func (r *results) Add(key string, urs resources.UnitResults) error {
-	if len(*r) == 0 {
-		*r = results{}
-	}
 	if urs == nil {
 		return fail.InvalidParameterError("urs", "cannot be nil")
 	}
+	if r.items == nil {
+		r.items = map[string]resources.UnitResults{}
+	}
 
-	(*r)[key] = urs
+	if _, ok := r.items[key]; !ok {
+		r.keys = append(r.keys, key)
+	}
+	r.items[key] = urs
 	return nil
 }
 
 // AddOne ...
 func (r *results) AddOne(key, unitName string, ur resources.UnitResult) error {
-	if *r == nil {
-		*r = results{}
-	}
 	if ur == nil {
 		return fail.InvalidParameterError("ur", "cannot be nil")
 	}
-	if _, ok := (*r)[key]; !ok {
-		(*r)[key] = &unitResults{}
+	if r.items == nil {
+		r.items = map[string]resources.UnitResults{}
 	}
-	(*r)[key].AddOne(unitName, ur)
+	if _, ok := r.items[key]; !ok {
+		r.keys = append(r.keys, key)
+		r.items[key] = &unitResults{}
+	}
+	r.items[key].AddOne(unitName, ur)
 	return nil
 }
 
 // Successful ...
 func (r results) Successful() bool {
-	if len(r) > 0 {
-		for _, v := range r {
+	if len(r.items) > 0 {
+		for _, v := range r.items {
 			if !v.Successful() {
 				return false
 			}
@@ -157,8 +166,8 @@ func (r results) Successful() bool {
 // AllErrorMessages ...
 func (r results) AllErrorMessages() string {
 	output := ""
-	for _, v := range r {
-		val := strings.TrimSpace(v.ErrorMessages())
+	for _, k := range r.keys {
+		val := strings.TrimSpace(r.items[k].ErrorMessages())
 		if val != "" {
 			output += val + "\n"
 		}
@@ -168,7 +177,7 @@ func (r results) AllErrorMessages() string {
 
 // ErrorMessagesOfKey ...
 func (r results) ErrorMessagesOfKey(key string) string {
-	if step, ok := r[key]; ok {
+	if step, ok := r.items[key]; ok {
 		return step.ErrorMessages()
 	}
 	return ""
@@ -177,8 +186,8 @@ func (r results) ErrorMessagesOfKey(key string) string {
 // ErrorMessagesOfUnit ...
 func (r results) ErrorMessagesOfUnit(unitName string) string {
 	output := ""
-	for _, urs := range r {
-		rurs := urs.(*unitResults)
+	for _, k := range r.keys {
+		rurs := r.items[k].(*unitResults)
 		for k, v := range *rurs {
 			if k == unitName {
 				val := v.Error().Error()
@@ -194,7 +203,7 @@ func (r results) ErrorMessagesOfUnit(unitName string) string {
 // ResultsOfUnit ...
 func (r results) ResultsOfUnit(unitName string) resources.UnitResults {
 	newSrs := unitResults{}
-	for _, urs := range r {
+	for _, urs := range r.items {
 		rurs := urs.(*unitResults)
 		for k, v := range *rurs {
 			if k == unitName {
@@ -207,17 +216,48 @@ func (r results) ResultsOfUnit(unitName string) resources.UnitResults {
 
 // ResultsOfKey ...
 func (r results) ResultsOfKey(key string) resources.UnitResults {
-	if ret, ok := r[key]; ok {
+	if ret, ok := r.items[key]; ok {
 		return ret
 	}
 	return &unitResults{}
 }
 
-// Keys returns the keys of the Results
+// Keys returns the keys of the Results, in the order they have been added
 func (r results) Keys() []string {
-	var keys []string
-	for k := range r {
-		keys = append(keys, k)
-	}
+	keys := make([]string, len(r.keys))
+	copy(keys, r.keys)
 	return keys
 }
+
+// FeatureScript is the script of a step of a feature action, rendered for a host by a dry run
+type FeatureScript struct {
+	Step   string
+	Host   string
+	Script string
+}
+
+// DryRunScripts returns the scripts rendered by a dry run of a feature action, in the order of execution of the steps
+// then by host name
+func DryRunScripts(r resources.Results) []FeatureScript {
+	var out []FeatureScript
+	if r == nil {
+		return out
+	}
+	for _, step := range r.Keys() {
+		urs, ok := r.ResultsOfKey(step).(*unitResults)
+		if !ok {
+			continue
+		}
+		hosts := make([]string, 0, len(*urs))
+		for k := range *urs {
+			hosts = append(hosts, k)
+		}
+		sort.Strings(hosts)
+		for _, h := range hosts {
+			if sr, ok := (*urs)[h].(stepResult); ok {
+				out = append(out, FeatureScript{Step: step, Host: h, Script: sr.output})
+			}
+		}
+	}
+	return out
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/CS-SI/SafeScale/lib/protocol"
+	"github.com/CS-SI/SafeScale/lib/server/events"
 	"github.com/CS-SI/SafeScale/lib/server/iaas"
 	"github.com/CS-SI/SafeScale/lib/server/iaas/userdata"
 	"github.com/CS-SI/SafeScale/lib/server/resources"
@@ -514,6 +515,20 @@ func (rh *host) Create(task concurrency.Task, hostReq abstract.HostRequest, host
 		}
 	}
 
+	// Reserves the quotas before creating anything, unless the cluster creating the host already did
+	tmpl, xerr := svc.InspectTemplate(hostReq.TemplateID)
+	if xerr != nil {
+		return nil, fail.Wrap(xerr, "failed to inspect template '%s'", hostReq.TemplateID)
+	}
+	quotaUsage := templateQuotaUsage(tmpl, hostReq.PublicIP, 1)
+	var reservation *quotaReservation
+	if hostReq.QuotaReservation == "" {
+		if reservation, xerr = reserveQuotas(task, svc, quotaUsage, ""); xerr != nil {
+			return nil, xerr
+		}
+		defer reservation.release()
+	}
+
 	// identify default Subnet
 	var defaultSubnet resources.Subnet
 	if len(hostReq.Subnets) > 0 {
@@ -657,6 +672,7 @@ func (rh *host) Create(task concurrency.Task, hostReq abstract.HostRequest, host
 				creator = "unknown@" + hostname
 			}
 			hostDescriptionV1.Creator = creator
+			hostDescriptionV1.Owner = ownerOfTask(task)
 			return nil
 		})
 		if innerXErr != nil {
@@ -690,6 +706,13 @@ func (rh *host) Create(task concurrency.Task, hostReq abstract.HostRequest, host
 	if xerr != nil {
 		return nil, xerr
 	}
+
+	// The resources of the host are now counted from its metadata
+	if hostReq.QuotaReservation != "" {
+		consumeQuotaReservation(hostReq.QuotaReservation, quotaUsage)
+	} else {
+		reservation.release()
+	}
 	defer rh.onFailureUndoSetSecurityGroups(task, &xerr, hostReq.KeepOnFailure)
 
 	if xerr = rh.cacheAccessInformation(task); xerr != nil {
@@ -747,6 +770,7 @@ func (rh *host) Create(task concurrency.Task, hostReq abstract.HostRequest, host
 	}
 
 	logrus.Infof("host '%s' created successfully", rh.GetName())
+	events.Publish(task, events.Event{Type: protocol.EventType_ET_HOST_CREATED, ResourceID: rh.GetID(), ResourceName: rh.GetName()})
 	return userdataContent, nil
 }
 
@@ -1460,6 +1484,7 @@ func (rh *host) relaxedDeleteHost(task concurrency.Task) fail.Error {
 	defer rh.SafeUnlock(task)
 
 	svc := rh.GetService()
+	hostID, hostName := rh.GetID(), rh.GetName()
 
 	var shares map[string]*propertiesv1.HostShare
 	xerr := rh.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
@@ -1737,6 +1762,7 @@ func (rh *host) relaxedDeleteHost(task concurrency.Task) fail.Error {
 		}
 	}
 
+	events.Publish(task, events.Event{Type: protocol.EventType_ET_HOST_DELETED, ResourceID: hostID, ResourceName: hostName})
 	newHost := nullHost()
 	*rh = *newHost
 	return nil
@@ -2050,6 +2076,7 @@ func (rh host) Start(task concurrency.Task) (xerr fail.Error) {
 	if xerr != nil {
 		return fail.Wrap(xerr, "timeout waiting host '%s' to be started", hostName)
 	}
+	events.Publish(task, events.Event{Type: protocol.EventType_ET_HOST_STATE_CHANGED, ResourceID: hostID, ResourceName: hostName, State: hoststate.STARTED.String()})
 	return nil
 }
 
@@ -2080,6 +2107,7 @@ func (rh host) Stop(task concurrency.Task) (xerr fail.Error) {
 	if xerr != nil {
 		return fail.Wrap(xerr, "timeout waiting host '%s' to be stopped", hostName)
 	}
+	events.Publish(task, events.Event{Type: protocol.EventType_ET_HOST_STATE_CHANGED, ResourceID: hostID, ResourceName: hostName, State: hoststate.STOPPED.String()})
 	return nil
 }
 
@@ -2119,32 +2147,33 @@ func (rh *host) AddFeature(task concurrency.Task, name string, vars data.Map, se
 	if xerr != nil {
 		return nil, xerr
 	}
-	xerr = rh.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
-		var innerXErr fail.Error
-		outcomes, innerXErr = feat.Add(rh, vars, settings)
-		if innerXErr != nil {
-			return innerXErr
-		}
+	// Note: the feature is installed outside of rh.Alter(), the installation inspecting the host from other tasks
+	outcomes, xerr = feat.Add(rh, vars, settings)
+	if xerr != nil || !outcomes.Successful() || settings.DryRun {
+		return outcomes, xerr
+	}
 
+	requires, xerr := feat.GetRequirements()
+	if xerr != nil {
+		return outcomes, xerr
+	}
+	xerr = rh.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
 		// updates HostFeatures property for host
 		return props.Alter(task, hostproperty.FeaturesV1, func(clonable data.Clonable) fail.Error {
 			hostFeaturesV1, ok := clonable.(*propertiesv1.HostFeatures)
 			if !ok {
 				return fail.InconsistentError("expected '*propertiesv1.HostFeatures', received '%s'", reflect.TypeOf(clonable))
 			}
-			requires, innerXErr := feat.GetRequirements()
-			if innerXErr != nil {
-				return innerXErr
-			}
-			hostFeaturesV1.Installed[name] = &propertiesv1.HostInstalledFeature{
+			hostFeaturesV1.Installed[feat.GetName()] = &propertiesv1.HostInstalledFeature{
 				HostContext: true,
 				Requires:    requires,
+				Version:     feat.GetVersion(),
 			}
 			return nil
 		})
 	})
 	if xerr != nil {
-		return nil, xerr
+		return outcomes, xerr
 	}
 	return outcomes, nil
 }
@@ -2212,28 +2241,97 @@ func (rh *host) DeleteFeature(task concurrency.Task, name string, vars data.Map,
 	// 	return srvutils.ThrowErr(err)
 	// }
 
+	outcomes, xerr := feat.Remove(rh, vars, settings)
+	if xerr != nil {
+		return nil, fail.NewError(xerr, nil, "error uninstalling feature '%s' on '%s'", name, rh.GetName())
+	}
+	if !outcomes.Successful() {
+		msg := fmt.Sprintf("failed to delete feature '%s' from host '%s'", name, rh.GetName())
+		tracer.Trace(strprocess.Capitalize(msg) + ":\n" + outcomes.AllErrorMessages())
+		return outcomes, nil
+	}
+	if settings.DryRun {
+		return outcomes, nil
+	}
+
 	xerr = rh.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
-		outcomes, innerXErr := feat.Remove(rh, vars, settings)
-		if innerXErr != nil {
-			return fail.NewError(innerXErr, nil, "error uninstalling feature '%s' on '%s'", name, rh.GetName())
-		}
-		if !outcomes.Successful() {
-			msg := fmt.Sprintf("failed to delete feature '%s' from host '%s'", name, rh.GetName())
-			tracer.Trace(strprocess.Capitalize(msg) + ":\n" + outcomes.AllErrorMessages())
-			return fail.NewError(msg)
-		}
+		// updates HostFeatures property for host
+		return props.Alter(task, hostproperty.FeaturesV1, func(clonable data.Clonable) fail.Error {
+			hostFeaturesV1, ok := clonable.(*propertiesv1.HostFeatures)
+			if !ok {
+				return fail.InconsistentError("expected '*propertiesv1.HostFeatures', received '%s'", reflect.TypeOf(clonable))
+			}
+			delete(hostFeaturesV1.Installed, feat.GetName())
+			return nil
+		})
+	})
+	return outcomes, xerr
+}
+
+// UpgradeFeature handles 'safescale host upgrade-feature <host name> <feature name>'
+func (rh *host) UpgradeFeature(task concurrency.Task, name string, vars data.Map, settings resources.FeatureSettings) (outcomes resources.Results, xerr fail.Error) {
+	if rh.IsNull() {
+		return nil, fail.InvalidInstanceError()
+	}
+	if task.IsNull() {
+		return nil, fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+	if name == "" {
+		return nil, fail.InvalidParameterError("name", "cannot be empty string")
+	}
+
+	tracer := debug.NewTracer(task, tracing.ShouldTrace("resources.host"), "(%s)", name).Entering()
+	defer tracer.Exiting()
+
+	featureName, _ := splitFeatureReference(name)
+	var (
+		installed string
+		recorded  bool
+	)
+	xerr = rh.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Inspect(task, hostproperty.FeaturesV1, func(clonable data.Clonable) fail.Error {
+			hostFeaturesV1, ok := clonable.(*propertiesv1.HostFeatures)
+			if !ok {
+				return fail.InconsistentError("expected '*propertiesv1.HostFeatures', received '%s'", reflect.TypeOf(clonable))
+			}
+			if item, ok := hostFeaturesV1.Installed[featureName]; ok {
+				installed, recorded = item.Version, true
+			}
+			return nil
+		})
+	})
+	if xerr != nil {
+		return nil, xerr
+	}
+
+	// Note: the feature is upgraded outside of rh.Alter(), the upgrade inspecting the host from other tasks
+	feat, outcomes, xerr := upgradeFeatureOnTarget(task, rh, name, installed, recorded, vars, settings)
+	if xerr != nil || feat == nil || !outcomes.Successful() || settings.DryRun {
+		return outcomes, xerr
+	}
 
+	requires, xerr := feat.GetRequirements()
+	if xerr != nil {
+		return outcomes, xerr
+	}
+	xerr = rh.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
 		// updates HostFeatures property for host
 		return props.Alter(task, hostproperty.FeaturesV1, func(clonable data.Clonable) fail.Error {
 			hostFeaturesV1, ok := clonable.(*propertiesv1.HostFeatures)
 			if !ok {
 				return fail.InconsistentError("expected '*propertiesv1.HostFeatures', received '%s'", reflect.TypeOf(clonable))
 			}
-			delete(hostFeaturesV1.Installed, name)
+			item, ok := hostFeaturesV1.Installed[feat.GetName()]
+			if !ok {
+				item = &propertiesv1.HostInstalledFeature{HostContext: true}
+				hostFeaturesV1.Installed[feat.GetName()] = item
+			}
+			item.Requires = requires
+			item.Version = feat.GetVersion()
 			return nil
 		})
 	})
-	return nil, xerr
+	return outcomes, xerr
 }
 
 // TargetType returns the type of the target.
@@ -2483,6 +2581,58 @@ func (rh host) InstalledFeatures(task concurrency.Task) []string {
 	return list
 }
 
+// RecordFeatureHistory records in metadata an action on a feature of the host
+// satisfies interface install.Targetable
+func (rh *host) RecordFeatureHistory(task concurrency.Task, entry *propertiesv1.FeatureHistoryEntry) fail.Error {
+	if rh.IsNull() {
+		return fail.InvalidInstanceError()
+	}
+	if task.IsNull() {
+		return fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+	if entry == nil {
+		return fail.InvalidParameterError("entry", "cannot be nil")
+	}
+
+	return rh.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Alter(task, hostproperty.FeatureHistoryV1, func(clonable data.Clonable) fail.Error {
+			historyV1, ok := clonable.(*propertiesv1.FeatureHistory)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.FeatureHistory' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			historyV1.AddEntry(entry)
+			return nil
+		})
+	})
+}
+
+// GetFeatureHistory returns the last actions on features of the host
+// satisfies interface install.Targetable
+func (rh host) GetFeatureHistory(task concurrency.Task) (*propertiesv1.FeatureHistory, fail.Error) {
+	if rh.IsNull() {
+		return nil, fail.InvalidInstanceError()
+	}
+	if task.IsNull() {
+		return nil, fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+
+	var history *propertiesv1.FeatureHistory
+	xerr := rh.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Inspect(task, hostproperty.FeatureHistoryV1, func(clonable data.Clonable) fail.Error {
+			historyV1, ok := clonable.(*propertiesv1.FeatureHistory)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.FeatureHistory' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			history = historyV1.Clone().(*propertiesv1.FeatureHistory)
+			return nil
+		})
+	})
+	if xerr != nil {
+		return nil, xerr
+	}
+	return history, nil
+}
+
 // ComplementFeatureParameters configures parameters that are appropriate for the target
 // satisfies interface install.Targetable
 func (rh host) ComplementFeatureParameters(task concurrency.Task, v data.Map) fail.Error {
@@ -26,7 +26,7 @@ import (
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
 )
 
-// bashInstaller is an installer using script to add and remove a feature
+// bashInstaller is an installer using script to add, remove and upgrade a feature
 type bashInstaller struct{}
 
 // GetName ...
@@ -86,6 +86,9 @@ func (i *bashInstaller) Add(f resources.Feature, t resources.Targetable, v data.
 		logrus.Info(xerr.Error())
 		return nil, xerr
 	}
+	if xerr = w.ExpandCluster(); xerr != nil {
+		return nil, xerr
+	}
 	if !w.ConcernsCluster() {
 		if _, ok := v["Username"]; !ok {
 			v["Username"] = "safescale"
@@ -126,6 +129,37 @@ func (i *bashInstaller) Remove(f resources.Feature, t resources.Targetable, v da
 	return w.Proceed(v, s)
 }
 
+// Upgrade upgrades the feature using the upgrade script in Specs
+func (i *bashInstaller) Upgrade(f resources.Feature, t resources.Targetable, v data.Map, s resources.FeatureSettings) (resources.Results, fail.Error) {
+	if f.IsNull() {
+		return nil, fail.InvalidParameterError("f", "cannot be nil")
+	}
+	if t == nil {
+		return nil, fail.InvalidParameterError("t", "cannot be nil")
+	}
+
+	if !f.(*feature).Specs().IsSet("feature.install.bash.upgrade") {
+		msg := `syntax error in feature '%s' specification file (%s):
+				no key 'feature.install.bash.upgrade' found`
+		return nil, fail.SyntaxError(msg, f.GetName(), f.GetDisplayFilename())
+	}
+
+	w, xerr := newWorker(f, t, installmethod.Bash, installaction.Upgrade, nil)
+	if xerr != nil {
+		return nil, xerr
+	}
+	if xerr = w.CanProceed(s); xerr != nil {
+		logrus.Info(xerr.Error())
+		return nil, xerr
+	}
+	if !w.ConcernsCluster() {
+		if _, ok := v["Username"]; !ok {
+			v["Username"] = "safescale"
+		}
+	}
+	return w.Proceed(v, s)
+}
+
 // newBashInstaller creates a new instance of Installer using script
 func newBashInstaller() Installer {
 	return &bashInstaller{}
@@ -32,11 +32,12 @@ import (
 // genericPackager is an object implementing the OS package management
 // It handles package management on single host or entire cluster
 type genericPackager struct {
-	keyword       string
-	method        installmethod.Enum
-	checkCommand  alterCommandCB
-	addCommand    alterCommandCB
-	removeCommand alterCommandCB
+	keyword        string
+	method         installmethod.Enum
+	checkCommand   alterCommandCB
+	addCommand     alterCommandCB
+	removeCommand  alterCommandCB
+	upgradeCommand alterCommandCB
 }
 
 // Check checks if the feature is installed
@@ -91,6 +92,9 @@ func (g *genericPackager) Add(f resources.Feature, t resources.Targetable, v dat
 		logrus.Info(xerr.Error())
 		return nil, xerr
 	}
+	if xerr = worker.ExpandCluster(); xerr != nil {
+		return nil, xerr
+	}
 
 	return worker.Proceed(v, s)
 }
@@ -122,6 +126,33 @@ func (g *genericPackager) Remove(f resources.Feature, t resources.Targetable, v
 	return worker.Proceed(v, s)
 }
 
+// Upgrade upgrades the feature to the last version of the package
+func (g *genericPackager) Upgrade(f resources.Feature, t resources.Targetable, v data.Map, s resources.FeatureSettings) (resources.Results, fail.Error) {
+	if f.IsNull() {
+		return nil, fail.InvalidParameterError("f", "cannot be nil")
+	}
+	if t == nil {
+		return nil, fail.InvalidParameterError("t", "cannot be nil")
+	}
+
+	yamlKey := "feature.install." + g.keyword + ".upgrade"
+	if !f.(*feature).Specs().IsSet(yamlKey) {
+		msg := `syntax error in feature '%s' specification file (%s):
+				no key '%s' found`
+		return nil, fail.SyntaxError(msg, f.GetName(), f.GetDisplayFilename(), yamlKey)
+	}
+
+	worker, xerr := newWorker(f, t, g.method, installaction.Upgrade, g.upgradeCommand)
+	if xerr != nil {
+		return nil, xerr
+	}
+	if xerr = worker.CanProceed(s); xerr != nil {
+		logrus.Info(xerr.Error())
+		return nil, xerr
+	}
+	return worker.Proceed(v, s)
+}
+
 // aptInstaller is an installer using script to add and remove a feature
 type aptInstaller struct {
 	genericPackager
@@ -142,6 +173,9 @@ func NewAptInstaller() Installer {
 			removeCommand: func(pkg string) string {
 				return fmt.Sprintf("sudo apt-get remove -y '%s'", pkg)
 			},
+			upgradeCommand: func(pkg string) string {
+				return fmt.Sprintf("sudo apt-get install -y --only-upgrade '%s'", pkg)
+			},
 		},
 	}
 }
@@ -166,6 +200,9 @@ func NewYumInstaller() Installer {
 			removeCommand: func(pkg string) string {
 				return fmt.Sprintf("sudo yum remove -y %s", pkg)
 			},
+			upgradeCommand: func(pkg string) string {
+				return fmt.Sprintf("sudo yum update -y %s", pkg)
+			},
 		},
 	}
 }
@@ -190,6 +227,9 @@ func NewDnfInstaller() Installer {
 			removeCommand: func(pkg string) string {
 				return fmt.Sprintf("sudo dnf uninstall -y %s", pkg)
 			},
+			upgradeCommand: func(pkg string) string {
+				return fmt.Sprintf("sudo dnf upgrade -y %s", pkg)
+			},
 		},
 	}
 }
@@ -32,4 +32,6 @@ type Installer interface {
 	Add(resources.Feature, resources.Targetable, data.Map, resources.FeatureSettings) (resources.Results, fail.Error)
 	// Remove executes deletion of feature
 	Remove(resources.Feature, resources.Targetable, data.Map, resources.FeatureSettings) (resources.Results, fail.Error)
+	// Upgrade executes upgrade of feature
+	Upgrade(resources.Feature, resources.Targetable, data.Map, resources.FeatureSettings) (resources.Results, fail.Error)
 }
@@ -37,6 +37,7 @@ import (
 	"github.com/CS-SI/SafeScale/lib/utils/data"
 	"github.com/CS-SI/SafeScale/lib/utils/debug"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
 	"github.com/CS-SI/SafeScale/lib/utils/serialize"
 	"github.com/CS-SI/SafeScale/lib/utils/template"
 	"github.com/CS-SI/SafeScale/lib/utils/temporal"
@@ -55,6 +56,7 @@ type stepResult struct {
 	output    string
 	success   bool  // if true, the script has been run successfully and the result is a success
 	err       error // if an error occurred, contains the err
+	duration  time.Duration
 }
 
 func (sr stepResult) Successful() bool {
@@ -439,12 +441,19 @@ func (is *step) taskRunOnHost(task concurrency.Task, params concurrency.TaskPara
 		return nil, fail.InvalidParameterError("params", "must be of type 'runOnHostParameters'")
 	}
 
+	start := time.Now()
+
 	// Updates variables in step script
 	command, xerr := replaceVariablesInString(is.Script, p.Variables)
 	if xerr != nil {
 		return stepResult{err: fail.Wrap(xerr, "failed to finalize installer script for step '%s'", is.Name)}, nil
 	}
 
+	// In dry run, the rendered script (with secrets masked) is the output of the step, nothing is executed
+	if is.Worker.settings.DryRun {
+		return stepResult{success: true, completed: true, output: secret.Mask(command)}, nil
+	}
+
 	// If options file is defined, upload it to the remote rh
 	if is.OptionsFileContent != "" {
 		rfcItem := remotefile.Item{
@@ -486,12 +495,13 @@ func (is *step) taskRunOnHost(task concurrency.Task, params concurrency.TaskPara
 
 	// Executes the script on the remote host
 	retcode, outrun, _, xerr := p.Host.Run(task, command, outputs.COLLECT, temporal.GetConnectionTimeout(), is.WallTime)
+	outrun = secret.Mask(outrun)
 	if xerr != nil {
 		_ = xerr.Annotate("stdout", outrun)
-		return stepResult{err: xerr, retcode: retcode, output: outrun}, nil
+		return stepResult{err: xerr, retcode: retcode, output: outrun, duration: time.Since(start)}, nil
 	}
 
-	return stepResult{success: retcode == 0, completed: true, err: nil, retcode: retcode, output: outrun}, nil
+	return stepResult{success: retcode == 0, completed: true, err: nil, retcode: retcode, output: outrun, duration: time.Since(start)}, nil
 }
 
 // realizeVariables replaces any template occuring in every variable
@@ -511,6 +521,10 @@ func realizeVariables(variables data.Map) (data.Map, fail.Error) {
 			}
 
 			cloneV[k] = buffer.String()
+			// values of parameters looking like secrets are masked in logs and outputs
+			if secretFeatureParameter.MatchString(k) {
+				secret.Register(buffer.String())
+			}
 		}
 	}
 
@@ -118,6 +118,10 @@ type worker struct {
 	rootKey string
 	// function to alter the content of 'run' key of specification file
 	commandCB alterCommandCB
+
+	// nodes to add to the cluster by ExpandCluster to meet the sizing requirements of the feature
+	expansionCount  uint
+	expansionSizing abstract.HostSizingRequirements
 }
 
 // newWorker ...
@@ -161,8 +165,8 @@ func (w *worker) CanProceed(s resources.FeatureSettings) fail.Error {
 	switch w.target.TargetType() {
 	case featuretargettype.CLUSTER:
 		xerr := w.validateContextForCluster()
-		if xerr == nil && !s.SkipSizingRequirements {
-			xerr = w.validateClusterSizing()
+		if xerr == nil && w.action == installaction.Add && !s.SkipSizingRequirements {
+			xerr = w.validateClusterSizing(s)
 		}
 		return xerr
 	// case featuretargettype.NODE:
@@ -177,6 +181,26 @@ func (w *worker) CanProceed(s resources.FeatureSettings) fail.Error {
 	return nil
 }
 
+// ExpandCluster adds to the cluster the nodes needed to meet the sizing requirements of the feature, as determined by
+// CanProceed when s.AutoExpand is true; does nothing if no node is needed
+func (w *worker) ExpandCluster() fail.Error {
+	if w.cluster == nil || w.expansionCount == 0 {
+		return nil
+	}
+
+	logrus.Infof("[cluster %s] adding %d node%s to meet the sizing requirements of feature '%s'", w.cluster.GetName(), w.expansionCount, strprocess.Plural(w.expansionCount), w.feature.GetName())
+	if _, xerr := w.cluster.AddNodes(w.feature.task, "", w.expansionCount, w.expansionSizing); xerr != nil {
+		return fail.Wrap(xerr, "failed to expand cluster to meet the sizing requirements of feature '%s'", w.feature.GetName())
+	}
+	w.expansionCount = 0
+
+	// forces the reload of the list of nodes
+	w.allNodes = nil
+	w.concernedNodes = nil
+	w.availableNode = nil
+	return nil
+}
+
 // identifyAvailableMaster finds a master available, and keep track of it
 // for all the life of the action (prevent to request too often)
 func (w *worker) identifyAvailableMaster() (resources.Host, fail.Error) {
@@ -232,7 +256,12 @@ func (w *worker) identifyConcernedMasters() ([]resources.Host, fail.Error) {
 // extractHostsFailingCheck identifies from the list passed as parameter which
 // hosts fail feature check.
 // The checks are done in parallel.
+// In dry run, all the hosts are concerned: the scripts are rendered even where the feature is already installed
 func (w *worker) extractHostsFailingCheck(hosts []resources.Host) ([]resources.Host, fail.Error) {
+	if w.settings.DryRun {
+		return hosts, nil
+	}
+
 	var concernedHosts []resources.Host
 	dones := map[resources.Host]chan fail.Error{}
 	res := map[resources.Host]chan resources.Results{}
@@ -241,7 +270,6 @@ func (w *worker) extractHostsFailingCheck(hosts []resources.Host) ([]resources.H
 	if w.cluster != nil {
 		settings.IgnoreSuitability = true
 	}
-
 	for _, h := range hosts {
 		d := make(chan fail.Error)
 		r := make(chan resources.Results)
@@ -481,8 +509,8 @@ func (w *worker) Proceed(v data.Map, s resources.FeatureSettings) (outcomes reso
 	}
 	order := strings.Split(pace, ",")
 
-	// Applies reverseproxy rules to make it functional (feature may need it during the install)
-	if w.action == installaction.Add && !s.SkipProxy {
+	// Applies reverseproxy rules to make it functional (feature may need it during the install or the upgrade)
+	if (w.action == installaction.Add || w.action == installaction.Upgrade) && !s.SkipProxy && !s.DryRun {
 		if w.cluster != nil {
 			if xerr := w.setReverseProxy(); xerr != nil {
 				return nil, xerr
@@ -801,33 +829,46 @@ func (w *worker) validateContextForHost(settings resources.FeatureSettings) fail
 	return fail.NotAvailableError("feature '%s' not suitable for host", w.feature.GetName())
 }
 
-func (w *worker) validateClusterSizing() (xerr fail.Error) {
-	clusterFlavor, xerr := w.cluster.GetFlavor(w.feature.task)
+// validateClusterSizing checks that the masters and the nodes of the cluster meet the requirements of the feature
+// for the flavor and the complexity of the cluster, declared in 'feature.requirements.clusterSizing.<flavor>.<complexity>'
+// If s.AutoExpand is true, a count of nodes below the requirements is not an error: the nodes missing are recorded, to be
+// added by ExpandCluster
+func (w *worker) validateClusterSizing(s resources.FeatureSettings) (xerr fail.Error) {
+	task := w.feature.task
+	clusterFlavor, xerr := w.cluster.GetFlavor(task)
+	if xerr != nil {
+		return xerr
+	}
+	clusterComplexity, xerr := w.cluster.GetComplexity(task)
 	if xerr != nil {
 		return xerr
 	}
-	yamlKey := "feature.requirements.clusterSizing." + strings.ToLower(clusterFlavor.String())
+	yamlKey := "feature.requirements.clusterSizing." + strings.ToLower(clusterFlavor.String()) + "." + strings.ToLower(clusterComplexity.String())
 	if !w.feature.specs.IsSet(yamlKey) {
 		return nil
 	}
 
 	sizing := w.feature.specs.GetStringMap(yamlKey)
+	var report []string
 	if anon, ok := sizing["masters"]; ok {
 		request, ok := anon.(string)
 		if !ok {
 			return fail.SyntaxError("invalid masters key")
 		}
-		count, _, _, xerr := w.parseClusterSizingRequest(request)
+		count, cpu, ram, xerr := parseClusterSizingRequest(request)
+		if xerr != nil {
+			return fail.Wrap(xerr, "invalid key '%s.masters'", yamlKey)
+		}
+		hosts, xerr := w.identifyAllMasters()
 		if xerr != nil {
 			return xerr
 		}
-		masters, xerr := w.cluster.ListMasterIDs(w.feature.task)
+		sizings, xerr := hostsEffectiveSizing(task, hosts)
 		if xerr != nil {
 			return xerr
 		}
-		curMasters := len(masters)
-		if curMasters < count {
-			return fail.NotAvailableError("cluster does not meet the minimum number of masters (%d < %d)", curMasters, count)
+		if missing, undersized := evaluateClusterSizing(sizings, count, cpu, ram); missing > 0 || undersized > 0 {
+			report = append(report, clusterSizingReport("master", len(hosts), count, cpu, ram, missing, undersized))
 		}
 	}
 	if anon, ok := sizing["nodes"]; ok {
@@ -835,26 +876,138 @@ func (w *worker) validateClusterSizing() (xerr fail.Error) {
 		if !ok {
 			return fail.SyntaxError("invalid nodes key")
 		}
-		count, _, _, xerr := w.parseClusterSizingRequest(request)
+		count, cpu, ram, xerr := parseClusterSizingRequest(request)
+		if xerr != nil {
+			return fail.Wrap(xerr, "invalid key '%s.nodes'", yamlKey)
+		}
+		hosts, xerr := w.identifyAllNodes()
 		if xerr != nil {
 			return xerr
 		}
-		list, xerr := w.cluster.ListNodeIDs(w.feature.task)
+		sizings, xerr := hostsEffectiveSizing(task, hosts)
 		if xerr != nil {
 			return xerr
 		}
-		curNodes := len(list)
-		if curNodes < count {
-			return fail.NotAvailableError("cluster does not meet the minimum number of nodes (%d < %d)", curNodes, count)
+		missing, undersized := evaluateClusterSizing(sizings, count, cpu, ram)
+		if missing > 0 && undersized == 0 && s.AutoExpand && !s.DryRun && len(report) == 0 {
+			// the maximums are left to the node pool, complementHostDefinition leaves them unbounded if below the minimums
+			w.expansionCount = uint(missing)
+			w.expansionSizing = abstract.HostSizingRequirements{MinCores: cpu, MinRAMSize: ram}
+		} else if missing > 0 || undersized > 0 {
+			report = append(report, clusterSizingReport("node", len(hosts), count, cpu, ram, missing, undersized))
 		}
 	}
 
+	if len(report) > 0 {
+		msg := fmt.Sprintf("cluster '%s' does not meet the sizing requirements of feature '%s' (flavor %s, complexity %s):\n- %s",
+			w.cluster.GetName(), w.feature.GetName(), clusterFlavor.String(), clusterComplexity.String(), strings.Join(report, "\n- "))
+		return fail.NotAvailableError(msg)
+	}
 	return nil
 }
 
 // parseClusterSizingRequest returns count, cpu and ram components of request
-func (w *worker) parseClusterSizingRequest(request string) (int, int, float32, fail.Error) {
-	return 0, 0, 0.0, fail.NotImplementedError("parseClusterSizingRequest() not yet implemented")
+// request is a list of comma-separated conditions, like "count >= 3, cpu >= 2, ram >= 7.5"; missing conditions are returned as 0
+func parseClusterSizingRequest(request string) (count int, cpu int, ram float32, xerr fail.Error) {
+	for _, v := range strings.Split(request, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		parts := strings.SplitN(v, ">=", 2)
+		if len(parts) != 2 {
+			return 0, 0, 0.0, fail.SyntaxError("invalid condition '%s' in cluster sizing request '%s', only '>=' is supported", v, request)
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+		switch key {
+		case "count", "cpu":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return 0, 0, 0.0, fail.SyntaxError("invalid value '%s' of '%s' in cluster sizing request '%s'", value, key, request)
+			}
+			if key == "count" {
+				count = n
+			} else {
+				cpu = n
+			}
+		case "ram":
+			f, err := strconv.ParseFloat(value, 32)
+			if err != nil || f < 0 {
+				return 0, 0, 0.0, fail.SyntaxError("invalid value '%s' of 'ram' in cluster sizing request '%s'", value, request)
+			}
+			ram = float32(f)
+		default:
+			return 0, 0, 0.0, fail.SyntaxError("unknown key '%s' in cluster sizing request '%s'", key, request)
+		}
+	}
+	return count, cpu, ram, nil
+}
+
+// evaluateClusterSizing compares the effective sizing of hosts with the requested count, cpu and ram
+// Returns the number of hosts satisfying cpu and ram missing to reach count and, if count is 0 (meaning all the hosts
+// have to satisfy cpu and ram), the number of hosts not satisfying cpu and ram
+func evaluateClusterSizing(sizings []*propertiesv1.HostEffectiveSizing, count, cpu int, ram float32) (missing int, undersized int) {
+	satisfying := 0
+	for _, v := range sizings {
+		if v.Cores >= cpu && v.RAMSize >= ram {
+			satisfying++
+		}
+	}
+	if count == 0 {
+		return 0, len(sizings) - satisfying
+	}
+	if satisfying < count {
+		missing = count - satisfying
+	}
+	return missing, 0
+}
+
+// clusterSizingReport describes an unmet cluster sizing requirement
+func clusterSizingReport(role string, found, count, cpu int, ram float32, missing, undersized int) string {
+	var with []string
+	if cpu > 0 {
+		with = append(with, fmt.Sprintf("%d core%s", cpu, strprocess.Plural(uint(cpu))))
+	}
+	if ram > 0 {
+		with = append(with, fmt.Sprintf("%.1f GB of RAM", ram))
+	}
+	requirement := ""
+	if len(with) > 0 {
+		requirement = "at least " + strings.Join(with, " and ")
+	}
+	if undersized > 0 {
+		return fmt.Sprintf("every %s requires %s, %d out of %d %s%s below", role, requirement, undersized, found, role, strprocess.Plural(uint(found)))
+	}
+	if requirement != "" {
+		requirement = " with " + requirement
+	}
+	return fmt.Sprintf("%d %s%s%s required, %d missing (%d %s%s in cluster)", count, role, strprocess.Plural(uint(count)), requirement, missing, found, role, strprocess.Plural(uint(found)))
+}
+
+// hostsEffectiveSizing returns the effective sizing of the hosts
+func hostsEffectiveSizing(task concurrency.Task, hosts []resources.Host) ([]*propertiesv1.HostEffectiveSizing, fail.Error) {
+	list := make([]*propertiesv1.HostEffectiveSizing, 0, len(hosts))
+	for _, h := range hosts {
+		xerr := h.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+			return props.Inspect(task, hostproperty.SizingV1, func(clonable data.Clonable) fail.Error {
+				hostSizingV1, ok := clonable.(*propertiesv1.HostSizing)
+				if !ok {
+					return fail.InconsistentError("'*propertiesv1.HostSizing' expected, '%s' provided", reflect.TypeOf(clonable).String())
+				}
+				sizing := propertiesv1.NewHostEffectiveSizing()
+				if hostSizingV1.AllocatedSize != nil {
+					*sizing = *hostSizingV1.AllocatedSize
+				}
+				list = append(list, sizing)
+				return nil
+			})
+		})
+		if xerr != nil {
+			return nil, xerr
+		}
+	}
+	return list, nil
 }
 
 // setReverseProxy applies the reverse proxy rules defined in specification file (if there are some)
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources"
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
+)
+
+func Test_parseClusterSizingRequest(t *testing.T) {
+	count, cpu, ram, xerr := parseClusterSizingRequest("count >= 3, cpu >= 2, ram >= 7.5")
+	require.Nil(t, xerr)
+	require.Equal(t, 3, count)
+	require.Equal(t, 2, cpu)
+	require.Equal(t, float32(7.5), ram)
+
+	count, cpu, ram, xerr = parseClusterSizingRequest("cpu >= 2")
+	require.Nil(t, xerr)
+	require.Equal(t, 0, count)
+	require.Equal(t, 2, cpu)
+	require.Equal(t, float32(0), ram)
+
+	_, _, _, xerr = parseClusterSizingRequest("count = 3")
+	require.NotNil(t, xerr)
+	_, _, _, xerr = parseClusterSizingRequest("gpu >= 1")
+	require.NotNil(t, xerr)
+	_, _, _, xerr = parseClusterSizingRequest("cpu >= two")
+	require.NotNil(t, xerr)
+}
+
+func Test_evaluateClusterSizing(t *testing.T) {
+	sizings := []*propertiesv1.HostEffectiveSizing{
+		{Cores: 4, RAMSize: 15.0},
+		{Cores: 2, RAMSize: 7.0},
+		{Cores: 1, RAMSize: 2.0},
+	}
+
+	missing, undersized := evaluateClusterSizing(sizings, 2, 2, 0)
+	require.Equal(t, 0, missing)
+	require.Equal(t, 0, undersized)
+
+	missing, undersized = evaluateClusterSizing(sizings, 3, 2, 7.0)
+	require.Equal(t, 1, missing)
+	require.Equal(t, 0, undersized)
+
+	missing, undersized = evaluateClusterSizing(sizings, 0, 2, 0)
+	require.Equal(t, 0, missing)
+	require.Equal(t, 1, undersized)
+
+	missing, undersized = evaluateClusterSizing(nil, 3, 0, 0)
+	require.Equal(t, 3, missing)
+	require.Equal(t, 0, undersized)
+}
+
+func Test_complementHostDefinitionForAutoExpand(t *testing.T) {
+	pool := propertiesv1.HostSizingRequirements{MinCores: 2, MaxCores: 4, MinRAMSize: 7.0, MaxRAMSize: 16.0}
+
+	// requirements within the pool sizing keep its maximums
+	def := complementHostDefinition(abstract.HostSizingRequirements{MinCores: 4, MinRAMSize: 8.0}, pool)
+	require.Equal(t, 4, def.MaxCores)
+	require.Equal(t, float32(16.0), def.MaxRAMSize)
+
+	// requirements above the pool sizing (or the default one) leave the maximums unbounded
+	def = complementHostDefinition(abstract.HostSizingRequirements{MinCores: 8, MinRAMSize: 32.0}, pool)
+	require.Equal(t, 8, def.MinCores)
+	require.Equal(t, 0, def.MaxCores)
+	require.Equal(t, float32(32.0), def.MinRAMSize)
+	require.Equal(t, float32(0.0), def.MaxRAMSize)
+	def = complementHostDefinition(abstract.HostSizingRequirements{MinCores: 8}, propertiesv1.HostSizingRequirements{})
+	require.Equal(t, 0, def.MaxCores)
+	require.Equal(t, float32(16.0), def.MaxRAMSize)
+
+	// requested maximums are kept
+	def = complementHostDefinition(abstract.HostSizingRequirements{MinCores: 8, MaxCores: 6}, pool)
+	require.Equal(t, 6, def.MaxCores)
+}
+
+func Test_extractHostsFailingCheckInDryRun(t *testing.T) {
+	// in dry run, the feature is not checked (the worker has no feature to check) and every host is concerned
+	w := &worker{settings: resources.FeatureSettings{DryRun: true}}
+	hosts := []resources.Host{&host{}, &host{}}
+	concerned, xerr := w.extractHostsFailingCheck(hosts)
+	require.Nil(t, xerr)
+	require.Equal(t, hosts, concerned)
+}
@@ -30,12 +30,26 @@ import (
 	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
 	"github.com/CS-SI/SafeScale/lib/utils/crypt"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/metrics"
 	netretry "github.com/CS-SI/SafeScale/lib/utils/net"
 	"github.com/CS-SI/SafeScale/lib/utils/retry"
 	"github.com/CS-SI/SafeScale/lib/utils/retry/enums/verdict"
 	"github.com/CS-SI/SafeScale/lib/utils/temporal"
 )
 
+var (
+	metadataDuration = metrics.NewHistogramVec("safescale_metadata_duration_seconds", "Duration of the reads and writes of metadata in Object Storage", nil, "operation")
+	metadataFailures = metrics.NewCounterVec("safescale_metadata_failures_total", "Number of reads and writes of metadata in Object Storage that failed", "operation")
+)
+
+// observeMetadata records the duration and the result of a read or a write of metadata
+func observeMetadata(operation string, start time.Time, xerr fail.Error) {
+	metadataDuration.Observe(time.Since(start).Seconds(), operation)
+	if xerr != nil {
+		metadataFailures.Inc(operation)
+	}
+}
+
 // folder describes a metadata folder
 type folder struct {
 	// path contains the base path where to read/write record in Object Storage
@@ -163,7 +177,7 @@ func (f folder) Delete(path string, name string) fail.Error {
 // returns false, err if an error occured
 // returns true, nil if the object has been found
 // The callback function has to know how to decode it and where to store the result
-func (f folder) Read(path string, name string, callback func([]byte) fail.Error) fail.Error {
+func (f folder) Read(path string, name string, callback func([]byte) fail.Error) (xerr fail.Error) {
 	if f.IsNull() {
 		return fail.InvalidInstanceError()
 	}
@@ -174,6 +188,8 @@ func (f folder) Read(path string, name string, callback func([]byte) fail.Error)
 		return fail.InvalidParameterError("callback", "cannot be nil")
 	}
 
+	defer func(start time.Time) { observeMetadata("read", start, xerr) }(time.Now())
+
 	// if xerr := f.Lookup(path, name); xerr != nil {
 	// 	switch xerr.(type) {
 	// 	case *fail.ErrNotFound:
@@ -184,7 +200,7 @@ func (f folder) Read(path string, name string, callback func([]byte) fail.Error)
 	// }
 
 	var buffer bytes.Buffer
-	xerr := netretry.WhileCommunicationUnsuccessfulDelay1Second(
+	xerr = netretry.WhileCommunicationUnsuccessfulDelay1Second(
 		func() error {
 			innerErr := f.service.ReadObject(f.getBucket().Name, f.absolutePath(path, name), &buffer, 0, 0)
 			return innerErr
@@ -214,7 +230,7 @@ func (f folder) Read(path string, name string, callback func([]byte) fail.Error)
 // Returns nil on success (with assurance the write has been committed on remote side)
 // May return fail.ErrTimeout if the read-after-write operation timed out.
 // Return any other errors that can occur from the remote side
-func (f folder) Write(path string, name string, content []byte) fail.Error {
+func (f folder) Write(path string, name string, content []byte) (xerr fail.Error) {
 	if f.IsNull() {
 		return fail.InvalidInstanceError()
 	}
@@ -222,6 +238,8 @@ func (f folder) Write(path string, name string, content []byte) fail.Error {
 		return fail.InvalidParameterError("name", "cannot be empty string")
 	}
 
+	defer func(start time.Time) { observeMetadata("write", start, xerr) }(time.Now())
+
 	var data []byte
 	if f.crypt {
 		var err error
@@ -237,7 +255,7 @@ func (f folder) Write(path string, name string, content []byte) fail.Error {
 	timeout := temporal.GetMetadataReadAfterWriteTimeout()
 
 	// Outer retry will write the metadata at most 3 times
-	xerr := retry.Action(
+	xerr = retry.Action(
 		func() error {
 			var innerXErr fail.Error
 			source := bytes.NewBuffer(data)
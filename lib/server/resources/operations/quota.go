@@ -0,0 +1,451 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/CS-SI/SafeScale/lib/server/auth"
+	"github.com/CS-SI/SafeScale/lib/server/iaas"
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/hostproperty"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/volumeproperty"
+	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
+	propertiesv2 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v2"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+	"github.com/CS-SI/SafeScale/lib/utils/data"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/serialize"
+)
+
+// QuotaUsage contains amounts of resources, indexed by kind of resource
+type QuotaUsage map[iaas.QuotaResource]float64
+
+// add adds the amounts of other to the usage
+func (u QuotaUsage) add(other QuotaUsage) {
+	for k, v := range other {
+		u[k] += v
+	}
+}
+
+// TenantUsage contains the resources used on a tenant, computed from metadata
+type TenantUsage struct {
+	Total   QuotaUsage            // resources of the whole tenant
+	ByOwner map[string]QuotaUsage // resources created by each authenticated caller, indexed by name of the caller
+}
+
+// addTo adds usage to the total and to the resources of the owner, if any
+func (tu TenantUsage) addTo(owner string, usage QuotaUsage) {
+	tu.Total.add(usage)
+	if owner == "" {
+		return
+	}
+	if _, ok := tu.ByOwner[owner]; !ok {
+		tu.ByOwner[owner] = QuotaUsage{}
+	}
+	tu.ByOwner[owner].add(usage)
+}
+
+// ComputeTenantUsage computes from metadata the resources used on the tenant of svc, including the resources reserved
+// by the creations in progress
+func ComputeTenantUsage(task concurrency.Task, svc iaas.Service) (TenantUsage, fail.Error) {
+	usage, xerr := computeTenantUsage(task, svc, "")
+	if xerr != nil {
+		return TenantUsage{}, xerr
+	}
+	addReservedQuotaUsage(usage, svc.GetName())
+	if providerQuotas, xerr := svc.GetProviderQuotas(); xerr == nil {
+		addProviderPublicIPs(usage, providerQuotas)
+	}
+	return usage, nil
+}
+
+// addProviderPublicIPs raises the number of public IPs used on the tenant to the number reported by the provider, which
+// includes the public IPs not attached to a host
+func addProviderPublicIPs(usage TenantUsage, providerQuotas abstract.ProviderQuotas) {
+	if quota, ok := providerQuotas[string(iaas.QuotaPublicIPs)]; ok && quota.Used > usage.Total[iaas.QuotaPublicIPs] {
+		usage.Total[iaas.QuotaPublicIPs] = quota.Used
+	}
+}
+
+// computeTenantUsage computes from metadata the resources used on the tenant of svc, ignoring the cluster named
+// 'ignoredCluster' (used when the metadata of a cluster being created already exist)
+func computeTenantUsage(task concurrency.Task, svc iaas.Service, ignoredCluster string) (TenantUsage, fail.Error) {
+	if task.IsNull() {
+		return TenantUsage{}, fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+	if svc.IsNull() {
+		return TenantUsage{}, fail.InvalidParameterError("svc", "cannot be null value of 'iaas.Service'")
+	}
+
+	usage := TenantUsage{Total: QuotaUsage{}, ByOwner: map[string]QuotaUsage{}}
+
+	rh, xerr := NewHost(svc)
+	if xerr != nil {
+		return TenantUsage{}, xerr
+	}
+	xerr = rh.Browse(task, func(ahc *abstract.HostCore) fail.Error {
+		owner, hostUsage, innerXErr := hostUsageOf(task, svc, ahc.ID)
+		if innerXErr != nil {
+			if _, ok := innerXErr.(*fail.ErrNotFound); ok {
+				// host deleted in the meantime
+				return nil
+			}
+			return innerXErr
+		}
+		usage.addTo(owner, hostUsage)
+		return nil
+	})
+	if xerr != nil {
+		return TenantUsage{}, fail.Wrap(xerr, "failed to compute the resources used by hosts")
+	}
+
+	rv, xerr := NewVolume(svc)
+	if xerr != nil {
+		return TenantUsage{}, xerr
+	}
+	xerr = rv.Browse(task, func(av *abstract.Volume) fail.Error {
+		owner, innerXErr := volumeOwnerOf(task, svc, av.ID)
+		if innerXErr != nil {
+			if _, ok := innerXErr.(*fail.ErrNotFound); ok {
+				// volume deleted in the meantime
+				return nil
+			}
+			return innerXErr
+		}
+		usage.addTo(owner, QuotaUsage{iaas.QuotaVolumeGB: float64(av.Size)})
+		return nil
+	})
+	if xerr != nil {
+		return TenantUsage{}, fail.Wrap(xerr, "failed to compute the resources used by volumes")
+	}
+
+	rc, xerr := NewCluster(task, svc)
+	if xerr != nil {
+		return TenantUsage{}, xerr
+	}
+	xerr = rc.Browse(task, func(aci *abstract.ClusterIdentity) fail.Error {
+		if aci.Name != ignoredCluster {
+			usage.addTo(aci.Owner, QuotaUsage{iaas.QuotaClusters: 1})
+		}
+		return nil
+	})
+	if xerr != nil {
+		return TenantUsage{}, fail.Wrap(xerr, "failed to compute the number of clusters")
+	}
+
+	return usage, nil
+}
+
+// hostUsageOf returns the owner of the host and the resources it uses
+func hostUsageOf(task concurrency.Task, svc iaas.Service, id string) (owner string, usage QuotaUsage, xerr fail.Error) {
+	rh, xerr := LoadHost(task, svc, id)
+	if xerr != nil {
+		return "", nil, xerr
+	}
+
+	usage = QuotaUsage{iaas.QuotaHosts: 1}
+	xerr = rh.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		innerXErr := props.Inspect(task, hostproperty.SizingV1, func(clonable data.Clonable) fail.Error {
+			hostSizingV1, ok := clonable.(*propertiesv1.HostSizing)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.HostSizing' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			if hostSizingV1.AllocatedSize != nil {
+				usage[iaas.QuotaCores] = float64(hostSizingV1.AllocatedSize.Cores)
+				usage[iaas.QuotaRAM] = float64(hostSizingV1.AllocatedSize.RAMSize)
+				usage[iaas.QuotaGPUs] = float64(hostSizingV1.AllocatedSize.GPUNumber)
+			}
+			return nil
+		})
+		if innerXErr != nil {
+			return innerXErr
+		}
+
+		innerXErr = props.Inspect(task, hostproperty.DescriptionV1, func(clonable data.Clonable) fail.Error {
+			hostDescriptionV1, ok := clonable.(*propertiesv1.HostDescription)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv1.HostDescription' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			owner = hostDescriptionV1.Owner
+			return nil
+		})
+		if innerXErr != nil {
+			return innerXErr
+		}
+
+		return props.Inspect(task, hostproperty.NetworkV2, func(clonable data.Clonable) fail.Error {
+			hnV2, ok := clonable.(*propertiesv2.HostNetworking)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv2.HostNetworking' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			if hnV2.PublicIPv4 != "" || hnV2.PublicIPv6 != "" {
+				usage[iaas.QuotaPublicIPs] = 1
+			}
+			return nil
+		})
+	})
+	if xerr != nil {
+		return "", nil, xerr
+	}
+	return owner, usage, nil
+}
+
+// volumeOwnerOf returns the owner of the volume
+func volumeOwnerOf(task concurrency.Task, svc iaas.Service, id string) (owner string, xerr fail.Error) {
+	rv, xerr := LoadVolume(task, svc, id)
+	if xerr != nil {
+		return "", xerr
+	}
+
+	xerr = rv.Inspect(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		if !props.Lookup(volumeproperty.DescriptionV2) {
+			return nil
+		}
+		return props.Inspect(task, volumeproperty.DescriptionV2, func(clonable data.Clonable) fail.Error {
+			volumeDescriptionV2, ok := clonable.(*propertiesv2.VolumeDescription)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv2.VolumeDescription' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			owner = volumeDescriptionV2.Owner
+			return nil
+		})
+	})
+	return owner, xerr
+}
+
+// ownerOfTask returns the name of the authenticated caller on behalf of which the task runs (empty without
+// authentication)
+func ownerOfTask(task concurrency.Task) string {
+	if task.IsNull() {
+		return ""
+	}
+	ctx, xerr := task.GetContext()
+	if xerr != nil {
+		return ""
+	}
+	if identity := auth.FromContext(ctx); identity != nil {
+		return identity.Name
+	}
+	return ""
+}
+
+// templateQuotaUsage returns the resources used by 'count' hosts of template 'tmpl'
+func templateQuotaUsage(tmpl abstract.HostTemplate, publicIP bool, count uint) QuotaUsage {
+	usage := QuotaUsage{
+		iaas.QuotaHosts: float64(count),
+		iaas.QuotaCores: float64(count) * float64(tmpl.Cores),
+		iaas.QuotaRAM:   float64(count) * float64(tmpl.RAMSize),
+		iaas.QuotaGPUs:  float64(count) * float64(tmpl.GPUNumber),
+	}
+	if publicIP {
+		usage[iaas.QuotaPublicIPs] = float64(count)
+	}
+	return usage
+}
+
+// sizingQuotaUsage returns the resources used by 'count' hosts satisfying 'def'
+func sizingQuotaUsage(svc iaas.Service, def abstract.HostSizingRequirements, publicIP bool, count uint) (QuotaUsage, fail.Error) {
+	if count == 0 {
+		return QuotaUsage{}, nil
+	}
+
+	var (
+		tmpl *abstract.HostTemplate
+		xerr fail.Error
+	)
+	if def.Template != "" {
+		tmpl, xerr = svc.FindTemplateByName(def.Template)
+	} else {
+		tmpl, xerr = svc.FindTemplateBySizing(def)
+	}
+	if xerr != nil {
+		return nil, xerr
+	}
+	return templateQuotaUsage(*tmpl, publicIP, count), nil
+}
+
+// quotaReservation contains the resources reserved for a creation in progress, until they are recorded in metadata
+type quotaReservation struct {
+	id     string
+	tenant string
+	owner  string
+	usage  QuotaUsage
+}
+
+var (
+	// quotaReservations contains the reservations of the creations in progress, indexed by id
+	quotaReservations     = map[string]*quotaReservation{}
+	quotaReservationsLock sync.Mutex
+	quotaReservationCount uint64
+	// tenantQuotaLocks serializes the checks and reservations of quotas of each tenant, indexed by name of tenant
+	tenantQuotaLocks     = map[string]*sync.Mutex{}
+	tenantQuotaLocksLock sync.Mutex
+)
+
+// tenantQuotaLock returns the lock serializing the reservations of quotas on the tenant
+func tenantQuotaLock(tenant string) *sync.Mutex {
+	tenantQuotaLocksLock.Lock()
+	defer tenantQuotaLocksLock.Unlock()
+
+	lock, ok := tenantQuotaLocks[tenant]
+	if !ok {
+		lock = &sync.Mutex{}
+		tenantQuotaLocks[tenant] = lock
+	}
+	return lock
+}
+
+// newQuotaReservation registers the reservation of the resources 'requested' on the tenant by the owner
+func newQuotaReservation(tenant, owner string, requested QuotaUsage) *quotaReservation {
+	r := &quotaReservation{
+		id:     fmt.Sprintf("%s-%d", tenant, atomic.AddUint64(&quotaReservationCount, 1)),
+		tenant: tenant,
+		owner:  owner,
+		usage:  QuotaUsage{},
+	}
+	r.usage.add(requested)
+
+	quotaReservationsLock.Lock()
+	defer quotaReservationsLock.Unlock()
+
+	quotaReservations[r.id] = r
+	return r
+}
+
+// addReservedQuotaUsage adds to usage the resources reserved on the tenant by the creations in progress
+func addReservedQuotaUsage(usage TenantUsage, tenant string) {
+	quotaReservationsLock.Lock()
+	defer quotaReservationsLock.Unlock()
+
+	for _, r := range quotaReservations {
+		if r.tenant == tenant {
+			usage.addTo(r.owner, r.usage)
+		}
+	}
+}
+
+// consume removes from the reservation the resources 'usage', now recorded in metadata
+func (r *quotaReservation) consume(usage QuotaUsage) {
+	if r == nil {
+		return
+	}
+	quotaReservationsLock.Lock()
+	defer quotaReservationsLock.Unlock()
+
+	for k, v := range usage {
+		r.usage[k] -= v
+		if r.usage[k] < 0 {
+			r.usage[k] = 0
+		}
+	}
+}
+
+// release removes the reservation, the creation being over
+func (r *quotaReservation) release() {
+	if r == nil {
+		return
+	}
+	quotaReservationsLock.Lock()
+	defer quotaReservationsLock.Unlock()
+
+	delete(quotaReservations, r.id)
+}
+
+// consumeQuotaReservation removes the resources 'usage' from the reservation identified by 'id', if it still exists
+func consumeQuotaReservation(id string, usage QuotaUsage) {
+	quotaReservationsLock.Lock()
+	r := quotaReservations[id]
+	quotaReservationsLock.Unlock()
+
+	r.consume(usage)
+}
+
+// reserveQuotas verifies that creating the resources 'requested' on behalf of the caller of the task respects the
+// quotas of the tenant, of the caller and of the provider, counting the resources reserved by the other creations in
+// progress, then reserves them until the returned reservation is released; the cluster named 'ignoredCluster' is not
+// counted in use
+// The checks and reservations of a tenant are serialized, so concurrent creations cannot exceed the quotas together
+// Returns *fail.ErrOverflow if a quota would be exceeded
+func reserveQuotas(task concurrency.Task, svc iaas.Service, requested QuotaUsage, ignoredCluster string) (*quotaReservation, fail.Error) {
+	if task.IsNull() {
+		return nil, fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
+	}
+	if svc.IsNull() {
+		return nil, fail.InvalidParameterError("svc", "cannot be null value of 'iaas.Service'")
+	}
+
+	tenant := svc.GetName()
+	lock := tenantQuotaLock(tenant)
+	lock.Lock()
+	defer lock.Unlock()
+
+	owner := ownerOfTask(task)
+	providerQuotas, xerr := svc.GetProviderQuotas()
+	if xerr != nil {
+		switch xerr.(type) {
+		case *fail.ErrNotImplemented:
+		default:
+			logrus.Warnf("failed to get the quotas of the provider, not checked: %v", xerr)
+		}
+		providerQuotas = nil
+	}
+
+	quotas := svc.GetQuotas()
+	if !quotas.IsEmpty() {
+		userLimits := quotas.ForUser(owner)
+		usage, xerr := computeTenantUsage(task, svc, ignoredCluster)
+		if xerr != nil {
+			return nil, xerr
+		}
+		addReservedQuotaUsage(usage, tenant)
+		addProviderPublicIPs(usage, providerQuotas)
+		ownerUsage := usage.ByOwner[owner]
+		for _, r := range iaas.QuotaResources {
+			if requested[r] <= 0 {
+				continue
+			}
+			if limit, ok := quotas.Tenant[r]; ok && usage.Total[r]+requested[r] > limit {
+				return nil, fail.OverflowError(nil, 0, "quota '%s' of the tenant exceeded (%g used, %g requested, limit %g)", r, usage.Total[r], requested[r], limit)
+			}
+			if limit, ok := userLimits[r]; ok && ownerUsage[r]+requested[r] > limit {
+				return nil, fail.OverflowError(nil, 0, "quota '%s' of user '%s' exceeded (%g used, %g requested, limit %g)", r, owner, ownerUsage[r], requested[r], limit)
+			}
+		}
+	}
+
+	// Checks the quotas of the provider to fail before starting a creation that cannot complete; the resources reserved
+	// are not yet reported as used by the provider
+	reserved := TenantUsage{Total: QuotaUsage{}, ByOwner: map[string]QuotaUsage{}}
+	addReservedQuotaUsage(reserved, tenant)
+	for _, r := range iaas.QuotaResources {
+		quota, ok := providerQuotas[string(r)]
+		if !ok || quota.Limit < 0 || requested[r] <= 0 {
+			continue
+		}
+		if quota.Used+reserved.Total[r]+requested[r] > quota.Limit {
+			return nil, fail.OverflowError(nil, 0, "quota '%s' of the provider exceeded (%g used, %g reserved, %g requested, limit %g)", r, quota.Used, reserved.Total[r], requested[r], quota.Limit)
+		}
+	}
+
+	return newQuotaReservation(tenant, owner, requested), nil
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/server/auth"
+	"github.com/CS-SI/SafeScale/lib/server/iaas"
+	"github.com/CS-SI/SafeScale/lib/server/resources/abstract"
+	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
+)
+
+func Test_templateQuotaUsage(t *testing.T) {
+	tmpl := abstract.HostTemplate{Cores: 4, RAMSize: 15.5, GPUNumber: 1}
+	assert.Equal(t, QuotaUsage{iaas.QuotaHosts: 3, iaas.QuotaCores: 12, iaas.QuotaRAM: 46.5, iaas.QuotaGPUs: 3}, templateQuotaUsage(tmpl, false, 3))
+	assert.Equal(t, float64(2), templateQuotaUsage(tmpl, true, 2)[iaas.QuotaPublicIPs])
+}
+
+func TestTenantUsage(t *testing.T) {
+	usage := TenantUsage{Total: QuotaUsage{}, ByOwner: map[string]QuotaUsage{}}
+	usage.addTo("alice", QuotaUsage{iaas.QuotaHosts: 1, iaas.QuotaCores: 2})
+	usage.addTo("alice", QuotaUsage{iaas.QuotaHosts: 1, iaas.QuotaCores: 4})
+	usage.addTo("", QuotaUsage{iaas.QuotaVolumeGB: 100})
+	assert.Equal(t, QuotaUsage{iaas.QuotaHosts: 2, iaas.QuotaCores: 6, iaas.QuotaVolumeGB: 100}, usage.Total)
+	assert.Equal(t, map[string]QuotaUsage{"alice": {iaas.QuotaHosts: 2, iaas.QuotaCores: 6}}, usage.ByOwner)
+}
+
+func Test_ownerOfTask(t *testing.T) {
+	task, xerr := concurrency.NewTaskWithContext(auth.NewContext(context.Background(), &auth.Identity{Name: "alice"}), nil)
+	require.Nil(t, xerr)
+	assert.Equal(t, "alice", ownerOfTask(task))
+
+	task, xerr = concurrency.NewTaskWithContext(context.Background(), nil)
+	require.Nil(t, xerr)
+	assert.Equal(t, "", ownerOfTask(task))
+}
+
+func Test_quotaReservation(t *testing.T) {
+	cluster := newQuotaReservation("test", "alice", QuotaUsage{iaas.QuotaHosts: 3, iaas.QuotaCores: 6})
+	volume := newQuotaReservation("test", "", QuotaUsage{iaas.QuotaVolumeGB: 100})
+	other := newQuotaReservation("other", "bob", QuotaUsage{iaas.QuotaHosts: 1})
+	defer other.release()
+
+	usage := TenantUsage{Total: QuotaUsage{iaas.QuotaHosts: 1}, ByOwner: map[string]QuotaUsage{}}
+	addReservedQuotaUsage(usage, "test")
+	assert.Equal(t, QuotaUsage{iaas.QuotaHosts: 4, iaas.QuotaCores: 6, iaas.QuotaVolumeGB: 100}, usage.Total)
+	assert.Equal(t, map[string]QuotaUsage{"alice": {iaas.QuotaHosts: 3, iaas.QuotaCores: 6}}, usage.ByOwner)
+
+	// the hosts created by the cluster are counted from metadata instead of the reservation
+	consumeQuotaReservation(cluster.id, QuotaUsage{iaas.QuotaHosts: 1, iaas.QuotaCores: 4})
+	consumeQuotaReservation(cluster.id, QuotaUsage{iaas.QuotaHosts: 1, iaas.QuotaCores: 4})
+	volume.release()
+	usage = TenantUsage{Total: QuotaUsage{}, ByOwner: map[string]QuotaUsage{}}
+	addReservedQuotaUsage(usage, "test")
+	assert.Equal(t, QuotaUsage{iaas.QuotaHosts: 1, iaas.QuotaCores: 0}, usage.Total)
+
+	cluster.release()
+	consumeQuotaReservation(cluster.id, QuotaUsage{iaas.QuotaHosts: 1})
+	usage = TenantUsage{Total: QuotaUsage{}, ByOwner: map[string]QuotaUsage{}}
+	addReservedQuotaUsage(usage, "test")
+	assert.Empty(t, usage.Total)
+}
+
+func Test_addProviderPublicIPs(t *testing.T) {
+	usage := TenantUsage{Total: QuotaUsage{iaas.QuotaPublicIPs: 2}, ByOwner: map[string]QuotaUsage{}}
+	addProviderPublicIPs(usage, abstract.ProviderQuotas{"PublicIPs": {Limit: 10, Used: 1}})
+	assert.Equal(t, float64(2), usage.Total[iaas.QuotaPublicIPs])
+
+	// the public IPs not attached to a host are only known by the provider
+	addProviderPublicIPs(usage, abstract.ProviderQuotas{"PublicIPs": {Limit: 10, Used: 5}})
+	assert.Equal(t, float64(5), usage.Total[iaas.QuotaPublicIPs])
+
+	addProviderPublicIPs(usage, nil)
+	assert.Equal(t, float64(5), usage.Total[iaas.QuotaPublicIPs])
+}
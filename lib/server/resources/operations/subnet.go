@@ -614,6 +614,7 @@ func (rs *subnet) Create(task concurrency.Task, req abstract.SubnetRequest, gwna
 		TemplateID:       template.ID,
 		KeepOnFailure:    req.KeepOnFailure,
 		SecurityGroupIDs: sgs,
+		QuotaReservation: req.QuotaReservation,
 	}
 
 	var (
@@ -34,6 +34,7 @@ import (
 	"github.com/CS-SI/SafeScale/lib/server/resources/enums/volumespeed"
 	"github.com/CS-SI/SafeScale/lib/server/resources/operations/converters"
 	propertiesv1 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v1"
+	propertiesv2 "github.com/CS-SI/SafeScale/lib/server/resources/properties/v2"
 	"github.com/CS-SI/SafeScale/lib/system/nfs"
 	"github.com/CS-SI/SafeScale/lib/utils/cli/enums/outputs"
 	"github.com/CS-SI/SafeScale/lib/utils/concurrency"
@@ -284,6 +285,12 @@ func (rv *volume) Create(task concurrency.Task, req abstract.VolumeRequest) (xer
 	}
 
 	svc := rv.GetService()
+	reservation, xerr := reserveQuotas(task, svc, QuotaUsage{iaas.QuotaVolumeGB: float64(req.Size)}, "")
+	if xerr != nil {
+		return xerr
+	}
+	defer reservation.release()
+
 	av, xerr := svc.CreateVolume(req)
 	if xerr != nil {
 		return xerr
@@ -298,8 +305,32 @@ func (rv *volume) Create(task concurrency.Task, req abstract.VolumeRequest) (xer
 		}
 	}()
 
+	if xerr = rv.Carry(task, av); xerr != nil {
+		return xerr
+	}
+
+	// Starting from here, remove metadata if exiting with error
+	defer func() {
+		if xerr != nil {
+			if derr := rv.core.Delete(task); derr != nil {
+				_ = xerr.AddConsequence(fail.Wrap(derr, "cleaning up on failure, failed to delete metadata of volume '%s'", req.Name))
+			}
+		}
+	}()
+
 	// Sets err to possibly trigger defer calls
-	return rv.Carry(task, av)
+	xerr = rv.Alter(task, func(_ data.Clonable, props *serialize.JSONProperties) fail.Error {
+		return props.Alter(task, volumeproperty.DescriptionV2, func(clonable data.Clonable) fail.Error {
+			volumeDescriptionV2, ok := clonable.(*propertiesv2.VolumeDescription)
+			if !ok {
+				return fail.InconsistentError("'*propertiesv2.VolumeDescription' expected, '%s' provided", reflect.TypeOf(clonable).String())
+			}
+			volumeDescriptionV2.Created = time.Now()
+			volumeDescriptionV2.Owner = ownerOfTask(task)
+			return nil
+		})
+	})
+	return xerr
 }
 
 // Attach a volume to an host
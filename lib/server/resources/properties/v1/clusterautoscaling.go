@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package propertiesv1
+
+import (
+	"time"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterproperty"
+	"github.com/CS-SI/SafeScale/lib/utils/data"
+	"github.com/CS-SI/SafeScale/lib/utils/serialize"
+)
+
+const (
+	// MaxClusterAutoscalingDecisions is the number of decisions of the autoscaler kept in metadata
+	MaxClusterAutoscalingDecisions = 20
+
+	// ClusterAutoscalingMetricLoad drives the autoscaler with the average load per core of the nodes, collected over SSH
+	ClusterAutoscalingMetricLoad = "load"
+	// ClusterAutoscalingMetricPods drives the autoscaler with the number of pending pods reported by the Flavor (K8S only)
+	ClusterAutoscalingMetricPods = "pods"
+)
+
+// ClusterAutoscalingPolicy describes how the autoscaler manages the size of a node pool of the cluster
+// not FROZEN yet
+// Note: if tagged as FROZEN, must not be changed ever.
+//       Create a new version instead with needed supplemental/overriding fields
+type ClusterAutoscalingPolicy struct {
+	Enabled            bool          `json:"enabled"`
+	Pool               string        `json:"pool,omitempty"`                 // node pool managed by the autoscaler (default pool if empty)
+	MinNodes           uint          `json:"min_nodes"`                      // minimum number of nodes in the pool
+	MaxNodes           uint          `json:"max_nodes"`                      // maximum number of nodes in the pool
+	Cooldown           time.Duration `json:"cooldown"`                       // minimum delay between 2 scaling operations
+	Metric             string        `json:"metric"`                         // source of the metric driving the decisions ('load' or 'pods')
+	ScaleUpThreshold   float64       `json:"scale_up_threshold,omitempty"`   // average load per core above which a node is added
+	ScaleDownThreshold float64       `json:"scale_down_threshold,omitempty"` // average load per core under which a node is removed
+}
+
+// ClusterAutoscalingDecision describes an evaluation of the policy by the autoscaler
+// not FROZEN yet
+type ClusterAutoscalingDecision struct {
+	Date   time.Time `json:"date"`
+	Action string    `json:"action"`          // 'expand', 'shrink' or 'none'
+	Count  int       `json:"count,omitempty"` // number of nodes added or removed
+	Reason string    `json:"reason,omitempty"`
+	Error  string    `json:"error,omitempty"` // error message if the scaling operation failed
+}
+
+// ClusterAutoscaling contains the autoscaling policy of the cluster and the last decisions of the autoscaler
+// not FROZEN yet
+type ClusterAutoscaling struct {
+	Policy      ClusterAutoscalingPolicy     `json:"policy"`
+	LastScaling time.Time                    `json:"last_scaling,omitempty"` // date of the last scaling operation, used to honor cooldown
+	Decisions   []ClusterAutoscalingDecision `json:"decisions,omitempty"`    // last decisions, the most recent last
+}
+
+func newClusterAutoscaling() *ClusterAutoscaling {
+	return &ClusterAutoscaling{
+		Decisions: []ClusterAutoscalingDecision{},
+	}
+}
+
+// Clone ...
+// satisfies interface data.Clonable
+func (ca ClusterAutoscaling) Clone() data.Clonable {
+	return newClusterAutoscaling().Replace(&ca)
+}
+
+// Replace ...
+// satisfies interface data.Clonable
+func (ca *ClusterAutoscaling) Replace(p data.Clonable) data.Clonable {
+	// Do not test with IsNull(), it's allowed to clone a null value...
+	if ca == nil || p == nil {
+		return ca
+	}
+
+	src := p.(*ClusterAutoscaling)
+	*ca = *src
+	ca.Decisions = make([]ClusterAutoscalingDecision, len(src.Decisions))
+	copy(ca.Decisions, src.Decisions)
+	return ca
+}
+
+// AddDecision records a decision, keeping only the MaxClusterAutoscalingDecisions most recent ones
+func (ca *ClusterAutoscaling) AddDecision(decision ClusterAutoscalingDecision) {
+	ca.Decisions = append(ca.Decisions, decision)
+	if len(ca.Decisions) > MaxClusterAutoscalingDecisions {
+		ca.Decisions = ca.Decisions[len(ca.Decisions)-MaxClusterAutoscalingDecisions:]
+	}
+}
+
+func init() {
+	serialize.PropertyTypeRegistry.Register("resources.cluster", clusterproperty.AutoscalingV1, newClusterAutoscaling())
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package propertiesv1
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoscaling_Clone(t *testing.T) {
+	ct := newClusterAutoscaling()
+	ct.Policy = ClusterAutoscalingPolicy{Enabled: true, MinNodes: 1, MaxNodes: 5, Cooldown: 5 * time.Minute, Metric: "load"}
+	ct.AddDecision(ClusterAutoscalingDecision{Action: "expand", Count: 1, Reason: "average load 0.93 above 0.80"})
+
+	clonedCt, ok := ct.Clone().(*ClusterAutoscaling)
+	if !ok {
+		t.Fail()
+	}
+
+	assert.Equal(t, ct, clonedCt)
+	clonedCt.Decisions[0].Action = "shrink"
+
+	areEqual := reflect.DeepEqual(ct, clonedCt)
+	if areEqual {
+		t.Error("It's a shallow clone !")
+		t.Fail()
+	}
+}
+
+func TestAutoscaling_AddDecision(t *testing.T) {
+	ct := newClusterAutoscaling()
+	for i := 0; i < MaxClusterAutoscalingDecisions+5; i++ {
+		ct.AddDecision(ClusterAutoscalingDecision{Action: "none", Count: i})
+	}
+	assert.Equal(t, MaxClusterAutoscalingDecisions, len(ct.Decisions))
+	assert.Equal(t, 5, ct.Decisions[0].Count)
+	assert.Equal(t, MaxClusterAutoscalingDecisions+4, ct.Decisions[len(ct.Decisions)-1].Count)
+}
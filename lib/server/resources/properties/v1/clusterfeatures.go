@@ -29,6 +29,7 @@ import (
 type ClusterInstalledFeature struct {
 	RequiredBy []string `json:"required_by,omitempty"` // tells what feature(s) needs this one
 	Requires   []string `json:"requires,omitempty"`
+	Version    string   `json:"version,omitempty"` // tells what version of the feature is installed
 }
 
 // newClusterInstalledFeature ...
@@ -58,6 +59,7 @@ func (cif *ClusterInstalledFeature) Replace(p data.Clonable) data.Clonable {
 	copy(cif.RequiredBy, src.RequiredBy)
 	cif.Requires = make([]string, len(src.Requires))
 	copy(cif.Requires, src.Requires)
+	cif.Version = src.Version
 	return cif
 }
 
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package propertiesv1
+
+import (
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterproperty"
+	"github.com/CS-SI/SafeScale/lib/utils/data"
+	"github.com/CS-SI/SafeScale/lib/utils/serialize"
+)
+
+// ClusterNodePool describes a group of nodes of the cluster sharing the same settings
+// not FROZEN yet
+// Note: if tagged as FROZEN, must not be changed ever.
+//       Create a new version instead with needed supplemental/overriding fields
+type ClusterNodePool struct {
+	Name   string                 `json:"name"`
+	Sizing HostSizingRequirements `json:"sizing"`           // sizing of the nodes of the pool
+	Image  string                 `json:"image,omitempty"`  // image of the nodes of the pool (cluster default image if empty)
+	Count  uint                   `json:"count"`            // number of nodes requested at cluster creation
+	Labels map[string]string      `json:"labels,omitempty"` // labels applied to the nodes of the pool (if the flavor supports it)
+	Taints []string               `json:"taints,omitempty"` // taints applied to the nodes of the pool, in the form 'key[=value]:effect' (if the flavor supports it)
+	// Nodes contains the numerical IDs of the nodes of the pool; unused for the default pool,
+	// which owns every private node not belonging to another pool
+	Nodes []uint `json:"nodes,omitempty"`
+}
+
+// NewClusterNodePool ...
+func NewClusterNodePool() *ClusterNodePool {
+	return &ClusterNodePool{
+		Labels: map[string]string{},
+		Taints: []string{},
+		Nodes:  []uint{},
+	}
+}
+
+// Clone ...
+// satisfies interface data.Clonable
+func (np ClusterNodePool) Clone() data.Clonable {
+	return NewClusterNodePool().Replace(&np)
+}
+
+// Replace ...
+// satisfies interface data.Clonable
+func (np *ClusterNodePool) Replace(p data.Clonable) data.Clonable {
+	// Do not test with IsNull(), it's allowed to clone a null value...
+	if np == nil || p == nil {
+		return np
+	}
+
+	src := p.(*ClusterNodePool)
+	*np = *src
+	np.Labels = make(map[string]string, len(src.Labels))
+	for k, v := range src.Labels {
+		np.Labels[k] = v
+	}
+	np.Taints = make([]string, len(src.Taints))
+	copy(np.Taints, src.Taints)
+	np.Nodes = make([]uint, len(src.Nodes))
+	copy(np.Nodes, src.Nodes)
+	return np
+}
+
+// ClusterNodePools contains the node pools of the cluster
+// not FROZEN yet
+type ClusterNodePools struct {
+	ByName map[string]*ClusterNodePool `json:"by_name"` // pools indexed by name
+}
+
+func newClusterNodePools() *ClusterNodePools {
+	return &ClusterNodePools{
+		ByName: map[string]*ClusterNodePool{},
+	}
+}
+
+// Clone ...
+// satisfies interface data.Clonable
+func (nps ClusterNodePools) Clone() data.Clonable {
+	return newClusterNodePools().Replace(&nps)
+}
+
+// Replace ...
+// satisfies interface data.Clonable
+func (nps *ClusterNodePools) Replace(p data.Clonable) data.Clonable {
+	// Do not test with IsNull(), it's allowed to clone a null value...
+	if nps == nil || p == nil {
+		return nps
+	}
+
+	src := p.(*ClusterNodePools)
+	nps.ByName = make(map[string]*ClusterNodePool, len(src.ByName))
+	for k, v := range src.ByName {
+		nps.ByName[k] = v.Clone().(*ClusterNodePool)
+	}
+	return nps
+}
+
+// PoolOfNode returns the name of the pool containing the node identified by its numerical ID, or "" if the node belongs to the default pool
+func (nps ClusterNodePools) PoolOfNode(numericalID uint) string {
+	for k, v := range nps.ByName {
+		for _, id := range v.Nodes {
+			if id == numericalID {
+				return k
+			}
+		}
+	}
+	return ""
+}
+
+func init() {
+	serialize.PropertyTypeRegistry.Register("resources.cluster", clusterproperty.NodePoolsV1, newClusterNodePools())
+}
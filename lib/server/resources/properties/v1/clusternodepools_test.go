@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package propertiesv1
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodePools_Clone(t *testing.T) {
+	ct := newClusterNodePools()
+	ct.ByName["gpu"] = &ClusterNodePool{
+		Name:   "gpu",
+		Sizing: HostSizingRequirements{MinGPU: 1},
+		Count:  2,
+		Labels: map[string]string{"accelerator": "nvidia"},
+		Taints: []string{"nvidia.com/gpu=true:NoSchedule"},
+		Nodes:  []uint{3, 4},
+	}
+
+	clonedCt, ok := ct.Clone().(*ClusterNodePools)
+	if !ok {
+		t.Fail()
+	}
+
+	assert.Equal(t, ct, clonedCt)
+	clonedCt.ByName["gpu"].Labels["accelerator"] = "amd"
+	clonedCt.ByName["gpu"].Nodes[0] = 5
+
+	areEqual := reflect.DeepEqual(ct, clonedCt)
+	if areEqual {
+		t.Error("It's a shallow clone !")
+		t.Fail()
+	}
+	assert.Equal(t, "nvidia", ct.ByName["gpu"].Labels["accelerator"])
+	assert.Equal(t, uint(3), ct.ByName["gpu"].Nodes[0])
+}
+
+func TestNodePools_PoolOfNode(t *testing.T) {
+	ct := newClusterNodePools()
+	ct.ByName["gpu"] = &ClusterNodePool{Name: "gpu", Nodes: []uint{3, 4}}
+	ct.ByName["highmem"] = &ClusterNodePool{Name: "highmem", Nodes: []uint{5}}
+
+	assert.Equal(t, "gpu", ct.PoolOfNode(4))
+	assert.Equal(t, "highmem", ct.PoolOfNode(5))
+	assert.Equal(t, "", ct.PoolOfNode(1))
+}
@@ -0,0 +1,188 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package propertiesv1
+
+import (
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/clusterproperty"
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/hostproperty"
+	"github.com/CS-SI/SafeScale/lib/utils/data"
+	"github.com/CS-SI/SafeScale/lib/utils/serialize"
+)
+
+const (
+	// MaxFeatureHistoryEntries is the number of feature actions kept in metadata of a host or a cluster
+	MaxFeatureHistoryEntries = 50
+	// MaxFeatureHistoryOutputLength is the maximum length of the output of a step kept in metadata (the end of the output is kept)
+	MaxFeatureHistoryOutputLength = 4096
+	// MaxFeatureHistoryEntrySize is the serialized size of an entry beyond which the outputs of its steps are dropped,
+	// those of the successful steps first
+	MaxFeatureHistoryEntrySize = 64 * 1024
+	// MaxFeatureHistorySize is the serialized size of the history beyond which the oldest entries are dropped, the
+	// history being stored inside the metadata of the host or the cluster
+	MaxFeatureHistorySize = 512 * 1024
+
+	// droppedFeatureOutput replaces the outputs dropped to limit the size of the history
+	droppedFeatureOutput = "[output dropped to limit the size of the history]"
+)
+
+// FeatureStepResult describes the execution of a step of a feature action on a host
+// not FROZEN yet
+// Note: if tagged as FROZEN, must not be changed ever.
+//       Create a new version instead with needed supplemental/overriding fields
+type FeatureStepResult struct {
+	Step      string        `json:"step"`
+	Host      string        `json:"host"`                // name of the host where the step has been executed
+	ExitCode  int           `json:"exit_code"`           // exit code of the script of the step
+	Completed bool          `json:"completed,omitempty"` // tells if the script has been run to completion
+	Success   bool          `json:"success,omitempty"`
+	Output    string        `json:"output,omitempty"` // output of the script, truncated to MaxFeatureHistoryOutputLength
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration,omitempty"`
+}
+
+// FeatureHistoryEntry describes an action ('add', 'remove' or 'upgrade') on a feature
+// not FROZEN yet
+type FeatureHistoryEntry struct {
+	Feature    string              `json:"feature"`
+	Version    string              `json:"version,omitempty"`
+	Action     string              `json:"action"`
+	Parameters map[string]string   `json:"parameters,omitempty"` // parameters of the feature, the secrets being masked
+	Start      time.Time           `json:"start"`
+	End        time.Time           `json:"end"`
+	Success    bool                `json:"success,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	Steps      []FeatureStepResult `json:"steps,omitempty"`
+}
+
+// FeatureHistory contains the last actions on features of a host or a cluster
+// not FROZEN yet
+type FeatureHistory struct {
+	Entries []*FeatureHistoryEntry `json:"entries,omitempty"` // last actions, the most recent last
+}
+
+// NewFeatureHistory ...
+func NewFeatureHistory() *FeatureHistory {
+	return &FeatureHistory{
+		Entries: []*FeatureHistoryEntry{},
+	}
+}
+
+// Reset resets the content of the property
+func (fh *FeatureHistory) Reset() {
+	*fh = FeatureHistory{
+		Entries: []*FeatureHistoryEntry{},
+	}
+}
+
+// Clone ...
+// satisfies interface data.Clonable
+func (fh FeatureHistory) Clone() data.Clonable {
+	return NewFeatureHistory().Replace(&fh)
+}
+
+// Replace ...
+// satisfies interface data.Clonable
+func (fh *FeatureHistory) Replace(p data.Clonable) data.Clonable {
+	// Do not test with IsNull(), it's allowed to clone a null value...
+	if fh == nil || p == nil {
+		return fh
+	}
+
+	src := p.(*FeatureHistory)
+	fh.Entries = make([]*FeatureHistoryEntry, 0, len(src.Entries))
+	for _, v := range src.Entries {
+		item := *v
+		item.Parameters = make(map[string]string, len(v.Parameters))
+		for k, p := range v.Parameters {
+			item.Parameters[k] = p
+		}
+		item.Steps = make([]FeatureStepResult, len(v.Steps))
+		copy(item.Steps, v.Steps)
+		fh.Entries = append(fh.Entries, &item)
+	}
+	return fh
+}
+
+// AddEntry records an action on a feature, keeping only the MaxFeatureHistoryEntries most recent ones within
+// MaxFeatureHistorySize; the outputs of the steps of the entry are dropped if it exceeds MaxFeatureHistoryEntrySize
+func (fh *FeatureHistory) AddEntry(entry *FeatureHistoryEntry) {
+	entry.limitSize()
+	fh.Entries = append(fh.Entries, entry)
+	if len(fh.Entries) > MaxFeatureHistoryEntries {
+		fh.Entries = fh.Entries[len(fh.Entries)-MaxFeatureHistoryEntries:]
+	}
+
+	sizes := make([]int, len(fh.Entries))
+	total := 0
+	for i, v := range fh.Entries {
+		sizes[i] = v.size()
+		total += sizes[i]
+	}
+	first := 0
+	for total > MaxFeatureHistorySize && first < len(fh.Entries)-1 {
+		total -= sizes[first]
+		first++
+	}
+	fh.Entries = fh.Entries[first:]
+}
+
+// size returns the serialized size of the entry
+func (e *FeatureHistoryEntry) size() int {
+	jsoned, err := json.Marshal(e)
+	if err != nil {
+		return 0
+	}
+	return len(jsoned)
+}
+
+// limitSize drops the outputs of the steps, those of the successful steps first, until the entry fits in
+// MaxFeatureHistoryEntrySize
+func (e *FeatureHistoryEntry) limitSize() {
+	size := e.size()
+	for _, success := range []bool{true, false} {
+		for i := len(e.Steps) - 1; i >= 0 && size > MaxFeatureHistoryEntrySize; i-- {
+			step := &e.Steps[i]
+			if step.Success != success || len(step.Output) <= len(droppedFeatureOutput) {
+				continue
+			}
+			size -= len(step.Output) - len(droppedFeatureOutput)
+			step.Output = droppedFeatureOutput
+		}
+	}
+}
+
+// TruncateFeatureOutput keeps the end of the output of a step, limited to MaxFeatureHistoryOutputLength bytes without
+// splitting a character
+func TruncateFeatureOutput(output string) string {
+	if len(output) <= MaxFeatureHistoryOutputLength {
+		return output
+	}
+	start := len(output) - MaxFeatureHistoryOutputLength
+	for start < len(output) && !utf8.RuneStart(output[start]) {
+		start++
+	}
+	return "[...]" + output[start:]
+}
+
+func init() {
+	serialize.PropertyTypeRegistry.Register("resources.host", hostproperty.FeatureHistoryV1, NewFeatureHistory())
+	serialize.PropertyTypeRegistry.Register("resources.cluster", clusterproperty.FeatureHistoryV1, NewFeatureHistory())
+}
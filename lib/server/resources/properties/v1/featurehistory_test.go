@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package propertiesv1
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatureHistory_Clone(t *testing.T) {
+	fh := NewFeatureHistory()
+	fh.AddEntry(&FeatureHistoryEntry{
+		Feature:    "docker",
+		Action:     "add",
+		Parameters: map[string]string{"Version": "19.03"},
+		Steps:      []FeatureStepResult{{Step: "install", Host: "host1", Success: true}},
+	})
+
+	clonedFh, ok := fh.Clone().(*FeatureHistory)
+	if !ok {
+		t.Fail()
+	}
+
+	assert.Equal(t, fh, clonedFh)
+	clonedFh.Entries[0].Parameters["Version"] = "20.10"
+	clonedFh.Entries[0].Steps[0].Success = false
+
+	areEqual := reflect.DeepEqual(fh, clonedFh)
+	if areEqual {
+		t.Error("It's a shallow clone !")
+		t.Fail()
+	}
+}
+
+func TestFeatureHistory_AddEntry(t *testing.T) {
+	fh := NewFeatureHistory()
+	for i := 0; i < MaxFeatureHistoryEntries+5; i++ {
+		fh.AddEntry(&FeatureHistoryEntry{Feature: "docker", Steps: make([]FeatureStepResult, i)})
+	}
+	assert.Equal(t, MaxFeatureHistoryEntries, len(fh.Entries))
+	assert.Equal(t, 5, len(fh.Entries[0].Steps))
+}
+
+func TestTruncateFeatureOutput(t *testing.T) {
+	assert.Equal(t, "short", TruncateFeatureOutput("short"))
+
+	output := strings.Repeat("a", MaxFeatureHistoryOutputLength) + "end"
+	truncated := TruncateFeatureOutput(output)
+	assert.True(t, strings.HasPrefix(truncated, "[...]"))
+	assert.True(t, strings.HasSuffix(truncated, "end"))
+	assert.Equal(t, MaxFeatureHistoryOutputLength+len("[...]"), len(truncated))
+
+	// the output is not cut inside a character ('é' is 2 bytes long)
+	output = strings.Repeat("é", MaxFeatureHistoryOutputLength/2) + "end"
+	truncated = TruncateFeatureOutput(output)
+	assert.True(t, utf8.ValidString(truncated))
+	assert.True(t, strings.HasSuffix(truncated, "end"))
+	assert.Equal(t, MaxFeatureHistoryOutputLength-1+len("[...]"), len(truncated))
+}
+
+func TestFeatureHistory_AddEntrySize(t *testing.T) {
+	output := strings.Repeat("x", MaxFeatureHistoryOutputLength)
+	newEntry := func() *FeatureHistoryEntry {
+		entry := &FeatureHistoryEntry{Feature: "docker", Action: "add"}
+		for i := 0; i < 40; i++ {
+			entry.Steps = append(entry.Steps, FeatureStepResult{Step: "install", Host: "host", Success: i != 0, Output: output})
+		}
+		return entry
+	}
+
+	// the outputs of the successful steps are dropped first
+	fh := NewFeatureHistory()
+	fh.AddEntry(newEntry())
+	require.Len(t, fh.Entries, 1)
+	entry := fh.Entries[0]
+	assert.True(t, entry.size() <= MaxFeatureHistoryEntrySize)
+	assert.Equal(t, output, entry.Steps[0].Output)
+	assert.Equal(t, droppedFeatureOutput, entry.Steps[39].Output)
+
+	// the oldest entries are dropped beyond the size of the history
+	for i := 0; i < MaxFeatureHistoryEntries; i++ {
+		fh.AddEntry(newEntry())
+	}
+	size := 0
+	for _, v := range fh.Entries {
+		size += v.size()
+	}
+	assert.True(t, len(fh.Entries) < MaxFeatureHistoryEntries)
+	assert.True(t, size <= MaxFeatureHistorySize)
+	assert.True(t, size > MaxFeatureHistorySize-MaxFeatureHistoryEntrySize)
+}
@@ -35,6 +35,7 @@ type HostDescription struct {
 	Purpose string    `json:"purpose,omitempty"`  // contains a description of the use of a host (not set for now)
 	Tenant  string    `json:"tenant,omitempty"`   // contains the tenant name used to create the host
 	Domain  string    `json:"domain,omitempty"`   // Contains the domain used to define the FQDN of the host at creation (taken from first network attached to the host)
+	Owner   string    `json:"owner,omitempty"`    // contains the name of the authenticated caller who created the host (empty without authentication)
 }
 
 // NewHostDescription ...
@@ -30,6 +30,7 @@ type HostInstalledFeature struct {
 	HostContext bool     `json:"host_context,omitempty"` // tells if the feature has been explicitly installed for host (opposed to for cluster)
 	RequiredBy  []string `json:"required_by,omitempty"`  // tells what feature(s) needs this one
 	Requires    []string `json:"requires,omitempty"`
+	Version     string   `json:"version,omitempty"` // tells what version of the feature is installed
 }
 
 // NewHostInstalledFeature ...
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package propertiesv2
+
+import (
+	"time"
+
+	"github.com/CS-SI/SafeScale/lib/server/resources/enums/volumeproperty"
+	"github.com/CS-SI/SafeScale/lib/utils/data"
+	"github.com/CS-SI/SafeScale/lib/utils/serialize"
+)
+
+// VolumeDescription contains additional information describing the volume, in V2
+// not FROZEN yet
+// Note: if tagged as FROZEN, must not be changed ever.
+//       Create a new version instead with needed supplemental fields
+type VolumeDescription struct {
+	Purpose string    `json:"purpose,omitempty"` // contains the reason of the existence of the volume
+	Created time.Time `json:"created,omitempty"` // contains the time of creation of the volume
+	Owner   string    `json:"owner,omitempty"`   // contains the name of the authenticated caller who created the volume (empty without authentication)
+}
+
+// NewVolumeDescription ...
+func NewVolumeDescription() *VolumeDescription {
+	return &VolumeDescription{}
+}
+
+// Reset resets the content of the property
+func (vd *VolumeDescription) Reset() {
+	*vd = VolumeDescription{}
+}
+
+// Content ...
+func (vd *VolumeDescription) Content() interface{} {
+	return vd
+}
+
+// Clone ...
+func (vd VolumeDescription) Clone() data.Clonable {
+	return NewVolumeDescription().Replace(&vd)
+}
+
+// Replace ...
+func (vd *VolumeDescription) Replace(p data.Clonable) data.Clonable {
+	// Do not test with IsNull(), it's allowed to clone a null value...
+	if vd == nil || p == nil {
+		return vd
+	}
+
+	*vd = *p.(*VolumeDescription)
+	return vd
+}
+
+func init() {
+	serialize.PropertyTypeRegistry.Register("resources.volume", string(volumeproperty.DescriptionV2), NewVolumeDescription())
+}
@@ -17,18 +17,30 @@
 package utils
 
 import (
+	"context"
+	"crypto/tls"
 	"log"
 	"strings"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/CS-SI/SafeScale/lib/protocol"
 )
 
+// TenantMetadataKey is the key of the metadata of a call carrying the name of the tenant the call applies to
+const TenantMetadataKey = "x-safescale-tenant"
+
 // GetConnection returns a connection to GRPC server
-func GetConnection(server string) *grpc.ClientConn {
+// If tlsConfig is nil, the connection is not encrypted
+func GetConnection(server string, tlsConfig *tls.Config, opts ...grpc.DialOption) *grpc.ClientConn {
+	transport := grpc.WithInsecure()
+	if tlsConfig != nil {
+		transport = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+
 	// Set up a connection to the server.
-	conn, err := grpc.Dial(server, grpc.WithInsecure())
+	conn, err := grpc.Dial(server, append([]grpc.DialOption{transport}, opts...)...)
 	if err != nil {
 		log.Fatalf("failed to connect to safescaled (%s): %v", server, err)
 	}
@@ -51,3 +63,31 @@ func GetReference(in *protocol.Reference) (string, string) {
 	}
 	return ref, refLabel
 }
+
+// ChainUnaryServerInterceptors returns an interceptor calling the interceptors in order, the last one calling the handler
+func ChainUnaryServerInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, h := interceptors[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, h)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// ChainStreamServerInterceptors returns an interceptor calling the interceptors in order, the last one calling the handler
+func ChainStreamServerInterceptors(interceptors ...grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, h := interceptors[i], next
+			next = func(srv interface{}, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, h)
+			}
+		}
+		return next(srv, ss)
+	}
+}
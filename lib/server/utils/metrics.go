@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/CS-SI/SafeScale/lib/utils/metrics"
+)
+
+var (
+	rpcRequests = metrics.NewCounterVec("safescale_rpc_requests_total", "Number of RPCs handled by safescaled, by service, method and status code", "service", "method", "code")
+	rpcDuration = metrics.NewHistogramVec("safescale_rpc_duration_seconds", "Duration of the RPCs handled by safescaled, by service and method", nil, "service", "method")
+)
+
+// splitMethod returns the service and the method of a gRPC full method name ('/<package>.<service>/<method>')
+func splitMethod(fullMethod string) (string, string) {
+	service := strings.TrimPrefix(fullMethod, "/")
+	method := ""
+	if i := strings.Index(service, "/"); i >= 0 {
+		service, method = service[:i], service[i+1:]
+	}
+	if i := strings.LastIndex(service, "."); i >= 0 {
+		service = service[i+1:]
+	}
+	return service, method
+}
+
+func observeRPC(fullMethod string, start time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+	rpcRequests.Inc(service, method, status.Code(err).String())
+	rpcDuration.Observe(time.Since(start).Seconds(), service, method)
+}
+
+// MetricsUnaryServerInterceptor returns the interceptor counting and timing the unary RPCs
+func MetricsUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeRPC(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// MetricsStreamServerInterceptor returns the interceptor counting and timing the streaming RPCs
+func MetricsStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeRPC(info.FullMethod, start, err)
+		return err
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"context"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/CS-SI/SafeScale/lib/utils/telemetry"
+)
+
+const (
+	// TraceIDDetailKey is the key of the metadata of the error details carrying the trace id of a failed call
+	TraceIDDetailKey = "trace_id"
+
+	errorInfoDomain = "safescale"
+	errorInfoReason = "TRACED_CALL_FAILED"
+)
+
+// startRPCSpan starts the span of the handling of the call, child of the span of the caller if the call carries one
+func startRPCSpan(ctx context.Context, fullMethod string) (context.Context, *telemetry.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(telemetry.TraceparentKey); len(values) > 0 {
+			ctx = telemetry.ContextWithTraceparent(ctx, values[0])
+		}
+	}
+	service, method := splitMethod(fullMethod)
+	ctx, span := telemetry.Start(ctx, service+"/"+method, telemetry.KindServer)
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.service", service)
+	span.SetAttribute("rpc.method", method)
+	return ctx, span
+}
+
+// endRPCSpan ends the span of the call; if the call failed, returns the error with the trace id in its details
+func endRPCSpan(span *telemetry.Span, err error) error {
+	if span == nil {
+		return err
+	}
+	st := status.Convert(err)
+	span.SetAttribute("rpc.grpc.status_code", int(st.Code()))
+	span.End(err)
+	if err == nil {
+		return nil
+	}
+
+	detailed, derr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   errorInfoReason,
+		Domain:   errorInfoDomain,
+		Metadata: map[string]string{TraceIDDetailKey: span.TraceID()},
+	})
+	if derr != nil {
+		return err
+	}
+	return detailed.Err()
+}
+
+// TraceIDFromError returns the trace id carried by the details of the error of a call, empty string if there is none
+func TraceIDFromError(err error) string {
+	if err == nil {
+		return ""
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return ""
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == errorInfoDomain {
+			if id := info.GetMetadata()[TraceIDDetailKey]; id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// TelemetryUnaryServerInterceptor returns the interceptor tracing the unary RPCs
+func TelemetryUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := startRPCSpan(ctx, info.FullMethod)
+		if span == nil {
+			return handler(ctx, req)
+		}
+
+		resp, err := handler(ctx, req)
+		return resp, endRPCSpan(span, err)
+	}
+}
+
+// TelemetryStreamServerInterceptor returns the interceptor tracing the streaming RPCs
+func TelemetryStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startRPCSpan(ss.Context(), info.FullMethod)
+		if span == nil {
+			return handler(srv, ss)
+		}
+
+		err := handler(srv, &tracedStream{ServerStream: ss, ctx: ctx})
+		return endRPCSpan(span, err)
+	}
+}
+
+// tracedStream is a grpc.ServerStream whose context carries the span of the call
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context carrying the span of the call
+func (s *tracedStream) Context() context.Context {
+	return s.ctx
+}
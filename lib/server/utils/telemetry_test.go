@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/CS-SI/SafeScale/lib/utils/telemetry"
+)
+
+func TestTelemetryUnaryServerInterceptor(t *testing.T) {
+	interceptor := TelemetryUnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/HostService/Create"}
+	failing := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "image not found")
+	}
+
+	// Tracing disabled: the error is left as is
+	_, err := interceptor(context.Background(), nil, info, failing)
+	assert.Equal(t, "", TraceIDFromError(err))
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+	exporter, xerr := telemetry.NewOTLPExporter(collector.URL, "safescaled")
+	require.Nil(t, xerr)
+	telemetry.SetDefaultExporter(exporter)
+	defer func() {
+		telemetry.SetDefaultExporter(nil)
+		exporter.Shutdown()
+	}()
+
+	var span *telemetry.Span
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(telemetry.TraceparentKey, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"))
+	_, err = interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		span = telemetry.FromContext(ctx)
+		return failing(ctx, req)
+	})
+	require.NotNil(t, span)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "image not found", status.Convert(err).Message())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", TraceIDFromError(err))
+
+	_, err = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/CS-SI/SafeScale/lib/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// ServerTLSConfig returns the TLS configuration of safescaled using the certificate and private key files
+// If clientCAFile is not empty, the clients must present a certificate signed by one of the certificate authorities
+// it contains (mutual TLS)
+func ServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, fail.Error) {
+	if certFile == "" {
+		return nil, fail.InvalidParameterError("certFile", "cannot be empty string")
+	}
+	if keyFile == "" {
+		return nil, fail.InvalidParameterError("keyFile", "cannot be empty string")
+	}
+
+	cert, err := tls.LoadX509KeyPair(utils.AbsPathify(certFile), utils.AbsPathify(keyFile))
+	if err != nil {
+		return nil, fail.Wrap(err, "failed to load server certificate")
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		pool, xerr := loadCertPool(clientCAFile)
+		if xerr != nil {
+			return nil, xerr
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// ClientTLSConfig returns the TLS configuration used to connect to safescaled
+// caFile contains the certificate authorities used to verify the certificate of safescaled (system ones if empty);
+// certFile and keyFile, if not empty, contain the certificate used to authenticate the client (mutual TLS);
+// serverName, if not empty, replaces the host name of the server when verifying its certificate
+func ClientTLSConfig(caFile, certFile, keyFile, serverName string) (*tls.Config, fail.Error) {
+	config := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if caFile != "" {
+		pool, xerr := loadCertPool(caFile)
+		if xerr != nil {
+			return nil, xerr
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fail.InvalidRequestError("both client certificate and private key must be set")
+		}
+		cert, err := tls.LoadX509KeyPair(utils.AbsPathify(certFile), utils.AbsPathify(keyFile))
+		if err != nil {
+			return nil, fail.Wrap(err, "failed to load client certificate")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// loadCertPool returns a pool containing the PEM encoded certificates of the file
+func loadCertPool(file string) (*x509.CertPool, fail.Error) {
+	content, err := ioutil.ReadFile(utils.AbsPathify(file))
+	if err != nil {
+		return nil, fail.Wrap(err, "failed to read certificate authorities file '%s'", file)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fail.SyntaxError("no PEM encoded certificate found in file '%s'", file)
+	}
+	return pool, nil
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/CS-SI/SafeScale/lib/utils/crypt"
+)
+
+func writePEM(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	require.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	caCert, caKey, xerr := crypt.GenerateCertificateAuthority("Test CA", time.Hour)
+	require.Nil(t, xerr)
+	serverCert, serverKey, xerr := crypt.GenerateCertificate(caCert, caKey, "safescaled", []string{"localhost", "127.0.0.1"}, crypt.ServerCertificate, time.Hour)
+	require.Nil(t, xerr)
+	clientCert, clientKey, xerr := crypt.GenerateCertificate(caCert, caKey, "alice", nil, crypt.ClientCertificate, time.Hour)
+	require.Nil(t, xerr)
+	otherCA, otherKey, xerr := crypt.GenerateCertificateAuthority("Other CA", time.Hour)
+	require.Nil(t, xerr)
+	otherCert, otherClientKey, xerr := crypt.GenerateCertificate(otherCA, otherKey, "mallory", nil, crypt.ClientCertificate, time.Hour)
+	require.Nil(t, xerr)
+	_, _, xerr = crypt.GenerateCertificate(serverCert, serverKey, "bob", nil, crypt.ClientCertificate, time.Hour)
+	assert.NotNil(t, xerr)
+
+	caFile := writePEM(t, dir, "ca.crt", caCert)
+	serverConfig, xerr := ServerTLSConfig(writePEM(t, dir, "server.crt", serverCert), writePEM(t, dir, "server.key", serverKey), caFile)
+	require.Nil(t, xerr)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(serverConfig)))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	check := func(certFile, keyFile string) error {
+		config, xerr := ClientTLSConfig(caFile, certFile, keyFile, "")
+		require.Nil(t, xerr)
+		conn := GetConnection(lis.Addr().String(), config)
+		defer func() { _ = conn.Close() }()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		return err
+	}
+
+	assert.Nil(t, check(writePEM(t, dir, "alice.crt", clientCert), writePEM(t, dir, "alice.key", clientKey)))
+	assert.NotNil(t, check("", ""))
+	assert.NotNil(t, check(writePEM(t, dir, "mallory.crt", otherCert), writePEM(t, dir, "mallory.key", otherClientKey)))
+
+	_, xerr = ClientTLSConfig(caFile, "alice.crt", "", "")
+	assert.NotNil(t, xerr)
+}
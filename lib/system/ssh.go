@@ -41,7 +41,9 @@ import (
 	"github.com/CS-SI/SafeScale/lib/utils/debug"
 	"github.com/CS-SI/SafeScale/lib/utils/debug/tracing"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/metrics"
 	"github.com/CS-SI/SafeScale/lib/utils/retry"
+	"github.com/CS-SI/SafeScale/lib/utils/telemetry"
 	"github.com/CS-SI/SafeScale/lib/utils/temporal"
 )
 
@@ -54,6 +56,11 @@ const (
 	sshCopyTemplate = `scp -i {{.IdentityFile}} -P {{.Port}} {{.Options}} {{if .IsUpload}}"{{.LocalPath}}" {{.User}}@{{.IPAddress}}:"{{.RemotePath}}"{{else}}{{.User}}@{{.IPAddress}}:"{{.RemotePath}}" "{{.LocalPath}}"{{end}}`
 )
 
+var (
+	sshCommandDuration = metrics.NewHistogramVec("safescale_ssh_command_duration_seconds", "Duration of the commands run through SSH", nil)
+	sshCommandFailures = metrics.NewCounterVec("safescale_ssh_command_failures_total", "Number of commands run through SSH that failed, by reason ('exit_code' if the command returned a non-zero exit code, else the kind of error)", "reason")
+)
+
 var (
 	sshErrorMap = map[int]string{
 		1:  "Malformed configuration or invalid cli options",
@@ -514,7 +521,7 @@ func (scmd *SSHCommand) Run(task concurrency.Task, outs outputs.Enum) (int, stri
 // - xerr fail.Error
 //   . *fail.ErrNotAvailable if remote SSH is not available
 //   . *fail.ErrTimeout if 'timeout' is reached
-func (scmd *SSHCommand) RunWithTimeout(task concurrency.Task, outs outputs.Enum, timeout time.Duration) (int, string, string, fail.Error) {
+func (scmd *SSHCommand) RunWithTimeout(task concurrency.Task, outs outputs.Enum, timeout time.Duration) (retcode int, stdout string, stderr string, xerr fail.Error) {
 	if scmd == nil {
 		return -1, "", "", fail.InvalidInstanceError()
 	}
@@ -522,6 +529,18 @@ func (scmd *SSHCommand) RunWithTimeout(task concurrency.Task, outs outputs.Enum,
 		return -1, "", "", fail.InvalidParameterError("task", "cannot be null value of 'concurrency.Task'")
 	}
 
+	span := startSSHSpan(task, "ssh.run", scmd.hostname)
+	defer func(start time.Time) {
+		sshCommandDuration.Observe(time.Since(start).Seconds())
+		if xerr != nil {
+			sshCommandFailures.Inc(metrics.ErrorKind(xerr))
+		} else if retcode != 0 {
+			sshCommandFailures.Inc("exit_code")
+		}
+		span.SetAttribute("retcode", retcode)
+		span.End(xerr)
+	}(time.Now())
+
 	tracer := debug.NewTracer(task, tracing.ShouldTrace("ssh"), "(%s, %v)", outs.String(), timeout).WithStopwatch().Entering()
 	tracer.Trace("host='%s', command=\n%s\n", scmd.hostname, scmd.runCmdString)
 	defer tracer.Exiting()
@@ -552,6 +571,19 @@ func (scmd *SSHCommand) RunWithTimeout(task concurrency.Task, outs outputs.Enum,
 	return -1, "", "", fail.InconsistentError("'result' should have been of type 'data.Map'")
 }
 
+// startSSHSpan starts the span of a remote step on the host, child of the span of the task
+func startSSHSpan(task concurrency.Task, name, hostname string) *telemetry.Span {
+	var parent *telemetry.Span
+	if task != nil && !task.IsNull() {
+		if ctx, xerr := task.GetContext(); xerr == nil {
+			parent = telemetry.FromContext(ctx)
+		}
+	}
+	span := parent.StartChild(name, telemetry.KindClient)
+	span.SetAttribute("host", hostname)
+	return span
+}
+
 type taskExecuteParameters struct {
 	// stdout, stderr io.ReadCloser
 	collectOutputs bool
@@ -946,6 +978,13 @@ func (sconf *SSHConfig) copy(
 	timeout time.Duration,
 ) (retcode int, stdout string, stderr string, xerr fail.Error) {
 
+	span := startSSHSpan(task, "ssh.copy", sconf.Hostname)
+	span.SetAttribute("upload", isUpload)
+	defer func() {
+		span.SetAttribute("retcode", retcode)
+		span.End(xerr)
+	}()
+
 	tunnels, sshConfig, xerr := sconf.CreateTunneling()
 	if xerr != nil {
 		return 0, "", "", fail.Wrap(xerr, "unable to create tunnels")
@@ -19,6 +19,9 @@ package concurrency
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -29,6 +32,8 @@ import (
 	"github.com/CS-SI/SafeScale/lib/utils/data"
 	"github.com/CS-SI/SafeScale/lib/utils/debug/tracing"
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/metrics"
+	"github.com/CS-SI/SafeScale/lib/utils/telemetry"
 )
 
 // TaskStatus ...
@@ -43,6 +48,15 @@ const (
 	TIMEOUT                   // the task ran out of time
 )
 
+// activeTasks is the number of tasks whose action is running
+var activeTasks int64
+
+func init() {
+	metrics.NewGaugeFunc("safescale_tasks_active", "Number of tasks running", func() float64 {
+		return float64(atomic.LoadInt64(&activeTasks))
+	})
+}
+
 // TaskParameters ...
 type TaskParameters interface{}
 
@@ -121,6 +135,8 @@ type task struct {
 
 	abortDisengaged bool
 	subtasks        map[string]Task // list of subtasks created from this task
+
+	span *telemetry.Span // span of the action, child of the span of the context of the task if any
 }
 
 var globalTask atomic.Value
@@ -352,6 +368,7 @@ func (t *task) StartWithTimeout(action TaskAction, params TaskParameters, timeou
 		t.status = DONE
 	} else {
 		t.status = RUNNING
+		t.ctx, t.span = telemetry.StartChild(t.ctx, actionName(action), telemetry.KindInternal)
 		t.doneCh = make(chan bool, 1)
 		t.abortCh = make(chan bool, 1)
 		t.finishCh = make(chan struct{}, 1)
@@ -486,12 +503,16 @@ func (t *task) controller(action TaskAction, params TaskParameters, timeout time
 
 // run executes the function 'action'
 func (t *task) run(action TaskAction, params TaskParameters) {
+	atomic.AddInt64(&activeTasks, 1)
+	defer atomic.AddInt64(&activeTasks, -1)
+
 	defer func() {
 		if err := recover(); err != nil {
 			t.mu.Lock()
 			defer t.mu.Unlock()
 
 			t.err = fail.RuntimePanicError("panic happened: %v", err)
+			t.span.End(t.err)
 			t.result = nil
 			t.doneCh <- false
 			defer close(t.doneCh)
@@ -499,6 +520,7 @@ func (t *task) run(action TaskAction, params TaskParameters) {
 	}()
 
 	result, err := action(t, params)
+	t.span.End(err)
 
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -509,6 +531,19 @@ func (t *task) run(action TaskAction, params TaskParameters) {
 	defer close(t.doneCh)
 }
 
+// actionName returns the name of the function of the action, without its package path (ex: 'operations.(*cluster).taskCreateNode')
+func actionName(action TaskAction) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(action).Pointer())
+	if fn == nil {
+		return "task"
+	}
+	name := strings.TrimSuffix(fn.Name(), "-fm")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
 // Run starts task, waits its completion then return the error code
 func (t *task) Run(action TaskAction, params TaskParameters) (TaskResult, fail.Error) {
 	if t.IsNull() {
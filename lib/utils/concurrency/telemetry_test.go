@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package concurrency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/telemetry"
+)
+
+func TestSpanPropagation(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+	exporter, xerr := telemetry.NewOTLPExporter(collector.URL, "safescaled")
+	require.Nil(t, xerr)
+	telemetry.SetDefaultExporter(exporter)
+	defer func() {
+		telemetry.SetDefaultExporter(nil)
+		exporter.Shutdown()
+	}()
+
+	ctx, root := telemetry.Start(context.Background(), "ClusterService/Create", telemetry.KindServer)
+	task, xerr := NewTaskWithContext(ctx, nil)
+	require.Nil(t, xerr)
+
+	tg, xerr := NewTaskGroupWithParent(task)
+	require.Nil(t, xerr)
+	spans := make(chan *telemetry.Span, 2)
+	for i := 0; i < 2; i++ {
+		_, xerr = tg.Start(func(t Task, _ TaskParameters) (TaskResult, fail.Error) {
+			ctx, xerr := t.GetContext()
+			if xerr != nil {
+				return nil, xerr
+			}
+			spans <- telemetry.FromContext(ctx)
+			return nil, nil
+		}, nil)
+		require.Nil(t, xerr)
+	}
+	_, xerr = tg.Wait()
+	require.Nil(t, xerr)
+	close(spans)
+
+	count := 0
+	for span := range spans {
+		count++
+		require.NotNil(t, span)
+		assert.NotEqual(t, root.SpanID(), span.SpanID())
+		assert.Equal(t, root.TraceID(), span.TraceID())
+	}
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package crypt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// CertificateUsage tells what a certificate is used for
+type CertificateUsage int
+
+const (
+	// ServerCertificate is a certificate used by a TLS server
+	ServerCertificate CertificateUsage = iota
+	// ClientCertificate is a certificate used by a TLS client to authenticate itself
+	ClientCertificate
+)
+
+// GenerateCertificateAuthority creates a self-signed certificate authority, returned as PEM encoded certificate and private key
+func GenerateCertificateAuthority(commonName string, validity time.Duration) (certPEM string, keyPEM string, xerr fail.Error) {
+	if commonName == "" {
+		return "", "", fail.InvalidParameterError("commonName", "cannot be empty string")
+	}
+	if validity <= 0 {
+		return "", "", fail.InvalidParameterError("validity", "must be greater than 0")
+	}
+
+	template, xerr := newCertificateTemplate(commonName, validity)
+	if xerr != nil {
+		return "", "", xerr
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", fail.ToError(err)
+	}
+	return encodeCertificate(template, template, &key.PublicKey, key, key)
+}
+
+// GenerateCertificate creates a certificate signed by the certificate authority, returned as PEM encoded certificate and private key
+// hosts contains the DNS names and IP addresses the certificate is valid for (used by server certificates)
+func GenerateCertificate(caCertPEM, caKeyPEM string, commonName string, hosts []string, usage CertificateUsage, validity time.Duration) (certPEM string, keyPEM string, xerr fail.Error) {
+	if commonName == "" {
+		return "", "", fail.InvalidParameterError("commonName", "cannot be empty string")
+	}
+	if validity <= 0 {
+		return "", "", fail.InvalidParameterError("validity", "must be greater than 0")
+	}
+
+	caCert, caKey, xerr := decodeCertificateAuthority(caCertPEM, caKeyPEM)
+	if xerr != nil {
+		return "", "", xerr
+	}
+
+	template, xerr := newCertificateTemplate(commonName, validity)
+	if xerr != nil {
+		return "", "", xerr
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	switch usage {
+	case ServerCertificate:
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		for _, h := range hosts {
+			if ip := net.ParseIP(h); ip != nil {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			} else {
+				template.DNSNames = append(template.DNSNames, h)
+			}
+		}
+	case ClientCertificate:
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	default:
+		return "", "", fail.InvalidParameterError("usage", "must be 'ServerCertificate' or 'ClientCertificate'")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", fail.ToError(err)
+	}
+	return encodeCertificate(template, caCert, &key.PublicKey, caKey, key)
+}
+
+func newCertificateTemplate(commonName string, validity time.Duration) (*x509.Certificate, fail.Error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fail.ToError(err)
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"SafeScale"}},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+func encodeCertificate(template, parent *x509.Certificate, pub *ecdsa.PublicKey, signer *ecdsa.PrivateKey, key *ecdsa.PrivateKey) (string, string, fail.Error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		return "", "", fail.Wrap(err, "failed to create certificate")
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", fail.ToError(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+	return string(certPEM), string(keyPEM), nil
+}
+
+func decodeCertificateAuthority(caCertPEM, caKeyPEM string) (*x509.Certificate, *ecdsa.PrivateKey, fail.Error) {
+	block, _ := pem.Decode([]byte(caCertPEM))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, nil, fail.InvalidParameterError("caCertPEM", "is not a PEM encoded certificate")
+	}
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fail.Wrap(err, "failed to parse certificate authority")
+	}
+	if !caCert.IsCA {
+		return nil, nil, fail.InvalidParameterError("caCertPEM", "is not a certificate authority")
+	}
+
+	block, _ = pem.Decode([]byte(caKeyPEM))
+	if block == nil || block.Type != "EC PRIVATE KEY" {
+		return nil, nil, fail.InvalidParameterError("caKeyPEM", "is not a PEM encoded EC private key")
+	}
+	caKey, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, fail.Wrap(err, "failed to parse private key of certificate authority")
+	}
+	return caCert, caKey, nil
+}
@@ -24,9 +24,21 @@ import (
 	"crypto/rand"
 	"io"
 
+	"golang.org/x/crypto/scrypt"
+
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
 )
 
+// SaltSize is the size in bytes of the salts generated by NewSalt
+const SaltSize = 16
+
+// scrypt cost parameters (recommended values for interactive logins)
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
 // Key ...
 type Key [32]byte
 
@@ -60,6 +72,33 @@ func NewEncryptionKey(text []byte) (*Key, error) {
 	return &key, nil
 }
 
+// NewSalt generates a random salt to be used by DeriveKey()
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fail.Wrap(err, "cannot read enough random bytes (you should consider to stop using this computer)")
+	}
+	return salt, nil
+}
+
+// DeriveKey derives a 256-bit key for Encrypt() and Decrypt() from a passphrase and a salt, using scrypt
+func DeriveKey(passphrase []byte, salt []byte) (*Key, error) {
+	if len(passphrase) == 0 {
+		return nil, fail.InvalidParameterError("passphrase", "cannot be empty")
+	}
+	if len(salt) == 0 {
+		return nil, fail.InvalidParameterError("salt", "cannot be empty")
+	}
+
+	derived, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, len(Key{}))
+	if err != nil {
+		return nil, err
+	}
+	key := Key{}
+	copy(key[:], derived)
+	return &key, nil
+}
+
 // Encrypt encrypts data using 256-bit AES-GCM.  This both hides the content of
 // the data and provides a check that it hasn't been altered. Output takes the
 // form nonce|ciphertext|tag where '|' indicates concatenation.
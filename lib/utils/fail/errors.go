@@ -750,6 +750,53 @@ func (e *ErrOverload) Annotate(key string, value data.Annotation) data.Annotatab
 	return e
 }
 
+// ErrThrottled when action has been refused because too many requests have been sent to the provider in a given time,
+// by the provider itself or by the rate limiter of SafeScale; the caller must wait before sending it again
+type ErrThrottled struct {
+	*errorCore
+	retryAfter time.Duration
+}
+
+// ThrottledError creates a ErrThrottled error; retryAfter is the time to wait before retrying, 0 if unknown
+func ThrottledError(retryAfter time.Duration, msg ...interface{}) *ErrThrottled {
+	r := newError(nil, nil, msg...)
+	r.grpcCode = codes.ResourceExhausted
+	return &ErrThrottled{errorCore: r, retryAfter: retryAfter}
+}
+
+// IsNull tells if the instance is null
+func (e *ErrThrottled) IsNull() bool {
+	return e == nil || e.errorCore.IsNull()
+}
+
+// RetryAfter returns the time to wait before retrying, 0 if unknown
+func (e *ErrThrottled) RetryAfter() time.Duration {
+	if e.IsNull() {
+		return 0
+	}
+	return e.retryAfter
+}
+
+// AddConsequence ...
+func (e *ErrThrottled) AddConsequence(err error) Error {
+	if e.IsNull() {
+		logrus.Errorf(callstack.DecorateWith("invalid call:", "ErrThrottled.AddConsequence()", "from null instance", 0))
+		return e
+	}
+	_ = e.errorCore.AddConsequence(err)
+	return e
+}
+
+// Annotate ...
+func (e *ErrThrottled) Annotate(key string, value data.Annotation) data.Annotatable {
+	if e.IsNull() {
+		logrus.Errorf(callstack.DecorateWith("invalid call:", "ErrThrottled.Annotate()", "from null instance", 0))
+		return e
+	}
+	_ = e.errorCore.Annotate(key, value)
+	return e
+}
+
 // ErrNotImplemented ...
 type ErrNotImplemented struct {
 	*errorCore
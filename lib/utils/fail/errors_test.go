@@ -179,6 +179,16 @@ func TestInterfaceMatching(t *testing.T) {
 		}
 	}
 
+	{
+		val := ThrottledError(0, "")
+		if _, ok := interface{}(val).(Error); !ok {
+			logrus.Fatal("*ErrThrottled doesn't satisfy interface Error")
+		}
+		if _, ok := interface{}(val).(error); !ok {
+			logrus.Fatal("*ErrThrottled doesn't satisfy interface error")
+		}
+	}
+
 	{
 		val := RuntimePanicError("")
 		if _, ok := interface{}(val).(Error); !ok {
@@ -0,0 +1,372 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package metrics provides counters, gauges and histograms exposed in the Prometheus text format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultBuckets are the upper bounds of the buckets of the histograms of durations in seconds, from 5ms to 10mn
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// collector is a metric able to write its samples
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+var (
+	collectors     = map[string]collector{}
+	collectorsLock sync.Mutex
+)
+
+// register adds the metric to those exposed by Handler; panics if a metric with the same name exists, as it is a programming error
+func register(c collector) {
+	collectorsLock.Lock()
+	defer collectorsLock.Unlock()
+	if _, ok := collectors[c.name()]; ok {
+		panic("metric '" + c.name() + "' registered twice")
+	}
+	collectors[c.name()] = c
+}
+
+// Write writes the samples of all the metrics in the Prometheus text format
+func Write(w io.Writer) {
+	collectorsLock.Lock()
+	list := make([]collector, 0, len(collectors))
+	for _, c := range collectors {
+		list = append(list, c)
+	}
+	collectorsLock.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].name() < list[j].name() })
+
+	bw := bufio.NewWriter(w)
+	for _, c := range list {
+		c.write(bw)
+	}
+	_ = bw.Flush()
+}
+
+// Handler returns the HTTP handler exposing the metrics to Prometheus
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w)
+	})
+}
+
+// desc describes a metric and its labels
+type desc struct {
+	metricName string
+	help       string
+	kind       string
+	labels     []string
+}
+
+func (d desc) name() string {
+	return d.metricName
+}
+
+func (d desc) writeHeader(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.metricName, strings.Replace(d.help, "\n", " ", -1), d.metricName, d.kind)
+}
+
+// key returns the label pairs of the values, checking there is one value per label
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metric '%s' has %d labels, %d values given", d.metricName, len(d.labels), len(values)))
+	}
+	pairs := make([]string, 0, len(values))
+	for i, v := range values {
+		pairs = append(pairs, d.labels[i]+`="`+escape(v)+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func escape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// sample formats a sample line
+func sample(name, labels string, value float64) string {
+	if labels == "" {
+		return name + " " + formatFloat(value) + "\n"
+	}
+	return name + "{" + labels + "} " + formatFloat(value) + "\n"
+}
+
+// sortedKeys returns the keys of the map of samples in order
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	list := make([]string, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, k.String())
+	}
+	sort.Strings(list)
+	return list
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	desc
+	lock   sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec creates and registers a counter
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{metricName: name, help: help, kind: "counter", labels: labels}, values: map[string]float64{}}
+	register(c)
+	return c
+}
+
+// Inc increments the counter of the label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v (which must be positive) to the counter of the label values
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(labelValues)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.values[key] += v
+}
+
+// Value returns the value of the counter of the label values
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.values[key]
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.writeHeader(w)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, k := range sortedKeys(c.values) {
+		_, _ = io.WriteString(w, sample(c.metricName, k, c.values[k]))
+	}
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct {
+	desc
+	lock   sync.Mutex
+	values map[string]float64
+}
+
+// NewGaugeVec creates and registers a gauge
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{desc: desc{metricName: name, help: help, kind: "gauge", labels: labels}, values: map[string]float64{}}
+	register(g)
+	return g
+}
+
+// Set sets the gauge of the label values
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	key := g.key(labelValues)
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.values[key] = v
+}
+
+// Add adds v to the gauge of the label values
+func (g *GaugeVec) Add(v float64, labelValues ...string) {
+	key := g.key(labelValues)
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.values[key] += v
+}
+
+// Inc increments the gauge of the label values
+func (g *GaugeVec) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec decrements the gauge of the label values
+func (g *GaugeVec) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Value returns the value of the gauge of the label values
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	key := g.key(labelValues)
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.values[key]
+}
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.writeHeader(w)
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	for _, k := range sortedKeys(g.values) {
+		_, _ = io.WriteString(w, sample(g.metricName, k, g.values[k]))
+	}
+}
+
+// GaugeFunc is a gauge whose value is computed when the metrics are collected
+type GaugeFunc struct {
+	desc
+	value func() float64
+}
+
+// NewGaugeFunc creates and registers a gauge whose value is given by the function
+func NewGaugeFunc(name, help string, value func() float64) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{metricName: name, help: help, kind: "gauge"}, value: value}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.writeHeader(w)
+	_, _ = io.WriteString(w, sample(g.metricName, "", g.value()))
+}
+
+// histogram contains the observations of a combination of label values
+type histogram struct {
+	counts []uint64 // count of observations per bucket (not cumulative)
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	desc
+	buckets []float64
+	lock    sync.Mutex
+	values  map[string]*histogram
+}
+
+// NewHistogramVec creates and registers a histogram; buckets are the upper bounds of the buckets, in increasing order
+// (DefaultBuckets if nil)
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	h := &HistogramVec{desc: desc{metricName: name, help: help, kind: "histogram", labels: labels}, buckets: buckets, values: map[string]*histogram{}}
+	register(h)
+	return h
+}
+
+// Observe adds an observation to the histogram of the label values
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	item, ok := h.values[key]
+	if !ok {
+		item = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = item
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		item.counts[i]++
+	}
+	item.count++
+	item.sum += v
+}
+
+// Count returns the number of observations of the histogram of the label values
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if item, ok := h.values[key]; ok {
+		return item.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.writeHeader(w)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, k := range sortedKeys(h.values) {
+		item := h.values[k]
+		prefix := k
+		if prefix != "" {
+			prefix += ","
+		}
+		var cumulative uint64
+		for i, b := range h.buckets {
+			cumulative += item.counts[i]
+			_, _ = io.WriteString(w, sample(h.metricName+"_bucket", prefix+`le="`+formatFloat(b)+`"`, float64(cumulative)))
+		}
+		_, _ = io.WriteString(w, sample(h.metricName+"_bucket", prefix+`le="+Inf"`, float64(item.count)))
+		_, _ = io.WriteString(w, sample(h.metricName+"_sum", k, item.sum))
+		_, _ = io.WriteString(w, sample(h.metricName+"_count", k, float64(item.count)))
+	}
+}
+
+// ErrorKind returns the kind of the error, suitable as label value: the name of its type in snake case without the
+// 'Err' prefix for the errors of package fail (ex: 'not_found' for *fail.ErrNotFound, 'overload' for *fail.ErrOverload),
+// 'error' for the other errors; empty string if err is nil
+func ErrorKind(err error) string {
+	if err == nil {
+		return ""
+	}
+	t := reflect.TypeOf(err)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := t.Name()
+	if !strings.HasSuffix(t.PkgPath(), "lib/utils/fail") || name == "" || !unicode.IsUpper(rune(name[0])) {
+		return "error"
+	}
+	if len(name) > 3 && strings.HasPrefix(name, "Err") && unicode.IsUpper(rune(name[3])) {
+		name = name[3:]
+	}
+
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+func TestHandler(t *testing.T) {
+	counter := NewCounterVec("test_calls_total", "Calls", "method", "code")
+	counter.Inc("Create", "OK")
+	counter.Inc("Create", "OK")
+	counter.Add(3, "Delete", `Not"Found`)
+	histogram := NewHistogramVec("test_call_duration_seconds", "Durations", []float64{0.1, 1}, "method")
+	histogram.Observe(0.05, "Create")
+	histogram.Observe(0.5, "Create")
+	histogram.Observe(2, "Create")
+	NewGaugeFunc("test_active", "Active", func() float64 { return 4 })
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "version=0.0.4")
+	assert.Contains(t, body, "# TYPE test_calls_total counter\n")
+	assert.Contains(t, body, `test_calls_total{method="Create",code="OK"} 2`+"\n")
+	assert.Contains(t, body, `test_calls_total{method="Delete",code="Not\"Found"} 3`+"\n")
+	assert.Contains(t, body, `test_call_duration_seconds_bucket{method="Create",le="0.1"} 1`+"\n")
+	assert.Contains(t, body, `test_call_duration_seconds_bucket{method="Create",le="1"} 2`+"\n")
+	assert.Contains(t, body, `test_call_duration_seconds_bucket{method="Create",le="+Inf"} 3`+"\n")
+	assert.Contains(t, body, `test_call_duration_seconds_sum{method="Create"} 2.55`+"\n")
+	assert.Contains(t, body, `test_call_duration_seconds_count{method="Create"} 3`+"\n")
+	assert.Contains(t, body, "test_active 4\n")
+
+	assert.Panics(t, func() { NewGaugeFunc("test_active", "Active", func() float64 { return 0 }) })
+	assert.Panics(t, func() { counter.Inc("Create") })
+}
+
+func TestErrorKind(t *testing.T) {
+	assert.Equal(t, "", ErrorKind(nil))
+	assert.Equal(t, "not_found", ErrorKind(fail.NotFoundError("host not found")))
+	assert.Equal(t, "overload", ErrorKind(fail.OverloadError("too many requests")))
+	assert.Equal(t, "invalid_parameter", ErrorKind(fail.InvalidParameterError("name", "cannot be empty string")))
+	assert.Equal(t, "error", ErrorKind(fail.NewError("failed")))
+	assert.Equal(t, "error", ErrorKind(fmt.Errorf("failed")))
+}
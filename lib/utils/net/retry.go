@@ -108,6 +108,8 @@ func normalizeError(in error) (err error) {
 		switch realErr := in.(type) {
 		case *url.Error:
 			return normalizeURLError(realErr)
+		case *fail.ErrThrottled: // the remote asked to slow down, it's worth another try
+			return realErr
 		case fail.Error: // a fail.Error may contain a cause of type *url.Error; it's the way used to propagate an *url.Error received by drivers.
 			// In this case, normalize this url.Error accordingly
 			switch cause := realErr.Cause().(type) {
@@ -25,9 +25,13 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/metrics"
 	"github.com/CS-SI/SafeScale/lib/utils/retry/enums/verdict"
 )
 
+// retries counts the retries decided by the arbiters, by kind of the error of the failed try
+var retries = metrics.NewCounterVec("safescale_retries_total", "Number of retries of actions, by kind of error of the failed try ('' if the try did not fail)", "error_kind")
+
 // Try keeps track of the number of tries, starting from 1. Action is valid only when Err is nil.
 type Try struct {
 	Start time.Time
@@ -376,6 +380,7 @@ func (a action) loopWithSoftTimeout() fail.Error {
 			return retryErr
 		default:
 			// Retry is wanted, so blocks the loop the amount of time needed
+			retries.Inc(metrics.ErrorKind(try.Err))
 			if a.Officer != nil {
 				a.Officer.Block(try)
 			}
@@ -457,6 +462,7 @@ func (a action) loopWithHardTimeout(timeout time.Duration) fail.Error {
 			return retryErr
 		default:
 			// Retry is wanted, so blocks the loop the amount of time needed
+			retries.Inc(metrics.ErrorKind(try.Err))
 			if a.Officer != nil {
 				go func() {
 					a.Officer.Block(try)
@@ -0,0 +1,229 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/CS-SI/SafeScale/lib/utils/crypt"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// fileHeader starts the content of the secret files, followed by the salt of the key and by the encrypted secrets
+const fileHeader = "SAFESCALE-SECRETS-1\n"
+
+// fileStore is a Store keeping the secrets in a file encrypted with AES-GCM, the key being derived from a passphrase
+// with scrypt and a random salt stored in the header of the file
+type fileStore struct {
+	path       string
+	passphrase []byte
+	salt       []byte
+	key        *crypt.Key
+	lock       sync.Mutex
+}
+
+// NewFileStore returns a Store keeping the secrets in the encrypted file 'path' (created on first write)
+func NewFileStore(path, passphrase string) (Store, fail.Error) {
+	if path == "" {
+		return nil, fail.InvalidParameterError("path", "cannot be empty string")
+	}
+	if passphrase == "" {
+		return nil, fail.InvalidParameterError("passphrase", "cannot be empty string")
+	}
+
+	fs := &fileStore{path: path, passphrase: []byte(passphrase)}
+
+	// Checks the passphrase opens the existing file
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	if _, xerr := fs.load(); xerr != nil {
+		return nil, xerr
+	}
+
+	// A new file gets a new salt
+	if fs.key == nil {
+		salt, err := crypt.NewSalt()
+		if err != nil {
+			return nil, fail.ToError(err)
+		}
+		if xerr := fs.deriveKey(salt); xerr != nil {
+			return nil, xerr
+		}
+	}
+	return fs, nil
+}
+
+// deriveKey derives the key from the passphrase and the salt
+func (fs *fileStore) deriveKey(salt []byte) fail.Error {
+	key, err := crypt.DeriveKey(fs.passphrase, salt)
+	if err != nil {
+		return fail.ToError(err)
+	}
+	fs.salt = salt
+	fs.key = key
+	return nil
+}
+
+// Get returns the value of the secret stored at path
+func (fs *fileStore) Get(path string) (string, fail.Error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	content, xerr := fs.load()
+	if xerr != nil {
+		return "", xerr
+	}
+	value, ok := content[path]
+	if !ok {
+		return "", fail.NotFoundError("failed to find secret '%s'", path)
+	}
+	return value, nil
+}
+
+// Set stores the value of the secret at path
+func (fs *fileStore) Set(path, value string) fail.Error {
+	if path == "" {
+		return fail.InvalidParameterError("path", "cannot be empty string")
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	content, xerr := fs.load()
+	if xerr != nil {
+		return xerr
+	}
+	content[path] = value
+	return fs.save(content)
+}
+
+// Delete removes the secret stored at path
+func (fs *fileStore) Delete(path string) fail.Error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	content, xerr := fs.load()
+	if xerr != nil {
+		return xerr
+	}
+	if _, ok := content[path]; !ok {
+		return fail.NotFoundError("failed to find secret '%s'", path)
+	}
+	delete(content, path)
+	return fs.save(content)
+}
+
+// List returns the sorted paths of the secrets stored
+func (fs *fileStore) List() ([]string, fail.Error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	content, xerr := fs.load()
+	if xerr != nil {
+		return nil, xerr
+	}
+	out := make([]string, 0, len(content))
+	for k := range content {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Values returns the values of the secrets whose path starts with prefix, indexed by path, decrypting the file once
+func (fs *fileStore) Values(prefix string) (map[string]string, fail.Error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	content, xerr := fs.load()
+	if xerr != nil {
+		return nil, xerr
+	}
+	out := map[string]string{}
+	for k, v := range content {
+		if strings.HasPrefix(k, prefix) {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+// load reads and decrypts the content of the file; a missing file is an empty store
+func (fs *fileStore) load() (map[string]string, fail.Error) {
+	content := map[string]string{}
+	raw, err := ioutil.ReadFile(fs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return content, nil
+		}
+		return nil, fail.Wrap(err, "failed to read secret file '%s'", fs.path)
+	}
+	if !bytes.HasPrefix(raw, []byte(fileHeader)) || len(raw) < len(fileHeader)+crypt.SaltSize {
+		return nil, fail.SyntaxError("invalid content of secret file '%s': missing header", fs.path)
+	}
+	salt := raw[len(fileHeader) : len(fileHeader)+crypt.SaltSize]
+	ciphertext := raw[len(fileHeader)+crypt.SaltSize:]
+
+	// the key is derived again only if the file has been written with another salt
+	if fs.key == nil || !bytes.Equal(salt, fs.salt) {
+		if xerr := fs.deriveKey(append([]byte{}, salt...)); xerr != nil {
+			return nil, xerr
+		}
+	}
+	plaintext, err := crypt.Decrypt(ciphertext, fs.key)
+	if err != nil {
+		return nil, fail.InvalidRequestError("failed to decrypt secret file '%s' (wrong passphrase?)", fs.path)
+	}
+	if err = json.Unmarshal(plaintext, &content); err != nil {
+		return nil, fail.SyntaxError("invalid content of secret file '%s': %s", fs.path, err.Error())
+	}
+	return content, nil
+}
+
+// save encrypts and writes the content in the file, replacing it atomically
+func (fs *fileStore) save(content map[string]string) fail.Error {
+	plaintext, err := json.Marshal(content)
+	if err != nil {
+		return fail.ToError(err)
+	}
+	ciphertext, err := crypt.Encrypt(plaintext, fs.key)
+	if err != nil {
+		return fail.ToError(err)
+	}
+	raw := make([]byte, 0, len(fileHeader)+len(fs.salt)+len(ciphertext))
+	raw = append(append(append(raw, fileHeader...), fs.salt...), ciphertext...)
+
+	if err = os.MkdirAll(filepath.Dir(fs.path), 0700); err != nil {
+		return fail.Wrap(err, "failed to create folder of secret file '%s'", fs.path)
+	}
+	tmp := fs.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, raw, 0600); err != nil {
+		return fail.Wrap(err, "failed to write secret file '%s'", fs.path)
+	}
+	if err = os.Rename(tmp, fs.path); err != nil {
+		_ = os.Remove(tmp)
+		return fail.Wrap(err, "failed to write secret file '%s'", fs.path)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Masked is the string replacing the values of the secrets
+	Masked = "********"
+
+	// minMaskedLength is the minimal length of a value to be masked; shorter values would mask too much
+	minMaskedLength = 4
+)
+
+var (
+	maskedValues     []string
+	maskedValuesLock sync.RWMutex
+)
+
+// Register registers value as a secret, to be masked by Mask
+func Register(value string) {
+	if len(value) < minMaskedLength {
+		return
+	}
+
+	maskedValuesLock.Lock()
+	defer maskedValuesLock.Unlock()
+
+	for _, v := range maskedValues {
+		if v == value {
+			return
+		}
+	}
+	maskedValues = append(maskedValues, value)
+	// longest values first, to mask completely a secret containing another one
+	sort.SliceStable(maskedValues, func(i, j int) bool {
+		return len(maskedValues[i]) > len(maskedValues[j])
+	})
+}
+
+// Mask replaces the values of the registered secrets in s
+func Mask(s string) string {
+	maskedValuesLock.RLock()
+	defer maskedValuesLock.RUnlock()
+
+	for _, v := range maskedValues {
+		if strings.Contains(s, v) {
+			s = strings.Replace(s, v, Masked, -1)
+		}
+	}
+	return s
+}
+
+// MaskingHook is a logrus hook masking the values of the registered secrets in the messages and the fields of the logs
+type MaskingHook struct{}
+
+// Levels returns the levels concerned by the hook (all of them)
+func (MaskingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire masks the secrets in the entry
+func (MaskingHook) Fire(entry *logrus.Entry) error {
+	entry.Message = Mask(entry.Message)
+	for k, v := range entry.Data {
+		switch value := v.(type) {
+		case string:
+			entry.Data[k] = Mask(value)
+		case error:
+			if masked := Mask(value.Error()); masked != value.Error() {
+				entry.Data[k] = masked
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/CS-SI/SafeScale/lib/utils"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// Store is the interface of a secret store
+type Store interface {
+	Get(path string) (string, fail.Error) // returns the value of the secret stored at path
+	Set(path, value string) fail.Error    // stores the value of the secret at path
+	Delete(path string) fail.Error        // removes the secret stored at path
+	List() ([]string, fail.Error)         // returns the paths of the secrets stored
+}
+
+const (
+	// EnvStore is the name of the environment variable selecting the kind of store ('file' or 'vault')
+	EnvStore = "SAFESCALE_SECRET_STORE"
+	// EnvFile is the name of the environment variable containing the path of the file vault
+	EnvFile = "SAFESCALE_SECRET_FILE"
+	// EnvPassphrase is the name of the environment variable containing the passphrase of the file vault
+	EnvPassphrase = "SAFESCALE_SECRET_PASSPHRASE"
+	// EnvVaultAddress is the name of the environment variable containing the URL of the HashiCorp Vault server
+	EnvVaultAddress = "VAULT_ADDR"
+	// EnvVaultToken is the name of the environment variable containing the token used to access HashiCorp Vault
+	EnvVaultToken = "VAULT_TOKEN"
+	// EnvVaultMount is the name of the environment variable containing the mount point of the KV secrets engine in HashiCorp Vault
+	EnvVaultMount = "SAFESCALE_VAULT_MOUNT"
+
+	defaultFile       = "$HOME/.safescale/secrets.vault"
+	defaultVaultMount = "secret"
+)
+
+var (
+	defaultStore     Store
+	defaultStoreLock sync.RWMutex
+
+	// referencePattern matches the references to a secret ({{secret "<path>"}}), to an environment variable
+	// ({{env "<name>"}}) or to the content of a file ({{file "<path>"}})
+	referencePattern = regexp.MustCompile(`\{\{\s*(secret|env|file)\s+"([^"]+)"\s*\}\}`)
+)
+
+// SetDefaultStore sets the store used to resolve the references to secrets (nil disables the resolution)
+func SetDefaultStore(store Store) {
+	defaultStoreLock.Lock()
+	defer defaultStoreLock.Unlock()
+	defaultStore = store
+}
+
+// DefaultStore returns the store used to resolve the references to secrets (nil if none is configured)
+func DefaultStore() Store {
+	defaultStoreLock.RLock()
+	defer defaultStoreLock.RUnlock()
+	return defaultStore
+}
+
+// NewStoreFromEnv creates the store described by the environment variables
+// Returns nil, nil if SAFESCALE_SECRET_STORE is not set
+func NewStoreFromEnv() (Store, fail.Error) {
+	kind := strings.ToLower(strings.TrimSpace(os.Getenv(EnvStore)))
+	switch kind {
+	case "":
+		return nil, nil
+	case "file":
+		path := os.Getenv(EnvFile)
+		if path == "" {
+			path = defaultFile
+		}
+		passphrase := os.Getenv(EnvPassphrase)
+		if passphrase == "" {
+			return nil, fail.InvalidRequestError("environment variable '%s' must be set to use a file vault", EnvPassphrase)
+		}
+		Register(passphrase)
+		return NewFileStore(utils.AbsPathify(path), passphrase)
+	case "vault":
+		mount := os.Getenv(EnvVaultMount)
+		if mount == "" {
+			mount = defaultVaultMount
+		}
+		token := os.Getenv(EnvVaultToken)
+		Register(token)
+		return NewVaultStore(os.Getenv(EnvVaultAddress), token, mount)
+	default:
+		return nil, fail.SyntaxError("invalid value '%s' of environment variable '%s': must be 'file' or 'vault'", kind, EnvStore)
+	}
+}
+
+// Reference returns the reference to the secret stored at path, as it can be used in templates and tenant files
+func Reference(path string) string {
+	return fmt.Sprintf(`{{secret "%s"}}`, path)
+}
+
+// Lookup returns the value of the secret stored at path in the default store
+// The value is registered to be masked in logs and outputs
+func Lookup(path string) (string, fail.Error) {
+	if path == "" {
+		return "", fail.InvalidParameterError("path", "cannot be empty string")
+	}
+	store := DefaultStore()
+	if store == nil {
+		return "", fail.NotAvailableError("cannot read secret '%s': no secret store configured", path)
+	}
+	value, xerr := store.Get(path)
+	if xerr != nil {
+		return "", xerr
+	}
+	Register(value)
+	return value, nil
+}
+
+// valuesReader is implemented by the stores able to return several secrets at once
+type valuesReader interface {
+	Values(prefix string) (map[string]string, fail.Error)
+}
+
+// Values returns the values of the secrets of store whose path starts with prefix, indexed by path
+// The file store decrypts its file only once
+func Values(store Store, prefix string) (map[string]string, fail.Error) {
+	if store == nil {
+		return nil, fail.InvalidParameterError("store", "cannot be nil")
+	}
+	if vr, ok := store.(valuesReader); ok {
+		return vr.Values(prefix)
+	}
+
+	list, xerr := store.List()
+	if xerr != nil {
+		return nil, xerr
+	}
+	out := map[string]string{}
+	for _, path := range list {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		value, xerr := store.Get(path)
+		if xerr != nil {
+			return nil, xerr
+		}
+		out[path] = value
+	}
+	return out, nil
+}
+
+// Protect stores value in the default store at path and returns the reference to it
+// If no store is configured, returns value unchanged
+// In both cases, the value is registered to be masked in logs and outputs
+func Protect(path, value string) (string, fail.Error) {
+	if path == "" {
+		return "", fail.InvalidParameterError("path", "cannot be empty string")
+	}
+	Register(value)
+	store := DefaultStore()
+	if store == nil {
+		return value, nil
+	}
+	if xerr := store.Set(path, value); xerr != nil {
+		return "", xerr
+	}
+	return Reference(path), nil
+}
+
+// Discard removes from the default store the secret referenced by ref, if ref is a reference to a secret
+func Discard(ref string) fail.Error {
+	m := referencePattern.FindStringSubmatch(strings.TrimSpace(ref))
+	if m == nil || m[1] != "secret" {
+		return nil
+	}
+	store := DefaultStore()
+	if store == nil {
+		return fail.NotAvailableError("cannot delete secret '%s': no secret store configured", m[2])
+	}
+	return store.Delete(m[2])
+}
+
+// Resolve replaces the references in s by their values
+// The values are registered to be masked in logs and outputs
+func Resolve(s string) (string, fail.Error) {
+	var xerr fail.Error
+	out := referencePattern.ReplaceAllStringFunc(s, func(ref string) string {
+		if xerr != nil {
+			return ref
+		}
+		m := referencePattern.FindStringSubmatch(ref)
+		var value string
+		value, xerr = resolveReference(m[1], m[2])
+		return value
+	})
+	if xerr != nil {
+		return "", xerr
+	}
+	return out, nil
+}
+
+// resolveReference returns the value of the reference of kind 'kind' ('secret', 'env' or 'file') to 'name'
+func resolveReference(kind, name string) (string, fail.Error) {
+	switch kind {
+	case "env":
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fail.NotFoundError("environment variable '%s' is not set", name)
+		}
+		Register(value)
+		return value, nil
+	case "file":
+		content, err := ioutil.ReadFile(utils.AbsPathify(name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return "", fail.NotFoundError("failed to find file '%s'", name)
+			}
+			return "", fail.Wrap(err, "failed to read file '%s'", name)
+		}
+		value := strings.TrimRight(string(content), "\r\n")
+		Register(value)
+		return value, nil
+	default:
+		return Lookup(name)
+	}
+}
+
+// Check checks the syntax of the references in s, without resolving them
+// The content of s is not repeated in the error, as it may contain values to protect
+func Check(s string) fail.Error {
+	rest := referencePattern.ReplaceAllString(s, "")
+	if strings.Contains(rest, "{{") || strings.Contains(rest, "}}") {
+		return fail.SyntaxError(`invalid reference: must be {{secret "<path>"}}, {{env "<name>"}} or {{file "<path>"}}`)
+	}
+	return nil
+}
+
+// CheckMap checks the syntax of the references in the string values of in (including in sub-maps and slices),
+// without resolving them
+func CheckMap(in map[string]interface{}) fail.Error {
+	return checkValue(in)
+}
+
+func checkValue(in interface{}) fail.Error {
+	switch v := in.(type) {
+	case string:
+		return Check(v)
+	case map[string]interface{}:
+		for k, item := range v {
+			if xerr := checkValue(item); xerr != nil {
+				return fail.Wrap(xerr, "failed to check '%s'", k)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if xerr := checkValue(item); xerr != nil {
+				return xerr
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveMap returns a copy of in where the references in string values (including in sub-maps and slices)
+// are replaced by their values
+func ResolveMap(in map[string]interface{}) (map[string]interface{}, fail.Error) {
+	out, xerr := resolveValue(in)
+	if xerr != nil {
+		return nil, xerr
+	}
+	return out.(map[string]interface{}), nil
+}
+
+func resolveValue(in interface{}) (interface{}, fail.Error) {
+	switch v := in.(type) {
+	case string:
+		return Resolve(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			resolved, xerr := resolveValue(item)
+			if xerr != nil {
+				return nil, fail.Wrap(xerr, "failed to resolve '%s'", k)
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			resolved, xerr := resolveValue(item)
+			if xerr != nil {
+				return nil, xerr
+			}
+			out = append(out, resolved)
+		}
+		return out, nil
+	default:
+		return in, nil
+	}
+}
@@ -0,0 +1,284 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/utils/crypt"
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// vaultStandIn is a minimal in-memory implementation of the HTTP API of the KV version 2 secrets engine of HashiCorp Vault
+type vaultStandIn struct {
+	token   string
+	mount   string
+	lock    sync.Mutex
+	secrets map[string]map[string]interface{}
+}
+
+func (v *vaultStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Token") != v.token {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	prefix := "/v1/" + v.mount + "/"
+	switch {
+	case strings.HasPrefix(r.URL.Path, prefix+"data/"):
+		path := strings.TrimPrefix(r.URL.Path, prefix+"data/")
+		switch r.Method {
+		case http.MethodGet:
+			content, ok := v.secrets[path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": content}})
+		case http.MethodPost:
+			var request struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			v.secrets[path] = request.Data
+		}
+	case strings.HasPrefix(r.URL.Path, prefix+"metadata/"):
+		path := strings.TrimPrefix(r.URL.Path, prefix+"metadata/")
+		switch r.Method {
+		case http.MethodDelete:
+			delete(v.secrets, path)
+			w.WriteHeader(http.StatusNoContent)
+		case "LIST":
+			keys := map[string]bool{}
+			for k := range v.secrets {
+				if !strings.HasPrefix(k, path) {
+					continue
+				}
+				rest := strings.TrimPrefix(k, path)
+				if i := strings.Index(rest, "/"); i >= 0 {
+					rest = rest[:i+1]
+				}
+				keys[rest] = true
+			}
+			if len(keys) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			list := make([]string, 0, len(keys))
+			for k := range keys {
+				list = append(list, k)
+			}
+			sort.Strings(list)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"keys": list}})
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testStore(t *testing.T, store Store) {
+	_, xerr := store.Get("db/password")
+	assert.IsType(t, &fail.ErrNotFound{}, xerr)
+
+	require.Nil(t, store.Set("db/password", "s3cr3t!"))
+	require.Nil(t, store.Set("tenants/ovh/password", "0vhPa55"))
+	value, xerr := store.Get("db/password")
+	require.Nil(t, xerr)
+	assert.Equal(t, "s3cr3t!", value)
+
+	list, xerr := store.List()
+	require.Nil(t, xerr)
+	assert.Equal(t, []string{"db/password", "tenants/ovh/password"}, list)
+
+	values, xerr := Values(store, "tenants/")
+	require.Nil(t, xerr)
+	assert.Equal(t, map[string]string{"tenants/ovh/password": "0vhPa55"}, values)
+
+	require.Nil(t, store.Delete("db/password"))
+	_, xerr = store.Get("db/password")
+	assert.IsType(t, &fail.ErrNotFound{}, xerr)
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	require.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "secrets.vault")
+
+	store, xerr := NewFileStore(path, "my passphrase")
+	require.Nil(t, xerr)
+	testStore(t, store)
+
+	// content is encrypted
+	content, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	assert.NotContains(t, string(content), "0vhPa55")
+
+	// the key is derived with a random salt kept in the header of the file
+	require.True(t, strings.HasPrefix(string(content), fileHeader))
+	salt := content[len(fileHeader) : len(fileHeader)+crypt.SaltSize]
+	other, xerr := NewFileStore(filepath.Join(dir, "other.vault"), "my passphrase")
+	require.Nil(t, xerr)
+	require.Nil(t, other.Set("tenants/ovh/password", "0vhPa55"))
+	otherContent, err := ioutil.ReadFile(filepath.Join(dir, "other.vault"))
+	require.Nil(t, err)
+	assert.NotEqual(t, salt, otherContent[len(fileHeader):len(fileHeader)+crypt.SaltSize])
+
+	// content survives a reopening with the same passphrase only
+	store, xerr = NewFileStore(path, "my passphrase")
+	require.Nil(t, xerr)
+	value, xerr := store.Get("tenants/ovh/password")
+	require.Nil(t, xerr)
+	assert.Equal(t, "0vhPa55", value)
+
+	_, xerr = NewFileStore(path, "wrong passphrase")
+	assert.IsType(t, &fail.ErrInvalidRequest{}, xerr)
+}
+
+func TestVaultStore(t *testing.T) {
+	server := httptest.NewServer(&vaultStandIn{token: "root", mount: "kv", secrets: map[string]map[string]interface{}{}})
+	defer server.Close()
+
+	store, xerr := NewVaultStore(server.URL, "root", "kv")
+	require.Nil(t, xerr)
+	testStore(t, store)
+
+	store, xerr = NewVaultStore(server.URL, "bad token", "kv")
+	require.Nil(t, xerr)
+	_, xerr = store.Get("tenants/ovh/password")
+	assert.IsType(t, &fail.ErrForbidden{}, xerr)
+}
+
+func TestResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	require.Nil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// without store, strings without reference are left untouched, references cannot be resolved
+	SetDefaultStore(nil)
+	out, xerr := Resolve("no reference")
+	require.Nil(t, xerr)
+	assert.Equal(t, "no reference", out)
+	_, xerr = Resolve(`{{secret "db/password"}}`)
+	assert.IsType(t, &fail.ErrNotAvailable{}, xerr)
+	value, xerr := Protect("clusters/c1/admin-password", "AdminPa55")
+	require.Nil(t, xerr)
+	assert.Equal(t, "AdminPa55", value)
+
+	store, xerr := NewFileStore(filepath.Join(dir, "secrets.vault"), "passphrase")
+	require.Nil(t, xerr)
+	SetDefaultStore(store)
+	defer SetDefaultStore(nil)
+	require.Nil(t, store.Set("db/password", "DbPa55"))
+
+	out, xerr = Resolve(`user:{{ secret "db/password" }}@host`)
+	require.Nil(t, xerr)
+	assert.Equal(t, "user:DbPa55@host", out)
+	_, xerr = Resolve(`{{secret "unknown"}}`)
+	assert.IsType(t, &fail.ErrNotFound{}, xerr)
+
+	tenant := map[string]interface{}{
+		"name":     "ovh",
+		"identity": map[string]interface{}{"Password": `{{secret "db/password"}}`, "Port": 22},
+		"regions":  []interface{}{"GRA5", `{{secret "db/password"}}`},
+	}
+	resolved, xerr := ResolveMap(tenant)
+	require.Nil(t, xerr)
+	assert.Equal(t, "DbPa55", resolved["identity"].(map[string]interface{})["Password"])
+	assert.Equal(t, 22, resolved["identity"].(map[string]interface{})["Port"])
+	assert.Equal(t, []interface{}{"GRA5", "DbPa55"}, resolved["regions"])
+	assert.Equal(t, `{{secret "db/password"}}`, tenant["identity"].(map[string]interface{})["Password"])
+
+	ref, xerr := Protect("clusters/c1/admin-password", "AdminPa55")
+	require.Nil(t, xerr)
+	assert.Equal(t, Reference("clusters/c1/admin-password"), ref)
+	out, xerr = Resolve(ref)
+	require.Nil(t, xerr)
+	assert.Equal(t, "AdminPa55", out)
+	require.Nil(t, Discard(ref))
+	_, xerr = store.Get("clusters/c1/admin-password")
+	assert.IsType(t, &fail.ErrNotFound{}, xerr)
+	assert.Nil(t, Discard("not a reference"))
+
+	// environment variables and files
+	require.Nil(t, os.Setenv("SAFESCALE_TEST_SECRET", "EnvPa55"))
+	defer func() { _ = os.Unsetenv("SAFESCALE_TEST_SECRET") }()
+	keyFile := filepath.Join(dir, "key")
+	require.Nil(t, ioutil.WriteFile(keyFile, []byte("FilePa55\n"), 0600))
+	out, xerr = Resolve(`{{env "SAFESCALE_TEST_SECRET"}}:{{file "` + keyFile + `"}}`)
+	require.Nil(t, xerr)
+	assert.Equal(t, "EnvPa55:FilePa55", out)
+	assert.Equal(t, Masked+":"+Masked, Mask(out))
+	_, xerr = Resolve(`{{env "SAFESCALE_TEST_UNSET"}}`)
+	assert.IsType(t, &fail.ErrNotFound{}, xerr)
+	_, xerr = Resolve(`{{file "` + filepath.Join(dir, "missing") + `"}}`)
+	assert.IsType(t, &fail.ErrNotFound{}, xerr)
+	assert.Nil(t, Discard(`{{env "SAFESCALE_TEST_SECRET"}}`))
+}
+
+func TestCheck(t *testing.T) {
+	assert.Nil(t, Check("no reference"))
+	assert.Nil(t, Check(`user:{{ secret "db/password" }}@{{env "SAFESCALE_TEST_UNSET"}}`))
+	xerr := Check(`{{secret "db/password" | b64enc}}`)
+	assert.IsType(t, &fail.ErrSyntax{}, xerr)
+	assert.NotContains(t, xerr.Error(), "db/password")
+
+	// references are not resolved
+	assert.Nil(t, CheckMap(map[string]interface{}{
+		"identity": map[string]interface{}{"Password": `{{secret "unknown"}}`, "Port": 22},
+		"regions":  []interface{}{"GRA5", `{{file "/missing"}}`},
+	}))
+	xerr = CheckMap(map[string]interface{}{"identity": map[string]interface{}{"Password": `{{secret "p"`}})
+	assert.IsType(t, &fail.ErrSyntax{}, fail.RootCause(xerr))
+	assert.Contains(t, xerr.Error(), "Password")
+}
+
+func TestMask(t *testing.T) {
+	Register("abc")
+	Register("Pa55word")
+	Register("Pa55word+more")
+
+	assert.Equal(t, "abc", Mask("abc"))
+	assert.Equal(t, "pass="+Masked+" other="+Masked, Mask("pass=Pa55word+more other=Pa55word"))
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Message = "connecting with Pa55word"
+	entry.Data = logrus.Fields{"password": "Pa55word", "error": fail.NewError("denied for Pa55word"), "count": 1}
+	require.Nil(t, MaskingHook{}.Fire(entry))
+	assert.Equal(t, "connecting with "+Masked, entry.Message)
+	assert.Equal(t, Masked, entry.Data["password"])
+	assert.Equal(t, "denied for "+Masked, entry.Data["error"])
+	assert.Equal(t, 1, entry.Data["count"])
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// vaultValueField is the field of the Vault secret containing the value
+const vaultValueField = "value"
+
+// vaultStore is a Store using the KV version 2 secrets engine of a HashiCorp Vault server, through its HTTP API
+// The value of the secret 'path' is stored in the field 'value' of the Vault secret '<mount>/<path>'
+type vaultStore struct {
+	address string
+	token   string
+	mount   string
+	client  *http.Client
+}
+
+// NewVaultStore returns a Store using the KV version 2 secrets engine mounted at 'mount' of the Vault server at 'address'
+func NewVaultStore(address, token, mount string) (Store, fail.Error) {
+	if address == "" {
+		return nil, fail.InvalidParameterError("address", "cannot be empty string")
+	}
+	if mount == "" {
+		return nil, fail.InvalidParameterError("mount", "cannot be empty string")
+	}
+	if _, err := url.Parse(address); err != nil {
+		return nil, fail.SyntaxError("invalid Vault address '%s': %s", address, err.Error())
+	}
+
+	return &vaultStore{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		mount:   strings.Trim(mount, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Get returns the value of the secret stored at path
+func (vs *vaultStore) Get(path string) (string, fail.Error) {
+	var response struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if xerr := vs.do(http.MethodGet, "data", path, nil, &response); xerr != nil {
+		return "", xerr
+	}
+	value, ok := response.Data.Data[vaultValueField].(string)
+	if !ok {
+		return "", fail.NotFoundError("failed to find field '%s' in Vault secret '%s'", vaultValueField, path)
+	}
+	return value, nil
+}
+
+// Set stores the value of the secret at path
+func (vs *vaultStore) Set(path, value string) fail.Error {
+	if path == "" {
+		return fail.InvalidParameterError("path", "cannot be empty string")
+	}
+	request := map[string]interface{}{
+		"data": map[string]interface{}{vaultValueField: value},
+	}
+	return vs.do(http.MethodPost, "data", path, request, nil)
+}
+
+// Delete removes the secret stored at path, with all its versions
+func (vs *vaultStore) Delete(path string) fail.Error {
+	return vs.do(http.MethodDelete, "metadata", path, nil, nil)
+}
+
+// List returns the sorted paths of the secrets stored
+func (vs *vaultStore) List() ([]string, fail.Error) {
+	var out []string
+	if xerr := vs.list("", &out); xerr != nil {
+		return nil, xerr
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// list collects recursively the paths of the secrets under folder 'prefix'
+func (vs *vaultStore) list(prefix string, out *[]string) fail.Error {
+	var response struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	xerr := vs.do("LIST", "metadata", prefix, nil, &response)
+	if xerr != nil {
+		if _, ok := xerr.(*fail.ErrNotFound); ok {
+			return nil
+		}
+		return xerr
+	}
+	for _, k := range response.Data.Keys {
+		if strings.HasSuffix(k, "/") {
+			if xerr = vs.list(prefix+k, out); xerr != nil {
+				return xerr
+			}
+			continue
+		}
+		*out = append(*out, prefix+k)
+	}
+	return nil
+}
+
+// do sends a request to the Vault API on '<mount>/<kind>/<path>' and decodes the response in 'response' if not nil
+func (vs *vaultStore) do(method, kind, path string, request, response interface{}) fail.Error {
+	var body io.Reader
+	if request != nil {
+		content, err := json.Marshal(request)
+		if err != nil {
+			return fail.ToError(err)
+		}
+		body = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, vs.address+"/v1/"+vs.mount+"/"+kind+"/"+strings.TrimPrefix(path, "/"), body)
+	if err != nil {
+		return fail.ToError(err)
+	}
+	if vs.token != "" {
+		req.Header.Set("X-Vault-Token", vs.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := vs.client.Do(req)
+	if err != nil {
+		return fail.Wrap(err, "failed to contact Vault server")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fail.Wrap(err, "failed to read response of Vault server")
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fail.NotFoundError("failed to find secret '%s'", path)
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnauthorized:
+		return fail.ForbiddenError("access to secret '%s' denied by Vault server", path)
+	case resp.StatusCode >= 300:
+		return fail.NewError("Vault server responded '%s' on secret '%s': %s", resp.Status, path, strings.TrimSpace(string(content)))
+	}
+
+	if response != nil && len(content) > 0 {
+		if err = json.Unmarshal(content, response); err != nil {
+			return fail.SyntaxError("invalid response of Vault server: %s", err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+const (
+	tracesPath    = "/v1/traces"
+	queueSize     = 4096
+	batchSize     = 256
+	flushInterval = 5 * time.Second
+	exportTimeout = 10 * time.Second
+)
+
+// OTLPExporter sends the spans by batch to an OpenTelemetry collector, with the OTLP protocol over HTTP in JSON
+type OTLPExporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+	queue       chan *Span
+	flush       chan chan struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
+	closed      bool         // set by Shutdown; the spans ended after are dropped
+	closedLock  sync.RWMutex // protects closed and the sends on queue against its closing
+}
+
+var (
+	defaultExporter     *OTLPExporter
+	defaultExporterLock sync.RWMutex
+)
+
+// SetDefaultExporter sets the exporter of the ended spans, enabling tracing if exporter is not nil
+func SetDefaultExporter(exporter *OTLPExporter) {
+	defaultExporterLock.Lock()
+	defer defaultExporterLock.Unlock()
+	defaultExporter = exporter
+}
+
+// DefaultExporter returns the exporter of the ended spans, nil if tracing is not enabled
+func DefaultExporter() *OTLPExporter {
+	defaultExporterLock.RLock()
+	defer defaultExporterLock.RUnlock()
+	return defaultExporter
+}
+
+// Enabled tells if the spans are exported
+func Enabled() bool {
+	return DefaultExporter() != nil
+}
+
+// NewOTLPExporter creates an exporter sending the spans of the service to the collector at endpoint
+// ('http(s)://<host>:<port>', the OTLP/HTTP port of the collector being usually 4318)
+func NewOTLPExporter(endpoint, serviceName string) (*OTLPExporter, fail.Error) {
+	if endpoint = strings.TrimSpace(endpoint); endpoint == "" {
+		return nil, fail.InvalidParameterError("endpoint", "cannot be empty string")
+	}
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return nil, fail.InvalidParameterError("endpoint", "must be an URL 'http(s)://<host>:<port>'")
+	}
+	if serviceName == "" {
+		return nil, fail.InvalidParameterError("serviceName", "cannot be empty string")
+	}
+
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, tracesPath) {
+		url += tracesPath
+	}
+	e := &OTLPExporter{
+		url:         url,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: exportTimeout},
+		queue:       make(chan *Span, queueSize),
+		flush:       make(chan chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go e.loop()
+	return e, nil
+}
+
+// URL returns the URL the spans are sent to
+func (e *OTLPExporter) URL() string {
+	return e.url
+}
+
+// enqueue adds the span to the next batch; the span is dropped if the queue is full, the collector being unreachable
+// or too slow
+func (e *OTLPExporter) enqueue(s *Span) {
+	e.closedLock.RLock()
+	defer e.closedLock.RUnlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.queue <- s:
+	default:
+		logrus.Debugf("Dropping span '%s' of trace %s: export queue full", s.name, s.TraceID())
+	}
+}
+
+// Flush sends the spans ended so far
+func (e *OTLPExporter) Flush() {
+	ack := make(chan struct{})
+	select {
+	case e.flush <- ack:
+		<-ack
+	case <-e.done:
+	}
+}
+
+// Shutdown sends the remaining spans and stops the exporter; if it is the default exporter, tracing is disabled
+func (e *OTLPExporter) Shutdown() {
+	e.closeOnce.Do(func() {
+		defaultExporterLock.Lock()
+		if defaultExporter == e {
+			defaultExporter = nil
+		}
+		defaultExporterLock.Unlock()
+
+		e.closedLock.Lock()
+		e.closed = true
+		close(e.queue)
+		e.closedLock.Unlock()
+		<-e.done
+	})
+}
+
+func (e *OTLPExporter) loop() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	var batch []*Span
+	send := func() {
+		if len(batch) > 0 {
+			if xerr := e.export(batch); xerr != nil {
+				logrus.Warnf("Failed to export %d spans: %v", len(batch), xerr)
+			}
+			batch = nil
+		}
+	}
+	for {
+		select {
+		case s, ok := <-e.queue:
+			if !ok {
+				send()
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				send()
+			}
+		case ack := <-e.flush:
+			// Takes the spans already queued
+			for pending := len(e.queue); pending > 0; pending-- {
+				if s, ok := <-e.queue; ok {
+					batch = append(batch, s)
+				}
+			}
+			send()
+			close(ack)
+		case <-ticker.C:
+			send()
+		}
+	}
+}
+
+// export posts the spans to the collector
+func (e *OTLPExporter) export(spans []*Span) fail.Error {
+	content, err := json.Marshal(exportRequest(e.serviceName, spans))
+	if err != nil {
+		return fail.ToError(err)
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return fail.Wrap(err, "failed to send spans to '%s'", e.url)
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode/100 != 2 {
+		return fail.NewError("collector '%s' answered %s", e.url, resp.Status)
+	}
+	return nil
+}
+
+// The messages of OTLP in JSON (https://github.com/open-telemetry/opentelemetry-proto), the identifiers being
+// encoded in hexadecimal and the 64-bit integers as strings
+
+// ExportRequest is the body of the requests sent to the collector
+type ExportRequest struct {
+	ResourceSpans []ResourceSpans `json:"resourceSpans"`
+}
+
+// ResourceSpans contains the spans of a service
+type ResourceSpans struct {
+	Resource   Resource     `json:"resource"`
+	ScopeSpans []ScopeSpans `json:"scopeSpans"`
+}
+
+// Resource describes the service producing the spans
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+// ScopeSpans contains the spans produced by an instrumentation library
+type ScopeSpans struct {
+	Scope Scope      `json:"scope"`
+	Spans []SpanData `json:"spans"`
+}
+
+// Scope describes an instrumentation library
+type Scope struct {
+	Name string `json:"name"`
+}
+
+// SpanData is an exported span
+type SpanData struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              Kind       `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	Status            Status     `json:"status"`
+}
+
+// KeyValue is an attribute
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue is the value of an attribute
+type AnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// Status is the status of a span
+type Status struct {
+	Code    int    `json:"code,omitempty"` // 0: unset, 1: ok, 2: error
+	Message string `json:"message,omitempty"`
+}
+
+const statusCodeError = 2
+
+func exportRequest(serviceName string, spans []*Span) ExportRequest {
+	data := make([]SpanData, 0, len(spans))
+	for _, s := range spans {
+		data = append(data, s.data())
+	}
+	return ExportRequest{
+		ResourceSpans: []ResourceSpans{{
+			Resource:   Resource{Attributes: []KeyValue{keyValue("service.name", serviceName)}},
+			ScopeSpans: []ScopeSpans{{Scope: Scope{Name: "github.com/CS-SI/SafeScale"}, Spans: data}},
+		}},
+	}
+}
+
+func (s *Span) data() SpanData {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	d := SpanData{
+		TraceID:           s.TraceID(),
+		SpanID:            s.SpanID(),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	if s.parentID != [8]byte{} {
+		d.ParentSpanID = fmt.Sprintf("%x", s.parentID[:])
+	}
+	for _, a := range s.attributes {
+		d.Attributes = append(d.Attributes, keyValue(a.key, a.value))
+	}
+	if s.err != "" {
+		d.Status = Status{Code: statusCodeError, Message: s.err}
+	}
+	return d
+}
+
+func keyValue(key string, value interface{}) KeyValue {
+	var v AnyValue
+	switch value := value.(type) {
+	case string:
+		v.StringValue = &value
+	case bool:
+		v.BoolValue = &value
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		s := fmt.Sprintf("%d", value)
+		v.IntValue = &s
+	case float32:
+		f := float64(value)
+		v.DoubleValue = &f
+	case float64:
+		v.DoubleValue = &value
+	default:
+		s := fmt.Sprintf("%v", value)
+		v.StringValue = &s
+	}
+	return KeyValue{Key: key, Value: v}
+}
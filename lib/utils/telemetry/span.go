@@ -0,0 +1,221 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package telemetry provides distributed tracing: spans correlated by trace, propagated with the W3C Trace Context
+// and exported to an OpenTelemetry collector with the OTLP protocol
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Kind is the kind of a span, with the values of OTLP
+type Kind int
+
+const (
+	// KindInternal is the kind of the spans of internal operations
+	KindInternal Kind = 1
+	// KindServer is the kind of the spans of the handling of remote calls
+	KindServer Kind = 2
+	// KindClient is the kind of the spans of remote calls
+	KindClient Kind = 3
+)
+
+// TraceparentKey is the key of the header (or gRPC metadata) carrying the parent span of a remote call
+const TraceparentKey = "traceparent"
+
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// Span is a timed operation of a trace
+// A nil *Span is valid and does nothing, allowing the code to be instrumented whether tracing is enabled or not
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     Kind
+	start    time.Time
+	end      time.Time
+
+	lock       sync.Mutex
+	attributes []attribute
+	err        string
+	ended      bool
+}
+
+type spanKey struct{}
+
+// remoteParent is the span of the caller of a remote call
+type remoteParent struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+type remoteParentKey struct{}
+
+// Start starts a span, child of the span carried by ctx (or of the remote parent of the call) if any, starting a new
+// trace otherwise; returns a copy of ctx carrying the span
+// Returns ctx and a nil span if tracing is not enabled
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+	if parent := FromContext(ctx); parent != nil {
+		span := parent.StartChild(name, kind)
+		return ContextWithSpan(ctx, span), span
+	}
+
+	span := newSpan(name, kind)
+	if remote, ok := ctx.Value(remoteParentKey{}).(remoteParent); ok {
+		span.traceID, span.parentID = remote.traceID, remote.spanID
+	} else {
+		_, _ = rand.Read(span.traceID[:])
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// StartChild starts a span child of the span carried by ctx; returns ctx and a nil span if ctx does not carry a span
+func StartChild(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	span := parent.StartChild(name, kind)
+	return ContextWithSpan(ctx, span), span
+}
+
+func newSpan(name string, kind Kind) *Span {
+	span := &Span{name: name, kind: kind, start: time.Now()}
+	_, _ = rand.Read(span.spanID[:])
+	return span
+}
+
+// StartChild starts a span child of s; returns nil if s is nil
+func (s *Span) StartChild(name string, kind Kind) *Span {
+	if s == nil || !Enabled() {
+		return nil
+	}
+	span := newSpan(name, kind)
+	span.traceID, span.parentID = s.traceID, s.spanID
+	return span
+}
+
+// SetAttribute sets an attribute of the span; value may be a string, a bool, an integer or a float
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := range s.attributes {
+		if s.attributes[i].key == key {
+			s.attributes[i].value = value
+			return
+		}
+	}
+	s.attributes = append(s.attributes, attribute{key: key, value: value})
+}
+
+// End ends the span, in error if err is not nil, and hands it to the exporter
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.lock.Unlock()
+
+	if exporter := DefaultExporter(); exporter != nil {
+		exporter.enqueue(s)
+	}
+}
+
+// TraceID returns the identifier of the trace of the span in hexadecimal, empty string if s is nil
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// SpanID returns the identifier of the span in hexadecimal, empty string if s is nil
+func (s *Span) SpanID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.spanID[:])
+}
+
+// Traceparent returns the W3C Trace Context header designating the span as parent of a remote call, empty string if
+// s is nil
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return "00-" + s.TraceID() + "-" + s.SpanID() + "-01"
+}
+
+// ContextWithSpan returns a copy of ctx carrying the span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// FromContext returns the span carried by ctx, nil if there is none
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithTraceparent returns a copy of ctx carrying the remote parent designated by the W3C Trace Context header;
+// returns ctx if the header is invalid
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	// version-traceid-parentid-flags
+	if len(traceparent) != 55 || traceparent[2] != '-' || traceparent[35] != '-' || traceparent[52] != '-' {
+		return ctx
+	}
+	var remote remoteParent
+	if _, err := hex.Decode(remote.traceID[:], []byte(traceparent[3:35])); err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(remote.spanID[:], []byte(traceparent[36:52])); err != nil {
+		return ctx
+	}
+	if remote.traceID == [16]byte{} || remote.spanID == [8]byte{} {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteParentKey{}, remote)
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2018-2021, CS Systemes d'Information, http://csgroup.eu
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CS-SI/SafeScale/lib/utils/fail"
+)
+
+// collector is an in-process OpenTelemetry collector keeping the spans received
+type collector struct {
+	lock     sync.Mutex
+	services []string
+	spans    []SpanData
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var req ExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, rs := range req.ResourceSpans {
+		c.services = append(c.services, *rs.Resource.Attributes[0].Value.StringValue)
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *collector) span(name string) *SpanData {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i := range c.spans {
+		if c.spans[i].Name == name {
+			return &c.spans[i]
+		}
+	}
+	return nil
+}
+
+func TestDisabled(t *testing.T) {
+	ctx, span := Start(context.Background(), "HostService/Create", KindServer)
+	assert.Nil(t, span)
+	assert.Nil(t, FromContext(ctx))
+	span.SetAttribute("host", "web1")
+	span.End(nil)
+	assert.Equal(t, "", span.TraceID())
+}
+
+func TestExport(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	exporter, xerr := NewOTLPExporter(server.URL, "safescaled")
+	require.Nil(t, xerr)
+	SetDefaultExporter(exporter)
+	defer SetDefaultExporter(nil)
+
+	// The call comes from a remote parent
+	ctx := ContextWithTraceparent(context.Background(), "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	ctx, root := Start(ctx, "ClusterService/Create", KindServer)
+	require.NotNil(t, root)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", root.TraceID())
+
+	ctx, task := StartChild(ctx, "task", KindInternal)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		call := FromContext(ctx).StartChild("openstack.CreateHost", KindClient)
+		call.SetAttribute("retries", 2)
+		call.End(fail.OverloadError("too many requests"))
+	}()
+	<-done
+	task.End(nil)
+	root.End(nil)
+	exporter.Flush()
+
+	c.lock.Lock()
+	assert.Equal(t, []string{"safescaled"}, c.services)
+	assert.Len(t, c.spans, 3)
+	c.lock.Unlock()
+
+	rootData := c.span("ClusterService/Create")
+	require.NotNil(t, rootData)
+	assert.Equal(t, "b7ad6b7169203331", rootData.ParentSpanID)
+	assert.Equal(t, KindServer, rootData.Kind)
+	assert.Equal(t, 0, rootData.Status.Code)
+
+	taskData := c.span("task")
+	require.NotNil(t, taskData)
+	assert.Equal(t, root.SpanID(), taskData.ParentSpanID)
+
+	callData := c.span("openstack.CreateHost")
+	require.NotNil(t, callData)
+	assert.Equal(t, root.TraceID(), callData.TraceID)
+	assert.Equal(t, task.SpanID(), callData.ParentSpanID)
+	assert.Equal(t, statusCodeError, callData.Status.Code)
+	assert.Contains(t, callData.Status.Message, "too many requests")
+	require.Len(t, callData.Attributes, 1)
+	assert.Equal(t, "2", *callData.Attributes[0].Value.IntValue)
+
+	// the spans ending after the shutdown are dropped
+	_, late := Start(context.Background(), "HostService/Delete", KindServer)
+	require.NotNil(t, late)
+	exporter.Shutdown()
+	assert.False(t, Enabled())
+	late.End(nil)
+	exporter.enqueue(late)
+	exporter.Shutdown()
+}
@@ -22,9 +22,22 @@ import (
 	"github.com/Masterminds/sprig"
 
 	"github.com/CS-SI/SafeScale/lib/utils/fail"
+	"github.com/CS-SI/SafeScale/lib/utils/secret"
 )
 
+// secretFuncs defines the functions giving access to the secret store in templates
+var secretFuncs = txttmpl.FuncMap{
+	"secret": func(path string) (string, error) {
+		value, xerr := secret.Lookup(path)
+		if xerr != nil {
+			return "", xerr
+		}
+		return value, nil
+	},
+}
+
 // Parse returns a text template with default funcs declared
+// The function 'secret' returns the value of a secret of the secret store (ie '{{secret "db/password"}}')
 func Parse(title, content string) (*txttmpl.Template, fail.Error) {
 	if title == "" {
 		return nil, fail.InvalidParameterError("title", "cannot be empty string")
@@ -32,7 +45,31 @@ func Parse(title, content string) (*txttmpl.Template, fail.Error) {
 	if content == "" {
 		return nil, fail.InvalidParameterError("content", "cannot be empty string")
 	}
-	r, err := txttmpl.New(title).Funcs(sprig.TxtFuncMap()).Parse(content)
+	r, err := txttmpl.New(title).Funcs(sprig.TxtFuncMap()).Funcs(secretFuncs).Parse(content)
+	if err != nil {
+		return nil, fail.ToError(err)
+	}
+	return r, nil
+}
+
+// restrictedFuncs lists the sprig functions giving access to the environment of the daemon
+var restrictedFuncs = []string{"env", "expandenv", "getHostByName"}
+
+// ParseRestricted returns a text template for content supplied by a client (ie the values of feature parameters)
+// The function 'secret' and the functions of sprig giving access to the environment of the daemon are not declared,
+// so the parsing of a content using them fails.
+func ParseRestricted(title, content string) (*txttmpl.Template, fail.Error) {
+	if title == "" {
+		return nil, fail.InvalidParameterError("title", "cannot be empty string")
+	}
+	if content == "" {
+		return nil, fail.InvalidParameterError("content", "cannot be empty string")
+	}
+	funcs := sprig.TxtFuncMap()
+	for _, v := range restrictedFuncs {
+		delete(funcs, v)
+	}
+	r, err := txttmpl.New(title).Funcs(funcs).Parse(content)
 	if err != nil {
 		return nil, fail.ToError(err)
 	}